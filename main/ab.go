@@ -0,0 +1,427 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file ab.go
+ * @brief A/B comparison of two parameter sets (wator compare -config-a a.yaml -config-b b.yaml).
+ * @details Both runs start from the command-line flags, take their own changes from a
+ * parameter file, and share one seed, so with identical files they are identical and any
+ * difference comes from the parameters. They are stepped in lockstep and drawn side by side,
+ * optionally saved as two stacked PNG strips of evenly spaced chronons, and summarised as a
+ * CSV table comparing the two population trajectories.
+ *
+ * A parameter file is a flat YAML mapping of the names cf.with accepts:
+ *
+ *     # Slower-breeding sharks
+ *     SharkBreed: 6
+ *     shark-vision: 2
+ */
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+/** Column names written as the first row of the comparison table. */
+var abColumns = []string{"species", "final_a", "final_b", "mean_a", "mean_b", "mean_diff", "rms_diff", "max_abs_diff", "max_diff_chronon", "diverged_at"}
+
+/**
+ * @brief Reports whether compare was asked to run two parameter files rather than compare fingerprints.
+ */
+func abRequested(args []string) bool {
+	return slices.ContainsFunc(args, func(a string) bool {
+		name, _, _ := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		return strings.HasPrefix(a, "-") && (name == "config-a" || name == "config-b")
+	})
+}
+
+/**
+ * @brief The A/B form of the compare subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdCompareRuns(args []string) int {
+	cf := newConfigFlags("compare", "Runs the parameter files -config-a and -config-b with the same seed side by side and prints how their populations differ as CSV.", os.Stderr)
+	pathA := cf.fs.String("config-a", "", "parameter `file` of run A: \"name: value\" lines, e.g. \"SharkBreed: 6\"")
+	pathB := cf.fs.String("config-b", "", "parameter `file` of run B")
+	quiet := cf.fs.Bool("quiet", false, "do not draw the runs, only print the comparison")
+	stripPath := cf.fs.String("png", "", "write the runs as two stacked strips of -frames frames to `file`")
+	frames := cf.fs.Int("frames", 8, "`number` of evenly spaced chronons in each -png strip")
+	cfg, code, ok := setupCommand(cf, args)
+	if !ok {
+		return code
+	}
+	if *pathA == "" || *pathB == "" || *frames < 1 || cfg.Ensemble > 1 || cfg.Diff || cfg.Viewport > 0 || cfg.Origin != "" || cfg.Center || cfg.History > 0 || cfg.Inspect || cfg.Paint || cfg.Accessible {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\ncompare needs -config-a, -config-b and -frames of at least 1, and no -ensemble, -diff, -viewport, -origin, -center, -history, -inspect, -paint or -accessible")
+		return exitConfigError
+	}
+
+	var configs [2]Config
+	for i, path := range []string{*pathA, *pathB} {
+		params, err := readParamFile(path)
+		if err == nil {
+			configs[i], err = cf.with(params)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid parameters:\n%s: %v\n", path, err)
+			return exitConfigError
+		}
+		configs[i].Seed = cfg.Seed
+	}
+
+	var draw func(a, b *Frame)
+	if !*quiet {
+		split := NewSplitRenderer(configs, os.Stdout)
+		draw = split.Render
+	}
+	var strip *abStrip
+	if *stripPath != "" {
+		strip = newABStrip(max(configs[0].Chronons, configs[1].Chronons), *frames)
+		draw = strip.wrap(draw)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	seriesA, seriesB, err := runComparison(ctx, configs[0], configs[1], draw)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if strip != nil {
+		if err := strip.WritePNG(*stripPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	}
+	if err := writeComparison(os.Stdout, seriesA, seriesB); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if ctx.Err() != nil {
+		return exitInterrupted
+	}
+	return exitOK
+}
+
+/**
+ * @brief Reads a parameter file: a flat YAML mapping of parameter names to values.
+ * @details Blank lines, "#" comments and "---" are skipped and values may be quoted. The
+ * seed is shared by both runs and cannot be set here.
+ * @param path The file.
+ * @return The parameters by name, or an error naming the offending line.
+ */
+func readParamFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseParams(f)
+}
+
+/**
+ * @brief Parses the contents of a parameter file; see readParamFile.
+ */
+func parseParams(r io.Reader) (map[string]string, error) {
+	params := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		if trimmed := strings.TrimSpace(line); trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, fmt.Errorf("line %d: nested values are not supported", n)
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want \"name: value\", got %q", n, line)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		switch {
+		case strings.EqualFold(name, "seed"):
+			return nil, fmt.Errorf("line %d: both runs use the same seed; set it with -seed", n)
+		case params[name] != "":
+			return nil, fmt.Errorf("line %d: %s is set twice", n, name)
+		}
+		params[name] = value
+	}
+	return params, scanner.Err()
+}
+
+/**
+ * @brief Runs two simulations in lockstep and records their populations.
+ * @details Each run stops at its own -chronons; the longer one carries on alone, with draw
+ * still receiving the shorter one's final frame.
+ * @param ctx Context of the comparison; cancelling it stops both runs between chronons.
+ * @param a, b The configurations, normally with the same Seed.
+ * @param draw Called with both frames before every chronon and with the final frames; may be nil.
+ * @return The population series of both runs, or an error if a simulation could not be created.
+ */
+func runComparison(ctx context.Context, a, b Config, draw func(a, b *Frame)) (*PopulationSeries, *PopulationSeries, error) {
+	var sims [2]*Simulation
+	var series [2]*PopulationSeries
+	for i, cfg := range []Config{a, b} {
+		sim, err := NewSimulation(cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("run %c: %w", 'A'+i, err)
+		}
+		s := &PopulationSeries{}
+		s.Add(sim.Population())
+		sim.OnStats(func(p Population, _ StepReport) { s.Add(p) })
+		sims[i], series[i] = sim, s
+	}
+
+	pace := newPacer(a.Tick)
+	for n := 0; n < max(a.Chronons, b.Chronons); n++ {
+		if draw != nil {
+			draw(sims[0].Snapshot(), sims[1].Snapshot())
+		}
+		if pace.wait(ctx) != nil || ctx.Err() != nil {
+			break
+		}
+		for _, sim := range sims {
+			if n < sim.Config().Chronons {
+				sim.Step(ctx)
+			}
+		}
+	}
+	if draw != nil && ctx.Err() == nil {
+		draw(sims[0].Snapshot(), sims[1].Snapshot())
+	}
+	return series[0], series[1], nil
+}
+
+/**
+ * @brief Writes the comparison of two population trajectories as a CSV table.
+ * @details One row per species. Differences are B minus A over the chronons both runs
+ * reached; diverged_at is the first chronon at which the counts differ, empty if never.
+ * @param w Destination writer.
+ * @param a, b The series of runs A and B.
+ * @return Any write error.
+ */
+func writeComparison(w io.Writer, a, b *PopulationSeries) error {
+	out := csv.NewWriter(w)
+	out.Write(abColumns)
+	for _, species := range []struct {
+		name string
+		a, b []float64
+	}{{"fish", a.Fish, b.Fish}, {"sharks", a.Sharks, b.Sharks}} {
+		n := min(len(species.a), len(species.b))
+		var sumA, sumB, sumDiff, sumSq, maxAbs float64
+		maxAt, diverged := a.Start, ""
+		for i := 0; i < n; i++ {
+			d := species.b[i] - species.a[i]
+			sumA, sumB, sumDiff, sumSq = sumA+species.a[i], sumB+species.b[i], sumDiff+d, sumSq+d*d
+			if math.Abs(d) > maxAbs {
+				maxAbs, maxAt = math.Abs(d), a.Start+i
+			}
+			if d != 0 && diverged == "" {
+				diverged = strconv.Itoa(a.Start + i)
+			}
+		}
+		nf := float64(max(n, 1))
+		out.Write([]string{species.name, formatFloat(species.a[len(species.a)-1]), formatFloat(species.b[len(species.b)-1]),
+			formatFloat(sumA / nf), formatFloat(sumB / nf), formatFloat(sumDiff / nf), formatFloat(math.Sqrt(sumSq / nf)),
+			formatFloat(maxAbs), strconv.Itoa(maxAt), diverged})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+/**
+ * @struct SplitRenderer
+ * @brief Draws two frames next to each other with the renderer each configuration selects.
+ * @details The block renderers downsample to half the terminal width so both panes fit.
+ */
+type SplitRenderer struct {
+	W     io.Writer
+	views [2]Renderer     ///< Renderers writing into bufs
+	bufs  [2]bytes.Buffer ///< Output of the last Render
+}
+
+/**
+ * @brief Creates the renderer.
+ * @param configs The configurations of runs A and B.
+ * @param w Destination writer.
+ */
+func NewSplitRenderer(configs [2]Config, w io.Writer) *SplitRenderer {
+	r := &SplitRenderer{W: w}
+	half := (terminalWidth() - 3) / 2 ///< Each pane gets half the terminal, minus the gap
+	for i := range r.views {
+		r.views[i] = newRenderer(configs[i], &r.bufs[i])
+		switch v := r.views[i].(type) {
+		case *HalfBlockRenderer:
+			v.Width = half
+		case *BrailleRenderer:
+			v.Width = half
+		}
+	}
+	return r
+}
+
+/**
+ * @brief Draws run A on the left and run B on the right.
+ */
+func (r *SplitRenderer) Render(a, b *Frame) {
+	var panes [2][]string
+	for i, f := range []*Frame{a, b} {
+		r.bufs[i].Reset()
+		r.views[i].Render(f)
+		panes[i] = strings.Split(strings.TrimSuffix(r.bufs[i].String(), "\n"), "\n")
+	}
+	width := len("A")
+	for _, line := range panes[0] {
+		width = max(width, visibleWidth(line))
+	}
+	var out strings.Builder
+	out.WriteString("A" + strings.Repeat(" ", width+3) + "B\n")
+	for i := 0; i < max(len(panes[0]), len(panes[1])); i++ {
+		left, right := "", ""
+		if i < len(panes[0]) {
+			left = panes[0][i]
+		}
+		if i < len(panes[1]) {
+			right = panes[1][i]
+		}
+		out.WriteString(left + strings.Repeat(" ", width-visibleWidth(left)+3) + right + "\n")
+	}
+	io.WriteString(r.W, out.String())
+}
+
+/**
+ * @struct abStrip
+ * @brief Evenly spaced frames of both runs, for the -png strips.
+ */
+type abStrip struct {
+	at     []int       ///< Chronons to keep, ascending
+	frames [2][]*Frame ///< Kept frames of runs A and B
+}
+
+/**
+ * @brief Plans a strip of count frames over chronons 0..chronons.
+ */
+func newABStrip(chronons, count int) *abStrip {
+	s := &abStrip{}
+	for i := 0; i < count; i++ {
+		c := 0
+		if count > 1 {
+			c = i * chronons / (count - 1)
+		}
+		if len(s.at) == 0 || s.at[len(s.at)-1] != c {
+			s.at = append(s.at, c)
+		}
+	}
+	return s
+}
+
+/**
+ * @brief Returns a draw function that keeps the planned frames and then calls next (may be nil).
+ */
+func (s *abStrip) wrap(next func(a, b *Frame)) func(a, b *Frame) {
+	return func(a, b *Frame) {
+		for i, f := range []*Frame{a, b} {
+			if k := len(s.frames[i]); k < len(s.at) && f.Chronon() >= s.at[k] {
+				s.frames[i] = append(s.frames[i], f)
+			}
+		}
+		if next != nil {
+			next(a, b)
+		}
+	}
+}
+
+/**
+ * @brief Writes run A's frames in a row above run B's, in the colours of the block renderers.
+ * @details Small grids are scaled up so that each frame is at least roughly 128 pixels wide;
+ * frames are separated by a 4-pixel gap.
+ * @param path Destination file path.
+ * @return An error if the file could not be created or encoded.
+ */
+func (s *abStrip) WritePNG(path string) error {
+	size := 0
+	for _, row := range s.frames {
+		for _, f := range row {
+			size = max(size, f.Size())
+		}
+	}
+	if size == 0 {
+		return fmt.Errorf("no frames to write to %s", path)
+	}
+	const gap = 4
+	scale := max(1, 128/size)
+	cell := size*scale + gap
+	img := image.NewRGBA(image.Rect(0, 0, len(s.at)*cell-gap, 2*cell-gap))
+	for r, row := range s.frames {
+		for c, f := range row {
+			paintFrame(img, f, c*cell, r*cell, scale)
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/**
+ * @brief Draws a frame into an image in the colours of the block renderers.
+ * @details Rows of the grid map to image rows, as in the heatmaps.
+ * @param img Destination image.
+ * @param f The frame.
+ * @param left, top Image position of the frame's first cell.
+ * @param scale Pixels per cell, per side.
+ */
+func paintFrame(img *image.RGBA, f *Frame, left, top, scale int) {
+	palette := map[Species][3]int{SpeciesNone: waterRGB, SpeciesFish: fishRGB, SpeciesShark: sharkRGB}
+	for x := 0; x < f.Size()*scale; x++ {
+		for y := 0; y < f.Size()*scale; y++ {
+			rgb := palette[f.At(x/scale, y/scale)]
+			if tag := f.Lineage(x/scale, y/scale); tag > 0 {
+				rgb = lineageColour(tag)
+			} else if f.Juvenile(x/scale, y/scale) {
+				rgb = juvenileRGB
+			} else if f.At(x/scale, y/scale) == SpeciesNone {
+				rgb = waterColour(f, x/scale, y/scale)
+				if f.Tide(x/scale, y/scale) {
+					rgb = tideRGB
+				}
+			}
+			img.SetRGBA(left+y, top+x, color.RGBA{uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2]), 255})
+		}
+	}
+}
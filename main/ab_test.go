@@ -0,0 +1,110 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file ab_test.go
+ * @brief Tests for the A/B form of the compare subcommand.
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseParams(t *testing.T) {
+	params, err := parseParams(strings.NewReader("---\n# Slower sharks\nSharkBreed: 6 # was 3\nshark-vision: \"2\"\n\nruleset: 'stochastic'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 3 || params["SharkBreed"] != "6" || params["shark-vision"] != "2" || params["ruleset"] != "stochastic" {
+		t.Errorf("parsed %v", params)
+	}
+	for _, bad := range []string{"seed: 4\n", "SharkBreed 6\n", "a: 1\na: 2\n", "zones:\n  north: 1\n"} {
+		if _, err := parseParams(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestABRequested(t *testing.T) {
+	if abRequested([]string{"old.txt", "new.txt"}) || !abRequested([]string{"--config-a=a.yaml", "-config-b", "b.yaml"}) {
+		t.Error("abRequested should only match -config-a and -config-b")
+	}
+}
+
+func TestRunComparison(t *testing.T) {
+	a := testConfig()
+	a.Engine, a.Chronons = "sections", 20 ///< Deterministic for a given thread count, unlike moves
+	b := a
+	b.SharkBreed = 6
+
+	var draws int
+	strip := newABStrip(a.Chronons, 3)
+	same, twin, err := runComparison(context.Background(), a, a, strip.wrap(func(fa, fb *Frame) { draws++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if draws != a.Chronons+1 || len(strip.frames[0]) != 3 || strip.frames[1][2].Chronon() != a.Chronons {
+		t.Errorf("drew %d times and kept %d frames, want %d and 3", draws, len(strip.frames[0]), a.Chronons+1)
+	}
+	path := filepath.Join(t.TempDir(), "strip.png")
+	if err := strip.WritePNG(path); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := os.Open(path)
+	img, err := png.Decode(f)
+	f.Close()
+	if err != nil || img.Bounds().Dx() != 3*(20*6+4)-4 || img.Bounds().Dy() != 2*(20*6+4)-4 {
+		t.Errorf("strip image %v, err %v", img.Bounds(), err)
+	}
+
+	var out strings.Builder
+	writeComparison(&out, same, twin)
+	rows, _ := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if len(rows) != 3 || rows[1][len(abColumns)-1] != "" || rows[1][6] != formatFloat(0) {
+		t.Errorf("identical runs reported as different:\n%s", out.String())
+	}
+
+	base, slow, err := runComparison(context.Background(), a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	writeComparison(&out, base, slow)
+	rows, _ = csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if rows[2][0] != "sharks" || rows[2][len(abColumns)-1] == "" {
+		t.Errorf("a slower shark breeding time should change the shark trajectory:\n%s", out.String())
+	}
+}
+
+func TestSplitRendererAlignsPanes(t *testing.T) {
+	cfg := testConfig()
+	cfg.Theme = "ascii"
+	var out strings.Builder
+	sim, _ := NewSimulation(cfg)
+	NewSplitRenderer([2]Config{cfg, cfg}, &out).Render(sim.Snapshot(), sim.Snapshot())
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2+cfg.GridSize+2 || !strings.HasPrefix(lines[1], "Step 0:") {
+		t.Fatalf("got %d lines:\n%s", len(lines), out.String())
+	}
+	col := (len(lines[3])-3)/2 + 3 ///< A grid row is the widest line of each pane
+	for _, line := range lines[1:] {
+		if len(line) <= col || strings.TrimRight(line[:col], " ") != line[col:] {
+			t.Errorf("panes differ or are misaligned: %q", line)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file accessible.go
+ * @brief Textual summaries instead of grids (the -accessible option).
+ * @details With -accessible every chronon is described in one line of plain words, without
+ * grids, symbols or escape sequences, so a screen reader can read it aloud and a log keeps it
+ * greppable:
+ *
+ *     Chronon 12: 340 fish, up 5; 45 sharks, down 2. Most fish in nw (14.2%), most sharks in se (3.1%). Fish peaked at 352 in chronon 11.
+ *
+ * The regions are the -zones, or the four quadrants without them. Notable events are a species
+ * dying out or returning, sharks coming to outnumber fish or falling behind again, a red tide
+ * appearing or clearing, a resize, and the turning points of the population cycles: a peak is
+ * reported once a population has fallen a tenth below it, and a trough once it has risen a
+ * tenth above it, so small fluctuations are not mistaken for cycles.
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+/**
+ * @struct turningPoints
+ * @brief Follows one population to report its peaks and troughs.
+ */
+type turningPoints struct {
+	falling bool ///< Whether the population is heading for a trough rather than a peak
+	extreme int  ///< Highest (or lowest, when falling) count since the last turning point
+	at      int  ///< Chronon of extreme
+}
+
+/**
+ * @brief Adds a population count.
+ * @return A sentence reporting the turning point this count confirms, or empty.
+ */
+func (t *turningPoints) record(name string, n, chronon int) string {
+	margin := max(t.extreme/10, 1)
+	switch {
+	case !t.falling && n >= t.extreme, t.falling && n <= t.extreme:
+		t.extreme, t.at = n, chronon
+	case !t.falling && n <= t.extreme-margin:
+		peak, at := t.extreme, t.at
+		*t = turningPoints{falling: true, extreme: n, at: chronon}
+		return fmt.Sprintf("%s peaked at %d in chronon %d.", name, peak, at)
+	case t.falling && n >= t.extreme+margin:
+		trough, at := t.extreme, t.at
+		*t = turningPoints{extreme: n, at: chronon}
+		if trough == 0 {
+			return "" ///< Reported as a return instead
+		}
+		return fmt.Sprintf("%s bottomed out at %d in chronon %d.", name, trough, at)
+	}
+	return ""
+}
+
+/**
+ * @struct AccessibleRenderer
+ * @brief Describes each frame in a line of text.
+ * @details Keeps the previous frame's counts to report changes; a frame that is not later
+ * than the previous one, such as a rewound one, starts the description afresh.
+ */
+type AccessibleRenderer struct {
+	W     io.Writer ///< Destination, usually os.Stdout
+	Zones []Zone    ///< Regions whose densities are reported; nil reports none
+
+	started               bool
+	chronon, size         int
+	fish, sharks, tide    int
+	fishTurns, sharkTurns turningPoints
+}
+
+/**
+ * @brief Writes the summary line of a frame.
+ */
+func (r *AccessibleRenderer) Render(f *Frame) {
+	fish, sharks := f.Counts()
+	var b strings.Builder
+	if !r.started || f.Chronon() <= r.chronon {
+		fmt.Fprintf(&b, "Chronon %d: %d fish, %d sharks, in a %dx%d world.", f.Chronon(), fish, sharks, f.Size(), f.Size())
+		r.fishTurns = turningPoints{extreme: fish, at: f.Chronon()}
+		r.sharkTurns = turningPoints{extreme: sharks, at: f.Chronon()}
+	} else {
+		fmt.Fprintf(&b, "Chronon %d: %d fish, %s; %d sharks, %s.", f.Chronon(), fish, change(fish-r.fish), sharks, change(sharks-r.sharks))
+	}
+	b.WriteString(r.densest(f))
+	if r.started && f.Chronon() > r.chronon {
+		for _, event := range r.events(f, fish, sharks) {
+			b.WriteString(" " + event)
+		}
+	}
+	fmt.Fprintln(r.W, b.String())
+	r.started, r.chronon, r.size = true, f.Chronon(), f.Size()
+	r.fish, r.sharks, r.tide = fish, sharks, f.TideCells()
+}
+
+/**
+ * @brief Describes a change in a population count.
+ */
+func change(d int) string {
+	switch {
+	case d > 0:
+		return fmt.Sprintf("up %d", d)
+	case d < 0:
+		return fmt.Sprintf("down %d", -d)
+	}
+	return "unchanged"
+}
+
+/**
+ * @brief Names the region where each species is densest, as a sentence starting with a space.
+ * @return The sentence, or empty without regions or animals.
+ */
+func (r *AccessibleRenderer) densest(f *Frame) string {
+	var parts []string
+	for _, sp := range []struct {
+		name  string
+		count func(fish, sharks int) int
+	}{
+		{"fish", func(fish, _ int) int { return fish }},
+		{"sharks", func(_, sharks int) int { return sharks }},
+	} {
+		best, density := "", 0.0
+		for _, z := range r.Zones {
+			cells := (min(z.X2, f.Size()-1) - z.X1 + 1) * (min(z.Y2, f.Size()-1) - z.Y1 + 1)
+			if cells <= 0 {
+				continue ///< Cut off by a resize
+			}
+			if d := float64(sp.count(z.Count(f))) / float64(cells); d > density {
+				best, density = z.Name, d
+			}
+		}
+		if best != "" {
+			parts = append(parts, fmt.Sprintf("most %s in %s (%.1f%%)", sp.name, best, 100*density))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	s := strings.Join(parts, ", ")
+	return " " + strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+/**
+ * @brief Returns sentences describing what changed notably since the previous frame.
+ */
+func (r *AccessibleRenderer) events(f *Frame, fish, sharks int) []string {
+	var events []string
+	if f.Size() != r.size {
+		events = append(events, fmt.Sprintf("The world was resized to %dx%d.", f.Size(), f.Size()))
+	}
+	for _, p := range []struct {
+		name      string
+		now, then int
+	}{{"Fish", fish, r.fish}, {"Sharks", sharks, r.sharks}} {
+		switch {
+		case p.now == 0 && p.then > 0:
+			events = append(events, p.name+" died out.")
+		case p.now > 0 && p.then == 0:
+			events = append(events, p.name+" returned.")
+		}
+	}
+	switch {
+	case sharks > fish && r.sharks <= r.fish:
+		events = append(events, "Sharks now outnumber fish.")
+	case sharks < fish && r.sharks > r.fish:
+		events = append(events, "Fish outnumber sharks again.")
+	}
+	switch tide := f.TideCells(); {
+	case tide > 0 && r.tide == 0:
+		events = append(events, fmt.Sprintf("A red tide appeared over %d cells.", tide))
+	case tide == 0 && r.tide > 0:
+		events = append(events, "The red tide cleared.")
+	}
+	for _, s := range []string{r.fishTurns.record("Fish", fish, f.Chronon()), r.sharkTurns.record("Sharks", sharks, f.Chronon())} {
+		if s != "" {
+			events = append(events, s)
+		}
+	}
+	return events
+}
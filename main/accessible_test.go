@@ -0,0 +1,69 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file accessible_test.go
+ * @brief Tests for the -accessible summaries.
+ */
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAccessibleRenderer(t *testing.T) {
+	var out strings.Builder
+	zones, err := parseZones("quadrants", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &AccessibleRenderer{W: &out, Zones: zones}
+	maps := []string{
+		"FF..\nF...\n....\n...S\n",
+		"FFF.\nF...\n....\n..SS\n",
+		"F...\n....\n....\n.SSS\n",
+		"....\n....\n....\n.SS.\n",
+	}
+	for i, m := range maps {
+		f := frameFromASCII(t, m, 3)
+		f.chronon = i
+		r.Render(f)
+	}
+	want := []string{
+		"Chronon 0: 3 fish, 1 sharks, in a 4x4 world. Most fish in nw (75.0%), most sharks in se (25.0%).",
+		"Chronon 1: 4 fish, up 1; 2 sharks, up 1. Most fish in nw (75.0%), most sharks in se (50.0%).",
+		"Chronon 2: 1 fish, down 3; 3 sharks, up 1. Most fish in nw (25.0%), most sharks in se (50.0%). Sharks now outnumber fish. Fish peaked at 4 in chronon 1.",
+		"Chronon 3: 0 fish, down 1; 2 sharks, down 1. Most sharks in sw (25.0%). Fish died out. Sharks peaked at 3 in chronon 2.",
+	}
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(got), len(want), out.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d:\n got %s\nwant %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAccessibleRestartsOnRewind(t *testing.T) {
+	var out strings.Builder
+	r := &AccessibleRenderer{W: &out}
+	for _, c := range []int{4, 5, 2} {
+		f := frameFromASCII(t, "F.\n.S\n", 3)
+		f.chronon = c
+		r.Render(f)
+	}
+	if got := strings.Split(out.String(), "\n")[2]; got != "Chronon 2: 1 fish, 1 sharks, in a 2x2 world." {
+		t.Errorf("rewound frame described as %q", got)
+	}
+}
@@ -0,0 +1,147 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file affinity.go
+ * @brief Pinned worker threads (the -pin-workers option).
+ * @details Normally every phase starts fresh goroutines and the Go scheduler runs them on
+ * whichever OS thread and CPU is free, so a worker's rows are often cold in the cache of the
+ * CPU that picks them up. With pinning, worker i is a long-lived goroutine locked to its own
+ * OS thread, and that thread is bound to one CPU. Since partitionRows always hands worker i
+ * the same contiguous band of rows, the band stays in that CPU's cache from one phase and
+ * chronon to the next. CPUs are ordered by socket, and workers are spread evenly over them in
+ * order, so neighbouring bands, which read each other's edge rows, share a socket.
+ */
+package main
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+/**
+ * @struct workerPool
+ * @brief Long-lived worker goroutines, each locked to an OS thread bound to one CPU.
+ */
+type workerPool struct {
+	mu     sync.Mutex
+	cpus   []int           ///< CPUs this process may run on, grouped by socket
+	queues []chan poolTask ///< One queue per worker; created on first use
+	warn   sync.Once       ///< Pinning failures are logged once
+}
+
+/**
+ * @struct poolTask
+ * @brief A unit of work for one pinned worker.
+ */
+type poolTask struct {
+	cpu int    ///< CPU the worker should be bound to
+	fn  func() ///< The work
+}
+
+/**
+ * @brief Creates an empty pool; workers start when first used.
+ * @details The pool's threads end when Close is called, or when the owning simulation is
+ * garbage collected.
+ */
+func newWorkerPool() *workerPool {
+	cpus := allowedCPUs()
+	slices.SortStableFunc(cpus, func(a, b int) int { return cpuPackage(a) - cpuPackage(b) })
+	return &workerPool{cpus: cpus}
+}
+
+/**
+ * @brief Runs fn on the given worker's pinned thread.
+ * @details The worker count may change between chronons (see -auto-threads); workers are
+ * then re-bound so that they stay evenly spread over the CPUs.
+ * @param worker Index of the worker, which owns the same rows in every phase.
+ * @param workers Number of workers in this chronon.
+ * @param fn The work; it must signal its own completion.
+ */
+func (p *workerPool) Go(worker, workers int, fn func()) {
+	p.mu.Lock()
+	for len(p.queues) <= worker {
+		q := make(chan poolTask)
+		p.queues = append(p.queues, q)
+		go p.serve(q)
+	}
+	q := p.queues[worker]
+	p.mu.Unlock()
+
+	cpu := p.cpus[worker%len(p.cpus)]
+	if workers <= len(p.cpus) {
+		cpu = p.cpus[worker*len(p.cpus)/workers]
+	}
+	q <- poolTask{cpu: cpu, fn: fn}
+}
+
+/**
+ * @brief The body of one pinned worker.
+ * @details The goroutine never unlocks its thread, so the Go runtime discards the thread
+ * when the worker ends rather than reusing a CPU-bound thread for unrelated goroutines.
+ * @param q The worker's queue.
+ */
+func (p *workerPool) serve(q <-chan poolTask) {
+	runtime.LockOSThread()
+	bound := -1
+	for task := range q {
+		if task.cpu != bound {
+			if err := pinThread(task.cpu); err != nil {
+				p.warn.Do(func() {
+					slog.Warn("cannot pin worker threads; workers keep their own threads but may move between CPUs", "err", err)
+				})
+			}
+			bound = task.cpu
+		}
+		task.fn()
+	}
+}
+
+/**
+ * @brief Stops every worker. The pool must not be used afterwards.
+ */
+func (p *workerPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, q := range p.queues {
+		close(q)
+	}
+	p.queues = nil
+}
+
+/** Context key of the pinned worker pool. */
+type poolKey struct{}
+
+/**
+ * @brief Returns a context whose engine workers run on the pool.
+ */
+func withWorkerPool(ctx context.Context, p *workerPool) context.Context {
+	return context.WithValue(ctx, poolKey{}, p)
+}
+
+/**
+ * @brief Starts an engine worker: on its pinned thread if the context carries a pool,
+ * otherwise as a new goroutine.
+ * @param ctx Context of the chronon.
+ * @param worker Index of the worker.
+ * @param workers Number of workers in this chronon.
+ * @param fn The work; it must signal its own completion.
+ */
+func spawn(ctx context.Context, worker, workers int, fn func()) {
+	if p, _ := ctx.Value(poolKey{}).(*workerPool); p != nil {
+		p.Go(worker, workers, fn)
+		return
+	}
+	go fn()
+}
@@ -0,0 +1,86 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file affinity_linux.go
+ * @brief CPU affinity through the sched_setaffinity system call.
+ */
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+/** A CPU set large enough for 1024 CPUs, as in glibc's cpu_set_t. */
+type cpuMask [16]uint64
+
+/**
+ * @brief Binds the calling OS thread to one CPU.
+ * @param cpu The CPU number.
+ * @return The system call's error, if any.
+ */
+func pinThread(cpu int) error {
+	var mask cpuMask
+	if cpu < 0 || cpu >= len(mask)*64 {
+		return fmt.Errorf("CPU %d is out of range", cpu)
+	}
+	mask[cpu/64] |= 1 << (cpu % 64)
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+/**
+ * @brief Returns the CPUs the process may run on, in ascending order.
+ * @details Honours restrictions such as taskset or a container's cpuset; falls back to
+ * every CPU the runtime reports if the set cannot be read.
+ */
+func allowedCPUs() []int {
+	var mask cpuMask
+	var cpus []int
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno == 0 {
+		for cpu := 0; cpu < len(mask)*64; cpu++ {
+			if mask[cpu/64]&(1<<(cpu%64)) != 0 {
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	if len(cpus) == 0 {
+		for cpu := 0; cpu < runtime.NumCPU(); cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+/**
+ * @brief Returns the socket (physical package) of a CPU, or 0 if it is unknown.
+ */
+func cpuPackage(cpu int) int {
+	b, err := os.ReadFile(fmt.Sprintf("/sys/devices/system/cpu/cpu%d/topology/physical_package_id", cpu))
+	if err != nil {
+		return 0
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,50 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build !linux
+
+/**
+ * @file affinity_other.go
+ * @brief Stand-in for platforms without a CPU affinity call in the standard library.
+ * @details Pinned workers still keep their own OS threads, but the operating system
+ * decides which CPU those threads run on.
+ */
+package main
+
+import (
+	"errors"
+	"runtime"
+)
+
+/**
+ * @brief Reports that threads cannot be bound to CPUs on this platform.
+ */
+func pinThread(int) error {
+	return errors.New("CPU affinity is not supported on this platform")
+}
+
+/**
+ * @brief Returns every CPU the runtime reports.
+ */
+func allowedCPUs() []int {
+	cpus := make([]int, runtime.NumCPU())
+	for i := range cpus {
+		cpus[i] = i
+	}
+	return cpus
+}
+
+/**
+ * @brief Returns 0; the socket layout is unknown on this platform.
+ */
+func cpuPackage(int) int {
+	return 0
+}
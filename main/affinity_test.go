@@ -0,0 +1,87 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file affinity_test.go
+ * @brief Tests for pinned worker threads.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPinnedRunMatchesUnpinned(t *testing.T) {
+	cfg := testConfig()
+	cfg.Engine, cfg.Threads = "sections", 4 ///< Deterministic for a given thread count, unlike moves
+	plain, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.PinWorkers = true
+	pinned, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pinned.pool.Close()
+
+	for i := 0; i < 20; i++ {
+		plain.Step(context.Background())
+		pinned.Step(context.Background())
+	}
+	if !slices.Equal(plain.Snapshot().cells, pinned.Snapshot().cells) {
+		t.Error("pinning workers changed the simulation's result")
+	}
+}
+
+func TestWorkerPoolRunsEveryTask(t *testing.T) {
+	p := newWorkerPool()
+	defer p.Close()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var ran []int
+	for round := 0; round < 3; round++ {
+		for worker := 0; worker < 5; worker++ {
+			wg.Add(1)
+			p.Go(worker, 5, func() {
+				defer wg.Done()
+				mu.Lock()
+				ran = append(ran, worker)
+				mu.Unlock()
+			})
+		}
+		wg.Wait()
+	}
+	if len(ran) != 15 || len(p.queues) != 5 {
+		t.Errorf("ran %d tasks on %d workers, want 15 on 5", len(ran), len(p.queues))
+	}
+}
+
+func TestBenchTableSpeedup(t *testing.T) {
+	var out bytes.Buffer
+	writeBenchTable(&out, []benchResult{
+		{Engine: "moves", Threads: 2, Chronons: 10, Best: 2 * time.Second},
+		{Engine: "moves", Threads: 2, Pinned: true, Chronons: 10, Best: time.Second},
+	})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(strings.TrimSpace(lines[2]), "2.00x") {
+		t.Errorf("unexpected table:\n%s", out.String())
+	}
+	if strings.Contains(lines[1], "x") {
+		t.Errorf("the unpinned row should have no speed-up:\n%s", lines[1])
+	}
+}
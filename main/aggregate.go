@@ -0,0 +1,473 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file aggregate.go
+ * @brief The aggregate subcommand: sweep tables consolidated into partitioned Parquet.
+ * @details Reads the tables of a sweep directory (the task-<i>.csv and, while tasks are still
+ * running, task-<i>.csv.part files of sweep -emit-jobs, or any sweep output saved as .csv) and
+ * appends their rows to Hive-partitioned Parquet datasets under the output directory:
+ *
+ *     runs/param=<name>/value=<value>/part-<source>-<row>.parquet       sweep rows, one per run
+ *     ensembles/param=<name>/value=<value>/part-<source>-<row>.parquet  sweep -ensemble rows
+ *
+ * The partition columns live in the directory names only, as DuckDB, Spark and pyarrow
+ * expect. Which rows of each source have been written is kept in _aggregate.json, so running
+ * aggregate again (or with -watch) only converts rows that appeared since, and a task whose
+ * .csv.part is renamed to .csv when it finishes is picked up where it left off. Part names
+ * are derived from the source and first row, so a pass interrupted before the state was saved
+ * rewrites the same files instead of duplicating rows.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+/** Name of the file recording which rows have been aggregated, in the output directory. */
+const aggregateStateName = "_aggregate.json"
+
+/**
+ * @struct sweepRunRow
+ * @brief Schema of the runs dataset: one sweep row, without its param and value partition columns.
+ */
+type sweepRunRow struct {
+	Seed              int64    `parquet:"seed"`
+	Chronons          int32    `parquet:"chronons"`
+	Fish              int32    `parquet:"fish"`
+	Sharks            int32    `parquet:"sharks"`
+	Extinct           string   `parquet:"extinct,optional,dict"` ///< "fish", "sharks" or "both"; null if both survived
+	ExtinctionChronon *int32   `parquet:"extinction_chronon,optional"`
+	FishPeriod        *float64 `parquet:"fish_period,optional"` ///< Null where the run did not oscillate
+	FishAmplitude     *float64 `parquet:"fish_amplitude,optional"`
+	SharksPeriod      *float64 `parquet:"sharks_period,optional"`
+	SharksAmplitude   *float64 `parquet:"sharks_amplitude,optional"`
+}
+
+/**
+ * @struct sweepEnsembleRow
+ * @brief Schema of the ensembles dataset: one chronon of one value's ensemble.
+ */
+type sweepEnsembleRow struct {
+	Chronon        int32   `parquet:"chronon"`
+	Members        int32   `parquet:"members"`
+	FishMean       float64 `parquet:"fish_mean"`
+	FishStddev     float64 `parquet:"fish_stddev"`
+	SharksMean     float64 `parquet:"sharks_mean"`
+	SharksStddev   float64 `parquet:"sharks_stddev"`
+	FishCI95Low    float64 `parquet:"fish_ci95_low"`
+	FishCI95High   float64 `parquet:"fish_ci95_high"`
+	SharksCI95Low  float64 `parquet:"sharks_ci95_low"`
+	SharksCI95High float64 `parquet:"sharks_ci95_high"`
+}
+
+/**
+ * @struct aggregateState
+ * @brief Contents of _aggregate.json: the rows of each source already written.
+ */
+type aggregateState struct {
+	Sources map[string]int `json:"sources"` ///< Rows written, by source path without any .part suffix
+}
+
+/**
+ * @brief The aggregate subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdAggregate(args []string) int {
+	fs := flag.NewFlagSet("wator aggregate", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	output := fs.String("o", "", "`directory` of the Parquet datasets (default <dir>/parquet)")
+	watch := fs.Duration("watch", 0, "keep aggregating new rows every `interval` until interrupted")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: wator aggregate [-o dir] [-watch interval] <sweep-dir | table.csv...>\n")
+		fmt.Fprintf(fs.Output(), "Appends the rows of sweep tables to Hive-partitioned Parquet datasets (runs/ and ensembles/), for DuckDB and similar tools.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitConfigError
+	}
+	if fs.NArg() == 0 || *watch < 0 {
+		fs.Usage()
+		return exitConfigError
+	}
+	if *output == "" {
+		base := fs.Arg(0)
+		if info, err := os.Stat(base); err != nil || !info.IsDir() {
+			base = filepath.Dir(base)
+		}
+		*output = filepath.Join(base, "parquet")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for {
+		rows, err := aggregateSweep(fs.Args(), *output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		if rows > 0 || *watch == 0 {
+			fmt.Fprintf(os.Stderr, "aggregated %d new rows into %s\n", rows, *output)
+		}
+		if *watch == 0 {
+			return exitOK
+		}
+		select {
+		case <-ctx.Done():
+			return exitOK
+		case <-time.After(*watch):
+		}
+	}
+}
+
+/**
+ * @brief Lists the tables to aggregate: the given CSV files, and the .csv and .csv.part files of given directories.
+ * @details A directory with a sweep -emit-jobs manifest contributes only its tasks' tables, so
+ * a merged table saved beside them is not counted twice. Of a finished table and a .part
+ * version of it, only the finished one is listed.
+ */
+func aggregateSources(inputs []string) ([]string, error) {
+	var sources []string
+	for _, in := range inputs {
+		info, err := os.Stat(in)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			sources = append(sources, in)
+			continue
+		}
+		var names []string
+		if data, err := os.ReadFile(filepath.Join(in, jobManifestName)); err == nil {
+			var m jobManifest
+			if err := json.Unmarshal(data, &m); err != nil {
+				return nil, fmt.Errorf("%s: %w", filepath.Join(in, jobManifestName), err)
+			}
+			for _, task := range m.Tasks {
+				names = append(names, task.Output, task.Output+".part")
+			}
+		} else {
+			entries, err := os.ReadDir(in)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if !e.IsDir() {
+					names = append(names, e.Name())
+				}
+			}
+		}
+		for _, name := range names {
+			path := filepath.Join(in, name)
+			switch {
+			case strings.HasSuffix(name, ".csv"):
+				if _, err := os.Stat(path); err == nil {
+					sources = append(sources, path)
+				}
+			case strings.HasSuffix(name, ".csv.part"):
+				_, finished := os.Stat(strings.TrimSuffix(path, ".part"))
+				if _, err := os.Stat(path); err == nil && finished != nil {
+					sources = append(sources, path)
+				}
+			}
+		}
+	}
+	return sources, nil
+}
+
+/**
+ * @brief Reads the complete rows of a table that may still be being written.
+ * @details A last line without its newline is left for the next pass. Comment lines
+ * starting with # are skipped.
+ */
+func readCompleteRows(path string) ([][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	table, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return table, nil
+}
+
+/**
+ * @brief Appends the rows of sweep tables not yet aggregated to the Parquet datasets.
+ * @param inputs Sweep directories and CSV files.
+ * @param out Output directory.
+ * @return The number of rows written, or an error; rows written before the error are kept.
+ */
+func aggregateSweep(inputs []string, out string) (int, error) {
+	statePath := filepath.Join(out, aggregateStateName)
+	state := aggregateState{Sources: map[string]int{}}
+	if data, err := os.ReadFile(statePath); err == nil {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return 0, fmt.Errorf("%s: %w", statePath, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	sources, err := aggregateSources(inputs)
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for _, src := range sources {
+		key := strings.TrimSuffix(src, ".part")
+		table, err := readCompleteRows(src)
+		if err != nil {
+			return written, err
+		}
+		if len(table) <= 1+state.Sources[key] {
+			continue
+		}
+		n, err := aggregateTable(table[0], table[1+state.Sources[key]:], out, key, state.Sources[key])
+		if err != nil {
+			return written, fmt.Errorf("%s: %w", src, err)
+		}
+		state.Sources[key] += n
+		written += n
+	}
+	if written == 0 {
+		return 0, nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return written, err
+	}
+	tmp := statePath + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return written, err
+	}
+	return written, os.Rename(tmp, statePath)
+}
+
+/**
+ * @brief Writes rows of one sweep table to its dataset, one part file per partition.
+ * @param header The table's columns, which decide the dataset.
+ * @param rows The rows to write.
+ * @param out Output directory.
+ * @param source The table the rows come from, for the part names.
+ * @param first Index of the first row in the table, for the part names.
+ * @return The number of rows written, or an error for a table that is no sweep output.
+ */
+func aggregateTable(header []string, rows [][]string, out, source string, first int) (int, error) {
+	col := func(name string) int { return slices.Index(header, name) }
+	param, value := col("param"), col("value")
+	var dataset string
+	switch {
+	case param < 0 || value < 0:
+		return 0, errors.New("not a sweep table: no param and value columns")
+	case col("seed") >= 0:
+		dataset = "runs"
+	case col("members") >= 0:
+		dataset = "ensembles"
+	default:
+		return 0, errors.New("neither a sweep nor a sweep -ensemble table")
+	}
+
+	type partition struct{ param, value string }
+	var order []partition
+	parts := map[partition][][]string{}
+	for _, row := range rows {
+		if len(row) != len(header) {
+			return 0, fmt.Errorf("row %d has %d fields, want %d", first+1, len(row), len(header))
+		}
+		p := partition{row[param], row[value]}
+		if _, ok := parts[p]; !ok {
+			order = append(order, p)
+		}
+		parts[p] = append(parts[p], row)
+	}
+
+	name := fmt.Sprintf("part-%s-%06d.parquet", strings.TrimSuffix(filepath.Base(source), ".csv"), first)
+	for _, p := range order {
+		dir := filepath.Join(out, dataset, "param="+url.PathEscape(p.param), "value="+url.PathEscape(p.value))
+		var err error
+		if dataset == "runs" {
+			err = writeParquetPart(dir, name, parts[p], col, parseSweepRunRow)
+		} else {
+			err = writeParquetPart(dir, name, parts[p], col, parseSweepEnsembleRow)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
+
+/**
+ * @brief Converts rows with parse and writes them as one Parquet file, replacing it atomically.
+ */
+func writeParquetPart[T any](dir, name string, rows [][]string, col func(string) int, parse func([]string, func(string) int) (T, error)) error {
+	records := make([]T, len(rows))
+	for i, row := range rows {
+		var err error
+		if records[i], err = parse(row, col); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "."+name+".tmp") ///< Hidden and without the .parquet extension, so readers globbing *.parquet skip it
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := parquet.NewGenericWriter[T](f, parquet.Compression(&zstd.Codec{}))
+	if _, err = w.Write(records); err == nil {
+		err = w.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+/**
+ * @struct fieldParser
+ * @brief Parses named fields of a CSV row, keeping the first error.
+ */
+type fieldParser struct {
+	row []string
+	col func(string) int
+	err error
+}
+
+/**
+ * @brief Returns a field's text; empty if the table has no such column.
+ */
+func (fp *fieldParser) text(name string) string {
+	if i := fp.col(name); i >= 0 {
+		return fp.row[i]
+	}
+	return ""
+}
+
+/**
+ * @brief Parses an integer field; an empty field is nil.
+ */
+func (fp *fieldParser) int(name string) *int64 {
+	s := fp.text(name)
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil && fp.err == nil {
+		fp.err = fmt.Errorf("column %s: %w", name, err)
+	}
+	return &v
+}
+
+/**
+ * @brief Parses a 32-bit integer field; an empty field is nil.
+ */
+func (fp *fieldParser) int32(name string) *int32 {
+	v := fp.int(name)
+	if v == nil {
+		return nil
+	}
+	n := int32(*v)
+	return &n
+}
+
+/**
+ * @brief Parses a floating-point field; an empty field is nil.
+ */
+func (fp *fieldParser) float(name string) *float64 {
+	s := fp.text(name)
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil && fp.err == nil {
+		fp.err = fmt.Errorf("column %s: %w", name, err)
+	}
+	return &v
+}
+
+/**
+ * @brief Returns the pointed-to value, or zero for nil.
+ */
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+/**
+ * @brief Parses a row of a sweep table.
+ */
+func parseSweepRunRow(row []string, col func(string) int) (sweepRunRow, error) {
+	fp := &fieldParser{row: row, col: col}
+	r := sweepRunRow{
+		Seed:              deref(fp.int("seed")),
+		Chronons:          deref(fp.int32("chronons")),
+		Fish:              deref(fp.int32("fish")),
+		Sharks:            deref(fp.int32("sharks")),
+		Extinct:           fp.text("extinct"),
+		ExtinctionChronon: fp.int32("extinction_chronon"),
+		FishPeriod:        fp.float("fish_period"),
+		FishAmplitude:     fp.float("fish_amplitude"),
+		SharksPeriod:      fp.float("sharks_period"),
+		SharksAmplitude:   fp.float("sharks_amplitude"),
+	}
+	return r, fp.err
+}
+
+/**
+ * @brief Parses a row of a sweep -ensemble table.
+ */
+func parseSweepEnsembleRow(row []string, col func(string) int) (sweepEnsembleRow, error) {
+	fp := &fieldParser{row: row, col: col}
+	f := func(name string) float64 { return deref(fp.float(name)) }
+	r := sweepEnsembleRow{
+		Chronon: deref(fp.int32("chronon")), Members: deref(fp.int32("members")),
+		FishMean: f("fish_mean"), FishStddev: f("fish_stddev"), SharksMean: f("sharks_mean"), SharksStddev: f("sharks_stddev"),
+		FishCI95Low: f("fish_ci95_low"), FishCI95High: f("fish_ci95_high"), SharksCI95Low: f("sharks_ci95_low"), SharksCI95High: f("sharks_ci95_high"),
+	}
+	return r, fp.err
+}
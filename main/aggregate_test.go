@@ -0,0 +1,77 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file aggregate_test.go
+ * @brief Tests for aggregating sweep tables into partitioned Parquet.
+ */
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestAggregateAppendsNewRowsOnly(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "parquet")
+	header := "param,value,seed,chronons,fish,sharks,extinct,extinction_chronon,fish_period,fish_amplitude,sharks_period,sharks_amplitude\n"
+	rows := []string{
+		"SharkBreed,2,7,100,0,40,fish,31,,,,\n",
+		"SharkBreed,2,8,100,12,9,,,24.5,10.0,24.0,6.5\n",
+		"SharkBreed,4,7,100,55,0,sharks,0,,,,\n",
+	}
+	part := filepath.Join(dir, "task-0.csv.part")
+	os.WriteFile(part, []byte(header+rows[0]+rows[1][:10]), 0o644) ///< A task still writing its second row
+
+	if n, err := aggregateSweep([]string{dir}, out); err != nil || n != 1 {
+		t.Fatalf("first pass wrote %d rows (%v), want the one complete row", n, err)
+	}
+	os.WriteFile(part, []byte(header+strings.Join(rows, "")), 0o644)
+	os.Rename(part, filepath.Join(dir, "task-0.csv")) ///< The task finishes
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644)
+	if n, err := aggregateSweep([]string{dir}, out); err != nil || n != 2 {
+		t.Fatalf("second pass wrote %d rows (%v), want the 2 new ones", n, err)
+	}
+	if n, err := aggregateSweep([]string{dir}, out); err != nil || n != 0 {
+		t.Fatalf("third pass wrote %d rows (%v), want none", n, err)
+	}
+
+	got := map[string][]sweepRunRow{}
+	for _, value := range []string{"2", "4"} {
+		files, _ := filepath.Glob(filepath.Join(out, "runs", "param=SharkBreed", "value="+value, "*.parquet"))
+		for _, f := range files {
+			records, err := parquet.ReadFile[sweepRunRow](f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[value] = append(got[value], records...)
+		}
+	}
+	if len(got["2"]) != 2 || len(got["4"]) != 1 {
+		t.Fatalf("partitions hold %d and %d rows, want 2 and 1", len(got["2"]), len(got["4"]))
+	}
+	if r := got["4"][0]; r.Extinct != "sharks" || r.ExtinctionChronon == nil || *r.ExtinctionChronon != 0 || r.FishPeriod != nil {
+		t.Errorf("row %+v: want sharks extinct at chronon 0 (not null) and no fish period", r)
+	}
+	if r := got["2"][1]; r.Seed != 8 || r.Extinct != "" || r.ExtinctionChronon != nil || r.SharksAmplitude == nil || *r.SharksAmplitude != 6.5 {
+		t.Errorf("row %+v: want seed 8 surviving with a shark amplitude of 6.5", r)
+	}
+
+	os.WriteFile(filepath.Join(dir, "other.csv"), []byte("a,b\n1,2\n"), 0o644)
+	if _, err := aggregateSweep([]string{dir}, out); err == nil {
+		t.Error("a table that is no sweep output was aggregated")
+	}
+}
@@ -0,0 +1,86 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file ascii_test.go
+ * @brief Tests for ASCII map import and export.
+ */
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestASCIIRoundTrip(t *testing.T) {
+	const m = "F..S\n.FF.\n....\nS..F\n"
+	g, err := ReadASCII(strings.NewReader(m), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fish, sharks := g.CountEntities(); fish != 4 || sharks != 2 {
+		t.Errorf("read %d fish and %d sharks, want 4 and 2", fish, sharks)
+	}
+	if s := g.At(0, 3).(*Shark); s.Energy != 4 {
+		t.Errorf("shark energy %d, want 4", s.Energy)
+	}
+
+	var out strings.Builder
+	if err := g.WriteASCII(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != m {
+		t.Errorf("round trip changed the map:\n%s", out.String())
+	}
+}
+
+func TestASCIIReadsPrintedLayout(t *testing.T) {
+	g, err := ReadASCII(strings.NewReader("# from a bug report\n\nF . S\n. . .\n\nS . F\n"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fish, sharks := g.CountEntities(); g.Size != 3 || fish != 2 || sharks != 2 {
+		t.Errorf("got size %d with %d fish, %d sharks", g.Size, fish, sharks)
+	}
+}
+
+func TestASCIIErrors(t *testing.T) {
+	cases := map[string]string{
+		"":            "empty",
+		"F.\n.":       "line 2 has 1 cells, want 2",
+		"F.\n.X":      `line 2: unexpected 'X' at column 2`,
+		"# only\n\n ": "empty",
+	}
+	for m, want := range cases {
+		if _, err := ReadASCII(strings.NewReader(m), 3); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want one containing %q", m, err, want)
+		}
+	}
+}
+
+func TestSimulationFromGridFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.txt")
+	if err := os.WriteFile(path, []byte("S.F\n...\nF.F\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.GridFile = path
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := sim.Config()
+	if got.GridSize != 3 || got.NumFish != 3 || got.NumShark != 1 {
+		t.Errorf("config not taken from the map: size %d, fish %d, sharks %d", got.GridSize, got.NumFish, got.NumShark)
+	}
+}
@@ -0,0 +1,177 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file auth.go
+ * @brief API keys and client certificates for the serve subcommand.
+ * @details A server reachable from the internet should not take requests from anyone. With
+ * -api-keys, every request must carry one of the keys in the file, as "Authorization: Bearer
+ * <key>" or "X-API-Key: <key>"; with -client-ca, clients must also present a TLS certificate
+ * signed by that authority (mutual TLS). The key, or failing that the certificate's common
+ * name, names the client, replacing any X-Wator-User header it sent, so with -tenants a client
+ * cannot reach another's simulations by claiming its name.
+ */
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+/**
+ * @struct APIKeys
+ * @brief The accepted keys and the client each names.
+ * @details Keys are held as SHA-256 digests and compared in constant time, so neither the
+ * process memory nor response timing gives a key away.
+ */
+type APIKeys struct {
+	digests [][sha256.Size]byte
+	users   []string
+}
+
+/**
+ * @brief Reads API keys, one "<user> <key>" pair per line.
+ * @details Blank lines and lines starting with # are ignored.
+ * @param r The key file.
+ * @return The keys, or an error naming every malformed line.
+ */
+func parseAPIKeys(r io.Reader) (*APIKeys, error) {
+	keys := &APIKeys{}
+	var errs []error
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			errs = append(errs, fmt.Errorf("line %d: want \"<user> <key>\"", line))
+			continue
+		}
+		if len(fields[1]) < 16 {
+			errs = append(errs, fmt.Errorf("line %d: the key of %s is shorter than 16 characters", line, fields[0]))
+			continue
+		}
+		keys.digests = append(keys.digests, sha256.Sum256([]byte(fields[1])))
+		keys.users = append(keys.users, fields[0])
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys.users) == 0 && len(errs) == 0 {
+		errs = append(errs, errors.New("no keys"))
+	}
+	return keys, errors.Join(errs...)
+}
+
+/**
+ * @brief Reads the API key file given to -api-keys.
+ */
+func readAPIKeys(path string) (*APIKeys, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseAPIKeys(f)
+}
+
+/**
+ * @brief Returns the client a key names.
+ * @return The user, or false if the key is not accepted.
+ */
+func (k *APIKeys) lookup(key string) (string, bool) {
+	digest := sha256.Sum256([]byte(key))
+	user, ok := "", false
+	for i, d := range k.digests {
+		if subtle.ConstantTimeCompare(digest[:], d[:]) == 1 { ///< Every key is compared, whichever matches
+			user, ok = k.users[i], true
+		}
+	}
+	return user, ok
+}
+
+/**
+ * @brief Returns the key a request carries, from Authorization: Bearer or X-API-Key.
+ */
+func requestKey(r *http.Request) string {
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(key)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+/**
+ * @brief Wraps a handler so that only authenticated clients reach it.
+ * @param next The handler to protect.
+ * @param keys The accepted keys; nil accepts any request that passed the TLS handshake.
+ * @return The handler, which answers 401 to a missing or unknown key and otherwise sets the
+ * X-Wator-User header to the client's name: the key's user, else the certificate's common name.
+ */
+func requireAuth(next http.Handler, keys *APIKeys) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(tenantHeader) ///< Only authentication names the client
+		if keys != nil {
+			user, ok := keys.lookup(requestKey(r))
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="wator"`)
+				writeError(w, http.StatusUnauthorized, errors.New("missing or unknown API key"))
+				return
+			}
+			r.Header.Set(tenantHeader, user)
+		} else if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			r.Header.Set(tenantHeader, r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+/**
+ * @brief Builds the TLS configuration of the server.
+ * @param clientCA PEM file of the authority client certificates must be signed by; empty
+ * accepts clients without certificates.
+ * @return The configuration, or an error if the file is unreadable or holds no certificate.
+ */
+func serverTLSConfig(clientCA string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clientCA == "" {
+		return cfg, nil
+	}
+	pem, err := os.ReadFile(clientCA)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no PEM certificates", clientCA)
+	}
+	cfg.ClientCAs, cfg.ClientAuth = pool, tls.RequireAndVerifyClientCert
+	return cfg, nil
+}
+
+/**
+ * @brief Reports whether a listening address only accepts connections from this machine.
+ */
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
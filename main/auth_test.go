@@ -0,0 +1,85 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file auth_test.go
+ * @brief Tests for the API keys of the serve subcommand.
+ */
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := parseAPIKeys(strings.NewReader("# users\nann 0123456789abcdef\n\nbob fedcba9876543210\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, ok := keys.lookup("fedcba9876543210"); !ok || user != "bob" {
+		t.Errorf("lookup = %q, %v; want bob", user, ok)
+	}
+	if _, ok := keys.lookup("fedcba987654321"); ok {
+		t.Error("a prefix of a key was accepted")
+	}
+
+	_, err = parseAPIKeys(strings.NewReader("ann\nbob short\n"))
+	for _, want := range []string{`line 1: want "<user> <key>"`, "line 2: the key of bob is shorter than 16 characters"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got error %v, want one containing %q", err, want)
+		}
+	}
+	if _, err := parseAPIKeys(strings.NewReader("# none\n")); err == nil {
+		t.Error("a file without keys was accepted")
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	keys, err := parseAPIKeys(strings.NewReader("ann 0123456789abcdef\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, tenantOf(r))
+	}), keys))
+	defer srv.Close()
+
+	cases := []struct {
+		header, value string
+		wantCode      int
+		wantUser      string
+	}{
+		{wantCode: http.StatusUnauthorized},
+		{header: "Authorization", value: "Bearer wrong-key-0000000", wantCode: http.StatusUnauthorized},
+		{header: "Authorization", value: "Bearer 0123456789abcdef", wantCode: http.StatusOK, wantUser: "ann"},
+		{header: "X-API-Key", value: "0123456789abcdef", wantCode: http.StatusOK, wantUser: "ann"},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		req.Header.Set(tenantHeader, "mallory") ///< Must be ignored
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != c.wantCode || (c.wantUser != "" && string(body) != c.wantUser) {
+			t.Errorf("%s %q: got %d %q, want %d %q", c.header, c.value, resp.StatusCode, body, c.wantCode, c.wantUser)
+		}
+	}
+}
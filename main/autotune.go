@@ -0,0 +1,140 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file autotune.go
+ * @brief Adaptive worker count (the -auto-threads option).
+ * @details The best number of workers depends on the machine, the grid size and the
+ * engine, so instead of guessing, the tuner tries each candidate count for a few chronons
+ * while the simulation runs and keeps the fastest. Populations change from one chronon to
+ * the next, so latency is compared per entity rather than per chronon. Each worker owns a
+ * band of rows, so the worker count also sets the tile (band) size: GridSize/threads rows.
+ * Changing the worker count changes how the random source is split, so runs tuned this
+ * way are not reproducible from their seed.
+ */
+package main
+
+import (
+	"log/slog"
+	"runtime"
+	"slices"
+	"time"
+)
+
+/** Chronons per candidate: the first warms caches and is discarded. */
+const (
+	tuneWarmup  = 1
+	tuneSamples = 4
+)
+
+/**
+ * @struct AutoTuner
+ * @brief Tries worker counts in turn and settles on the fastest.
+ */
+type AutoTuner struct {
+	sim        *Simulation
+	candidates []int           ///< Worker counts to try, in order
+	current    int             ///< Index of the candidate being measured
+	seen       int             ///< Chronons measured for the current candidate
+	total      time.Duration   ///< Summed latency of the current candidate
+	entities   int             ///< Summed entity count over the same chronons
+	scores     map[int]float64 ///< Nanoseconds per entity per chronon, by worker count
+	chosen     int             ///< Winning worker count; 0 while still exploring
+}
+
+/**
+ * @brief Creates a tuner and switches the simulation to the first candidate.
+ * @details Candidates are the powers of two up to twice GOMAXPROCS, plus GOMAXPROCS itself
+ * and the configured count, capped at the number of rows.
+ * @param sim The simulation to tune; attach Record with OnChrononEnd.
+ * @param configured The worker count from the configuration.
+ * @return The tuner.
+ */
+func NewAutoTuner(sim *Simulation, configured int) *AutoTuner {
+	procs := runtime.GOMAXPROCS(0)
+	rows := sim.Config().GridSize
+	candidates := []int{min(procs, rows), min(max(configured, 1), rows)}
+	for n := 1; n <= 2*procs && n <= rows; n *= 2 {
+		candidates = append(candidates, n)
+	}
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+
+	a := &AutoTuner{sim: sim, candidates: candidates, scores: map[int]float64{}}
+	sim.SetThreads(candidates[0])
+	return a
+}
+
+/**
+ * @brief Measures a chronon and moves on to the next candidate when enough are collected.
+ * @details Matches ChrononEndHook.
+ * @param f The frame after the chronon.
+ * @param report The chronon's report; Elapsed is the measured latency.
+ */
+func (a *AutoTuner) Record(f *Frame, report StepReport) {
+	if a.chosen != 0 {
+		return
+	}
+	a.seen++
+	if a.seen <= tuneWarmup {
+		return
+	}
+	fish, sharks := f.Counts()
+	a.total += report.Elapsed
+	a.entities += fish + sharks
+	if a.seen < tuneWarmup+tuneSamples {
+		return
+	}
+
+	threads := a.candidates[a.current]
+	a.scores[threads] = float64(a.total) / float64(max(a.entities, 1))
+	slog.Debug("auto-threads trial", "threads", threads, "ns_per_entity", a.scores[threads])
+	a.current++
+	a.seen, a.total, a.entities = 0, 0, 0
+
+	if a.current < len(a.candidates) {
+		a.sim.SetThreads(a.candidates[a.current])
+		return
+	}
+	a.chosen = a.candidates[0]
+	for _, n := range a.candidates {
+		if a.scores[n] < a.scores[a.chosen] {
+			a.chosen = n
+		}
+	}
+	a.sim.SetThreads(a.chosen)
+	slog.Info("auto-threads chose", "threads", a.chosen)
+}
+
+/**
+ * @brief Returns the chosen worker count, or 0 if the run ended while still exploring.
+ */
+func (a *AutoTuner) Chosen() int {
+	return a.chosen
+}
+
+/**
+ * @brief Logs the measured candidates and the chosen configuration.
+ */
+func (a *AutoTuner) Report() {
+	for _, n := range a.candidates {
+		if score, ok := a.scores[n]; ok {
+			slog.Info("auto-threads candidate", "threads", n, "rows_per_worker", (a.sim.Config().GridSize+n-1)/n,
+				"ns_per_entity", int(score))
+		}
+	}
+	if a.chosen == 0 {
+		slog.Warn("auto-threads did not finish exploring; run more chronons", "needed",
+			len(a.candidates)*(tuneWarmup+tuneSamples), "threads", a.sim.Threads())
+		return
+	}
+	slog.Info("auto-threads result", "threads", a.chosen, "rows_per_worker", (a.sim.Config().GridSize+a.chosen-1)/a.chosen)
+}
@@ -0,0 +1,78 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file autotune_test.go
+ * @brief Tests for the adaptive worker count.
+ */
+package main
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAutoTunerCandidates(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAutoTuner(sim, 3)
+	if !slices.IsSorted(a.candidates) || a.candidates[0] != 1 || !slices.Contains(a.candidates, 3) {
+		t.Errorf("candidates %v should be sorted, start at 1 and include the configured count", a.candidates)
+	}
+	if !slices.Contains(a.candidates, min(runtime.GOMAXPROCS(0), 20)) {
+		t.Errorf("candidates %v should include GOMAXPROCS", a.candidates)
+	}
+	if last := a.candidates[len(a.candidates)-1]; last > 20 {
+		t.Errorf("candidate %d exceeds the number of rows", last)
+	}
+	if sim.Threads() != 1 {
+		t.Errorf("the first candidate should be applied, got %d threads", sim.Threads())
+	}
+}
+
+func TestAutoTunerPicksFastest(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAutoTuner(sim, 2)
+	a.candidates = []int{1, 2, 4}
+	sim.SetThreads(1)
+	latency := map[int]time.Duration{1: 9 * time.Millisecond, 2: 3 * time.Millisecond, 4: 5 * time.Millisecond}
+	f := sim.Snapshot()
+	for a.Chosen() == 0 {
+		a.Record(f, StepReport{Elapsed: latency[sim.Threads()]})
+	}
+	if a.Chosen() != 2 || sim.Threads() != 2 {
+		t.Errorf("chose %d (simulation uses %d), want 2", a.Chosen(), sim.Threads())
+	}
+}
+
+func TestAutoTunerDuringRun(t *testing.T) {
+	cfg := testConfig()
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAutoTuner(sim, cfg.Threads)
+	sim.OnChrononEnd(a.Record)
+	if _, err := sim.Run(context.Background(), len(a.candidates)*(tuneWarmup+tuneSamples)); err != nil {
+		t.Fatal(err)
+	}
+	if a.Chosen() == 0 || sim.Threads() != a.Chosen() {
+		t.Errorf("tuner did not settle: chose %d, simulation uses %d", a.Chosen(), sim.Threads())
+	}
+}
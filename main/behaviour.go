@@ -0,0 +1,368 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file behaviour.go
+ * @brief Movement decisions written in Starlark (the -behaviour option).
+ * @details A behaviour script defines fish(n) and/or shark(n). Each is called once per
+ * entity per chronon with its neighbourhood and returns "north", "south", "west", "east",
+ * "stay", or None to fall back to the built-in movement. A shark that steps onto a fish eats
+ * it; breeding, energy and starvation still follow the configured rules. For example:
+ *
+ *     def shark(n):
+ *         for d in ("north", "south", "west", "east"):
+ *             if getattr(n, d) == "fish":
+ *                 return d
+ *         return None if n.energy > 2 else "stay"   # rest when hungry
+ *
+ * The script is compiled and its top level run once; the resulting functions are frozen and
+ * shared by every worker, and each worker takes its own interpreter thread from a pool, so
+ * calls need no locking. Starlark has no I/O, so a script cannot touch anything but the
+ * value it is given.
+ */
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+/** Direction names a decision may return, in neighbourOffsets order. */
+var behaviourDirections = []string{"north", "south", "west", "east"}
+
+/**
+ * @struct Behaviour
+ * @brief Movement decisions that replace the built-in ones, per species.
+ * @details Filled by a -behaviour script, by -plugin modules (plugin.go), or both.
+ */
+type Behaviour struct {
+	fish    decider ///< Decides for fish; nil keeps the built-in movement
+	shark   decider ///< Decides for sharks; nil keeps the built-in movement
+	failure atomic.Pointer[error]
+}
+
+/**
+ * @interface decider
+ * @brief Decides where the entities of one species step. Must be safe for concurrent use.
+ */
+type decider interface {
+	/**
+	 * @brief Asks where an entity at (x, y) should step.
+	 * @return An index into neighbourOffsets or -1 to stay, false to use the built-in
+	 * movement, and an error if the decision failed.
+	 */
+	decide(g *Grid, rng *rand.Rand, e Entity, x, y int) (int, bool, error)
+}
+
+/**
+ * @brief Installs the decider for a species.
+ * @param sp SpeciesFish or SpeciesShark.
+ * @param d The decider.
+ * @param source What the decider came from, for the error message.
+ * @return An error if the species already has a decider.
+ */
+func (b *Behaviour) set(sp Species, d decider, source string) error {
+	slot := &b.fish
+	if sp == SpeciesShark {
+		slot = &b.shark
+	}
+	if *slot != nil {
+		return fmt.Errorf("%s: %s movement is already decided by another script or plugin", source, speciesWord(sp))
+	}
+	*slot = d
+	return nil
+}
+
+/**
+ * @struct starlarkDecider
+ * @brief Calls one function of a behaviour script.
+ */
+type starlarkDecider struct {
+	path    string
+	fn      starlark.Callable
+	threads *sync.Pool ///< Interpreter threads, one per concurrently deciding worker
+}
+
+/**
+ * @brief Compiles a behaviour script and runs its top level.
+ * @param path The Starlark file.
+ * @return The behaviour, or an error if the file does not compile or defines neither function.
+ */
+func loadBehaviour(path string) (*Behaviour, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening behaviour script: %w", err)
+	}
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{While: true, Recursion: true},
+		&starlark.Thread{Name: "load"}, path, src, nil)
+	if err != nil {
+		return nil, err ///< Starlark errors already start with the file name and position
+	}
+	b := &Behaviour{}
+	threads := &sync.Pool{New: func() any { return &starlark.Thread{Name: "worker"} }}
+	for _, sp := range []Species{SpeciesFish, SpeciesShark} {
+		v, ok := globals[speciesWord(sp)]
+		if !ok {
+			continue
+		}
+		fn, ok := v.(starlark.Callable)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s must be a function, got %s", path, speciesWord(sp), v.Type())
+		}
+		b.set(sp, starlarkDecider{path: path, fn: fn, threads: threads}, path)
+	}
+	if b.fish == nil && b.shark == nil {
+		return nil, fmt.Errorf("%s: defines neither fish(n) nor shark(n)", path)
+	}
+	return b, nil
+}
+
+/**
+ * @brief Returns the first error a decision raised, or nil (also for a nil behaviour).
+ */
+func (b *Behaviour) Err() error {
+	if b == nil {
+		return nil
+	}
+	if err := b.failure.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+/**
+ * @brief Asks the species' decider where an entity at (x, y) should step.
+ * @details A nil behaviour never decides. After the first error no further calls are made
+ * and the built-in movement is used.
+ * @param g The grid being stepped.
+ * @param rng The worker's random source.
+ * @param e The entity deciding.
+ * @param x The entity's x-coordinate.
+ * @param y The entity's y-coordinate.
+ * @return An index into neighbourOffsets or -1 to stay, and false to use the built-in movement.
+ */
+func (b *Behaviour) decide(g *Grid, rng *rand.Rand, e Entity, x, y int) (int, bool) {
+	if b == nil {
+		return 0, false
+	}
+	d := b.fish
+	if _, ok := e.(*Shark); ok {
+		d = b.shark
+	}
+	if d == nil || b.failure.Load() != nil {
+		return 0, false
+	}
+	dir, ok, err := d.decide(g, rng, e, x, y)
+	if err != nil {
+		b.failure.CompareAndSwap(nil, &err)
+		return 0, false
+	}
+	return dir, ok
+}
+
+/**
+ * @brief Calls the script function with the entity's neighbourhood.
+ * @details The neighbourhood exposes the worker's random source as n.rand.
+ */
+func (d starlarkDecider) decide(g *Grid, rng *rand.Rand, e Entity, x, y int) (int, bool, error) {
+	thread := d.threads.Get().(*starlark.Thread)
+	defer d.threads.Put(thread)
+
+	v, err := starlark.Call(thread, d.fn, starlark.Tuple{&neighbourhood{g: g, rng: rng, e: e, x: x, y: y}}, nil)
+	if err == nil {
+		switch s, _ := starlark.AsString(v); {
+		case v == starlark.None:
+			return 0, false, nil
+		case s == "stay":
+			return -1, true, nil
+		case slices.Contains(behaviourDirections, s):
+			return slices.Index(behaviourDirections, s), true, nil
+		default:
+			err = fmt.Errorf("%s returned %s; want a direction, \"stay\" or None", d.fn.Name(), v.String())
+		}
+	}
+	if evalErr := (*starlark.EvalError)(nil); errors.As(err, &evalErr) {
+		err = errors.New(evalErr.Backtrace())
+	}
+	return 0, false, fmt.Errorf("%s: %w", d.path, err)
+}
+
+/**
+ * @brief Chooses where an entity ends up: by the behaviour script if it decides, else by choosePath.
+ * @details Used by the engines that step entities one at a time (sections and claims). A
+ * scripted entity moves at most one cell, whatever its speed and vision.
+ * @param taken Destinations already claimed this chronon.
+ * @param rng The worker's random source.
+ * @param e The entity moving.
+ * @param x The x-coordinate of the entity.
+ * @param y The y-coordinate of the entity.
+ * @param speed Maximum number of cells to move without a script; 0 stays put (a resting shark).
+ * @param vision 0 for fish and sated sharks, which do not eat; for hunting sharks, the path
+ * length within which fish are pursued.
+ * @param rules The rules, carrying the behaviour script if there is one.
+ * @return The destination, or (-1, -1) to stay, and whether a shark ate a fish there.
+ */
+func (g *Grid) decidePath(taken cellClaims, rng *rand.Rand, e Entity, x, y, speed, vision int, rules Rules) (int, int, bool) {
+	if speed == 0 {
+		return -1, -1, false
+	}
+	dir, ok := rules.Behaviour.decide(g, rng, e, x, y)
+	if !ok {
+		return g.choosePath(taken, rng, x, y, speed, vision)
+	}
+	if dir < 0 {
+		return -1, -1, false
+	}
+	nx, ny := g.neighbour(x, y, dir)
+	if taken.claimed(nx, ny) {
+		return -1, -1, false ///< Someone got there first
+	}
+	switch g.At(nx, ny).(type) {
+	case nil:
+		return nx, ny, false
+	case *Fish:
+		if _, ok := e.(*Shark); ok && vision > 0 {
+			return nx, ny, true
+		}
+	}
+	return -1, -1, false ///< Blocked
+}
+
+/**
+ * @brief Narrows a plan to the cell the script chose.
+ * @details Used by the planning engines (moves, deterministic and serial). A chosen fish
+ * becomes the only prey and a chosen empty cell the only candidate; anything else stays put.
+ * @param m The plan to narrow; its candidates are already filled in.
+ * @param dir The script's choice: an index into neighbourOffsets, or -1 to stay.
+ */
+func (g *Grid) scriptedPlan(m *plannedMove, dir int) {
+	var target [2]int
+	if dir >= 0 {
+		target[0], target[1] = g.neighbour(m.x, m.y, dir)
+	}
+	prey, empty := m.nPrey, m.nEmpty
+	m.nPrey, m.nEmpty = 0, 0
+	for i := 0; i < prey && dir >= 0; i++ {
+		if m.prey[i] == target {
+			m.prey[0], m.nPrey = target, 1
+		}
+	}
+	for i := 0; i < empty && dir >= 0; i++ {
+		if m.empty[i] == target {
+			m.empty[0], m.nEmpty = target, 1
+		}
+	}
+}
+
+/**
+ * @struct neighbourhood
+ * @brief The value a decision function receives.
+ * @details Attributes: species, x, y, chronon, breed (breeding counter), energy (0 for fish),
+ * north, south, west and east ("fish", "shark" or "empty"); methods cell(dx, dy), which
+ * names the contents of any cell relative to the entity, and rand(k), a random integer in
+ * [0, k) from the worker's seeded source.
+ */
+type neighbourhood struct {
+	g    *Grid
+	rng  *rand.Rand
+	e    Entity
+	x, y int
+}
+
+/** Attribute names of a neighbourhood, sorted. */
+var neighbourhoodAttrs = []string{"breed", "cell", "chronon", "east", "energy", "north", "rand", "south", "species", "west", "x", "y"}
+
+/** @brief Describes the value when a script prints it. */
+func (n *neighbourhood) String() string { return fmt.Sprintf("<neighbourhood of %d,%d>", n.x, n.y) }
+
+/** @brief Returns the Starlark type name. */
+func (n *neighbourhood) Type() string { return "neighbourhood" }
+
+/** @brief Does nothing: a neighbourhood has no mutable state. */
+func (n *neighbourhood) Freeze() {}
+
+/** @brief Reports that a neighbourhood is truthy. */
+func (n *neighbourhood) Truth() starlark.Bool { return true }
+
+/** @brief Refuses hashing, so a neighbourhood cannot be kept as a dict key. */
+func (n *neighbourhood) Hash() (uint32, error) {
+	return 0, errors.New("unhashable type: neighbourhood")
+}
+
+/** @brief Lists the attributes for dir(). */
+func (n *neighbourhood) AttrNames() []string { return neighbourhoodAttrs }
+
+/**
+ * @brief Returns the named attribute, or nil if there is none.
+ */
+func (n *neighbourhood) Attr(name string) (starlark.Value, error) {
+	if i := slices.Index(behaviourDirections, name); i >= 0 {
+		return n.cell(neighbourOffsets[i][0], neighbourOffsets[i][1]), nil
+	}
+	switch name {
+	case "species":
+		return n.cell(0, 0), nil
+	case "x":
+		return starlark.MakeInt(n.x), nil
+	case "y":
+		return starlark.MakeInt(n.y), nil
+	case "chronon":
+		return starlark.MakeInt(n.g.Chronon), nil
+	case "breed":
+		switch e := n.e.(type) {
+		case *Fish:
+			return starlark.MakeInt(e.BreedCounter), nil
+		case *Shark:
+			return starlark.MakeInt(e.BreedCounter), nil
+		}
+	case "energy":
+		if s, ok := n.e.(*Shark); ok {
+			return starlark.MakeInt(s.Energy), nil
+		}
+		return starlark.MakeInt(0), nil
+	case "cell":
+		return starlark.NewBuiltin("cell", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var dx, dy int
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &dx, &dy); err != nil {
+				return nil, err
+			}
+			return n.cell(dx, dy), nil
+		}), nil
+	case "rand":
+		return starlark.NewBuiltin("rand", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var k int
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &k); err != nil {
+				return nil, err
+			}
+			if k < 1 {
+				return nil, fmt.Errorf("rand: k must be at least 1, got %d", k)
+			}
+			return starlark.MakeInt(n.rng.Intn(k)), nil
+		}), nil
+	}
+	return nil, nil
+}
+
+/**
+ * @brief Names the contents of the cell at an offset from the entity, wrapping around the edges.
+ */
+func (n *neighbourhood) cell(dx, dy int) starlark.String {
+	size := n.g.Size
+	return starlark.String(speciesWord(speciesOf(n.g.At(wrap(n.x+dx, size), wrap(n.y+dy, size)))))
+}
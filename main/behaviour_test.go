@@ -0,0 +1,129 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file behaviour_test.go
+ * @brief Tests for Starlark behaviour scripts.
+ */
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Writes a behaviour script to a temporary file and returns its path.
+ */
+func writeBehaviour(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "behaviour.star")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBehaviourStayKeepsEveryonePut(t *testing.T) {
+	path := writeBehaviour(t, "def fish(n):\n    return \"stay\"\n\ndef shark(n):\n    return \"stay\"\n")
+	for _, engine := range engineNames() {
+		cfg := testConfig()
+		cfg.Behaviour, cfg.Engine, cfg.StarveEnergy = path, engine, 10
+		sim, err := NewSimulation(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := sim.Checkpoint().Entities
+		sim.Step(context.Background())
+		after := sim.Checkpoint().Entities
+		if len(before) != len(after) {
+			t.Fatalf("%s: %d entities became %d", engine, len(before), len(after))
+		}
+		for i := range before {
+			if before[i].X != after[i].X || before[i].Y != after[i].Y {
+				t.Errorf("%s: entity at %d,%d moved to %d,%d", engine, before[i].X, before[i].Y, after[i].X, after[i].Y)
+				break
+			}
+		}
+	}
+}
+
+func TestBehaviourSharksHunt(t *testing.T) {
+	path := writeBehaviour(t, `
+def shark(n):
+    if n.species != "shark" or n.cell(0, 0) != "shark":
+        fail("wrong neighbourhood")
+    for d in ("north", "south", "west", "east"):
+        if getattr(n, d) == "fish":
+            return d
+    return "stay"
+`)
+	cfg := testConfig()
+	cfg.Behaviour, cfg.Engine, cfg.Threads = path, "moves", 4
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var eaten int
+	sim.OnChrononEnd(func(_ *Frame, r StepReport) { eaten += r.FishEaten })
+	sim.Run(context.Background(), 5) ///< Run with -race: workers share the compiled script
+	if err := sim.Rules().Behaviour.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if eaten == 0 {
+		t.Error("hunting sharks ate nothing")
+	}
+}
+
+func TestBehaviourDeterministicMatchesSerial(t *testing.T) {
+	path := writeBehaviour(t, "def fish(n):\n    return (\"north\", \"south\", \"west\", \"east\", None)[n.rand(5)]\n")
+	run := func(engine string, threads int) []checkpointEntity {
+		cfg := testConfig()
+		cfg.Behaviour, cfg.Engine, cfg.Threads = path, engine, threads
+		sim, err := NewSimulation(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sim.Run(context.Background(), 10)
+		return sim.Checkpoint().Entities
+	}
+	if !slices.Equal(run("serial", 1), run("deterministic", 4)) {
+		t.Error("a script using n.rand should keep the deterministic engine identical to serial")
+	}
+}
+
+func TestBehaviourErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		"def fish(n)\n":                    "want ':'",
+		"x = 1\n":                          "neither fish(n) nor shark(n)",
+		"shark = 3\n":                      "must be a function",
+		"def fish(n):\n    return 7\n":     "fish returned 7",
+		"def fish(n):\n    return n.fin\n": "no .fin field",
+	} {
+		cfg := testConfig()
+		cfg.Behaviour = writeBehaviour(t, src)
+		sim, err := NewSimulation(cfg)
+		if err == nil {
+			sim.Run(context.Background(), 3)
+			err = sim.Rules().Behaviour.Err()
+			if sim.Snapshot().Chronon() != 1 {
+				t.Errorf("%q: the run should stop after the chronon that failed, ran %d", src, sim.Snapshot().Chronon())
+			}
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want one mentioning %q", src, err, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file bench.go
+ * @brief The bench subcommand: compares engines and thread counts on the same world.
+ * @details Every combination simulates the same seed without rendering; the best of several
+ * repeats is reported so that one-off scheduling noise does not decide the comparison. With
+ * "-pin off,on" each combination is also timed with pinned workers (see affinity.go), and the
+ * speed-up column shows the measured gain of pinning over the unpinned run. The table is
+ * followed by the strong-scaling summary of scaling.go, which -report markdown or csv prints
+ * on its own as a report.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+/**
+ * @struct benchResult
+ * @brief The best timing of one engine and thread count.
+ */
+type benchResult struct {
+	Engine   string
+	Threads  int
+	Pinned   bool
+	Chronons int
+	Best     time.Duration
+}
+
+/**
+ * @brief The bench subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdBench(args []string) int {
+	cf := newConfigFlags("bench", "Times headless runs of the same world for each engine and thread count.", os.Stderr)
+	engines := cf.fs.String("engines", strings.Join(engineNames(), ","), "comma-separated `engines` to compare")
+	threadList := cf.fs.String("thread-counts", "", "comma-separated thread `counts` to compare (default: Threads)")
+	repeat := cf.fs.Int("repeat", 3, "runs per combination; the fastest is reported")
+	pinModes := cf.fs.String("pin", "", "comma-separated worker pinning `modes` to compare: off, on (default: -pin-workers)")
+	reportFlag := cf.fs.String("report", "table", "output `format`: table (with a scaling summary), markdown or csv (see scaling.go)")
+	cfg, code, ok := setupCommand(cf, args)
+	if !ok {
+		return code
+	}
+	report, err := parseBenchReport(*reportFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid parameters:\n%v\n", err)
+		return exitConfigError
+	}
+
+	threads := []int{cfg.Threads}
+	if *threadList != "" {
+		if threads, err = parseInts(*threadList); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid parameters:\n-thread-counts: %v\n", err)
+			return exitConfigError
+		}
+	}
+
+	pinned := []bool{cfg.PinWorkers}
+	if *pinModes != "" {
+		pinned = nil
+		for _, mode := range strings.Split(*pinModes, ",") {
+			switch strings.TrimSpace(mode) {
+			case "off":
+				pinned = append(pinned, false)
+			case "on":
+				pinned = append(pinned, true)
+			default:
+				fmt.Fprintf(os.Stderr, "Invalid parameters:\n-pin: %q is not off or on\n", mode)
+				return exitConfigError
+			}
+		}
+	}
+
+	var results []benchResult
+	for _, engine := range strings.Split(*engines, ",") {
+		for _, n := range threads {
+			for _, pin := range pinned {
+				c := cfg
+				c.Engine, c.Threads, c.PinWorkers = strings.TrimSpace(engine), n, pin
+				if err := c.Validate(); err != nil {
+					fmt.Fprintf(os.Stderr, "Invalid parameters:\n%v\n", err)
+					return exitConfigError
+				}
+				r, err := benchOne(context.Background(), c, max(*repeat, 1))
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					return exitFailure
+				}
+				results = append(results, r)
+			}
+		}
+	}
+	groups := analyseScaling(results)
+	switch report {
+	case "markdown":
+		writeScalingMarkdown(os.Stdout, cfg, max(*repeat, 1), groups)
+	case "csv":
+		if err := writeScalingCSV(os.Stdout, groups); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	default:
+		writeBenchTable(os.Stdout, results)
+		writeScalingText(os.Stdout, groups)
+	}
+	return exitOK
+}
+
+/**
+ * @brief Times repeated headless runs of one configuration.
+ * @param ctx Context of the runs.
+ * @param cfg The configuration to time.
+ * @param repeat Number of runs.
+ * @return The fastest run, or an error if the simulation could not be created.
+ */
+func benchOne(ctx context.Context, cfg Config, repeat int) (benchResult, error) {
+	r := benchResult{Engine: cfg.Engine, Threads: cfg.Threads, Pinned: cfg.PinWorkers, Chronons: cfg.Chronons}
+	for i := 0; i < repeat; i++ {
+		s, err := runHeadless(ctx, cfg)
+		if err != nil {
+			return r, err
+		}
+		if d := time.Duration(s.WallSeconds * float64(time.Second)); i == 0 || d < r.Best {
+			r.Best = d
+		}
+	}
+	return r, nil
+}
+
+/**
+ * @brief Prints benchmark results as an aligned table.
+ * @details The speed-up of a pinned run is its rate divided by the rate of the unpinned run
+ * with the same engine and thread count; it is left blank when there is nothing to compare.
+ * @param w Destination writer.
+ * @param results The results to print.
+ */
+func writeBenchTable(w io.Writer, results []benchResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "engine\tthreads\tpinned\tchronons\tbest\tchronons/sec\tspeed-up\t")
+	unpinned := map[string]time.Duration{}
+	for _, r := range results {
+		if !r.Pinned {
+			unpinned[fmt.Sprint(r.Engine, r.Threads)] = r.Best
+		}
+	}
+	for _, r := range results {
+		rate := 0.0
+		if r.Best > 0 {
+			rate = float64(r.Chronons) / r.Best.Seconds()
+		}
+		speedup := ""
+		if base, ok := unpinned[fmt.Sprint(r.Engine, r.Threads)]; ok && r.Pinned && r.Best > 0 {
+			speedup = fmt.Sprintf("%.2fx", base.Seconds()/r.Best.Seconds())
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%d\t%v\t%.1f\t%s\t\n", r.Engine, r.Threads, r.Pinned, r.Chronons, r.Best.Round(time.Microsecond), rate, speedup)
+	}
+	tw.Flush()
+}
+
+/**
+ * @brief Parses a comma-separated list of whole numbers.
+ * @param list The list.
+ * @return The numbers, or an error naming the first invalid entry.
+ */
+func parseInts(list string) ([]int, error) {
+	var out []int
+	for _, field := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a whole number", field)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -0,0 +1,334 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file blockrender.go
+ * @brief High-density renderers for grids too large to print one cell per character.
+ * @details Both renderers downsample the frame into pixels, each covering a square block of
+ * cells whose colour mixes water, fish and sharks in proportion to their share of the block.
+ * The half-block renderer packs two pixels per character (upper and lower half, one in the
+ * foreground and one in the background colour); the braille renderer packs a 2x4 dot matrix
+ * per character, lighting a dot when at least half of its block is occupied.
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/** Names accepted by -renderer. */
+var rendererNames = []string{"text", "halfblock", "braille"}
+
+/**
+ * @brief Builds the renderer selected by the configuration.
+ * @param cfg Validated configuration; the ascii theme turns off colours in the block renderers.
+ * @param w Destination writer.
+ * @return The renderer.
+ */
+func newRenderer(cfg Config, w io.Writer) Renderer {
+	if cfg.Accessible {
+		spec := cfg.Zones
+		if spec == "" {
+			spec = "quadrants"
+		}
+		zones, _ := parseZones(spec, cfg.GridSize) ///< Validated; quadrants fail only on a 1x1 grid, which has no regions
+		return &AccessibleRenderer{W: w, Zones: zones}
+	}
+	noColor := cfg.Theme == "ascii"
+	styles, _ := parseStyles(cfg.Style) ///< Validated by parseConfig
+	switch cfg.Renderer {
+	case "halfblock":
+		return &HalfBlockRenderer{W: w, NoColor: noColor, Styles: &styles}
+	case "braille":
+		return &BrailleRenderer{W: w, NoColor: noColor, Styles: &styles}
+	}
+	theme, _ := themeByName(cfg.Theme) ///< Validated by parseConfig
+	theme.Styles = styles
+	if cfg.Diff {
+		return &DiffRenderer{W: w, Theme: theme, MaxEnergy: cfg.StarveEnergy}
+	}
+	return &TextRenderer{W: w, Theme: theme, MaxEnergy: cfg.StarveEnergy}
+}
+
+/** Colours mixed into a pixel, as RGB. */
+var (
+	waterRGB    = [3]int{20, 40, 90}
+	fishRGB     = [3]int{60, 220, 90}
+	juvenileRGB = [3]int{150, 240, 170} ///< Juvenile fish, paler than adults
+	sharkRGB    = [3]int{235, 50, 50}
+	tideRGB     = [3]int{170, 70, 40}  ///< Empty water under a red tide
+	coldRGB     = [3]int{10, 25, 120}  ///< Empty water at temperature 0, with -temperature-overlay
+	warmRGB     = [3]int{40, 130, 120} ///< Empty water at temperature 1
+)
+
+/**
+ * @brief Returns the colour a fraction t of the way from a to b.
+ */
+func mixRGB(a, b [3]int, t float64) [3]int {
+	var c [3]int
+	for i := range c {
+		c[i] = a[i] + int(float64(b[i]-a[i])*t)
+	}
+	return c
+}
+
+/**
+ * @brief Returns the colour of empty water at (x, y), tinted by temperature when the frame has an overlay.
+ */
+func waterColour(f *Frame, x, y int) [3]int {
+	if t, ok := f.Temperature(x, y); ok {
+		return mixRGB(coldRGB, warmRGB, float64(t))
+	}
+	return waterRGB
+}
+
+/**
+ * @struct blockSample
+ * @brief What a square block of cells contains.
+ */
+type blockSample struct {
+	fish, sharks, cells int
+	juveniles           int     ///< Fish that are juvenile, included in fish
+	tide                int     ///< Empty cells under a red tide
+	warmth              float64 ///< Summed temperature of the other empty cells, with an overlay
+	overlay             bool    ///< Whether the frame carries a temperature overlay
+	taggedFish          int     ///< Fish of a lineage, included in fish and drawn in its colour
+	taggedSharks        int     ///< Sharks of a lineage, included in sharks
+	tagged              [3]int  ///< Summed lineage colours of the tagged animals
+}
+
+/**
+ * @brief Adds another block's contents to this one.
+ */
+func (b *blockSample) add(o blockSample) {
+	b.fish, b.sharks, b.cells = b.fish+o.fish, b.sharks+o.sharks, b.cells+o.cells
+	b.juveniles, b.tide, b.warmth = b.juveniles+o.juveniles, b.tide+o.tide, b.warmth+o.warmth
+	b.overlay = b.overlay || o.overlay
+	b.taggedFish, b.taggedSharks = b.taggedFish+o.taggedFish, b.taggedSharks+o.taggedSharks
+	for i := range b.tagged {
+		b.tagged[i] += o.tagged[i]
+	}
+}
+
+/**
+ * @brief Returns the mixed colour of the block.
+ * @param styles Supplies the foreground colours of water, fish and sharks; nil uses the built-in palette.
+ */
+func (b blockSample) rgb(styles *Styles) [3]int {
+	palette := [numSpecies][3]int{waterRGB, fishRGB, sharkRGB}
+	if styles != nil {
+		for sp := range palette {
+			palette[sp] = styles.rgb(Species(sp), palette[sp])
+		}
+	}
+	if b.cells == 0 {
+		return palette[SpeciesNone]
+	}
+	water := b.cells - b.fish - b.sharks - b.tide
+	sea := palette[SpeciesNone]
+	if b.overlay && water > 0 {
+		sea = mixRGB(coldRGB, warmRGB, b.warmth/float64(water))
+	}
+	var c [3]int
+	for i := range c {
+		c[i] = (water*sea[i] + b.tide*tideRGB[i] + (b.fish-b.juveniles-b.taggedFish)*palette[SpeciesFish][i] + b.juveniles*juvenileRGB[i] +
+			(b.sharks-b.taggedSharks)*palette[SpeciesShark][i] + b.tagged[i]) / b.cells
+	}
+	return c
+}
+
+/**
+ * @brief Reports whether at least half of the block is occupied.
+ */
+func (b blockSample) lit() bool {
+	return b.cells > 0 && 2*(b.fish+b.sharks) >= b.cells
+}
+
+/**
+ * @brief Samples the scale x scale block whose top-left cell is (row*scale, col*scale).
+ * @details Blocks past the edge of the grid are clipped; a block entirely outside is empty.
+ */
+func sampleBlock(f *Frame, row, col, scale int) blockSample {
+	var b blockSample
+	for x := row * scale; x < min((row+1)*scale, f.Size()); x++ {
+		for y := col * scale; y < min((col+1)*scale, f.Size()); y++ {
+			b.cells++
+			tag := f.Lineage(x, y)
+			if tag > 0 {
+				c := lineageColour(tag)
+				for i := range b.tagged {
+					b.tagged[i] += c[i]
+				}
+			}
+			switch f.At(x, y) {
+			case SpeciesFish:
+				b.fish++
+				if tag > 0 {
+					b.taggedFish++
+				} else if f.Juvenile(x, y) {
+					b.juveniles++
+				}
+			case SpeciesShark:
+				b.sharks++
+				if tag > 0 {
+					b.taggedSharks++
+				}
+			default:
+				if f.Tide(x, y) {
+					b.tide++
+				} else if t, ok := f.Temperature(x, y); ok {
+					b.warmth += float64(t)
+					b.overlay = true
+				}
+			}
+		}
+	}
+	return b
+}
+
+/**
+ * @brief Chooses how many cells each pixel covers so the frame fits in the given width.
+ * @param size The grid dimension.
+ * @param pixelsPerColumn Horizontal pixels per character (1 for half blocks, 2 for braille).
+ * @param width Terminal width in characters.
+ * @return The smallest block size that fits, at least 1.
+ */
+func blockScale(size, pixelsPerColumn, width int) int {
+	pixels := max(width, 1) * pixelsPerColumn
+	return max((size+pixels-1)/pixels, 1)
+}
+
+/**
+ * @brief Returns the terminal width from $COLUMNS, or 80 if it is unset or invalid.
+ */
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
+
+/**
+ * @struct HalfBlockRenderer
+ * @brief Draws two vertically stacked pixels per character with '▀'.
+ */
+type HalfBlockRenderer struct {
+	W       io.Writer ///< Destination, usually os.Stdout
+	Width   int       ///< Maximum characters per line; 0 uses the terminal width
+	NoColor bool      ///< Use '▀', '▄', '█' and ' ' for occupancy instead of colours
+	Styles  *Styles   ///< Species colours; nil uses the built-in palette
+}
+
+/**
+ * @brief Prints a step header followed by the downsampled frame.
+ * @param f The frame to print.
+ */
+func (r *HalfBlockRenderer) Render(f *Frame) {
+	width := r.Width
+	if width <= 0 {
+		width = terminalWidth()
+	}
+	scale := blockScale(f.Size(), 1, width)
+	pixels := (f.Size() + scale - 1) / scale
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Step %d (%d cells per pixel):\n", f.Chronon(), scale*scale)
+	for row := 0; row < pixels; row += 2 {
+		for col := 0; col < pixels; col++ {
+			top := sampleBlock(f, row, col, scale)
+			bottom := sampleBlock(f, row+1, col, scale)
+			if r.NoColor {
+				b.WriteString([2][2]string{{" ", "▄"}, {"▀", "█"}}[btoi(top.lit())][btoi(bottom.lit())])
+				continue
+			}
+			t, u := top.rgb(r.Styles), bottom.rgb(r.Styles)
+			if row+1 >= pixels {
+				u = [3]int{0, 0, 0} ///< Odd number of pixel rows: blank lower half
+			}
+			fmt.Fprintf(&b, "\033[38;2;%d;%d;%dm\033[48;2;%d;%d;%dm▀", t[0], t[1], t[2], u[0], u[1], u[2])
+		}
+		if !r.NoColor {
+			b.WriteString("\033[0m")
+		}
+		b.WriteByte('\n')
+	}
+	io.WriteString(r.W, b.String())
+}
+
+/**
+ * @brief Converts a bool to 0 or 1.
+ */
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+/** Bit of each braille dot, indexed by [row][column] within the 4x2 matrix. */
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+/**
+ * @struct BrailleRenderer
+ * @brief Draws a 2x4 matrix of pixels per character with Unicode braille patterns.
+ */
+type BrailleRenderer struct {
+	W       io.Writer ///< Destination, usually os.Stdout
+	Width   int       ///< Maximum characters per line; 0 uses the terminal width
+	NoColor bool      ///< Omit the colour of each character
+	Styles  *Styles   ///< Species colours; nil uses the built-in palette
+}
+
+/**
+ * @brief Prints a step header followed by the downsampled frame.
+ * @details Each character's colour mixes all eight of its pixels.
+ * @param f The frame to print.
+ */
+func (r *BrailleRenderer) Render(f *Frame) {
+	width := r.Width
+	if width <= 0 {
+		width = terminalWidth()
+	}
+	scale := blockScale(f.Size(), 2, width)
+	pixels := (f.Size() + scale - 1) / scale
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Step %d (%d cells per dot):\n", f.Chronon(), scale*scale)
+	for row := 0; row < pixels; row += 4 {
+		for col := 0; col < pixels; col += 2 {
+			glyph := rune(0x2800)
+			var all blockSample
+			for dr := 0; dr < 4; dr++ {
+				for dc := 0; dc < 2; dc++ {
+					s := sampleBlock(f, row+dr, col+dc, scale)
+					if s.lit() {
+						glyph |= brailleDots[dr][dc]
+					}
+					all.add(s)
+				}
+			}
+			if !r.NoColor {
+				c := all.rgb(r.Styles)
+				fmt.Fprintf(&b, "\033[38;2;%d;%d;%dm", c[0], c[1], c[2])
+			}
+			b.WriteRune(glyph)
+		}
+		if !r.NoColor {
+			b.WriteString("\033[0m")
+		}
+		b.WriteByte('\n')
+	}
+	io.WriteString(r.W, b.String())
+}
@@ -0,0 +1,102 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file branch_test.go
+ * @brief Tests for resuming checkpoints as branches.
+ */
+package main
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+/**
+ * @brief Runs a deterministic simulation for 10 chronons and checkpoints it.
+ * @return The simulation and the checkpoint's path.
+ */
+func branchPoint(t *testing.T) (*Simulation, string) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Engine, cfg.FishBreed = "deterministic", 4
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Run(context.Background(), 10)
+	path := filepath.Join(t.TempDir(), "branch.json")
+	if err := sim.Checkpoint().WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	return sim, path
+}
+
+func TestResumeIsReproducible(t *testing.T) {
+	original, path := branchPoint(t)
+	var runs [2]*Simulation
+	for i := range runs {
+		cfg, err := newConfigFlags("run", "", io.Discard).parse([]string{"-resume", path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runs[i], err = NewSimulation(cfg); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(runs[i].Checkpoint().Entities, original.Checkpoint().Entities) || runs[i].Snapshot().Chronon() != 10 {
+			t.Fatalf("resumed at chronon %d with a different world", runs[i].Snapshot().Chronon())
+		}
+		runs[i].Run(context.Background(), 15)
+	}
+	if !slices.Equal(runs[0].Checkpoint().Entities, runs[1].Checkpoint().Entities) {
+		t.Error("two deterministic branches with the same parameters should be identical")
+	}
+	if b := runs[0].Checkpoint().Branch; b == nil || b.From != path || b.Chronon != 10 || len(b.Changes) != 0 {
+		t.Errorf("branch point %+v", b)
+	}
+}
+
+func TestResumeWithChangedParameters(t *testing.T) {
+	_, path := branchPoint(t)
+	cfg, err := newConfigFlags("run", "", io.Discard).parse([]string{"-resume", path, "-seed", "5", "-set", "sharkBreed=6, shark-vision=2", "-chronons", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SharkBreed != 6 || cfg.SharkVision != 2 || cfg.Seed != 5 || cfg.FishBreed != 4 || cfg.Engine != "deterministic" || cfg.Chronons != 3 {
+		t.Errorf("resumed config %+v: want SharkBreed 6, SharkVision 2, Seed 5, Chronons 3 and the inherited FishBreed 4 and engine", cfg)
+	}
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Run(context.Background(), cfg.Chronons)
+	cp := sim.Checkpoint()
+	want := []string{"SharkBreed: 3 -> 6", "SharkVision: 1 -> 2", "Seed: 11 -> 5"}
+	if cp.Chronon != 13 || cp.Branch == nil || !slices.Equal(cp.Branch.Changes, want) || cp.Branch.Seed != 11 {
+		t.Errorf("checkpoint at chronon %d with branch %+v, want chronon 13 and changes %v", cp.Chronon, cp.Branch, want)
+	}
+
+	second := filepath.Join(t.TempDir(), "second.json")
+	cp.WriteFile(second)
+	cfg, err = newConfigFlags("run", "", io.Discard).parse([]string{"-resume", second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim, err = NewSimulation(cfg); err != nil || sim.Branch().Parent == nil || sim.Branch().Parent.From != path {
+		t.Errorf("a branch of a branch should record its lineage, got %+v (err %v)", sim.Branch(), err)
+	}
+	if _, err := newConfigFlags("run", "", io.Discard).parse([]string{"-resume", path, "-set", "sharkBreed"}); err == nil {
+		t.Error("-set without a value accepted")
+	}
+}
@@ -0,0 +1,70 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file canvas.go
+ * @brief Renders frames into an RGBA pixel buffer, one pixel per cell.
+ * @details The buffer uses the layout of an HTML canvas ImageData (row-major, 4 bytes per
+ * pixel), so the browser front end can copy it straight onto a canvas. It has no terminal
+ * or OS dependencies and is also usable for image export.
+ */
+package main
+
+/**
+ * @struct PixelRenderer
+ * @brief Draws each frame into Pix, replacing its previous contents.
+ */
+type PixelRenderer struct {
+	Pix       []byte ///< RGBA pixels of the last frame; resized to match the frame
+	MaxEnergy int    ///< Energy of a freshly fed shark; when set, hungry sharks are drawn darker
+}
+
+/**
+ * @brief Draws a frame.
+ * @details Colours match the block renderers: blue water, green fish, red sharks.
+ * @param f The frame to draw.
+ */
+func (r *PixelRenderer) Render(f *Frame) {
+	n := f.Size() * f.Size() * 4
+	if cap(r.Pix) < n {
+		r.Pix = make([]byte, n)
+	}
+	r.Pix = r.Pix[:n]
+
+	for x := 0; x < f.Size(); x++ {
+		for y := 0; y < f.Size(); y++ {
+			c := waterRGB
+			switch f.At(x, y) {
+			case SpeciesNone:
+				c = waterColour(f, x, y)
+				if f.Tide(x, y) {
+					c = tideRGB
+				}
+			case SpeciesFish:
+				c = fishRGB
+				if f.Juvenile(x, y) {
+					c = juvenileRGB
+				}
+			case SpeciesShark:
+				c = sharkRGB
+				if r.MaxEnergy > 0 {
+					level := 80 + 175*min(f.Energy(x, y), r.MaxEnergy)/r.MaxEnergy
+					c = [3]int{c[0] * level / 255, c[1] * level / 255, c[2] * level / 255}
+				}
+			}
+			if tag := f.Lineage(x, y); tag > 0 {
+				c = lineageColour(tag)
+			}
+			p := r.Pix[(x*f.Size()+y)*4:]
+			p[0], p[1], p[2], p[3] = byte(c[0]), byte(c[1]), byte(c[2]), 255
+		}
+	}
+}
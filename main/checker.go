@@ -0,0 +1,155 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file checker.go
+ * @brief Invariant checking between chronons (the -check mode).
+ * @details Validates the grid after every chronon so that races and rule bugs surface as an
+ * immediate failure on the offending frame instead of as subtly wrong population curves.
+ */
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/**
+ * @struct InvariantChecker
+ * @brief Remembers the previous frame so consecutive frames can be compared.
+ */
+type InvariantChecker struct {
+	MaxEnergy  int               ///< Upper bound on shark energy (the starvation energy)
+	Speed      int               ///< Maximum number of cells an entity may move per chronon
+	prevPos    map[Entity][2]int ///< Position of every entity in the previous frame
+	prevFish   int               ///< Fish population of the previous frame
+	prevSharks int               ///< Shark population of the previous frame
+}
+
+/**
+ * @brief Creates a checker and validates the initial frame.
+ * @param g The freshly initialised grid.
+ * @param maxEnergy Upper bound on shark energy.
+ * @return The checker, and an error describing any violation in the initial frame.
+ */
+func NewInvariantChecker(g *Grid, maxEnergy int) (*InvariantChecker, error) {
+	c := &InvariantChecker{MaxEnergy: maxEnergy, Speed: 1}
+	pos, fish, sharks, violations := c.scan(g)
+	c.prevPos, c.prevFish, c.prevSharks = pos, fish, sharks
+	return c, violationError(violations)
+}
+
+/**
+ * @brief Validates a frame produced by one chronon.
+ * @details Checks that no entity occupies two cells, shark energy lies in [0, MaxEnergy],
+ * populations match the previous frame plus births minus deaths, and no entity moved
+ * further than Speed cells (toroidal Manhattan distance).
+ * @param g The grid after the chronon.
+ * @param counts The births and deaths reported for the chronon.
+ * @return An error listing the violations, or nil if the frame is consistent.
+ */
+func (c *InvariantChecker) Check(g *Grid, counts StepCounts) error {
+	pos, fish, sharks, violations := c.scan(g)
+
+	if want := c.prevFish + counts.FishBorn - counts.FishEaten - counts.FishCrowded - counts.FishPoisoned; fish != want {
+		violations = append(violations, fmt.Sprintf("fish population %d, expected %d (previous %d + born %d - eaten %d - crowded %d - poisoned %d)",
+			fish, want, c.prevFish, counts.FishBorn, counts.FishEaten, counts.FishCrowded, counts.FishPoisoned))
+	}
+	if want := c.prevSharks + counts.SharksBorn - counts.SharksStarved; sharks != want {
+		violations = append(violations, fmt.Sprintf("shark population %d, expected %d (previous %d + born %d - starved %d)",
+			sharks, want, c.prevSharks, counts.SharksBorn, counts.SharksStarved))
+	}
+
+	for e, p := range pos {
+		old, ok := c.prevPos[e]
+		if !ok {
+			continue ///< Newborn
+		}
+		if d := torusDistance(g.Size, old, p); d > c.Speed {
+			violations = append(violations, fmt.Sprintf("%s moved %d cells from (%d,%d) to (%d,%d), speed is %d",
+				speciesName(e), d, old[0], old[1], p[0], p[1], c.Speed))
+		}
+	}
+
+	c.prevPos, c.prevFish, c.prevSharks = pos, fish, sharks
+	return violationError(violations)
+}
+
+/**
+ * @brief Collects positions and populations, checking per-cell invariants on the way.
+ * @param g The grid to scan.
+ * @return Entity positions, fish and shark counts, and any per-cell violations.
+ */
+func (c *InvariantChecker) scan(g *Grid) (map[Entity][2]int, int, int, []string) {
+	pos := make(map[Entity][2]int)
+	fish, sharks := 0, 0
+	var violations []string
+
+	for x := 0; x < g.Size; x++ {
+		for y := 0; y < g.Size; y++ {
+			e := g.At(x, y)
+			if e == nil {
+				continue
+			}
+			if p, dup := pos[e]; dup {
+				violations = append(violations, fmt.Sprintf("%s occupies both (%d,%d) and (%d,%d)",
+					speciesName(e), p[0], p[1], x, y))
+			}
+			pos[e] = [2]int{x, y}
+
+			switch v := e.(type) {
+			case *Fish:
+				fish++
+			case *Shark:
+				sharks++
+				if v.Energy < 0 || v.Energy > c.MaxEnergy {
+					violations = append(violations, fmt.Sprintf("shark at (%d,%d) has energy %d outside [0,%d]",
+						x, y, v.Energy, c.MaxEnergy))
+				}
+			}
+		}
+	}
+	return pos, fish, sharks, violations
+}
+
+/**
+ * @brief Computes the Manhattan distance between two cells on a torus.
+ * @param size The grid dimension.
+ * @param a The first cell.
+ * @param b The second cell.
+ * @return The shortest wrapped distance.
+ */
+func torusDistance(size int, a, b [2]int) int {
+	d := 0
+	for i := 0; i < 2; i++ {
+		delta := a[i] - b[i]
+		if delta < 0 {
+			delta = -delta
+		}
+		if size-delta < delta {
+			delta = size - delta
+		}
+		d += delta
+	}
+	return d
+}
+
+/**
+ * @brief Joins violation messages into a single error.
+ * @param violations The messages to join.
+ * @return nil if there are no violations.
+ */
+func violationError(violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d invariant violation(s):\n  %s", len(violations), strings.Join(violations, "\n  "))
+}
@@ -0,0 +1,119 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file checker_test.go
+ * @brief Tests that the invariant checker accepts consistent frames and reports broken ones.
+ */
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInvariantCheckerReportsViolations(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(g *Grid, fish *Fish, shark *Shark) StepCounts ///< Turns the starting frame into the next one
+		want   string                                             ///< Expected in the error; empty for a consistent frame
+	}{
+		{"consistent", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			g.Set(1, 1, nil)
+			g.Set(1, 2, fish)
+			g.Set(1, 1, &Fish{}) ///< Offspring left behind
+			return StepCounts{FishBorn: 1}
+		}, ""},
+		{"wrapped move", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			g.Set(4, 4, nil)
+			g.Set(0, 4, shark) ///< One cell across the edge
+			return StepCounts{}
+		}, ""},
+		{"duplicate", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			g.Set(1, 2, fish)
+			return StepCounts{FishBorn: 1}
+		}, "fish occupies both (1,1) and (1,2)"},
+		{"energy too high", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			shark.Energy = 6
+			return StepCounts{}
+		}, "energy 6 outside [0,5]"},
+		{"energy negative", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			shark.Energy = -1
+			return StepCounts{}
+		}, "energy -1 outside [0,5]"},
+		{"too fast", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			g.Set(1, 1, nil)
+			g.Set(1, 3, fish)
+			return StepCounts{}
+		}, "fish moved 2 cells from (1,1) to (1,3), speed is 1"},
+		{"unreported birth", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			g.Set(2, 2, &Fish{})
+			return StepCounts{}
+		}, "fish population 2, expected 1"},
+		{"unreported eating", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			g.Set(1, 1, nil)
+			return StepCounts{}
+		}, "fish population 0, expected 1"},
+		{"reported death that did not happen", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			return StepCounts{SharksStarved: 1}
+		}, "shark population 1, expected 0"},
+		{"unreported starvation", func(g *Grid, fish *Fish, shark *Shark) StepCounts {
+			g.Set(4, 4, nil)
+			return StepCounts{FishEaten: 1}
+		}, "shark population 0, expected 1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGrid(5)
+			fish, shark := &Fish{}, &Shark{Energy: 5}
+			g.Set(1, 1, fish)
+			g.Set(4, 4, shark)
+			checker, err := NewInvariantChecker(g, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = checker.Check(g, c.change(g, fish, shark))
+			switch {
+			case c.want == "" && err != nil:
+				t.Errorf("consistent frame reported: %v", err)
+			case c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)):
+				t.Errorf("got %v, want an error containing %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestInvariantCheckerRejectsBadInitialFrame(t *testing.T) {
+	g := NewGrid(3)
+	g.Set(0, 0, &Shark{Energy: 9})
+	if _, err := NewInvariantChecker(g, 5); err == nil || !strings.Contains(err.Error(), "energy 9 outside [0,5]") {
+		t.Errorf("got %v, want the initial shark's energy reported", err)
+	}
+}
+
+func TestInvariantCheckerAllowsConfiguredSpeed(t *testing.T) {
+	g := NewGrid(6)
+	fish := &Fish{}
+	g.Set(0, 0, fish)
+	checker, _ := NewInvariantChecker(g, 5)
+	checker.Speed = 2
+	g.Set(0, 0, nil)
+	g.Set(5, 5, fish) ///< Two cells away across both edges
+	if err := checker.Check(g, StepCounts{}); err != nil {
+		t.Errorf("a move within the speed was reported: %v", err)
+	}
+	g.Set(5, 5, nil)
+	g.Set(2, 5, fish) ///< Three cells from (5,5)
+	if err := checker.Check(g, StepCounts{}); err == nil || !strings.Contains(err.Error(), "moved 3 cells") {
+		t.Errorf("got %v, want the three-cell move reported", err)
+	}
+}
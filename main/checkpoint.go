@@ -0,0 +1,253 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file checkpoint.go
+ * @brief Saving and loading complete simulation state (.wtr checkpoint files).
+ * @details Unlike a Frame, a checkpoint keeps every entity's breeding counter and energy, and
+ * also the chronon, the red-tide levels and the run's parameters, so a run can be inspected
+ * or continued from where it stopped. The random sources are not saved: a continued run
+ * restarts them from the seed, so it follows the same rules but not the exact future the
+ * original run would have had (see branch.go). The file is a single JSON document so it can
+ * also be read from other languages.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+/** Format version written to every checkpoint. */
+const checkpointVersion = 1
+
+/**
+ * @struct Checkpoint
+ * @brief The serialised state of a simulation.
+ */
+type Checkpoint struct {
+	Version  int                `json:"version"`  ///< Format version (checkpointVersion)
+	Config   Config             `json:"config"`   ///< Parameters the run was started with
+	Chronon  int                `json:"chronon"`  ///< Chronons simulated so far
+	Size     int                `json:"size"`     ///< Grid dimension
+	Entities []checkpointEntity `json:"entities"` ///< Every fish and shark, in row-major order
+
+	Provenance *Provenance      `json:"provenance,omitempty"` ///< Build and host that wrote the checkpoint; absent in older files
+	Signature  *Signature       `json:"signature,omitempty"`  ///< With -sign
+	Branch     *BranchPoint     `json:"branch,omitempty"`     ///< Where the run was resumed from, with -resume
+	Tide       []checkpointTide `json:"tide,omitempty"`       ///< Cells under a red tide, in row-major order; absent when the water is clear
+}
+
+/**
+ * @struct checkpointEntity
+ * @brief One fish or shark and its position.
+ */
+type checkpointEntity struct {
+	X            int    `json:"x"`
+	Y            int    `json:"y"`
+	Species      string `json:"species"` ///< "fish" or "shark"
+	BreedCounter int    `json:"breed"`
+	Energy       int    `json:"energy,omitempty"`   ///< Sharks only
+	Juvenile     int    `json:"juvenile,omitempty"` ///< Fish only: chronons until it matures
+	Sated        int    `json:"sated,omitempty"`    ///< Sharks only: chronons it still skips hunting
+	Lineage      int    `json:"lineage,omitempty"`  ///< Tag inherited from an ancestor tagged with -tag
+	Acted        int64  `json:"acted,omitempty"`    ///< Chronon of its last turn; 0 for a newborn
+}
+
+/**
+ * @struct checkpointTide
+ * @brief The toxin level of one cell under a red tide.
+ */
+type checkpointTide struct {
+	X     int     `json:"x"`
+	Y     int     `json:"y"`
+	Level float32 `json:"level"` ///< Above 0, at most 1
+}
+
+/**
+ * @brief Captures the state of a grid.
+ * @param g The grid; must not be stepped during the call.
+ * @param cfg The configuration of the run.
+ * @return The checkpoint.
+ */
+func NewCheckpoint(g *Grid, cfg Config) *Checkpoint {
+	cp := &Checkpoint{Version: checkpointVersion, Config: cfg, Chronon: g.Chronon, Size: g.Size, Provenance: currentProvenance()}
+	for x := 0; x < g.Size; x++ {
+		for y := 0; y < g.Size; y++ {
+			switch e := g.At(x, y).(type) {
+			case *Fish:
+				cp.Entities = append(cp.Entities, checkpointEntity{X: x, Y: y, Species: "fish", BreedCounter: e.BreedCounter, Juvenile: e.Juvenile, Lineage: e.Lineage, Acted: e.Acted})
+			case *Shark:
+				cp.Entities = append(cp.Entities, checkpointEntity{X: x, Y: y, Species: "shark", BreedCounter: e.BreedCounter, Energy: e.Energy, Sated: e.Sated, Lineage: e.Lineage, Acted: e.Acted})
+			}
+		}
+	}
+	return cp
+}
+
+/**
+ * @brief Writes the checkpoint as JSON.
+ * @param w Destination writer.
+ * @return Any encoding error.
+ */
+func (cp *Checkpoint) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false) ///< Keeps "->" in branch changes readable
+	return enc.Encode(cp)
+}
+
+/**
+ * @brief Writes the checkpoint to a file, replacing it only once the new state is complete.
+ * @param path Destination file path.
+ * @return An error if the file could not be written.
+ */
+func (cp *Checkpoint) WriteFile(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("creating checkpoint: %w", err)
+	}
+	if err := cp.Write(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	return os.Rename(tmp, path) ///< An interrupted write never clobbers the previous checkpoint
+}
+
+/**
+ * @brief Saves the checkpoint as an output of the run, through the -output sink.
+ * @details On the local filesystem the file is replaced atomically as by WriteFile; an object
+ * store replaces the object in one upload anyway.
+ * @param path Destination path, as given on the command line.
+ * @return An error if the checkpoint could not be written.
+ */
+func (cp *Checkpoint) Save(path string) error {
+	if local, ok := outputs.(localSink); ok {
+		return cp.WriteFile(local.resolve(path))
+	}
+	if err := writeOutput(path, cp.Write); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	return nil
+}
+
+/**
+ * @brief Reads a checkpoint file.
+ * @param path The file.
+ * @return The checkpoint, or an error naming the file.
+ */
+func readCheckpointFile(path string) (*Checkpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cp, err := ReadCheckpoint(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cp, nil
+}
+
+/**
+ * @brief Reads a checkpoint written by Write.
+ * @param r Source reader.
+ * @return The checkpoint, or an error if it is malformed or of an unknown version.
+ */
+func ReadCheckpoint(r io.Reader) (*Checkpoint, error) {
+	var cp Checkpoint
+	if err := json.NewDecoder(r).Decode(&cp); err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d (want %d)", cp.Version, checkpointVersion)
+	}
+	return &cp, nil
+}
+
+/**
+ * @brief Rebuilds the grid stored in the checkpoint.
+ * @param storageName The storage backend to use.
+ * @return The grid, or an error if the storage is unknown or an entity is invalid.
+ */
+func (cp *Checkpoint) Grid(storageName string) (*Grid, error) {
+	g, err := NewGridWithStorage(cp.Size, storageName)
+	if err != nil {
+		return nil, err
+	}
+	g.Chronon = cp.Chronon
+	for _, e := range cp.Entities {
+		if e.X < 0 || e.X >= cp.Size || e.Y < 0 || e.Y >= cp.Size {
+			return nil, fmt.Errorf("checkpoint entity at (%d,%d) lies outside the %dx%d grid", e.X, e.Y, cp.Size, cp.Size)
+		}
+		if g.At(e.X, e.Y) != nil {
+			return nil, fmt.Errorf("checkpoint has two entities at (%d,%d)", e.X, e.Y)
+		}
+		switch e.Species {
+		case "fish":
+			g.Set(e.X, e.Y, &Fish{BreedCounter: e.BreedCounter, Juvenile: e.Juvenile, Lineage: e.Lineage, Acted: e.Acted})
+		case "shark":
+			g.Set(e.X, e.Y, &Shark{BreedCounter: e.BreedCounter, Energy: e.Energy, Sated: e.Sated, Lineage: e.Lineage, Acted: e.Acted})
+		default:
+			return nil, fmt.Errorf("checkpoint entity at (%d,%d) has unknown species %q", e.X, e.Y, e.Species)
+		}
+	}
+	return g, nil
+}
+
+/**
+ * @brief Captures the current state of the simulation.
+ * @details Takes the grid lock, so it is safe to call from any goroutine.
+ * @return The checkpoint.
+ */
+func (s *Simulation) Checkpoint() *Checkpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp := NewCheckpoint(s.grid, s.cfg)
+	cp.Branch = s.branch
+	if s.tide != nil {
+		cp.Tide = s.tide.checkpoint()
+	}
+	return cp
+}
+
+/**
+ * @brief Rebuilds the red tide stored in the checkpoint.
+ * @param seed The seed of the resumed run, from which the tide's random source restarts.
+ * @return The tide, nil when the checkpoint has none, or an error if a cell is invalid.
+ */
+func (cp *Checkpoint) redTide(seed int64) (*Tide, error) {
+	if len(cp.Tide) == 0 {
+		return nil, nil
+	}
+	t := newTide(cp.Size, seed)
+	for _, c := range cp.Tide {
+		if c.X < 0 || c.X >= cp.Size || c.Y < 0 || c.Y >= cp.Size {
+			return nil, fmt.Errorf("checkpoint tide at (%d,%d) lies outside the %dx%d grid", c.X, c.Y, cp.Size, cp.Size)
+		}
+		if !(c.Level > 0 && c.Level <= 1) {
+			return nil, fmt.Errorf("checkpoint tide at (%d,%d) has level %g outside (0,1]", c.X, c.Y, c.Level)
+		}
+		i := c.X*cp.Size + c.Y
+		if t.level[i] == 0 {
+			t.cells++
+		}
+		t.level[i] = c.Level
+	}
+	return t, nil
+}
@@ -0,0 +1,130 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file checkpoint_test.go
+ * @brief Round-trip and validation tests for checkpoint files.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Run(context.Background(), 5)
+
+	var buf bytes.Buffer
+	if err := sim.Checkpoint().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := ReadCheckpoint(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Config != testConfig() {
+		t.Errorf("config changed in round trip: %+v", cp.Config)
+	}
+
+	for _, storageName := range storageNames() {
+		g, err := cp.Grid(storageName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Chronon != 5 {
+			t.Errorf("%s: restored chronon %d, want 5", storageName, g.Chronon)
+		}
+		var a, b strings.Builder
+		sim.Grid().Fprint(&a)
+		g.Fprint(&b)
+		if a.String() != b.String() {
+			t.Errorf("%s: restored grid differs from the original", storageName)
+		}
+		for x := 0; x < g.Size; x++ {
+			for y := 0; y < g.Size; y++ {
+				if s, ok := sim.Grid().At(x, y).(*Shark); ok {
+					r := g.At(x, y).(*Shark)
+					if *r != *s {
+						t.Fatalf("%s: shark at (%d,%d) restored as %+v, want %+v", storageName, x, y, *r, *s)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCheckpointKeepsRedTide(t *testing.T) {
+	cfg := testConfig()
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Bloom(4, 4, 6, 6)
+	sim.Run(context.Background(), 3) ///< Spread and decay, so levels differ from cell to cell
+	saved := sim.Checkpoint()
+	if len(saved.Tide) <= 9 {
+		t.Fatalf("checkpoint holds %d tide cells, want the spreading bloom", len(saved.Tide))
+	}
+	path := filepath.Join(t.TempDir(), "tide.wtr")
+	if err := saved.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Resume = path
+	resumed, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resumed.Checkpoint().Tide; !slices.Equal(got, saved.Tide) {
+		t.Errorf("resumed tide %v, want %v", got, saved.Tide)
+	}
+	if got, want := resumed.Snapshot().TideCells(), sim.Snapshot().TideCells(); got != want {
+		t.Errorf("resumed frame shows %d tide cells, want %d", got, want)
+	}
+	sim.Step(context.Background())
+	resumed.Step(context.Background())
+	if got, want := resumed.Checkpoint().Tide, sim.Checkpoint().Tide; !slices.Equal(got, want) {
+		t.Errorf("resumed tide spread to %v, want %v", got, want)
+	}
+}
+
+func TestCheckpointRejectsBadInput(t *testing.T) {
+	cases := map[string]string{
+		"version":    `{"version":99,"size":2}`,
+		"outside":    `{"version":1,"size":2,"entities":[{"x":2,"y":0,"species":"fish"}]}`,
+		"twice":      `{"version":1,"size":2,"entities":[{"x":0,"y":0,"species":"fish"},{"x":0,"y":0,"species":"shark"}]}`,
+		"species":    `{"version":1,"size":2,"entities":[{"x":0,"y":0,"species":"whale"}]}`,
+		"tide cell":  `{"version":1,"size":2,"tide":[{"x":0,"y":-1,"level":0.5}]}`,
+		"tide level": `{"version":1,"size":2,"tide":[{"x":0,"y":0,"level":1.5}]}`,
+		"not json":   `F S .`,
+	}
+	for name, doc := range cases {
+		cp, err := ReadCheckpoint(strings.NewReader(doc))
+		if err == nil {
+			_, err = cp.Grid("entities")
+		}
+		if err == nil {
+			_, err = cp.redTide(1)
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file chunks.go
+ * @brief The "chunks" storage: a world of 64x64 chunks allocated on demand.
+ * @details A chunk is only allocated when something is first placed in it, and it keeps a
+ * count of its occupants. Every chronon builds its next grid from an empty store, so chunks
+ * that nothing moves into are never allocated again and regions that have died out give
+ * their memory back. Full-grid loops ask Grid.nextOccupied for the next column worth
+ * visiting and jump over chunks that are unallocated or empty, the way Life simulators
+ * skip dead parts of a big board: only the occupied chunks cost CPU or memory.
+ */
+package main
+
+import "sync/atomic"
+
+const (
+	chunkShift = 6               ///< log2 of the chunk side
+	chunkSide  = 1 << chunkShift ///< Cells per chunk side
+	chunkMask  = chunkSide - 1
+)
+
+/**
+ * @struct chunk
+ * @brief The cells of one 64x64 block.
+ */
+type chunk struct {
+	cells    [chunkSide * chunkSide]Entity
+	kinds    [chunkSide * chunkSide]Species ///< Species of each cell
+	occupied atomic.Int32                   ///< Non-nil cells; written by every worker that places into the chunk
+}
+
+/**
+ * @struct chunkStorage
+ * @brief Row-major table of lazily allocated chunks.
+ * @details Workers may place into the same chunk, or allocate the same missing chunk, at
+ * once, so chunk pointers are installed with a compare-and-swap and occupant counts are
+ * atomic. Individual cells need no locking: the engines never write one cell concurrently.
+ */
+type chunkStorage struct {
+	counts rowCounts               ///< Fish and sharks per row of cells
+	size   int                     ///< Side of the grid in cells
+	across int                     ///< Chunks per side
+	chunks []atomic.Pointer[chunk] ///< nil until first written
+	skip   []bool                  ///< Chunks next passes over even if occupied (see fastforward.go)
+}
+
+func newChunkStorage(size int) storage {
+	across := (size + chunkMask) >> chunkShift
+	return &chunkStorage{counts: make(rowCounts, size), size: size, across: across, chunks: make([]atomic.Pointer[chunk], across*across)}
+}
+
+/**
+ * @brief Returns the chunk holding (x, y), or nil if it was never allocated.
+ */
+func (s *chunkStorage) chunk(x, y int) *chunk {
+	return s.chunks[(x>>chunkShift)*s.across+y>>chunkShift].Load()
+}
+
+func (s *chunkStorage) at(x, y int) Entity {
+	c := s.chunk(x, y)
+	if c == nil {
+		return nil
+	}
+	return c.cells[(x&chunkMask)<<chunkShift|y&chunkMask]
+}
+
+func (s *chunkStorage) set(x, y int, e Entity) {
+	slot := &s.chunks[(x>>chunkShift)*s.across+y>>chunkShift]
+	c := slot.Load()
+	if c == nil {
+		if e == nil {
+			return ///< Emptying a cell of an unallocated chunk changes nothing
+		}
+		slot.CompareAndSwap(nil, new(chunk)) ///< Fails harmlessly if another worker allocated it first
+		c = slot.Load()
+	}
+	i := (x&chunkMask)<<chunkShift | y&chunkMask
+	switch {
+	case c.cells[i] == nil && e != nil:
+		c.occupied.Add(1)
+	case c.cells[i] != nil && e == nil:
+		c.occupied.Add(-1)
+	}
+	sp := speciesOf(e)
+	s.counts.change(x, c.kinds[i], sp)
+	c.cells[i], c.kinds[i] = e, sp
+}
+
+func (s *chunkStorage) row(x int) (int, int) { return s.counts.row(x) }
+
+func (s *chunkStorage) species(x, y int) Species {
+	c := s.chunk(x, y)
+	if c == nil {
+		return SpeciesNone
+	}
+	return c.kinds[(x&chunkMask)<<chunkShift|y&chunkMask]
+}
+
+func (s *chunkStorage) empty() storage { return newChunkStorage(s.size) }
+
+func (s *chunkStorage) next(x, y int) int {
+	for y < s.size {
+		i := (x>>chunkShift)*s.across + y>>chunkShift
+		if c := s.chunks[i].Load(); c != nil && c.occupied.Load() > 0 && (s.skip == nil || !s.skip[i]) {
+			return y
+		}
+		y = (y | chunkMask) + 1 ///< First column of the next chunk
+	}
+	return s.size
+}
+
+/**
+ * @brief Reports whether chunk i is allocated and every one of its cells holds a fish.
+ */
+func (s *chunkStorage) saturated(i int) bool {
+	c := s.chunks[i].Load()
+	cx, cy := i/s.across, i%s.across
+	rows, cols := min(chunkSide, s.size-cx*chunkSide), min(chunkSide, s.size-cy*chunkSide)
+	if c == nil || int(c.occupied.Load()) != rows*cols {
+		return false
+	}
+	for x := 0; x < rows; x++ {
+		for y := 0; y < cols; y++ {
+			if c.kinds[x<<chunkShift|y] != SpeciesFish {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+/**
+ * @brief Returns the number of chunks currently allocated, for the -storage chunks log line.
+ */
+func (s *chunkStorage) allocated() int {
+	n := 0
+	for i := range s.chunks {
+		if s.chunks[i].Load() != nil {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,62 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file claims_test.go
+ * @brief Tests for the lock-free claims engine.
+ */
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClaimsMatchesSectionsOnOneThread(t *testing.T) {
+	for _, rules := range []Rules{
+		{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5},
+		{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5, FishSpeed: 2, SharkSpeed: 3, SharkVision: 4},
+	} {
+		a, b := NewGrid(30), NewGrid(30)
+		a.Seed(4)
+		b.Seed(4)
+		a.Initialize(300, 60, rules.StarveEnergy)
+		b.Initialize(300, 60, rules.StarveEnergy)
+		for c := 0; c < 30; c++ {
+			sectionsEngine{}.Step(context.Background(), a, rules, 1)
+			claimsEngine{}.Step(context.Background(), b, rules, 1)
+		}
+		if hashGrid(a) != hashGrid(b) {
+			t.Errorf("rules %+v: one-thread claims run differs from sections", rules)
+		}
+	}
+}
+
+func TestClaimHasOneWinner(t *testing.T) {
+	claims := newClaimGrid(4)
+	var wins atomic.Int32
+	var wg sync.WaitGroup
+	for id := int32(1); id <= 16; id++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if claims.claim(2, 3, id) {
+				wins.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if wins.Load() != 1 || !claims.claimed(2, 3) || claims.claimed(3, 2) {
+		t.Errorf("%d movers won the same cell, want exactly 1", wins.Load())
+	}
+}
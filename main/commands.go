@@ -0,0 +1,190 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file commands.go
+ * @brief Subcommand dispatch for the wator binary.
+ * @details "wator <command> [flags]" selects one of run, bench, sweep, replay, serve and verify, each
+ * with its own -h. Arguments that do not start with a command name are handed to run, so
+ * the original "go run . [flags] <NumShark> ... <Threads>" form keeps working.
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+/**
+ * @struct command
+ * @brief A subcommand of the binary.
+ */
+type command struct {
+	name    string                  ///< Word that selects the command
+	summary string                  ///< One-line description for the command list
+	run     func(args []string) int ///< Runs the command and returns the exit code
+}
+
+/** The subcommands, in the order they are listed by "wator help". */
+var commands []command
+
+func init() {
+	commands = []command{ ///< Assigned in init because cmdHelp refers back to the table
+		{"run", "simulate and render a single world (the default)", cmdRun},
+		{"bench", "time headless runs of each engine and thread count", cmdBench},
+		{"sweep", "run a parameter over a list of values and seeds and print outcomes as CSV", cmdSweep},
+		{"collect", "merge the tables of a sweep split into cluster tasks with sweep -emit-jobs", cmdCollect},
+		{"aggregate", "append sweep tables to Hive-partitioned Parquet datasets for DuckDB", cmdAggregate},
+		{"scan", "bifurcation scan: steady-state populations over a range of one parameter", cmdScan},
+		{"mc", "Monte Carlo probabilities of extinction and coexistence with confidence intervals", cmdMC},
+		{"oceans", "run several worlds coupled by migration channels and print their populations as CSV", cmdOceans},
+		{"replay", "re-simulate the run saved in a checkpoint and draw every frame", cmdReplay},
+		{"serve", "run a simulation in the background and serve its frames over HTTP", cmdServe},
+		{"compare", "report where two -fingerprint files differ, or run two parameter files side by side", cmdCompare},
+		{"diff", "compare two checkpoints or ASCII maps cell by cell", cmdDiff},
+		{"attest", "check the signature of a -sign summary or checkpoint by re-simulating the run", cmdAttest},
+		{"verify", "check the deterministic engine against the serial reference, chronon by chronon", cmdVerify},
+		{"help", "list the commands, or show the flags of one (wator help <command>)", cmdHelp},
+	}
+}
+
+/**
+ * @brief Runs the subcommand named by the first argument.
+ * @param args Arguments without the program name.
+ * @param output Destination of usage and error text.
+ * @return The process exit code.
+ */
+func runCommand(args []string, output io.Writer) int {
+	if len(args) == 0 {
+		return cmdRun(args)
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		return cmdHelp(nil)
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:])
+		}
+	}
+	if _, err := strconv.Atoi(args[0]); err == nil || args[0][0] == '-' {
+		return cmdRun(args) ///< Flags or positional parameters without a command
+	}
+	fmt.Fprintf(output, "Unknown command %q.\n", args[0])
+	printCommands(output)
+	return exitConfigError
+}
+
+/**
+ * @brief The help subcommand: lists the commands, or shows one command's flags.
+ * @param args Optionally the name of a command.
+ * @return The exit code.
+ */
+func cmdHelp(args []string) int {
+	if len(args) > 0 && args[0] != "help" {
+		return runCommand([]string{args[0], "-h"}, os.Stderr)
+	}
+	printCommands(os.Stderr)
+	return exitOK
+}
+
+/**
+ * @brief Prints the command list.
+ * @param w Destination writer.
+ */
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Usage: wator <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-7s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nRun \"wator <command> -h\" for the flags of a command.")
+}
+
+/**
+ * @brief Parses a subcommand's flags and prepares logging and the seed.
+ * @details Reports errors to stderr itself, so callers only need to return the exit code.
+ * @param cf The subcommand's flags.
+ * @param args Arguments after the command name.
+ * @return The configuration with a fixed seed and ok set, or the exit code to return.
+ */
+func setupCommand(cf *configFlags, args []string) (cfg Config, code int, ok bool) {
+	cfg, err := cf.parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return cfg, exitOK, false
+	}
+	if err == nil {
+		err = setupLogger(cfg.LogLevel, cfg.LogJSON)
+	}
+	if err == nil {
+		outputs, err = openSink(cfg.Output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid parameters:\n%v\nRun with -h for usage.\n", err)
+		return cfg, exitConfigError, false
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	return cfg, exitOK, true
+}
+
+/**
+ * @brief Runs a simulation without rendering and summarises it.
+ * @param ctx Context of the run; cancelling it stops between chronons.
+ * @param cfg The configuration; Seed must already be fixed.
+ * @return The summary (with Interrupted set if ctx was cancelled), or an error if the
+ * simulation could not be created.
+ */
+func runHeadless(ctx context.Context, cfg Config) (RunSummary, error) {
+	summary, _, err := runRecorded(ctx, cfg, nil)
+	return summary, err
+}
+
+/**
+ * @brief Runs a simulation without rendering and keeps its population series.
+ * @param ctx Context of the run; cancelling it stops between chronons.
+ * @param cfg The configuration; Seed must already be fixed.
+ * @param attach Called with the simulation before it runs, to register more hooks; may be nil.
+ * @return The summary, the populations of every chronon, or an error if the simulation
+ * could not be created.
+ */
+func runRecorded(ctx context.Context, cfg Config, attach func(*Simulation)) (RunSummary, *PopulationSeries, error) {
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		return RunSummary{}, nil, err
+	}
+	if attach != nil {
+		attach(sim)
+	}
+	var totals StepCounts
+	watch := &ExtinctionWatch{}
+	watch.Add(sim.Population())
+	series := &PopulationSeries{}
+	series.Add(sim.Population())
+	sim.OnStats(func(p Population, report StepReport) { ///< No frame copies: only the counts are needed
+		totals.Add(report.StepCounts)
+		watch.Add(p)
+		series.Add(p)
+	})
+
+	start := time.Now()
+	ran, interrupted := sim.Run(ctx, cfg.Chronons)
+	summary := NewRunSummary(sim.Config(), sim.Snapshot(), totals, watch, ran, time.Since(start), interrupted != nil)
+	summary.Oscillation = AnalyseOscillation(series)
+	return summary, series, nil
+}
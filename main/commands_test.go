@@ -0,0 +1,227 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file commands_test.go
+ * @brief Tests for the subcommands and their helpers.
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnknownCommandIsConfigError(t *testing.T) {
+	var out strings.Builder
+	if code := runCommand([]string{"bnech"}, &out); code != exitConfigError {
+		t.Errorf("exit code %d, want %d", code, exitConfigError)
+	}
+	if !strings.Contains(out.String(), "bench") {
+		t.Errorf("unknown command should list the commands, got %q", out.String())
+	}
+}
+
+func TestConfigFlagsWith(t *testing.T) {
+	cf := newConfigFlags("sweep", "", io.Discard)
+	base, err := cf.parse([]string{"-shark-vision", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := cf.with(map[string]string{"FishBreed": "7"})
+	if err != nil || c.FishBreed != 7 || c.SharkVision != 2 {
+		t.Errorf("with(FishBreed, 7) = FishBreed %d, SharkVision %d, err %v", c.FishBreed, c.SharkVision, err)
+	}
+	if c, err = cf.with(map[string]string{"sharkBreed": "5"}); err != nil || c.SharkBreed != 5 {
+		t.Errorf("with(sharkBreed, 5) = %d, err %v; positional names should ignore case", c.SharkBreed, err)
+	}
+	if c, err = cf.with(map[string]string{"shark-vision": "4"}); err != nil || c.SharkVision != 4 {
+		t.Errorf("with(shark-vision, 4) = %d, err %v", c.SharkVision, err)
+	}
+	if _, err := cf.with(map[string]string{"no-such-param": "1"}); err == nil {
+		t.Error("unknown parameter accepted")
+	}
+	if _, err := cf.with(map[string]string{"GridSize": "0"}); err == nil {
+		t.Error("invalid value accepted")
+	}
+	if *cf.cfg != base {
+		t.Error("with modified the parsed configuration")
+	}
+}
+
+func TestRunSweepWritesOneRowPerRun(t *testing.T) {
+	cf := newConfigFlags("sweep", "", io.Discard)
+	if _, err := cf.parse([]string{"-chronons", "3", "-engine", "moves", "10", "40", "3", "3", "4", "15", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	var configs []Config
+	values := []string{"2", "4"}
+	for _, v := range values {
+		c, err := cf.with(map[string]string{"SharkBreed": v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, c)
+	}
+
+	var out strings.Builder
+	if err := runSweep(context.Background(), "SharkBreed", values, configs, 7, 2, sweepShare{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1+2*2 || rows[0][8] != "fish_period" {
+		t.Fatalf("got %d rows, want header plus 4 runs:\n%s", len(rows), out.String())
+	}
+	if got := rows[4][:4]; strings.Join(got, ",") != "SharkBreed,4,8,3" {
+		t.Errorf("last row starts %v, want SharkBreed,4,8,3", got)
+	}
+}
+
+func TestRunSweepEnsemblesAggregates(t *testing.T) {
+	plot := filepath.Join(t.TempDir(), "sweep.svg")
+	cf := newConfigFlags("sweep", "", io.Discard)
+	if _, err := cf.parse([]string{"-chronons", "3", "-engine", "moves", "-ensemble", "3", "-plot", plot, "10", "40", "3", "3", "4", "15", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	var configs []Config
+	values := []string{"2", "4"}
+	for _, v := range values {
+		c, err := cf.with(map[string]string{"SharkBreed": v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, c)
+	}
+
+	var out strings.Builder
+	if err := runSweepEnsembles(context.Background(), "SharkBreed", values, configs, sweepShare{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1+2*4 {
+		t.Fatalf("got %d rows, want header plus 4 chronons for each of 2 values:\n%s", len(rows), out.String())
+	}
+	if got := strings.Join(rows[8][:4], ","); got != "SharkBreed,4,3,3" {
+		t.Errorf("last row starts %s, want SharkBreed,4,3,3", got)
+	}
+	svg, err := os.ReadFile(plot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(svg), "<polyline"); n != 4 {
+		t.Errorf("plot has %d mean lines, want 2 values x 2 species", n)
+	}
+}
+
+func TestRunScan(t *testing.T) {
+	cf := newConfigFlags("scan", "", io.Discard)
+	if _, err := cf.parse([]string{"-chronons", "6", "-engine", "moves", "10", "40", "3", "3", "4", "15", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	var configs []Config
+	values := []float64{2, 3, 4}
+	for _, v := range values {
+		c, err := cf.with(map[string]string{"SharkBreed": formatValue(v)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, c)
+	}
+
+	var out strings.Builder
+	points, err := runScan(context.Background(), "SharkBreed", values, configs, 7, 2, 3, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1+3 || len(points) != 3 || rows[3][1] != "4" || rows[3][2] != "2" {
+		t.Fatalf("got %d rows, want header plus one row of 2 runs per value:\n%s", len(rows), out.String())
+	}
+	for _, p := range points {
+		if p.FishMin > p.FishMean || p.FishMean > p.FishMax || p.SharkMin > p.SharkMean || p.SharkMean > p.SharkMax {
+			t.Errorf("value %g: mean outside its range: %+v", p.Value, p)
+		}
+	}
+
+	var svg strings.Builder
+	if err := writeScanPlot(&svg, "SharkBreed", points); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(svg.String(), "<circle"); n != 6 {
+		t.Errorf("diagram has %d mean markers, want 3 values x 2 species", n)
+	}
+}
+
+func TestScanThresholds(t *testing.T) {
+	points := []ScanPoint{
+		{Value: 1, Runs: 2, SharkExtinct: 2},
+		{Value: 2, Runs: 2, SharkExtinct: 1}, ///< Half the runs is not most of them
+		{Value: 3, Runs: 2},
+		{Value: 4, Runs: 2, FishExtinct: 2},
+	}
+	got := scanThresholds(points)
+	want := []ScanThreshold{{"fish", 3, 4, true}, {"sharks", 1, 2, false}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseInts(t *testing.T) {
+	if got, err := parseInts("1, 2,8"); err != nil || len(got) != 3 || got[2] != 8 {
+		t.Errorf("parseInts = %v, %v", got, err)
+	}
+	if _, err := parseInts("1,x"); err == nil {
+		t.Error("invalid entry accepted")
+	}
+}
+
+func TestServeFrameJSON(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newServeMux(sim, &WorkerStats{}))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/frame.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var doc frameDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	fish, sharks := sim.Snapshot().Counts()
+	if doc.Fish != fish || doc.Sharks != sharks || len(doc.Rows) != doc.Size {
+		t.Errorf("frame.json = %d fish, %d sharks, %d rows; want %d, %d, %d", doc.Fish, doc.Sharks, len(doc.Rows), fish, sharks, doc.Size)
+	}
+	if n := strings.Count(strings.Join(doc.Rows, ""), "F"); n != fish {
+		t.Errorf("rows hold %d fish, want %d", n, fish)
+	}
+}
@@ -0,0 +1,748 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file config.go
+ * @brief Command-line parsing and validation of simulation parameters.
+ * @details Every parameter is checked before the simulation starts, so mistakes such as
+ * "ten" instead of "10" or more entities than cells are reported with an actionable message
+ * instead of silently running with zero values or hanging during placement.
+ */
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/** Process exit codes. */
+const (
+	exitOK          = 0   ///< Simulation completed
+	exitFailure     = 1   ///< Runtime failure (I/O error, invariant violation)
+	exitExtinct     = 2   ///< Simulation completed, but fish or sharks died out
+	exitConfigError = 3   ///< Invalid command line or parameters
+	exitInterrupted = 130 ///< Stopped early by SIGINT or SIGTERM (128 + SIGINT, as shells report it)
+)
+
+/**
+ * @struct Config
+ * @brief All parameters of a simulation run.
+ */
+type Config struct {
+	NumShark      int     ///< Initial number of sharks
+	NumFish       int     ///< Initial number of fish
+	FishBreed     int     ///< Chronons before a fish can reproduce
+	SharkBreed    int     ///< Chronons before a shark can reproduce
+	StarveEnergy  int     ///< Chronons a shark survives without eating
+	GridSize      int     ///< Grid dimensions (GridSize x GridSize)
+	Threads       int     ///< Number of worker threads
+	FishSpeed     int     ///< Cells a fish may move per chronon
+	SharkSpeed    int     ///< Cells a shark may move per chronon
+	SharkVision   int     ///< Path length within which sharks pursue fish
+	MoveCost      int     ///< Energy a shark spends to move without eating
+	StayCost      int     ///< Energy a shark spends to stay put
+	BirthShare    float64 ///< Fraction of parent energy given to a newborn shark (0 starts it full)
+	CrowdingK     int     ///< Fish neighbours that make a fish crowded (0 disables the rule)
+	CrowdingDeath float64 ///< Chance that a crowded fish dies each chronon
+
+	FishMaturity   int    ///< Chronons a newborn fish stays juvenile (0 disables life stages)
+	JuvenileEnergy int    ///< Energy a shark gains from a juvenile fish (0 feeds it fully)
+	Satiation      int    ///< Chronons a shark skips hunting after eating (0 disables satiation)
+	SatedRest      bool   ///< Sated sharks stay put instead of wandering
+	FoodWeb        string ///< Predation matrix, e.g. "shark eats fish 2" (empty: sharks eat fish and are fully fed)
+
+	TideProb   float64 ///< Chance of a red-tide bloom per chronon (0 disables random blooms)
+	TideSpread float64 ///< Fraction of a neighbour's toxin level a cell takes on
+	TideDecay  float64 ///< Fraction of its toxin level a cell keeps each chronon
+	TideKill   float64 ///< Death chance of a fish under a full-strength tide
+
+	Temperature        string  ///< "gradient" or a temperature map file slowing breeding in cold cells (empty disables)
+	TemperatureEffect  float64 ///< Extra breeding time at temperature 0, as a fraction of the base time
+	TemperatureOverlay bool    ///< Tint empty water by temperature when rendering
+
+	RuleSet        string        ///< Breeding/starvation model ("deterministic" or "stochastic")
+	FishBreedProb  float64       ///< Stochastic fish breeding chance per chronon (0 derives 1/FishBreed)
+	SharkBreedProb float64       ///< Stochastic shark breeding chance per chronon (0 derives 1/SharkBreed)
+	StarveProb     float64       ///< Stochastic starvation chance per chronon (0 derives 1/Starve)
+	Order          string        ///< Update order ("row", "random" or "checkerboard")
+	Engine         string        ///< Concurrency strategy ("sections", "moves", "claims" or "deterministic")
+	Deterministic  bool          ///< Same result for any thread count (selects the deterministic engine)
+	FastForward    int           ///< Quiet chronons before a saturated chunk is skipped (0 disables)
+	Storage        string        ///< Cell storage backend ("entities", "cells", "sparse" or "chunks")
+	Ensemble       int           ///< Number of independent simulations to run and aggregate
+	Plot           string        ///< SVG plot of the ensemble's populations (empty disables)
+	Chronons       int           ///< Number of chronons to simulate
+	AutoThreads    bool          ///< Tune the worker count while running
+	PinWorkers     bool          ///< Run workers on long-lived threads bound to CPUs
+	Paint          bool          ///< Allow painting the grid while the display is paused
+	Console        bool          ///< Accept colon commands querying and changing the running simulation
+	Control        string        ///< Unix socket accepting JSON control commands (empty disables)
+	Tick           time.Duration ///< Wall-clock interval between chronons (0 runs flat out)
+
+	Seed          int64    ///< Random seed (0 picks one from the clock)
+	Check         bool     ///< Validate invariants after every chronon
+	HeatmapPrefix string   ///< Path prefix for heatmap PNGs (empty disables)
+	EventsFile    string   ///< JSON-lines event log (empty disables)
+	CSVFile       string   ///< Per-chronon statistics CSV (empty disables)
+	Database      string   ///< SQLite results database the run is appended to (empty disables)
+	Export        string   ///< Per-cell frame dataset, Parquet or CSV by extension (empty disables)
+	Npy           string   ///< Whole run as a (steps, H, W) uint8 tensor, .npy or .npz (empty disables)
+	Zones         string   ///< Named regions counted separately, e.g. "quadrants" (empty disables)
+	ZoneCSV       string   ///< Per-chronon, per-zone populations CSV (empty disables)
+	Fingerprint   string   ///< Per-chronon grid hashes (empty disables)
+	MaxMemory     ByteSize ///< Memory cap; nearing it sheds history and event lines, exceeding it stops the run (0 disables)
+	Publish       string   ///< NATS or MQTT URL to stream stats and events to (empty disables)
+	Pipe          bool     ///< Drive the simulation through the JSON protocol on stdin/stdout
+	SharedFrames  string   ///< Memory-mapped file every frame is published to (empty disables)
+	SummaryJSON   string   ///< Final JSON summary ("-" for stdout, empty disables)
+	FitLV         bool     ///< Fit the populations to the Lotka–Volterra equations after the run
+	Checkpoint    string   ///< Checkpoint written when the run ends or is interrupted (empty disables)
+	Triggers      string   ///< Conditions that save a screenshot and checkpoint when they become true, separated by semicolons (empty disables)
+	TriggerPrefix string   ///< Path prefix of the files saved by triggers
+	Notify        string   ///< Notifications on extinctions and triggers: bell, desktop or both, comma-separated (empty disables)
+	SignKey       string   ///< File holding the HMAC key that signs the summary and checkpoint (empty disables)
+	GridFile      string   ///< ASCII map to start from instead of random placement (empty disables)
+	Resume        string   ///< Checkpoint to continue from, inheriting its rules and seed (empty disables)
+	Tag           float64  ///< Fraction of the starting fish and sharks tagged with a lineage (0 disables)
+	Lineages      int      ///< Number of lineages the tagged animals are divided among
+	Script        string   ///< Scenario script of timed events (empty disables)
+	Behaviour     string   ///< Starlark script deciding where entities move (empty disables)
+	Plugins       string   ///< WebAssembly plugins deciding where entities move, e.g. "shark=hunter" (empty disables)
+	PluginDir     string   ///< Directory the plugins are read from
+	Theme         string   ///< Terminal rendering theme
+	Style         string   ///< Per-species symbols, colours and priorities, e.g. "fish: fg=blue" (empty keeps the defaults)
+	Renderer      string   ///< Grid renderer: text, halfblock or braille
+	Diff          bool     ///< Redraw only changed cells, in place
+	Viewport      int      ///< Side of the scrollable window in shown cells (0 shows the whole grid)
+	Zoom          int      ///< World cells per shown cell in the window, per side
+	Follow        string   ///< Species the window follows: shark, fish or empty
+	Origin        string   ///< World cell drawn at the top-left corner, "x,y" (empty draws (0,0) first)
+	Center        bool     ///< Re-centre the drawn world on the centroid of the animals every frame
+	History       int      ///< Recent frames kept for rewinding the display (0 disables)
+	Inspect       bool     ///< Show an entity inspection panel beside the grid
+	Accessible    bool     ///< Describe each chronon in a line of text instead of drawing the grid
+	LogLevel      string   ///< Minimum log level
+	LogJSON       bool     ///< Emit JSON log records
+	OTelEndpoint  string   ///< OTLP/HTTP collector for chronon spans (empty disables)
+	PprofAddr     string   ///< Listen address for pprof and /metrics (empty disables)
+	TraceFile     string   ///< Runtime trace output (empty disables)
+	Output        string   ///< Directory or s3:// or gs:// URL that output files are written under (empty: as given)
+}
+
+/**
+ * @brief Returns the default parameters used when no positional arguments are given.
+ */
+func defaultConfig() Config {
+	return Config{
+		NumShark:          100, // Initial number of sharks
+		NumFish:           100, // Initial number of fish
+		FishBreed:         3,   // Fish breed after 3 chronons
+		SharkBreed:        3,   // Sharks breed after 3 chronons
+		StarveEnergy:      4,   // Sharks die if they don’t eat within 4 chronons
+		GridSize:          100, // Grid size (100x100 by default)
+		Threads:           10,  // Default number of threads for concurrency
+		FishSpeed:         1,
+		SharkSpeed:        1,
+		SharkVision:       1,
+		MoveCost:          1,
+		StayCost:          1,
+		CrowdingDeath:     0.1,
+		TideSpread:        0.8,
+		TideDecay:         0.95,
+		TideKill:          0.2,
+		TemperatureEffect: 1,
+		RuleSet:           RuleSetDeterministic,
+		Order:             OrderRowMajor,
+		Engine:            "sections",
+		Storage:           "entities",
+		Ensemble:          1,
+		Chronons:          50,
+		PluginDir:         "plugins",
+		TriggerPrefix:     "trigger",
+		Lineages:          1,
+		Theme:             "ansi",
+		Renderer:          "text",
+		Zoom:              1,
+		LogLevel:          "info",
+	}
+}
+
+/** Names of the positional parameters, in command-line order. */
+var positionalNames = []string{"NumShark", "NumFish", "FishBreed", "SharkBreed", "Starve", "GridSize", "Threads"}
+
+/**
+ * @brief Parses and validates the command line of the run subcommand.
+ * @param args Arguments without the program or subcommand name.
+ * @param output Destination for usage text.
+ * @return The validated configuration; flag.ErrHelp if help was requested; otherwise an error
+ * describing every problem found.
+ */
+func parseConfig(args []string, output io.Writer) (Config, error) {
+	return newConfigFlags("run", "", output).parse(args)
+}
+
+/**
+ * @struct configFlags
+ * @brief The simulation flags shared by every subcommand, bound to one Config.
+ * @details Subcommands add their own flags to fs before calling parse.
+ */
+type configFlags struct {
+	fs     *flag.FlagSet
+	cfg    *Config     ///< Filled in by the flags
+	colour colourFlags ///< -no-color and -force-color, applied after the other flags
+	set    *string     ///< The -set pairs, applied after the other flags
+}
+
+/**
+ * @brief Registers the simulation flags on a new flag set.
+ * @param name Subcommand name, shown in the usage line.
+ * @param about One-line description of the subcommand, printed above the flags (may be empty).
+ * @param output Destination for usage text.
+ * @return The flag set, ready for subcommand-specific flags.
+ */
+func newConfigFlags(name, about string, output io.Writer) *configFlags {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("wator "+name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.IntVar(&cfg.Chronons, "chronons", cfg.Chronons, "`number` of chronons to simulate")
+	fs.BoolVar(&cfg.Paint, "paint", false, "edit the grid while paused: p pauses, I/J/K/L move the cursor, 1/2/0 paint fish, sharks or empty cells, b changes the brush size (implies -inspect)")
+	fs.StringVar(&cfg.Control, "control", "", "listen on the Unix socket `path` for newline-delimited JSON commands: pause, resume, step, stats, snapshot and shutdown")
+	fs.BoolVar(&cfg.Console, "console", false, "accept console commands on control lines starting with a colon, e.g. \":count sharks in (0,0)-(50,50)\", \":find entity 12\", \":set param FishBreed 4\" or \":help\"; answers go to stderr")
+	fs.BoolVar(&cfg.PinWorkers, "pin-workers", false, "keep each worker on its own OS thread bound to one CPU, so its rows stay in that CPU's cache (Linux; helps large grids on many-core machines)")
+	fs.BoolVar(&cfg.AutoThreads, "auto-threads", false, "try several worker counts during the run and keep the fastest for this machine (runs are then not reproducible from -seed)")
+	fs.DurationVar(&cfg.Tick, "tick", 0, "pace the run to one chronon per `interval` of wall-clock time, e.g. 100ms (0 runs as fast as possible)")
+	fs.StringVar(&cfg.Engine, "engine", cfg.Engine, "concurrency `strategy`: "+strings.Join(engineNames(), " or "))
+	fs.BoolVar(&cfg.Deterministic, "deterministic", false, "bit-identical results for any -threads value (same as -engine deterministic)")
+	fs.IntVar(&cfg.FishSpeed, "fish-speed", cfg.FishSpeed, "`cells` a fish may move per chronon")
+	fs.IntVar(&cfg.SharkSpeed, "shark-speed", cfg.SharkSpeed, "`cells` a shark may move per chronon; it stops early to eat")
+	fs.IntVar(&cfg.SharkVision, "shark-vision", cfg.SharkVision, "sharks step toward the nearest fish within `n` cells (1 sees only adjacent fish)")
+	fs.IntVar(&cfg.MoveCost, "move-cost", cfg.MoveCost, "`energy` a shark spends to move without eating")
+	fs.IntVar(&cfg.StayCost, "stay-cost", cfg.StayCost, "`energy` a shark spends to stay put")
+	fs.Float64Var(&cfg.BirthShare, "birth-share", 0, "`fraction` of its parent's energy a newborn shark takes (0 starts newborns at Starve energy)")
+	fs.IntVar(&cfg.CrowdingK, "crowding-k", 0, "fish with at least `k` fish among their 8 neighbours may die of crowding (0 disables)")
+	fs.Float64Var(&cfg.CrowdingDeath, "crowding-death", cfg.CrowdingDeath, "per-chronon death `probability` of a crowded fish")
+	fs.IntVar(&cfg.FishMaturity, "fish-maturity", 0, "newborn fish are juveniles that cannot breed for `m` chronons (0 disables life stages)")
+	fs.IntVar(&cfg.JuvenileEnergy, "juvenile-energy", 0, "with -fish-maturity, a shark that eats a juvenile gains only `energy` instead of being fully fed (0 feeds it fully)")
+	fs.IntVar(&cfg.Satiation, "satiation", 0, "a shark that has eaten skips hunting for `r` chronons, wandering to empty cells instead (0 disables)")
+	fs.BoolVar(&cfg.SatedRest, "sated-rest", false, "with -satiation, sated sharks stay put instead of wandering")
+	fs.StringVar(&cfg.FoodWeb, "food-web", "", "who eats whom: `entries` \"<predator> eats <prey> [energy]\" separated by semicolons, or none (default: sharks eat fish and are fully fed)")
+	fs.Float64Var(&cfg.TideProb, "tide-prob", 0, "per-chronon `probability` that a red tide blooms at a random cell, spreading and killing fish it covers (0 disables)")
+	fs.Float64Var(&cfg.TideSpread, "tide-spread", cfg.TideSpread, "`fraction` of its strongest neighbour's toxin level a cell takes on each chronon")
+	fs.Float64Var(&cfg.TideDecay, "tide-decay", cfg.TideDecay, "`fraction` of its toxin level a cell keeps each chronon")
+	fs.Float64Var(&cfg.TideKill, "tide-kill", cfg.TideKill, "per-chronon death `probability` of a fish under a full-strength tide, scaled by the toxin level")
+	fs.StringVar(&cfg.Temperature, "temperature", "", "per-cell `field` slowing breeding in cold water: gradient (warm middle rows, cold first and last) or a file of numbers from 0 (cold) to 1 (warm), one row per line")
+	fs.Float64Var(&cfg.TemperatureEffect, "temperature-effect", cfg.TemperatureEffect, "with -temperature, breeding at temperature t takes 1 + `s`*(1-t) times as long")
+	fs.BoolVar(&cfg.TemperatureOverlay, "temperature-overlay", false, "with -temperature, tint empty water from blue (cold) to teal (warm) in the colour themes and pixel renderers")
+	fs.StringVar(&cfg.RuleSet, "ruleset", cfg.RuleSet, "breeding and starvation `model`: "+strings.Join(ruleSetNames, " or "))
+	fs.Float64Var(&cfg.FishBreedProb, "fish-breed-prob", 0, "stochastic rules: per-chronon fish breeding `probability` (default 1/FishBreed)")
+	fs.Float64Var(&cfg.SharkBreedProb, "shark-breed-prob", 0, "stochastic rules: per-chronon shark breeding `probability` (default 1/SharkBreed)")
+	fs.Float64Var(&cfg.StarveProb, "starve-prob", 0, "stochastic rules: per-chronon shark starvation `probability` (default 1/Starve)")
+	fs.StringVar(&cfg.Order, "order", cfg.Order, "`order` in which entities are updated each chronon: "+strings.Join(orderNames, " or "))
+	fs.StringVar(&cfg.Storage, "storage", cfg.Storage, "cell storage `backend`: "+strings.Join(storageNames(), " or "))
+	fs.IntVar(&cfg.FastForward, "fast-forward", 0, "experimental, with -storage chunks: skip chunks packed with fish that have been sealed off from empty cells and sharks for `k` chronons (0 disables)")
+	fs.IntVar(&cfg.Ensemble, "ensemble", cfg.Ensemble, "run `n` simulations with seeds seed..seed+n-1 and print per-chronon population mean and stddev as CSV")
+	fs.StringVar(&cfg.Plot, "plot", "", "with -ensemble, also draw the mean populations and their 95% confidence bands to the SVG `file`")
+	fs.StringVar(&cfg.HeatmapPrefix, "heatmap", "", "write occupancy heatmaps to `prefix`-fish.png and prefix-sharks.png")
+	fs.StringVar(&cfg.EventsFile, "events", "", "write events such as shark starvation to `file` as JSON lines")
+	fs.StringVar(&cfg.CSVFile, "csv", "", "write per-chronon population statistics to `file` as CSV")
+	fs.StringVar(&cfg.Database, "db", "", "append the run, its per-chronon statistics and its events to the SQLite `file` (tables runs, steps and events)")
+	fs.StringVar(&cfg.Npy, "npy", "", "write the whole run as a (steps, H, W) uint8 NumPy tensor of species codes to `file` (.npy, or .npz for a compressed archive)")
+	fs.StringVar(&cfg.Export, "export", "", "write every fish and shark of every chronon (chronon, x, y, species, energy, age) to `file`: Parquet if it ends in .parquet, otherwise CSV")
+	fs.StringVar(&cfg.Zones, "zones", "", "count populations separately in the `zones` \"quadrants\" or name=(x1,y1)-(x2,y2), separated by semicolons")
+	fs.Var(&cfg.MaxMemory, "max-memory", "cap memory at `size` (e.g. 2GB): near it, drop -history frames and -events lines; above it, stop the run")
+	fs.StringVar(&cfg.Fingerprint, "fingerprint", "", "write a hash of the grid after every chronon to `file`; compare two such files with \"wator compare\"")
+	fs.StringVar(&cfg.ZoneCSV, "zone-csv", "", "write every zone's populations after every chronon to `file` as CSV")
+	fs.StringVar(&cfg.GridFile, "grid", "", "start from the ASCII map in `file` (F fish, S shark, . empty) instead of random placement")
+	fs.Float64Var(&cfg.Tag, "tag", 0, "tag this `fraction` of the starting fish and sharks with a lineage their offspring inherit, drawn and counted separately (0 disables)")
+	fs.IntVar(&cfg.Lineages, "lineages", cfg.Lineages, fmt.Sprintf("with -tag, divide the tagged animals among `n` lineages (at most %d)", maxLineages))
+	fs.StringVar(&cfg.Resume, "resume", "", "continue from the checkpoint in `file` with its rules, engine and seed; flags and -set given here change them")
+	set := fs.String("set", "", "change `parameters` given as name=value pairs separated by commas, with positional or flag names, e.g. sharkBreed=6,shark-vision=2")
+	fs.StringVar(&cfg.Script, "script", "", "apply the timed events in `file`, e.g. \"at chronon 100 add 50 sharks in (10,10)-(30,30)\" or \"at chronon 200 set FishBreed=5\"")
+	fs.StringVar(&cfg.Behaviour, "behaviour", "", "let the Starlark functions fish(n) and shark(n) in `file` decide where each entity moves")
+	fs.StringVar(&cfg.Plugins, "plugin", "", "let WebAssembly `plugins` from -plugin-dir decide where entities move, e.g. shark=hunter or fish=shy,shark=hunter")
+	fs.StringVar(&cfg.PluginDir, "plugin-dir", cfg.PluginDir, "`directory` holding <name>.wasm plugins")
+	fs.StringVar(&cfg.Publish, "publish", "", "stream per-chronon stats and events to `url`: nats://host:4222/prefix or mqtt://host:1883/prefix")
+	fs.BoolVar(&cfg.Pipe, "pipe", false, "instead of running, read JSON commands (step, stats, frame, config, reset, quit) from stdin and reply with JSON lines on stdout")
+	fs.StringVar(&cfg.SharedFrames, "shm", "", "publish every frame to the memory-mapped `file` (e.g. /dev/shm/wator) for external visualisers")
+	fs.StringVar(&cfg.SummaryJSON, "summary-json", "", "write a JSON summary of the run (final counts, extinction, throughput, seed, parameters) to `file`, or - for stdout")
+	fs.BoolVar(&cfg.FitLV, "fit-lv", false, "after the run, fit the populations to the Lotka-Volterra equations and report the parameters and residuals")
+	fs.StringVar(&cfg.Checkpoint, "checkpoint", "", "save the final state to `file` when the run ends or is interrupted")
+	fs.StringVar(&cfg.Triggers, "trigger", "", "when a `condition` on chronon, fish, sharks, empty or size becomes true, save a PNG and a checkpoint; e.g. \"fish < 100; sharks extinct; chronon % 500 == 0\"")
+	fs.StringVar(&cfg.Notify, "notify", "", "announce extinctions and -trigger firings with `notifiers` bell (terminal bell), desktop (notify-send or osascript) or bell,desktop")
+	fs.StringVar(&cfg.TriggerPrefix, "trigger-prefix", cfg.TriggerPrefix, "path `prefix` of the files saved by -trigger, followed by -<chronon>.png and .wtr")
+	fs.StringVar(&cfg.Output, "output", "", "write output files (-csv, -events, -checkpoint, -heatmap, -trigger, -summary-json, ...) under `dest`: a directory, s3://bucket/prefix or gs://bucket/prefix")
+	fs.StringVar(&cfg.SignKey, "sign", "", "sign -summary-json and -checkpoint with the HMAC key in `file`, for checking with wator attest")
+	colour := addRenderFlags(fs, &cfg)
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "minimum log `level`: debug, info or warn")
+	fs.BoolVar(&cfg.LogJSON, "log-json", false, "emit log records as JSON")
+	fs.StringVar(&cfg.PprofAddr, "pprof", "", "serve net/http/pprof and /metrics on `addr` (e.g. :6060)")
+	fs.StringVar(&cfg.OTelEndpoint, "otel-endpoint", "", "export a span per chronon phase to the OTLP/HTTP collector at `url` (e.g. http://localhost:4318)")
+	fs.StringVar(&cfg.TraceFile, "trace", "", "write a runtime execution trace to `file`")
+	fs.BoolVar(&cfg.Check, "check", false, "validate simulation invariants after every chronon and abort on the first violation")
+	fs.Int64Var(&cfg.Seed, "seed", 0, "random `seed` for reproducible runs (0 picks one from the clock)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: wator %s [flags] [<%s>]\n", name, strings.Join(positionalNames, "> <"))
+		if about != "" {
+			fmt.Fprintf(fs.Output(), "%s\n", about)
+		}
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "Every parameter can also be set by an environment variable named after it, e.g. %s=200 or %s=2; command-line values take precedence.\n",
+			envName("GridSize"), envName("shark-vision"))
+	}
+	return &configFlags{fs: fs, cfg: &cfg, colour: colour, set: set}
+}
+
+/**
+ * @brief Registers the flags that control how frames are drawn.
+ * @param fs The flag set.
+ * @param cfg The configuration the flags write into.
+ * @return The -no-color and -force-color flags, which parse applies after the other flags.
+ */
+func addRenderFlags(fs *flag.FlagSet, cfg *Config) colourFlags {
+	fs.StringVar(&cfg.Theme, "theme", cfg.Theme, "terminal rendering `theme`: "+strings.Join(themeNames(), ", "))
+	fs.StringVar(&cfg.Style, "style", "", "per-species `styles` \"<water|fish|shark>: symbol=C fg=COLOUR bg=COLOUR priority=N\" separated by semicolons; colours are names such as red or bright-blue, or #rrggbb")
+	fs.StringVar(&cfg.Renderer, "renderer", cfg.Renderer, "grid `renderer`: "+strings.Join(rendererNames, ", ")+" (halfblock and braille downsample large grids to the terminal width)")
+	fs.BoolVar(&cfg.Diff, "diff", false, "redraw the grid in place, emitting only cells that changed (text renderer on a terminal)")
+	fs.IntVar(&cfg.Viewport, "viewport", 0, "show a scrollable `n`x`n` window instead of the whole grid; type w/a/s/d to scroll, +/- to zoom, f/g to follow a shark/fish and u to stop, then Enter")
+	fs.IntVar(&cfg.Zoom, "zoom", cfg.Zoom, "initial viewport zoom: world cells per shown cell, per side")
+	fs.StringVar(&cfg.Follow, "follow", "", "start the viewport following a `species`: shark or fish")
+	fs.StringVar(&cfg.Origin, "origin", "", "draw world cell `x,y` at the top-left corner, wrapping the rest around; type w/a/s/d to shift, o to toggle -center and O to reset, then Enter")
+	fs.BoolVar(&cfg.Center, "center", false, "re-centre the drawn world on the centroid of the fish and sharks every frame, so groups do not drift across the edges (display only)")
+	fs.BoolVar(&cfg.Inspect, "inspect", false, "show a panel with the entity under a cursor: type I/J/K/L to move the cursor, c to select the entity and C to clear, then Enter")
+	fs.BoolVar(&cfg.Accessible, "accessible", false, "describe each chronon in one line of plain text (counts, densest -zones or quadrants, notable events) instead of drawing the grid, for screen readers and logs")
+	fs.IntVar(&cfg.History, "history", 0, "keep the last `k` frames for rewinding; type p to pause or resume, [ and ] to step back and forward while paused, then Enter")
+	return addColourFlags(fs)
+}
+
+/**
+ * @brief Parses the arguments, applies the positional parameters, and validates the result.
+ * @param args Arguments without the program or subcommand name.
+ * @return The validated configuration, or flag.ErrHelp or an error describing every problem.
+ */
+func (cf *configFlags) parse(args []string) (Config, error) {
+	if err := cf.fs.Parse(args); err != nil {
+		return *cf.cfg, err
+	}
+	explicit := map[string]bool{}
+	cf.fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if err := cf.applyEnv(explicit); err != nil {
+		return *cf.cfg, err
+	}
+	if cf.cfg.Resume != "" {
+		cp, err := readCheckpointFile(cf.cfg.Resume)
+		if err != nil {
+			return *cf.cfg, fmt.Errorf("-resume: %w", err)
+		}
+		cf.cfg.inherit(cp.Config)
+		cf.fs.Parse(args)     ///< Flags given explicitly override the inherited values
+		cf.applyEnv(explicit) ///< And so do the environment variables, already checked above
+	}
+
+	if err := cf.cfg.applyPositional(cf.fs.Args()); err != nil {
+		return *cf.cfg, err
+	}
+	if *cf.set != "" {
+		params := map[string]string{}
+		for _, pair := range strings.Split(*cf.set, ",") {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return *cf.cfg, fmt.Errorf("-set: want name=value, got %q", pair)
+			}
+			params[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		if err := cf.apply(params); err != nil {
+			return *cf.cfg, fmt.Errorf("-set: %w", err)
+		}
+	}
+
+	if err := cf.applyShorthands(); err != nil {
+		return *cf.cfg, err
+	}
+	return *cf.cfg, cf.cfg.Validate()
+}
+
+/** Prefix of the environment variables that set parameters, e.g. WATOR_GRID_SIZE or WATOR_SHARK_VISION. */
+const envPrefix = "WATOR_"
+
+/**
+ * @brief Returns the environment variable that sets a parameter.
+ * @param name A positional name such as "GridSize" or a flag name such as "shark-vision".
+ * @return The variable name, e.g. "WATOR_GRID_SIZE" or "WATOR_SHARK_VISION".
+ */
+func envName(name string) string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for i, r := range name {
+		switch {
+		case r == '-':
+			b.WriteByte('_')
+		case unicode.IsUpper(r) && i > 0:
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+/**
+ * @brief Sets the parameters given as WATOR_* environment variables, so container deployments
+ * need no argument lists.
+ * @details Every positional parameter and every flag of the subcommand has a variable (see envName);
+ * values are checked like the command-line ones. Flags given on the command line win, as do
+ * positional parameters and -set, which are applied afterwards.
+ * @param explicit Names of the flags given on the command line, which are left alone.
+ * @return An error naming every variable with an invalid value.
+ */
+func (cf *configFlags) applyEnv(explicit map[string]bool) error {
+	names := slices.Clone(positionalNames)
+	cf.fs.VisitAll(func(f *flag.Flag) {
+		if !explicit[f.Name] {
+			names = append(names, f.Name)
+		}
+	})
+	var errs []error
+	for _, name := range names {
+		value, ok := os.LookupEnv(envName(name))
+		if !ok {
+			continue
+		}
+		if err := cf.apply(map[string]string{name: value}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", envName(name), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/**
+ * @brief Returns a copy of the parsed configuration with some parameters changed.
+ * @details A parameter may be a positional name in any case (e.g. "FishBreed" or "fishBreed")
+ * or a flag name without the dash (e.g. "shark-vision"). The parsed configuration itself is left unchanged.
+ * @param params New values by parameter name, as they would be written on the command line.
+ * @return The validated configuration, or an error if a name or value is invalid.
+ */
+func (cf *configFlags) with(params map[string]string) (Config, error) {
+	saved := *cf.cfg
+	defer func() { *cf.cfg = saved }()
+
+	if err := cf.apply(params); err != nil {
+		return saved, err
+	}
+	if err := cf.applyShorthands(); err != nil {
+		return saved, err
+	}
+	return *cf.cfg, cf.cfg.Validate()
+}
+
+/**
+ * @brief Sets parameters by name on the bound configuration; see with for the names accepted.
+ * @return An error if a name or value is invalid.
+ */
+func (cf *configFlags) apply(params map[string]string) error {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	slices.Sort(names) ///< Report errors in a stable order
+	for _, name := range names {
+		value := params[name]
+		if i := slices.IndexFunc(positionalNames, func(p string) bool { return strings.EqualFold(p, name) }); i >= 0 {
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s must be a whole number, got %q", name, value)
+			}
+			*cf.cfg.positionalTargets()[i] = v
+		} else if err := cf.fs.Set(name, value); err != nil {
+			return fmt.Errorf("-%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+/**
+ * @brief Applies the flags that stand for another setting: -no-color, -force-color and -deterministic.
+ * @return An error if -deterministic is combined with a different -engine.
+ */
+func (cf *configFlags) applyShorthands() error {
+	cf.colour.apply(cf.cfg)
+	if cf.cfg.Deterministic {
+		if e := cf.cfg.Engine; e != defaultConfig().Engine && e != "deterministic" {
+			return fmt.Errorf("-deterministic selects the deterministic engine and cannot be combined with -engine %s", e)
+		}
+		cf.cfg.Engine = "deterministic"
+	}
+	return nil
+}
+
+/**
+ * @brief Fills the core parameters from the seven positional arguments.
+ * @param args The positional arguments; must be empty or exactly seven values.
+ * @return An error naming every argument that is not a whole number.
+ */
+func (c *Config) applyPositional(args []string) error {
+	if len(args) == 0 {
+		return nil ///< Keep the defaults
+	}
+	if len(args) != len(positionalNames) {
+		return fmt.Errorf("expected %d positional parameters (%s), got %d",
+			len(positionalNames), strings.Join(positionalNames, " "), len(args))
+	}
+
+	targets := c.positionalTargets()
+	var errs []error
+	for i, arg := range args {
+		v, err := strconv.Atoi(arg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s must be a whole number, got %q", positionalNames[i], arg))
+			continue
+		}
+		*targets[i] = v
+	}
+	return errors.Join(errs...)
+}
+
+/**
+ * @brief Returns pointers to the fields set by the positional parameters, in positionalNames order.
+ */
+func (c *Config) positionalTargets() []*int {
+	return []*int{&c.NumShark, &c.NumFish, &c.FishBreed, &c.SharkBreed, &c.StarveEnergy, &c.GridSize, &c.Threads}
+}
+
+/**
+ * @brief Returns the rule parameters of the configuration.
+ * @details Unset stochastic probabilities are derived from the matching threshold, so both rule
+ * sets have the same mean breeding interval and hungry lifetime.
+ */
+func (c Config) Rules() Rules {
+	orInverse := func(p float64, threshold int) float64 {
+		if p == 0 && threshold > 0 {
+			return 1 / float64(threshold)
+		}
+		return p
+	}
+	web, _ := parseFoodWeb(c.FoodWeb) ///< Validated
+	return Rules{FishBreed: c.FishBreed, SharkBreed: c.SharkBreed, StarveEnergy: c.StarveEnergy,
+		FishSpeed: c.FishSpeed, SharkSpeed: c.SharkSpeed, SharkVision: c.SharkVision,
+		MoveCost: c.MoveCost, StayCost: c.StayCost, BirthShare: c.BirthShare,
+		CrowdingK: c.CrowdingK, CrowdingDeath: c.CrowdingDeath,
+		FishMaturity: c.FishMaturity, JuvenileEnergy: c.JuvenileEnergy,
+		Satiation: c.Satiation, SatedRest: c.SatedRest, FoodWeb: web,
+		TideProb: c.TideProb, TideSpread: c.TideSpread, TideDecay: c.TideDecay, TideKill: c.TideKill,
+		TemperatureEffect: c.TemperatureEffect,
+		RuleSet:           c.RuleSet,
+		FishBreedProb:     orInverse(c.FishBreedProb, c.FishBreed),
+		SharkBreedProb:    orInverse(c.SharkBreedProb, c.SharkBreed),
+		StarveProb:        orInverse(c.StarveProb, c.StarveEnergy),
+		Order:             c.Order}
+}
+
+/**
+ * @brief Rejects parameter combinations the simulation cannot run.
+ * @return An error describing every problem found, or nil.
+ */
+func (c Config) Validate() error {
+	var errs []error
+	atLeast := func(name string, v, least int) {
+		if v < least {
+			errs = append(errs, fmt.Errorf("%s must be at least %d, got %d", name, least, v))
+		}
+	}
+
+	atLeast("NumShark", c.NumShark, 0)
+	atLeast("NumFish", c.NumFish, 0)
+	atLeast("FishBreed", c.FishBreed, 1)
+	atLeast("SharkBreed", c.SharkBreed, 1)
+	atLeast("Starve", c.StarveEnergy, 1)
+	atLeast("GridSize", c.GridSize, 1)
+	atLeast("Threads", c.Threads, 1)
+	atLeast("-ensemble", c.Ensemble, 1)
+	atLeast("-chronons", c.Chronons, 0)
+	if c.Tick < 0 {
+		errs = append(errs, fmt.Errorf("-tick must not be negative, got %v", c.Tick))
+	}
+	atLeast("-fish-speed", c.FishSpeed, 1)
+	atLeast("-shark-speed", c.SharkSpeed, 1)
+	atLeast("-shark-vision", c.SharkVision, 1)
+	atLeast("-move-cost", c.MoveCost, 1)
+	atLeast("-stay-cost", c.StayCost, 1)
+	atLeast("-crowding-k", c.CrowdingK, 0)
+	atLeast("-fish-maturity", c.FishMaturity, 0)
+	atLeast("-juvenile-energy", c.JuvenileEnergy, 0)
+	if c.JuvenileEnergy > 0 && c.FishMaturity == 0 {
+		errs = append(errs, errors.New("-juvenile-energy needs -fish-maturity"))
+	}
+	atLeast("-satiation", c.Satiation, 0)
+	if c.SatedRest && c.Satiation == 0 {
+		errs = append(errs, errors.New("-sated-rest needs -satiation"))
+	}
+	atLeast("-viewport", c.Viewport, 0)
+	atLeast("-history", c.History, 0)
+	atLeast("-zoom", c.Zoom, 1)
+	if c.CrowdingK > len(mooreOffsets) {
+		errs = append(errs, fmt.Errorf("-crowding-k must be at most %d (the number of neighbours), got %d", len(mooreOffsets), c.CrowdingK))
+	}
+
+	if c.Plot != "" && c.Ensemble < 2 {
+		errs = append(errs, errors.New("-plot draws ensemble statistics and needs -ensemble of at least 2"))
+	}
+	if c.Ensemble > 1 && c.MaxMemory > 0 {
+		errs = append(errs, errors.New("-max-memory applies to single runs and cannot be combined with -ensemble"))
+	}
+	if c.Ensemble > 1 && c.Pipe {
+		errs = append(errs, errors.New("-ensemble cannot be combined with -pipe"))
+	}
+	if c.Control != "" && (c.Ensemble > 1 || c.Pipe) {
+		errs = append(errs, errors.New("-control cannot be combined with -ensemble or -pipe"))
+	}
+	if c.Ensemble > 1 && (c.HeatmapPrefix != "" || c.EventsFile != "" || c.CSVFile != "" || c.Database != "" || c.Checkpoint != "" || c.SummaryJSON != "" || c.SharedFrames != "" || c.Publish != "" || c.Check || c.FitLV || c.Zones != "" || c.Fingerprint != "" || c.Export != "" || c.Npy != "" || c.Triggers != "" || c.Notify != "") {
+		errs = append(errs, errors.New("-ensemble cannot be combined with -heatmap, -events, -csv, -db, -checkpoint, -summary-json, -shm, -publish, -check, -fit-lv, -zones, -fingerprint, -export, -npy, -trigger or -notify, which describe a single run"))
+	}
+	if c.Triggers != "" {
+		if _, err := parseTriggers(c.Triggers); err != nil {
+			errs = append(errs, fmt.Errorf("-trigger: %w", err))
+		}
+	}
+	if _, err := parseNotify(c.Notify); err != nil {
+		errs = append(errs, fmt.Errorf("-notify: %w", err))
+	}
+
+	if c.SignKey != "" && c.SummaryJSON == "" && c.Checkpoint == "" {
+		errs = append(errs, errors.New("-sign needs -summary-json or -checkpoint to sign"))
+	}
+	if c.SignKey != "" && (c.AutoThreads || c.Paint || c.Console || (c.Threads > 1 && c.Engine != "deterministic" && c.Engine != "serial")) {
+		errs = append(errs, errors.New("-sign needs a run that is reproducible from its seed (-engine deterministic or serial, or -threads 1) without -auto-threads, -paint or -console"))
+	}
+
+	if c.GridFile != "" && c.Resume != "" {
+		errs = append(errs, errors.New("-grid and -resume both give the starting world; use one"))
+	}
+	if c.GridFile == "" && c.Resume == "" && c.GridSize > 0 && c.NumShark >= 0 && c.NumFish >= 0 {
+		if cells := c.GridSize * c.GridSize; c.NumShark+c.NumFish > cells {
+			errs = append(errs, fmt.Errorf("NumShark + NumFish = %d does not fit in a %dx%d grid (%d cells); increase GridSize or reduce the populations",
+				c.NumShark+c.NumFish, c.GridSize, c.GridSize, cells))
+		}
+	}
+
+	if _, err := engineByName(c.Engine); err != nil {
+		errs = append(errs, fmt.Errorf("-engine: %w", err))
+	}
+
+	if _, err := themeByName(c.Theme); err != nil {
+		errs = append(errs, fmt.Errorf("-theme: %w", err))
+	}
+	if _, err := parseStyles(c.Style); err != nil {
+		errs = append(errs, fmt.Errorf("-style: %w", err))
+	}
+
+	if _, err := parseFoodWeb(c.FoodWeb); err != nil {
+		errs = append(errs, fmt.Errorf("-food-web: %w", err))
+	}
+	if c.Follow != "" && c.Follow != "shark" && c.Follow != "fish" {
+		errs = append(errs, fmt.Errorf("-follow: unknown species %q (want shark or fish)", c.Follow))
+	}
+	if c.Origin != "" {
+		if _, _, err := parseOrigin(c.Origin); err != nil {
+			errs = append(errs, fmt.Errorf("-origin: %w", err))
+		}
+	}
+	if c.Origin != "" && c.Center {
+		errs = append(errs, errors.New("-origin and -center both place the drawn world; use one"))
+	}
+	if (c.Origin != "" || c.Center) && c.Viewport > 0 {
+		errs = append(errs, errors.New("-origin and -center cannot be combined with -viewport, which has its own camera"))
+	}
+
+	if !slices.Contains(rendererNames, c.Renderer) {
+		errs = append(errs, fmt.Errorf("-renderer: unknown renderer %q (want %s)", c.Renderer, strings.Join(rendererNames, ", ")))
+	} else if c.Diff && c.Renderer != "text" {
+		errs = append(errs, fmt.Errorf("-diff works only with -renderer text, got %q", c.Renderer))
+	}
+	if c.Diff && (c.Inspect || c.Paint) {
+		errs = append(errs, errors.New("-inspect and -paint cannot be combined with -diff, which redraws cells in place"))
+	}
+	if c.Accessible && (c.Renderer != "text" || c.Diff || c.Viewport > 0 || c.Origin != "" || c.Center || c.Inspect || c.Paint) {
+		errs = append(errs, errors.New("-accessible draws no grid and cannot be combined with -renderer, -diff, -viewport, -origin, -center, -inspect or -paint"))
+	}
+	if c.Paint && c.Check {
+		errs = append(errs, errors.New("-paint cannot be combined with -check: painted cells break population conservation"))
+	}
+	if c.Plugins != "" {
+		if _, err := parsePluginSpec(c.Plugins); err != nil {
+			errs = append(errs, fmt.Errorf("-plugin: %w", err))
+		}
+	}
+	if c.ZoneCSV != "" && c.Zones == "" {
+		errs = append(errs, errors.New("-zone-csv needs -zones"))
+	}
+	if c.Zones != "" && c.GridFile == "" && c.Resume == "" && c.GridSize > 0 {
+		if _, err := parseZones(c.Zones, c.GridSize); err != nil {
+			errs = append(errs, fmt.Errorf("-zones: %w", err)) ///< With -grid or -resume, the world's size is checked at setup
+		}
+	}
+	if c.Script != "" && c.Check {
+		errs = append(errs, errors.New("-script cannot be combined with -check: scripted additions and removals break population conservation"))
+	}
+
+	if err := validateRuleSet(c.RuleSet); err != nil {
+		errs = append(errs, fmt.Errorf("-ruleset: %w", err))
+	}
+	if err := validateOrder(c.Order); err != nil {
+		errs = append(errs, fmt.Errorf("-order: %w", err))
+	}
+	for _, p := range []struct {
+		name string
+		v    float64
+	}{{"-birth-share", c.BirthShare}, {"-crowding-death", c.CrowdingDeath}, {"-fish-breed-prob", c.FishBreedProb}, {"-shark-breed-prob", c.SharkBreedProb}, {"-starve-prob", c.StarveProb},
+		{"-tag", c.Tag}, {"-tide-prob", c.TideProb}, {"-tide-spread", c.TideSpread}, {"-tide-decay", c.TideDecay}, {"-tide-kill", c.TideKill}} {
+		if p.v < 0 || p.v > 1 {
+			errs = append(errs, fmt.Errorf("%s must be between 0 and 1, got %g", p.name, p.v))
+		}
+	}
+
+	if c.Lineages < 1 || c.Lineages > maxLineages {
+		errs = append(errs, fmt.Errorf("-lineages must be between 1 and %d, got %d", maxLineages, c.Lineages))
+	}
+	if c.TemperatureEffect < 0 {
+		errs = append(errs, fmt.Errorf("-temperature-effect must be at least 0, got %g", c.TemperatureEffect))
+	}
+	if c.TemperatureOverlay && c.Temperature == "" {
+		errs = append(errs, errors.New("-temperature-overlay needs -temperature"))
+	}
+
+	if _, err := storageByName(c.Storage); err != nil {
+		errs = append(errs, fmt.Errorf("-storage: %w", err))
+	}
+	atLeast("-fast-forward", c.FastForward, 0)
+	if c.FastForward > 0 && c.Storage != "chunks" {
+		errs = append(errs, fmt.Errorf("-fast-forward needs -storage chunks, got %q", c.Storage))
+	}
+	if c.FastForward > 0 && (c.CrowdingK > 0 || c.Behaviour != "" || c.Plugins != "") {
+		errs = append(errs, errors.New("-fast-forward cannot be combined with -crowding-k, -behaviour or -plugin, which let fish die or move without an empty neighbour"))
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn":
+	default:
+		errs = append(errs, fmt.Errorf("-log-level must be debug, info or warn, got %q", c.LogLevel))
+	}
+
+	return errors.Join(errs...)
+}
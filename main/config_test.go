@@ -0,0 +1,103 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file config_test.go
+ * @brief Tests for command-line parsing and parameter validation.
+ */
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	cases := []struct {
+		args    string
+		wantErr string ///< Substring of the expected error; empty means success
+	}{
+		{args: ""},
+		{args: "-seed 5 10 20 3 3 4 10 2"},
+		{args: "ten 100 3 3 4 10 2", wantErr: `NumShark must be a whole number, got "ten"`},
+		{args: "1 2 3", wantErr: "expected 7 positional parameters"},
+		{args: "1 -2 3 3 4 10 2", wantErr: "NumFish must be at least 0"},
+		{args: "1 2 3 3 4 0 2", wantErr: "GridSize must be at least 1"},
+		{args: "1 2 3 3 4 10 0", wantErr: "Threads must be at least 1"},
+		{args: "50 60 3 3 4 10 2", wantErr: "does not fit in a 10x10 grid"},
+		{args: "-log-level loud", wantErr: "-log-level must be"},
+		{args: "-ensemble 3 -csv out.csv", wantErr: "-ensemble cannot be combined"},
+		{args: "-shark-speed 0", wantErr: "-shark-speed must be at least 1"},
+		{args: "-ruleset stochastic -starve-prob 0.5"},
+		{args: "-crowding-k 9", wantErr: "-crowding-k must be at most 8"},
+		{args: "-theme emoji"},
+		{args: "-theme neon", wantErr: `unknown theme "neon"`},
+		{args: "-ruleset random", wantErr: `unknown rule set "random"`},
+		{args: "-order checkerboard"},
+		{args: "-order spiral", wantErr: `unknown update order "spiral"`},
+		{args: "-fish-breed-prob 1.5", wantErr: "-fish-breed-prob must be between 0 and 1"},
+		{args: "-deterministic -engine deterministic"},
+		{args: "-deterministic -engine moves", wantErr: "cannot be combined with -engine moves"},
+		{args: "-origin -3,5"},
+		{args: "-origin 3", wantErr: `-origin: want two integers "x,y", got "3"`},
+		{args: "-center -viewport 20", wantErr: "cannot be combined with -viewport"},
+		{args: "-accessible"},
+		{args: "-accessible -renderer braille", wantErr: "-accessible draws no grid"},
+		{args: "-notify bell,desktop"},
+		{args: "-notify siren", wantErr: `unknown notifier "siren"`},
+		{args: "-control /tmp/w.sock -pipe", wantErr: "-control cannot be combined with -ensemble or -pipe"},
+	}
+	for _, c := range cases {
+		_, err := parseConfig(strings.Fields(c.args), io.Discard)
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", c.args, err)
+		case c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)):
+			t.Errorf("%q: got error %v, want one containing %q", c.args, err, c.wantErr)
+		}
+	}
+}
+
+func TestRulesDeriveProbabilities(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.FishBreed, cfg.SharkBreed, cfg.StarveEnergy, cfg.StarveProb = 4, 5, 2, 0.9
+	r := cfg.Rules()
+	if r.FishBreedProb != 0.25 || r.SharkBreedProb != 0.2 || r.StarveProb != 0.9 {
+		t.Errorf("got probabilities %v/%v/%v, want 0.25/0.2/0.9", r.FishBreedProb, r.SharkBreedProb, r.StarveProb)
+	}
+}
+
+func TestParseConfigEnvironment(t *testing.T) {
+	t.Setenv("WATOR_GRID_SIZE", "40")
+	t.Setenv("WATOR_SHARK_VISION", "3")
+	t.Setenv("WATOR_SEED", "9")
+	t.Setenv("WATOR_PAINT", "true")
+	cfg, err := parseConfig(strings.Fields("-seed 5"), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GridSize != 40 || cfg.SharkVision != 3 || !cfg.Paint {
+		t.Errorf("got GridSize %d, SharkVision %d, Paint %v; want 40, 3, true", cfg.GridSize, cfg.SharkVision, cfg.Paint)
+	}
+	if cfg.Seed != 5 {
+		t.Errorf("Seed = %d, want 5 from the command line", cfg.Seed)
+	}
+
+	t.Setenv("WATOR_GRID_SIZE", "forty")
+	t.Setenv("WATOR_SHARK_VISION", "x")
+	_, err = parseConfig(nil, io.Discard)
+	for _, want := range []string{`WATOR_GRID_SIZE: GridSize must be a whole number, got "forty"`, "WATOR_SHARK_VISION: -shark-vision"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got error %v, want one containing %q", err, want)
+		}
+	}
+}
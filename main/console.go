@@ -0,0 +1,195 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file console.go
+ * @brief Queries and commands typed into a running simulation (the -console option).
+ * @details With -console, a control line starting with a colon is a console command rather
+ * than keys, answered on standard error so the frames on standard output stay clean:
+ *
+ *     :count sharks in (0,0)-(50,50)
+ *     :find entity 1234
+ *     :set param fishBreed 4
+ *     :help
+ *
+ * count reads the latest snapshot and find the entity registry, which describes the world at
+ * the start of the latest chronon, so neither stops the run; set changes a parameter from the next chronon on, exactly like a scenario script's
+ * set, and accepts the same parameters. Only the named parameter changes, so the console and a
+ * script can both set parameters during a run. Lines are read when Enter is pressed, like the
+ * other control keys, and work while the display is paused.
+ */
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/** Usage shown by :help and after an unknown command. */
+const consoleHelp = `commands:
+  count fish|sharks|animals|empty [in (x1,y1)-(x2,y2)]
+  find entity <id>
+  set param <name> <value>   (any parameter a -script set accepts)
+  help`
+
+/**
+ * @brief Receives the control lines that start with a colon, without the colon.
+ */
+type lineHandler interface {
+	HandleLine(line string)
+}
+
+/**
+ * @struct Console
+ * @brief Evaluates console commands against a simulation.
+ */
+type Console struct {
+	Sim *Simulation
+	W   io.Writer    ///< Where answers go, usually os.Stderr
+	cf  *configFlags ///< The parameters as last set, checked like a script's
+}
+
+/**
+ * @brief Creates a console for a simulation.
+ * @details Starts the simulation's entity registry, so that entities can be found by ID.
+ */
+func NewConsole(sim *Simulation, w io.Writer) *Console {
+	sim.Registry()
+	cf := newConfigFlags("console", "", io.Discard)
+	*cf.cfg, *cf.colour.noColor, *cf.colour.force = sim.Config(), false, true ///< The theme is already resolved
+	return &Console{Sim: sim, W: w, cf: cf}
+}
+
+/**
+ * @brief Ignores keys, so that the console can sit among the other control handlers.
+ */
+func (c *Console) HandleKey(byte) bool {
+	return false
+}
+
+/**
+ * @brief Evaluates a command line and writes the answer.
+ */
+func (c *Console) HandleLine(line string) {
+	answer, err := c.Eval(line)
+	if err != nil {
+		answer = "error: " + err.Error()
+	}
+	if answer != "" {
+		fmt.Fprintln(c.W, answer)
+	}
+}
+
+/**
+ * @brief Evaluates one command.
+ * @param line The command, without the leading colon.
+ * @return The answer, or an error for a malformed or failing command.
+ */
+func (c *Console) Eval(line string) (string, error) {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return "", nil
+	}
+	switch {
+	case words[0] == "help":
+		return consoleHelp, nil
+	case words[0] == "count":
+		return c.count(words[1:])
+	case len(words) == 3 && words[0] == "find" && words[1] == "entity":
+		return c.find(words[2])
+	case len(words) == 4 && words[0] == "set" && words[1] == "param":
+		return c.set(words[2], words[3])
+	}
+	return "", fmt.Errorf("unknown command %q\n%s", line, consoleHelp)
+}
+
+/**
+ * @brief Answers "count <what> [in <region>]".
+ */
+func (c *Console) count(words []string) (string, error) {
+	if len(words) != 1 && !(len(words) == 3 && words[1] == "in") {
+		return "", errors.New(`expected "count fish|sharks|animals|empty [in (x1,y1)-(x2,y2)]"`)
+	}
+	f := c.Sim.Snapshot()
+	var match func(Species) bool
+	switch words[0] {
+	case "fish":
+		match = func(sp Species) bool { return sp == SpeciesFish }
+	case "shark", "sharks":
+		match = func(sp Species) bool { return sp == SpeciesShark }
+	case "animal", "animals":
+		match = func(sp Species) bool { return sp != SpeciesNone }
+	case "empty":
+		match = func(sp Species) bool { return sp == SpeciesNone }
+	default:
+		return "", fmt.Errorf("cannot count %q (want fish, sharks, animals or empty)", words[0])
+	}
+	region := ScriptEvent{X2: f.Size() - 1, Y2: f.Size() - 1}
+	if len(words) == 3 {
+		if err := region.parseRegion(words[2], f.Size()); err != nil {
+			return "", err
+		}
+	}
+	n := 0
+	for x := region.X1; x <= region.X2; x++ {
+		for y := region.Y1; y <= region.Y2; y++ {
+			if match(f.At(x, y)) {
+				n++
+			}
+		}
+	}
+	return fmt.Sprintf("chronon %d: %d %s in (%d,%d)-(%d,%d)", f.Chronon(), n, words[0], region.X1, region.Y1, region.X2, region.Y2), nil
+}
+
+/**
+ * @brief Answers "find entity <id>".
+ */
+func (c *Console) find(word string) (string, error) {
+	id, err := strconv.ParseUint(word, 10, 64)
+	if err != nil || id == 0 {
+		return "", fmt.Errorf("entity ID must be a whole number of at least 1, got %q", word)
+	}
+	info, ok := c.Sim.Registry().Lookup(id)
+	if !ok {
+		return fmt.Sprintf("entity %d is not alive", id), nil
+	}
+	at := info.Trail[len(info.Trail)-1]
+	answer := fmt.Sprintf("entity %d: %s at (%d,%d), age %d, breed counter %d", id, speciesWord(info.Species), at[0], at[1], info.Age, info.BreedCounter)
+	if info.Species == SpeciesShark {
+		answer += fmt.Sprintf(", energy %d", info.Energy)
+	}
+	return answer, nil
+}
+
+/**
+ * @brief Carries out "set param <name> <value>".
+ */
+func (c *Console) set(name, value string) (string, error) {
+	if !slices.ContainsFunc(scriptParams, func(p string) bool { return strings.EqualFold(p, name) }) {
+		return "", fmt.Errorf("cannot set %q during a run; settable parameters: %s", name, strings.Join(scriptParams, ", "))
+	}
+	next, err := c.cf.with(map[string]string{name: value})
+	if err != nil {
+		return "", err
+	}
+	c.Sim.ChangeRules(c.cf.cfg.Rules(), next.Rules()) ///< Only what this set changed, so a script's sets stay in force
+	*c.cf.cfg = next                                  ///< Later sets start from this one
+	if strings.EqualFold(name, "Threads") {
+		c.Sim.SetThreads(next.Threads)
+	}
+	slog.Info("console", "set", name, "value", value)
+	return fmt.Sprintf("%s set to %s from the next chronon", name, value), nil
+}
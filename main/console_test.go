@@ -0,0 +1,161 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file console_test.go
+ * @brief Tests for the -console commands.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConsoleCount(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConsole(sim, &strings.Builder{})
+	fish, sharks := sim.Snapshot().Counts()
+	for cmd, want := range map[string]string{
+		"count fish":                   fmt.Sprintf("chronon 0: %d fish in (0,0)-(19,19)", fish),
+		"count sharks":                 fmt.Sprintf("chronon 0: %d sharks in (0,0)-(19,19)", sharks),
+		"count empty in (0,0)-(19,19)": fmt.Sprintf("chronon 0: %d empty in (0,0)-(19,19)", 400-fish-sharks),
+	} {
+		if got, err := c.Eval(cmd); err != nil || got != want {
+			t.Errorf("%s: got %q, %v; want %q", cmd, got, err, want)
+		}
+	}
+	if _, err := c.Eval("count sharks in (0,0)-(50,50)"); err == nil {
+		t.Error("a region outside the grid was counted")
+	}
+	if _, err := c.Eval("count whales"); err == nil {
+		t.Error("whales were counted")
+	}
+}
+
+func TestConsoleFindEntity(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConsole(sim, &strings.Builder{})
+	f := sim.Snapshot()
+	sim.Run(context.Background(), 1) ///< The registry observes the world at the start of the chronon
+	var x, y int
+	for x = 0; f.At(x, y) != SpeciesShark; x, y = x+(y+1)/f.Size(), (y+1)%f.Size() {
+	}
+	id := sim.Registry().At(x, y)
+	got, err := c.Eval(fmt.Sprintf("find entity %d", id))
+	if want := fmt.Sprintf("entity %d: shark at (%d,%d)", id, x, y); err != nil || !strings.HasPrefix(got, want) || !strings.Contains(got, "energy") {
+		t.Errorf("got %q, %v; want it to start with %q and give the energy", got, err, want)
+	}
+	if got, _ := c.Eval("find entity 999999"); got != "entity 999999 is not alive" {
+		t.Errorf("missing entity: got %q", got)
+	}
+}
+
+func TestConsoleSetParam(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	c := NewConsole(sim, &out)
+	c.HandleLine("set param fishBreed 7")
+	c.HandleLine("set param shark-vision 2")
+	if r := sim.Rules(); r.FishBreed != 7 || r.SharkVision != 2 {
+		t.Errorf("rules FishBreed %d, SharkVision %d; want 7 and 2", r.FishBreed, r.SharkVision)
+	}
+	c.HandleLine("set param GridSize 50")
+	c.HandleLine("set param FishBreed 0")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "error: cannot set \"GridSize\"") || !strings.HasPrefix(lines[3], "error:") {
+		t.Errorf("unexpected answers:\n%s", out.String())
+	}
+	if sim.Rules().FishBreed != 7 {
+		t.Error("a rejected set changed the rules")
+	}
+}
+
+func TestConsoleAndScriptSetsKeepEachOther(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.txt")
+	if err := os.WriteFile(path, []byte("at chronon 2 set Starve=9\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.Script = path
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConsole(sim, io.Discard)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sim.Run(context.Background(), 4)
+	}()
+	c.HandleLine("set param fishBreed 7") ///< Concurrently with the run, as from stdin
+	<-done
+	if r := sim.Rules(); r.FishBreed != 7 || r.StarveEnergy != 9 {
+		t.Errorf("rules FishBreed %d, Starve %d; want 7 and 9", r.FishBreed, r.StarveEnergy)
+	}
+	c.HandleLine("set param shark-vision 2")
+	if r := sim.Rules(); r.FishBreed != 7 || r.StarveEnergy != 9 || r.SharkVision != 2 {
+		t.Errorf("a later console set undid the script's: FishBreed %d, Starve %d, SharkVision %d", r.FishBreed, r.StarveEnergy, r.SharkVision)
+	}
+}
+
+func TestChangedRules(t *testing.T) {
+	cur := Rules{FishBreed: 3, StarveEnergy: 9, Behaviour: &Behaviour{}}
+	before := Rules{FishBreed: 3, StarveEnergy: 4}
+	after := Rules{FishBreed: 5, StarveEnergy: 4}
+	got := changedRules(cur, before, after)
+	if got.FishBreed != 5 || got.StarveEnergy != 9 || got.Behaviour != cur.Behaviour {
+		t.Errorf("changedRules = %+v, want FishBreed 5 with Starve and the behaviour kept", got)
+	}
+}
+
+func TestControlLinesReachConsole(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	keys := &recordingKeys{}
+	readControls(strings.NewReader("p\n:count fish in (0,0)-(0,0)\n]"), keys, NewConsole(sim, &out))
+	if string(keys.keys) != "p\n]" {
+		t.Errorf("keys %q, want the lines without a colon", keys.keys)
+	}
+	if !strings.HasPrefix(out.String(), "chronon 0: ") {
+		t.Errorf("console answered %q", out.String())
+	}
+}
+
+/**
+ * @brief Records the keys it receives.
+ */
+type recordingKeys struct {
+	keys []byte
+}
+
+func (r *recordingKeys) HandleKey(key byte) bool {
+	r.keys = append(r.keys, key)
+	return true
+}
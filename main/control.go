@@ -0,0 +1,253 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file control.go
+ * @brief A control socket for a running simulation (the -control option).
+ * @details With -control <path> the run listens on a Unix domain socket at path, so shell
+ * scripts and other processes can steer it without an HTTP stack. Like -pipe, each request is
+ * one JSON object per line and gets exactly one JSON reply line with "ok" set, and "error"
+ * when ok is false:
+ *   {"cmd":"pause"}          hold the run between chronons; replies once it is held, with stats
+ *   {"cmd":"resume"}         let a paused run continue
+ *   {"cmd":"step","n":10}    while paused, run n chronons (default 1) and reply with stats
+ *   {"cmd":"stats"}          chronon, fish, sharks, mean_shark_energy, totals and paused
+ *   {"cmd":"snapshot"}       stats plus "size" and "rows" (F fish, S shark, . empty)
+ *   {"cmd":"shutdown"}       end the run after the current chronon, writing its outputs as usual
+ * For example: echo '{"cmd":"stats"}' | nc -U /tmp/wator.sock. Several clients may be
+ * connected at once. Pausing blocks a chronon-start hook, like the p key of -history, so the
+ * engine is never interrupted mid-chronon. A stale socket file left by a killed run is
+ * replaced; the socket is removed when the run ends.
+ */
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+)
+
+/**
+ * @struct controlRequest
+ * @brief One control request.
+ */
+type controlRequest struct {
+	Cmd string `json:"cmd"`
+	N   int    `json:"n"`
+}
+
+/**
+ * @struct ControlServer
+ * @brief Serves the control protocol for one simulation. Safe for concurrent use.
+ */
+type ControlServer struct {
+	sim *Simulation
+	ln  net.Listener
+
+	mu       sync.Mutex
+	changed  *sync.Cond        ///< Signalled when a chronon starts or is held and when the pause or release changes
+	paused   bool              ///< Whether the run is to be held
+	until    int               ///< While paused, chronons before this one are let through (step)
+	chronon  int               ///< Chronon of the latest frame that reached hold
+	holding  bool              ///< Whether hold is blocking the run now
+	released bool              ///< Set once the run is ending; never hold again
+	totals   StepCounts        ///< Births and deaths since the server started
+	conns    map[net.Conn]bool ///< Open client connections, closed with the server
+}
+
+/**
+ * @brief Listens on a Unix socket and attaches the server to a simulation.
+ * @details Call before Run; the hold hook is registered on sim, after any renderer already
+ * registered, so a paused run shows its latest frame.
+ * @param sim The simulation to control.
+ * @param path The socket path.
+ * @return The server, or an error if the socket could not be created.
+ */
+func ListenControl(sim *Simulation, path string) (*ControlServer, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path) ///< Left behind by a run that was killed
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	cs := &ControlServer{sim: sim, ln: ln, conns: map[net.Conn]bool{}}
+	cs.changed = sync.NewCond(&cs.mu)
+	cs.chronon = sim.Population().Chronon
+	sim.OnChrononStart(cs.hold)
+	sim.OnStats(func(_ Population, report StepReport) {
+		cs.mu.Lock()
+		cs.totals.Add(report.StepCounts)
+		cs.mu.Unlock()
+	})
+	go cs.serve()
+	return cs, nil
+}
+
+/**
+ * @brief Records the frame about to be stepped and blocks while the run is paused.
+ * @param f The frame.
+ */
+func (cs *ControlServer) hold(f *Frame) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.chronon = f.Chronon()
+	cs.changed.Broadcast()
+	for cs.paused && f.Chronon() >= cs.until && !cs.released {
+		cs.holding = true
+		cs.changed.Broadcast()
+		cs.changed.Wait()
+	}
+	cs.holding = false
+}
+
+/**
+ * @brief Stops holding the run for good, e.g. when it is interrupted.
+ */
+func (cs *ControlServer) Release() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.released = true
+	cs.changed.Broadcast()
+}
+
+/**
+ * @brief Releases the run, closes the socket and disconnects every client.
+ */
+func (cs *ControlServer) Close() error {
+	cs.Release()
+	err := cs.ln.Close() ///< Also removes the socket file
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for c := range cs.conns {
+		c.Close()
+	}
+	return err
+}
+
+/**
+ * @brief Accepts clients until the listener is closed.
+ */
+func (cs *ControlServer) serve() {
+	for {
+		conn, err := cs.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Warn("control socket stopped", "err", err)
+			}
+			return
+		}
+		cs.mu.Lock()
+		cs.conns[conn] = true
+		cs.mu.Unlock()
+		go cs.session(conn)
+	}
+}
+
+/**
+ * @brief Answers one client's requests until it disconnects.
+ */
+func (cs *ControlServer) session(conn net.Conn) {
+	defer func() {
+		cs.mu.Lock()
+		delete(cs.conns, conn)
+		cs.mu.Unlock()
+		conn.Close()
+	}()
+	if err := cs.answer(conn, conn); err != nil && !errors.Is(err, net.ErrClosed) {
+		slog.Debug("control client dropped", "err", err)
+	}
+}
+
+/**
+ * @brief Reads requests and writes their replies, one JSON line each.
+ * @return An error if the requests could not be read or a reply could not be written.
+ */
+func (cs *ControlServer) answer(r io.Reader, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var req controlRequest
+		var reply map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			reply = map[string]any{"error": fmt.Sprintf("malformed request: %v", err)}
+		} else {
+			reply = cs.handle(req)
+		}
+		_, failed := reply["error"]
+		reply["ok"] = !failed
+		if err := enc.Encode(reply); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+/**
+ * @brief Handles one request.
+ * @return The reply, without "ok".
+ */
+func (cs *ControlServer) handle(req controlRequest) map[string]any {
+	cs.mu.Lock()
+	switch req.Cmd {
+	case "pause":
+		cs.paused, cs.until = true, 0
+		cs.changed.Broadcast()
+		for !cs.holding && !cs.released {
+			cs.changed.Wait()
+		}
+	case "resume":
+		cs.paused = false
+		cs.changed.Broadcast()
+	case "step":
+		if !cs.paused {
+			cs.mu.Unlock()
+			return map[string]any{"error": "step needs a paused run; send pause first"}
+		}
+		target := cs.chronon + max(req.N, 1)
+		cs.until = target
+		cs.changed.Broadcast()
+		for !(cs.holding && cs.chronon >= target) && !cs.released {
+			cs.changed.Wait()
+		}
+		if cs.released {
+			chronon := cs.chronon
+			cs.mu.Unlock()
+			return map[string]any{"error": fmt.Sprintf("the run ended at chronon %d", chronon)}
+		}
+	case "shutdown":
+		cs.sim.Stop()
+		cs.paused = false ///< Let a held run reach the end of Run
+		cs.changed.Broadcast()
+	case "stats", "snapshot":
+	default:
+		cs.mu.Unlock()
+		return map[string]any{"error": fmt.Sprintf("unknown command %q (want pause, resume, step, stats, snapshot or shutdown)", req.Cmd)}
+	}
+	totals, paused := cs.totals, cs.paused
+	cs.mu.Unlock()
+
+	f := cs.sim.Snapshot()
+	fish, sharks := f.Counts()
+	reply := map[string]any{"chronon": f.Chronon(), "fish": fish, "sharks": sharks,
+		"mean_shark_energy": f.MeanSharkEnergy(), "totals": totals, "paused": paused}
+	if req.Cmd == "snapshot" {
+		doc := newFrameDocument(f)
+		reply["size"], reply["rows"] = doc.Size, doc.Rows
+	}
+	return reply
+}
@@ -0,0 +1,96 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file control_test.go
+ * @brief Tests for the -control socket.
+ */
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestControlSocket(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "wator.sock")
+	cs, err := ListenControl(sim, path)
+	if err != nil {
+		t.Skipf("no Unix sockets here: %v", err)
+	}
+	done := make(chan int)
+	go func() {
+		ran, _ := sim.Run(context.Background(), 1_000_000)
+		cs.Close()
+		done <- ran
+	}()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies := bufio.NewScanner(conn)
+	send := func(req string) map[string]any {
+		t.Helper()
+		if _, err := conn.Write([]byte(req + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		if !replies.Scan() {
+			t.Fatalf("%s: no reply: %v", req, replies.Err())
+		}
+		var reply map[string]any
+		if err := json.Unmarshal(replies.Bytes(), &reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+
+	if r := send(`{"cmd":"step"}`); r["ok"] != false {
+		t.Errorf("step while running: %v", r)
+	}
+	paused := send(`{"cmd":"pause"}`)
+	if paused["ok"] != true || paused["paused"] != true {
+		t.Fatalf("pause: %v", paused)
+	}
+	at := paused["chronon"].(float64)
+	if r := send(`{"cmd":"stats"}`); r["chronon"] != at {
+		t.Errorf("paused run moved from chronon %v to %v", at, r["chronon"])
+	}
+	if r := send(`{"cmd":"step","n":3}`); r["chronon"] != at+3 {
+		t.Errorf("step 3 from chronon %v reached %v", at, r["chronon"])
+	}
+	snap := send(`{"cmd":"snapshot"}`)
+	if rows, _ := snap["rows"].([]any); len(rows) != 20 || snap["size"] != 20.0 {
+		t.Errorf("snapshot of size %v with %d rows", snap["size"], len(rows))
+	}
+	if r := send(`{"cmd":"dance"}`); r["ok"] != false {
+		t.Errorf("unknown command: %v", r)
+	}
+	if r := send(`{"cmd":"shutdown"}`); r["ok"] != true {
+		t.Errorf("shutdown: %v", r)
+	}
+	if ran := <-done; ran >= 1_000_000 {
+		t.Error("shutdown did not end the run")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file left behind: %v", err)
+	}
+}
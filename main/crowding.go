@@ -0,0 +1,94 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file crowding.go
+ * @brief Optional carrying-capacity rule: crowded fish may die.
+ * @details Without it, fish fill the whole grid once the sharks die out and the run stops
+ * being interesting. With the rule enabled, a fish with at least CrowdingK fish among its
+ * eight neighbours dies with probability CrowdingDeath at the start of its move.
+ */
+package main
+
+import (
+	"log/slog"
+	"math/rand"
+)
+
+/** Offsets of the eight neighbours of a cell (the Moore neighbourhood). */
+var mooreOffsets = [8][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+
+/**
+ * @brief Counts the fish among the eight neighbours of a cell in this grid.
+ * @param x The x-coordinate of the cell.
+ * @param y The y-coordinate of the cell.
+ * @return The number of neighbouring fish (0 to 8).
+ */
+func (g *Grid) fishNeighbours(x, y int) int {
+	n := 0
+	for _, d := range mooreOffsets {
+		if g.speciesAt((x+d[0]+g.Size)%g.Size, (y+d[1]+g.Size)%g.Size) == SpeciesFish {
+			n++
+		}
+	}
+	return n
+}
+
+/**
+ * @brief Returns the fish in row x and the rows either side, from the per-row counts.
+ * @details An upper bound on the fish around a cell of row x, itself included, that lets the
+ * crowding rule skip the neighbour scan in sparse rows.
+ */
+func (g *Grid) fishNearRow(x int) int {
+	n := 0
+	for _, r := range [3]int{g.adj.prev[x], x, g.adj.next[x]} {
+		fish, _ := g.rowCount(r)
+		n += fish
+	}
+	return n
+}
+
+/**
+ * @brief Reports whether the crowding rule is enabled.
+ */
+func (r Rules) crowding() bool {
+	return r.CrowdingK > 0 && r.CrowdingDeath > 0
+}
+
+/**
+ * @brief Decides whether the fish at (x, y) dies of crowding this chronon.
+ * @details Neighbours are counted in the grid as it was at the start of the chronon. The random
+ * source is only drawn from for crowded fish, so runs without the rule are unaffected.
+ * @param g The grid at the start of the chronon.
+ * @param rng The worker's random source.
+ * @param x The x-coordinate of the fish.
+ * @param y The y-coordinate of the fish.
+ * @return True if the fish dies.
+ */
+func (r Rules) crowdedOut(g *Grid, rng *rand.Rand, x, y int) bool {
+	if !r.crowding() || g.fishNearRow(x)-1 < r.CrowdingK || g.fishNeighbours(x, y) < r.CrowdingK {
+		return false
+	}
+	return rng.Float64() < r.CrowdingDeath
+}
+
+/**
+ * @brief Records the death of a crowded fish.
+ * @details The fish is not copied into the new grid, so its cell is free next chronon.
+ * @param tally The worker's birth, death, and event tally.
+ * @param x The x-coordinate of the fish.
+ * @param y The y-coordinate of the fish.
+ */
+func dieCrowded(tally *workerTally, x, y int) {
+	tally.FishCrowded++
+	tally.Events = append(tally.Events, Event{Chronon: tally.chronon, Kind: EventFishCrowded, X: x, Y: y})
+	slog.Debug("fish died of crowding", "chronon", tally.chronon, "x", x, "y", y)
+}
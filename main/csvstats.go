@@ -0,0 +1,122 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file csvstats.go
+ * @brief Per-chronon population statistics as CSV (the -csv option).
+ * @details One row per chronon with the populations after the move and the births and
+ * deaths that produced them, ready for plotting population curves in a spreadsheet.
+ */
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+/**
+ * Column names written as the first CSV row; with -fish-maturity, juvenile_fish follows them, and
+ * with red tides (-tide-prob or a -script) fish_poisoned and tide_cells, and with -tag the fish
+ * and sharks of every lineage (lineage_1_fish, lineage_1_sharks, ...).
+ */
+var csvHeader = []string{"chronon", "fish", "sharks", "fish_born", "sharks_born", "fish_eaten", "sharks_starved", "fish_crowded", "mean_shark_energy"}
+
+/**
+ * @struct CSVWriter
+ * @brief Writes one statistics row per chronon.
+ */
+type CSVWriter struct {
+	f      io.WriteCloser
+	w      *csv.Writer
+	stages bool  ///< Whether to count juvenile fish
+	tides  bool  ///< Whether to report red tides
+	tagged int   ///< Lineages counted; 0 without -tag
+	err    error ///< First write error; later rows are dropped
+}
+
+/**
+ * @brief Creates (or truncates) a CSV file and writes the provenance comments and header row.
+ * @param path Destination file path.
+ * @param cfg The configuration of the run.
+ * @return The writer, or an error if the file could not be created.
+ */
+func NewCSVWriter(path string, cfg Config) (*CSVWriter, error) {
+	f, err := createOutput(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating CSV stats: %w", err)
+	}
+	cw := &CSVWriter{f: f, w: csv.NewWriter(f), stages: cfg.FishMaturity > 0, tides: cfg.redTide()}
+	if cfg.Tag > 0 {
+		cw.tagged = cfg.Lineages
+	}
+	header := csvHeader
+	if cw.stages {
+		header = append(slices.Clip(header), "juvenile_fish")
+	}
+	if cw.tides {
+		header = append(slices.Clip(header), "fish_poisoned", "tide_cells")
+	}
+	header = append(slices.Clip(header), lineageColumns(cw.tagged)...)
+	if cw.err = writeProvenanceComments(f, cfg); cw.err == nil {
+		cw.err = cw.w.Write(header)
+	}
+	return cw, nil
+}
+
+/**
+ * @brief Appends the row for one chronon.
+ * @details Matches ChrononEndHook so it can be attached with Simulation.OnChrononEnd.
+ * @param f The frame produced by the chronon.
+ * @param report The engine's report for the chronon.
+ */
+func (cw *CSVWriter) Record(f *Frame, report StepReport) {
+	if cw.err != nil {
+		return
+	}
+	fish, sharks := f.Counts()
+	row := []int{f.Chronon(), fish, sharks, report.FishBorn, report.SharksBorn, report.FishEaten, report.SharksStarved, report.FishCrowded}
+	fields := make([]string, len(row))
+	for i, v := range row {
+		fields[i] = strconv.Itoa(v)
+	}
+	fields = append(fields, strconv.FormatFloat(f.MeanSharkEnergy(), 'f', 3, 64))
+	if cw.stages {
+		fields = append(fields, strconv.Itoa(f.Juveniles()))
+	}
+	if cw.tides {
+		fields = append(fields, strconv.Itoa(report.FishPoisoned), strconv.Itoa(f.TideCells()))
+	}
+	if cw.tagged > 0 {
+		fish, sharks := f.LineageCounts(cw.tagged)
+		for k := range fish {
+			fields = append(fields, strconv.Itoa(fish[k]), strconv.Itoa(sharks[k]))
+		}
+	}
+	cw.err = cw.w.Write(fields)
+}
+
+/**
+ * @brief Flushes buffered rows and closes the file.
+ * @return The first write, flush, or close error, if any.
+ */
+func (cw *CSVWriter) Close() error {
+	cw.w.Flush()
+	if cw.err == nil {
+		cw.err = cw.w.Error()
+	}
+	if err := cw.f.Close(); cw.err == nil {
+		cw.err = err
+	}
+	return cw.err
+}
@@ -0,0 +1,169 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file daemon.go
+ * @brief Running serve unattended (the serve -state-dir option).
+ * @details With a state directory, serve keeps everything it needs to survive restarts there:
+ *
+ *     latest.wtr       the world, checkpointed every -checkpoint-every and on shutdown
+ *     wator.log        the log, also written to standard error; rotated to wator.log.1,
+ *                      wator.log.2, ... once it passes -log-max-size, keeping -log-keep files
+ *
+ * On startup serve resumes from latest.wtr when it exists, so a service manager restarting a
+ * crashed or rebooted instance (systemd's Restart=on-failure) carries on from the last
+ * checkpoint with the same rules and seed. Random sources are not checkpointed, so the
+ * restarted world does not repeat the chronons the crashed instance would have run. Checkpoints are written to a temporary file and
+ * renamed, so a crash mid-write leaves the previous one intact; a checkpoint that cannot be
+ * read is moved aside to latest.wtr.bad and the instance starts a fresh world.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/** Names of the files kept in a state directory. */
+const (
+	stateCheckpoint = "latest.wtr"
+	stateLog        = "wator.log"
+)
+
+/**
+ * @brief Finds the checkpoint of a state directory to resume from.
+ * @param dir The state directory.
+ * @return The checkpoint path, empty when there is none to resume, or an error if an
+ * unreadable checkpoint could not be moved aside.
+ */
+func stateResume(dir string) (string, error) {
+	path := filepath.Join(dir, stateCheckpoint)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", nil
+	}
+	if _, err := readCheckpointFile(path); err != nil {
+		slog.Warn("unreadable checkpoint moved aside; starting a new world", "err", err, "moved_to", path+".bad")
+		return "", os.Rename(path, path+".bad")
+	}
+	return path, nil
+}
+
+/**
+ * @brief Checkpoints a simulation into a state directory at a fixed interval until ctx ends.
+ * @param ctx Context of the service.
+ * @param sim The simulation.
+ * @param dir The state directory.
+ * @param every Interval between checkpoints.
+ */
+func checkpointEvery(ctx context.Context, sim *Simulation, dir string, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			saveState(sim, dir)
+		}
+	}
+}
+
+/**
+ * @brief Writes the simulation's checkpoint into a state directory, logging any failure.
+ */
+func saveState(sim *Simulation, dir string) {
+	cp := sim.Checkpoint()
+	if err := cp.WriteFile(filepath.Join(dir, stateCheckpoint)); err != nil {
+		slog.Error("state checkpoint failed", "err", err)
+		return
+	}
+	slog.Debug("state checkpoint written", "chronon", cp.Chronon)
+}
+
+/**
+ * @struct rotatingFile
+ * @brief A log file that is rotated when it grows past a size. Safe for concurrent use.
+ */
+type rotatingFile struct {
+	mu   sync.Mutex
+	path string
+	max  int64 ///< Size in bytes after which the next write rotates
+	keep int   ///< Rotated files kept: path.1 (newest) to path.keep
+	f    *os.File
+	size int64
+}
+
+/**
+ * @brief Opens a log file for appending, to be rotated past maxSize bytes.
+ * @return The file, or an error if it cannot be opened.
+ */
+func openRotatingFile(path string, maxSize int64, keep int) (*rotatingFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &rotatingFile{path: path, max: maxSize, keep: keep, f: f, size: fi.Size()}, nil
+}
+
+/**
+ * @brief Appends to the file, rotating first if it would grow past its limit.
+ */
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.max {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+/**
+ * @brief Shifts the rotated files up by one, dropping the oldest, and starts an empty file.
+ */
+func (r *rotatingFile) rotate() error {
+	r.f.Close()
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.keep))
+	for i := r.keep - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if r.keep > 0 {
+		os.Rename(r.path, r.path+".1")
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	r.f, r.size = f, 0
+	return nil
+}
+
+/**
+ * @brief Closes the current file.
+ */
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
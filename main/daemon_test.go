@@ -0,0 +1,85 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file daemon_test.go
+ * @brief Tests for the serve -state-dir checkpoints and log rotation.
+ */
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wator.log")
+	r, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+	for name, want := range map[string]string{"": "dddddd\n", ".1": "cccccc\n", ".2": "bbbbbb\n"} {
+		got, err := os.ReadFile(path + name)
+		if err != nil || string(got) != want {
+			t.Errorf("wator.log%s holds %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("kept more rotated logs than asked")
+	}
+}
+
+func TestStateResume(t *testing.T) {
+	dir := t.TempDir()
+	if path, err := stateResume(dir); path != "" || err != nil {
+		t.Fatalf("empty directory: %q, %v", path, err)
+	}
+
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Run(context.Background(), 3)
+	saveState(sim, dir)
+	path, err := stateResume(dir)
+	if err != nil || path != filepath.Join(dir, stateCheckpoint) {
+		t.Fatalf("saved state: %q, %v", path, err)
+	}
+	cf := newConfigFlags("serve", "", io.Discard)
+	cfg, err := cf.parse([]string{"-resume", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resumed.Snapshot().Hash(); got != sim.Snapshot().Hash() {
+		t.Error("resumed world differs from the saved one")
+	}
+
+	os.WriteFile(path, []byte("{not a checkpoint"), 0o644)
+	if path, err := stateResume(dir); path != "" || err != nil {
+		t.Fatalf("corrupt state: %q, %v", path, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, stateCheckpoint+".bad")); err != nil {
+		t.Errorf("corrupt checkpoint not moved aside: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file density.go
+ * @brief Per-row occupancy counts and density queries over regions of the grid.
+ * @details Every storage backend counts the fish and sharks of each row as cells are set, so
+ * the totals of any band of rows are a sum over rows rather than a scan of cells. Grid.Density
+ * answers full-width regions from the counts alone, in O(rows); narrower regions still visit
+ * their cells, but only in rows that are neither empty nor full. CountEntities, the capacity
+ * check of Initialize and the crowding rule's early exit use the same counts.
+ */
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+/**
+ * @struct rowCount
+ * @brief The fish and sharks of one row.
+ * @details Atomic because the row-partitioned engines write into the rows next to their
+ * section as well as their own.
+ */
+type rowCount struct {
+	fish, sharks atomic.Int32
+}
+
+/**
+ * @brief The counts of every row of a store, indexed by row.
+ */
+type rowCounts []rowCount
+
+/**
+ * @brief Records that a cell of row x changed from one species to another.
+ */
+func (c rowCounts) change(x int, from, to Species) {
+	if from == to {
+		return
+	}
+	c.add(x, from, -1)
+	c.add(x, to, 1)
+}
+
+/**
+ * @brief Adds n to the count of a species in row x; empty cells are not counted.
+ */
+func (c rowCounts) add(x int, sp Species, n int32) {
+	switch sp {
+	case SpeciesFish:
+		c[x].fish.Add(n)
+	case SpeciesShark:
+		c[x].sharks.Add(n)
+	}
+}
+
+/**
+ * @brief Returns the fish and sharks of row x.
+ */
+func (c rowCounts) row(x int) (fish, sharks int) {
+	return int(c[x].fish.Load()), int(c[x].sharks.Load())
+}
+
+/**
+ * @struct Region
+ * @brief A rectangle of cells, corners inclusive, as in the regions of -script.
+ */
+type Region struct {
+	X1, Y1 int ///< First corner: the lowest row and column
+	X2, Y2 int ///< Opposite corner: the highest row and column
+}
+
+/**
+ * @brief Returns the region covering a whole grid of the given size.
+ */
+func WholeGrid(size int) Region {
+	return Region{X2: size - 1, Y2: size - 1}
+}
+
+/**
+ * @brief Returns the number of cells in the region.
+ */
+func (r Region) Cells() int {
+	return (r.X2 - r.X1 + 1) * (r.Y2 - r.Y1 + 1)
+}
+
+/**
+ * @struct Density
+ * @brief How many of a region's cells hold fish and sharks.
+ */
+type Density struct {
+	Cells  int ///< Cells in the region
+	Fish   int
+	Sharks int
+}
+
+/** @brief Returns the number of empty cells. */
+func (d Density) Empty() int { return d.Cells - d.Fish - d.Sharks }
+
+/** @brief Returns the fraction of cells holding fish. */
+func (d Density) FishFraction() float64 { return float64(d.Fish) / float64(d.Cells) }
+
+/** @brief Returns the fraction of cells holding sharks. */
+func (d Density) SharkFraction() float64 { return float64(d.Sharks) / float64(d.Cells) }
+
+/** @brief Returns the fraction of cells holding either. */
+func (d Density) Occupied() float64 { return float64(d.Fish+d.Sharks) / float64(d.Cells) }
+
+/**
+ * @brief Returns the fish and sharks of row x.
+ */
+func (g *Grid) rowCount(x int) (fish, sharks int) {
+	return g.store.row(x)
+}
+
+/**
+ * @brief Counts the fish and sharks of a region.
+ * @details Rows are read from the per-row counts when the region spans the full width, or when
+ * a row is empty or entirely one species; other rows are scanned between Y1 and Y2. The grid
+ * must not be stepping while it is queried.
+ * @param r The region; it must lie within the grid.
+ * @return The counts, or an error if the region is empty or leaves the grid.
+ */
+func (g *Grid) Density(r Region) (Density, error) {
+	if r.X1 < 0 || r.Y1 < 0 || r.X1 > r.X2 || r.Y1 > r.Y2 || r.X2 >= g.Size || r.Y2 >= g.Size {
+		return Density{}, fmt.Errorf("region (%d,%d)-(%d,%d) is not inside the %dx%d grid", r.X1, r.Y1, r.X2, r.Y2, g.Size, g.Size)
+	}
+	d := Density{Cells: r.Cells()}
+	width := r.Y2 - r.Y1 + 1
+	for x := r.X1; x <= r.X2; x++ {
+		fish, sharks := g.rowCount(x)
+		switch {
+		case width == g.Size:
+			d.Fish += fish
+			d.Sharks += sharks
+		case fish == g.Size:
+			d.Fish += width
+		case sharks == g.Size:
+			d.Sharks += width
+		case fish+sharks > 0:
+			for y := g.nextOccupied(x, r.Y1); y <= r.Y2; y = g.nextOccupied(x, y+1) {
+				switch g.speciesAt(x, y) {
+				case SpeciesFish:
+					d.Fish++
+				case SpeciesShark:
+					d.Sharks++
+				}
+			}
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,95 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file density_test.go
+ * @brief Tests for the per-row occupancy counts and Grid.Density.
+ */
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+/**
+ * @brief Fails the test if any row's counts differ from a scan of its cells.
+ */
+func checkRowCounts(t *testing.T, name string, g *Grid) {
+	t.Helper()
+	for x := 0; x < g.Size; x++ {
+		fish, sharks := 0, 0
+		for y := 0; y < g.Size; y++ {
+			switch g.At(x, y).(type) {
+			case *Fish:
+				fish++
+			case *Shark:
+				sharks++
+			}
+		}
+		if f, s := g.rowCount(x); f != fish || s != sharks {
+			t.Fatalf("%s: row %d counts %d fish and %d sharks, its cells hold %d and %d", name, x, f, s, fish, sharks)
+		}
+	}
+}
+
+func TestRowCountsFollowEveryEngine(t *testing.T) {
+	for _, engine := range engineNames() {
+		for _, store := range storageNames() {
+			cfg := testConfig()
+			cfg.Engine, cfg.Storage, cfg.Threads = engine, store, 1
+			sim, err := NewSimulation(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sim.Run(context.Background(), 15)
+			checkRowCounts(t, engine+"/"+store, sim.Grid())
+		}
+	}
+}
+
+func TestDensityOfRegions(t *testing.T) {
+	for _, store := range storageNames() {
+		g, _ := NewGridWithStorage(12, store)
+		g.Seed(3)
+		if err := g.Initialize(50, 10, 4); err != nil {
+			t.Fatal(err)
+		}
+		for y := 0; y < 12; y++ {
+			g.Set(5, y, &Fish{}) ///< A full row, counted without a scan
+		}
+		for _, r := range []Region{WholeGrid(12), {X1: 2, Y1: 3, X2: 7, Y2: 9}, {X1: 5, Y1: 4, X2: 5, Y2: 6}, {X1: 11, Y1: 0, X2: 11, Y2: 0}} {
+			want := Density{Cells: r.Cells()}
+			for x := r.X1; x <= r.X2; x++ {
+				for y := r.Y1; y <= r.Y2; y++ {
+					switch g.At(x, y).(type) {
+					case *Fish:
+						want.Fish++
+					case *Shark:
+						want.Sharks++
+					}
+				}
+			}
+			if got, err := g.Density(r); err != nil || got != want {
+				t.Errorf("%s: density of %+v = %+v, %v; want %+v", store, r, got, err, want)
+			}
+		}
+		d, _ := g.Density(WholeGrid(12))
+		if fish, sharks := g.CountEntities(); fish != d.Fish || sharks != d.Sharks || d.Empty() != 144-fish-sharks {
+			t.Errorf("%s: CountEntities %d, %d disagrees with %+v", store, fish, sharks, d)
+		}
+		for _, r := range []Region{{X1: -1, X2: 3, Y2: 3}, {X2: 12, Y2: 3}, {X1: 4, X2: 3, Y2: 3}} {
+			if _, err := g.Density(r); err == nil {
+				t.Errorf("%s: region %+v outside the grid accepted", store, r)
+			}
+		}
+	}
+}
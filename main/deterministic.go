@@ -0,0 +1,175 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file deterministic.go
+ * @brief The "deterministic" engine: the same world for any number of threads.
+ * @details The other engines give each worker its own random source, so the result depends on
+ * -threads, and the moves and claims engines also let workers race for contested cells, so
+ * it depends on scheduling too. Here every
+ * random choice an entity makes is drawn from a stream keyed on (run seed, chronon, x, y),
+ * so a plan does not depend on which worker made it. Workers plan their rows in parallel
+ * (as in the moves engine), and the plans are then committed one by one in the update order
+ * of the entity's starting cell (row-major unless -order says otherwise), which fixes who wins
+ * every conflict. The result is bit-identical for any thread count, which makes it the mode
+ * to debug in and the reference that parallel runs are validated against.
+ */
+package main
+
+import (
+	"context"
+	"math/rand"
+	"runtime/trace"
+	"sync"
+	"time"
+)
+
+/**
+ * @struct cellSource
+ * @brief A splitmix64 random source that can be repositioned on a per-cell stream.
+ * @details Reseeding math/rand's default source costs hundreds of words of state, far too
+ * much to do for every entity; this one is a single word.
+ */
+type cellSource struct {
+	state uint64
+}
+
+func (s *cellSource) Seed(seed int64) { s.state = uint64(seed) }
+
+func (s *cellSource) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+func (s *cellSource) Int63() int64 { return int64(s.Uint64() >> 1) }
+
+/** Streams of a cell: one for planning, one for committing; cell (0, 0) also has the chronon's update order. */
+const (
+	streamPlan = iota
+	streamCommit
+	streamOrder
+)
+
+/**
+ * @brief Positions the source at the start of one cell's stream.
+ * @param base The chronon's base seed, drawn from the grid's source.
+ * @param chronon The chronon being computed.
+ * @param x The cell's x-coordinate.
+ * @param y The cell's y-coordinate.
+ * @param stream streamPlan, streamCommit or streamOrder.
+ */
+func (s *cellSource) seek(base int64, chronon, x, y, stream int) {
+	s.state = uint64(base) ^ uint64(chronon)*0xd1b54a32d192ed03 ^ uint64(x)*0xabc98388fb8fac03 ^
+		uint64(y)*0x8cb92ba72f3d8dd7 ^ uint64(stream)*0x9e3779b97f4a7c15
+	s.state = s.Uint64() ///< Scramble, so neighbouring cells start far apart
+}
+
+/**
+ * @brief Returns the source that shuffles a chronon's update order, the same for every thread count.
+ */
+func orderRand(base int64, chronon int) *rand.Rand {
+	src := &cellSource{}
+	src.seek(base, chronon, 0, 0, streamOrder)
+	return rand.New(src)
+}
+
+/**
+ * @struct deterministicEngine
+ * @brief Parallel planning, commits in a fixed order, per-cell random streams.
+ */
+type deterministicEngine struct{}
+
+func (deterministicEngine) Name() string { return "deterministic" }
+
+/**
+ * @brief Runs one chronon whose result does not depend on the thread count.
+ * @param ctx Context of the run; labels the planners in execution traces.
+ * @param g The grid to advance.
+ * @param rules The simulation rules.
+ * @param threads Number of planning threads; clamped to the number of rows.
+ * @return A StepReport; WorkerTimes holds the planning time of each worker.
+ */
+func (deterministicEngine) Step(ctx context.Context, g *Grid, rules Rules, threads int) StepReport {
+	base := g.rng.Int63() ///< The only draw from the grid's source, whatever the thread count
+	chronon := g.Chronon + 1
+	sections := partitionRows(g.Size, threads)
+	report := StepReport{WorkerTimes: make([]time.Duration, len(sections))}
+	plans := make([][]plannedMove, len(sections)) ///< Each worker's plans, in row-major order
+
+	var wg sync.WaitGroup
+	for i, section := range sections {
+		wg.Add(1)
+		worker := i
+		spawn(ctx, worker, len(sections), func() {
+			defer wg.Done()
+			began := time.Now()
+			_, end := startSpan(ctx, "plan", "worker", worker, "rows", section.End-section.Start)
+			trace.WithRegion(ctx, "plan", func() {
+				src := &cellSource{}
+				rng := rand.New(src)
+				for x := section.Start; x < section.End; x++ {
+					for y := g.nextOccupied(x, 0); y < g.Size; y = g.nextOccupied(x, y+1) {
+						src.seek(base, chronon, x, y, streamPlan)
+						if m, ok := g.planCell(rng, x, y, rules); ok {
+							plans[worker] = append(plans[worker], m)
+						}
+					}
+				}
+			})
+			end()
+			report.WorkerTimes[worker] = time.Since(began)
+		})
+	}
+	wg.Wait()
+
+	newGrid := g.emptyLike()
+	fates := make([][]fishFate, g.Size)
+	for i := range fates {
+		fates[i] = make([]fishFate, g.Size)
+	}
+	tally := workerTally{chronon: chronon}
+	src := &cellSource{}
+	rng := rand.New(src)
+	commit := func(m plannedMove) {
+		src.seek(base, chronon, m.x, m.y, streamCommit)
+		g.commitMove(newGrid, rng, fates, &tally, m, rules)
+	}
+	_, endCommit := startSpan(ctx, "commit")
+	if order := g.scanOrder(rules.Order); order.rowMajor() {
+		for _, band := range plans { ///< Bands are in row order, so this is row-major overall
+			for _, m := range band {
+				commit(m)
+			}
+		}
+	} else {
+		byCell := make([]*plannedMove, g.Size*g.Size) ///< Plans by starting cell, visited in the update order
+		for _, band := range plans {
+			for i := range band {
+				byCell[band[i].x*g.Size+band[i].y] = &band[i]
+			}
+		}
+		g.eachCell(order, orderRand(base, chronon), rowRange{End: g.Size}, SpeciesNone, func(x, y int) {
+			if m := byCell[x*g.Size+y]; m != nil {
+				commit(*m)
+			}
+		})
+	}
+	endCommit()
+
+	report.StepCounts = tally.StepCounts
+	report.Events = tally.Events
+	g.store = newGrid.store
+	g.Chronon++
+	return report
+}
@@ -0,0 +1,55 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file deterministic_test.go
+ * @brief Tests that the deterministic engine ignores the thread count.
+ */
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestDeterministicAcrossThreadCounts(t *testing.T) {
+	for _, rules := range []Rules{
+		{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5},
+		{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5, FishSpeed: 2, SharkSpeed: 3, SharkVision: 4, CrowdingK: 4, CrowdingDeath: 0.3},
+		{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5, RuleSet: RuleSetStochastic, FishBreedProb: 0.3, SharkBreedProb: 0.15, StarveProb: 0.2},
+	} {
+		var want uint64
+		for _, threads := range []int{1, 2, 3, 7, 30} {
+			g := NewGrid(30)
+			g.Seed(9)
+			g.Initialize(300, 60, rules.StarveEnergy)
+			for c := 0; c < 40; c++ {
+				deterministicEngine{}.Step(context.Background(), g, rules, threads)
+			}
+			if threads == 1 {
+				want = hashGrid(g)
+			} else if got := hashGrid(g); got != want {
+				t.Errorf("rules %+v: %d threads gave grid %x, 1 thread gave %x", rules, threads, got, want)
+			}
+		}
+	}
+}
+
+func TestDeterministicFlagSelectsEngine(t *testing.T) {
+	cfg, err := parseConfig([]string{"-deterministic"}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Engine != "deterministic" {
+		t.Errorf("-deterministic selected engine %q", cfg.Engine)
+	}
+}
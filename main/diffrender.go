@@ -0,0 +1,99 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file diffrender.go
+ * @brief Terminal renderer that redraws only the cells that changed.
+ * @details The first frame clears the screen and is drawn in full, in the same bordered
+ * layout as TextRenderer. Later frames move the cursor to each cell whose glyph differs
+ * from the previous frame and overwrite just that cell, so a mostly static grid costs a
+ * few bytes per chronon instead of the whole grid.
+ */
+package main
+
+import (
+	"io"
+	"strconv"
+)
+
+/**
+ * @struct DiffRenderer
+ * @brief Draws frames in place using cursor movement, emitting only changed cells.
+ * @details The output only makes sense on a terminal; redirect logs away from it, since
+ * anything else printed to the screen is overwritten or displaced.
+ */
+type DiffRenderer struct {
+	W         io.Writer   ///< Destination, usually os.Stdout
+	Theme     Theme       ///< Glyphs to use; the zero value means "ansi"
+	MaxEnergy int         ///< Energy of a freshly fed shark, used for shading
+	prev      []string    ///< Glyph of every cell in the last drawn frame; nil before the first
+	next      []string    ///< The slice the next frame's glyphs go into, swapped with prev
+	size      int         ///< Size of the last drawn frame
+	glyphs    *glyphCache ///< Rebuilt if Theme or MaxEnergy change
+	buf       []byte      ///< Output of the last frame, reused for the next
+}
+
+/**
+ * @brief Draws the frame, in full the first time and as a diff afterwards.
+ * @details A frame of a different size from the previous one is drawn in full.
+ * @param f The frame to draw.
+ */
+func (r *DiffRenderer) Render(f *Frame) {
+	if !r.glyphs.matches(r.Theme, r.MaxEnergy) {
+		r.glyphs, r.prev = newGlyphCache(r.Theme, r.MaxEnergy), nil
+	}
+	width := max(r.glyphs.theme.Width, 1) + 1 ///< Glyph plus separating space
+
+	cells := f.Size() * f.Size()
+	if cap(r.next) < cells {
+		r.next = make([]string, cells)
+	}
+	glyphs := r.next[:cells]
+	for x := 0; x < f.Size(); x++ {
+		for y := 0; y < f.Size(); y++ {
+			glyphs[x*f.Size()+y] = r.glyphs.cell(f, x, y) ///< Cached strings: comparing them is cheap
+		}
+	}
+
+	b := r.buf[:0]
+	if r.prev == nil || r.size != f.Size() {
+		b = append(b, "\033[H\033[2J"...)
+		b = appendStepHeader(b, f.Chronon())
+		b = appendFrame(b, f, r.glyphs)
+	} else {
+		b = append(b, "\033[1;1HStep "...)
+		b = strconv.AppendInt(b, int64(f.Chronon()), 10)
+		b = append(b, ":\033[K"...)
+		for i, g := range glyphs {
+			if g == r.prev[i] {
+				continue
+			}
+			x, y := i/f.Size(), i%f.Size()
+			b = appendCursor(b, x+3, 3+y*width) ///< Line 1 is the header, line 2 the border; "| " precedes the cells
+			b = append(b, g...)
+		}
+		b = appendCursor(b, f.Size()+4, 1) ///< Park the cursor below the grid
+	}
+	r.W.Write(b)
+	r.buf, r.size = b, f.Size()
+	r.prev, r.next = glyphs, r.prev
+}
+
+/**
+ * @brief Appends the escape sequence moving the cursor to a 1-based line and column.
+ */
+func appendCursor(b []byte, line, col int) []byte {
+	b = append(b, "\033["...)
+	b = strconv.AppendInt(b, int64(line), 10)
+	b = append(b, ';')
+	b = strconv.AppendInt(b, int64(col), 10)
+	return append(b, 'H')
+}
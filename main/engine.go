@@ -0,0 +1,175 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file engine.go
+ * @brief Selectable concurrency strategies for advancing the grid by one chronon.
+ * @details All strategies share the Grid, entities, and Rules, so they differ only in how the
+ * work of a chronon is spread over threads:
+ *  - "sections": each thread owns two bands of rows and writes straight into the new grid;
+ *    even and odd bands take turns, so no two threads touch the same cell (see partitionBands).
+ *  - "moves": threads only plan moves and send them over a channel to a single committer
+ *    goroutine, which resolves every conflict sequentially.
+ *  - "claims": like sections, but destinations are claimed with a compare-and-swap on a
+ *    per-cell owner slot, and a mover that loses a claim re-plans (see claims.go).
+ *  - "deterministic": planned like moves, with per-cell random streams and commits in a
+ *    fixed order, so the result is the same for any thread count (see deterministic.go).
+ *  - "serial": a single-threaded reference for the deterministic engine (see serial.go).
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+/**
+ * @struct Rules
+ * @brief Parameters of the predator-prey rules applied every chronon.
+ */
+type Rules struct {
+	FishBreed    int ///< Chronons before a fish can reproduce
+	SharkBreed   int ///< Chronons before a shark can reproduce
+	StarveEnergy int ///< Energy of a newborn or freshly fed shark; it starves when this runs out
+	FishSpeed    int ///< Cells a fish may move per chronon (values below 1 mean 1)
+	SharkSpeed   int ///< Cells a shark may move per chronon (values below 1 mean 1)
+	SharkVision  int ///< Path length within which a shark pursues fish; 1 or less sees only adjacent cells
+	MoveCost     int ///< Energy a shark spends to move without eating (values below 1 mean 1)
+	StayCost     int ///< Energy a shark spends to stay put (values below 1 mean 1)
+
+	BirthShare float64 ///< Fraction of the parent's energy given to a newborn shark; 0 starts it at StarveEnergy
+
+	CrowdingK     int     ///< Fish neighbours (of 8) at which crowding mortality applies; 0 disables it
+	CrowdingDeath float64 ///< Chance that a crowded fish dies in a chronon
+
+	FishMaturity   int ///< Chronons a newborn fish stays juvenile and cannot breed; 0 disables life stages
+	JuvenileEnergy int ///< Energy a shark gains from eating a juvenile; 0 feeds it fully, like an adult
+
+	Satiation int  ///< Chronons a shark skips hunting after eating; 0 disables satiation
+	SatedRest bool ///< Sated sharks stay put instead of wandering
+
+	FoodWeb *FoodWeb ///< Who eats whom and the energy of a meal (nil: sharks eat fish and are fully fed)
+
+	TideProb   float64 ///< Chance that a red tide blooms at a random cell each chronon; 0 disables random blooms
+	TideSpread float64 ///< Fraction of a neighbour's toxin level a cell takes on each chronon
+	TideDecay  float64 ///< Fraction of its toxin level a cell keeps each chronon
+	TideKill   float64 ///< Chance that a fish in a cell at full toxin level dies each chronon
+
+	Temperature       *Temperature ///< Per-cell temperature slowing breeding in cold water (nil disables)
+	TemperatureEffect float64      ///< How much longer breeding takes at temperature 0 than at 1, as a fraction
+
+	RuleSet        string  ///< RuleSetDeterministic (also when empty) or RuleSetStochastic
+	FishBreedProb  float64 ///< Stochastic rules: chance that a moving fish breeds
+	SharkBreedProb float64 ///< Stochastic rules: chance that a moving shark breeds
+	StarveProb     float64 ///< Stochastic rules: chance that a shark starves in a chronon
+
+	Behaviour *Behaviour ///< Scripted movement decisions (nil uses the built-in movement)
+
+	Order string ///< Update order: OrderRowMajor (also when empty), OrderRandom or OrderCheckerboard
+}
+
+/**
+ * @brief Applies the difference between two sets of rules to a third.
+ * @details Used for parameter changes made during a run, which are computed from a copy of
+ * the configuration: fields the change left alone, and those the configuration does not hold
+ * (the compiled -behaviour script and the temperature field), keep their value in cur.
+ * @param cur The rules in force.
+ * @param before The rules the change was made from.
+ * @param after The rules with the change.
+ * @return cur with every field that differs between before and after taken from after.
+ */
+func changedRules(cur, before, after Rules) Rules {
+	c, b, a := reflect.ValueOf(&cur).Elem(), reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := 0; i < c.NumField(); i++ {
+		if !reflect.DeepEqual(b.Field(i).Interface(), a.Field(i).Interface()) {
+			c.Field(i).Set(a.Field(i))
+		}
+	}
+	return cur
+}
+
+/**
+ * @brief Returns how many cells a fish may move per chronon.
+ */
+func (r Rules) fishSpeed() int {
+	return max(r.FishSpeed, 1)
+}
+
+/**
+ * @brief Returns how many cells a shark may move per chronon.
+ */
+func (r Rules) sharkSpeed() int {
+	return max(r.SharkSpeed, 1)
+}
+
+/**
+ * @brief Returns the path length within which a shark sees fish.
+ */
+func (r Rules) sharkVision() int {
+	return max(r.SharkVision, 1)
+}
+
+/**
+ * @brief A strategy for running one chronon on a grid.
+ */
+type Engine interface {
+	Name() string                                                           ///< Name used by the -engine flag
+	Step(ctx context.Context, g *Grid, rules Rules, threads int) StepReport ///< Advance the grid by one chronon
+}
+
+/** Registered engines by name. */
+var engines = map[string]Engine{
+	"sections": sectionsEngine{},
+	"moves":    movesEngine{},
+	"claims":   claimsEngine{},
+
+	"deterministic": deterministicEngine{},
+	"serial":        serialEngine{},
+}
+
+/**
+ * @brief Looks up an engine by name.
+ * @param name The engine name.
+ * @return The engine, or an error listing the valid names.
+ */
+func engineByName(name string) (Engine, error) {
+	if e, ok := engines[name]; ok {
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown engine %q (want %s)", name, strings.Join(engineNames(), " or "))
+}
+
+/**
+ * @brief Returns the registered engine names in sorted order.
+ */
+func engineNames() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/**
+ * @struct sectionsEngine
+ * @brief Row-partitioned strategy writing directly into a shared new grid.
+ */
+type sectionsEngine struct{}
+
+func (sectionsEngine) Name() string { return "sections" }
+
+func (sectionsEngine) Step(ctx context.Context, g *Grid, rules Rules, threads int) StepReport {
+	return g.stepSections(ctx, rules, threads)
+}
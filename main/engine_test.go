@@ -0,0 +1,214 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file engine_test.go
+ * @brief Comparative tests and benchmarks for the concurrency strategies.
+ * @details Every engine must keep the simulation invariants; the benchmarks compare their
+ * throughput at several thread counts ("go test -bench Engine").
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+/**
+ * @brief Runs an engine from a seeded grid, checking invariants after every chronon.
+ * @return The fish and shark populations averaged over the run.
+ */
+func runEngineChecked(t *testing.T, e Engine, rules Rules, threads int, seed int64) (meanFish, meanSharks float64) {
+	t.Helper()
+	g := NewGrid(30)
+	g.Seed(seed)
+	g.Initialize(300, 60, rules.StarveEnergy)
+
+	checker, err := NewInvariantChecker(g, rules.StarveEnergy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker.Speed = max(rules.fishSpeed(), rules.sharkSpeed())
+	const chronons = 60
+	for c := 1; c <= chronons; c++ {
+		report := e.Step(context.Background(), g, rules, threads)
+		if err := checker.Check(g, report.StepCounts); err != nil {
+			t.Fatalf("%s engine, %d threads, chronon %d: %v", e.Name(), threads, c, err)
+		}
+		fish, sharks := g.CountEntities()
+		meanFish += float64(fish) / chronons
+		meanSharks += float64(sharks) / chronons
+	}
+	return meanFish, meanSharks
+}
+
+func TestEnginesKeepInvariants(t *testing.T) {
+	cases := []struct {
+		engine  Engine
+		threads int
+	}{
+		{sectionsEngine{}, 1},
+		{sectionsEngine{}, 4}, ///< Alternating row bands make the sections engine safe at any thread count
+		{movesEngine{}, 1},
+		{movesEngine{}, 4},  ///< The single committer makes the moves engine safe at any thread count
+		{claimsEngine{}, 4}, ///< Atomic claims make the claims engine safe at any thread count
+		{deterministicEngine{}, 4},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s/%d", c.engine.Name(), c.threads), func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				fish, sharks := runEngineChecked(t, c.engine, Rules{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5}, c.threads, seed)
+				t.Logf("seed %d: mean fish %.1f, mean sharks %.1f", seed, fish, sharks)
+			}
+		})
+	}
+}
+
+func TestEnginesKeepInvariantsWithSpeed(t *testing.T) {
+	rules := Rules{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5, FishSpeed: 2, SharkSpeed: 3, SharkVision: 4}
+	for _, name := range engineNames() {
+		e, _ := engineByName(name)
+		for seed := int64(1); seed <= 3; seed++ {
+			runEngineChecked(t, e, rules, 1, seed)
+		}
+	}
+}
+
+func TestEnginesKeepInvariantsWithEnergyCosts(t *testing.T) {
+	for _, rules := range []Rules{
+		{FishBreed: 3, SharkBreed: 4, StarveEnergy: 8, MoveCost: 2, StayCost: 1, BirthShare: 0.5},
+		{FishBreed: 3, SharkBreed: 4, StarveEnergy: 8, MoveCost: 1, StayCost: 3}, ///< Staying can starve
+	} {
+		for _, name := range engineNames() {
+			e, _ := engineByName(name)
+			for seed := int64(1); seed <= 3; seed++ {
+				runEngineChecked(t, e, rules, 1, seed)
+			}
+		}
+	}
+}
+
+func TestCrowdingCapsFishPopulation(t *testing.T) {
+	base := Rules{FishBreed: 2, SharkBreed: 3, StarveEnergy: 3}
+	crowded := base
+	crowded.CrowdingK, crowded.CrowdingDeath = 4, 0.5
+	for _, name := range engineNames() {
+		e, _ := engineByName(name)
+		plain, _ := runEngineChecked(t, e, base, 1, 7)
+		capped, _ := runEngineChecked(t, e, crowded, 1, 7)
+		if capped >= plain {
+			t.Errorf("%s: crowding should lower the mean fish population, got %.1f with vs %.1f without", name, capped, plain)
+		}
+	}
+}
+
+func TestEnginesKeepInvariantsStochastic(t *testing.T) {
+	rules := Rules{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5,
+		RuleSet: RuleSetStochastic, FishBreedProb: 0.3, SharkBreedProb: 0.15, StarveProb: 0.2}
+	for _, name := range engineNames() {
+		e, _ := engineByName(name)
+		for seed := int64(1); seed <= 3; seed++ {
+			runEngineChecked(t, e, rules, 1, seed)
+		}
+	}
+}
+
+func TestStochasticExtremes(t *testing.T) {
+	for _, name := range engineNames() {
+		e, _ := engineByName(name)
+		g := NewGrid(10)
+		g.Seed(4)
+		g.Initialize(20, 10, 5)
+		report := e.Step(context.Background(), g, Rules{FishBreed: 1, SharkBreed: 1, StarveEnergy: 5,
+			RuleSet: RuleSetStochastic, FishBreedProb: 0, SharkBreedProb: 0, StarveProb: 1}, 1)
+		if report.SharksStarved != 10 || report.FishBorn != 0 || report.SharksBorn != 0 {
+			t.Errorf("%s: with starvation certain and breeding impossible got %+v", name, report.StepCounts)
+		}
+	}
+}
+
+func TestSharkWalksToDistantFish(t *testing.T) {
+	for _, name := range engineNames() {
+		e, _ := engineByName(name)
+		for _, speed := range []int{1, 2} {
+			eaten := 0
+			for seed := int64(1); seed <= 40; seed++ {
+				g := NewGrid(7)
+				g.Seed(seed)
+				g.Set(3, 1, &Shark{Energy: 5})
+				g.Set(3, 3, &Fish{}) ///< Two cells away: reachable only by walking
+				report := e.Step(context.Background(), g, Rules{FishBreed: 9, SharkBreed: 9, StarveEnergy: 5, SharkSpeed: speed}, 1)
+				eaten += report.FishEaten
+			}
+			if speed == 1 && eaten != 0 {
+				t.Errorf("%s: a speed-1 shark ate a fish two cells away", name)
+			}
+			if speed == 2 && eaten == 0 {
+				t.Errorf("%s: a speed-2 shark never reached a fish two cells away in 40 seeds", name)
+			}
+		}
+	}
+}
+
+func TestSharkVisionStepsTowardFish(t *testing.T) {
+	for _, name := range engineNames() {
+		e, _ := engineByName(name)
+		for seed := int64(1); seed <= 20; seed++ {
+			g := NewGrid(9)
+			g.Seed(seed)
+			g.Set(3, 1, &Shark{Energy: 5})
+			g.Set(3, 4, &Fish{}) ///< Three cells east; sharks move before fish, so it is still there
+			e.Step(context.Background(), g, Rules{FishBreed: 9, SharkBreed: 9, StarveEnergy: 5, SharkVision: 3}, 1)
+			if _, ok := g.At(3, 2).(*Shark); !ok {
+				t.Fatalf("%s, seed %d: the shark did not step toward the fish three cells away", name, seed)
+			}
+		}
+	}
+}
+
+func TestEnginesDeterministicSingleThread(t *testing.T) {
+	for _, name := range engineNames() {
+		e, _ := engineByName(name)
+		build := func() *Grid {
+			g := NewGrid(20)
+			g.Seed(9)
+			g.Initialize(120, 30, 4)
+			return g
+		}
+		a, b := build(), build()
+		for c := 0; c < 20; c++ {
+			e.Step(context.Background(), a, Rules{FishBreed: 3, SharkBreed: 3, StarveEnergy: 4}, 1)
+			e.Step(context.Background(), b, Rules{FishBreed: 3, SharkBreed: 3, StarveEnergy: 4}, 1)
+		}
+		if hashGrid(a) != hashGrid(b) {
+			t.Errorf("%s engine is not reproducible from a fixed seed", name)
+		}
+	}
+}
+
+func BenchmarkEngine(b *testing.B) {
+	for _, name := range engineNames() {
+		e, _ := engineByName(name)
+		for _, threads := range []int{1, 4, 8} {
+			b.Run(fmt.Sprintf("%s/threads=%d", name, threads), func(b *testing.B) {
+				g := NewGrid(200)
+				g.Seed(1)
+				g.Initialize(12000, 2000, 4)
+				rules := Rules{FishBreed: 3, SharkBreed: 3, StarveEnergy: 4}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					e.Step(context.Background(), g, rules, threads)
+				}
+			})
+		}
+	}
+}
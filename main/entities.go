@@ -20,21 +20,47 @@ type Entity interface {
 
 // Fish struct represents a fish entity with a breeding counter.
 type Fish struct {
-	BreedCounter int // Tracks the number of steps since the fish last reproduced.
+	BreedCounter int    // Tracks the number of steps since the fish last reproduced.
+	Juvenile     int    // Chronons until the fish matures and may breed; 0 for an adult.
+	Lineage      int    // Tag inherited from an ancestor tagged at the start (see -tag); 0 if untagged.
+	ID           uint64 // Identity given by an EntityRegistry; 0 until one has seen the fish.
+	Born         int    // Chronon in which the registry first saw the fish.
+	Acted        int64  // Chronon of the fish's last turn, so it never takes two in one chronon (see takeTurn).
 }
 
-// Symbol returns the colored representation of a fish ("F") in green.
+// entitySymbols holds the default ansi glyph of each species, built once for Symbol.
+var entitySymbols = newGlyphCache(themes["ansi"], 0).plain
+
+// Symbol returns the fish in its default style under the ansi theme (a green "F").
 func (f *Fish) Symbol() string {
-	return fmt.Sprintf("\033[32mF\033[0m")
+	return entitySymbols[SpeciesFish]
 }
 
 // Shark struct represents a shark entity with a breeding counter and energy level.
 type Shark struct {
-	BreedCounter int // Tracks the number of steps since the shark last reproduced.
-	Energy       int // Tracks the shark's energy level (decreases each step without food).
+	BreedCounter int    // Tracks the number of steps since the shark last reproduced.
+	Energy       int    // Tracks the shark's energy level (decreases each step without food).
+	Sated        int    // Chronons the shark still skips hunting after its last meal.
+	Lineage      int    // Tag inherited from an ancestor tagged at the start (see -tag); 0 if untagged.
+	ID           uint64 // Identity given by an EntityRegistry; 0 until one has seen the shark.
+	Born         int    // Chronon in which the registry first saw the shark.
+	Acted        int64  // Chronon of the shark's last turn, so it never takes two in one chronon (see takeTurn).
 }
 
-// Symbol returns the colored representation of a shark ("S") in red.
+// Symbol returns the shark in its default style under the ansi theme (a red "S").
 func (s *Shark) Symbol() string {
-	return fmt.Sprintf("\033[31mS\033[0m")
+	return entitySymbols[SpeciesShark]
+}
+
+// speciesName returns a plain-text name for an entity, used in log records.
+func speciesName(e Entity) string {
+	switch e.(type) {
+	case *Fish:
+		return "fish"
+	case *Shark:
+		return "shark"
+	case nil:
+		return "empty"
+	}
+	return fmt.Sprintf("%T", e)
 }
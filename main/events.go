@@ -0,0 +1,98 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file events.go
+ * @brief Notable simulation events and their JSON-lines output.
+ * @details Events are recorded by the worker that caused them and returned in the chronon's
+ * StepReport, so deaths are visible in stats and logs rather than just disappearing from
+ * the grid.
+ */
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+/**
+ * @brief Identifies the kind of an Event.
+ */
+type EventKind string
+
+const (
+	EventSharkStarved EventKind = "shark_starved" ///< A shark ran out of energy and died
+	EventFishCrowded  EventKind = "fish_crowded"  ///< A fish died under the crowding rule
+	EventFishPoisoned EventKind = "fish_poisoned" ///< A fish died in a red tide
+)
+
+/**
+ * @struct Event
+ * @brief Something notable that happened to an entity during a chronon.
+ */
+type Event struct {
+	Chronon int       `json:"chronon"` ///< Chronon in which the event happened (1 = first move)
+	Kind    EventKind `json:"kind"`    ///< What happened
+	X       int       `json:"x"`       ///< Row of the affected cell
+	Y       int       `json:"y"`       ///< Column of the affected cell
+}
+
+/**
+ * @struct EventWriter
+ * @brief Writes events to a file, one JSON object per line.
+ */
+type EventWriter struct {
+	f   io.WriteCloser
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+/**
+ * @brief Creates (or truncates) an event log file.
+ * @param path Destination file path.
+ * @return The writer, or an error if the file could not be created.
+ */
+func NewEventWriter(path string) (*EventWriter, error) {
+	f, err := createOutput(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating event log: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	return &EventWriter{f: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+/**
+ * @brief Appends events to the log.
+ * @param events The events to write.
+ * @return The first encoding error, if any.
+ */
+func (ew *EventWriter) Write(events []Event) error {
+	for _, e := range events {
+		if err := ew.enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * @brief Flushes buffered events and closes the file.
+ * @return The first flush or close error, if any.
+ */
+func (ew *EventWriter) Close() error {
+	if err := ew.w.Flush(); err != nil {
+		ew.f.Close()
+		return err
+	}
+	return ew.f.Close()
+}
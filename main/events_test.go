@@ -0,0 +1,63 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file events_test.go
+ * @brief Tests that starvation deaths are reported and vacate their cells with concurrent workers.
+ */
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStarvationEventsWithConcurrentSections(t *testing.T) {
+	cfg := testConfig()
+	cfg.Engine, cfg.Threads, cfg.GridSize = "sections", 4, 40
+	cfg.NumShark, cfg.NumFish, cfg.StarveEnergy = 300, 200, 2 ///< Mostly hungry sharks, so many starve every chronon
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker, err := NewInvariantChecker(sim.Grid(), cfg.StarveEnergy) ///< As -check sets it up
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	starved := 0
+	sim.OnChrononEnd(func(f *Frame, report StepReport) {
+		if err := checker.Check(sim.Grid(), report.StepCounts); err != nil {
+			t.Fatalf("chronon %d: %v", f.Chronon(), err)
+		}
+		events := 0
+		for _, e := range report.Events {
+			if e.Kind != EventSharkStarved {
+				continue
+			}
+			events++
+			if e.Chronon != f.Chronon() {
+				t.Errorf("starvation at (%d,%d) stamped chronon %d in chronon %d", e.X, e.Y, e.Chronon, f.Chronon())
+			}
+			if other := sim.Grid().At(e.X, e.Y); other != nil {
+				t.Errorf("chronon %d: cell (%d,%d) of a starved shark holds a %s", f.Chronon(), e.X, e.Y, speciesName(other))
+			}
+		}
+		if events != report.SharksStarved {
+			t.Errorf("chronon %d: %d starvation events, report counts %d", f.Chronon(), events, report.SharksStarved)
+		}
+		starved += events
+	})
+	sim.Run(context.Background(), 15)
+	if starved == 0 {
+		t.Error("no shark starved")
+	}
+}
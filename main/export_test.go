@@ -0,0 +1,119 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file export_test.go
+ * @brief Tests for the CSV and Parquet frame export.
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+/**
+ * @brief Runs a small simulation with an exporter attached as run does.
+ * @return The populations of chronons 0..chronons.
+ */
+func exportRun(t *testing.T, path string, chronons int) []Population {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fe, err := NewFrameExporter(path, testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Registry()
+	sim.OnChrononStart(func(*Frame) { fe.Record(sim.Grid()) })
+	pops := []Population{sim.Population()}
+	sim.OnStats(func(p Population, _ StepReport) { pops = append(pops, p) })
+	sim.Run(context.Background(), chronons)
+	sim.Registry().Observe(sim.Grid())
+	fe.Record(sim.Grid())
+	if err := fe.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return pops
+}
+
+/**
+ * @brief Checks exported rows against the populations and the age rules.
+ */
+func checkExport(t *testing.T, rows []FrameRow, pops []Population) {
+	perChronon := make([]Population, len(pops))
+	for _, r := range rows {
+		p := &perChronon[r.Chronon]
+		switch r.Species {
+		case "fish":
+			p.Fish++
+		case "shark":
+			p.Sharks++
+		}
+		if r.Age < 0 || r.Age > r.Chronon {
+			t.Fatalf("row %+v: age outside 0..chronon", r)
+		}
+		if r.Chronon == 0 && r.Age != 0 {
+			t.Fatalf("row %+v: the starting population must have age 0", r)
+		}
+	}
+	for c, p := range pops {
+		if perChronon[c].Fish != p.Fish || perChronon[c].Sharks != p.Sharks {
+			t.Errorf("chronon %d: exported %d fish and %d sharks, want %d and %d", c, perChronon[c].Fish, perChronon[c].Sharks, p.Fish, p.Sharks)
+		}
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frames.csv")
+	pops := exportRun(t, path, 6)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comment = '#' ///< Provenance
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 || records[0][5] != "age" {
+		t.Fatalf("unexpected header %v", records[:1])
+	}
+	var rows []FrameRow
+	for _, rec := range records[1:] {
+		n := make([]int32, 6)
+		for _, i := range []int{0, 1, 2, 4, 5} {
+			v, _ := strconv.Atoi(rec[i])
+			n[i] = int32(v)
+		}
+		rows = append(rows, FrameRow{Chronon: n[0], X: n[1], Y: n[2], Species: rec[3], Energy: n[4], Age: n[5]})
+	}
+	checkExport(t, rows, pops)
+}
+
+func TestExportParquet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frames.parquet")
+	pops := exportRun(t, path, 6)
+	rows, err := parquet.ReadFile[FrameRow](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExport(t, rows, pops)
+}
@@ -0,0 +1,150 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file fastforward.go
+ * @brief Experimental fast-forward of stable chunks (the -fast-forward option).
+ * @details A chunk of the "chunks" storage whose every cell holds a fish, with no empty cell
+ * on the ring of cells around it and no shark within a shark's reach of it, cannot change:
+ * no fish in it has anywhere to go (fish only breed when they move) and nothing can get in.
+ * After such a chunk has stayed that way for K chronons it is frozen: the engines skip it,
+ * as they skip empty chunks, and after the chronon the chunk is carried into the next grid
+ * with every fish's breeding counter advanced, as stepping it would have done. Each chronon
+ * only the ring of a frozen chunk is re-checked; a change there, an edit, or a rule change
+ * that makes fish act on their own (crowding, scripts, plugins) thaws it.
+ *
+ * Under the deterministic and serial engines, which give every cell its own random stream,
+ * the result is identical to an unaccelerated run. The other engines draw from one stream
+ * per worker, so skipping cells shifts the draws of later cells: the rules are the same but
+ * the run differs from one without -fast-forward.
+ */
+package main
+
+/**
+ * @struct fastForward
+ * @brief Per-chunk stability tracking for one simulation.
+ */
+type fastForward struct {
+	after   int    ///< Quiet chronons before a chunk is frozen
+	quiet   []int  ///< Consecutive quiet chronons per chunk
+	frozen  []bool ///< Chunks skipped this chronon, indexed like chunkStorage.chunks
+	skipped int    ///< Chunk-chronons skipped so far
+}
+
+/**
+ * @brief Creates the tracker.
+ * @param after Quiet chronons before a chunk is frozen; at least 1.
+ */
+func newFastForward(after int) *fastForward {
+	return &fastForward{after: max(after, 1)}
+}
+
+/**
+ * @brief Thaws every chunk and forgets their history, e.g. after cells were edited.
+ */
+func (ff *fastForward) reset() {
+	clear(ff.quiet)
+	clear(ff.frozen)
+}
+
+/**
+ * @brief Decides which chunks to skip this chronon and tells the grid's storage.
+ * @details Must run under the simulation lock, before the engine steps g.
+ * @return Whether any chunk is frozen, i.e. whether finish must run after the step.
+ */
+func (ff *fastForward) prepare(g *Grid, rules Rules) bool {
+	s, ok := g.store.(*chunkStorage)
+	if !ok {
+		return false
+	}
+	if len(ff.quiet) != len(s.chunks) {
+		ff.quiet, ff.frozen = make([]int, len(s.chunks)), make([]bool, len(s.chunks))
+	}
+	if rules.crowding() || rules.Behaviour != nil {
+		ff.reset() ///< Fish may die or move without an empty neighbour
+		return false
+	}
+	some := false
+	for i := range s.chunks {
+		cx, cy := i/s.across, i%s.across
+		// A frozen chunk was not stepped, so only its surroundings can have changed
+		if ff.frozen[i] || s.saturated(i) {
+			if ff.sealed(g, cx, cy, rules.sharkSpeed()) {
+				ff.quiet[i]++
+			} else {
+				ff.quiet[i] = 0
+			}
+		} else {
+			ff.quiet[i] = 0
+		}
+		ff.frozen[i] = ff.quiet[i] >= ff.after
+		some = some || ff.frozen[i]
+	}
+	if some {
+		s.skip = ff.frozen
+	}
+	return some
+}
+
+/**
+ * @brief Carries the frozen chunks into the grid the engine produced.
+ * @details Must run under the simulation lock, after the engine stepped g.
+ * @param old The storage the chronon started from.
+ */
+func (ff *fastForward) finish(g *Grid, old *chunkStorage) {
+	old.skip = nil
+	s := g.store.(*chunkStorage) ///< empty() keeps the kind
+	for i, frozen := range ff.frozen {
+		if !frozen {
+			continue
+		}
+		c := old.chunks[i].Load()
+		for _, e := range c.cells {
+			if fish, ok := e.(*Fish); ok {
+				fish.grow() ///< What processFish does to a fish that cannot move
+				fish.Acted = int64(g.Chronon)
+			}
+		}
+		s.chunks[i].Store(c) ///< Nothing moved in: its cells were all fish and no shark could reach them
+		cx, cy := i/s.across*chunkSide, i%s.across*chunkSide
+		for x := cx; x < min(cx+chunkSide, s.size); x++ {
+			s.counts.add(x, SpeciesFish, int32(min(chunkSide, s.size-cy)))
+		}
+		ff.skipped++
+	}
+}
+
+/**
+ * @brief Reports whether nothing can enter or leave chunk (cx, cy) this chronon.
+ * @details The ring one cell wide around the chunk must hold no empty cell, and the ring as
+ * wide as a shark's reach no shark. Coordinates wrap around the torus.
+ */
+func (ff *fastForward) sealed(g *Grid, cx, cy, reach int) bool {
+	x1, y1 := cx*chunkSide, cy*chunkSide
+	x2, y2 := min(x1+chunkSide, g.Size)-1, min(y1+chunkSide, g.Size)-1
+	for x := x1 - reach; x <= x2+reach; x++ {
+		for y := y1 - reach; y <= y2+reach; y++ {
+			if x >= x1 && x <= x2 && y >= y1 && y <= y2 {
+				y = y2 ///< Skip the chunk itself
+				continue
+			}
+			switch g.At(wrap(x, g.Size), wrap(y, g.Size)).(type) {
+			case nil:
+				if x >= x1-1 && x <= x2+1 && y >= y1-1 && y <= y2+1 {
+					return false ///< A border fish could move out
+				}
+			case *Shark:
+				return false
+			}
+		}
+	}
+	return true
+}
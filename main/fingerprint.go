@@ -0,0 +1,220 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file fingerprint.go
+ * @brief Grid hashes and run fingerprints (the -fingerprint option and the compare command).
+ * @details Every frame is hashed with xxHash64 over its species and shark energy bytes, and
+ * the run's fingerprint is the hash of that sequence of hashes. Two runs with the same
+ * fingerprint went through the same states; when they differ, the per-chronon hashes
+ * written by -fingerprint show the first chronon at which they parted, which "wator
+ * compare" finds.
+ */
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+/**
+ * @brief Hashes the state of a frame: every cell's species and shark energy.
+ * @details The chronon is not included, so equal worlds hash equally whenever they occur.
+ */
+func (f *Frame) Hash() uint64 {
+	d := xxhash.New()
+	d.Write(unsafe.Slice((*byte)(unsafe.SliceData(f.cells)), len(f.cells))) ///< Species is a byte
+	buf := make([]byte, 0, 2*len(f.energy))
+	for _, e := range f.energy {
+		buf = binary.LittleEndian.AppendUint16(buf, e)
+	}
+	d.Write(buf)
+	return d.Sum64()
+}
+
+/**
+ * @struct Fingerprinter
+ * @brief Hashes every frame of a run, optionally writing the hashes to a file.
+ */
+type Fingerprinter struct {
+	run  *xxhash.Digest ///< Hash of the sequence of frame hashes
+	file io.WriteCloser ///< Per-chronon hashes (nil when not written)
+	out  *bufio.Writer
+	err  error ///< First write error; later lines are dropped
+}
+
+/**
+ * @brief Creates a fingerprinter.
+ * @param path File for the per-chronon hashes, one "chronon hash" line each after the
+ * provenance comments, or empty to keep only the run's fingerprint.
+ * @param cfg The configuration of the run.
+ * @return The fingerprinter, or an error if the file could not be created.
+ */
+func NewFingerprinter(path string, cfg Config) (*Fingerprinter, error) {
+	fp := &Fingerprinter{run: xxhash.New()}
+	if path != "" {
+		f, err := createOutput(path)
+		if err != nil {
+			return nil, fmt.Errorf("creating fingerprint file: %w", err)
+		}
+		fp.file, fp.out = f, bufio.NewWriter(f)
+		fp.err = writeProvenanceComments(fp.out, cfg)
+	}
+	return fp, nil
+}
+
+/**
+ * @brief Hashes a frame and adds it to the run's fingerprint.
+ * @param f The frame; frames must arrive in chronon order.
+ */
+func (fp *Fingerprinter) Observe(f *Frame) {
+	h := f.Hash()
+	fp.run.Write(binary.LittleEndian.AppendUint64(nil, h))
+	if fp.out != nil && fp.err == nil {
+		_, fp.err = fmt.Fprintf(fp.out, "%d %016x\n", f.Chronon(), h)
+	}
+}
+
+/**
+ * @brief Hashes the frame produced by a chronon; usable as a chronon-end hook.
+ */
+func (fp *Fingerprinter) Record(f *Frame, _ StepReport) {
+	fp.Observe(f)
+}
+
+/**
+ * @brief Returns the run's fingerprint so far as 16 hex digits.
+ */
+func (fp *Fingerprinter) Sum() string {
+	return fmt.Sprintf("%016x", fp.run.Sum64())
+}
+
+/**
+ * @brief Flushes and closes the hash file, if any.
+ * @return The first write, flush, or close error, if any.
+ */
+func (fp *Fingerprinter) Close() error {
+	if fp.file == nil {
+		return nil
+	}
+	if err := fp.out.Flush(); fp.err == nil {
+		fp.err = err
+	}
+	if err := fp.file.Close(); fp.err == nil {
+		fp.err = err
+	}
+	return fp.err
+}
+
+/**
+ * @struct fingerprintLine
+ * @brief One line of a -fingerprint file.
+ */
+type fingerprintLine struct {
+	chronon int
+	hash    string
+}
+
+/**
+ * @brief Reads a -fingerprint file, skipping "#" comments such as the provenance.
+ * @return The lines in file order, or an error naming the first malformed line.
+ */
+func readFingerprints(r io.Reader) ([]fingerprintLine, error) {
+	var lines []fingerprintLine
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		chronon, err := strconv.Atoi(fields[0])
+		if len(fields) != 2 || err != nil || len(fields[1]) != 16 {
+			return nil, fmt.Errorf("line %d: expected \"<chronon> <16 hex digits>\", got %q", n, scanner.Text())
+		}
+		lines = append(lines, fingerprintLine{chronon, fields[1]})
+	}
+	return lines, scanner.Err()
+}
+
+/**
+ * @brief Finds the first chronon at which two fingerprint sequences disagree.
+ * @return A description of the first difference, or the empty string if they match.
+ */
+func compareFingerprints(a, b []fingerprintLine) string {
+	for i := range min(len(a), len(b)) {
+		switch {
+		case a[i].chronon != b[i].chronon:
+			return fmt.Sprintf("line %d: chronon %d against chronon %d; the files do not cover the same chronons", i+1, a[i].chronon, b[i].chronon)
+		case a[i].hash != b[i].hash:
+			return fmt.Sprintf("first difference at chronon %d: %s against %s", a[i].chronon, a[i].hash, b[i].hash)
+		}
+	}
+	if len(a) != len(b) {
+		return fmt.Sprintf("identical for %d chronons, then one file ends (%d against %d lines)", min(len(a), len(b)), len(a), len(b))
+	}
+	return ""
+}
+
+/**
+ * @brief The compare subcommand: reports where two -fingerprint files first differ.
+ * @param args The two files.
+ * @return exitOK if they match, exitFailure at a difference, exitConfigError for bad input.
+ */
+func cmdCompare(args []string) int {
+	if abRequested(args) {
+		return cmdCompareRuns(args)
+	}
+	fs := flag.NewFlagSet("wator compare", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wator compare <a> <b>")
+		fmt.Fprintln(fs.Output(), "Reports the first chronon at which two -fingerprint files differ.")
+		fmt.Fprintln(fs.Output(), "\nUsage: wator compare -config-a <file> -config-b <file> [flags]")
+		fmt.Fprintln(fs.Output(), "Runs two parameter files side by side; see wator compare -config-a x -h.")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitConfigError
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitConfigError
+	}
+	var seqs [2][]fingerprintLine
+	for i, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err == nil {
+			seqs[i], err = readFingerprints(f)
+			f.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return exitConfigError
+		}
+	}
+	if diff := compareFingerprints(seqs[0], seqs[1]); diff != "" {
+		fmt.Println(diff)
+		return exitFailure
+	}
+	fmt.Printf("identical for all %d chronons\n", len(seqs[0]))
+	return exitOK
+}
@@ -0,0 +1,96 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file fingerprint_test.go
+ * @brief Tests for grid hashes, run fingerprints and the compare command's helpers.
+ */
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Runs the test world and returns its fingerprint and per-chronon hash file.
+ */
+func fingerprintRun(t *testing.T, seed int64, threads int) (string, []fingerprintLine) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Engine, cfg.Seed, cfg.Threads = "deterministic", seed, threads
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fp.txt")
+	fp, err := NewFingerprinter(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp.Observe(sim.Snapshot())
+	sim.OnChrononEnd(fp.Record)
+	sim.Run(context.Background(), 8)
+	if err := fp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines, err := readFingerprints(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fp.Sum(), lines
+}
+
+func TestFingerprintReproducible(t *testing.T) {
+	sum1, lines1 := fingerprintRun(t, 3, 1)
+	sum4, lines4 := fingerprintRun(t, 3, 4)
+	if sum1 != sum4 || compareFingerprints(lines1, lines4) != "" {
+		t.Errorf("the deterministic engine gave fingerprints %s and %s for 1 and 4 threads", sum1, sum4)
+	}
+	if len(lines1) != 9 || lines1[0].chronon != 0 || lines1[8].chronon != 8 {
+		t.Errorf("got %d lines, want chronons 0 to 8", len(lines1))
+	}
+	other, lines := fingerprintRun(t, 4, 1)
+	if other == sum1 {
+		t.Error("different seeds gave the same fingerprint")
+	}
+	if diff := compareFingerprints(lines1, lines); !strings.Contains(diff, "at chronon 0") {
+		t.Errorf("got %q, want the first difference at chronon 0", diff)
+	}
+}
+
+func TestCompareFingerprints(t *testing.T) {
+	a := []fingerprintLine{{0, "00000000000000aa"}, {1, "00000000000000bb"}, {2, "00000000000000cc"}}
+	for _, tc := range []struct {
+		b    []fingerprintLine
+		want string
+	}{
+		{a, ""},
+		{[]fingerprintLine{a[0], {1, "00000000000000ff"}, a[2]}, "first difference at chronon 1"},
+		{a[:2], "identical for 2 chronons"},
+		{[]fingerprintLine{a[0], a[2]}, "do not cover the same chronons"},
+	} {
+		if got := compareFingerprints(a, tc.b); (tc.want == "") != (got == "") || !strings.Contains(got, tc.want) {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+	if _, err := readFingerprints(strings.NewReader("0 00000000000000aa\n1 bb\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("got %v, want an error for line 2", err)
+	}
+}
@@ -0,0 +1,152 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file foodweb.go
+ * @brief Who eats whom (the -food-web option).
+ * @details "Sharks eat fish" is one entry of a predation matrix over the registered species.
+ * A -food-web value lists the entries, separated by semicolons:
+ *
+ *     shark eats fish 2     # a meal adds 2 energy, up to Starve
+ *     shark eats fish       # a meal restores the shark fully (the default web)
+ *     none                  # nobody eats: sharks wander until they starve
+ *
+ * Every entry is checked at startup against what the registered species can do: a predator
+ * must carry energy to gain, and its prey must be a species the engines remove when it is
+ * eaten. Today fish and sharks are registered, so the webs that pass are the default, a
+ * different energy per meal, and no predation at all; a species added to registeredSpecies,
+ * with its step in the engines, extends the matrix without a new parser or new flags. The
+ * web is an ordinary parameter, so parameter files describe it like any other and resumed runs
+ * inherit it.
+ */
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+ * @brief What the engines support for each species, indexed by its Species code.
+ */
+var registeredSpecies = [...]struct {
+	name   string
+	energy bool ///< Carries energy, so it can gain some by eating
+	edible bool ///< Removed by the engines when a predator moves onto it
+}{
+	SpeciesFish:  {name: "fish", edible: true},
+	SpeciesShark: {name: "shark", energy: true},
+}
+
+/** Species codes the food web covers, SpeciesNone included so codes index it directly. */
+const numSpecies = len(registeredSpecies)
+
+/**
+ * @struct FoodWeb
+ * @brief A validated predation matrix. Read-only once parsed; a nil web is the classic one.
+ */
+type FoodWeb struct {
+	eats [numSpecies][numSpecies]bool ///< [predator][prey]
+	gain [numSpecies][numSpecies]int  ///< Energy a meal adds; 0 restores the predator fully
+}
+
+/**
+ * @brief Looks up a registered species by name, singular or plural.
+ */
+func speciesByName(name string) (Species, bool) {
+	name = strings.ToLower(name)
+	for sp, s := range registeredSpecies {
+		if s.name != "" && (name == s.name || name == s.name+"s") {
+			return Species(sp), true
+		}
+	}
+	return SpeciesNone, false
+}
+
+/**
+ * @brief Parses and validates a -food-web value.
+ * @param spec Entries "<predator> eats <prey> [energy]" separated by semicolons, or "none".
+ * @return The web, nil for an empty spec (the classic web), or an error naming the bad entry.
+ */
+func parseFoodWeb(spec string) (*FoodWeb, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	w := &FoodWeb{}
+	if strings.EqualFold(strings.TrimSpace(spec), "none") {
+		return w, nil
+	}
+	for _, entry := range strings.Split(spec, ";") {
+		words := strings.Fields(entry)
+		if len(words) < 3 || len(words) > 4 || !strings.EqualFold(words[1], "eats") {
+			return nil, fmt.Errorf("expected \"<predator> eats <prey> [energy]\", got %q", strings.TrimSpace(entry))
+		}
+		pred, ok := speciesByName(words[0])
+		if !ok {
+			return nil, fmt.Errorf("unknown species %q (want %s)", words[0], registeredSpeciesNames())
+		}
+		prey, ok := speciesByName(words[2])
+		if !ok {
+			return nil, fmt.Errorf("unknown species %q (want %s)", words[2], registeredSpeciesNames())
+		}
+		gain := 0
+		if len(words) == 4 {
+			var err error
+			if gain, err = strconv.Atoi(words[3]); err != nil || gain < 1 {
+				return nil, fmt.Errorf("%s: energy must be a whole number of at least 1, got %q", strings.TrimSpace(entry), words[3])
+			}
+		}
+		switch {
+		case !registeredSpecies[pred].energy:
+			return nil, fmt.Errorf("%s: %s carry no energy, so they cannot be predators", strings.TrimSpace(entry), registeredSpecies[pred].name)
+		case !registeredSpecies[prey].edible:
+			return nil, fmt.Errorf("%s: the engines cannot remove an eaten %s", strings.TrimSpace(entry), registeredSpecies[prey].name)
+		case w.eats[pred][prey]:
+			return nil, fmt.Errorf("%s eats %s is given twice", registeredSpecies[pred].name, registeredSpecies[prey].name)
+		}
+		w.eats[pred][prey], w.gain[pred][prey] = true, gain
+	}
+	return w, nil
+}
+
+/**
+ * @brief Returns the registered species names for messages.
+ */
+func registeredSpeciesNames() string {
+	var names []string
+	for _, s := range registeredSpecies {
+		if s.name != "" {
+			names = append(names, s.name)
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+/**
+ * @brief Reports whether a predator eats a prey; a nil web is the classic one, sharks eating fish.
+ */
+func (w *FoodWeb) Eats(predator, prey Species) bool {
+	if w == nil {
+		return predator == SpeciesShark && prey == SpeciesFish
+	}
+	return w.eats[predator][prey]
+}
+
+/**
+ * @brief Returns the energy a meal adds to a predator; 0 restores it fully.
+ */
+func (w *FoodWeb) Gain(predator, prey Species) int {
+	if w == nil {
+		return 0
+	}
+	return w.gain[predator][prey]
+}
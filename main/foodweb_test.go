@@ -0,0 +1,79 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file foodweb_test.go
+ * @brief Tests for the predation matrix.
+ */
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseFoodWeb(t *testing.T) {
+	cases := []struct {
+		spec    string
+		wantErr string ///< Substring of the expected error; empty means success
+	}{
+		{spec: ""},
+		{spec: "none"},
+		{spec: "shark eats fish"},
+		{spec: "Sharks eats fish 3"},
+		{spec: "shark eats fish 3; shark eats fish", wantErr: "shark eats fish is given twice"},
+		{spec: "fish eats shark", wantErr: "fish carry no energy"},
+		{spec: "shark eats shark", wantErr: "cannot remove an eaten shark"},
+		{spec: "shark eats squid", wantErr: `unknown species "squid" (want fish or shark)`},
+		{spec: "shark eats fish 0", wantErr: "energy must be a whole number of at least 1"},
+		{spec: "shark likes fish", wantErr: "expected"},
+	}
+	for _, c := range cases {
+		_, err := parseFoodWeb(c.spec)
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", c.spec, err)
+		case c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)):
+			t.Errorf("%q: error %v, want one containing %q", c.spec, err, c.wantErr)
+		}
+	}
+}
+
+func TestFoodWebWithoutPredation(t *testing.T) {
+	web, _ := parseFoodWeb("none")
+	rules := Rules{FishBreed: 10, SharkBreed: 10, StarveEnergy: 5, FoodWeb: web}
+	for _, name := range engineNames() {
+		engine, _ := engineByName(name)
+		g, _ := surroundedShark(0)
+		if report := engine.Step(context.Background(), g, rules, 1); report.FishEaten != 0 {
+			t.Errorf("%s: a shark with no prey ate %d fish", name, report.FishEaten)
+		}
+	}
+}
+
+func TestFoodWebMealEnergy(t *testing.T) {
+	web, _ := parseFoodWeb("shark eats fish 2")
+	rules := Rules{FishBreed: 10, SharkBreed: 10, StarveEnergy: 6, FoodWeb: web}
+	for _, name := range engineNames() {
+		engine, _ := engineByName(name)
+		g, shark := surroundedShark(0)
+		shark.Energy = 3
+		if report := engine.Step(context.Background(), g, rules, 1); report.FishEaten != 1 || shark.Energy != 5 {
+			t.Errorf("%s: ate %d fish and has energy %d, want 1 and 3+2", name, report.FishEaten, shark.Energy)
+		}
+	}
+	shark := &Shark{Energy: 5}
+	rules.feed(shark, &Fish{})
+	if shark.Energy != 6 {
+		t.Errorf("a meal took energy 5 to %d, want it capped at Starve 6", shark.Energy)
+	}
+}
@@ -0,0 +1,200 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file frame.go
+ * @brief Immutable snapshots of the grid for renderers, statistics, and servers.
+ */
+package main
+
+import "io"
+
+/**
+ * @brief Compact species code stored per cell in a Frame.
+ */
+type Species byte
+
+const (
+	SpeciesNone  Species = iota ///< Empty cell
+	SpeciesFish                 ///< Cell holds a fish
+	SpeciesShark                ///< Cell holds a shark
+)
+
+/**
+ * @brief Returns the species code of an entity.
+ */
+func speciesOf(e Entity) Species {
+	switch e.(type) {
+	case *Fish:
+		return SpeciesFish
+	case *Shark:
+		return SpeciesShark
+	}
+	return SpeciesNone
+}
+
+/**
+ * @struct Frame
+ * @brief Read-only copy of one chronon: a species byte per cell plus metadata.
+ * @details Fields are unexported so consumers cannot modify a frame that other goroutines
+ * may be reading at the same time.
+ */
+type Frame struct {
+	chronon   int
+	size      int
+	cells     []Species    ///< Row-major species codes
+	energy    []uint16     ///< Row-major shark energy (0 for other cells), saturating at 65535
+	young     []bool       ///< Row-major juvenile fish; nil when there are none
+	tide      []bool       ///< Row-major cells under a red tide; nil when there are none
+	temp      *Temperature ///< Temperature overlay; nil unless -temperature-overlay is set
+	lineage   []uint8      ///< Row-major lineage tags of tagged animals; nil when none is tagged
+	fish      int
+	sharks    int
+	total     int ///< Total shark energy
+	juveniles int ///< Juvenile fish
+	tideCells int ///< Cells under a red tide
+}
+
+/**
+ * @brief Copies the grid into a new frame.
+ * @param g The grid to copy; must not be mutated during the call.
+ * @return The new frame.
+ */
+func newFrame(g *Grid) *Frame {
+	f := &Frame{chronon: g.Chronon, size: g.Size, cells: make([]Species, g.Size*g.Size), energy: make([]uint16, g.Size*g.Size)}
+	for x := 0; x < g.Size; x++ {
+		for y := g.nextOccupied(x, 0); y < g.Size; y = g.nextOccupied(x, y+1) {
+			sp := g.speciesAt(x, y)
+			f.cells[x*g.Size+y] = sp
+			if tag := lineageOf(g.At(x, y)); tag > 0 {
+				f.markLineage(x*g.Size+y, tag)
+			}
+			switch sp {
+			case SpeciesFish:
+				f.fish++
+				if g.At(x, y).(*Fish).Juvenile > 0 {
+					f.markJuvenile(x*g.Size + y)
+				}
+			case SpeciesShark:
+				f.sharks++
+				e := g.At(x, y).(*Shark).Energy
+				f.total += e
+				f.energy[x*g.Size+y] = uint16(min(max(e, 0), 65535))
+			}
+		}
+	}
+	return f
+}
+
+/** @brief Returns the chronon the frame was taken at. */
+func (f *Frame) Chronon() int { return f.chronon }
+
+/** @brief Returns the grid dimension. */
+func (f *Frame) Size() int { return f.size }
+
+/** @brief Returns the species at (x, y). */
+func (f *Frame) At(x, y int) Species { return f.cells[x*f.size+y] }
+
+/** @brief Returns the energy of the shark at (x, y), or 0 if the cell holds no shark. */
+func (f *Frame) Energy(x, y int) int { return int(f.energy[x*f.size+y]) }
+
+/** @brief Reports whether (x, y) holds a juvenile fish. */
+func (f *Frame) Juvenile(x, y int) bool { return f.young != nil && f.young[x*f.size+y] }
+
+/** @brief Returns the number of juvenile fish; the rest of the fish are adults. */
+func (f *Frame) Juveniles() int { return f.juveniles }
+
+/** @brief Marks the fish in row-major cell i as juvenile. */
+func (f *Frame) markJuvenile(i int) {
+	if f.young == nil {
+		f.young = make([]bool, len(f.cells)) ///< Runs without life stages never allocate it
+	}
+	f.young[i] = true
+	f.juveniles++
+}
+
+/** @brief Reports whether (x, y) lies under a red tide. */
+func (f *Frame) Tide(x, y int) bool { return f.tide != nil && f.tide[x*f.size+y] }
+
+/** @brief Returns the number of cells under a red tide. */
+func (f *Frame) TideCells() int { return f.tideCells }
+
+/** @brief Marks row-major cell i as under a red tide. */
+func (f *Frame) markTide(i int) {
+	if f.tide == nil {
+		f.tide = make([]bool, len(f.cells)) ///< Runs without tides never allocate it
+	}
+	f.tide[i] = true
+	f.tideCells++
+}
+
+/** @brief Returns the temperature of (x, y), and whether the frame carries a temperature overlay. */
+func (f *Frame) Temperature(x, y int) (float32, bool) {
+	if f.temp == nil {
+		return 0, false
+	}
+	return f.temp.At(x, y), true
+}
+
+/** @brief Returns the lineage of the animal at (x, y), or 0 if it is untagged or the cell is empty. */
+func (f *Frame) Lineage(x, y int) int {
+	if f.lineage == nil {
+		return 0
+	}
+	return int(f.lineage[x*f.size+y])
+}
+
+/** @brief Marks the animal in row-major cell i as belonging to a lineage. */
+func (f *Frame) markLineage(i, tag int) {
+	if f.lineage == nil {
+		f.lineage = make([]uint8, len(f.cells)) ///< Runs without -tag never allocate it
+	}
+	f.lineage[i] = uint8(tag)
+}
+
+/**
+ * @brief Counts the fish and sharks of every lineage.
+ * @param lineages Number of lineages of the run.
+ * @return Per-lineage counts; index k holds lineage k+1.
+ */
+func (f *Frame) LineageCounts(lineages int) (fish, sharks []int) {
+	fish, sharks = make([]int, lineages), make([]int, lineages)
+	for i, tag := range f.lineage {
+		if tag == 0 || int(tag) > lineages {
+			continue
+		}
+		if f.cells[i] == SpeciesFish {
+			fish[tag-1]++
+		} else {
+			sharks[tag-1]++
+		}
+	}
+	return fish, sharks
+}
+
+/** @brief Returns the fish and shark populations. */
+func (f *Frame) Counts() (numFish, numSharks int) { return f.fish, f.sharks }
+
+/** @brief Returns the mean energy of the sharks, or 0 if there are none. */
+func (f *Frame) MeanSharkEnergy() float64 {
+	if f.sharks == 0 {
+		return 0
+	}
+	return float64(f.total) / float64(f.sharks)
+}
+
+/**
+ * @brief Writes the frame with borders and ANSI colours, in the same format as Grid.Print.
+ * @param w Destination writer.
+ */
+func (f *Frame) Fprint(w io.Writer) {
+	writeFrame(w, f, themes["ansi"], 0)
+}
@@ -0,0 +1,148 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file golden_test.go
+ * @brief Golden-file regression tests for the simulation rules.
+ * @details Each scenario is run from a fixed seed on a single thread and the grid is hashed
+ * after every chronon. The hashes are compared against testdata/golden/<scenario>.golden so
+ * that any change to breeding, starvation, or eating shows up as a test failure.
+ * Every storage backend must reproduce the same hashes. Regenerate the files after an intentional rule change with:
+ *   go test -run TestGolden -update
+ */
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regenerate golden files instead of comparing against them")
+
+/**
+ * @struct goldenScenario
+ * @brief A seeded starting configuration and rule parameters.
+ */
+type goldenScenario struct {
+	name         string
+	seed         int64
+	size         int
+	fish, sharks int
+	fishBreed    int
+	sharkBreed   int
+	starveEnergy int
+	chronons     int
+}
+
+var goldenScenarios = []goldenScenario{
+	{name: "balanced", seed: 1, size: 16, fish: 60, sharks: 12, fishBreed: 3, sharkBreed: 3, starveEnergy: 4, chronons: 30},
+	{name: "fish-breeding", seed: 2, size: 10, fish: 5, sharks: 0, fishBreed: 2, sharkBreed: 3, starveEnergy: 4, chronons: 15},
+	{name: "shark-starvation", seed: 3, size: 10, fish: 0, sharks: 8, fishBreed: 3, sharkBreed: 10, starveEnergy: 4, chronons: 8},
+	{name: "crowded", seed: 4, size: 8, fish: 48, sharks: 16, fishBreed: 4, sharkBreed: 5, starveEnergy: 3, chronons: 20},
+}
+
+/**
+ * @brief Hashes every cell's species and counters.
+ * @param g The grid to hash.
+ * @return A 64-bit FNV-1a digest of the grid.
+ */
+func hashGrid(g *Grid) uint64 {
+	h := fnv.New64a()
+	for x := 0; x < g.Size; x++ {
+		for y := 0; y < g.Size; y++ {
+			switch e := g.At(x, y).(type) {
+			case *Fish:
+				fmt.Fprintf(h, "F%d,", e.BreedCounter)
+			case *Shark:
+				fmt.Fprintf(h, "S%d:%d,", e.BreedCounter, e.Energy)
+			default:
+				h.Write([]byte{'.'})
+			}
+		}
+	}
+	return h.Sum64()
+}
+
+/**
+ * @brief Runs a scenario and records one line per chronon.
+ * @param sc The scenario to run.
+ * @param storageName The storage backend to run on.
+ * @return Lines of the form "<chronon> <hash> fish=<n> sharks=<n>".
+ */
+func runGoldenScenario(t *testing.T, sc goldenScenario, storageName string) []string {
+	g, err := NewGridWithStorage(sc.size, storageName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Seed(sc.seed)
+	g.Initialize(sc.fish, sc.sharks, sc.starveEnergy)
+
+	var lines []string
+	record := func(chronon int) {
+		fish, sharks := g.CountEntities()
+		lines = append(lines, fmt.Sprintf("%d %016x fish=%d sharks=%d", chronon, hashGrid(g), fish, sharks))
+	}
+	record(0)
+	for c := 1; c <= sc.chronons; c++ {
+		g.MoveEntitiesWithThreads(sc.fishBreed, sc.sharkBreed, sc.starveEnergy, 1)
+		record(c)
+	}
+	return lines
+}
+
+func TestGolden(t *testing.T) {
+	for _, sc := range goldenScenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			path := filepath.Join("testdata", "golden", sc.name+".golden")
+
+			if *update {
+				got := runGoldenScenario(t, sc, "entities")
+				if err := os.WriteFile(path, []byte(strings.Join(got, "\n")+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			defer f.Close()
+
+			var want []string
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				want = append(want, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, storageName := range storageNames() {
+				got := runGoldenScenario(t, sc, storageName)
+				if len(got) != len(want) {
+					t.Fatalf("%s storage: got %d chronons, golden file has %d", storageName, len(got), len(want))
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Fatalf("%s storage: first divergence at chronon %d:\n got: %s\nwant: %s", storageName, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
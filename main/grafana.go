@@ -0,0 +1,229 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file grafana.go
+ * @brief Windowed population time series for the Grafana JSON datasource (serve's /grafana).
+ * @details serve keeps the populations and the births and deaths of its recent chronons,
+ * stamped with the wall-clock time they completed, and answers the protocol of the Grafana
+ * JSON datasource plugin (and the older SimpleJSON one) under /grafana:
+ *   GET  /grafana/         connection test
+ *   POST /grafana/metrics  the series that can be charted (also POST /grafana/search)
+ *   POST /grafana/query    datapoints of the requested series over the dashboard's time range
+ * The range is cut into buckets of the panel's interval (widened to respect maxDataPoints).
+ * Populations are the mean of the chronons in a bucket; the *_rate series are per-second rates
+ * of births, deaths, moves and chronons over the bucket. Buckets without chronons are left out.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+/**
+ * @struct seriesSample
+ * @brief One chronon of the time series.
+ */
+type seriesSample struct {
+	At      time.Time
+	Chronon int
+	Fish    int
+	Sharks  int
+	Counts  StepCounts
+}
+
+/**
+ * @struct grafanaMetric
+ * @brief A series offered to Grafana.
+ */
+type grafanaMetric struct {
+	Label string                 `json:"label"`
+	Value string                 `json:"value"`
+	rate  bool                   ///< Summed and divided by the bucket's seconds rather than averaged
+	value func(seriesSample) int ///< The sample's contribution
+}
+
+/** Series served, in the order Grafana lists them. */
+var grafanaMetrics = []grafanaMetric{
+	{"Fish", "fish", false, func(s seriesSample) int { return s.Fish }},
+	{"Sharks", "sharks", false, func(s seriesSample) int { return s.Sharks }},
+	{"Fish born per second", "fish_born_rate", true, func(s seriesSample) int { return s.Counts.FishBorn }},
+	{"Sharks born per second", "sharks_born_rate", true, func(s seriesSample) int { return s.Counts.SharksBorn }},
+	{"Fish eaten per second", "fish_eaten_rate", true, func(s seriesSample) int { return s.Counts.FishEaten }},
+	{"Sharks starved per second", "sharks_starved_rate", true, func(s seriesSample) int { return s.Counts.SharksStarved }},
+	{"Fish crowded out per second", "fish_crowded_rate", true, func(s seriesSample) int { return s.Counts.FishCrowded }},
+	{"Fish poisoned per second", "fish_poisoned_rate", true, func(s seriesSample) int { return s.Counts.FishPoisoned }},
+	{"Moves per second", "moves_rate", true, func(s seriesSample) int { return s.Counts.Moves }},
+	{"Chronons per second", "chronons_rate", true, func(seriesSample) int { return 1 }},
+}
+
+/**
+ * @struct TimeSeries
+ * @brief The most recent chronons of a simulation, safe for concurrent recording and queries.
+ */
+type TimeSeries struct {
+	mu      sync.Mutex
+	samples []seriesSample ///< Ring buffer, oldest at next once full
+	next    int
+	full    bool
+}
+
+/**
+ * @brief Creates a series keeping at most capacity chronons.
+ */
+func NewTimeSeries(capacity int) *TimeSeries {
+	return &TimeSeries{samples: make([]seriesSample, capacity)}
+}
+
+/**
+ * @brief Appends a chronon stamped with the current time; usable as a stats hook.
+ */
+func (ts *TimeSeries) Record(p Population, report StepReport) {
+	ts.add(seriesSample{At: time.Now(), Chronon: p.Chronon, Fish: p.Fish, Sharks: p.Sharks, Counts: report.StepCounts})
+}
+
+/**
+ * @brief Appends a sample, overwriting the oldest once full.
+ */
+func (ts *TimeSeries) add(s seriesSample) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.samples[ts.next] = s
+	if ts.next++; ts.next == len(ts.samples) {
+		ts.next, ts.full = 0, true
+	}
+}
+
+/**
+ * @brief Returns the samples in [from, to), oldest first.
+ */
+func (ts *TimeSeries) window(from, to time.Time) []seriesSample {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ordered := ts.samples[:ts.next]
+	if ts.full {
+		ordered = append(slices.Clone(ts.samples[ts.next:]), ts.samples[:ts.next]...)
+	}
+	start, _ := slices.BinarySearchFunc(ordered, from, func(s seriesSample, t time.Time) int { return s.At.Compare(t) })
+	end, _ := slices.BinarySearchFunc(ordered, to, func(s seriesSample, t time.Time) int { return s.At.Compare(t) })
+	return slices.Clone(ordered[start:end])
+}
+
+/**
+ * @struct grafanaQuery
+ * @brief Body of POST /grafana/query, as sent by the JSON datasource.
+ */
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs    int64 `json:"intervalMs"`
+	MaxDataPoints int   `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+		Hide   bool   `json:"hide"`
+	} `json:"targets"`
+}
+
+/**
+ * @struct grafanaSeries
+ * @brief One series of a query response: datapoints are [value, unix milliseconds].
+ */
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	RefID      string       `json:"refId,omitempty"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+/**
+ * @brief Buckets samples and evaluates a metric on every bucket that has chronons.
+ * @param samples The samples, oldest first.
+ * @param from Start of the first bucket.
+ * @param interval Bucket width.
+ */
+func (m grafanaMetric) points(samples []seriesSample, from time.Time, interval time.Duration) [][2]float64 {
+	points := [][2]float64{}
+	for i := 0; i < len(samples); {
+		bucket := samples[i].At.Sub(from) / interval
+		start := from.Add(bucket * interval)
+		sum, n := 0, 0
+		for ; i < len(samples) && samples[i].At.Before(start.Add(interval)); i++ {
+			sum += m.value(samples[i])
+			n++
+		}
+		v := float64(sum) / float64(n)
+		if m.rate {
+			v = float64(sum) / interval.Seconds()
+		}
+		points = append(points, [2]float64{v, float64(start.UnixMilli())})
+	}
+	return points
+}
+
+/**
+ * @brief Registers the /grafana endpoints for a series on mux.
+ */
+func (ts *TimeSeries) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /grafana/{$}", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("POST /grafana/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, grafanaMetrics)
+	})
+	mux.HandleFunc("POST /grafana/search", func(w http.ResponseWriter, _ *http.Request) {
+		names := make([]string, len(grafanaMetrics))
+		for i, m := range grafanaMetrics {
+			names[i] = m.Value
+		}
+		writeJSON(w, http.StatusOK, names)
+	})
+	mux.HandleFunc("POST /grafana/query", ts.serveQuery)
+}
+
+/**
+ * @brief Handles POST /grafana/query.
+ */
+func (ts *TimeSeries) serveQuery(w http.ResponseWriter, r *http.Request) {
+	var q grafanaQuery
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&q); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decoding query: %w", err))
+		return
+	}
+	if !q.Range.From.Before(q.Range.To) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("range from %s is not before to %s", q.Range.From, q.Range.To))
+		return
+	}
+	interval := max(time.Duration(q.IntervalMs)*time.Millisecond, time.Millisecond)
+	if q.MaxDataPoints > 0 {
+		interval = max(interval, q.Range.To.Sub(q.Range.From)/time.Duration(q.MaxDataPoints)+1)
+	}
+	samples := ts.window(q.Range.From, q.Range.To)
+	out := []grafanaSeries{}
+	for _, t := range q.Targets {
+		if t.Hide || t.Target == "" {
+			continue
+		}
+		i := slices.IndexFunc(grafanaMetrics, func(m grafanaMetric) bool { return m.Value == t.Target })
+		if i < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown target %q; POST /grafana/metrics lists them", t.Target))
+			return
+		}
+		out = append(out, grafanaSeries{Target: t.Target, RefID: t.RefID, Datapoints: grafanaMetrics[i].points(samples, q.Range.From, interval)})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,90 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file grafana_test.go
+ * @brief Tests for the Grafana JSON datasource endpoints.
+ */
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrafanaQueryBucketsSeries(t *testing.T) {
+	ts := NewTimeSeries(8)
+	base := time.Date(2024, 12, 7, 12, 0, 0, 0, time.UTC)
+	for i := range 10 { ///< Two more than fit: chronons 1 and 2 are forgotten
+		ts.add(seriesSample{At: base.Add(time.Duration(i) * 500 * time.Millisecond), Chronon: i + 1,
+			Fish: 100 + 10*i, Sharks: 20, Counts: StepCounts{FishBorn: 3}})
+	}
+	mux := http.NewServeMux()
+	ts.Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	query := `{"range":{"from":"2024-12-07T12:00:00Z","to":"2024-12-07T12:00:10Z"},"intervalMs":2000,"maxDataPoints":100,
+		"targets":[{"target":"fish","refId":"A"},{"target":"fish_born_rate","refId":"B"},{"target":"sharks","hide":true}]}`
+	resp, err := http.Post(srv.URL+"/grafana/query", "application/json", strings.NewReader(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got []grafanaSeries
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("query: %s, %v", resp.Status, err)
+	}
+	if len(got) != 2 || got[0].Target != "fish" || got[1].RefID != "B" {
+		t.Fatalf("series %+v, want fish and fish_born_rate without the hidden sharks", got)
+	}
+	ms := func(s float64) float64 { return float64(base.Add(time.Duration(s * float64(time.Second))).UnixMilli()) }
+	// Samples at 1.0-4.5s remain; the 2s buckets from 0s hold 2, 4 and 2 of them.
+	wantFish := [][2]float64{{125, ms(0)}, {155, ms(2)}, {185, ms(4)}}
+	wantRate := [][2]float64{{3, ms(0)}, {6, ms(2)}, {3, ms(4)}}
+	for i, want := range [][][2]float64{wantFish, wantRate} {
+		if len(got[i].Datapoints) != len(want) {
+			t.Fatalf("%s datapoints %v, want %v", got[i].Target, got[i].Datapoints, want)
+		}
+		for j := range want {
+			if got[i].Datapoints[j] != want[j] {
+				t.Errorf("%s datapoints %v, want %v", got[i].Target, got[i].Datapoints, want)
+				break
+			}
+		}
+	}
+
+	for path, want := range map[string]string{"/grafana/metrics": `"value":"sharks_starved_rate"`, "/grafana/search": `"chronons_rate"`} {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), want) {
+			t.Errorf("%s: %s lacks %s", path, body, want)
+		}
+	}
+
+	resp, err = http.Post(srv.URL+"/grafana/query", "application/json", strings.NewReader(strings.Replace(query, `"fish"`, `"whales"`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown target: %s, want 400", resp.Status)
+	}
+}
@@ -19,7 +19,10 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"math/rand"
+	"os"
+	"time"
 )
 
 /**
@@ -28,49 +31,178 @@ import (
  * @details The grid holds all entities (fish and sharks) and tracks their positions.
  */
 type Grid struct {
-	Size  int        ///< Dimensions of the grid
-	Cells [][]Entity ///< Holds entities at each grid position
+	Size    int         ///< Dimensions of the grid
+	Chronon int         ///< Number of chronons simulated so far
+	store   storage     ///< Holds entities at each grid position
+	rng     *rand.Rand  ///< Source of all randomness for placement and movement
+	adj     *adjacency  ///< Wrapped neighbour indices, shared by all grids of this size
+	order   []int32     ///< Cell permutation reused by the "random" update order
+	workers workerRands ///< Per-worker random sources, reseeded every chronon
 }
 
 /**
  * @brief Creates a new Grid of the specified size with empty cells.
+ * @details Uses the "entities" storage backend. The grid's random source is seeded from the
+ * clock; call Seed for reproducible runs.
  * @param size The dimensions of the grid (size x size).
  * @return A pointer to the newly created Grid.
  */
 func NewGrid(size int) *Grid {
+	return newGridWithStorage(size, newEntityStorage)
+}
+
+/**
+ * @brief Creates a new empty Grid backed by the named storage.
+ * @param size The dimensions of the grid (size x size).
+ * @param storageName A -storage name such as "entities" or "cells".
+ * @return The new Grid, or an error if the storage name is unknown.
+ */
+func NewGridWithStorage(size int, storageName string) (*Grid, error) {
+	newStore, err := storageByName(storageName)
+	if err != nil {
+		return nil, err
+	}
+	return newGridWithStorage(size, newStore), nil
+}
+
+/**
+ * @brief Creates a new empty Grid with the given storage constructor.
+ */
+func newGridWithStorage(size int, newStore func(size int) storage) *Grid {
+	return &Grid{Size: size, store: newStore(size), rng: rand.New(rand.NewSource(time.Now().UnixNano())), adj: adjacencyFor(size)}
+}
+
+/**
+ * @brief Returns the entity at (x, y), or nil if the cell is empty.
+ */
+func (g *Grid) At(x, y int) Entity {
+	return g.store.at(x, y)
+}
+
+/**
+ * @brief Returns the species at (x, y) without loading its entity.
+ * @details The engines' scans and neighbour searches use this instead of type assertions on At.
+ */
+func (g *Grid) speciesAt(x, y int) Species {
+	return g.store.species(x, y)
+}
+
+/**
+ * @brief Places e at (x, y); a nil entity empties the cell.
+ */
+func (g *Grid) Set(x, y int, e Entity) {
+	g.store.set(x, y, e)
+}
+
+/**
+ * @brief Returns the first column from y on in row x that may hold an entity, or Size.
+ * @details Lets full-grid loops jump over regions the storage knows to be empty, e.g.
+ * unallocated chunks: for y := g.nextOccupied(x, 0); y < g.Size; y = g.nextOccupied(x, y+1).
+ * Dense storages return y itself.
+ */
+func (g *Grid) nextOccupied(x, y int) int {
+	return g.store.next(x, y)
+}
+
+/**
+ * @brief Reports whether (x, y) is occupied; as a new grid, whether someone has moved there.
+ * @details Makes a new grid usable as the cellClaims of the sections engine.
+ */
+func (g *Grid) claimed(x, y int) bool {
+	return g.speciesAt(x, y) != SpeciesNone
+}
+
+/**
+ * @brief Returns an empty grid of the same size and storage kind, used as the next frame.
+ * @details The result has no random source of its own; only its cells are adopted.
+ */
+func (g *Grid) emptyLike() *Grid {
+	return &Grid{Size: g.Size, Chronon: g.Chronon, store: g.store.empty(), adj: g.adj}
+}
+
+/**
+ * @brief Allocates an empty size x size cell matrix.
+ * @param size The dimensions of the matrix.
+ * @return The new cell matrix.
+ */
+func newCells(size int) [][]Entity {
 	cells := make([][]Entity, size)
 	for i := range cells {
 		cells[i] = make([]Entity, size)
 	}
-	return &Grid{Size: size, Cells: cells}
+	return cells
+}
+
+/**
+ * @brief Reseeds the grid's random source.
+ * @details With a fixed seed and a single thread, placement and every chronon are fully
+ * reproducible. With several threads each worker receives its own seed derived from this one.
+ * @param seed The seed value.
+ */
+func (g *Grid) Seed(seed int64) {
+	g.rng = rand.New(rand.NewSource(seed))
 }
 
 /**
  * @brief Initialises and populates the grid with a specified number of fish and sharks.
+ * @details The empty cells are listed in row order and the first numFish+numSharks of a
+ * Fisher-Yates shuffle drawn from the grid's seed receive the fish and then the sharks, so
+ * placement takes the same time however full the grid gets and is reproducible from the seed.
+ * On an empty grid filled to less than a quarter the list is only virtual: the i-th empty
+ * cell is cell i, and a map holds the few positions the shuffle has swapped, so a sparse
+ * storage never pays for a list of every cell. Both draw the same cells from the same seed.
  * @param numFish The number of fish to add to the grid.
  * @param numSharks The number of sharks to add to the grid.
+ * @param sharkEnergy The starting energy of every shark.
+ * @return An error, with the grid unchanged, if there are fewer empty cells than entities.
  */
-func (g *Grid) Initialize(numFish, numSharks int) {
-	for i := 0; i < numFish; i++ {
-		g.addEntity(&Fish{}) ///< Add fish to random positions
+func (g *Grid) Initialize(numFish, numSharks, sharkEnergy int) error {
+	d, _ := g.Density(WholeGrid(g.Size))
+	n := numFish + numSharks
+	if numFish < 0 || numSharks < 0 || n > d.Empty() {
+		return fmt.Errorf("cannot place %d fish and %d sharks in %d empty cells", numFish, numSharks, d.Empty())
 	}
-	for i := 0; i < numSharks; i++ {
-		g.addEntity(&Shark{Energy: 4}) ///< Add sharks with initial energy to random positions
+	var free []int          ///< Row-major indices of the empty cells, when listed
+	var swapped map[int]int ///< Otherwise, the cells moved to each swapped position of the virtual list
+	if d.Empty() < g.Size*g.Size || 4*n >= d.Empty() {
+		free = make([]int, 0, d.Empty())
+		for x := 0; x < g.Size; x++ {
+			if fish, sharks := g.rowCount(x); fish+sharks == g.Size {
+				continue ///< Full row
+			}
+			for y := 0; y < g.Size; y++ {
+				if g.speciesAt(x, y) == SpeciesNone {
+					free = append(free, x*g.Size+y)
+				}
+			}
+		}
+	} else {
+		swapped = make(map[int]int, n)
 	}
-}
-
-/**
- * @brief Places a fish or shark in a random unoccupied cell on the grid.
- * @param e The entity (fish or shark) to place on the grid.
- */
-func (g *Grid) addEntity(e Entity) {
-	for {
-		x, y := rand.Intn(g.Size), rand.Intn(g.Size) ///< Randomly select grid position
-		if g.Cells[x][y] == nil {                    ///< Place entity only if cell is empty
-			g.Cells[x][y] = e
-			break
+	for i := 0; i < n; i++ {
+		j := i + g.rng.Intn(d.Empty()-i) ///< Partial shuffle: only the cells used are drawn
+		var cell int
+		if free != nil {
+			free[i], free[j] = free[j], free[i]
+			cell = free[i]
+		} else {
+			cell = j
+			if c, ok := swapped[j]; ok {
+				cell = c
+			}
+			if c, ok := swapped[i]; ok {
+				swapped[j] = c
+			} else {
+				swapped[j] = i ///< Position i is never drawn again, so only j needs recording
+			}
 		}
+		var e Entity = &Fish{}
+		if i >= numFish {
+			e = &Shark{Energy: sharkEnergy}
+		}
+		g.Set(cell/g.Size, cell%g.Size, e)
 	}
+	return nil
 }
 
 /**
@@ -79,14 +211,9 @@ func (g *Grid) addEntity(e Entity) {
  */
 func (g *Grid) CountEntities() (numFish, numSharks int) {
 	for x := 0; x < g.Size; x++ {
-		for y := 0; y < g.Size; y++ {
-			if _, ok := g.Cells[x][y].(*Fish); ok {
-				numFish++ ///< Increment fish count
-			}
-			if _, ok := g.Cells[x][y].(*Shark); ok {
-				numSharks++ ///< Increment shark count
-			}
-		}
+		fish, sharks := g.rowCount(x) ///< Kept by the storage as cells are set
+		numFish += fish
+		numSharks += sharks
 	}
 	return
 }
@@ -95,17 +222,26 @@ func (g *Grid) CountEntities() (numFish, numSharks int) {
  * @brief Displays the current state of the grid with borders for clarity.
  */
 func (g *Grid) Print() {
-	fmt.Println("+---------------------+")
-	for _, row := range g.Cells {
-		fmt.Print("| ")
-		for _, cell := range row {
-			if cell == nil {
-				fmt.Print(". ") ///< Print "." for empty cells
+	g.Fprint(os.Stdout)
+}
+
+/**
+ * @brief Writes the current state of the grid with borders to w.
+ * @param w Destination writer (e.g. stdout for rendering, stderr for diagnostics).
+ */
+func (g *Grid) Fprint(w io.Writer) {
+	b := append([]byte(nil), frameBorder...)
+	for x := 0; x < g.Size; x++ {
+		b = append(b, "| "...)
+		for y := 0; y < g.Size; y++ {
+			if sp := g.speciesAt(x, y); sp == SpeciesNone {
+				b = append(b, ". "...) ///< Print "." for empty cells
 			} else {
-				fmt.Print(cell.Symbol(), " ") ///< Print the symbol of the entity in the cell
+				b = append(b, entitySymbols[sp]...) ///< Print the symbol of the entity in the cell
+				b = append(b, ' ')
 			}
 		}
-		fmt.Println("|")
+		b = append(b, "|\n"...)
 	}
-	fmt.Println("+---------------------+")
+	w.Write(append(b, frameBorder...)) ///< One write for the whole grid
 }
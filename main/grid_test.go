@@ -0,0 +1,134 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file grid_test.go
+ * @brief Tests for random placement.
+ */
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestInitializeFillsEveryCell(t *testing.T) {
+	g := NewGrid(30)
+	g.Seed(5)
+	if err := g.Initialize(600, 300, 4); err != nil {
+		t.Fatal(err)
+	}
+	if fish, sharks := g.CountEntities(); fish != 600 || sharks != 300 {
+		t.Errorf("placed %d fish and %d sharks, want 600 and 300", fish, sharks)
+	}
+
+	again := NewGrid(30)
+	again.Seed(5)
+	again.Initialize(600, 300, 4)
+	if a, b := NewCheckpoint(g, Config{}).Entities, NewCheckpoint(again, Config{}).Entities; !slices.Equal(a, b) {
+		t.Errorf("placement with the same seed differs: %s", describeDifference(a, b))
+	}
+}
+
+func TestInitializeSparseMatchesListedShuffle(t *testing.T) {
+	const size, fish, sharks = 40, 90, 30 ///< Under a quarter full, so the free list stays virtual
+	g := NewGrid(size)
+	g.Seed(9)
+	if err := g.Initialize(fish, sharks, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	free := make([]int, size*size) ///< The listed shuffle, drawn from the same seed
+	for i := range free {
+		free[i] = i
+	}
+	rng := rand.New(rand.NewSource(9))
+	for i := 0; i < fish+sharks; i++ {
+		j := i + rng.Intn(len(free)-i)
+		free[i], free[j] = free[j], free[i]
+		want := SpeciesFish
+		if i >= fish {
+			want = SpeciesShark
+		}
+		if got := g.speciesAt(free[i]/size, free[i]%size); got != want {
+			t.Fatalf("entity %d: cell %d holds species %d, want %d", i, free[i], got, want)
+		}
+	}
+	if f, s := g.CountEntities(); f != fish || s != sharks {
+		t.Errorf("placed %d fish and %d sharks, want %d and %d", f, s, fish, sharks)
+	}
+}
+
+func TestInitializeRejectsOverCapacity(t *testing.T) {
+	g := NewGrid(4)
+	g.Seed(1)
+	g.Set(0, 0, &Fish{})
+	if err := g.Initialize(10, 6, 4); err == nil {
+		t.Fatal("placing 16 entities in 15 empty cells should fail")
+	}
+	if fish, sharks := g.CountEntities(); fish != 1 || sharks != 0 {
+		t.Errorf("a failed placement changed the grid: %d fish, %d sharks", fish, sharks)
+	}
+	if err := g.Initialize(9, 6, 4); err != nil {
+		t.Errorf("filling the 15 empty cells: %v", err)
+	}
+}
+
+func TestNeighboursWrapTheTorus(t *testing.T) {
+	for _, size := range []int{1, 2, 7} {
+		g := NewGrid(size)
+		for x := range size {
+			for y := range size {
+				for d, off := range neighbourOffsets {
+					wantX, wantY := (x+off[0]+size)%size, (y+off[1]+size)%size
+					if gotX, gotY := g.neighbour(x, y, d); gotX != wantX || gotY != wantY {
+						t.Fatalf("size %d: neighbour %d of (%d,%d) = (%d,%d), want (%d,%d)", size, d, x, y, gotX, gotY, wantX, wantY)
+					}
+				}
+			}
+		}
+		if g.emptyLike().adj != g.adj {
+			t.Errorf("size %d: the next frame built its own neighbour table", size)
+		}
+	}
+
+	seen := map[[4]int]bool{}
+	for _, order := range directionOrders {
+		sorted := order
+		slices.Sort(sorted[:])
+		if sorted != [4]int{0, 1, 2, 3} || seen[order] {
+			t.Fatalf("direction orders %v: %v is not a new permutation", directionOrders, order)
+		}
+		seen[order] = true
+	}
+}
+
+func BenchmarkGridAllocation(b *testing.B) {
+	for _, name := range storageNames() {
+		for _, size := range []int{200, 1000} {
+			b.Run(fmt.Sprintf("%s/size=%d", name, size), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					NewGridWithStorage(size, name)
+				}
+			})
+			b.Run(fmt.Sprintf("%s/size=%d/next-frame", name, size), func(b *testing.B) {
+				g, _ := NewGridWithStorage(size, name)
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					g.emptyLike() ///< Allocated once per chronon by the sections engine
+				}
+			})
+		}
+	}
+}
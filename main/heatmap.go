@@ -0,0 +1,137 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file heatmap.go
+ * @brief Accumulates per-cell occupancy over a run and exports it as heatmap images.
+ * @details Each recorded chronon adds one visit to every cell holding a fish or shark,
+ * so the exported images show where each species spends its time.
+ */
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+/**
+ * @struct Heatmap
+ * @brief Per-cell visit counters for fish and sharks.
+ */
+type Heatmap struct {
+	Size   int     ///< Dimensions of the tracked grid
+	Fish   [][]int ///< Number of recorded chronons each cell held a fish
+	Sharks [][]int ///< Number of recorded chronons each cell held a shark
+}
+
+/**
+ * @brief Creates an empty Heatmap for a grid of the given size.
+ * @param size The dimensions of the grid (size x size).
+ * @return A pointer to the newly created Heatmap.
+ */
+func NewHeatmap(size int) *Heatmap {
+	fish := make([][]int, size)
+	sharks := make([][]int, size)
+	for i := 0; i < size; i++ {
+		fish[i] = make([]int, size)
+		sharks[i] = make([]int, size)
+	}
+	return &Heatmap{Size: size, Fish: fish, Sharks: sharks}
+}
+
+/**
+ * @brief Adds the occupancy of a frame to the visit counters.
+ * @param f The frame to sample; frames of a different size from the heatmap (after a resize) are skipped.
+ */
+func (h *Heatmap) Record(f *Frame) {
+	if f.Size() != h.Size {
+		return
+	}
+	for x := 0; x < f.Size(); x++ {
+		for y := 0; y < f.Size(); y++ {
+			switch f.At(x, y) {
+			case SpeciesFish:
+				h.Fish[x][y]++
+			case SpeciesShark:
+				h.Sharks[x][y]++
+			}
+		}
+	}
+}
+
+/**
+ * @brief Writes one PNG heatmap per species.
+ * @details Files are named "<prefix>-fish.png" and "<prefix>-sharks.png". Intensity is
+ * normalised against the busiest cell of each species.
+ * @param prefix Path prefix for the generated files.
+ * @return An error if either image could not be written.
+ */
+func (h *Heatmap) WritePNGs(prefix string) error {
+	if err := writeHeatmapPNG(prefix+"-fish.png", h.Fish, color.RGBA{0, 255, 0, 255}); err != nil {
+		return err
+	}
+	return writeHeatmapPNG(prefix+"-sharks.png", h.Sharks, color.RGBA{255, 0, 0, 255})
+}
+
+/**
+ * @brief Renders a counter matrix as a black-to-colour ramp and saves it as PNG.
+ * @details Small grids are scaled up so that the output is at least roughly 512 pixels wide.
+ * @param path Destination file path.
+ * @param counts The per-cell visit counters to render.
+ * @param hot The colour used for the most visited cell.
+ * @return An error if the file could not be created or encoded.
+ */
+func writeHeatmapPNG(path string, counts [][]int, hot color.RGBA) error {
+	size := len(counts)
+	scale := 1
+	if size > 0 && size < 512 {
+		scale = 512 / size
+	}
+
+	maxCount := 0
+	for _, row := range counts {
+		for _, c := range row {
+			if c > maxCount {
+				maxCount = c
+			}
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size*scale, size*scale))
+	for x, row := range counts {
+		for y, c := range row {
+			shade := color.RGBA{0, 0, 0, 255}
+			if maxCount > 0 {
+				shade.R = uint8(int(hot.R) * c / maxCount)
+				shade.G = uint8(int(hot.G) * c / maxCount)
+				shade.B = uint8(int(hot.B) * c / maxCount)
+			}
+			// Rows of the grid map to image rows, so x is the vertical axis as in Grid.Print.
+			for py := x * scale; py < (x+1)*scale; py++ {
+				for px := y * scale; px < (y+1)*scale; px++ {
+					img.SetRGBA(px, py, shade)
+				}
+			}
+		}
+	}
+
+	f, err := createOutput(path)
+	if err != nil {
+		return fmt.Errorf("creating heatmap %s: %w", path, err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("encoding heatmap %s: %w", path, err)
+	}
+	return f.Close()
+}
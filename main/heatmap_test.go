@@ -0,0 +1,147 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file heatmap_test.go
+ * @brief Golden-file test of heatmap counting and rendering, and checks of the colour ramp.
+ * @details The "balanced" golden scenario is recorded into a heatmap every chronon; the visit
+ * counts of each cell and a digest of each rendered PNG's pixels are compared against
+ * testdata/golden/heatmap-balanced.golden. Regenerate it after an intentional change with:
+ *   go test -run TestHeatmapGolden -update
+ */
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Decodes a PNG written by the heatmap.
+ */
+func readHeatmapPNG(t *testing.T, path string) image.Image {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+/**
+ * @brief Describes a heatmap as text: its counters row by row, then the size and pixel digest of each image.
+ */
+func describeHeatmap(t *testing.T, h *Heatmap) string {
+	var b strings.Builder
+	for _, species := range []struct {
+		name   string
+		counts [][]int
+	}{{"fish", h.Fish}, {"sharks", h.Sharks}} {
+		fmt.Fprintln(&b, species.name)
+		for _, row := range species.counts {
+			fmt.Fprintln(&b, strings.Trim(fmt.Sprint(row), "[]"))
+		}
+	}
+
+	prefix := filepath.Join(t.TempDir(), "heat")
+	if err := h.WritePNGs(prefix); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"fish", "sharks"} {
+		img := readHeatmapPNG(t, prefix+"-"+name+".png")
+		digest := fnv.New64a()
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				digest.Write([]byte{c.R, c.G, c.B, c.A})
+			}
+		}
+		fmt.Fprintf(&b, "%s.png %dx%d %016x\n", name, bounds.Dx(), bounds.Dy(), digest.Sum64())
+	}
+	return b.String()
+}
+
+func TestHeatmapGolden(t *testing.T) {
+	sc := goldenScenarios[0] ///< balanced
+	g := NewGrid(sc.size)
+	g.Seed(sc.seed)
+	g.Initialize(sc.fish, sc.sharks, sc.starveEnergy)
+	h := NewHeatmap(sc.size)
+	h.Record(newFrame(g))
+	for c := 1; c <= sc.chronons; c++ {
+		g.MoveEntitiesWithThreads(sc.fishBreed, sc.sharkBreed, sc.starveEnergy, 1)
+		h.Record(newFrame(g))
+	}
+	h.Record(newFrame(NewGrid(sc.size + 1))) ///< A frame of another size, as after a resize, is skipped
+	got := describeHeatmap(t, h)
+
+	path := filepath.Join("testdata", "golden", "heatmap-"+sc.name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("heatmap differs from %s:\n got:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestHeatmapColourRamp(t *testing.T) {
+	counts := [][]int{{0, 1}, {2, 4}}
+	path := filepath.Join(t.TempDir(), "ramp.png")
+	if err := writeHeatmapPNG(path, counts, color.RGBA{200, 100, 40, 255}); err != nil {
+		t.Fatal(err)
+	}
+	img := readHeatmapPNG(t, path)
+	if b := img.Bounds(); b.Dx() != 512 || b.Dy() != 512 {
+		t.Fatalf("a 2x2 heatmap rendered at %dx%d, want 512x512", b.Dx(), b.Dy())
+	}
+	cases := []struct {
+		x, y int ///< Pixel inside the cell's 256x256 block
+		want color.RGBA
+	}{
+		{10, 10, color.RGBA{0, 0, 0, 255}},        ///< Never visited: black
+		{300, 10, color.RGBA{50, 25, 10, 255}},    ///< A quarter of the busiest cell
+		{10, 300, color.RGBA{100, 50, 20, 255}},   ///< Half: rows of the grid are image rows
+		{511, 511, color.RGBA{200, 100, 40, 255}}, ///< The busiest cell gets the full colour
+	}
+	for _, c := range cases {
+		if got := color.RGBAModel.Convert(img.At(c.x, c.y)).(color.RGBA); got != c.want {
+			t.Errorf("pixel (%d,%d) = %v, want %v", c.x, c.y, got, c.want)
+		}
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.png")
+	if err := writeHeatmapPNG(empty, [][]int{{0, 0}, {0, 0}}, color.RGBA{255, 0, 0, 255}); err != nil {
+		t.Fatal(err)
+	}
+	if got := color.RGBAModel.Convert(readHeatmapPNG(t, empty).At(400, 400)).(color.RGBA); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("a heatmap with no visits has pixel %v, want black", got)
+	}
+}
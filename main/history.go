@@ -0,0 +1,235 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file history.go
+ * @brief Rewinding the terminal display through recent chronons (the -history option).
+ * @details Frames are immutable snapshots, so the last K of them can simply be kept in a
+ * ring buffer; memory is bounded at about 3 bytes per cell per kept frame, whatever the
+ * length of the run. Pausing blocks the chronon-start hook that draws each frame, which
+ * holds the simulation between chronons without touching the engine. While paused, [ and ]
+ * step back and forward through the kept frames; ] on the newest frame runs exactly one more
+ * chronon. p pauses and resumes (resuming returns to the live frame).
+ */
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+)
+
+/**
+ * @struct History
+ * @brief A renderer that keeps the last frames and can pause on, and rewind through, them.
+ * @details Safe for concurrent use: Render runs on the simulation's goroutine while keys
+ * arrive on the controls goroutine.
+ */
+type History struct {
+	mu       sync.Mutex
+	resumed  *sync.Cond ///< Signalled when the pause ends or a single step is requested
+	frames   []*Frame   ///< Ring buffer of the newest frames
+	next     int        ///< Ring index the next frame is written to
+	count    int        ///< Frames currently kept
+	back     int        ///< How many frames behind the newest the display is (0 is live)
+	paused   bool       ///< Whether Render blocks after drawing
+	step     bool       ///< Let one chronon through, then pause again
+	released bool       ///< Set once the run is ending; never pause again
+
+	drawMu   sync.Mutex ///< Serialises drawing from both goroutines
+	Renderer Renderer   ///< Draws the selected frame
+}
+
+/**
+ * @brief Creates a history of the last k frames drawn by r.
+ * @param k Frames to keep; values below 1 are treated as 1.
+ * @param r The renderer that draws each selected frame.
+ * @return The history.
+ */
+func NewHistory(k int, r Renderer) *History {
+	h := &History{frames: make([]*Frame, max(k, 1)), Renderer: r}
+	h.resumed = sync.NewCond(&h.mu)
+	return h
+}
+
+/**
+ * @brief Keeps the frame, draws it, and waits while the display is paused.
+ * @param f The newest frame.
+ */
+func (h *History) Render(f *Frame) {
+	h.mu.Lock()
+	h.frames[h.next] = f
+	h.next = (h.next + 1) % len(h.frames)
+	h.count = min(h.count+1, len(h.frames))
+	h.back = 0 ///< A new chronon only arrives while live or single-stepping
+	h.mu.Unlock()
+
+	h.draw(f)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for h.paused && !h.step && !h.released {
+		h.resumed.Wait()
+	}
+	h.step = false
+}
+
+/**
+ * @brief Returns a kept frame.
+ * @param back 0 for the newest frame, 1 for the one before, and so on.
+ * @return The frame, or nil if it is no longer (or not yet) kept.
+ */
+func (h *History) Frame(back int) *Frame {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.frameLocked(back)
+}
+
+func (h *History) frameLocked(back int) *Frame {
+	if back < 0 || back >= h.count {
+		return nil
+	}
+	return h.frames[(h.next-1-back+2*len(h.frames))%len(h.frames)]
+}
+
+/**
+ * @brief Handles p (pause/resume), [ (back) and ] (forward).
+ * @details [ also pauses a running display. Other keys are not history controls, but while
+ * paused they redraw the shown frame, so that scrolling or zooming the viewport takes
+ * effect without waiting for the next chronon.
+ * @param key The key pressed.
+ * @return false if the key is not a history control.
+ */
+func (h *History) HandleKey(key byte) bool {
+	h.mu.Lock()
+	handled := true
+	switch key {
+	case 'p':
+		h.paused = !h.paused
+		if !h.paused {
+			h.back = 0
+			h.resumed.Broadcast()
+		}
+	case '[':
+		h.paused = true
+		h.back = min(h.back+1, max(h.count-1, 0))
+	case ']':
+		if !h.paused {
+			break
+		}
+		if h.back == 0 {
+			h.step = true ///< Render draws the new frame itself
+			h.resumed.Broadcast()
+			h.mu.Unlock()
+			return true
+		}
+		h.back--
+	default:
+		handled = false
+	}
+	f := h.frameLocked(h.back)
+	redraw := h.paused && f != nil
+	h.mu.Unlock()
+
+	if redraw {
+		h.draw(f)
+	}
+	return handled
+}
+
+/**
+ * @brief Reports whether the display is paused on the newest frame, where edits are allowed.
+ */
+func (h *History) PausedAtNewest() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.paused && h.back == 0 && !h.released
+}
+
+/**
+ * @brief Replaces the newest kept frame, e.g. after the grid was edited while paused.
+ * @details Does not draw; the next redraw shows the new frame.
+ * @param f The frame of the edited grid.
+ */
+func (h *History) Amend(f *Frame) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.count > 0 {
+		h.frames[(h.next-1+len(h.frames))%len(h.frames)] = f
+	}
+}
+
+/**
+ * @brief Drops every kept frame but the newest, to free memory; rewinding stops short.
+ */
+func (h *History) Trim() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	newest := h.frameLocked(0)
+	h.frames, h.next, h.back = []*Frame{newest}, 0, 0
+	h.count = min(h.count, 1)
+}
+
+/**
+ * @brief Ends any pause for good, so that a finishing run is never left waiting.
+ */
+func (h *History) Release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.released = true
+	h.resumed.Broadcast()
+}
+
+/** @brief Draws a frame, never overlapping another draw. */
+func (h *History) draw(f *Frame) {
+	h.drawMu.Lock()
+	defer h.drawMu.Unlock()
+	h.Renderer.Render(f)
+}
+
+/**
+ * @brief Receives single-key controls.
+ */
+type keyHandler interface {
+	HandleKey(key byte) bool
+}
+
+/**
+ * @brief Reads control keys until the reader is exhausted and offers each to every handler.
+ * @details Terminals deliver input a line at a time, so keys take effect after Enter;
+ * several keys may be typed on one line. A line starting with a colon is a command instead,
+ * passed whole to the handlers that are also lineHandlers (see console.go).
+ * @param r Source of keys, usually os.Stdin.
+ * @param handlers The handlers, in order.
+ */
+func readControls(r io.Reader, handlers ...keyHandler) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if cmd, ok := strings.CutPrefix(line, ":"); ok {
+			for _, h := range handlers {
+				if lh, ok := h.(lineHandler); ok {
+					lh.HandleLine(strings.TrimSpace(cmd))
+				}
+			}
+		} else {
+			for i := 0; i < len(line); i++ {
+				for _, h := range handlers {
+					h.HandleKey(line[i])
+				}
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
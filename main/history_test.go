@@ -0,0 +1,121 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file history_test.go
+ * @brief Tests for the rewind buffer.
+ */
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+/**
+ * @struct chrononRecorder
+ * @brief A renderer that records the chronon of every frame it draws.
+ */
+type chrononRecorder struct {
+	mu    sync.Mutex
+	drawn []int
+}
+
+func (r *chrononRecorder) Render(f *Frame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drawn = append(r.drawn, f.Chronon())
+}
+
+func (r *chrononRecorder) chronons() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.drawn)
+}
+
+func TestHistoryKeepsLastFrames(t *testing.T) {
+	h := NewHistory(3, &chrononRecorder{})
+	for c := 0; c < 5; c++ {
+		h.Render(&Frame{chronon: c})
+	}
+	for back, want := range []int{4, 3, 2} {
+		if f := h.Frame(back); f == nil || f.Chronon() != want {
+			t.Errorf("Frame(%d) = %v, want chronon %d", back, f, want)
+		}
+	}
+	if h.Frame(3) != nil {
+		t.Error("only 3 frames should be kept")
+	}
+}
+
+func TestHistoryTrimKeepsNewest(t *testing.T) {
+	h := NewHistory(5, &chrononRecorder{})
+	frames := []*Frame{{chronon: 1}, {chronon: 2}, {chronon: 3}}
+	for _, f := range frames {
+		h.Render(f)
+	}
+	h.Trim()
+	if h.Frame(0) != frames[2] || h.Frame(1) != nil {
+		t.Error("Trim should keep only the newest frame")
+	}
+	h.Render(&Frame{chronon: 4})
+	if h.Frame(0).Chronon() != 4 || h.Frame(1) != nil {
+		t.Error("a trimmed history should keep one frame")
+	}
+}
+
+func TestHistoryRewindAndStep(t *testing.T) {
+	rec := &chrononRecorder{}
+	h := NewHistory(4, rec)
+	for c := 0; c < 3; c++ {
+		h.Render(&Frame{chronon: c})
+	}
+	h.HandleKey('[') ///< Pauses and shows chronon 1
+	h.HandleKey('[')
+	h.HandleKey('[') ///< Nothing older than chronon 0 is kept
+	h.HandleKey(']')
+	h.HandleKey('x') ///< Not a control, but redraws while paused
+	if got, want := rec.chronons()[3:], []int{1, 0, 0, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("drew %v while rewinding, want %v", got, want)
+	}
+
+	h.HandleKey(']') ///< Back to the newest kept frame
+	if got := rec.chronons(); got[len(got)-1] != 2 {
+		t.Errorf("drew chronon %d, want the newest kept (2)", got[len(got)-1])
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.Render(&Frame{chronon: 3}) ///< Blocks: the display is paused
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Render returned while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+	h.HandleKey(']') ///< Single step: lets exactly one chronon through
+	<-done
+	if !h.paused {
+		t.Error("a single step should leave the display paused")
+	}
+
+	released := make(chan struct{})
+	go func() {
+		h.Render(&Frame{chronon: 4})
+		h.Render(&Frame{chronon: 5})
+		close(released)
+	}()
+	h.Release()
+	<-released
+}
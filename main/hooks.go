@@ -0,0 +1,85 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file hooks.go
+ * @brief Observer hooks fired by Simulation.Step.
+ * @details Renderers, stat writers, and early-stop conditions attach here instead of being
+ * wired into the simulation loop, so new consumers need no changes to the engine.
+ */
+package main
+
+/** @brief Called with the frame of a chronon before it is stepped. */
+type ChrononStartHook func(f *Frame)
+
+/** @brief Called with the frame produced by a chronon and the engine's report for it. */
+type ChrononEndHook func(f *Frame, report StepReport)
+
+/** @brief Called with the populations after a chronon and the engine's report for it. */
+type StatsHook func(p Population, report StepReport)
+
+/** @brief Called once for every event recorded during a chronon. */
+type EventHook func(e Event)
+
+/**
+ * @struct hooks
+ * @brief The hooks registered on a Simulation, in registration order.
+ */
+type hooks struct {
+	start []ChrononStartHook
+	end   []ChrononEndHook
+	stats []StatsHook
+	event []EventHook
+}
+
+/**
+ * @brief Registers a hook that runs before every chronon.
+ * @param fn The hook; receives the frame about to be stepped.
+ */
+func (s *Simulation) OnChrononStart(fn ChrononStartHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks.start = append(s.hooks.start, fn)
+}
+
+/**
+ * @brief Registers a hook that runs after every chronon.
+ * @param fn The hook; receives the new frame and the chronon's report.
+ */
+func (s *Simulation) OnChrononEnd(fn ChrononEndHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks.end = append(s.hooks.end, fn)
+}
+
+/**
+ * @brief Registers a hook that runs after every chronon without needing a frame.
+ * @details Stats hooks run after the event hooks and before the chronon-end hooks. Unlike
+ * those, they do not make Step copy the grid, so population tracking stays cheap on large
+ * grids.
+ * @param fn The hook; receives the running populations and the chronon's report.
+ */
+func (s *Simulation) OnStats(fn StatsHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks.stats = append(s.hooks.stats, fn)
+}
+
+/**
+ * @brief Registers a hook that runs for every event of a chronon.
+ * @details Event hooks run after the chronon has completed and before the chronon-end hooks.
+ * @param fn The hook.
+ */
+func (s *Simulation) OnEvent(fn EventHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks.event = append(s.hooks.event, fn)
+}
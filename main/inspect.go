@@ -0,0 +1,235 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file inspect.go
+ * @brief Entity inspection in the terminal display (the -inspect option).
+ * @details A cursor moves over the world with I/J/K/L (up, left, down, right); c selects the
+ * entity under it and C clears the selection. A side panel next to the grid shows the
+ * cursor's neighbourhood, magnified with the cursor cell in brackets so it can be found
+ * whatever the renderer, and the selected entity's ID, age, breeding counter, energy and
+ * recent positions. A selected entity is followed as it moves. Input is line-buffered like
+ * the other controls (keys take effect after Enter), which is also why cells are picked
+ * with the cursor rather than the mouse.
+ */
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+/** Cells shown on each side of the cursor in the magnifier. */
+const magnifierRadius = 2
+
+/**
+ * @struct Inspector
+ * @brief A renderer that adds an entity panel beside the grid drawn by another renderer.
+ * @details The wrapped renderer must write into the Inspector (see NewInspector), which
+ * then prints the grid and the panel side by side. Safe for concurrent use.
+ */
+type Inspector struct {
+	mu       sync.Mutex
+	registry *EntityRegistry
+	cursor   [2]int        ///< World cell under the cursor
+	selected uint64        ///< ID of the selected entity, 0 if none
+	lost     uint64        ///< ID of a selected entity that has died
+	Status   func() string ///< Optional extra panel line, e.g. the paint brush
+
+	W        io.Writer    ///< Final destination, usually os.Stdout
+	Renderer Renderer     ///< Draws the grid into the Inspector
+	buf      bytes.Buffer ///< Output of Renderer for the current frame
+}
+
+/**
+ * @brief Creates an inspector with the cursor at the centre of the world.
+ * @param registry Registry observed once per chronon.
+ * @param size World size, for the initial cursor.
+ * @param w Final destination of the combined output.
+ * @return The inspector; set Renderer to a renderer that writes into it.
+ */
+func NewInspector(registry *EntityRegistry, size int, w io.Writer) *Inspector {
+	return &Inspector{registry: registry, cursor: [2]int{size / 2, size / 2}, W: w}
+}
+
+/**
+ * @brief Buffers the wrapped renderer's output; the panel is added in Render.
+ */
+func (in *Inspector) Write(p []byte) (int, error) {
+	return in.buf.Write(p)
+}
+
+/**
+ * @brief Draws the frame through the wrapped renderer and prints it with the panel.
+ * @details The panel starts beside the first grid line, after the renderer's header.
+ * @param f The frame to draw.
+ */
+func (in *Inspector) Render(f *Frame) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.buf.Reset()
+	in.Renderer.Render(f)
+	grid := strings.Split(strings.TrimSuffix(in.buf.String(), "\n"), "\n")
+	panel := in.panel(f)
+
+	width := 0
+	for _, line := range grid {
+		width = max(width, visibleWidth(line))
+	}
+	var out strings.Builder
+	for i := 0; i < max(len(grid), len(panel)+1); i++ {
+		line := ""
+		if i < len(grid) {
+			line = grid[i]
+		}
+		out.WriteString(line)
+		if p := i - 1; p >= 0 && p < len(panel) {
+			out.WriteString(strings.Repeat(" ", width-visibleWidth(line)+3))
+			out.WriteString(panel[p])
+		}
+		out.WriteByte('\n')
+	}
+	io.WriteString(in.W, out.String())
+}
+
+/**
+ * @brief Builds the panel lines for a frame. Called with mu held.
+ */
+func (in *Inspector) panel(f *Frame) []string {
+	if in.selected != 0 {
+		if info, ok := in.registry.Lookup(in.selected); ok {
+			in.cursor = info.Trail[len(info.Trail)-1] ///< Follow the selection
+		} else {
+			in.lost, in.selected = in.selected, 0
+		}
+	}
+	x, y := wrap(in.cursor[0], f.Size()), wrap(in.cursor[1], f.Size())
+	lines := []string{fmt.Sprintf("Cursor (%d,%d): %s", x, y, speciesWord(f.At(x, y)))}
+	for dx := -magnifierRadius; dx <= magnifierRadius; dx++ {
+		var row strings.Builder
+		for dy := -magnifierRadius; dy <= magnifierRadius; dy++ {
+			glyph := map[Species]string{SpeciesNone: ".", SpeciesFish: "F", SpeciesShark: "S"}[f.At(wrap(x+dx, f.Size()), wrap(y+dy, f.Size()))]
+			if dx == 0 && dy == 0 {
+				row.WriteString("[" + glyph + "]")
+			} else {
+				row.WriteString(" " + glyph + " ")
+			}
+		}
+		lines = append(lines, row.String())
+	}
+
+	id := in.selected
+	if id == 0 {
+		id = in.registry.At(x, y)
+	}
+	info, ok := in.registry.Lookup(id)
+	switch {
+	case ok:
+		label := "Under cursor"
+		if in.selected != 0 {
+			label = "Selected"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s #%d", label, speciesWord(info.Species), info.ID),
+			fmt.Sprintf("Age %d (born chronon %d)", info.Age, info.Born),
+			fmt.Sprintf("Breed counter %d", info.BreedCounter))
+		if info.Species == SpeciesShark {
+			lines = append(lines, fmt.Sprintf("Energy %d", info.Energy))
+		}
+		trail := make([]string, len(info.Trail))
+		for i, p := range info.Trail {
+			trail[i] = fmt.Sprintf("(%d,%d)", p[0], p[1])
+		}
+		lines = append(lines, "Moves "+strings.Join(trail, " "))
+	case in.lost != 0:
+		lines = append(lines, fmt.Sprintf("#%d has died", in.lost))
+	}
+	lines = append(lines, "I/J/K/L move, c select, C clear")
+	if in.Status != nil {
+		lines = append(lines, in.Status())
+	}
+	return lines
+}
+
+/**
+ * @brief Returns the world cell under the cursor.
+ */
+func (in *Inspector) Cursor() (x, y int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.cursor[0], in.cursor[1]
+}
+
+/**
+ * @brief Handles the cursor and selection keys.
+ * @param key The key pressed.
+ * @return false if the key is not an inspector control.
+ */
+func (in *Inspector) HandleKey(key byte) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	switch key {
+	case 'I':
+		in.cursor[0]--
+	case 'K':
+		in.cursor[0]++
+	case 'J':
+		in.cursor[1]--
+	case 'L':
+		in.cursor[1]++
+	case 'c':
+		in.selected, in.lost = in.registry.At(in.cursor[0], in.cursor[1]), 0
+		return true
+	case 'C':
+		in.selected, in.lost = 0, 0
+		return true
+	default:
+		return false
+	}
+	in.selected, in.lost = 0, 0 ///< Moving the cursor lets go of the selection
+	return true
+}
+
+/**
+ * @brief Returns "fish", "shark" or "empty".
+ */
+func speciesWord(s Species) string {
+	return map[Species]string{SpeciesNone: "empty", SpeciesFish: "fish", SpeciesShark: "shark"}[s]
+}
+
+/**
+ * @brief Returns the length of a line in runes, ignoring ANSI colour sequences.
+ * @details Wide glyphs such as emoji count as one, which is harmless: every grid line holds
+ * the same number of glyphs, so the panel still starts in the same column on each of them.
+ */
+func visibleWidth(line string) int {
+	n, state := 0, 0 ///< 0: text, 1: after ESC, 2: inside a CSI sequence
+	for _, r := range line {
+		switch {
+		case state == 1:
+			state = 0
+			if r == '[' {
+				state = 2
+			}
+		case state == 2:
+			if r >= '@' && r <= '~' {
+				state = 0 ///< Final byte of the sequence
+			}
+		case r == '\033':
+			state = 1
+		default:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file inspect_test.go
+ * @brief Tests for the entity registry and the inspection panel.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRegistryTracksEntities(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := NewEntityRegistry()
+	sim.OnChrononStart(func(*Frame) { reg.Observe(sim.Grid()) })
+	reg.Observe(sim.Grid())
+	if len(reg.records) != 150 {
+		t.Fatalf("registered %d entities, want 150", len(reg.records))
+	}
+
+	var shark uint64
+	for x := 0; x < 20 && shark == 0; x++ {
+		for y := 0; y < 20; y++ {
+			if s, ok := sim.Grid().At(x, y).(*Shark); ok {
+				shark = s.ID
+				break
+			}
+		}
+	}
+	sim.Step(context.Background())
+	sim.Step(context.Background())
+	reg.Observe(sim.Grid())
+	info, ok := reg.Lookup(shark)
+	if !ok {
+		t.Fatalf("shark #%d is gone; starting with Starve energy it cannot starve within two chronons", shark)
+	}
+	if info.Age != 2 || info.Born != 0 || len(info.Trail) != 3 {
+		t.Errorf("shark #%d: age %d, born %d, trail %v; want age 2, born 0, 3 positions", shark, info.Age, info.Born, info.Trail)
+	}
+	last := info.Trail[len(info.Trail)-1]
+	if reg.At(last[0], last[1]) != shark {
+		t.Errorf("registry cell (%d,%d) does not hold shark #%d", last[0], last[1], shark)
+	}
+
+	for _, rec := range reg.records {
+		if rec.Born > 0 && rec.Age != sim.Grid().Chronon-rec.Born {
+			t.Errorf("newborn #%d has age %d", rec.ID, rec.Age)
+		}
+	}
+	fish, sharks := sim.Grid().CountEntities()
+	if len(reg.records) != fish+sharks {
+		t.Errorf("registry holds %d entities, grid %d", len(reg.records), fish+sharks)
+	}
+}
+
+func TestInspectorPanel(t *testing.T) {
+	g, err := readASCII(strings.NewReader("...\n.S.\n...\n"), 4, newEntityStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := NewEntityRegistry()
+	reg.Observe(g)
+	var out bytes.Buffer
+	in := NewInspector(reg, 3, &out)
+	in.Renderer = &TextRenderer{W: in, Theme: themes["ascii"]}
+	in.HandleKey('c')
+	in.Render(newFrame(g))
+
+	got := out.String()
+	for _, want := range []string{"Cursor (1,1): shark", "[S]", "Selected: shark #1", "Energy 4", "Moves (1,1)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("panel lacks %q:\n%s", want, got)
+		}
+	}
+	if lines := strings.Split(got, "\n"); !strings.HasPrefix(lines[0], "Step 0:") {
+		t.Errorf("panel should start beside the grid, not the header:\n%s", got)
+	}
+
+	g.Set(1, 1, nil)
+	g.Chronon++
+	reg.Observe(g)
+	out.Reset()
+	in.Render(newFrame(g))
+	if !strings.Contains(out.String(), "#1 has died") {
+		t.Errorf("panel should report the death of the selection:\n%s", out.String())
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	if n := visibleWidth(fmt.Sprint("| ", (&Fish{}).Symbol(), " |")); n != 5 {
+		t.Errorf("visibleWidth = %d, want 5", n)
+	}
+}
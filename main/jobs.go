@@ -0,0 +1,328 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file jobs.go
+ * @brief Cluster sweeps: sweep -emit-jobs slurm and the collect subcommand.
+ * @details A sweep too big for one machine is split into array tasks. The runs of a sweep
+ * (one per value and seed, or one ensemble per value) are numbered in the order a single sweep
+ * makes them, and task i of n takes runs i, i+n, i+2n, ..., so every task gets a similar mix of
+ * cheap and expensive values. -emit-jobs writes a batch script that runs "sweep -task
+ * $SLURM_ARRAY_TASK_ID -tasks n" with the sweep's flags (the seed included) into one CSV per
+ * task, and a manifest describing the tasks. collect checks that every task finished and merges
+ * their tables back into the order of a single sweep.
+ */
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/** Name of the manifest written next to the job script. */
+const jobManifestName = "manifest.json"
+
+/**
+ * @struct sweepShare
+ * @brief The runs of a sweep that one array task makes.
+ * @details The zero value makes every run.
+ */
+type sweepShare struct {
+	Task  int ///< Index of this task, from 0
+	Tasks int ///< Number of tasks; 0 means the sweep is not split
+}
+
+/**
+ * @brief Reports whether the task makes the run with the given index.
+ */
+func (s sweepShare) owns(run int) bool {
+	return s.Tasks == 0 || run%s.Tasks == s.Task
+}
+
+/**
+ * @brief Returns the number of runs the task makes out of a sweep of n runs.
+ */
+func (s sweepShare) count(n int) int {
+	if s.Tasks == 0 {
+		return n
+	}
+	return (n - s.Task + s.Tasks - 1) / s.Tasks
+}
+
+/**
+ * @struct jobManifest
+ * @brief What collect needs to know about a split sweep.
+ */
+type jobManifest struct {
+	Scheduler string    `json:"scheduler"`
+	Param     string    `json:"param"`
+	Values    []string  `json:"values"`
+	Seed      int64     `json:"seed"`
+	Seeds     int       `json:"seeds"`
+	Ensemble  bool      `json:"ensemble"` ///< Rows are chronons of ensembles rather than single runs
+	Command   []string  `json:"command"`  ///< The sweep each task runs, without -task and -tasks
+	Tasks     []jobTask `json:"tasks"`
+}
+
+/**
+ * @struct jobTask
+ * @brief One array task of a split sweep.
+ */
+type jobTask struct {
+	Index  int    `json:"index"`
+	Output string `json:"output"` ///< CSV the task writes, relative to the manifest
+	Rows   int    `json:"rows"`   ///< Rows the table holds when the task has finished, header excluded
+}
+
+/**
+ * @brief Rebuilds the command line of a sweep from the flags it was given.
+ * @details Every flag set on the command line or through the environment is written out, and
+ * -seed is pinned so that all tasks share the seeds of the sweep.
+ * @param fs The sweep's parsed flags.
+ * @param seed The sweep's first seed, already fixed.
+ * @param skip Flags left out: those that only concern emitting the jobs.
+ */
+func sweepCommand(fs *flag.FlagSet, seed int64, skip ...string) []string {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "wator"
+	}
+	args := []string{exe, "sweep"}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "seed" && !slices.Contains(skip, f.Name) {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	args = append(args, "-seed="+strconv.FormatInt(seed, 10))
+	return append(args, fs.Args()...)
+}
+
+/**
+ * @brief Quotes a word for a POSIX shell.
+ */
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_=./,:+@", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+/**
+ * @brief Writes a Slurm array job for a split sweep, and its manifest.
+ * @param dir Directory for the script, the manifest and the task outputs.
+ * @param m The manifest, without its tasks.
+ * @param tasks Number of array tasks.
+ * @param runs Number of runs in the whole sweep.
+ * @param rowsPerRun Rows each run adds to its task's table.
+ * @return The path of the script, or an error if a file could not be written.
+ */
+func emitSlurmJobs(dir string, m jobManifest, tasks, runs, rowsPerRun int) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	for i := 0; i < tasks; i++ {
+		share := sweepShare{Task: i, Tasks: tasks}
+		m.Tasks = append(m.Tasks, jobTask{Index: i, Output: fmt.Sprintf("task-%d.csv", i), Rows: share.count(runs) * rowsPerRun})
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, jobManifestName), append(manifest, '\n'), 0o644); err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	quoted := make([]string, len(m.Command))
+	for i, arg := range m.Command {
+		quoted[i] = shellQuote(arg)
+	}
+	out := shellQuote(filepath.Join(abs, "task-")) + `"$SLURM_ARRAY_TASK_ID"`
+	var b strings.Builder
+	fmt.Fprintf(&b, "#!/bin/sh\n")
+	fmt.Fprintf(&b, "#SBATCH --job-name=wator-sweep\n")
+	fmt.Fprintf(&b, "#SBATCH --array=0-%d\n", tasks-1)
+	fmt.Fprintf(&b, "#SBATCH --output=%s\n", filepath.Join(abs, "task-%a.log"))
+	fmt.Fprintf(&b, "# %s sweep of %d runs in %d tasks. Submit with: sbatch %s\n", m.Param, runs, tasks, filepath.Join(abs, "sweep.sbatch"))
+	fmt.Fprintf(&b, "# When every task has finished: wator collect %s > sweep.csv\n", filepath.Join(abs, jobManifestName))
+	fmt.Fprintf(&b, "set -e\n")
+	fmt.Fprintf(&b, "%s -task \"$SLURM_ARRAY_TASK_ID\" -tasks %d > %s.csv.part\n", strings.Join(quoted, " "), tasks, out)
+	fmt.Fprintf(&b, "mv %s.csv.part %s.csv\n", out, out) ///< Only a finished table gets its final name, which collect checks
+	script := filepath.Join(dir, "sweep.sbatch")
+	return script, os.WriteFile(script, []byte(b.String()), 0o755)
+}
+
+/**
+ * @brief The collect subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdCollect(args []string) int {
+	fs := flag.NewFlagSet("wator collect", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	output := fs.String("o", "", "write the merged table to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: wator collect [-o file] <manifest.json | task.csv...>\n")
+		fmt.Fprintf(fs.Output(), "Merges the tables of a sweep split with sweep -emit-jobs; given CSV files instead of a manifest, concatenates them.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitConfigError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitConfigError
+	}
+
+	var header []string
+	var rows [][]string
+	var err error
+	if fs.NArg() == 1 && strings.HasSuffix(fs.Arg(0), ".json") {
+		header, rows, err = collectManifest(fs.Arg(0))
+	} else {
+		header, rows, err = collectTables(fs.Args())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		defer f.Close()
+		w = f
+	}
+	out := csv.NewWriter(w)
+	out.Write(header)
+	out.WriteAll(rows)
+	if err := out.Error(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+/**
+ * @brief Reads CSV tables that must share one header.
+ * @return The header and every table's rows, in order, or an error naming each unreadable or mismatched file.
+ */
+func collectTables(paths []string) (header []string, rows [][]string, err error) {
+	var errs []error
+	for _, path := range paths {
+		table, err := readCSVFile(path)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case len(table) == 0:
+			errs = append(errs, fmt.Errorf("%s: empty table", path))
+		case header == nil:
+			header, rows = table[0], append(rows, table[1:]...)
+		case !slices.Equal(table[0], header):
+			errs = append(errs, fmt.Errorf("%s: columns %v differ from %v", path, table[0], header))
+		default:
+			rows = append(rows, table[1:]...)
+		}
+	}
+	return header, rows, errors.Join(errs...)
+}
+
+/**
+ * @brief Reads a whole CSV file.
+ */
+func readCSVFile(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	table, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return table, nil
+}
+
+/**
+ * @brief Merges the tables of a split sweep in the order of a single sweep.
+ * @param path The manifest written by sweep -emit-jobs.
+ * @return The header and rows, or an error naming every task that is missing or unfinished,
+ * so that exactly those can be resubmitted.
+ */
+func collectManifest(path string) (header []string, rows [][]string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var m jobManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var outputs []string
+	var unfinished []string
+	for _, task := range m.Tasks {
+		output := filepath.Join(filepath.Dir(path), task.Output)
+		table, err := readCSVFile(output)
+		if err != nil || len(table) != task.Rows+1 {
+			unfinished = append(unfinished, strconv.Itoa(task.Index))
+			continue
+		}
+		outputs = append(outputs, output)
+	}
+	if len(unfinished) > 0 {
+		return nil, nil, fmt.Errorf("%d of %d tasks are missing or unfinished; resubmit them with sbatch --array=%s",
+			len(unfinished), len(m.Tasks), strings.Join(unfinished, ","))
+	}
+	if header, rows, err = collectTables(outputs); err != nil {
+		return nil, nil, err
+	}
+
+	key := slices.Index(header, "seed") ///< Runs of one value are ordered by seed
+	if m.Ensemble {
+		key = slices.Index(header, "chronon") ///< Ensemble rows by chronon
+	}
+	value := slices.Index(header, "value")
+	if key < 0 || value < 0 {
+		return nil, nil, fmt.Errorf("%s: the tables have no value and %s columns", path, map[bool]string{false: "seed", true: "chronon"}[m.Ensemble])
+	}
+	slices.SortStableFunc(rows, func(a, b []string) int {
+		if c := cmp.Compare(slices.Index(m.Values, a[value]), slices.Index(m.Values, b[value])); c != 0 {
+			return c
+		}
+		x, _ := strconv.ParseInt(a[key], 10, 64)
+		y, _ := strconv.ParseInt(b[key], 10, 64)
+		return cmp.Compare(x, y)
+	})
+	return header, rows, nil
+}
@@ -0,0 +1,86 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file jobs_test.go
+ * @brief Tests for splitting sweeps into cluster tasks and collecting their tables.
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitSweepCollectsLikeOneSweep(t *testing.T) {
+	cf := newConfigFlags("sweep", "", io.Discard)
+	if _, err := cf.parse([]string{"-chronons", "3", "-engine", "moves", "10", "40", "3", "3", "4", "15", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	values := []string{"2", "4", "6"}
+	var configs []Config
+	for _, v := range values {
+		c, err := cf.with(map[string]string{"SharkBreed": v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, c)
+	}
+	var whole strings.Builder
+	if err := runSweep(context.Background(), "SharkBreed", values, configs, 7, 2, sweepShare{}, &whole); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	m := jobManifest{Scheduler: "slurm", Param: "SharkBreed", Values: values, Seed: 7, Seeds: 2, Command: []string{"wator", "sweep", "-values=2,4,6"}}
+	script, err := emitSlurmJobs(dir, m, 4, 6, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := os.ReadFile(script)
+	for _, want := range []string{"#SBATCH --array=0-3", `wator sweep -values=2,4,6 -task "$SLURM_ARRAY_TASK_ID" -tasks 4`} {
+		if !strings.Contains(string(text), want) {
+			t.Errorf("script lacks %q:\n%s", want, text)
+		}
+	}
+
+	manifest := filepath.Join(dir, jobManifestName)
+	if _, _, err := collectManifest(manifest); err == nil || !strings.Contains(err.Error(), "--array=0,1,2,3") {
+		t.Errorf("collect before any task ran: %v, want every task listed for resubmission", err)
+	}
+	data, _ := os.ReadFile(manifest)
+	json.Unmarshal(data, &m)
+	for _, task := range slices.Backward(m.Tasks) { ///< Finish out of order, as array tasks do
+		var out strings.Builder
+		if err := runSweep(context.Background(), "SharkBreed", values, configs, 7, 2, sweepShare{Task: task.Index, Tasks: 4}, &out); err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(dir, task.Output), []byte(out.String()), 0o644)
+	}
+	header, rows, err := collectManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var merged strings.Builder
+	w := csv.NewWriter(&merged)
+	w.Write(header)
+	w.WriteAll(rows)
+	if merged.String() != whole.String() {
+		t.Errorf("collected table differs from a single sweep:\n%s\nwant:\n%s", merged.String(), whole.String())
+	}
+}
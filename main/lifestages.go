@@ -0,0 +1,61 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file lifestages.go
+ * @brief Juvenile and adult fish (the -fish-maturity option).
+ * @details With FishMaturity M > 0, a fish born during the run is a juvenile for its first M
+ * chronons: it moves like any fish but cannot breed, and with JuvenileEnergy a shark that eats
+ * it gains only that much energy instead of being fully fed. Fish placed at the start, read
+ * from a -grid map or written into a checkpoint as adults stay adults. Every engine ages fish
+ * through grow and breeds and feeds through the Rules methods here, so the stages behave the
+ * same whichever engine runs them.
+ */
+package main
+
+/**
+ * @brief Returns a newborn fish, juvenile when life stages are enabled.
+ */
+func (r Rules) newFish() *Fish {
+	return &Fish{Juvenile: r.FishMaturity}
+}
+
+/**
+ * @brief Advances a fish by one chronon: its breeding counter and, for a juvenile, its maturity.
+ */
+func (f *Fish) grow() {
+	f.BreedCounter++
+	if f.Juvenile > 0 {
+		f.Juvenile--
+	}
+}
+
+/**
+ * @brief Feeds a shark that has just eaten prey.
+ * @details An adult fish restores the shark to StarveEnergy, or with a food web that gives
+ * the meal an energy, adds that much up to StarveEnergy; a juvenile, with JuvenileEnergy set,
+ * only raises it to JuvenileEnergy at most (never lowering it). Either way the shark is then
+ * sated for Satiation chronons.
+ * @param shark The shark.
+ * @param prey The fish it ate, as it stood in the grid the chronon started from.
+ */
+func (r Rules) feed(shark *Shark, prey Entity) {
+	shark.Sated = r.Satiation
+	fed := r.StarveEnergy
+	if gain := r.FoodWeb.Gain(SpeciesShark, speciesOf(prey)); gain > 0 {
+		fed = min(shark.Energy+gain, r.StarveEnergy)
+	}
+	if fish, ok := prey.(*Fish); ok && fish.Juvenile > 0 && r.JuvenileEnergy > 0 {
+		shark.Energy = max(shark.Energy, min(r.JuvenileEnergy, fed))
+		return
+	}
+	shark.Energy = fed
+}
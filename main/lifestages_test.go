@@ -0,0 +1,105 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file lifestages_test.go
+ * @brief Tests for juvenile and adult fish.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestJuvenilesMatureBeforeBreeding(t *testing.T) {
+	rules := Rules{FishBreed: 1, FishMaturity: 3, StarveEnergy: 5, JuvenileEnergy: 2}
+	fish := rules.newFish()
+	for i := 1; i <= 3; i++ {
+		fish.grow()
+		if got, want := rules.fishBreeds(nil, fish, 0, 0), i == 3; got != want {
+			t.Errorf("after %d chronons: breeds = %v, want %v", i, got, want)
+		}
+	}
+
+	shark := &Shark{Energy: 1}
+	rules.feed(shark, rules.newFish())
+	if shark.Energy != 2 {
+		t.Errorf("energy after eating a juvenile = %d, want 2", shark.Energy)
+	}
+	rules.feed(shark, &Fish{})
+	if shark.Energy != 5 {
+		t.Errorf("energy after eating an adult = %d, want 5", shark.Energy)
+	}
+}
+
+func TestLifeStagesInEveryEngine(t *testing.T) {
+	for _, engine := range engineNames() {
+		t.Run(engine, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Engine, cfg.FishBreed, cfg.FishMaturity, cfg.JuvenileEnergy = engine, 1, 4, 1
+			cfg.NumShark = 5 ///< Few enough that no thread interleaving lets them eat every fish
+			sim, err := NewSimulation(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sim.Run(context.Background(), 12)
+			f := sim.Snapshot()
+			juveniles := 0
+			for _, e := range sim.Checkpoint().Entities {
+				if e.Juvenile > cfg.FishMaturity {
+					t.Fatalf("fish at (%d,%d) is juvenile for %d more chronons, longer than -fish-maturity", e.X, e.Y, e.Juvenile)
+				}
+				if e.Juvenile > 0 {
+					juveniles++
+					if !f.Juvenile(e.X, e.Y) {
+						t.Errorf("frame does not mark the juvenile at (%d,%d)", e.X, e.Y)
+					}
+				}
+			}
+			if juveniles == 0 || f.Juveniles() != juveniles {
+				t.Errorf("frame counts %d juveniles, checkpoint %d", f.Juveniles(), juveniles)
+			}
+			if p := sim.Population(); p.Fish != f.fish || p.Sharks != f.sharks {
+				t.Errorf("population %+v does not match the frame", p)
+			}
+		})
+	}
+}
+
+func TestJuvenilesDrawnAndCheckpointed(t *testing.T) {
+	g := NewGrid(3)
+	g.Set(0, 0, &Fish{Juvenile: 2})
+	g.Set(0, 1, &Fish{})
+	var out bytes.Buffer
+	writeFrame(&out, newFrame(g), themes["ascii"], 0)
+	if !strings.Contains(out.String(), "| f F . |") {
+		t.Errorf("juvenile not drawn as f:\n%s", out.String())
+	}
+
+	var buf bytes.Buffer
+	if err := NewCheckpoint(g, Config{}).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := ReadCheckpoint(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := cp.Grid("entities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fish, ok := restored.At(0, 0).(*Fish); !ok || fish.Juvenile != 2 {
+		t.Errorf("restored %#v, want a fish juvenile for 2 more chronons", restored.At(0, 0))
+	}
+}
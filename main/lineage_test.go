@@ -0,0 +1,70 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file lineage_test.go
+ * @brief Tests for tagged lineages.
+ */
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTagLineages(t *testing.T) {
+	g := NewGrid(10)
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			g.Set(x, y, &Fish{})
+		}
+	}
+	g.At(0, 0).(*Fish).Lineage = 7
+	if n := tagLineages(g, 1, 3, 1); n != 99 {
+		t.Errorf("tagged %d fish with fraction 1, want the 99 untagged ones", n)
+	}
+	if tag := lineageOf(g.At(0, 0)); tag != 7 {
+		t.Errorf("an existing tag was replaced by %d", tag)
+	}
+	f := newFrame(g)
+	fish, sharks := f.LineageCounts(3)
+	if fish[0]+fish[1]+fish[2] != 99 || sharks[0]+sharks[1]+sharks[2] != 0 {
+		t.Errorf("lineage counts fish %v, sharks %v; want 99 fish among lineages 1-3", fish, sharks)
+	}
+}
+
+func TestOffspringInheritLineage(t *testing.T) {
+	cfg := testConfig()
+	cfg.Tag, cfg.Lineages = 1, 2
+	for _, engine := range engineNames() {
+		cfg.Engine = engine
+		sim, err := NewSimulation(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sim.Run(context.Background(), 10)
+		f := sim.Snapshot()
+		fish, sharks := f.LineageCounts(2)
+		allFish, allSharks := f.Counts()
+		if fish[0]+fish[1] != allFish || sharks[0]+sharks[1] != allSharks {
+			t.Errorf("%s: lineages hold %v fish and %v sharks of %d and %d; every descendant of a tagged animal should be tagged",
+				engine, fish, sharks, allFish, allSharks)
+		}
+
+		restored, err := sim.Checkpoint().Grid("entities")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := newFrame(restored).LineageCounts(2); got[0] != fish[0] || got[1] != fish[1] {
+			t.Errorf("%s: checkpoint restored lineage fish %v, want %v", engine, got, fish)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file logging.go
+ * @brief Configures structured logging for the simulation.
+ * @details Status, timing, and diagnostic messages go through log/slog on stderr so that
+ * stdout stays reserved for the rendered grid.
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+/**
+ * @brief Installs the default slog logger with the requested level and format.
+ * @param level One of "debug", "info", or "warn" (case-insensitive).
+ * @param jsonOutput Emit JSON records instead of key=value text.
+ * @return An error if the level is not recognised.
+ */
+func setupLogger(level string, jsonOutput bool) error {
+	return setupLoggerTo(os.Stderr, level, jsonOutput)
+}
+
+/**
+ * @brief Installs the default slog logger writing to w.
+ * @param w Destination of the records, e.g. standard error and a log file.
+ * @param level One of "debug", "info", or "warn" (case-insensitive).
+ * @param jsonOutput Emit JSON records instead of key=value text.
+ * @return An error if the level is not recognised.
+ */
+func setupLoggerTo(w io.Writer, level string, jsonOutput bool) error {
+	var lvl slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "info":
+		lvl = slog.LevelInfo
+	case "warn":
+		lvl = slog.LevelWarn
+	default:
+		return fmt.Errorf("unknown log level %q (want debug, info or warn)", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	if jsonOutput {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
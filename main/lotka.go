@@ -0,0 +1,286 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file lotka.go
+ * @brief Fits a run's populations to the Lotka–Volterra equations (the -fit-lv option).
+ * @details The mean-field model of predator and prey is
+ *
+ *     dx/dt = αx − βxy     (x fish)
+ *     dy/dt = δxy − γy     (y sharks)
+ *
+ * The fit starts from gradient matching: over each chronon, ln(x'/x) ≈ α − βy and
+ * ln(y'/y) ≈ δx − γ, which are linear regressions. Nelder–Mead then refines the four
+ * parameters, searching their logarithms so they stay positive, to minimise the squared
+ * difference between the recorded series and the model integrated from the first recorded
+ * state. Each species' residuals are scaled by its variance so the larger population does
+ * not dominate. The residuals and R² say how much of the run the mean-field model explains;
+ * spatial clustering usually damps Wa-Tor's cycles well below the model's.
+ */
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+/**
+ * @struct LotkaVolterraFit
+ * @brief Fitted Lotka–Volterra parameters and how well they reproduce a run.
+ */
+type LotkaVolterraFit struct {
+	Alpha       float64    `json:"alpha"`       ///< Fish growth rate per chronon
+	Beta        float64    `json:"beta"`        ///< Predation rate per shark per chronon
+	Gamma       float64    `json:"gamma"`       ///< Shark death rate per chronon
+	Delta       float64    `json:"delta"`       ///< Shark growth per fish per chronon
+	FishRMSE    float64    `json:"fish_rmse"`   ///< Root-mean-square fish residual
+	SharkRMSE   float64    `json:"sharks_rmse"` ///< Root-mean-square shark residual
+	FishR2      float64    `json:"fish_r2"`     ///< Fraction of the fish variance the model explains
+	SharkR2     float64    `json:"sharks_r2"`   ///< Fraction of the shark variance the model explains
+	Points      int        `json:"points"`      ///< Chronons fitted
+	Equilibrium [2]float64 `json:"equilibrium"` ///< Model fixed point (γ/δ fish, α/β sharks)
+}
+
+const (
+	lvMinPoints  = 10   ///< Shortest series worth fitting
+	lvSubsteps   = 4    ///< RK4 steps per chronon
+	lvIterations = 4000 ///< Nelder–Mead iteration limit
+)
+
+/**
+ * @brief Fits a population series to the Lotka–Volterra equations.
+ * @details Only the chronons before either species first dies out are used, since the
+ * model cannot leave zero.
+ * @param s The recorded populations.
+ * @return The fit, or an error if too few chronons had both species alive.
+ */
+func FitLotkaVolterra(s *PopulationSeries) (LotkaVolterraFit, error) {
+	n := 0
+	for n < s.Len() && s.Fish[n] > 0 && s.Sharks[n] > 0 {
+		n++
+	}
+	if n < lvMinPoints {
+		return LotkaVolterraFit{}, fmt.Errorf("need at least %d chronons with both species alive, have %d", lvMinPoints, n)
+	}
+	fish, sharks := s.Fish[:n], s.Sharks[:n]
+
+	guess, err := lvGradientGuess(fish, sharks)
+	if err != nil {
+		return LotkaVolterraFit{}, err
+	}
+	fishVar, sharkVar := variance(fish), variance(sharks)
+	cost := func(logp []float64) float64 {
+		p := [4]float64{math.Exp(logp[0]), math.Exp(logp[1]), math.Exp(logp[2]), math.Exp(logp[3])}
+		fx, sy, ok := lvIntegrate(p, fish[0], sharks[0], n)
+		if !ok {
+			return math.Inf(1)
+		}
+		return sumSquares(fish, fx)/math.Max(fishVar, 1) + sumSquares(sharks, sy)/math.Max(sharkVar, 1)
+	}
+	start := make([]float64, 4)
+	for i, v := range guess {
+		start[i] = math.Log(v)
+	}
+	best := nelderMead(cost, start, 0.2, lvIterations)
+
+	p := [4]float64{math.Exp(best[0]), math.Exp(best[1]), math.Exp(best[2]), math.Exp(best[3])}
+	fx, sy, ok := lvIntegrate(p, fish[0], sharks[0], n)
+	if !ok {
+		return LotkaVolterraFit{}, errors.New("the fitted model diverges")
+	}
+	fit := LotkaVolterraFit{Alpha: p[0], Beta: p[1], Gamma: p[2], Delta: p[3], Points: n}
+	fit.FishRMSE = math.Sqrt(sumSquares(fish, fx) / float64(n))
+	fit.SharkRMSE = math.Sqrt(sumSquares(sharks, sy) / float64(n))
+	fit.FishR2 = 1 - sumSquares(fish, fx)/(fishVar*float64(n))
+	fit.SharkR2 = 1 - sumSquares(sharks, sy)/(sharkVar*float64(n))
+	fit.Equilibrium = [2]float64{fit.Gamma / fit.Delta, fit.Alpha / fit.Beta}
+	return fit, nil
+}
+
+/**
+ * @brief Estimates the parameters by regressing per-chronon log growth on the other species.
+ * @return α, β, γ, δ, with any of the wrong sign replaced by a small positive value.
+ */
+func lvGradientGuess(fish, sharks []float64) ([4]float64, error) {
+	n := len(fish) - 1
+	gx, gy := make([]float64, n), make([]float64, n)
+	mx, my := make([]float64, n), make([]float64, n)
+	for t := range n {
+		gx[t] = math.Log(fish[t+1] / fish[t])
+		gy[t] = math.Log(sharks[t+1] / sharks[t])
+		mx[t] = (fish[t] + fish[t+1]) / 2 ///< Midpoints match the log growth over the chronon
+		my[t] = (sharks[t] + sharks[t+1]) / 2
+	}
+	slopeX, interceptX, okX := linearFit(my, gx)
+	slopeY, interceptY, okY := linearFit(mx, gy)
+	if !okX || !okY {
+		return [4]float64{}, errors.New("the populations do not vary enough to fit")
+	}
+	positive := func(v, scale float64) float64 {
+		if v > 0 {
+			return v
+		}
+		return 1e-3 / scale
+	}
+	return [4]float64{
+		positive(interceptX, 1),
+		positive(-slopeX, meanOf(sharks)),
+		positive(-interceptY, 1),
+		positive(slopeY, meanOf(fish)),
+	}, nil
+}
+
+/**
+ * @brief Integrates the model with RK4 from (x0, y0).
+ * @param p α, β, γ, δ.
+ * @param n Number of chronons to produce, the first being (x0, y0).
+ * @return The fish and shark trajectories, and false if they blew up.
+ */
+func lvIntegrate(p [4]float64, x0, y0 float64, n int) (fish, sharks []float64, ok bool) {
+	deriv := func(x, y float64) (float64, float64) {
+		return p[0]*x - p[1]*x*y, p[3]*x*y - p[2]*y
+	}
+	const h = 1.0 / lvSubsteps
+	fish, sharks = make([]float64, n), make([]float64, n)
+	x, y := x0, y0
+	for t := range n {
+		fish[t], sharks[t] = x, y
+		for range lvSubsteps {
+			k1x, k1y := deriv(x, y)
+			k2x, k2y := deriv(x+h/2*k1x, y+h/2*k1y)
+			k3x, k3y := deriv(x+h/2*k2x, y+h/2*k2y)
+			k4x, k4y := deriv(x+h*k3x, y+h*k3y)
+			x += h / 6 * (k1x + 2*k2x + 2*k3x + k4x)
+			y += h / 6 * (k1y + 2*k2y + 2*k3y + k4y)
+		}
+		if math.IsNaN(x) || math.IsNaN(y) || math.Abs(x) > 1e12 || math.Abs(y) > 1e12 {
+			return nil, nil, false
+		}
+	}
+	return fish, sharks, true
+}
+
+/**
+ * @brief Minimises f with the Nelder–Mead simplex method.
+ * @param f The function to minimise.
+ * @param start Starting point.
+ * @param step Initial simplex size along each axis.
+ * @param iterations Iteration limit.
+ * @return The best point found.
+ */
+func nelderMead(f func([]float64) float64, start []float64, step float64, iterations int) []float64 {
+	dim := len(start)
+	type vertex struct {
+		x []float64
+		v float64
+	}
+	simplex := make([]vertex, dim+1)
+	for i := range simplex {
+		x := append([]float64(nil), start...)
+		if i > 0 {
+			x[i-1] += step
+		}
+		simplex[i] = vertex{x, f(x)}
+	}
+	along := func(from, to []float64, t float64) []float64 {
+		x := make([]float64, dim)
+		for i := range x {
+			x[i] = from[i] + t*(to[i]-from[i])
+		}
+		return x
+	}
+	for range iterations {
+		sort.Slice(simplex, func(i, j int) bool { return simplex[i].v < simplex[j].v })
+		best, worst := simplex[0], simplex[dim]
+		if math.Abs(worst.v-best.v) <= 1e-10*(math.Abs(best.v)+1e-10) {
+			break
+		}
+		centroid := make([]float64, dim)
+		for _, vx := range simplex[:dim] {
+			for i := range centroid {
+				centroid[i] += vx.x[i] / float64(dim)
+			}
+		}
+		reflected := along(centroid, worst.x, -1)
+		rv := f(reflected)
+		switch {
+		case rv < best.v:
+			expanded := along(centroid, worst.x, -2)
+			if ev := f(expanded); ev < rv {
+				simplex[dim] = vertex{expanded, ev}
+			} else {
+				simplex[dim] = vertex{reflected, rv}
+			}
+		case rv < simplex[dim-1].v:
+			simplex[dim] = vertex{reflected, rv}
+		default:
+			contracted := along(centroid, worst.x, 0.5)
+			if cv := f(contracted); cv < worst.v {
+				simplex[dim] = vertex{contracted, cv}
+				continue
+			}
+			for i := 1; i <= dim; i++ { ///< Shrink towards the best vertex
+				x := along(best.x, simplex[i].x, 0.5)
+				simplex[i] = vertex{x, f(x)}
+			}
+		}
+	}
+	sort.Slice(simplex, func(i, j int) bool { return simplex[i].v < simplex[j].v })
+	return simplex[0].x
+}
+
+/**
+ * @brief Least-squares line through (xs, ys).
+ * @return The slope, the intercept, and false if xs has no spread.
+ */
+func linearFit(xs, ys []float64) (slope, intercept float64, ok bool) {
+	mx, my := meanOf(xs), meanOf(ys)
+	var sxy, sxx float64
+	for i := range xs {
+		sxy += (xs[i] - mx) * (ys[i] - my)
+		sxx += (xs[i] - mx) * (xs[i] - mx)
+	}
+	if sxx == 0 {
+		return 0, 0, false
+	}
+	slope = sxy / sxx
+	return slope, my - slope*mx, true
+}
+
+/** @brief Arithmetic mean of a non-empty slice. */
+func meanOf(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+/** @brief Population variance of a non-empty slice. */
+func variance(xs []float64) float64 {
+	m := meanOf(xs)
+	var sum float64
+	for _, x := range xs {
+		sum += (x - m) * (x - m)
+	}
+	return sum / float64(len(xs))
+}
+
+/** @brief Sum of squared differences between two equal-length slices. */
+func sumSquares(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return sum
+}
@@ -0,0 +1,72 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file lotka_test.go
+ * @brief Tests for the Lotka–Volterra fit.
+ */
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFitLotkaVolterraRecoversParameters(t *testing.T) {
+	want := [4]float64{0.05, 0.0005, 0.08, 0.0002}
+	fish, sharks, ok := lvIntegrate(want, 300, 80, 300)
+	if !ok {
+		t.Fatal("the reference model diverged")
+	}
+	fit, err := FitLotkaVolterra(&PopulationSeries{Fish: fish, Sharks: sharks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, got := range []float64{fit.Alpha, fit.Beta, fit.Gamma, fit.Delta} {
+		if math.Abs(got-want[i]) > 0.02*want[i] {
+			t.Errorf("parameter %d: got %g, want %g", i, got, want[i])
+		}
+	}
+	if fit.FishR2 < 0.99 || fit.SharkR2 < 0.99 || fit.Points != 300 {
+		t.Errorf("got R² %g and %g over %d points, want a near-perfect fit over 300", fit.FishR2, fit.SharkR2, fit.Points)
+	}
+}
+
+func TestFitLotkaVolterraSimulation(t *testing.T) {
+	cfg := testConfig()
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := &PopulationSeries{}
+	series.Observe(sim.Snapshot())
+	sim.OnChrononEnd(series.Record)
+	sim.Run(context.Background(), 40)
+	fit, err := FitLotkaVolterra(series)
+	if err != nil {
+		if !strings.Contains(err.Error(), "both species alive") {
+			t.Fatal(err)
+		}
+		return ///< The test world died out too soon to fit
+	}
+	if fit.Alpha <= 0 || fit.Beta <= 0 || fit.Gamma <= 0 || fit.Delta <= 0 || math.IsNaN(fit.FishRMSE) || fit.FishR2 > 1 {
+		t.Errorf("implausible fit %+v", fit)
+	}
+}
+
+func TestFitLotkaVolterraTooShort(t *testing.T) {
+	s := &PopulationSeries{Fish: []float64{10, 12, 0, 3}, Sharks: []float64{5, 4, 3, 2}}
+	if _, err := FitLotkaVolterra(s); err == nil || !strings.Contains(err.Error(), "have 2") {
+		t.Errorf("got %v, want an error about too few chronons", err)
+	}
+}
@@ -0,0 +1,239 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file manager.go
+ * @brief Runs ensembles of independent simulations in one process.
+ * @details A single Wa-Tor run is noisy; averaging many runs with different seeds shows the
+ * typical dynamics. The Manager steps every member on a shared, fixed-size pool of goroutines,
+ * one chronon at a time, so a large ensemble does not oversubscribe the machine.
+ */
+package main
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+/**
+ * @struct Manager
+ * @brief A set of simulations that share a configuration but not a seed.
+ */
+type Manager struct {
+	members []*Simulation
+	series  [][][2]int ///< Per member, the (fish, sharks) counts of every chronon from 0
+	pool    int        ///< Number of goroutines stepping members
+}
+
+/**
+ * @brief Creates an ensemble of simulations.
+ * @details Member i uses seed cfg.Seed + i, so an ensemble is reproducible from its base seed.
+ * @param cfg The configuration shared by every member; Seed must already be fixed.
+ * @param members Number of simulations.
+ * @param pool Number of goroutines stepping members concurrently; 0 uses GOMAXPROCS.
+ * @return The manager, or an error if a member could not be created.
+ */
+func NewManager(cfg Config, members, pool int) (*Manager, error) {
+	if pool <= 0 {
+		pool = runtime.GOMAXPROCS(0)
+	}
+	m := &Manager{pool: pool}
+	for i := 0; i < members; i++ {
+		memberCfg := cfg
+		memberCfg.Seed = cfg.Seed + int64(i)
+		sim, err := NewSimulation(memberCfg)
+		if err != nil {
+			return nil, err
+		}
+		m.members = append(m.members, sim)
+		m.series = append(m.series, [][2]int{counts(sim.Population())})
+	}
+	return m, nil
+}
+
+/**
+ * @brief Returns the fish and shark counts as a pair.
+ */
+func counts(p Population) [2]int {
+	return [2]int{p.Fish, p.Sharks}
+}
+
+/**
+ * @brief Returns the member simulations, e.g. to attach hooks before Run.
+ */
+func (m *Manager) Members() []*Simulation {
+	return m.members
+}
+
+/**
+ * @brief Advances every member by the given number of chronons.
+ * @details Each job steps one member by one chronon and then requeues it, so members progress
+ * at a similar pace and no member is stepped by two goroutines at once.
+ * @param ctx Context of the run; cancellation stops every member between chronons.
+ * @param chronons Number of chronons to run per member.
+ * @return The context's error if the run was cancelled.
+ */
+func (m *Manager) Run(ctx context.Context, chronons int) error {
+	if len(m.members) == 0 || chronons <= 0 {
+		return nil
+	}
+	jobs := make(chan int, len(m.members)) ///< Member indices ready for their next chronon
+	for i := range m.members {
+		jobs <- i
+	}
+
+	var remaining sync.WaitGroup ///< One count per member still running
+	remaining.Add(len(m.members))
+	go func() {
+		remaining.Wait()
+		close(jobs)
+	}()
+
+	var pool sync.WaitGroup
+	for w := 0; w < m.pool; w++ {
+		pool.Add(1)
+		go func() {
+			defer pool.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					remaining.Done() ///< Drop the member
+					continue
+				}
+				sim := m.members[i]
+				sim.Step(ctx)
+				m.series[i] = append(m.series[i], counts(sim.Population()))
+				if len(m.series[i]) > chronons {
+					remaining.Done()
+					continue
+				}
+				jobs <- i ///< Never blocks: the buffer holds every member
+			}
+		}()
+	}
+	pool.Wait()
+	return ctx.Err()
+}
+
+/**
+ * @struct EnsembleStat
+ * @brief Population statistics across the members for one chronon.
+ */
+type EnsembleStat struct {
+	Chronon     int     ///< Chronon the statistics describe
+	Members     int     ///< Number of members that reached this chronon
+	FishMean    float64 ///< Mean fish population
+	FishStddev  float64 ///< Population standard deviation of the fish count
+	SharkMean   float64 ///< Mean shark population
+	SharkStddev float64 ///< Population standard deviation of the shark count
+	FishCI95    float64 ///< Half-width of the 95% confidence interval of FishMean (0 for one member)
+	SharkCI95   float64 ///< Half-width of the 95% confidence interval of SharkMean (0 for one member)
+}
+
+/** CSV columns written for an EnsembleStat by record. */
+var ensembleColumns = []string{"chronon", "members", "fish_mean", "fish_stddev", "sharks_mean", "sharks_stddev",
+	"fish_ci95_low", "fish_ci95_high", "sharks_ci95_low", "sharks_ci95_high"}
+
+/**
+ * @brief Formats the statistics as CSV fields in ensembleColumns order.
+ */
+func (st EnsembleStat) record() []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	return []string{strconv.Itoa(st.Chronon), strconv.Itoa(st.Members),
+		f(st.FishMean), f(st.FishStddev), f(st.SharkMean), f(st.SharkStddev),
+		f(st.FishMean - st.FishCI95), f(st.FishMean + st.FishCI95),
+		f(st.SharkMean - st.SharkCI95), f(st.SharkMean + st.SharkCI95)}
+}
+
+/**
+ * @brief Aggregates the populations of every chronon run so far.
+ * @details Must not be called while Run is in progress.
+ * @return One entry per chronon, starting with the initial state.
+ */
+func (m *Manager) Stats() []EnsembleStat {
+	longest := 0
+	for _, s := range m.series {
+		longest = max(longest, len(s))
+	}
+
+	stats := make([]EnsembleStat, longest)
+	for c := range stats {
+		var fish, sharks []float64
+		for _, s := range m.series {
+			if c < len(s) {
+				fish = append(fish, float64(s[c][0]))
+				sharks = append(sharks, float64(s[c][1]))
+			}
+		}
+		stats[c] = EnsembleStat{Chronon: c, Members: len(fish)}
+		stats[c].FishMean, stats[c].FishStddev = meanStddev(fish)
+		stats[c].SharkMean, stats[c].SharkStddev = meanStddev(sharks)
+		stats[c].FishCI95 = ci95(stats[c].FishStddev, len(fish))
+		stats[c].SharkCI95 = ci95(stats[c].SharkStddev, len(sharks))
+	}
+	return stats
+}
+
+/**
+ * @brief Returns the mean and population standard deviation of a sample.
+ * @return (0, 0) for an empty sample.
+ */
+func meanStddev(xs []float64) (mean, stddev float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	for _, x := range xs {
+		stddev += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(stddev / float64(len(xs)))
+}
+
+/** Two-sided 95% critical values of Student's t distribution for 1 to 30 degrees of freedom. */
+var tCritical95 = []float64{12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042}
+
+/**
+ * @brief Returns the half-width of the 95% confidence interval of a sample mean.
+ * @details Uses Student's t distribution, which matters for the small ensembles typical
+ * of simulation studies; beyond 30 degrees of freedom the next lower tabulated value
+ * (40, 60 or 120) keeps the interval slightly conservative.
+ * @param stddev Population standard deviation of the sample, as returned by meanStddev.
+ * @param n Sample size.
+ * @return The half-width, or 0 when n < 2.
+ */
+func ci95(stddev float64, n int) float64 {
+	if n < 2 {
+		return 0
+	}
+	df := n - 1
+	t := 1.960
+	switch {
+	case df <= len(tCritical95):
+		t = tCritical95[df-1]
+	case df < 40:
+		t = tCritical95[len(tCritical95)-1]
+	case df < 60:
+		t = 2.021
+	case df < 120:
+		t = 2.000
+	default:
+		t = 1.980
+	}
+	sample := stddev * math.Sqrt(float64(n)/float64(df)) ///< Bessel-corrected standard deviation
+	return t * sample / math.Sqrt(float64(n))
+}
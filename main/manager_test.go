@@ -0,0 +1,86 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file manager_test.go
+ * @brief Tests for running and aggregating ensembles.
+ */
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestManagerStatsIndependentOfPoolSize(t *testing.T) {
+	cfg := testConfig()
+	cfg.Threads = 1 ///< Single-threaded members are reproducible from their seed
+
+	var runs [][]EnsembleStat
+	for _, pool := range []int{1, 3} {
+		m, err := NewManager(cfg, 4, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Run(context.Background(), 10); err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, m.Stats())
+	}
+
+	if len(runs[0]) != 11 {
+		t.Fatalf("got %d chronons of statistics, want 11 (initial state + 10)", len(runs[0]))
+	}
+	for c := range runs[0] {
+		if runs[0][c] != runs[1][c] {
+			t.Errorf("chronon %d differs between pool sizes: %+v vs %+v", c, runs[0][c], runs[1][c])
+		}
+		if runs[0][c].Members != 4 {
+			t.Errorf("chronon %d aggregates %d members, want 4", c, runs[0][c].Members)
+		}
+	}
+	if st := runs[0][0]; st.FishMean != float64(cfg.NumFish) || st.FishStddev != 0 {
+		t.Errorf("initial fish mean/stddev %v/%v, want %d/0", st.FishMean, st.FishStddev, cfg.NumFish)
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	m, err := NewManager(testConfig(), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Run(ctx, 10); err != context.Canceled {
+		t.Errorf("Run returned %v, want context.Canceled", err)
+	}
+	if n := len(m.Stats()); n != 1 {
+		t.Errorf("cancelled ensemble has %d chronons of statistics, want only the initial state", n)
+	}
+}
+
+func TestCI95(t *testing.T) {
+	_, sd := meanStddev([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if got := ci95(sd, 8); math.Abs(got-1.788) > 0.001 {
+		t.Errorf("got half-width %.4f, want 1.788 (t=2.365, sample sd 2.138, n=8)", got)
+	}
+	if got := ci95(3, 1); got != 0 {
+		t.Errorf("one member should have no interval, got %v", got)
+	}
+}
+
+func TestMeanStddev(t *testing.T) {
+	mean, sd := meanStddev([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if mean != 5 || math.Abs(sd-2) > 1e-12 {
+		t.Errorf("got mean %v stddev %v, want 5 and 2", mean, sd)
+	}
+}
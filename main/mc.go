@@ -0,0 +1,195 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file mc.go
+ * @brief The mc subcommand: Monte Carlo estimates of extinction probabilities.
+ * @details Runs one parameter set headless with seeds seed..seed+n-1, several runs at a
+ * time, and sorts every run by its state at the horizon (-chronons): fish extinct (the
+ * sharks then starve, so this includes both dying out), sharks extinct with fish left, or
+ * both species alive. Each outcome is reported with its share of the runs and a Wilson
+ * score interval, which stays inside [0, 1] and keeps its coverage for outcomes that are
+ * rare or certain, where the normal approximation fails.
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+/** Outcomes in the order they are reported. */
+var mcOutcomes = []string{"fish_extinct", "sharks_extinct", "coexistence"}
+
+/** Column names written as the first row of the mc table. */
+var mcColumns = []string{"outcome", "runs", "of", "probability", "ci_low", "ci_high", "mean_extinction_chronon"}
+
+/**
+ * @struct MCOutcome
+ * @brief How often one outcome occurred.
+ */
+type MCOutcome struct {
+	Name        string  ///< One of mcOutcomes
+	Count       int     ///< Runs that ended this way
+	Probability float64 ///< Count / runs
+	Low, High   float64 ///< Wilson score interval of the probability
+	MeanChronon float64 ///< Mean chronon of the first extinction (NaN for coexistence or no runs)
+}
+
+/**
+ * @brief The mc subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdMC(args []string) int {
+	cf := newConfigFlags("mc", "Runs -runs seeded simulations of -chronons chronons in parallel and prints the probability of each outcome with Wilson confidence intervals as CSV.", os.Stderr)
+	runs := cf.fs.Int("runs", 1000, "number of runs, with seeds seed..seed+n-1")
+	workers := cf.fs.Int("workers", runtime.GOMAXPROCS(0), "runs simulated at once")
+	confidence := cf.fs.Float64("confidence", 0.95, "`level` of the confidence intervals")
+	cfg, code, ok := setupCommand(cf, args)
+	if !ok {
+		return code
+	}
+	if *runs < 1 || *workers < 1 || *confidence <= 0 || *confidence >= 1 || cfg.Ensemble > 1 {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\nmc needs -runs and -workers of at least 1, a -confidence between 0 and 1, and no -ensemble")
+		return exitConfigError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	outcomes, err := runMonteCarlo(ctx, cfg, *runs, *workers, *confidence)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if ctx.Err() != nil {
+			return exitInterrupted
+		}
+		return exitFailure
+	}
+	if err := writeMonteCarlo(os.Stdout, outcomes, *runs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+/**
+ * @brief Runs the simulations and tallies their outcomes.
+ * @param ctx Context of the estimate; cancelling it stops every run between chronons.
+ * @param cfg The parameter set; run i uses seed cfg.Seed + i.
+ * @param runs Number of runs.
+ * @param workers Number of runs simulated at once.
+ * @param confidence Level of the intervals, e.g. 0.95.
+ * @return One entry per outcome, in mcOutcomes order, or an error if a run failed or the
+ * estimate was interrupted. The result does not depend on workers.
+ */
+func runMonteCarlo(ctx context.Context, cfg Config, runs, workers int, confidence float64) ([]MCOutcome, error) {
+	sums := make([]RunSummary, runs)
+	errs := make([]error, runs)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, runs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				c := cfg
+				c.Seed = cfg.Seed + int64(i)
+				sums[i], errs[i] = runHeadless(ctx, c)
+			}
+		}()
+	}
+	for i := 0; i < runs && ctx.Err() == nil; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("mc interrupted")
+	}
+
+	z := math.Sqrt2 * math.Erfinv(confidence) ///< Two-sided normal quantile
+	outcomes := make([]MCOutcome, len(mcOutcomes))
+	chronons := make([]float64, len(mcOutcomes))
+	for i, name := range mcOutcomes {
+		outcomes[i].Name = name
+	}
+	for i, sum := range sums {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		k := 2 ///< Coexistence
+		switch {
+		case sum.Fish == 0:
+			k = 0
+		case sum.Sharks == 0:
+			k = 1
+		}
+		outcomes[k].Count++
+		if sum.ExtinctionChronon != nil {
+			chronons[k] += float64(*sum.ExtinctionChronon)
+		}
+	}
+	for i := range outcomes {
+		o := &outcomes[i]
+		o.Probability = float64(o.Count) / float64(runs)
+		o.Low, o.High = wilson(o.Count, runs, z)
+		o.MeanChronon = math.NaN()
+		if o.Count > 0 && o.Name != "coexistence" {
+			o.MeanChronon = chronons[i] / float64(o.Count)
+		}
+	}
+	return outcomes, nil
+}
+
+/**
+ * @brief Returns the Wilson score interval of a binomial proportion.
+ * @param k Successes.
+ * @param n Trials; at least 1.
+ * @param z Normal quantile of the confidence level, e.g. 1.96 for 95%.
+ * @return The lower and upper bound.
+ */
+func wilson(k, n int, z float64) (low, high float64) {
+	p, nf := float64(k)/float64(n), float64(n)
+	centre := (p + z*z/(2*nf)) / (1 + z*z/nf)
+	half := z / (1 + z*z/nf) * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf))
+	return max(centre-half, 0), min(centre+half, 1)
+}
+
+/**
+ * @brief Writes the outcomes as a CSV table.
+ * @param w Destination writer.
+ * @param outcomes The result of runMonteCarlo.
+ * @param runs Total runs, for the "of" column.
+ * @return Any write error.
+ */
+func writeMonteCarlo(w io.Writer, outcomes []MCOutcome, runs int) error {
+	out := csv.NewWriter(w)
+	out.Write(mcColumns)
+	for _, o := range outcomes {
+		mean := ""
+		if !math.IsNaN(o.MeanChronon) {
+			mean = formatFloat(o.MeanChronon)
+		}
+		out.Write([]string{o.Name, strconv.Itoa(o.Count), strconv.Itoa(runs), strconv.FormatFloat(o.Probability, 'f', 4, 64),
+			strconv.FormatFloat(o.Low, 'f', 4, 64), strconv.FormatFloat(o.High, 'f', 4, 64), mean})
+	}
+	out.Flush()
+	return out.Error()
+}
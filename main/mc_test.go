@@ -0,0 +1,72 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file mc_test.go
+ * @brief Tests for the Monte Carlo extinction estimator.
+ */
+package main
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestWilson(t *testing.T) {
+	for _, c := range []struct {
+		k, n      int
+		low, high float64
+	}{
+		{5, 10, 0.2366, 0.7634},
+		{0, 20, 0, 0.1611},
+		{20, 20, 0.8389, 1},
+	} {
+		low, high := wilson(c.k, c.n, 1.96)
+		if math.Abs(low-c.low) > 1e-4 || math.Abs(high-c.high) > 1e-4 {
+			t.Errorf("wilson(%d, %d) = [%.4f, %.4f], want [%.4f, %.4f]", c.k, c.n, low, high, c.low, c.high)
+		}
+	}
+}
+
+func TestMonteCarloIndependentOfWorkers(t *testing.T) {
+	cfg := testConfig()
+	cfg.Chronons, cfg.Threads = 40, 1
+	var results [][]MCOutcome
+	for _, workers := range []int{1, 4} {
+		outcomes, err := runMonteCarlo(context.Background(), cfg, 12, workers, 0.95)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := 0.0
+		for _, o := range outcomes {
+			total += o.Probability
+			if o.Low > o.Probability || o.High < o.Probability {
+				t.Errorf("%s: probability %.3f outside its interval [%.3f, %.3f]", o.Name, o.Probability, o.Low, o.High)
+			}
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Errorf("probabilities sum to %g", total)
+		}
+		results = append(results, outcomes)
+	}
+	counts := func(os []MCOutcome) []int {
+		var c []int
+		for _, o := range os {
+			c = append(c, o.Count)
+		}
+		return c
+	}
+	if !slices.Equal(counts(results[0]), counts(results[1])) {
+		t.Errorf("counts depend on the worker count: %v against %v", counts(results[0]), counts(results[1]))
+	}
+}
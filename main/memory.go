@@ -0,0 +1,221 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file memory.go
+ * @brief Memory usage reporting and the -max-memory cap.
+ * @details The guard reads runtime.MemStats a few times a second, between chronons. Sys,
+ * the memory the runtime holds from the operating system, stands in for the resident set.
+ * At memoryDegradeShare of the cap the run sheds what it can do without (the -history
+ * frames and the per-event log lines), returns freed memory to the system and carries on;
+ * above the cap it stops after the chronon with an error. The cap is also given to the
+ * runtime as its soft memory limit, so the garbage collector works harder as it nears.
+ */
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/**
+ * @brief A number of bytes, written on the command line as e.g. 512MB or 2GB.
+ */
+type ByteSize int64
+
+/** Unit suffixes, longest first so that MiB is not read as B. KB, MB, GB and TB are binary. */
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40}, {"B", 1},
+}
+
+/**
+ * @brief Parses a size such as 2GB, 1.5G, 512MiB or 1048576.
+ * @details Implements flag.Value.
+ */
+func (b *ByteSize) Set(s string) error {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(text, u.suffix) {
+			text, unit = strings.TrimSpace(strings.TrimSuffix(text, u.suffix)), u.size
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil || v < 0 {
+		return fmt.Errorf("expected a size such as 512MB or 2GB, got %q", s)
+	}
+	*b = ByteSize(v * float64(unit))
+	return nil
+}
+
+/**
+ * @brief Formats the size in whole units where exact, otherwise in MB or KB with one decimal.
+ */
+func (b ByteSize) String() string {
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}} {
+		if int64(b) >= u.size && int64(b)%u.size == 0 {
+			return strconv.FormatInt(int64(b)/u.size, 10) + u.suffix
+		}
+	}
+	switch {
+	case b >= 1<<20:
+		return strconv.FormatFloat(float64(b)/(1<<20), 'f', 1, 64) + "MB"
+	case b >= 1<<10:
+		return strconv.FormatFloat(float64(b)/(1<<10), 'f', 1, 64) + "KB"
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+const (
+	memoryDegradeShare = 0.8                    ///< Share of the cap at which the run sheds optional memory
+	memorySampleEvery  = 250 * time.Millisecond ///< Minimum time between samples
+)
+
+/** The error a run stops with when it exceeds -max-memory. */
+var errMemoryCap = errors.New("memory cap exceeded")
+
+/**
+ * @struct MemoryGuard
+ * @brief Samples memory use between chronons and enforces a cap.
+ */
+type MemoryGuard struct {
+	limit    uint64      ///< Cap in bytes (0 only reports)
+	stop     func()      ///< Stops the run once the cap is exceeded
+	degrade  []func()    ///< Run once when memory nears the cap
+	last     time.Time   ///< Time of the last sample
+	peak     uint64      ///< Highest Sys seen
+	degraded atomic.Bool ///< Whether the degrade steps have run
+	err      error       ///< errMemoryCap once exceeded
+}
+
+/**
+ * @brief Creates a guard.
+ * @param limit Cap in bytes, or 0 to only track the peak.
+ * @param stop Called once when the cap is exceeded, normally Simulation.Stop.
+ * @return The guard; with a cap, the runtime's soft memory limit is set to it.
+ */
+func NewMemoryGuard(limit ByteSize, stop func()) *MemoryGuard {
+	if limit > 0 {
+		debug.SetMemoryLimit(int64(limit))
+	}
+	return &MemoryGuard{limit: uint64(limit), stop: stop}
+}
+
+/**
+ * @brief Adds a step that frees memory when usage nears the cap.
+ */
+func (g *MemoryGuard) OnPressure(f func()) {
+	g.degrade = append(g.degrade, f)
+}
+
+/**
+ * @brief Reports whether the run has shed optional memory; safe from any goroutine.
+ */
+func (g *MemoryGuard) Degraded() bool {
+	return g.degraded.Load()
+}
+
+/**
+ * @brief Samples memory if enough time has passed; usable as a chronon-end hook.
+ */
+func (g *MemoryGuard) Record(f *Frame, _ StepReport) {
+	if time.Since(g.last) < memorySampleEvery {
+		return
+	}
+	g.last = time.Now()
+	g.sample(f.Chronon())
+}
+
+/**
+ * @brief Reads the memory statistics and acts on the cap.
+ * @param chronon The chronon just finished, for the log.
+ */
+func (g *MemoryGuard) sample(chronon int) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	g.peak = max(g.peak, ms.Sys)
+	if g.limit == 0 || g.err != nil {
+		return
+	}
+	if !g.degraded.Load() && float64(ms.Sys) >= memoryDegradeShare*float64(g.limit) {
+		slog.Warn("memory nearing cap, dropping history and event lines", "chronon", chronon,
+			"sys", ByteSize(ms.Sys).String(), "cap", ByteSize(g.limit).String())
+		g.degraded.Store(true)
+		for _, f := range g.degrade {
+			f()
+		}
+		debug.FreeOSMemory()
+		runtime.ReadMemStats(&ms)
+	}
+	if ms.Sys > g.limit {
+		g.err = fmt.Errorf("%w: %s in use after chronon %d, cap %s", errMemoryCap, ByteSize(ms.Sys), chronon, ByteSize(g.limit))
+		g.stop()
+	}
+}
+
+/**
+ * @brief Returns the error the run stopped with, if the cap was exceeded.
+ */
+func (g *MemoryGuard) Err() error {
+	return g.err
+}
+
+/**
+ * @brief Returns the peak resident set of the process in bytes.
+ * @details Uses the kernel's high-water mark (VmHWM) where /proc is available, and the
+ * highest sampled runtime Sys elsewhere.
+ */
+func (g *MemoryGuard) PeakRSS() uint64 {
+	if rss, ok := peakRSS(); ok {
+		return rss
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return max(g.peak, ms.Sys)
+}
+
+/**
+ * @brief Reads VmHWM from /proc/self/status.
+ * @return The peak resident set in bytes, and false if it is not available.
+ */
+func peakRSS() (uint64, bool) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rest, ok := strings.CutPrefix(scanner.Text(), "VmHWM:"); ok {
+			kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(rest), " kB"), 10, 64)
+			return kb << 10, err == nil
+		}
+	}
+	return 0, false
+}
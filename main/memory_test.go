@@ -0,0 +1,67 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file memory_test.go
+ * @brief Tests for memory reporting and the -max-memory cap.
+ */
+package main
+
+import (
+	"errors"
+	"math"
+	"runtime/debug"
+	"testing"
+)
+
+func TestByteSize(t *testing.T) {
+	for in, want := range map[string]ByteSize{"2GB": 2 << 30, "1.5g": 3 << 29, "512 MiB": 512 << 20, "4096": 4096, "10kb": 10 << 10} {
+		var b ByteSize
+		if err := b.Set(in); err != nil || b != want {
+			t.Errorf("%q: got %d, %v, want %d", in, b, err, want)
+		}
+	}
+	for _, bad := range []string{"", "GB", "-1MB", "2XB"} {
+		var b ByteSize
+		if err := b.Set(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+	for b, want := range map[ByteSize]string{2 << 30: "2GB", 3 << 29: "1536MB", 15466496: "14.8MB", 1536: "1.5KB", 12: "12B"} {
+		if got := b.String(); got != want {
+			t.Errorf("%d: got %q, want %q", b, got, want)
+		}
+	}
+}
+
+func TestMemoryGuardDegradesThenStops(t *testing.T) {
+	defer debug.SetMemoryLimit(math.MaxInt64) ///< The guard sets the process-wide limit
+	stopped, degraded := false, 0
+	g := NewMemoryGuard(1<<20, func() { stopped = true }) ///< Far below any Go process
+	g.OnPressure(func() { degraded++ })
+	g.sample(3)
+	g.sample(4)
+	if degraded != 1 || !g.Degraded() {
+		t.Errorf("pressure steps ran %d times, want once", degraded)
+	}
+	if !stopped || !errors.Is(g.Err(), errMemoryCap) {
+		t.Errorf("stopped %v with %v, want the run stopped by the cap", stopped, g.Err())
+	}
+	if g.PeakRSS() == 0 {
+		t.Error("no peak memory reported")
+	}
+
+	g = NewMemoryGuard(0, func() { t.Error("an uncapped guard stopped the run") })
+	g.sample(1)
+	if g.Degraded() || g.Err() != nil || g.peak == 0 {
+		t.Errorf("an uncapped guard should only track the peak, got degraded %v, err %v, peak %d", g.Degraded(), g.Err(), g.peak)
+	}
+}
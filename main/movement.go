@@ -18,166 +18,436 @@
 package main
 
 import (
+	"context"
+	"log/slog"
 	"math/rand"
+	"runtime/trace"
 	"sync"
+	"time"
 )
 
+/**
+ * @struct StepReport
+ * @brief Execution details of a single chronon.
+ */
+type StepReport struct {
+	StepCounts
+	Events      []Event         ///< Notable events, in worker order
+	WorkerTimes []time.Duration ///< Busy time of each worker goroutine
+	Elapsed     time.Duration   ///< Wall time of the whole engine step (set by Simulation.Step)
+}
+
+/**
+ * @struct workerTally
+ * @brief Everything one worker records while processing its section.
+ * @details Owned by a single goroutine, so no locking is needed; tallies are merged into the
+ * StepReport once all workers finish.
+ */
+type workerTally struct {
+	StepCounts
+	chronon int     ///< Chronon being computed, used to stamp events
+	Events  []Event ///< Events recorded by this worker
+}
+
+/**
+ * @struct StepCounts
+ * @brief Births, deaths and moves tallied during a chronon.
+ * @details Every worker keeps its own tally; they are summed once all workers finish.
+ */
+type StepCounts struct {
+	FishBorn      int ///< Fish created by breeding
+	SharksBorn    int ///< Sharks created by breeding
+	FishEaten     int ///< Fish removed by sharks
+	SharksStarved int ///< Sharks removed by starvation
+	FishCrowded   int ///< Fish removed by the crowding rule
+	FishPoisoned  int ///< Fish removed by a red tide
+	Moves         int ///< Fish and sharks that changed cell
+}
+
+/**
+ * @brief Adds another tally to this one.
+ * @param o The tally to add.
+ */
+func (c *StepCounts) Add(o StepCounts) {
+	c.FishBorn += o.FishBorn
+	c.SharksBorn += o.SharksBorn
+	c.FishEaten += o.FishEaten
+	c.SharksStarved += o.SharksStarved
+	c.FishCrowded += o.FishCrowded
+	c.FishPoisoned += o.FishPoisoned
+	c.Moves += o.Moves
+}
+
 /**
  * @brief Moves fish and sharks concurrently in the grid using threads.
  * @details Divides the grid into sections handled by separate threads for parallel processing.
+ * Each chronon runs in two phases separated by a barrier: first every shark moves (and eats),
+ * then every fish moves. A fish whose cell was claimed by a shark in the first phase has been
+ * eaten and is dropped, so no fish can be eaten and escape in the same chronon. Within a phase
+ * every thread owns two bands of rows (see partitionBands) and all threads first process their
+ * even band, then, after another barrier, their odd one. Bands processed together are far
+ * enough apart that no two threads ever read or write the same new-grid cell, so no entity is
+ * lost at any thread count.
  * @param fishBreed Number of chronons before fish can reproduce.
  * @param sharkBreed Number of chronons before sharks can reproduce.
  * @param starveEnergy Maximum energy level before sharks die of starvation.
- * @param threads Number of threads to use for concurrent processing.
+ * @param threads Number of threads to use for concurrent processing; clamped to the number of rows.
+ * @return A StepReport describing how the chronon was executed.
+ */
+func (g *Grid) MoveEntitiesWithThreads(fishBreed, sharkBreed, starveEnergy, threads int) StepReport {
+	return g.stepSections(context.Background(), Rules{FishBreed: fishBreed, SharkBreed: sharkBreed, StarveEnergy: starveEnergy}, threads)
+}
+
+/**
+ * @brief Runs one chronon with the row-partitioned "sections" strategy.
+ * @details See MoveEntitiesWithThreads. A chronon always runs to completion; the context
+ * only labels the worker goroutines in execution traces.
+ * @param ctx Context of the run.
+ * @param rules The simulation rules.
+ * @param threads Number of threads to use; clamped to the number of rows.
+ * @return A StepReport describing how the chronon was executed.
  */
-func (g *Grid) MoveEntitiesWithThreads(fishBreed, sharkBreed, starveEnergy, threads int) {
-	newGrid := NewGrid(g.Size) ///< Create a new grid for updated positions
+func (g *Grid) stepSections(ctx context.Context, rules Rules, threads int) StepReport {
+	newGrid := g.emptyLike() ///< Create a new grid for updated positions
+
+	reach := max(rules.fishSpeed(), rules.sharkSpeed())               ///< Furthest row an entity touches in the new grid
+	bands := partitionBands(g.Size, threads, reach)                   ///< Divide rows into bands, two per thread
+	workers := max(len(bands)/2, 1)                                   ///< Worker w owns bands 2w and 2w+1
+	order := g.scanOrder(rules.Order)                                 ///< Each worker shuffles only its own rows
+	report := StepReport{WorkerTimes: make([]time.Duration, workers)} ///< Each worker writes only its own slot
+	tallies := make([]workerTally, workers)                           ///< Per-worker tallies, merged after the wait
+	rngs := make([]*rand.Rand, workers)                               ///< Per-worker sources, since rand.Rand is not goroutine-safe
+	for i := range rngs {
+		rngs[i] = g.workerRand(i)
+		tallies[i].chronon = g.Chronon + 1
+	}
 
-	rowsPerThread := g.Size / threads ///< Divide rows among threads
-	var wg sync.WaitGroup             ///< WaitGroup to synchronise goroutines
+	for _, phase := range []phase{phaseSharks, phaseFish} {
+		phaseCtx, endPhase := startSpan(ctx, phase.String())
 
-	// Launch threads to process sections of the grid
-	for i := 0; i < threads; i++ {
-		startRow := i * rowsPerThread
-		endRow := startRow + rowsPerThread
-		if i == threads-1 {
-			endRow = g.Size // Ensure the last thread handles all remaining rows
+		// Even bands first, then odd ones: bands of one colour never share a new-grid row
+		for colour := 0; colour < len(bands) && colour < 2; colour++ {
+			var wg sync.WaitGroup ///< WaitGroup to synchronise goroutines
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				worker, section := i, bands[2*i+colour]
+				start, end := section.Start, section.End
+				spawn(ctx, worker, workers, func() {
+					defer wg.Done()
+					began := time.Now()
+					_, endWorker := startSpan(phaseCtx, "worker", "worker", worker, "rows", end-start)
+					trace.WithRegion(ctx, phase.String(), func() {
+						g.processSection(newGrid, rngs[worker], &tallies[worker], phase, order, start, end, rules)
+					})
+					endWorker()
+					elapsed := time.Since(began)
+					report.WorkerTimes[worker] += elapsed
+					slog.Debug("worker timing", "worker", worker, "phase", phase, "rows", end-start, "elapsed", elapsed)
+				})
+			}
+			wg.Wait() ///< Block until every band of this colour is done
 		}
+		endPhase()
+	}
 
-		wg.Add(1)
-		go func(start, end int) {
-			defer wg.Done()
-			g.processSection(newGrid, start, end, fishBreed, sharkBreed, starveEnergy)
-		}(startRow, endRow)
+	for _, t := range tallies {
+		report.Add(t.StepCounts)
+		report.Events = append(report.Events, t.Events...)
 	}
+	g.store = newGrid.store ///< Update the main grid with the new positions
+	g.Chronon++
+	return report
+}
 
-	wg.Wait()               ///< Block until all threads complete
-	g.Cells = newGrid.Cells ///< Update the main grid with the new positions
+/**
+ * @brief Selects which species a pass over the grid processes.
+ */
+type phase int
+
+const (
+	phaseSharks phase = iota ///< Sharks move, eat, breed, and starve
+	phaseFish                ///< Surviving fish move and breed
+)
+
+/**
+ * @brief Returns the phase name used in log records.
+ */
+func (p phase) String() string {
+	if p == phaseSharks {
+		return "sharks"
+	}
+	return "fish"
+}
+
+/**
+ * @brief Returns the species a phase moves.
+ */
+func (p phase) species() Species {
+	if p == phaseSharks {
+		return SpeciesShark
+	}
+	return SpeciesFish
 }
 
 /**
  * @brief Processes a section of the grid for movement and interactions.
- * @details Handles fish and shark movement in a specific section of the grid.
+ * @details Handles either the fish or the shark movement in a specific section of the grid.
  * @param newGrid The new grid for updated positions.
+ * @param rng The worker's random source.
+ * @param tally The worker's birth, death, and event tally.
+ * @param p The phase (species) to process.
+ * @param order The chronon's update order.
  * @param startRow The starting row for this section.
  * @param endRow The ending row for this section.
- * @param fishBreed Number of chronons before fish can reproduce.
- * @param sharkBreed Number of chronons before sharks can reproduce.
- * @param starveEnergy Maximum energy level before sharks die of starvation.
+ * @param rules The simulation rules.
  */
-func (g *Grid) processSection(newGrid *Grid, startRow, endRow, fishBreed, sharkBreed, starveEnergy int) {
-	for x := startRow; x < endRow; x++ {
-		for y := 0; y < g.Size; y++ {
-			if fish, ok := g.Cells[x][y].(*Fish); ok {
-				g.processFish(newGrid, fish, x, y, fishBreed)
-			} else if shark, ok := g.Cells[x][y].(*Shark); ok {
-				g.processShark(newGrid, shark, x, y, sharkBreed, starveEnergy)
-			}
+func (g *Grid) processSection(newGrid *Grid, rng *rand.Rand, tally *workerTally, p phase, order scanOrder, startRow, endRow int, rules Rules) {
+	g.eachCell(order, rng, rowRange{Start: startRow, End: endRow}, p.species(), func(x, y int) {
+		if p == phaseFish {
+			g.processFish(newGrid, rng, tally, g.At(x, y).(*Fish), x, y, rules)
+		} else {
+			g.processShark(newGrid, rng, tally, g.At(x, y).(*Shark), x, y, rules)
 		}
-	}
+	})
 }
 
 /**
  * @brief Handles movement and reproduction of fish.
- * @details Updates fish position and reproduces based on breeding counter.
+ * @details Updates fish position and reproduces based on breeding counter. A fish walks up to
+ * its speed in cells and only breeds when it moves, leaving its offspring in the cell it vacated.
  * @param newGrid The new grid for updated positions.
+ * @param rng The worker's random source.
+ * @param tally The worker's birth, death, and event tally.
  * @param fish The fish entity to process.
  * @param x The current x-coordinate of the fish.
  * @param y The current y-coordinate of the fish.
- * @param fishBreed Number of chronons before fish can reproduce.
+ * @param rules The simulation rules.
  */
-func (g *Grid) processFish(newGrid *Grid, fish *Fish, x, y, fishBreed int) {
-	newX, newY := g.findEmptyAdjacent(x, y)
-	if newX != -1 && newY != -1 {
-		newGrid.Cells[newX][newY] = fish ///< Move fish to the new position
-	} else {
-		newGrid.Cells[x][y] = fish ///< Fish stays in its current position
+func (g *Grid) processFish(newGrid *Grid, rng *rand.Rand, tally *workerTally, fish *Fish, x, y int, rules Rules) {
+	if newGrid.speciesAt(x, y) != SpeciesNone {
+		return ///< A shark claimed this cell in the shark phase: the fish was eaten
 	}
-	fish.BreedCounter++
-	if fish.BreedCounter >= fishBreed {
-		newGrid.Cells[x][y] = &Fish{} ///< Leave a new fish in the current position
-		fish.BreedCounter = 0         ///< Reset breeding counter
+	if !takeTurn(fish, tally.chronon) {
+		return
+	}
+	if rules.crowdedOut(g, rng, x, y) {
+		dieCrowded(tally, x, y)
+		return
+	}
+
+	fish.grow()
+	newX, newY, _ := g.decidePath(newGrid, rng, fish, x, y, rules.fishSpeed(), 0, rules)
+	if newX == -1 || newY == -1 {
+		place(newGrid, fish, x, y) ///< Fish stays in its current position
+		return
+	}
+
+	place(newGrid, fish, newX, newY) ///< Move fish to the new position
+	tally.Moves++
+	if rules.fishBreeds(rng, fish, x, y) {
+		place(newGrid, rules.spawnFish(fish), x, y) ///< Leave a new fish in the current position
+		tally.FishBorn++
+		fish.BreedCounter = 0 ///< Reset breeding counter
 	}
 }
 
 /**
  * @brief Handles movement, reproduction, and starvation of sharks.
- * @details Sharks move to eat fish or to adjacent empty cells and handle reproduction and energy depletion.
+ * @details Sharks move to eat fish or to empty cells and handle reproduction and energy depletion.
+ * A shark walks up to its speed in cells and stops as soon as it reaches a fish to eat. Like
+ * fish, a shark only breeds when it moves.
  * @param newGrid The new grid for updated positions.
+ * @param rng The worker's random source.
+ * @param tally The worker's birth, death, and event tally.
  * @param shark The shark entity to process.
  * @param x The current x-coordinate of the shark.
  * @param y The current y-coordinate of the shark.
- * @param sharkBreed Number of chronons before sharks can reproduce.
- * @param starveEnergy Maximum energy level before sharks die of starvation.
+ * @param rules The simulation rules.
  */
-func (g *Grid) processShark(newGrid *Grid, shark *Shark, x, y, sharkBreed, starveEnergy int) {
-	shark.Energy-- ///< Sharks lose energy each step
-	if shark.Energy <= 0 {
-		return ///< Shark dies if energy reaches 0
+func (g *Grid) processShark(newGrid *Grid, rng *rand.Rand, tally *workerTally, shark *Shark, x, y int, rules Rules) {
+	if !takeTurn(shark, tally.chronon) {
+		return
+	}
+	if rules.hunger(rng, shark) {
+		starve(newGrid, tally, shark, x, y) ///< Shark dies if it cannot afford to act
+		return
 	}
 
-	newX, newY := g.findNearestFish(x, y)
-	if newX != -1 && newY != -1 {
-		newGrid.Cells[newX][newY] = shark ///< Move shark to eat fish
-		shark.Energy = starveEnergy       ///< Reset energy after eating
+	shark.BreedCounter++
+	speed, vision := rules.sharkReach(shark)
+	newX, newY, ate := g.decidePath(newGrid, rng, shark, x, y, speed, vision, rules)
+	if newX == -1 || newY == -1 || (!ate && !rules.canMove(shark)) {
+		if rules.spend(shark, false) {
+			starve(newGrid, tally, shark, x, y)
+			return
+		}
+		place(newGrid, shark, x, y) ///< Shark stays in its current position
+		return
+	}
+	place(newGrid, shark, newX, newY) ///< Move shark to eat fish or to an empty cell
+	tally.Moves++
+	if ate {
+		tally.FishEaten++
+		rules.feed(shark, g.At(newX, newY)) ///< Reset energy after eating
 	} else {
-		newX, newY = g.findEmptyAdjacent(x, y)
-		if newX != -1 && newY != -1 {
-			newGrid.Cells[newX][newY] = shark ///< Move shark to an empty cell
-		} else {
-			newGrid.Cells[x][y] = shark ///< Shark stays in its current position
+		rules.spend(shark, true) ///< Affordable: checked by canMove
+	}
+
+	if rules.sharkBreeds(rng, shark, x, y) {
+		if child, ok := rules.offspring(shark); ok {
+			place(newGrid, child, x, y) ///< Reproduce a new shark
+			tally.SharksBorn++
+			shark.BreedCounter = 0 ///< Reset breeding counter
 		}
 	}
+}
+
+/**
+ * @brief Removes a shark that ran out of energy and records its death.
+ * @details Runs in the shark phase before the shark plans any move, so a dying shark never
+ * claims a destination or eats (a shark that must stay put but cannot afford to also starves
+ * in place, likewise without claiming anything). It is not copied into the new grid, leaving its cell free for
+ * the next chronon, and the death is counted and reported as an EventSharkStarved.
+ * @param newGrid The new grid for updated positions.
+ * @param tally The worker's birth, death, and event tally.
+ * @param shark The starving shark.
+ * @param x The x-coordinate of the shark.
+ * @param y The y-coordinate of the shark.
+ */
+func starve(newGrid *Grid, tally *workerTally, shark *Shark, x, y int) {
+	shark.Energy = 0
+	newGrid.Set(x, y, nil) ///< Nobody else may claim an occupied cell, so this only makes the vacancy explicit
+	tally.SharksStarved++
+	tally.Events = append(tally.Events, Event{Chronon: tally.chronon, Kind: EventSharkStarved, X: x, Y: y})
+	slog.Debug("shark starved", "chronon", tally.chronon, "x", x, "y", y)
+}
+
+/**
+ * @brief Writes an entity into the new grid, logging any conflict it resolves.
+ * @details Destinations are checked against the new grid before moving, and bands processed at
+ * the same time never share a new-grid row, so no two entities pick the same cell. The moves
+ * engine's committer relies on place to replace a fish that committed in place before being
+ * eaten; any such replacement is reported at debug level.
+ * @param newGrid The new grid for updated positions.
+ * @param e The entity to place.
+ * @param x The destination x-coordinate.
+ * @param y The destination y-coordinate.
+ */
+func place(newGrid *Grid, e Entity, x, y int) {
+	if prev := newGrid.At(x, y); prev != nil && prev != e {
+		slog.Debug("conflict resolved", "x", x, "y", y, "winner", speciesName(e), "loser", speciesName(prev))
+	}
+	newGrid.Set(x, y, e)
+}
 
-	shark.BreedCounter++
-	if shark.BreedCounter >= sharkBreed {
-		newGrid.Cells[x][y] = &Shark{Energy: starveEnergy} ///< Reproduce a new shark
-		shark.BreedCounter = 0                             ///< Reset breeding counter
+/**
+ * @brief Chooses where an entity ends up after walking up to speed cells.
+ * @details Each step moves to a random free neighbour of the current cell. A hunting shark
+ * first looks for an adjacent fish at every step and ends its walk on the first one it finds;
+ * with a vision above 1 it otherwise steps toward the nearest fish within sight.
+ * The walk also ends early when the current cell has no free neighbour that is not already on
+ * the path. With speed 1 this is a single findNearestFish/findEmptyAdjacent choice.
+ * @param taken Destinations already claimed this chronon (the new grid, or a claimGrid).
+ * @param rng The worker's random source.
+ * @param x The x-coordinate of the starting cell.
+ * @param y The y-coordinate of the starting cell.
+ * @param speed Maximum number of cells to move.
+ * @param vision 0 for fish; for sharks, the path length within which fish are pursued.
+ * @return The destination, or (-1, -1) if the entity cannot move, and whether it ate a fish.
+ */
+func (g *Grid) choosePath(taken cellClaims, rng *rand.Rand, x, y, speed, vision int) (int, int, bool) {
+	hunt := vision > 0
+	unclaimed := func(x, y int) bool { return !taken.claimed(x, y) }
+	var buf [4][2]int
+	path := buf[:0] ///< Cells walked so far; the start cell is occupied, so it is never revisited
+	destX, destY := -1, -1
+	for step := 0; step < speed; step++ {
+		if hunt {
+			if fx, fy := g.findNearestFish(taken, rng, x, y); fx != -1 && fy != -1 {
+				return fx, fy, true
+			}
+		}
+		nx, ny := -1, -1
+		if vision > 1 {
+			nx, ny = g.stepTowardFish(rng, x, y, vision, path, unclaimed)
+		}
+		if nx == -1 || ny == -1 {
+			nx, ny = g.findEmptyAdjacent(taken, rng, x, y, path)
+		}
+		if nx == -1 || ny == -1 {
+			break ///< Blocked
+		}
+		x, y = nx, ny
+		destX, destY = nx, ny
+		path = append(path, [2]int{nx, ny})
 	}
+	return destX, destY, false
 }
 
 /**
  * @brief Finds an adjacent empty cell for movement.
- * @details Searches the four directions (North, South, West, East) for empty cells.
+ * @details Searches the four directions (North, South, West, East) for cells that are empty
+ * in the current grid, not yet claimed in the new one, and not already on the path.
+ * @param taken Destinations already claimed this chronon.
+ * @param rng The worker's random source.
  * @param x The x-coordinate of the current cell.
  * @param y The y-coordinate of the current cell.
+ * @param path Cells already walked this chronon.
  * @return Coordinates of an empty cell, or (-1, -1) if none are available.
  */
-func (g *Grid) findEmptyAdjacent(x, y int) (int, int) {
-	directions := []struct{ dx, dy int }{
-		{-1, 0}, {1, 0}, {0, -1}, {0, 1}, // North, South, West, East
-	}
-	rand.Shuffle(len(directions), func(i, j int) { directions[i], directions[j] = directions[j], directions[i] }) // Randomise directions
-
-	for _, dir := range directions {
-		newX := (x + dir.dx + g.Size) % g.Size
-		newY := (y + dir.dy + g.Size) % g.Size
-		if g.Cells[newX][newY] == nil {
+func (g *Grid) findEmptyAdjacent(taken cellClaims, rng *rand.Rand, x, y int, path [][2]int) (int, int) {
+	for _, d := range randomDirections(rng) {
+		newX, newY := g.neighbour(x, y, d)
+		if g.speciesAt(newX, newY) == SpeciesNone && !taken.claimed(newX, newY) && !onPath(path, newX, newY) {
 			return newX, newY
 		}
 	}
 	return -1, -1 ///< No empty adjacent cells found
 }
 
+/**
+ * @brief Reports whether (x, y) is one of the path's cells.
+ */
+func onPath(path [][2]int, x, y int) bool {
+	for _, p := range path {
+		if p[0] == x && p[1] == y {
+			return true
+		}
+	}
+	return false
+}
+
 /**
  * @brief Finds the nearest adjacent fish for a shark to eat.
- * @details Searches the four cardinal directions for fish.
+ * @details Searches the four cardinal directions for fish not already taken by another shark.
+ * @param taken Destinations already claimed this chronon.
+ * @param rng The worker's random source.
  * @param x The x-coordinate of the current cell.
  * @param y The y-coordinate of the current cell.
  * @return Coordinates of the nearest fish, or (-1, -1) if none are found.
  */
-func (g *Grid) findNearestFish(x, y int) (int, int) {
-	directions := []struct{ dx, dy int }{
-		{-1, 0}, {1, 0}, {0, -1}, {0, 1}, // North, South, West, East
-	}
-	rand.Shuffle(len(directions), func(i, j int) { directions[i], directions[j] = directions[j], directions[i] }) // Randomise directions
-
-	for _, dir := range directions {
-		newX := (x + dir.dx + g.Size) % g.Size        ///< Wrap around toroidal grid horizontally
-		newY := (y + dir.dy + g.Size) % g.Size        ///< Wrap around toroidal grid vertically
-		if _, ok := g.Cells[newX][newY].(*Fish); ok { ///< Check if the cell contains a fish
+func (g *Grid) findNearestFish(taken cellClaims, rng *rand.Rand, x, y int) (int, int) {
+	for _, d := range randomDirections(rng) {
+		newX, newY := g.neighbour(x, y, d)
+		if g.speciesAt(newX, newY) == SpeciesFish && !taken.claimed(newX, newY) { ///< Check if the cell contains an unclaimed fish
 			return newX, newY
 		}
 	}
 	return -1, -1 ///< No fish found in adjacent cells
 }
+
+/**
+ * @brief Runs exactly one chronon on a single thread from the given seed.
+ * @details Deterministic: the same grid, seed, and parameters always produce the same result,
+ * which makes it the entry point for fuzzing and for reproducing bug reports.
+ * @param g The grid to advance.
+ * @param seed Seed for all random choices made during the chronon.
+ * @param fishBreed Number of chronons before fish can reproduce.
+ * @param sharkBreed Number of chronons before sharks can reproduce.
+ * @param starveEnergy Maximum energy level before sharks die of starvation.
+ * @return The births and deaths of the chronon.
+ */
+func StepOnce(g *Grid, seed int64, fishBreed, sharkBreed, starveEnergy int) StepReport {
+	g.Seed(seed)
+	return g.MoveEntitiesWithThreads(fishBreed, sharkBreed, starveEnergy, 1)
+}
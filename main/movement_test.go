@@ -0,0 +1,123 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file movement_test.go
+ * @brief Benchmarks of the sections engine's hot paths and of whole chronons.
+ * @details Compare two trees with "make bench-compare" from the repository root.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+/** Rules of every benchmark, as in BenchmarkEngine. */
+var benchRules = Rules{FishBreed: 3, SharkBreed: 3, StarveEnergy: 4}
+
+/**
+ * @brief Returns a seeded grid of the given side with density of its cells occupied, a sixth of them by sharks.
+ */
+func benchGrid(b *testing.B, size int, density float64) *Grid {
+	g := NewGrid(size)
+	g.Seed(1)
+	animals := int(density * float64(size*size))
+	if err := g.Initialize(animals-animals/6, animals/6, 4); err != nil {
+		b.Fatal(err)
+	}
+	return g
+}
+
+/**
+ * @brief Returns the cells of a grid holding the given kind of entity.
+ */
+func occupiedCells[T Entity](g *Grid) [][2]int {
+	var cells [][2]int
+	for x := 0; x < g.Size; x++ {
+		for y := 0; y < g.Size; y++ {
+			if _, ok := g.At(x, y).(T); ok {
+				cells = append(cells, [2]int{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+func BenchmarkProcessSection(b *testing.B) {
+	for _, density := range []float64{0.1, 0.5, 0.9} {
+		for _, p := range []phase{phaseSharks, phaseFish} {
+			b.Run(fmt.Sprintf("density=%.1f/%s", density, p), func(b *testing.B) {
+				g := benchGrid(b, 200, density)
+				rng := rand.New(rand.NewSource(1))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					next := g.emptyLike()                ///< Every pass starts from the same grid into a fresh frame
+					tally := workerTally{chronon: i + 1} ///< A new chronon, so every entity may take its turn again
+					b.StartTimer()
+					g.processSection(next, rng, &tally, p, g.scanOrder(benchRules.Order), 0, g.Size, benchRules)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkFindEmptyAdjacent(b *testing.B) {
+	for _, density := range []float64{0.1, 0.5, 0.9} {
+		b.Run(fmt.Sprintf("density=%.1f", density), func(b *testing.B) {
+			g := benchGrid(b, 200, density)
+			cells := occupiedCells[*Fish](g)
+			taken := g.emptyLike()
+			rng := rand.New(rand.NewSource(1))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c := cells[i%len(cells)]
+				g.findEmptyAdjacent(taken, rng, c[0], c[1], nil)
+			}
+		})
+	}
+}
+
+func BenchmarkFindNearestFish(b *testing.B) {
+	for _, density := range []float64{0.1, 0.5, 0.9} {
+		b.Run(fmt.Sprintf("density=%.1f", density), func(b *testing.B) {
+			g := benchGrid(b, 200, density)
+			cells := occupiedCells[*Shark](g)
+			taken := g.emptyLike()
+			rng := rand.New(rand.NewSource(1))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c := cells[i%len(cells)]
+				g.findNearestFish(taken, rng, c[0], c[1])
+			}
+		})
+	}
+}
+
+func BenchmarkChronon(b *testing.B) {
+	for _, size := range []int{50, 200, 1000} {
+		for _, density := range []float64{0.1, 0.5, 0.9} {
+			for _, threads := range []int{1, 4} {
+				b.Run(fmt.Sprintf("size=%d/density=%.1f/threads=%d", size, density, threads), func(b *testing.B) {
+					g := benchGrid(b, size, density)
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						g.stepSections(context.Background(), benchRules, threads)
+					}
+					b.ReportMetric(float64(size*size*b.N)/b.Elapsed().Seconds(), "cells/s")
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,382 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file moves.go
+ * @brief The channel-of-moves engine.
+ * @details Worker threads scan their rows and plan a move for every entity, listing the
+ * destinations they would accept in order of preference. The plans are sent over a channel
+ * to a single committer goroutine, which is the only writer of the new grid and of entity
+ * state. It applies each plan to the first destination still free, so conflicts are resolved
+ * without locks and nothing is ever overwritten. Planning is parallel; committing is serial.
+ */
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"runtime/trace"
+	"sync"
+	"time"
+)
+
+/**
+ * @struct plannedMove
+ * @brief The candidate destinations of one entity, produced by a planning worker.
+ */
+type plannedMove struct {
+	entity Entity
+	x, y   int
+	prey   [4][2]int ///< Adjacent fish cells a shark may eat, in preference order
+	nPrey  int
+	empty  [4][2]int ///< Adjacent empty cells, in preference order
+	nEmpty int
+	path   [][2]int ///< Multi-cell walk starting at empty[0], for entities faster than 1
+	far    [2]int   ///< Fish a shark reached at the end of its path
+	hasFar bool
+	dies   bool ///< The fish dies of crowding instead of moving
+}
+
+/**
+ * @brief State of the fish that stood in a cell at the start of the chronon.
+ */
+type fishFate uint8
+
+const (
+	fishPending fishFate = iota ///< Not committed yet: a shark may still eat it
+	fishStayed                  ///< Committed without moving: still edible in place
+	fishMoved                   ///< Committed to another cell: its old cell holds no fish
+	fishEaten                   ///< Eaten: its own plan must be discarded
+)
+
+/**
+ * @struct movesEngine
+ * @brief Plan-in-parallel, commit-serially strategy.
+ */
+type movesEngine struct{}
+
+func (movesEngine) Name() string { return "moves" }
+
+/**
+ * @brief Runs one chronon with the channel-of-moves strategy.
+ * @param ctx Context of the run; labels the planners in execution traces.
+ * @param g The grid to advance.
+ * @param rules The simulation rules.
+ * @param threads Number of planning threads; clamped to the number of rows.
+ * @return A StepReport; WorkerTimes holds the planning time of each worker.
+ */
+func (movesEngine) Step(ctx context.Context, g *Grid, rules Rules, threads int) StepReport {
+	sections := partitionRows(g.Size, threads)
+	report := StepReport{WorkerTimes: make([]time.Duration, len(sections))}
+	plans := make(chan plannedMove, 256) ///< Buffered so planners rarely wait on the committer
+	order := g.scanOrder(rules.Order)    ///< Plans reach the committer in each band's update order
+
+	var wg sync.WaitGroup
+	for i, section := range sections {
+		rng := g.workerRand(i) ///< Per-worker source, since rand.Rand is not goroutine-safe
+		wg.Add(1)
+		worker := i
+		spawn(ctx, worker, len(sections), func() {
+			defer wg.Done()
+			began := time.Now()
+			_, end := startSpan(ctx, "plan", "worker", worker, "rows", section.End-section.Start)
+			trace.WithRegion(ctx, "plan", func() { g.planSection(rng, order, section, rules, plans) })
+			end()
+			report.WorkerTimes[worker] = time.Since(began)
+			slog.Debug("worker timing", "worker", worker, "rows", section.End-section.Start, "elapsed", report.WorkerTimes[worker])
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(plans) ///< Lets the committer finish once every plan is in
+	}()
+
+	newGrid := g.emptyLike()
+	fates := make([][]fishFate, g.Size)
+	for i := range fates {
+		fates[i] = make([]fishFate, g.Size)
+	}
+	tally := workerTally{chronon: g.Chronon + 1}
+	var rng *rand.Rand ///< Committer's source; only the stochastic rule set draws from it
+	if rules.stochastic() {
+		rng = g.workerRand(len(sections)) ///< After the planners' sources, which they are still using
+	}
+	_, endCommit := startSpan(ctx, "commit") ///< Conflicting moves are resolved as they are committed
+	for m := range plans {
+		g.commitMove(newGrid, rng, fates, &tally, m, rules)
+	}
+	endCommit()
+
+	report.StepCounts = tally.StepCounts
+	report.Events = tally.Events
+	g.store = newGrid.store
+	g.Chronon++
+	return report
+}
+
+/**
+ * @brief Plans a move for every entity in a band of rows.
+ * @details Reads only the current grid, which nobody writes during the chronon.
+ * @param rng The worker's random source.
+ * @param order The chronon's update order, in which the plans are sent.
+ * @param section The rows to plan.
+ * @param rules The simulation rules.
+ * @param plans Channel receiving the plans.
+ */
+func (g *Grid) planSection(rng *rand.Rand, order scanOrder, section rowRange, rules Rules, plans chan<- plannedMove) {
+	g.eachCell(order, rng, section, SpeciesNone, func(x, y int) {
+		if m, ok := g.planCell(rng, x, y, rules); ok {
+			plans <- m
+		}
+	})
+}
+
+/**
+ * @brief Plans the move of the entity in one cell.
+ * @param rng The worker's random source.
+ * @param x The x-coordinate of the cell.
+ * @param y The y-coordinate of the cell.
+ * @param rules The simulation rules.
+ * @return The plan, or false if the cell is empty.
+ */
+func (g *Grid) planCell(rng *rand.Rand, x, y int, rules Rules) (plannedMove, bool) {
+	if g.speciesAt(x, y) == SpeciesNone {
+		return plannedMove{}, false
+	}
+	e := g.At(x, y)
+	m := plannedMove{entity: e, x: x, y: y}
+	shark, isShark := e.(*Shark)
+	if !isShark && rules.crowdedOut(g, rng, x, y) {
+		m.dies = true
+		return m, true
+	}
+	sated := isShark && shark.Sated > 0 ///< Sated sharks wander like fish; commitMove counts the satiation down
+	hunting := isShark && !sated && rules.FoodWeb.Eats(SpeciesShark, SpeciesFish)
+	if sated && rules.SatedRest {
+		return m, true ///< No candidates: the shark stays put
+	}
+	for _, d := range randomDirections(rng) {
+		nx, ny := g.neighbour(x, y, d)
+		switch g.speciesAt(nx, ny) {
+		case SpeciesNone:
+			m.empty[m.nEmpty] = [2]int{nx, ny}
+			m.nEmpty++
+		case SpeciesFish:
+			if hunting {
+				m.prey[m.nPrey] = [2]int{nx, ny}
+				m.nPrey++
+			}
+		}
+	}
+	if dir, ok := rules.Behaviour.decide(g, rng, e, x, y); ok {
+		g.scriptedPlan(&m, dir)
+		return m, true
+	}
+	speed := rules.fishSpeed()
+	if isShark {
+		speed = rules.sharkSpeed()
+		if hunting && rules.sharkVision() > 1 && m.nPrey == 0 && m.nEmpty > 0 {
+			g.preferStepTowardFish(rng, &m, rules.sharkVision())
+		}
+	}
+	if speed > 1 && m.nPrey == 0 && m.nEmpty > 0 {
+		g.planPath(rng, &m, hunting, speed)
+	}
+	return m, true
+}
+
+/**
+ * @brief Moves the empty neighbour on a shortest path to the nearest visible fish to the front
+ * of a shark's candidates.
+ * @param rng The worker's random source.
+ * @param m The plan to reorder.
+ * @param vision Maximum path length to a fish.
+ */
+func (g *Grid) preferStepTowardFish(rng *rand.Rand, m *plannedMove, vision int) {
+	sx, sy := g.stepTowardFish(rng, m.x, m.y, vision, nil, func(int, int) bool { return true })
+	for i := 0; i < m.nEmpty; i++ {
+		if m.empty[i] == [2]int{sx, sy} {
+			m.empty[0], m.empty[i] = m.empty[i], m.empty[0]
+			return
+		}
+	}
+}
+
+/**
+ * @brief Extends a plan with a walk of up to speed cells.
+ * @details The walk starts at the first empty neighbour and continues through cells that are
+ * empty in the current grid. A shark ends its walk on the first fish next to the path.
+ * @param rng The worker's random source.
+ * @param m The plan to extend.
+ * @param isShark Whether the entity hunts.
+ * @param speed Maximum number of cells to move.
+ */
+func (g *Grid) planPath(rng *rand.Rand, m *plannedMove, isShark bool, speed int) {
+	m.path = append(m.path, m.empty[0])
+	x, y := m.empty[0][0], m.empty[0][1]
+	for step := 1; step < speed; step++ {
+		next := [2]int{-1, -1}
+		for _, d := range randomDirections(rng) {
+			nx, ny := g.neighbour(x, y, d)
+			switch g.speciesAt(nx, ny) {
+			case SpeciesNone:
+				if next[0] == -1 && !onPath(m.path, nx, ny) {
+					next = [2]int{nx, ny}
+				}
+			case SpeciesFish:
+				if isShark {
+					m.far, m.hasFar = [2]int{nx, ny}, true
+					return
+				}
+			}
+		}
+		if next[0] == -1 {
+			return ///< Blocked
+		}
+		m.path = append(m.path, next)
+		x, y = next[0], next[1]
+	}
+}
+
+/** Offsets of the four cardinal neighbours: North, South, West, East. */
+var neighbourOffsets = [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+
+/**
+ * @brief Applies one plan to the new grid.
+ * @details Called only from the committer goroutine. Sharks lose energy and may starve, then
+ * eat the first prey that has not moved away or been eaten (adjacent prey first, then the fish
+ * at the end of their path), else move to the furthest free cell of their path or the first
+ * free empty cell. Fish that were eaten before their plan arrives are dropped. Entities whose
+ * every candidate is taken stay put; their own cell can never be claimed by anyone else. The
+ * receiver is the grid the chronon started from, read only to see what a shark eats.
+ * @param newGrid The new grid for updated positions.
+ * @param rng The committer's random source.
+ * @param fates Fate of each cell's starting fish.
+ * @param tally The committer's birth, death, and event tally.
+ * @param m The plan to apply.
+ * @param rules The simulation rules.
+ */
+func (g *Grid) commitMove(newGrid *Grid, rng *rand.Rand, fates [][]fishFate, tally *workerTally, m plannedMove, rules Rules) {
+	switch e := m.entity.(type) {
+	case *Shark:
+		if !takeTurn(e, tally.chronon) {
+			return
+		}
+		if rules.hunger(rng, e) {
+			starve(newGrid, tally, e, m.x, m.y)
+			return
+		}
+		e.BreedCounter++
+		e.digest() ///< planCell gave a sated shark no prey
+
+		prey := m.prey[:m.nPrey]
+		if m.hasFar {
+			prey = append(prey, m.far)
+		}
+		for _, p := range prey {
+			px, py := p[0], p[1]
+			if fate := fates[px][py]; fate == fishPending || fate == fishStayed {
+				fates[px][py] = fishEaten
+				place(newGrid, e, px, py) ///< Replaces the fish if it already committed in place
+				tally.FishEaten++
+				tally.Moves++
+				rules.feed(e, g.At(px, py))
+				breedShark(newGrid, rng, tally, e, m.x, m.y, rules)
+				return
+			}
+			slog.Debug("conflict resolved", "x", px, "y", py, "winner", "fish", "loser", "shark", "reason", "prey gone")
+		}
+		if nx, ny, ok := firstFree(newGrid, m); ok && rules.canMove(e) {
+			place(newGrid, e, nx, ny)
+			tally.Moves++
+			rules.spend(e, true)
+			breedShark(newGrid, rng, tally, e, m.x, m.y, rules)
+			return
+		}
+		if rules.spend(e, false) {
+			starve(newGrid, tally, e, m.x, m.y)
+			return
+		}
+		place(newGrid, e, m.x, m.y)
+
+	case *Fish:
+		if fates[m.x][m.y] == fishEaten {
+			return ///< A shark got here first
+		}
+		if !takeTurn(e, tally.chronon) {
+			return
+		}
+		if m.dies {
+			fates[m.x][m.y] = fishMoved ///< Its cell holds no fish any more
+			dieCrowded(tally, m.x, m.y)
+			return
+		}
+		e.grow()
+		if nx, ny, ok := firstFree(newGrid, m); ok {
+			fates[m.x][m.y] = fishMoved
+			place(newGrid, e, nx, ny)
+			tally.Moves++
+			if rules.fishBreeds(rng, e, m.x, m.y) {
+				place(newGrid, rules.spawnFish(e), m.x, m.y)
+				tally.FishBorn++
+				e.BreedCounter = 0
+			}
+			return
+		}
+		fates[m.x][m.y] = fishStayed
+		place(newGrid, e, m.x, m.y)
+	}
+}
+
+/**
+ * @brief Finds the planned destination to commit: the furthest free cell of the path, else the
+ * first free adjacent empty cell.
+ * @param newGrid The new grid for updated positions.
+ * @param m The plan.
+ * @return The destination and true, or false if every candidate is taken.
+ */
+func firstFree(newGrid *Grid, m plannedMove) (int, int, bool) {
+	for i := len(m.path) - 1; i > 0; i-- { ///< path[0] is empty[0], checked below
+		if nx, ny := m.path[i][0], m.path[i][1]; newGrid.speciesAt(nx, ny) == SpeciesNone {
+			return nx, ny, true
+		}
+	}
+	for i := 0; i < m.nEmpty; i++ {
+		nx, ny := m.empty[i][0], m.empty[i][1]
+		if newGrid.speciesAt(nx, ny) == SpeciesNone {
+			return nx, ny, true
+		}
+		slog.Debug("conflict resolved", "x", nx, "y", ny, "winner", speciesName(newGrid.At(nx, ny)), "loser", speciesName(m.entity), "reason", "re-planned")
+	}
+	return 0, 0, false
+}
+
+/**
+ * @brief Leaves a newborn shark in the vacated cell if the rule set says the parent breeds.
+ * @param newGrid The new grid for updated positions.
+ * @param rng The committer's random source.
+ * @param tally The committer's birth, death, and event tally.
+ * @param shark The parent, which has just moved away from (x, y).
+ * @param x The vacated x-coordinate.
+ * @param y The vacated y-coordinate.
+ * @param rules The simulation rules.
+ */
+func breedShark(newGrid *Grid, rng *rand.Rand, tally *workerTally, shark *Shark, x, y int, rules Rules) {
+	if !rules.sharkBreeds(rng, shark, x, y) {
+		return
+	}
+	if child, ok := rules.offspring(shark); ok {
+		place(newGrid, child, x, y)
+		tally.SharksBorn++
+		shark.BreedCounter = 0
+	}
+}
@@ -0,0 +1,214 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file notify.go
+ * @brief Audible and desktop notifications on milestones (the -notify option).
+ * @details Long runs are rarely watched. With -notify the run calls attention to itself when
+ * a species dies out and when a -trigger fires: "bell" rings the terminal bell (written to
+ * standard error, so piped frames stay clean) and "desktop" shows a desktop notification
+ * through notify-send on Linux and the BSDs or osascript on macOS. Both may be given, separated
+ * by commas. Desktop notifications are sent in the background, so a slow notification daemon
+ * never holds up the simulation; when the command is missing the run logs a warning and
+ * carries on without them.
+ */
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+/**
+ * @brief Something that can tell the user about a milestone.
+ */
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+/** Names accepted by -notify. */
+var notifierNames = []string{"bell", "desktop"}
+
+/**
+ * @struct BellNotifier
+ * @brief Rings the terminal bell.
+ */
+type BellNotifier struct {
+	W io.Writer ///< The terminal, usually os.Stderr
+}
+
+/**
+ * @brief Writes the BEL character.
+ */
+func (b BellNotifier) Notify(string, string) error {
+	_, err := io.WriteString(b.W, "\a")
+	return err
+}
+
+/**
+ * @struct DesktopNotifier
+ * @brief Shows desktop notifications by running the platform's notification command.
+ */
+type DesktopNotifier struct {
+	GOOS string ///< Platform whose command is used, usually runtime.GOOS
+}
+
+/**
+ * @brief Returns the command showing a desktop notification.
+ * @param goos The platform.
+ * @return The command line, or false on a platform without a supported command.
+ */
+func desktopCommand(goos, title, message string) ([]string, bool) {
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return []string{"notify-send", title, message}, true
+	case "darwin":
+		quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+		return []string{"osascript", "-e", fmt.Sprintf(`display notification "%s" with title "%s"`, quote.Replace(message), quote.Replace(title))}, true
+	}
+	return nil, false
+}
+
+/**
+ * @brief Reports whether the platform's notification command can be run.
+ */
+func (d DesktopNotifier) Available() error {
+	cmd, ok := desktopCommand(d.GOOS, "", "")
+	if !ok {
+		return fmt.Errorf("no desktop notification command on %s", d.GOOS)
+	}
+	_, err := exec.LookPath(cmd[0])
+	return err
+}
+
+/**
+ * @brief Starts the notification command without waiting for it.
+ */
+func (d DesktopNotifier) Notify(title, message string) error {
+	args, ok := desktopCommand(d.GOOS, title, message)
+	if !ok {
+		return fmt.Errorf("no desktop notification command on %s", d.GOOS)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait() ///< Reap the process; its exit status does not matter
+	return nil
+}
+
+/**
+ * @brief Sends every notification to several notifiers.
+ */
+type notifiers []Notifier
+
+/**
+ * @brief Notifies each notifier in turn.
+ * @return The errors of the notifiers that failed, joined.
+ */
+func (ns notifiers) Notify(title, message string) error {
+	var errs []error
+	for _, n := range ns {
+		errs = append(errs, n.Notify(title, message))
+	}
+	return errors.Join(errs...)
+}
+
+/**
+ * @brief Checks a -notify value.
+ * @param spec Comma-separated notifier names; empty disables notifications.
+ * @return The names, or an error naming an unknown one.
+ */
+func parseNotify(spec string) ([]string, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(notifierNames, name) {
+			return nil, fmt.Errorf("unknown notifier %q (want %s)", name, strings.Join(notifierNames, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+/**
+ * @brief Builds the notifiers of a validated -notify value.
+ * @return The notifier, or nil when there is none; unavailable desktop notifications are
+ * logged and left out.
+ */
+func newNotifier(spec string) Notifier {
+	names, _ := parseNotify(spec) ///< Validated by parseConfig
+	var ns notifiers
+	for _, name := range names {
+		switch name {
+		case "bell":
+			ns = append(ns, BellNotifier{W: os.Stderr})
+		case "desktop":
+			d := DesktopNotifier{GOOS: runtime.GOOS}
+			if err := d.Available(); err != nil {
+				slog.Warn("desktop notifications unavailable", "err", err)
+				continue
+			}
+			ns = append(ns, d)
+		}
+	}
+	if len(ns) == 0 {
+		return nil
+	}
+	return ns
+}
+
+/**
+ * @struct ExtinctionNotifier
+ * @brief Notifies when a species dies out. Usable as a StatsHook.
+ */
+type ExtinctionNotifier struct {
+	Notifier     Notifier
+	fish, sharks int  ///< Populations at the previous check
+	started      bool ///< Whether fish and sharks hold a previous check
+}
+
+/**
+ * @brief Checks the populations after a chronon and notifies for each species that has just died out.
+ * @details A species absent from the start is not reported, nor again until it has returned.
+ */
+func (en *ExtinctionNotifier) Observe(p Population, _ StepReport) {
+	if en.started {
+		for _, sp := range []struct {
+			name      string
+			now, then int
+		}{{"fish", p.Fish, en.fish}, {"sharks", p.Sharks, en.sharks}} {
+			if sp.now == 0 && sp.then > 0 {
+				notify(en.Notifier, "Wa-Tor: "+sp.name+" extinct", fmt.Sprintf("The %s died out at chronon %d.", sp.name, p.Chronon))
+			}
+		}
+	}
+	en.fish, en.sharks, en.started = p.Fish, p.Sharks, true
+}
+
+/**
+ * @brief Sends a notification, logging a failure instead of stopping the run.
+ */
+func notify(n Notifier, title, message string) {
+	if err := n.Notify(title, message); err != nil {
+		slog.Warn("notification failed", "err", err)
+	}
+}
@@ -0,0 +1,89 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file notify_test.go
+ * @brief Tests for -notify and the notifiers.
+ */
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Records the notifications it receives.
+ */
+type recordingNotifier struct {
+	titles []string
+}
+
+func (r *recordingNotifier) Notify(title, _ string) error {
+	r.titles = append(r.titles, title)
+	return nil
+}
+
+func TestExtinctionNotifier(t *testing.T) {
+	rec := &recordingNotifier{}
+	en := &ExtinctionNotifier{Notifier: rec}
+	pops := []Population{
+		{Chronon: 0, Fish: 10, Sharks: 0}, ///< Sharks absent from the start
+		{Chronon: 1, Fish: 3, Sharks: 0},
+		{Chronon: 2, Fish: 0, Sharks: 0},
+		{Chronon: 3, Fish: 0, Sharks: 0},
+		{Chronon: 4, Fish: 2, Sharks: 0},
+		{Chronon: 5, Fish: 0, Sharks: 0},
+	}
+	for _, p := range pops {
+		en.Observe(p, StepReport{})
+	}
+	want := "Wa-Tor: fish extinct|Wa-Tor: fish extinct"
+	if got := strings.Join(rec.titles, "|"); got != want {
+		t.Errorf("notifications %q, want %q", got, want)
+	}
+}
+
+func TestTriggerNotifies(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTriggerSet(sim, "chronon == 2", filepath.Join(t.TempDir(), "snap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &recordingNotifier{}
+	ts.Notifier = rec
+	sim.OnStats(ts.Observe)
+	sim.Run(context.Background(), 4)
+	if len(rec.titles) != 1 || rec.titles[0] != "Wa-Tor: trigger fired" {
+		t.Errorf("notifications %q, want one trigger notification", rec.titles)
+	}
+}
+
+func TestBellAndDesktopCommands(t *testing.T) {
+	var out strings.Builder
+	if err := (BellNotifier{W: &out}).Notify("t", "m"); err != nil || out.String() != "\a" {
+		t.Errorf("bell wrote %q, %v", out.String(), err)
+	}
+	if cmd, _ := desktopCommand("linux", "Wa-Tor", "fish extinct"); strings.Join(cmd, "|") != "notify-send|Wa-Tor|fish extinct" {
+		t.Errorf("linux command %q", cmd)
+	}
+	if cmd, _ := desktopCommand("darwin", "Wa-Tor", `say "hi"`); cmd[2] != `display notification "say \"hi\"" with title "Wa-Tor"` {
+		t.Errorf("macOS script %q", cmd[2])
+	}
+	if _, ok := desktopCommand("plan9", "", ""); ok {
+		t.Error("plan9 has a desktop command")
+	}
+}
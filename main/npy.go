@@ -0,0 +1,172 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file npy.go
+ * @brief NumPy tensor export of a whole run (the -npy option).
+ * @details The run is written as one (steps, H, W) uint8 array holding the species code of
+ * every cell (0 empty, 1 fish, 2 shark), step 0 being the starting state and the last step
+ * the final one; H runs over x, the rows. A .npy file loads with numpy.load(path) and a
+ * .npz archive with numpy.load(path)["frames"]; torch.from_numpy takes either result as is.
+ * Frames are streamed to disk as they come: the .npy header, whose shape is only known at
+ * the end, is written with room to spare and rewritten on Close, and a .npz is assembled on
+ * Close from a temporary .npy next to it, compressed with deflate. A .npz also holds the
+ * run's seed, parameters and provenance as provenance.json (numpy.load(path)["provenance.json"]
+ * returns its bytes); a bare .npy has no room for them.
+ */
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+)
+
+/** Bytes reserved for the .npy preamble and header, so the shape can be rewritten in place. */
+const npyHeaderSize = 128
+
+/** Name of the array inside a .npz archive. */
+const npzArray = "frames"
+
+/**
+ * @brief Returns the .npy preamble and header for a uint8 array of the given shape.
+ * @details Format version 1.0: magic, version, little-endian header length, then a Python
+ * dict literal padded with spaces and ending in a newline so the data starts at npyHeaderSize.
+ */
+func npyHeader(steps, h, w int) []byte {
+	dict := fmt.Sprintf("{'descr': '|u1', 'fortran_order': False, 'shape': (%d, %d, %d), }", steps, h, w)
+	header := []byte("\x93NUMPY\x01\x00")
+	header = binary.LittleEndian.AppendUint16(header, uint16(npyHeaderSize-10))
+	header = append(header, dict...)
+	header = append(header, strings.Repeat(" ", npyHeaderSize-len(header)-1)...)
+	return append(header, '\n')
+}
+
+/**
+ * @struct NpyWriter
+ * @brief Streams the frames of a run into a .npy or .npz file.
+ */
+type NpyWriter struct {
+	path  string        ///< Destination
+	npz   bool          ///< Whether Close packs the .npy into a .npz archive at path
+	f     *os.File      ///< The .npy being written: the destination, or a temporary file for .npz
+	out   *bufio.Writer ///< Buffers frame data
+	size  int           ///< Side of the grid
+	meta  []byte        ///< provenance.json of a .npz
+	steps int           ///< Frames written
+	last  int           ///< Chronon of the last frame, -1 before the first
+	err   error         ///< First write error; later frames are dropped
+}
+
+/**
+ * @brief Creates (or truncates) the tensor file.
+ * @param path Destination; a name ending in .npz gives a compressed archive, anything else .npy.
+ * @param cfg The configuration of the run.
+ * @return The writer, or an error if the file could not be created.
+ */
+func NewNpyWriter(path string, cfg Config) (*NpyWriter, error) {
+	npz := strings.EqualFold(filepath.Ext(path), ".npz")
+	meta, err := provenanceJSON(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var f *os.File
+	if npz {
+		f, err = os.CreateTemp(filepath.Dir(path), ".wator-*.npy")
+	} else {
+		f, err = os.Create(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating tensor file: %w", err)
+	}
+	nw := &NpyWriter{path: path, npz: npz, f: f, out: bufio.NewWriter(f), size: cfg.GridSize, meta: meta, last: -1}
+	_, nw.err = nw.out.Write(npyHeader(0, nw.size, nw.size))
+	return nw, nil
+}
+
+/**
+ * @brief Appends a frame; usable as a chronon-start hook.
+ * @details A chronon already written is skipped, so the final frame can be recorded after
+ * the run without duplicating it. Frames of a different size from the first (after a resize)
+ * are skipped, since the array has one shape.
+ */
+func (nw *NpyWriter) Record(f *Frame) {
+	if nw.err != nil || f.Chronon() == nw.last || f.Size() != nw.size {
+		return
+	}
+	nw.last = f.Chronon()
+	_, nw.err = nw.out.Write(unsafe.Slice((*byte)(unsafe.SliceData(f.cells)), len(f.cells))) ///< Species is a byte; row-major like a C-ordered (H, W) array
+	nw.steps++
+}
+
+/**
+ * @brief Writes the final shape and, for .npz, builds the archive.
+ * @return The first write, rewrite, or archive error, if any.
+ */
+func (nw *NpyWriter) Close() error {
+	if err := nw.out.Flush(); nw.err == nil {
+		nw.err = err
+	}
+	if nw.err == nil {
+		_, nw.err = nw.f.WriteAt(npyHeader(nw.steps, nw.size, nw.size), 0)
+	}
+	if nw.npz && nw.err == nil {
+		nw.err = writeNpz(nw.path, nw.f, nw.meta)
+	}
+	if err := nw.f.Close(); nw.err == nil {
+		nw.err = err
+	}
+	if nw.npz {
+		os.Remove(nw.f.Name()) ///< The temporary .npy
+	}
+	return nw.err
+}
+
+/**
+ * @brief Writes a .npz archive holding one .npy and the provenance.
+ * @param path Destination of the archive.
+ * @param npy The complete .npy file, read from the start.
+ * @param meta Contents of provenance.json.
+ * @return Any create, compress, or close error.
+ */
+func writeNpz(path string, npy io.ReadSeeker, meta []byte) error {
+	if _, err := npy.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating tensor file: %w", err)
+	}
+	archive := zip.NewWriter(f)
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: npzArray + ".npy", Method: zip.Deflate})
+	if err == nil {
+		_, err = io.Copy(entry, npy)
+	}
+	if err == nil {
+		entry, err = archive.Create("provenance.json")
+	}
+	if err == nil {
+		_, err = entry.Write(meta)
+	}
+	if cerr := archive.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
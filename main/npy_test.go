@@ -0,0 +1,99 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file npy_test.go
+ * @brief Tests for the .npy and .npz tensor export.
+ */
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ * @brief Parses a .npy file the way numpy.load does for a C-ordered uint8 array.
+ * @return The header dict and the data.
+ */
+func parseNpy(t *testing.T, b []byte) (string, []byte) {
+	t.Helper()
+	if len(b) < 10 || !bytes.Equal(b[:8], []byte("\x93NUMPY\x01\x00")) {
+		t.Fatalf("bad .npy preamble %q", b[:min(len(b), 10)])
+	}
+	n := int(binary.LittleEndian.Uint16(b[8:10]))
+	if (10+n)%64 != 0 || b[10+n-1] != '\n' {
+		t.Fatalf("header of %d bytes is not 64-byte aligned or not newline-terminated", n)
+	}
+	return string(bytes.TrimRight(b[10:10+n], " \n")), b[10+n:]
+}
+
+func TestNpyExport(t *testing.T) {
+	for _, name := range []string{"run.npy", "run.npz"} {
+		path := filepath.Join(t.TempDir(), name)
+		sim, err := NewSimulation(testConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		nw, err := NewNpyWriter(path, sim.Config())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []byte
+		sim.OnChrononStart(func(f *Frame) {
+			nw.Record(f)
+			for _, sp := range f.cells {
+				want = append(want, byte(sp))
+			}
+		})
+		sim.Run(context.Background(), 4)
+		nw.Record(sim.Snapshot())
+		nw.Record(sim.Snapshot()) ///< Duplicates are dropped
+		for _, sp := range sim.Snapshot().cells {
+			want = append(want, byte(sp))
+		}
+		if err := nw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "run.npz" {
+			archive, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(archive.File) != 2 || archive.File[0].Name != "frames.npy" || archive.File[1].Name != "provenance.json" {
+				t.Fatalf("archive holds %d files, want frames.npy and provenance.json", len(archive.File))
+			}
+			r, _ := archive.File[0].Open()
+			raw, _ = io.ReadAll(r)
+			if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".wator-*")); len(matches) > 0 {
+				t.Errorf("temporary files left behind: %v", matches)
+			}
+		}
+		header, data := parseNpy(t, raw)
+		if wantHeader := "{'descr': '|u1', 'fortran_order': False, 'shape': (5, 20, 20), }"; header != wantHeader {
+			t.Errorf("%s: header %q, want %q", name, header, wantHeader)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s: %d data bytes differ from the %d frame bytes", name, len(data), len(want))
+		}
+	}
+}
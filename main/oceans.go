@@ -0,0 +1,293 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file oceans.go
+ * @brief The oceans subcommand: several worlds coupled by migration channels.
+ * @details Every ocean is a full simulation with the command-line parameters and its own seed
+ * (seed, seed+1, ...). Each chronon the oceans are stepped in parallel, each with its own
+ * workers, and then the channels are applied in the order given: a channel a>b moves every
+ * fish and shark of ocean a to a random empty cell of ocean b with probability p, keeping its
+ * breeding counter and energy; a-b is a channel in both directions. An entity moves at most
+ * once per chronon, and when the destination is full the rest stay home. Migration draws from
+ * its own source seeded from the run's seed, so coupled runs are reproducible whenever the
+ * oceans themselves are.
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+/**
+ * @struct Channel
+ * @brief A one-way migration route between two oceans.
+ */
+type Channel struct {
+	From, To int     ///< Ocean indices
+	P        float64 ///< Per-entity probability of moving each chronon
+}
+
+/**
+ * @brief Parses channels such as "0-1:0.01,1>2:0.005".
+ * @param spec The -channels value; empty links each ocean to the next both ways with probability 0.01.
+ * @param oceans Number of oceans.
+ * @return The one-way channels, or an error naming the bad entry.
+ */
+func parseChannels(spec string, oceans int) ([]Channel, error) {
+	if spec == "" {
+		var links []string
+		for i := 0; i+1 < oceans; i++ {
+			links = append(links, fmt.Sprintf("%d-%d:0.01", i, i+1))
+		}
+		spec = strings.Join(links, ",")
+	}
+	var channels []Channel
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		route, prob, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("channel %q: want a-b:p or a>b:p", entry)
+		}
+		p, err := strconv.ParseFloat(prob, 64)
+		if err != nil || p < 0 || p > 1 {
+			return nil, fmt.Errorf("channel %q: probability must be between 0 and 1", entry)
+		}
+		sep := "-"
+		if strings.Contains(route, ">") {
+			sep = ">"
+		}
+		from, to, _ := strings.Cut(route, sep)
+		a, errA := strconv.Atoi(strings.TrimSpace(from))
+		b, errB := strconv.Atoi(strings.TrimSpace(to))
+		switch {
+		case errA != nil || errB != nil:
+			return nil, fmt.Errorf("channel %q: want a-b:p or a>b:p", entry)
+		case a < 0 || a >= oceans || b < 0 || b >= oceans:
+			return nil, fmt.Errorf("channel %q: oceans are numbered 0 to %d", entry, oceans-1)
+		case a == b:
+			return nil, fmt.Errorf("channel %q links an ocean to itself", entry)
+		}
+		channels = append(channels, Channel{From: a, To: b, P: p})
+		if sep == "-" {
+			channels = append(channels, Channel{From: b, To: a, P: p})
+		}
+	}
+	return channels, nil
+}
+
+/**
+ * @struct Oceans
+ * @brief Coupled simulations and the channels between them.
+ */
+type Oceans struct {
+	Sims     []*Simulation
+	Channels []Channel
+	rng      *rand.Rand
+}
+
+/**
+ * @brief Creates the oceans of a run.
+ * @param cfg Validated configuration shared by every ocean; ocean i uses seed cfg.Seed+i.
+ * @param n Number of oceans.
+ * @param channels Migration routes.
+ * @return The oceans, or an error if a simulation cannot be set up.
+ */
+func NewOceans(cfg Config, n int, channels []Channel) (*Oceans, error) {
+	o := &Oceans{Channels: channels, rng: rand.New(rand.NewSource(cfg.Seed))}
+	for i := 0; i < n; i++ {
+		c := cfg
+		c.Seed = cfg.Seed + int64(i)
+		sim, err := NewSimulation(c)
+		if err != nil {
+			return nil, fmt.Errorf("ocean %d: %w", i, err)
+		}
+		o.Sims = append(o.Sims, sim)
+	}
+	return o, nil
+}
+
+/**
+ * @brief Steps every ocean in parallel, then applies the channels.
+ * @param ctx Context of the run.
+ * @return The number of entities that migrated.
+ */
+func (o *Oceans) Step(ctx context.Context) int {
+	var wg sync.WaitGroup
+	for _, sim := range o.Sims {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sim.Step(ctx)
+		}()
+	}
+	wg.Wait()
+
+	moved := make(map[Entity]bool) ///< Entities that already migrated this chronon
+	migrants := 0
+	for _, ch := range o.Channels {
+		migrants += migrate(o.rng, o.Sims[ch.From], o.Sims[ch.To], ch.P, moved)
+	}
+	return migrants
+}
+
+/**
+ * @brief Moves entities from one simulation to random empty cells of another.
+ * @details Called between chronons, never concurrently with a step of either simulation.
+ * @param rng Source of the migration draws.
+ * @param from, to The two simulations; distinct.
+ * @param p Per-entity probability of moving.
+ * @param moved Entities not to move again; updated with the migrants.
+ * @return The number of entities moved.
+ */
+func migrate(rng *rand.Rand, from, to *Simulation, p float64, moved map[Entity]bool) int {
+	if p == 0 {
+		return 0
+	}
+	from.mu.Lock()
+	defer from.mu.Unlock()
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	var free [][2]int ///< Empty cells of the destination
+	for x := 0; x < to.grid.Size; x++ {
+		for y := 0; y < to.grid.Size; y++ {
+			if to.grid.At(x, y) == nil {
+				free = append(free, [2]int{x, y})
+			}
+		}
+	}
+	n := 0
+	for x := 0; x < from.grid.Size && len(free) > 0; x++ {
+		for y := 0; y < from.grid.Size && len(free) > 0; y++ {
+			e := from.grid.At(x, y)
+			if e == nil || moved[e] || rng.Float64() >= p {
+				continue
+			}
+			k := rng.Intn(len(free))
+			cell := free[k]
+			free[k] = free[len(free)-1]
+			free = free[:len(free)-1]
+			from.grid.Set(x, y, nil)
+			to.grid.Set(cell[0], cell[1], e)
+			moved[e] = true
+			from.pop.replace(speciesOf(e), SpeciesNone)
+			to.pop.replace(SpeciesNone, speciesOf(e))
+			n++
+		}
+	}
+	for _, s := range []*Simulation{from, to} {
+		s.frame = nil ///< The cached snapshot no longer matches the grid
+		if s.fast != nil {
+			s.fast.reset()
+		}
+	}
+	return n
+}
+
+/**
+ * @brief Writes the header of the per-chronon CSV.
+ */
+func writeOceansHeader(out *csv.Writer, n int) error {
+	header := []string{"chronon"}
+	for i := 0; i < n; i++ {
+		header = append(header, fmt.Sprintf("fish_%d", i), fmt.Sprintf("sharks_%d", i))
+	}
+	return out.Write(append(header, "migrants"))
+}
+
+/**
+ * @brief Writes one row of the per-chronon CSV.
+ */
+func (o *Oceans) writeRow(out *csv.Writer, migrants int) error {
+	row := []string{strconv.Itoa(o.Sims[0].Population().Chronon)}
+	for _, sim := range o.Sims {
+		p := sim.Population()
+		row = append(row, strconv.Itoa(p.Fish), strconv.Itoa(p.Sharks))
+	}
+	return out.Write(append(row, strconv.Itoa(migrants)))
+}
+
+/**
+ * @brief Runs the oceans and prints their populations after every chronon as CSV.
+ * @param ctx Context of the run; cancellation stops it between chronons.
+ * @param o The oceans.
+ * @param chronons Chronons to run.
+ * @param w Destination of the CSV.
+ * @return Any write error.
+ */
+func (o *Oceans) Run(ctx context.Context, chronons int, w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := writeOceansHeader(out, len(o.Sims)); err != nil {
+		return err
+	}
+	if err := o.writeRow(out, 0); err != nil {
+		return err
+	}
+	for n := 0; n < chronons && ctx.Err() == nil; n++ {
+		if err := o.writeRow(out, o.Step(ctx)); err != nil {
+			return err
+		}
+		out.Flush() ///< Rows appear as the run progresses
+	}
+	out.Flush()
+	return out.Error()
+}
+
+/**
+ * @brief The oceans subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdOceans(args []string) int {
+	cf := newConfigFlags("oceans", "Runs several worlds with the given parameters, coupled by migration channels, and prints every ocean's populations after every chronon as CSV.", os.Stderr)
+	n := cf.fs.Int("oceans", 2, "`number` of oceans; ocean i uses seed+i")
+	spec := cf.fs.String("channels", "", "migration `routes` a-b:p (both ways) or a>b:p (one way) separated by commas, e.g. 0-1:0.01,1>2:0.005; by default each ocean is linked to the next both ways with p 0.01")
+	cfg, code, ok := setupCommand(cf, args)
+	if !ok {
+		return code
+	}
+	if *n < 2 || cfg.Ensemble > 1 || cfg.Pipe || cfg.Paint {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\noceans needs -oceans of at least 2, and no -ensemble, -pipe or -paint")
+		return exitConfigError
+	}
+	channels, err := parseChannels(*spec, *n)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid parameters:\n-channels: %v\n", err)
+		return exitConfigError
+	}
+	oceans, err := NewOceans(cfg, *n, channels)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitConfigError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := oceans.Run(ctx, cfg.Chronons, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if ctx.Err() != nil {
+		return exitInterrupted
+	}
+	return exitOK
+}
@@ -0,0 +1,98 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file oceans_test.go
+ * @brief Tests for coupled oceans and migration.
+ */
+package main
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseChannels(t *testing.T) {
+	channels, err := parseChannels("0-1:0.5, 2>1:0.25", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Channel{{0, 1, 0.5}, {1, 0, 0.5}, {2, 1, 0.25}}
+	if !slices.Equal(channels, want) {
+		t.Errorf("channels = %v, want %v", channels, want)
+	}
+	if channels, _ := parseChannels("", 3); len(channels) != 4 {
+		t.Errorf("default channels for 3 oceans: %v, want a chain both ways", channels)
+	}
+	for _, bad := range []string{"0-1", "0-1:2", "0-0:0.1", "0-3:0.1", "a-b:0.1"} {
+		if _, err := parseChannels(bad, 3); err == nil {
+			t.Errorf("parseChannels(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestMigrationConservesEntities(t *testing.T) {
+	cfg := testConfig()
+	cfg.Threads = 1
+	from, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := from.Population()
+	moved := make(map[Entity]bool)
+	n := migrate(rand.New(rand.NewSource(1)), from, to, 0.5, moved)
+	if n == 0 || n != len(moved) {
+		t.Fatalf("moved %d entities, %d recorded", n, len(moved))
+	}
+	for _, sim := range []*Simulation{from, to} {
+		fish, sharks := sim.Snapshot().Counts()
+		if p := sim.Population(); p.Fish != fish || p.Sharks != sharks {
+			t.Errorf("population %+v does not match the grid's %d fish and %d sharks", p, fish, sharks)
+		}
+	}
+	pf, pt := from.Population(), to.Population()
+	if pf.Fish+pf.Sharks+pt.Fish+pt.Sharks != 2*(before.Fish+before.Sharks) {
+		t.Errorf("entities not conserved: %+v and %+v from two worlds of %+v", pf, pt, before)
+	}
+	if again := migrate(rand.New(rand.NewSource(1)), to, from, 1, moved); again != pt.Fish+pt.Sharks-n {
+		t.Errorf("moved %d entities back, want only the %d natives", again, pt.Fish+pt.Sharks-n)
+	}
+}
+
+func TestOceansAreReproducible(t *testing.T) {
+	cfg := testConfig()
+	cfg.Engine = "deterministic"
+	var outputs [2]string
+	for i := range outputs {
+		o, err := NewOceans(cfg, 3, []Channel{{0, 1, 0.05}, {1, 2, 0.05}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		if err := o.Run(context.Background(), 10, &out); err != nil {
+			t.Fatal(err)
+		}
+		outputs[i] = out.String()
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("runs differ:\n%s\n%s", outputs[0], outputs[1])
+	}
+	if lines := strings.Split(strings.TrimSpace(outputs[0]), "\n"); len(lines) != 12 || lines[0] != "chronon,fish_0,sharks_0,fish_1,sharks_1,fish_2,sharks_2,migrants" {
+		t.Errorf("unexpected CSV:\n%s", outputs[0])
+	}
+}
@@ -0,0 +1,143 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file order_test.go
+ * @brief Tests for the update ordering policies.
+ */
+package main
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestEachCellVisitsEveryEntityOnce(t *testing.T) {
+	g := NewGrid(9)
+	g.Seed(4)
+	g.Initialize(30, 10, 5)
+	for _, policy := range orderNames {
+		order := g.scanOrder(policy)
+		seen := map[int]int{}
+		var sequence []int
+		for _, band := range partitionRows(g.Size, 2) {
+			g.eachCell(order, rand.New(rand.NewSource(1)), band, SpeciesNone, func(x, y int) {
+				if x < band.Start || x >= band.End {
+					t.Errorf("%s: visited (%d,%d) outside rows %d-%d", policy, x, y, band.Start, band.End-1)
+				}
+				seen[x*g.Size+y]++
+				sequence = append(sequence, x*g.Size+y)
+			})
+		}
+		if len(seen) != 40 || len(sequence) != 40 {
+			t.Errorf("%s: visited %d entities %d times, want 40 once each", policy, len(seen), len(sequence))
+		}
+		sorted := true
+		for i := 1; i < len(sequence); i++ {
+			sorted = sorted && sequence[i-1] < sequence[i]
+		}
+		if sorted != (policy == OrderRowMajor) {
+			t.Errorf("%s: visits in row-major order is %v", policy, sorted)
+		}
+		if policy == OrderCheckerboard {
+			for _, band := range partitionRows(g.Size, 2) {
+				parity := 0
+				g.eachCell(order, nil, band, SpeciesShark, func(x, y int) {
+					if g.speciesAt(x, y) != SpeciesShark {
+						t.Errorf("checkerboard: visited (%d,%d), which holds no shark", x, y)
+					}
+					if (x+y)&1 < parity {
+						t.Errorf("checkerboard: even cell (%d,%d) visited after an odd one", x, y)
+					}
+					parity = (x + y) & 1
+				})
+			}
+		}
+	}
+}
+
+func TestDeterministicMatchesSerialInEveryOrder(t *testing.T) {
+	rules := Rules{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5}
+	for _, policy := range orderNames {
+		rules.Order = policy
+		hashes := map[uint64]bool{}
+		for _, engine := range []Engine{serialEngine{}, deterministicEngine{}} {
+			for _, threads := range []int{1, 3, 7} {
+				g := NewGrid(20)
+				g.Seed(11)
+				g.Initialize(150, 30, rules.StarveEnergy)
+				for c := 0; c < 25; c++ {
+					engine.Step(context.Background(), g, rules, threads)
+				}
+				hashes[hashGrid(g)] = true
+			}
+		}
+		if len(hashes) != 1 {
+			t.Errorf("%s: serial and deterministic runs gave %d different grids", policy, len(hashes))
+		}
+	}
+}
+
+/**
+ * @brief Four fish around the only empty cell of a full grid all want it; counts who gets it.
+ * @return How often the fish north, west, east and south of the cell won, over many seeds.
+ */
+func contestWinners(t *testing.T, policy string) [4]int {
+	t.Helper()
+	var wins [4]int
+	rules := Rules{FishBreed: 100, SharkBreed: 100, StarveEnergy: 5, Order: policy}
+	for seed := int64(0); seed < 400; seed++ {
+		g := NewGrid(5)
+		g.Seed(seed)
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				if x != 2 || y != 2 {
+					g.Set(x, y, &Fish{})
+				}
+			}
+		}
+		serialEngine{}.Step(context.Background(), g, rules, 1)
+		for i, c := range [4][2]int{{1, 2}, {2, 1}, {2, 3}, {3, 2}} {
+			if g.speciesAt(c[0], c[1]) == SpeciesNone {
+				wins[i]++ ///< The winner left its cell empty
+			}
+		}
+	}
+	return wins
+}
+
+func TestRandomOrderRemovesScanBias(t *testing.T) {
+	if wins := contestWinners(t, OrderRowMajor); wins[0] != 400 {
+		t.Errorf("row-major: the northern fish should always win, wins (N, W, E, S) = %v", wins)
+	}
+	for i, n := range contestWinners(t, OrderRandom) {
+		if n < 60 || n > 140 {
+			t.Errorf("random: neighbour %d won %d of 400 contests, want about 100", i, n)
+		}
+	}
+}
+
+func BenchmarkUpdateOrder(b *testing.B) {
+	for _, policy := range orderNames {
+		for _, engine := range []Engine{sectionsEngine{}, deterministicEngine{}} {
+			b.Run(policy+"/"+engine.Name(), func(b *testing.B) {
+				g := benchGrid(b, 200, 0.5)
+				rules := benchRules
+				rules.Order = policy
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					engine.Step(context.Background(), g, rules, 1)
+				}
+			})
+		}
+	}
+}
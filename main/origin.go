@@ -0,0 +1,247 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file origin.go
+ * @brief Shifting the drawn world around the torus (the -origin and -center options).
+ * @details The world wraps, so any cell can be drawn at the top-left corner. An Origin rolls
+ * every frame before it is drawn so that a chosen world cell comes first, and can instead
+ * re-centre each frame on the centroid of the animals, so a school drifting across an edge
+ * stays in the middle of the display rather than splitting between opposite sides. Only the
+ * drawing moves: the simulation, statistics, checkpoints, replays and the inspection cursor
+ * keep world coordinates. Controls are single keys read from standard input, one command per
+ * line.
+ */
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/**
+ * @struct Origin
+ * @brief The world cell drawn at the top-left corner. Safe for concurrent use.
+ */
+type Origin struct {
+	mu     sync.Mutex
+	x, y   int  ///< World cell at the top-left corner
+	centre bool ///< Whether every frame is re-centred on the animals
+	size   int  ///< Side of the last frame, for the scroll step
+}
+
+/**
+ * @brief Creates an origin.
+ * @param x, y The world cell to draw at the top-left corner.
+ * @param centre Whether to re-centre every frame on the centroid of the animals instead.
+ */
+func NewOrigin(x, y int, centre bool) *Origin {
+	return &Origin{x: x, y: y, centre: centre}
+}
+
+/**
+ * @brief Parses an -origin value.
+ * @param s Two integers "x,y"; coordinates wrap, so negative values count back from the far edge.
+ */
+func parseOrigin(s string) (x, y int, err error) {
+	a, b, ok := strings.Cut(strings.ReplaceAll(s, " ", ""), ",")
+	if ok {
+		if x, err = strconv.Atoi(a); err == nil {
+			y, err = strconv.Atoi(b)
+		}
+	}
+	if !ok || err != nil {
+		return 0, 0, fmt.Errorf("want two integers \"x,y\", got %q", s)
+	}
+	return x, y, nil
+}
+
+/**
+ * @brief Moves the top-left corner by whole world cells.
+ * @details Shifting stops centring.
+ * @param dx Rows to move down (negative moves up).
+ * @param dy Columns to move right (negative moves left).
+ */
+func (o *Origin) Shift(dx, dy int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.x += dx
+	o.y += dy
+	o.centre = false
+}
+
+/**
+ * @brief Turns re-centring on the centroid of the animals on or off.
+ * @details Turning it off leaves the world where the last centred frame put it.
+ */
+func (o *Origin) SetCentre(centre bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.centre = centre
+}
+
+/** @brief Returns the world cell drawn at the top-left corner. */
+func (o *Origin) At() (x, y int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.x, o.y
+}
+
+/**
+ * @brief Returns the world cell drawn at a cell of the display.
+ * @param x, y The drawn cell.
+ * @param size The world side.
+ */
+func (o *Origin) ToWorld(x, y, size int) (int, int) {
+	ox, oy := o.At()
+	return wrap(x+ox, size), wrap(y+oy, size)
+}
+
+/**
+ * @brief Handles one control key.
+ * @details w/a/s/d or k/h/j/l shift the world by an eighth of its side, o toggles centring
+ * and O puts world cell (0,0) back at the top-left corner.
+ * @param key The key pressed.
+ * @return false if the key is not a control.
+ */
+func (o *Origin) HandleKey(key byte) bool {
+	o.mu.Lock()
+	step := max(o.size/8, 1)
+	o.mu.Unlock()
+	switch key {
+	case 'w', 'k':
+		o.Shift(-step, 0)
+	case 's', 'j':
+		o.Shift(step, 0)
+	case 'a', 'h':
+		o.Shift(0, -step)
+	case 'd', 'l':
+		o.Shift(0, step)
+	case 'o':
+		o.mu.Lock()
+		o.centre = !o.centre
+		o.mu.Unlock()
+	case 'O':
+		o.mu.Lock()
+		o.x, o.y, o.centre = 0, 0, false
+		o.mu.Unlock()
+	default:
+		return false
+	}
+	return true
+}
+
+/**
+ * @brief Rolls a frame so the origin's cell comes first.
+ * @details When centring, the origin first moves so the centroid of the animals lands on the
+ * middle cell; a frame without animals leaves it where it was.
+ * @param f The world frame.
+ * @return A frame of the same size, chronon and counts, or f itself when no shift is needed.
+ */
+func (o *Origin) Apply(f *Frame) *Frame {
+	o.mu.Lock()
+	o.size = f.size
+	if o.centre {
+		if c, ok := torusCentroid(f); ok {
+			o.x, o.y = c[0]-f.size/2, c[1]-f.size/2
+		}
+	}
+	ox, oy := wrap(o.x, f.size), wrap(o.y, f.size)
+	o.mu.Unlock()
+	if ox == 0 && oy == 0 {
+		return f
+	}
+	return rollFrame(f, ox, oy)
+}
+
+/**
+ * @brief Returns a copy of a frame whose cell (x, y) is the original's (x+dx, y+dy), wrapping.
+ */
+func rollFrame(f *Frame, dx, dy int) *Frame {
+	n := f.size
+	out := &Frame{chronon: f.chronon, size: n, cells: make([]Species, n*n), energy: make([]uint16, n*n),
+		fish: f.fish, sharks: f.sharks, total: f.total}
+	if f.temp != nil {
+		out.temp = &Temperature{size: n, cells: make([]float32, n*n)}
+	}
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			i, j := x*n+y, wrap(x+dx, n)*n+wrap(y+dy, n)
+			out.cells[i], out.energy[i] = f.cells[j], f.energy[j]
+			if f.young != nil && f.young[j] {
+				out.markJuvenile(i)
+			}
+			if f.tide != nil && f.tide[j] {
+				out.markTide(i)
+			}
+			if f.lineage != nil && f.lineage[j] > 0 {
+				out.markLineage(i, int(f.lineage[j]))
+			}
+			if out.temp != nil {
+				out.temp.cells[i] = f.temp.cells[j]
+			}
+		}
+	}
+	return out
+}
+
+/**
+ * @brief Returns the centroid of a frame's fish and sharks on the torus.
+ * @details Each axis is treated as a circle and the centroid is the direction of the mean of
+ * the animals' positions on it, so a group straddling an edge is centred where it is rather
+ * than halfway across the world.
+ * @return The centroid, and false if the frame holds no animals or they are spread so evenly
+ * that no direction stands out.
+ */
+func torusCentroid(f *Frame) ([2]int, bool) {
+	var sum [2][2]float64 ///< Per axis, the summed cosines and sines
+	for x := 0; x < f.size; x++ {
+		for y := 0; y < f.size; y++ {
+			if f.At(x, y) == SpeciesNone {
+				continue
+			}
+			for axis, v := range [2]int{x, y} {
+				a := 2 * math.Pi * float64(v) / float64(f.size)
+				sum[axis][0] += math.Cos(a)
+				sum[axis][1] += math.Sin(a)
+			}
+		}
+	}
+	var c [2]int
+	for axis, s := range sum {
+		if math.Hypot(s[0], s[1]) < 1e-9 {
+			return c, false
+		}
+		a := math.Atan2(s[1], s[0])
+		c[axis] = wrap(int(math.Round(a/(2*math.Pi)*float64(f.size))), f.size)
+	}
+	return c, true
+}
+
+/**
+ * @struct OriginRenderer
+ * @brief Renders each frame rolled to an origin.
+ */
+type OriginRenderer struct {
+	Renderer Renderer ///< Draws the rolled frame
+	Origin   *Origin  ///< The cell to draw first
+}
+
+/**
+ * @brief Rolls the frame to the origin and passes it on.
+ * @param f The world frame.
+ */
+func (r *OriginRenderer) Render(f *Frame) {
+	r.Renderer.Render(r.Origin.Apply(f))
+}
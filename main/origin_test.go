@@ -0,0 +1,64 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file origin_test.go
+ * @brief Tests for shifting and centring the drawn world.
+ */
+package main
+
+import "testing"
+
+func TestOriginRollsFrame(t *testing.T) {
+	f := frameFromASCII(t, "F...\n....\n....\n...S\n", 3)
+	o := NewOrigin(3, -1, false)
+	got := o.Apply(f)
+	if want := "S...\n.F..\n....\n....\n"; frameRows(got) != want {
+		t.Errorf("rolled to (3,-1):\n%swant\n%s", frameRows(got), want)
+	}
+	if fish, sharks := got.Counts(); fish != 1 || sharks != 1 || got.Energy(0, 0) != 3 || got.Chronon() != f.Chronon() {
+		t.Errorf("rolled frame has %d fish, %d sharks and energy %d", fish, sharks, got.Energy(0, 0))
+	}
+	if x, y := o.ToWorld(0, 0, 4); x != 3 || y != 3 {
+		t.Errorf("drawn (0,0) is world (%d,%d), want (3,3)", x, y)
+	}
+	if NewOrigin(4, 0, false).Apply(f) != f {
+		t.Error("an origin that wraps to (0,0) should pass the frame through")
+	}
+}
+
+func TestOriginCentresAcrossTheEdge(t *testing.T) {
+	f := frameFromASCII(t, "FF...F\nFF...F\n......\n......\n......\nFF...F\n", 3)
+	if c, ok := torusCentroid(f); !ok || c != [2]int{0, 0} {
+		t.Fatalf("centroid of a group straddling the corner is %v, want (0,0)", c)
+	}
+	got := NewOrigin(0, 0, true).Apply(f)
+	if want := "......\n......\n..FFF.\n..FFF.\n..FFF.\n......\n"; frameRows(got) != want {
+		t.Errorf("centred:\n%swant\n%s", frameRows(got), want)
+	}
+}
+
+func TestOriginKeys(t *testing.T) {
+	o := NewOrigin(0, 0, true)
+	o.Apply(frameFromASCII(t, "F.......\n........\n........\n........\n........\n........\n........\n........\n", 3))
+	o.HandleKey('d')
+	if x, y := o.At(); x != -4 || y != -3 {
+		t.Errorf("after centring on (0,0) and shifting right the origin is (%d,%d), want (-4,-3)", x, y)
+	}
+	o.HandleKey('o')
+	o.HandleKey('O')
+	if x, y := o.At(); x != 0 || y != 0 || o.centre {
+		t.Errorf("O left the origin at (%d,%d), centring %v", x, y, o.centre)
+	}
+	if o.HandleKey('z') {
+		t.Error("z is not an origin control")
+	}
+}
@@ -0,0 +1,172 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file oscillation.go
+ * @brief Detects the predator-prey cycle in a run's populations.
+ * @details The period is the lag of the highest peak of the autocorrelation after it has
+ * first turned negative, refined between chronons by fitting a parabola through the peak.
+ * The highest rather than the first peak is taken because the breeding interval drives a
+ * short ripple on top of the predator-prey cycle; multiples of the period score lower as
+ * the cycle drifts. The
+ * first tenth of the run is skipped as the transient from the random start, and chronons
+ * after a species dies out are ignored. The amplitude is half the mean peak-to-trough range
+ * over whole periods, in individuals.
+ */
+package main
+
+import (
+	"math"
+	"strconv"
+)
+
+/**
+ * @struct Cycle
+ * @brief The dominant oscillation of one population.
+ */
+type Cycle struct {
+	Period    float64 `json:"period"`    ///< Chronons per cycle (0 if no cycle was found)
+	Amplitude float64 `json:"amplitude"` ///< Half the typical peak-to-trough range
+	Strength  float64 `json:"strength"`  ///< Autocorrelation at the period: near 1 for a clean cycle
+}
+
+/**
+ * @struct Oscillation
+ * @brief The cycles of both populations.
+ */
+type Oscillation struct {
+	Fish   Cycle `json:"fish"`   ///< Prey cycle
+	Sharks Cycle `json:"sharks"` ///< Predator cycle
+}
+
+const (
+	cycleMinSamples  = 20  ///< Shortest analysed series
+	cycleMinStrength = 0.3 ///< Weaker autocorrelation peaks are taken as noise
+)
+
+/**
+ * @brief Finds the cycles of a population series.
+ * @param s The recorded populations.
+ * @return The cycles, or nil if the series is too short to analyse.
+ */
+func AnalyseOscillation(s *PopulationSeries) *Oscillation {
+	n := 0
+	for n < s.Len() && s.Fish[n] > 0 && s.Sharks[n] > 0 {
+		n++
+	}
+	skip := n / 10
+	if n-skip < cycleMinSamples {
+		return nil
+	}
+	return &Oscillation{Fish: findCycle(s.Fish[skip:n]), Sharks: findCycle(s.Sharks[skip:n])}
+}
+
+/**
+ * @brief Finds the dominant cycle of one series.
+ * @param xs The series, at least cycleMinSamples long.
+ * @return The cycle, with a zero period if the series does not oscillate.
+ */
+func findCycle(xs []float64) Cycle {
+	acf := autocorrelation(xs, len(xs)/2)
+	if acf == nil {
+		return Cycle{} ///< Constant series
+	}
+	start := 1
+	for start < len(acf) && acf[start] >= 0 {
+		start++ ///< Skip the central peak
+	}
+	lag, best := 0, cycleMinStrength
+	for k := start + 1; k+1 < len(acf); k++ {
+		if acf[k] >= best && acf[k] >= acf[k-1] && acf[k] >= acf[k+1] {
+			lag, best = k, acf[k]
+		}
+	}
+	if lag == 0 {
+		return Cycle{}
+	}
+	// Vertex of the parabola through the peak and its neighbours
+	period := float64(lag)
+	if curve := acf[lag-1] - 2*acf[lag] + acf[lag+1]; curve < 0 {
+		period += (acf[lag-1] - acf[lag+1]) / (2 * curve)
+	}
+	return Cycle{Period: period, Amplitude: cycleAmplitude(xs, int(math.Round(period))), Strength: acf[lag]}
+}
+
+/**
+ * @brief Returns the normalised autocorrelation of xs for lags 0..maxLag-1.
+ * @return The coefficients, or nil if xs is constant.
+ */
+func autocorrelation(xs []float64, maxLag int) []float64 {
+	m := meanOf(xs)
+	var c0 float64
+	for _, x := range xs {
+		c0 += (x - m) * (x - m)
+	}
+	if c0 == 0 {
+		return nil
+	}
+	acf := make([]float64, maxLag)
+	for k := range acf {
+		var c float64
+		for t := 0; t+k < len(xs); t++ {
+			c += (xs[t] - m) * (xs[t+k] - m)
+		}
+		acf[k] = c / c0
+	}
+	return acf
+}
+
+/**
+ * @brief Returns half the mean peak-to-trough range over the whole periods of xs.
+ */
+func cycleAmplitude(xs []float64, period int) float64 {
+	var sum float64
+	windows := 0
+	for start := 0; start+period <= len(xs); start += period {
+		lo, hi := xs[start], xs[start]
+		for _, x := range xs[start : start+period] {
+			lo, hi = math.Min(lo, x), math.Max(hi, x)
+		}
+		sum += (hi - lo) / 2
+		windows++
+	}
+	return sum / float64(windows)
+}
+
+/**
+ * @brief Formats the periods and amplitudes as CSV fields, empty where no cycle was found.
+ * @return fish_period, fish_amplitude, sharks_period and sharks_amplitude.
+ */
+func (o *Oscillation) record() []string {
+	fields := make([]string, 0, 4)
+	for _, c := range []Cycle{o.cycle(SpeciesFish), o.cycle(SpeciesShark)} {
+		if c.Period == 0 {
+			fields = append(fields, "", "")
+			continue
+		}
+		fields = append(fields, strconv.FormatFloat(c.Period, 'f', 1, 64), strconv.FormatFloat(c.Amplitude, 'f', 1, 64))
+	}
+	return fields
+}
+
+/**
+ * @brief Returns one species' cycle; a nil analysis has none.
+ */
+func (o *Oscillation) cycle(sp Species) Cycle {
+	switch {
+	case o == nil:
+		return Cycle{}
+	case sp == SpeciesFish:
+		return o.Fish
+	default:
+		return o.Sharks
+	}
+}
@@ -0,0 +1,77 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file oscillation_test.go
+ * @brief Tests for the population cycle analysis.
+ */
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestAnalyseOscillationFindsCycle(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	s := &PopulationSeries{}
+	for c := range 400 {
+		phase := 2 * math.Pi * float64(c) / 37.5
+		ripple := 15 * math.Sin(2*math.Pi*float64(c)/8) ///< A breeding ripple the period must not lock onto
+		s.Fish = append(s.Fish, 2000+600*math.Sin(phase)+ripple+rng.NormFloat64()*20)
+		s.Sharks = append(s.Sharks, 500+150*math.Sin(phase-1)+rng.NormFloat64()*5)
+	}
+	o := AnalyseOscillation(s)
+	if o == nil {
+		t.Fatal("no analysis for a 400-chronon series")
+	}
+	for _, tc := range []struct {
+		name      string
+		c         Cycle
+		amplitude float64
+	}{{"fish", o.Fish, 600}, {"sharks", o.Sharks, 150}} {
+		if math.Abs(tc.c.Period-37.5) > 1 {
+			t.Errorf("%s period %.2f, want 37.5", tc.name, tc.c.Period)
+		}
+		if math.Abs(tc.c.Amplitude-tc.amplitude) > 0.15*tc.amplitude {
+			t.Errorf("%s amplitude %.1f, want about %g", tc.name, tc.c.Amplitude, tc.amplitude)
+		}
+		if tc.c.Strength < 0.5 {
+			t.Errorf("%s strength %.2f, want a clear cycle", tc.name, tc.c.Strength)
+		}
+	}
+}
+
+func TestAnalyseOscillationWithoutCycle(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	s := &PopulationSeries{}
+	for range 300 {
+		s.Fish = append(s.Fish, 1000+rng.NormFloat64()*30)
+		s.Sharks = append(s.Sharks, 200)
+	}
+	o := AnalyseOscillation(s)
+	if o == nil || o.Fish.Period != 0 || o.Sharks.Period != 0 {
+		t.Errorf("got %+v, want no cycles in noise and a constant", o)
+	}
+	if got := o.record(); !slices.Equal(got, []string{"", "", "", ""}) {
+		t.Errorf("record %q, want empty fields", got)
+	}
+
+	s.Sharks[15] = 0 ///< Only the 15 chronons before extinction count
+	if o := AnalyseOscillation(s); o != nil {
+		t.Errorf("got %+v for a series too short to analyse", o)
+	}
+	if got := (*Oscillation)(nil).record(); len(got) != 4 {
+		t.Errorf("nil record %q, want four empty fields", got)
+	}
+}
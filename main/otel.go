@@ -0,0 +1,219 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file otel.go
+ * @brief OpenTelemetry spans for chronon phases, exported over OTLP/HTTP (the -otel-endpoint option).
+ * @details Each chronon becomes one trace whose root span "chronon" contains "render" (the
+ * chronon-start hooks), "step" (the engine) and "stats" (event and chronon-end hooks). The
+ * engines add their own phases beneath "step": the sections engine a span per species
+ * phase with one child per worker; the moves engine a "plan" span per worker and a "commit"
+ * span for the committer, which resolves conflicting moves as it applies them. Spans are
+ * encoded in the OTLP JSON format and posted in the background, so a slow collector never
+ * stalls the simulation; if the exporter falls behind, whole chronons are dropped and
+ * counted. Without a tracer in the context, startSpan costs one context lookup.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * @struct otlpSpan
+ * @brief A finished span in the OTLP JSON encoding.
+ */
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"` ///< 1 = internal
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+/**
+ * @struct otlpAttribute
+ * @brief An integer span attribute.
+ */
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		IntValue string `json:"intValue"` ///< OTLP JSON encodes 64-bit integers as strings
+	} `json:"value"`
+}
+
+/**
+ * @struct Tracer
+ * @brief Collects the spans of each chronon and exports them in the background.
+ */
+type Tracer struct {
+	url     string
+	client  *http.Client
+	batches chan []otlpSpan ///< Finished chronons waiting to be posted
+	done    chan struct{}   ///< Closed when the exporter goroutine exits
+	mu      sync.Mutex
+	open    map[string][]otlpSpan ///< Finished spans by trace, until the root ends
+	dropped int                   ///< Chronons dropped because the exporter fell behind
+}
+
+/**
+ * @brief Creates a tracer posting to an OTLP/HTTP collector.
+ * @param endpoint Collector base URL, e.g. http://localhost:4318; "/v1/traces" is appended
+ * unless already present.
+ * @return The tracer; call Close to flush the remaining spans.
+ */
+func NewTracer(endpoint string) *Tracer {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	t := &Tracer{url: url, client: &http.Client{Timeout: 10 * time.Second},
+		batches: make(chan []otlpSpan, 64), done: make(chan struct{}), open: map[string][]otlpSpan{}}
+	go t.export()
+	return t
+}
+
+/**
+ * @brief Posts finished chronons until the tracer is closed.
+ */
+func (t *Tracer) export() {
+	defer close(t.done)
+	for spans := range t.batches {
+		if err := t.post(spans); err != nil {
+			slog.Warn("exporting spans failed", "err", err)
+		}
+	}
+}
+
+/**
+ * @brief Sends one batch of spans to the collector.
+ */
+func (t *Tracer) post(spans []otlpSpan) error {
+	body, err := json.Marshal(map[string]any{"resourceSpans": []any{map[string]any{
+		"resource": map[string]any{"attributes": []any{
+			map[string]any{"key": "service.name", "value": map[string]string{"stringValue": "wator"}}}},
+		"scopeSpans": []any{map[string]any{"scope": map[string]string{"name": "wator"}, "spans": spans}},
+	}}})
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector answered %s", resp.Status)
+	}
+	return nil
+}
+
+/**
+ * @brief Flushes the queued chronons and stops the exporter.
+ */
+func (t *Tracer) Close() {
+	close(t.batches)
+	<-t.done
+	if t.dropped > 0 {
+		slog.Warn("spans dropped because the collector was too slow", "chronons", t.dropped)
+	}
+}
+
+/**
+ * @brief Records a finished span; a finished root hands its whole trace to the exporter.
+ */
+func (t *Tracer) finish(s otlpSpan) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := append(t.open[s.TraceID], s)
+	if s.ParentSpanID != "" {
+		t.open[s.TraceID] = spans
+		return
+	}
+	delete(t.open, s.TraceID)
+	select {
+	case t.batches <- spans:
+	default:
+		t.dropped++
+	}
+}
+
+/** Context keys of the tracer and the current span. */
+type tracerKey struct{}
+type spanKey struct{}
+
+/**
+ * @struct spanRef
+ * @brief The identity of the current span, stored in the context.
+ */
+type spanRef struct {
+	traceID, spanID string
+}
+
+/**
+ * @brief Returns a context whose spans are collected by the tracer.
+ */
+func withTracer(ctx context.Context, t *Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+/**
+ * @brief Starts a span as a child of the context's current span, or as a new trace's root.
+ * @param ctx The context; without a tracer the span is not recorded.
+ * @param name The span name.
+ * @param attrs Alternating attribute keys (string) and integer values.
+ * @return A context carrying the span, and a function that ends it.
+ */
+func startSpan(ctx context.Context, name string, attrs ...any) (context.Context, func()) {
+	t, _ := ctx.Value(tracerKey{}).(*Tracer)
+	if t == nil {
+		return ctx, func() {}
+	}
+	s := otlpSpan{SpanID: randomHex(8), Name: name, Kind: 1, Start: strconv.FormatInt(time.Now().UnixNano(), 10)}
+	if parent, ok := ctx.Value(spanKey{}).(spanRef); ok {
+		s.TraceID, s.ParentSpanID = parent.traceID, parent.spanID
+	} else {
+		s.TraceID = randomHex(16)
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		var a otlpAttribute
+		a.Key = fmt.Sprint(attrs[i])
+		a.Value.IntValue = fmt.Sprint(attrs[i+1])
+		s.Attributes = append(s.Attributes, a)
+	}
+	return context.WithValue(ctx, spanKey{}, spanRef{s.TraceID, s.SpanID}), func() {
+		s.End = strconv.FormatInt(time.Now().UnixNano(), 10)
+		t.finish(s)
+	}
+}
+
+/**
+ * @brief Returns n random bytes as lowercase hex.
+ */
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
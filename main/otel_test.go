@@ -0,0 +1,99 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file otel_test.go
+ * @brief Tests for the OTLP span export.
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestTracerExportsChrononSpans(t *testing.T) {
+	var mu sync.Mutex
+	var spans []otlpSpan
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("posted to %s, want /v1/traces", r.URL.Path)
+		}
+		var body struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []otlpSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		spans = append(spans, body.ResourceSpans[0].ScopeSpans[0].Spans...)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	for _, engine := range []string{"moves", "sections"} {
+		spans = nil
+		cfg := testConfig()
+		cfg.Engine, cfg.Threads = engine, 2
+		sim, err := NewSimulation(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sim.OnChrononStart(func(*Frame) {}) ///< Gives the chronon a render span
+		tracer := NewTracer(collector.URL)
+		sim.Run(withTracer(context.Background(), tracer), 2)
+		tracer.Close()
+
+		names := map[string]int{}
+		byID := map[string]otlpSpan{}
+		for _, s := range spans {
+			names[s.Name]++
+			byID[s.SpanID] = s
+		}
+		want := map[string]int{"chronon": 2, "render": 2, "step": 2, "stats": 2}
+		if engine == "moves" {
+			want["plan"], want["commit"] = 4, 2
+		} else {
+			want["sharks"], want["fish"], want["worker"] = 2, 2, 16 ///< Two bands per worker per phase
+		}
+		for name, n := range want {
+			if names[name] != n {
+				t.Errorf("%s: %d %q spans, want %d (all: %v)", engine, names[name], name, n, names)
+			}
+		}
+		for _, s := range spans {
+			if s.Name == "chronon" {
+				continue
+			}
+			parent, ok := byID[s.ParentSpanID]
+			if !ok || parent.TraceID != s.TraceID {
+				t.Errorf("%s: span %q has no parent in its trace", engine, s.Name)
+			}
+		}
+	}
+}
+
+func TestStartSpanWithoutTracer(t *testing.T) {
+	ctx := context.Background()
+	got, end := startSpan(ctx, "idle")
+	end()
+	if got != ctx {
+		t.Error("startSpan without a tracer should return the context unchanged")
+	}
+}
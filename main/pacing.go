@@ -0,0 +1,78 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file pacing.go
+ * @brief Wall-clock pacing of chronons (the -tick option).
+ * @details Without a tick the simulation runs flat out. With one, each chronon starts a tick
+ * after the previous one: the sleep is shortened by however long the chronon and its hooks
+ * took. A chronon that overruns its tick is followed immediately, and the schedule restarts
+ * from there rather than bursting to catch up.
+ */
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+/**
+ * @struct pacer
+ * @brief Schedules chronon starts at a fixed interval.
+ */
+type pacer struct {
+	tick time.Duration
+	next time.Time ///< When the next chronon may start; zero before the first
+	late int       ///< Chronons that overran their tick
+	now  func() time.Time
+}
+
+/**
+ * @brief Creates a pacer; a tick of 0 or less never waits.
+ */
+func newPacer(tick time.Duration) *pacer {
+	return &pacer{tick: tick, now: time.Now}
+}
+
+/**
+ * @brief Blocks until the next chronon is due.
+ * @param ctx Cancelling it ends the wait early.
+ * @return The context's error if it was cancelled while waiting.
+ */
+func (p *pacer) wait(ctx context.Context) error {
+	if p.tick <= 0 {
+		return nil
+	}
+	now := p.now()
+	if p.next.IsZero() {
+		p.next = now.Add(p.tick) ///< The first chronon starts at once
+		return nil
+	}
+	if delay := p.next.Sub(now); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		p.next = p.next.Add(p.tick)
+		return nil
+	}
+
+	p.late++
+	if p.late == 1 {
+		slog.Warn("chronon overran its tick; the simulation cannot keep up", "tick", p.tick, "behind", now.Sub(p.next))
+	}
+	p.next = now.Add(p.tick) ///< Restart the schedule instead of bursting to catch up
+	return nil
+}
@@ -0,0 +1,114 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file paint.go
+ * @brief Painting the grid while the display is paused (the -paint option).
+ * @details Painting uses the inspection cursor (I/J/K/L) and the pause of the rewind
+ * history (p). While paused on the newest frame, 1 paints fish, 2 paints sharks and 0 erases
+ * a square brush centred on the cursor; b cycles the brush between 1x1, 3x3 and 5x5; + and -
+ * grow and shrink the world by paintResizeStep cells on every side. Edits go
+ * through Simulation.Apply, so they land between chronons, and the paused frame is redrawn
+ * at once. The world has no obstacle cells, so there is nothing else to paint.
+ */
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+/**
+ * @struct CellEdit
+ * @brief Replaces the contents of one cell.
+ */
+type CellEdit struct {
+	X, Y    int     ///< The cell; wrapped onto the torus
+	Species Species ///< SpeciesFish or SpeciesShark for a newborn, SpeciesNone to erase
+}
+
+/** Brush sides cycled by b. */
+var brushSizes = []int{1, 3, 5}
+
+/**
+ * @struct Painter
+ * @brief Turns paint keys into edits of the simulation.
+ */
+type Painter struct {
+	Sim       *Simulation
+	Inspector *Inspector   ///< Supplies the cursor and shows the brush
+	History   *History     ///< Painting is allowed only while paused on the newest frame
+	brush     atomic.Int32 ///< Index into brushSizes; read by the panel while drawing
+}
+
+/**
+ * @brief Handles 1, 2, 0 (paint fish, shark, erase), b (brush size) and + and - (world size).
+ * @param key The key pressed.
+ * @return false if the key is not a paint control.
+ */
+func (p *Painter) HandleKey(key byte) bool {
+	sp := SpeciesNone
+	switch key {
+	case 'b':
+		p.brush.Store((p.brush.Load() + 1) % int32(len(brushSizes)))
+		return true
+	case '1':
+		sp = SpeciesFish
+	case '2':
+		sp = SpeciesShark
+	case '0':
+	case '+', '-':
+		if p.History.PausedAtNewest() {
+			p.resize(key)
+		}
+		return true
+	default:
+		return false
+	}
+	if !p.History.PausedAtNewest() {
+		return true ///< Only the present can be edited, and only between chronons
+	}
+	cx, cy := p.Inspector.Cursor()
+	half := brushSizes[p.brush.Load()] / 2
+	var edits []CellEdit
+	for dx := -half; dx <= half; dx++ {
+		for dy := -half; dy <= half; dy++ {
+			edits = append(edits, CellEdit{X: cx + dx, Y: cy + dy, Species: sp})
+		}
+	}
+	p.Sim.Apply(edits...)
+	p.History.Amend(p.Sim.Snapshot()) ///< The history redraws it after this key
+	return true
+}
+
+/**
+ * @brief Grows (+) or shrinks (-) the world around its centre and redraws it.
+ */
+func (p *Painter) resize(key byte) {
+	size := p.Sim.Config().GridSize + 2*paintResizeStep
+	if key == '-' {
+		size = max(p.Sim.Config().GridSize-2*paintResizeStep, 1)
+	}
+	if err := p.Sim.Resize(size, size, ResizePad); err != nil {
+		slog.Warn("resize failed", "err", err)
+		return
+	}
+	p.History.Amend(p.Sim.Snapshot())
+}
+
+/**
+ * @brief Describes the brush for the inspection panel.
+ */
+func (p *Painter) Status() string {
+	n := brushSizes[p.brush.Load()]
+	return fmt.Sprintf("Brush %dx%d: 1 fish, 2 shark, 0 erase, b size, +/- world size (paused)", n, n)
+}
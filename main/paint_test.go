@@ -0,0 +1,100 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file paint_test.go
+ * @brief Tests for grid edits and the paint brush.
+ */
+package main
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestSimulationApply(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := sim.Snapshot()
+	sim.Apply(CellEdit{X: -1, Y: 0, Species: SpeciesShark}, CellEdit{X: 0, Y: 0, Species: SpeciesNone})
+	f := sim.Snapshot()
+	if f == before {
+		t.Fatal("Apply should invalidate the cached snapshot")
+	}
+	if f.At(19, 0) != SpeciesShark || f.Energy(19, 0) != sim.Rules().StarveEnergy || f.At(0, 0) != SpeciesNone {
+		t.Errorf("edits not applied: (19,0) is %v with energy %d, (0,0) is %v", f.At(19, 0), f.Energy(19, 0), f.At(0, 0))
+	}
+}
+
+func TestApplyBetweenChronons(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			sim.Step(context.Background())
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		sim.Apply(CellEdit{X: i, Y: i, Species: SpeciesFish}) ///< Run with -race
+	}
+	wg.Wait()
+}
+
+func TestPainterNeedsPause(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := NewEntityRegistry()
+	in := NewInspector(reg, 20, io.Discard)
+	in.Renderer = &TextRenderer{W: in, Theme: themes["ascii"]}
+	h := NewHistory(2, in)
+	p := &Painter{Sim: sim, Inspector: in, History: h}
+	h.Render(sim.Snapshot())
+
+	p.HandleKey('2')
+	if sim.Snapshot() != h.Frame(0) {
+		t.Error("painting while running should do nothing")
+	}
+
+	h.HandleKey('p')
+	p.HandleKey('b') ///< 3x3 brush around the cursor at (10,10)
+	p.HandleKey('2')
+	f := h.Frame(0)
+	for x := 9; x <= 11; x++ {
+		for y := 9; y <= 11; y++ {
+			if f.At(x, y) != SpeciesShark {
+				t.Errorf("(%d,%d) is %v, want a painted shark", x, y, f.At(x, y))
+			}
+		}
+	}
+	if p.Status() != "Brush 3x3: 1 fish, 2 shark, 0 erase, b size, +/- world size (paused)" {
+		t.Errorf("unexpected status %q", p.Status())
+	}
+
+	p.HandleKey('+')
+	if f := h.Frame(0); f.Size() != 30 || f.At(14, 14) != SpeciesShark {
+		t.Errorf("after + the shown world is %dx%d with %v at the painted centre", f.Size(), f.Size(), f.At(14, 14))
+	}
+	p.HandleKey('-')
+	if f := h.Frame(0); f.Size() != 20 || f.At(10, 10) != SpeciesShark {
+		t.Errorf("after + and - the shown world is %dx%d", f.Size(), f.Size())
+	}
+}
@@ -0,0 +1,81 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file partition.go
+ * @brief Splits grid rows into contiguous sections, one per worker thread.
+ */
+package main
+
+/**
+ * @struct rowRange
+ * @brief A half-open range of rows [Start, End) owned by one worker.
+ */
+type rowRange struct {
+	Start int ///< First row of the section
+	End   int ///< One past the last row of the section
+}
+
+/**
+ * @brief Divides rows among workers without ever producing an empty range.
+ * @details The worker count is clamped to [1, rows], so a 2-row grid run with 10 threads uses
+ * 2 workers. Leftover rows are spread one each over the first sections, keeping section sizes
+ * within one row of each other instead of piling them onto the last worker.
+ * @param rows Number of grid rows.
+ * @param workers Requested number of workers.
+ * @return The row ranges in ascending order; nil if there are no rows.
+ */
+func partitionRows(rows, workers int) []rowRange {
+	if rows <= 0 {
+		return nil
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > rows {
+		workers = rows ///< More workers than rows would leave some with nothing to do
+	}
+
+	ranges := make([]rowRange, workers)
+	base, extra := rows/workers, rows%workers
+	start := 0
+	for i := range ranges {
+		size := base
+		if i < extra {
+			size++
+		}
+		ranges[i] = rowRange{Start: start, End: start + size}
+		start += size
+	}
+	return ranges
+}
+
+/**
+ * @brief Divides rows into bands that workers of one colour can process at the same time.
+ * @details An entity reads and writes the new grid at most reach rows from where it starts,
+ * so two workers cannot touch the same new-grid row if the bands they own are separated by
+ * at least 2*reach rows. The rows are cut into an even number of bands, each at least
+ * 2*reach rows tall: worker w owns bands 2w and 2w+1, and all even bands run before all odd
+ * ones. Any two even bands are then separated by an odd band, and the other way round, which
+ * also holds across the wrap-around since the count is even. A grid too small for two such
+ * bands, or a single worker, gets one band.
+ * @param rows Number of grid rows.
+ * @param workers Requested number of workers.
+ * @param reach Furthest row offset at which an entity touches the new grid.
+ * @return The bands in ascending order: one, or an even number; nil if there are no rows.
+ */
+func partitionBands(rows, workers, reach int) []rowRange {
+	bands := min(2*workers, rows/(2*max(reach, 1)))
+	if bands < 2 {
+		return partitionRows(rows, 1)
+	}
+	return partitionRows(rows, bands&^1)
+}
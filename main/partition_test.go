@@ -0,0 +1,104 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file partition_test.go
+ * @brief Tests for row partitioning and banding, including grids smaller than the thread count.
+ */
+package main
+
+import "testing"
+
+func TestPartitionRows(t *testing.T) {
+	cases := []struct {
+		rows, workers int
+		want          []rowRange
+	}{
+		{rows: 1, workers: 10, want: []rowRange{{0, 1}}},
+		{rows: 2, workers: 10, want: []rowRange{{0, 1}, {1, 2}}},
+		{rows: 2, workers: 1, want: []rowRange{{0, 2}}},
+		{rows: 10, workers: 3, want: []rowRange{{0, 4}, {4, 7}, {7, 10}}},
+		{rows: 5, workers: 0, want: []rowRange{{0, 5}}},
+		{rows: 0, workers: 4, want: nil},
+	}
+	for _, c := range cases {
+		got := partitionRows(c.rows, c.workers)
+		if len(got) != len(c.want) {
+			t.Errorf("partitionRows(%d, %d) = %v, want %v", c.rows, c.workers, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("partitionRows(%d, %d) = %v, want %v", c.rows, c.workers, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPartitionRowsCoversEveryRowOnce(t *testing.T) {
+	for rows := 1; rows <= 40; rows++ {
+		for workers := 1; workers <= 50; workers++ {
+			next := 0
+			for _, r := range partitionRows(rows, workers) {
+				if r.Start != next || r.End <= r.Start {
+					t.Fatalf("partitionRows(%d, %d): bad range %v after row %d", rows, workers, r, next)
+				}
+				next = r.End
+			}
+			if next != rows {
+				t.Fatalf("partitionRows(%d, %d) covers %d rows", rows, workers, next)
+			}
+		}
+	}
+}
+
+func TestPartitionBandsKeepConcurrentBandsApart(t *testing.T) {
+	for rows := 0; rows <= 40; rows++ {
+		for workers := 1; workers <= 12; workers++ {
+			for reach := 1; reach <= 4; reach++ {
+				bands := partitionBands(rows, workers, reach)
+				if rows == 0 {
+					if bands != nil {
+						t.Fatalf("partitionBands(0, %d, %d) = %v, want nil", workers, reach, bands)
+					}
+					continue
+				}
+				if len(bands) != 1 && (len(bands)%2 != 0 || len(bands) > 2*workers) {
+					t.Fatalf("partitionBands(%d, %d, %d): %d bands", rows, workers, reach, len(bands))
+				}
+				for _, b := range bands {
+					if len(bands) > 1 && b.End-b.Start < 2*reach {
+						t.Fatalf("partitionBands(%d, %d, %d): band %v is narrower than %d rows", rows, workers, reach, b, 2*reach)
+					}
+				}
+				if workers > 1 && rows >= 4*reach && len(bands) < 2 {
+					t.Errorf("partitionBands(%d, %d, %d) = %v, want at least two bands", rows, workers, reach, bands)
+				}
+			}
+		}
+	}
+}
+
+func TestMoveOnGridsSmallerThanThreadCount(t *testing.T) {
+	for _, size := range []int{1, 2} {
+		g := NewGrid(size)
+		g.Seed(7)
+		g.Initialize(size, 0, 4) ///< Fish only, so nothing can die
+		report := g.MoveEntitiesWithThreads(3, 3, 4, 10)
+		if n := len(report.WorkerTimes); n < 1 || n > size {
+			t.Errorf("size %d: used %d workers, want 1 to %d", size, n, size)
+		}
+		if fish, _ := g.CountEntities(); fish != size+report.FishBorn {
+			t.Errorf("size %d: %d fish after one chronon, want %d", size, fish, size+report.FishBorn)
+		}
+	}
+}
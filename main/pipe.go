@@ -0,0 +1,166 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file pipe.go
+ * @brief Line-delimited JSON protocol on stdin/stdout (the -pipe mode).
+ * @details Lets another process, such as a Jupyter notebook, drive the simulation. Each
+ * request is one JSON object per line; each gets exactly one JSON reply line with "ok"
+ * set, and "error" when ok is false.
+ *   {"cmd":"step","n":10}    advance n chronons (default 1); replies with stats
+ *   {"cmd":"stats"}          chronon, fish, sharks, mean_shark_energy and birth/death totals
+ *   {"cmd":"frame"}          stats plus "size" and "rows" (F fish, S shark, . empty)
+ *   {"cmd":"config"}         the current parameters
+ *   {"cmd":"reset","params":{"FishBreed":5,"shark-vision":2},"seed":7}
+ *                            start a new world; params take positional or flag names
+ *   {"cmd":"quit"}           reply and exit
+ * tools/wator_client.py is a reference Python client.
+ */
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+/**
+ * @struct pipeRequest
+ * @brief One protocol request.
+ */
+type pipeRequest struct {
+	Cmd    string                 `json:"cmd"`
+	N      int                    `json:"n"`
+	Params map[string]json.Number `json:"params"`
+	Seed   int64                  `json:"seed"`
+}
+
+/**
+ * @struct pipeSession
+ * @brief The simulation driven by a pipe, with the totals since its last reset.
+ */
+type pipeSession struct {
+	cf     *configFlags ///< Parsed command line, the base of every reset
+	sim    *Simulation
+	totals StepCounts
+}
+
+/**
+ * @brief Serves the protocol until the input ends, a quit request arrives, or ctx is cancelled.
+ * @param ctx Context of the session; cancellation is noticed between requests and chronons.
+ * @param cf The parsed command line; its configuration is the initial world.
+ * @param cfg The initial configuration, with the seed fixed.
+ * @param r Source of requests.
+ * @param w Destination of replies.
+ * @return An error if the initial world could not be created or a reply could not be written.
+ */
+func runPipe(ctx context.Context, cf *configFlags, cfg Config, r io.Reader, w io.Writer) error {
+	s := &pipeSession{cf: cf}
+	if err := s.reset(cfg); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w) ///< Encode writes one line per value
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for ctx.Err() == nil && scanner.Scan() {
+		var req pipeRequest
+		reply, quit := map[string]any(nil), false
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			reply = map[string]any{"error": fmt.Sprintf("malformed request: %v", err)}
+		} else {
+			reply, quit = s.handle(ctx, req)
+		}
+		_, failed := reply["error"]
+		reply["ok"] = !failed
+		if err := enc.Encode(reply); err != nil {
+			return err
+		}
+		if quit {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+/**
+ * @brief Replaces the simulation with a new one.
+ * @param cfg The validated configuration of the new world.
+ * @return An error if the simulation could not be created.
+ */
+func (s *pipeSession) reset(cfg Config) error {
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		return err
+	}
+	sim.OnStats(func(_ Population, report StepReport) { s.totals.Add(report.StepCounts) })
+	s.sim, s.totals = sim, StepCounts{}
+	return nil
+}
+
+/**
+ * @brief Handles one request.
+ * @param ctx Context of the session.
+ * @param req The request.
+ * @return The reply (without "ok"), and whether the session should end.
+ */
+func (s *pipeSession) handle(ctx context.Context, req pipeRequest) (map[string]any, bool) {
+	switch req.Cmd {
+	case "step":
+		n := max(req.N, 1)
+		for i := 0; i < n && ctx.Err() == nil; i++ {
+			s.sim.Step(ctx)
+		}
+		return s.stats(), false
+	case "stats":
+		return s.stats(), false
+	case "frame":
+		reply := s.stats()
+		doc := newFrameDocument(s.sim.Snapshot())
+		reply["size"], reply["rows"] = doc.Size, doc.Rows
+		return reply, false
+	case "config":
+		return map[string]any{"config": s.sim.Config()}, false
+	case "reset":
+		params := make(map[string]string, len(req.Params))
+		for name, v := range req.Params {
+			params[name] = v.String()
+		}
+		cfg, err := s.cf.with(params)
+		if err == nil {
+			cfg.Seed = req.Seed
+			if cfg.Seed == 0 {
+				cfg.Seed = time.Now().UnixNano()
+			}
+			err = s.reset(cfg)
+		}
+		if err != nil {
+			return map[string]any{"error": err.Error()}, false
+		}
+		return s.stats(), false
+	case "quit":
+		return map[string]any{}, true
+	}
+	return map[string]any{"error": fmt.Sprintf("unknown command %q (want step, stats, frame, config, reset or quit)", req.Cmd)}, false
+}
+
+/**
+ * @brief Returns the current populations and the totals since the last reset.
+ */
+func (s *pipeSession) stats() map[string]any {
+	f := s.sim.Snapshot()
+	fish, sharks := f.Counts()
+	return map[string]any{"chronon": f.Chronon(), "fish": fish, "sharks": sharks,
+		"mean_shark_energy": f.MeanSharkEnergy(), "totals": s.totals, "seed": s.sim.Config().Seed}
+}
@@ -0,0 +1,94 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file pipe_test.go
+ * @brief Tests for the -pipe JSON protocol.
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Runs a pipe session over the given request lines and decodes the replies.
+ */
+func pipeReplies(t *testing.T, requests ...string) []map[string]any {
+	t.Helper()
+	cf := newConfigFlags("run", "", io.Discard)
+	cfg, err := cf.parse([]string{"-seed", "5", "-engine", "moves", "10", "60", "3", "3", "4", "15", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := runPipe(context.Background(), cf, cfg, strings.NewReader(strings.Join(requests, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+	var replies []map[string]any
+	dec := json.NewDecoder(strings.NewReader(out.String()))
+	for dec.More() {
+		var r map[string]any
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("reply is not JSON: %v\n%s", err, out.String())
+		}
+		replies = append(replies, r)
+	}
+	return replies
+}
+
+func TestPipeStepAndFrame(t *testing.T) {
+	replies := pipeReplies(t, `{"cmd":"step","n":3}`, `{"cmd":"frame"}`, `{"cmd":"quit"}`, `{"cmd":"stats"}`)
+	if len(replies) != 3 {
+		t.Fatalf("got %d replies, want 3 (nothing after quit)", len(replies))
+	}
+	if replies[0]["ok"] != true || replies[0]["chronon"] != float64(3) {
+		t.Errorf("step reply %v, want ok at chronon 3", replies[0])
+	}
+	frame := replies[1]
+	rows := frame["rows"].([]any)
+	if len(rows) != 15 || frame["size"] != float64(15) {
+		t.Fatalf("frame has %d rows, size %v; want 15", len(rows), frame["size"])
+	}
+	fish := 0
+	for _, row := range rows {
+		fish += strings.Count(row.(string), "F")
+	}
+	if float64(fish) != frame["fish"] {
+		t.Errorf("rows hold %d fish, reply says %v", fish, frame["fish"])
+	}
+}
+
+func TestPipeResetAndErrors(t *testing.T) {
+	replies := pipeReplies(t,
+		`{"cmd":"step"}`,
+		`{"cmd":"reset","params":{"GridSize":12,"shark-vision":2},"seed":9}`,
+		`{"cmd":"config"}`,
+		`{"cmd":"reset","params":{"GridSize":0}}`,
+		`{"cmd":"fly"}`,
+		`not json`)
+	if r := replies[1]; r["ok"] != true || r["chronon"] != float64(0) || r["seed"] != float64(9) {
+		t.Errorf("reset reply %v, want ok at chronon 0 with seed 9", r)
+	}
+	cfg := replies[2]["config"].(map[string]any)
+	if cfg["GridSize"] != float64(12) || cfg["SharkVision"] != float64(2) || cfg["NumFish"] != float64(60) {
+		t.Errorf("config after reset %v, want GridSize 12, SharkVision 2 and the other parameters kept", cfg)
+	}
+	for i, r := range replies[3:] {
+		if r["ok"] != false || r["error"] == nil {
+			t.Errorf("reply %d = %v, want an error", i+3, r)
+		}
+	}
+}
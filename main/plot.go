@@ -0,0 +1,187 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file plot.go
+ * @brief SVG plots of ensemble statistics (the -plot option).
+ * @details Draws the mean population of every chronon with its 95% confidence band, fish in
+ * the upper panel and sharks in the lower one, one colour per series. SVG keeps the axis
+ * labels crisp and needs no font files, and any browser can open the result.
+ */
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+/**
+ * @struct PlotSeries
+ * @brief One curve of a plot: the per-chronon statistics of an ensemble.
+ */
+type PlotSeries struct {
+	Label string         ///< Legend entry (empty for a plot with a single unlabelled series)
+	Stats []EnsembleStat ///< Statistics from chronon 0
+}
+
+/** Series colours, chosen to stay distinguishable for colour-blind readers. */
+var plotColours = []string{"#0072b2", "#d55e00", "#009e73", "#cc79a7", "#e69f00", "#56b4e9", "#000000", "#f0e442"}
+
+/** Plot geometry in SVG user units. */
+const (
+	plotWidth       = 800
+	plotPanelHeight = 260
+	plotTop         = 40 ///< Room for the title
+	plotLeft        = 70 ///< Room for the y-axis labels
+	plotRight       = 20
+	plotGap         = 50 ///< Between the panels, for the x-axis labels
+)
+
+/**
+ * @brief Writes a plot to a file.
+ * @param path Destination, normally ending in .svg.
+ * @param title Title drawn above the panels.
+ * @param series The curves.
+ * @return An error if the file could not be written.
+ */
+func writePlotFile(path, title string, series []PlotSeries) error {
+	f, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	if err := writePlot(f, title, series); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/**
+ * @brief Draws the fish and shark panels of a plot as an SVG document.
+ * @param w Destination of the document.
+ * @param title Title drawn above the panels.
+ * @param series The curves.
+ * @return Any write error.
+ */
+func writePlot(w io.Writer, title string, series []PlotSeries) error {
+	out := bufio.NewWriter(w)
+	height := plotTop + 2*plotPanelHeight + 2*plotGap
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", plotWidth, height)
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(out, `<text x="%d" y="24" text-anchor="middle" font-size="15">%s</text>`+"\n", plotWidth/2, html.EscapeString(title))
+
+	chronons := 0
+	for _, s := range series {
+		chronons = max(chronons, len(s.Stats)-1)
+	}
+	panels := []struct {
+		name  string
+		value func(EnsembleStat) (mean, ci float64)
+	}{
+		{"Fish", func(st EnsembleStat) (float64, float64) { return st.FishMean, st.FishCI95 }},
+		{"Sharks", func(st EnsembleStat) (float64, float64) { return st.SharkMean, st.SharkCI95 }},
+	}
+	for i, p := range panels {
+		top := plotTop + i*(plotPanelHeight+plotGap)
+		plotPanel(out, top, p.name, chronons, series, p.value)
+	}
+
+	legendY := plotTop + 14
+	for i, s := range series {
+		if s.Label == "" {
+			continue
+		}
+		colour := plotColours[i%len(plotColours)]
+		x := plotWidth - plotRight - 150
+		fmt.Fprintf(out, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="2"/>`+"\n", x, legendY-4, x+20, legendY-4, colour)
+		fmt.Fprintf(out, `<text x="%d" y="%d">%s</text>`+"\n", x+26, legendY, html.EscapeString(s.Label))
+		legendY += 16
+	}
+	fmt.Fprintln(out, "</svg>")
+	return out.Flush()
+}
+
+/**
+ * @brief Draws one panel: axes, ticks, and every series' band and mean line.
+ * @param out Destination.
+ * @param top Y coordinate of the panel's top edge.
+ * @param name Panel title.
+ * @param chronons Last chronon on the x axis.
+ * @param series The curves.
+ * @param value Extracts the mean and confidence half-width the panel shows.
+ */
+func plotPanel(out io.Writer, top int, name string, chronons int, series []PlotSeries, value func(EnsembleStat) (float64, float64)) {
+	width := float64(plotWidth - plotLeft - plotRight)
+	height := float64(plotPanelHeight)
+	yMax := 0.0
+	for _, s := range series {
+		for _, st := range s.Stats {
+			mean, ci := value(st)
+			yMax = max(yMax, mean+ci)
+		}
+	}
+	yStep := niceStep(yMax, 5)
+	yMax = math.Max(yStep, math.Ceil(yMax/yStep)*yStep)
+	xStep := niceStep(float64(chronons), 8)
+	px := func(c float64) float64 { return plotLeft + c/math.Max(float64(chronons), 1)*width }
+	py := func(v float64) float64 { return float64(top) + height - v/yMax*height }
+
+	fmt.Fprintf(out, `<text x="%d" y="%d" font-weight="bold">%s</text>`+"\n", plotLeft, top-6, name)
+	for v := 0.0; v <= yMax+yStep/2; v += yStep {
+		fmt.Fprintf(out, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n", plotLeft, py(v), plotWidth-plotRight, py(v))
+		fmt.Fprintf(out, `<text x="%d" y="%.1f" text-anchor="end">%g</text>`+"\n", plotLeft-6, py(v)+4, v)
+	}
+	for c := 0.0; c <= float64(chronons); c += xStep {
+		fmt.Fprintf(out, `<text x="%.1f" y="%.1f" text-anchor="middle">%g</text>`+"\n", px(c), float64(top)+height+16, c)
+	}
+	fmt.Fprintf(out, `<text x="%d" y="%.1f" text-anchor="end">chronon</text>`+"\n", plotWidth-plotRight, float64(top)+height+32)
+	fmt.Fprintf(out, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="#444"/>`+"\n", plotLeft, top, width, height)
+
+	for i, s := range series {
+		colour := plotColours[i%len(plotColours)]
+		var upper, lower, mid []string
+		for _, st := range s.Stats {
+			mean, ci := value(st)
+			x := px(float64(st.Chronon))
+			upper = append(upper, fmt.Sprintf("%.1f,%.1f", x, py(mean+ci)))
+			lower = append(lower, fmt.Sprintf("%.1f,%.1f", x, py(math.Max(mean-ci, 0))))
+			mid = append(mid, fmt.Sprintf("%.1f,%.1f", x, py(mean)))
+		}
+		for l, r := 0, len(lower)-1; l < r; l, r = l+1, r-1 {
+			lower[l], lower[r] = lower[r], lower[l] ///< The band's outline runs back along the lower edge
+		}
+		fmt.Fprintf(out, `<polygon points="%s" fill="%s" fill-opacity="0.2" stroke="none"/>`+"\n", strings.Join(append(upper, lower...), " "), colour)
+		fmt.Fprintf(out, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`+"\n", strings.Join(mid, " "), colour)
+	}
+}
+
+/**
+ * @brief Returns a round tick spacing (1, 2 or 5 times a power of ten) giving at most ticks steps.
+ * @param span Length of the axis.
+ * @param ticks Maximum number of steps.
+ */
+func niceStep(span float64, ticks int) float64 {
+	if span <= 0 {
+		return 1
+	}
+	raw := span / float64(ticks)
+	pow := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if m*pow >= raw {
+			return math.Max(m*pow, 1) ///< Chronons and populations are whole numbers
+		}
+	}
+	return 10 * pow
+}
@@ -0,0 +1,99 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file plugin.go
+ * @brief WebAssembly species plugins (the -plugin option).
+ * @details A plugin is a WASI (wasip1) module in the plugin directory, <dir>/<name>.wasm,
+ * that decides where the fish or sharks of a run move. Any language that compiles to
+ * wasm32-wasi works; plugins/hunter is an example in Go. The ABI:
+ *
+ *     exports  memory
+ *              wator_input() -> i32   address of a 48-byte input buffer
+ *              wator_decide() -> i32  0 north, 1 south, 2 west, 3 east, 4 stay,
+ *                                     -1 built-in movement
+ *              _initialize()          optional; called once per instance (reactor modules)
+ *
+ *     input    bytes 0-24   the 5x5 cells around the entity, row by row from (x-2, y-2)
+ *                           to (x+2, y+2), so the entity is byte 12: 0 empty, 1 fish, 2 shark
+ *              byte  25     the entity's species (1 fish, 2 shark)
+ *              bytes 28-31  breeding counter, little-endian i32
+ *              bytes 32-35  energy (0 for fish)
+ *              bytes 36-39  chronon
+ *              bytes 40-43  a random u32 from the worker's seeded source
+ *              bytes 26-27 and 44-47 are zero
+ *
+ * The host fills the buffer and calls wator_decide once per entity per chronon. Each worker
+ * uses its own instance of the module, taken from a pool, so a plugin needs no locking but
+ * must not expect to see every entity. Plugins get no files, environment or clock. The
+ * runtime lives in plugin_wazero.go; the browser build has no plugin support.
+ */
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+/**
+ * @brief Parses a -plugin value such as "shark=hunter,fish=shy".
+ * @return Plugin names by species, or an error for a malformed entry.
+ */
+func parsePluginSpec(spec string) (map[Species]string, error) {
+	names := make(map[Species]string)
+	for _, entry := range strings.Split(spec, ",") {
+		species, name, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		sp := map[string]Species{"fish": SpeciesFish, "shark": SpeciesShark, "sharks": SpeciesShark}[species]
+		switch {
+		case !ok || name == "":
+			return nil, fmt.Errorf("expected <species>=<name>, got %q", entry)
+		case sp == SpeciesNone:
+			return nil, fmt.Errorf("species must be fish or shark, got %q", species)
+		case strings.ContainsAny(name, `/\`):
+			return nil, fmt.Errorf("plugin name %q must not contain a path; plugins are read from the plugin directory", name)
+		case names[sp] != "":
+			return nil, fmt.Errorf("%s is given two plugins", species)
+		}
+		names[sp] = name
+	}
+	return names, nil
+}
+
+/**
+ * @brief Adds the plugins named by a -plugin value to a behaviour.
+ * @param b The behaviour to extend, or nil to start a new one.
+ * @param dir The plugin directory.
+ * @param spec The -plugin value.
+ * @return The behaviour, or an error if a plugin is missing, invalid, or clashes with a script.
+ */
+func loadPlugins(b *Behaviour, dir, spec string) (*Behaviour, error) {
+	names, err := parsePluginSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		b = &Behaviour{}
+	}
+	for _, sp := range []Species{SpeciesFish, SpeciesShark} {
+		if names[sp] == "" {
+			continue
+		}
+		p, err := loadPlugin(filepath.Join(dir, names[sp]+".wasm"), names[sp])
+		if err != nil {
+			return nil, err
+		}
+		if err := b.set(sp, p, "plugin "+names[sp]); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,27 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build js
+
+/**
+ * @file plugin_js.go
+ * @brief Stand-in for the browser build, which does not run species plugins.
+ */
+package main
+
+import "errors"
+
+/**
+ * @brief Reports that plugins are unavailable in the browser build.
+ */
+func loadPlugin(path, name string) (decider, error) {
+	return nil, errors.New("species plugins are not supported in the browser build")
+}
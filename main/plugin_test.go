@@ -0,0 +1,140 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file plugin_test.go
+ * @brief Tests for WebAssembly species plugins, using modules assembled by hand.
+ */
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Assembles a plugin whose wator_input returns 1024 and whose wator_decide runs body.
+ * @param exports Names for the two functions (normally wator_input and wator_decide).
+ * @param body Instructions of wator_decide, without the trailing end.
+ */
+func pluginModule(exports [2]string, body ...byte) []byte {
+	section := func(id byte, content ...byte) []byte { return append([]byte{id, byte(len(content))}, content...) }
+	name := func(s string) []byte { return append([]byte{byte(len(s))}, s...) }
+
+	input := []byte{0x00, 0x41, 0x80, 0x08, 0x0b} ///< No locals; i32.const 1024; end
+	decide := append(append([]byte{0x00}, body...), 0x0b)
+	exportSec := []byte{3}
+	exportSec = append(append(exportSec, name("memory")...), 0x02, 0x00)
+	exportSec = append(append(exportSec, name(exports[0])...), 0x00, 0x00)
+	exportSec = append(append(exportSec, name(exports[1])...), 0x00, 0x01)
+	codeSec := append([]byte{2, byte(len(input))}, input...)
+	codeSec = append(append(codeSec, byte(len(decide))), decide...)
+
+	mod := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	mod = append(mod, section(1, 1, 0x60, 0, 1, 0x7f)...) ///< One type: () -> i32
+	mod = append(mod, section(3, 2, 0, 0)...)             ///< Two functions of that type
+	mod = append(mod, section(5, 1, 0x00, 1)...)          ///< One page of memory
+	mod = append(mod, section(7, exportSec...)...)
+	return append(mod, section(10, codeSec...)...)
+}
+
+/**
+ * @brief Writes a plugin into a fresh plugin directory and returns the directory.
+ */
+func writePlugin(t *testing.T, name string, mod []byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name+".wasm"), mod, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+var pluginExports = [2]string{"wator_input", "wator_decide"}
+
+func TestPluginReadsInput(t *testing.T) {
+	///< Returns the entity's species (byte 25) plus 2: 4, stay, for a shark
+	dir := writePlugin(t, "rest", pluginModule(pluginExports, 0x41, 0x00, 0x2d, 0x00, 0x99, 0x08, 0x41, 0x02, 0x6a))
+	for _, engine := range engineNames() {
+		cfg := testConfig()
+		cfg.Plugins, cfg.PluginDir, cfg.Engine, cfg.StarveEnergy, cfg.NumFish = "shark=rest", dir, engine, 10, 0
+		sim, err := NewSimulation(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := sim.Checkpoint().Entities
+		sim.Step(context.Background())
+		if err := sim.Rules().Behaviour.Err(); err != nil {
+			t.Fatal(err)
+		}
+		after := sim.Checkpoint().Entities
+		for i := range before {
+			if before[i].X != after[i].X || before[i].Y != after[i].Y {
+				t.Errorf("%s: shark at %d,%d moved to %d,%d", engine, before[i].X, before[i].Y, after[i].X, after[i].Y)
+				break
+			}
+		}
+	}
+}
+
+func TestPluginConcurrentWorkers(t *testing.T) {
+	dir := writePlugin(t, "builtin", pluginModule(pluginExports, 0x41, 0x7f)) ///< i32.const -1
+	cfg := testConfig()
+	cfg.Plugins, cfg.PluginDir, cfg.Engine, cfg.Threads = "fish=builtin,shark=builtin", dir, "moves", 4
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Run(context.Background(), 5) ///< Run with -race: each worker needs its own instance
+	if err := sim.Rules().Behaviour.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, spec string
+		mod        []byte
+		want       string
+	}{
+		{"missing", "shark=other", pluginModule(pluginExports, 0x41, 0x04), "no such file"},
+		{"exports", "shark=p", pluginModule([2]string{"input", "decide"}, 0x41, 0x04), "must export"},
+		{"garbage", "shark=p", []byte("not wasm"), "plugin p"},
+		{"action", "shark=p", pluginModule(pluginExports, 0x41, 0x09), "returned 9"},
+		{"trap", "fish=p", pluginModule(pluginExports, 0x00), "unreachable"},
+	} {
+		cfg := testConfig()
+		cfg.Plugins, cfg.PluginDir = tc.spec, writePlugin(t, "p", tc.mod)
+		sim, err := NewSimulation(cfg)
+		if err == nil {
+			sim.Run(context.Background(), 2)
+			err = sim.Rules().Behaviour.Err()
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got error %v, want one mentioning %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestParsePluginSpec(t *testing.T) {
+	names, err := parsePluginSpec("fish=shy, shark=hunter")
+	if err != nil || names[SpeciesFish] != "shy" || names[SpeciesShark] != "hunter" {
+		t.Errorf("got %v, %v", names, err)
+	}
+	for _, bad := range []string{"hunter", "crab=x", "shark=", "shark=../x", "shark=a,shark=b"} {
+		if _, err := parsePluginSpec(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build !js
+
+/**
+ * @file plugin_wazero.go
+ * @brief Runs species plugins with the wazero WebAssembly runtime.
+ * @details Left out of the browser build, which would otherwise carry a second WebAssembly
+ * runtime inside the first.
+ */
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const pluginInputSize = 48 ///< Bytes the host writes before each call
+
+/**
+ * @struct wasmPlugin
+ * @brief A compiled plugin module and its idle instances.
+ */
+type wasmPlugin struct {
+	name     string
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	idle     chan *pluginInstance ///< Instances not in use; extra ones are closed on return
+}
+
+/**
+ * @struct pluginInstance
+ * @brief One instantiation of a plugin, used by one worker at a time.
+ */
+type pluginInstance struct {
+	mod    api.Module
+	decide api.Function
+	input  uint32 ///< Address of the input buffer in the instance's memory
+	buf    [pluginInputSize]byte
+}
+
+var (
+	pluginsMu sync.Mutex
+	plugins   = map[string]*wasmPlugin{} ///< Compiled plugins by path, shared by all simulations
+)
+
+/**
+ * @brief Compiles a plugin, or returns the one already compiled from the same file.
+ * @details The module is instantiated once to check its exports; that instance becomes
+ * the first idle one.
+ */
+func loadPlugin(path, name string) (decider, error) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if p, ok := plugins[path]; ok {
+		return p, nil
+	}
+
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", name, err)
+	}
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+	compiled, err := rt.CompileModule(ctx, bin)
+	if err != nil {
+		rt.Close(ctx)
+		return nil, fmt.Errorf("plugin %s: %w", name, err)
+	}
+	p := &wasmPlugin{name: name, runtime: rt, compiled: compiled, idle: make(chan *pluginInstance, 256)}
+	inst, err := p.instantiate()
+	if err != nil {
+		rt.Close(ctx)
+		return nil, err
+	}
+	p.idle <- inst
+	plugins[path] = p
+	return p, nil
+}
+
+/**
+ * @brief Creates a new instance of the plugin and finds its input buffer.
+ */
+func (p *wasmPlugin) instantiate() (*pluginInstance, error) {
+	ctx := context.Background()
+	mod, err := p.runtime.InstantiateModule(ctx, p.compiled,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+	inst := &pluginInstance{mod: mod, decide: mod.ExportedFunction("wator_decide")}
+	input := mod.ExportedFunction("wator_input")
+	if inst.decide == nil || input == nil || mod.Memory() == nil {
+		mod.Close(ctx)
+		return nil, fmt.Errorf("plugin %s: must export memory, wator_input and wator_decide", p.name)
+	}
+	for _, fn := range []api.Function{input, inst.decide} {
+		if def := fn.Definition(); len(def.ParamTypes()) != 0 || !slices.Equal(def.ResultTypes(), []api.ValueType{api.ValueTypeI32}) {
+			mod.Close(ctx)
+			return nil, fmt.Errorf("plugin %s: %s must take no parameters and return one i32", p.name, def.ExportNames()[0])
+		}
+	}
+	res, err := input.Call(ctx)
+	if err != nil {
+		mod.Close(ctx)
+		return nil, fmt.Errorf("plugin %s: wator_input: %w", p.name, err)
+	}
+	inst.input = api.DecodeU32(res[0])
+	if _, ok := mod.Memory().Read(inst.input, pluginInputSize); !ok {
+		mod.Close(ctx)
+		return nil, fmt.Errorf("plugin %s: wator_input returned %d, outside its memory", p.name, inst.input)
+	}
+	return inst, nil
+}
+
+/**
+ * @brief Fills the input buffer of an idle instance and calls wator_decide.
+ * @details An instance that traps is closed rather than reused.
+ */
+func (p *wasmPlugin) decide(g *Grid, rng *rand.Rand, e Entity, x, y int) (int, bool, error) {
+	var inst *pluginInstance
+	select {
+	case inst = <-p.idle:
+	default:
+		var err error
+		if inst, err = p.instantiate(); err != nil {
+			return 0, false, err
+		}
+	}
+
+	clear(inst.buf[:])
+	for dx := -2; dx <= 2; dx++ {
+		for dy := -2; dy <= 2; dy++ {
+			inst.buf[(dx+2)*5+dy+2] = byte(speciesOf(g.At(wrap(x+dx, g.Size), wrap(y+dy, g.Size))))
+		}
+	}
+	inst.buf[25] = byte(speciesOf(e))
+	switch e := e.(type) {
+	case *Fish:
+		binary.LittleEndian.PutUint32(inst.buf[28:], uint32(e.BreedCounter))
+	case *Shark:
+		binary.LittleEndian.PutUint32(inst.buf[28:], uint32(e.BreedCounter))
+		binary.LittleEndian.PutUint32(inst.buf[32:], uint32(e.Energy))
+	}
+	binary.LittleEndian.PutUint32(inst.buf[36:], uint32(g.Chronon))
+	binary.LittleEndian.PutUint32(inst.buf[40:], rng.Uint32())
+	inst.mod.Memory().Write(inst.input, inst.buf[:])
+
+	res, err := inst.decide.Call(context.Background())
+	if err != nil {
+		inst.mod.Close(context.Background())
+		return 0, false, fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+	p.release(inst)
+
+	switch action := api.DecodeI32(res[0]); {
+	case action == -1:
+		return 0, false, nil
+	case action == 4:
+		return -1, true, nil
+	case action >= 0 && action < 4:
+		return int(action), true, nil
+	default:
+		return 0, false, fmt.Errorf("plugin %s: wator_decide returned %d; want 0-4 or -1", p.name, action)
+	}
+}
+
+/**
+ * @brief Returns an instance to the idle pool, closing it if the pool is full.
+ */
+func (p *wasmPlugin) release(inst *pluginInstance) {
+	select {
+	case p.idle <- inst:
+	default:
+		inst.mod.Close(context.Background())
+	}
+}
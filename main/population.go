@@ -0,0 +1,71 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file population.go
+ * @brief Running population counts kept without reading the grid.
+ * @details The workers already tally births and deaths per section and the engine merges the
+ * tallies into the StepReport, so the populations after a chronon follow from those before
+ * it: fish gain FishBorn and lose FishEaten, FishCrowded and FishPoisoned, sharks gain SharksBorn and lose
+ * SharksStarved. The grid is counted once, when the simulation is created, and cell edits
+ * adjust the counts directly. Stats hooks receive these counts, so consumers that only need
+ * populations cost O(1) per chronon instead of a frame copy and a pass over every cell.
+ */
+package main
+
+/**
+ * @struct Population
+ * @brief The populations after a chronon.
+ */
+type Population struct {
+	Chronon int ///< Chronons completed
+	Fish    int ///< Number of fish
+	Sharks  int ///< Number of sharks
+}
+
+/**
+ * @brief Advances the counts by one chronon's tally.
+ * @param c The births and deaths of the chronon.
+ */
+func (p *Population) apply(c StepCounts) {
+	p.Chronon++
+	p.Fish += c.FishBorn - c.FishEaten - c.FishCrowded - c.FishPoisoned
+	p.Sharks += c.SharksBorn - c.SharksStarved
+}
+
+/**
+ * @brief Adjusts the counts for a cell changing from one species to another.
+ */
+func (p *Population) replace(from, to Species) {
+	switch from {
+	case SpeciesFish:
+		p.Fish--
+	case SpeciesShark:
+		p.Sharks--
+	}
+	switch to {
+	case SpeciesFish:
+		p.Fish++
+	case SpeciesShark:
+		p.Sharks++
+	}
+}
+
+/**
+ * @brief Returns the populations of the current chronon.
+ * @details Maintained from the workers' tallies, so it never copies or scans the grid. Safe to
+ * call from any goroutine.
+ */
+func (s *Simulation) Population() Population {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pop
+}
@@ -0,0 +1,73 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file profiling.go
+ * @brief Optional pprof endpoint and runtime execution tracing.
+ * @details Lets users inspect goroutine scheduling and contention of the worker threads
+ * without editing the code: pprof is served over HTTP and traces are written to a file
+ * that can be opened with "go tool trace".
+ */
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // Registers the /debug/pprof handlers on http.DefaultServeMux
+	"os"
+	"runtime/trace"
+)
+
+/**
+ * @brief Starts the requested profiling facilities.
+ * @param pprofAddr Listen address for net/http/pprof (e.g. ":6060"); empty disables it.
+ * @param traceFile Path of the runtime trace to capture; empty disables tracing.
+ * @return A stop function that finishes the trace and must be called before exit,
+ * or an error if either facility could not be started.
+ */
+func startProfiling(pprofAddr, traceFile string) (func(), error) {
+	stop := func() {}
+
+	if pprofAddr != "" {
+		ln, err := net.Listen("tcp", pprofAddr) ///< Bind eagerly so a bad address fails the run
+		if err != nil {
+			return stop, fmt.Errorf("pprof listener: %w", err)
+		}
+		slog.Info("pprof listening", "addr", ln.Addr().String())
+		go func() {
+			if err := http.Serve(ln, nil); err != nil {
+				slog.Warn("pprof server stopped", "err", err)
+			}
+		}()
+	}
+
+	if traceFile != "" {
+		f, err := os.Create(traceFile)
+		if err != nil {
+			return stop, fmt.Errorf("trace file: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return stop, fmt.Errorf("starting trace: %w", err)
+		}
+		stop = func() {
+			trace.Stop()
+			if err := f.Close(); err != nil {
+				slog.Warn("closing trace file", "err", err)
+			}
+			slog.Info("trace written", "file", traceFile)
+		}
+	}
+
+	return stop, nil
+}
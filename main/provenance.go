@@ -0,0 +1,112 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file provenance.go
+ * @brief Where and how a run was produced, recorded in every file it writes.
+ * @details The parameters and seed say what was simulated; the provenance adds the build
+ * (VCS commit, uncommitted changes, Go version), the host and the start time, so a CSV or
+ * checkpoint found months later can be traced to the code that wrote it. JSON documents
+ * carry it as a "provenance" object, CSV and fingerprint files as "#" comment lines before
+ * the data (pandas: read_csv(path, comment="#")), Parquet files as key-value metadata, and
+ * .npz archives as a provenance.json member.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+/**
+ * @struct Provenance
+ * @brief The build and machine a run came from.
+ */
+type Provenance struct {
+	Commit    string    `json:"commit"`     ///< VCS revision of the build, "unknown" for builds without VCS information (e.g. go run)
+	Modified  bool      `json:"modified"`   ///< Whether the build had uncommitted changes
+	GoVersion string    `json:"go_version"` ///< Go release that compiled the binary
+	Host      string    `json:"host"`       ///< Host name of the machine
+	Started   time.Time `json:"started"`    ///< When the process started, in UTC
+}
+
+/** The provenance of this process, gathered on first use. */
+var runProvenance = sync.OnceValue(func() Provenance {
+	p := Provenance{Commit: "unknown", GoVersion: runtime.Version(), Host: "unknown", Started: time.Now().UTC().Truncate(time.Second)}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				p.Commit = s.Value
+			case "vcs.modified":
+				p.Modified = s.Value == "true"
+			}
+		}
+	}
+	if host, err := os.Hostname(); err == nil {
+		p.Host = host
+	}
+	return p
+})
+
+/**
+ * @brief Returns the provenance of this process as a pointer, for omitempty JSON fields.
+ */
+func currentProvenance() *Provenance {
+	p := runProvenance()
+	return &p
+}
+
+/**
+ * @brief Returns the commit, marked "(modified)" for a build with uncommitted changes.
+ */
+func (p Provenance) commit() string {
+	if p.Modified {
+		return p.Commit + " (modified)"
+	}
+	return p.Commit
+}
+
+/**
+ * @brief Writes the provenance and parameters of a run as "# name: value" lines.
+ * @details Used as the header of CSV and fingerprint files; the parameters are one line of JSON.
+ * @param w Destination writer.
+ * @param cfg The configuration of the run.
+ * @return Any write or encoding error.
+ */
+func writeProvenanceComments(w io.Writer, cfg Config) error {
+	params, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	p := runProvenance()
+	_, err = fmt.Fprintf(w, "# seed: %d\n# commit: %s\n# go_version: %s\n# host: %s\n# started: %s\n# parameters: %s\n",
+		cfg.Seed, p.commit(), p.GoVersion, p.Host, p.Started.Format(time.RFC3339), params)
+	return err
+}
+
+/**
+ * @brief Returns the seed, parameters and provenance of a run as one JSON object.
+ * @details Used where a file format has room for a single blob: Parquet metadata and .npz members.
+ */
+func provenanceJSON(cfg Config) ([]byte, error) {
+	return json.Marshal(struct {
+		Seed       int64      `json:"seed"`
+		Parameters Config     `json:"parameters"`
+		Provenance Provenance `json:"provenance"`
+	}{cfg.Seed, cfg, runProvenance()})
+}
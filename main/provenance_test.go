@@ -0,0 +1,89 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file provenance_test.go
+ * @brief Tests for the provenance recorded in output files.
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestProvenanceComments(t *testing.T) {
+	cfg := testConfig()
+	path := filepath.Join(t.TempDir(), "stats.csv")
+	cw, err := NewCSVWriter(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	values := map[string]string{}
+	for _, line := range lines[:len(lines)-1] {
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "# "), ": ")
+		if !strings.HasPrefix(line, "# ") || !ok {
+			t.Fatalf("comment line %q is not \"# name: value\"", line)
+		}
+		values[name] = value
+	}
+	if lines[len(lines)-1] != strings.Join(csvHeader, ",") {
+		t.Errorf("last line %q, want the CSV header", lines[len(lines)-1])
+	}
+	if values["seed"] != "11" || values["go_version"] != runtime.Version() || values["commit"] == "" || values["host"] == "" || values["started"] == "" {
+		t.Errorf("comments %v", values)
+	}
+	var params Config
+	if err := json.Unmarshal([]byte(values["parameters"]), &params); err != nil || params != cfg {
+		t.Errorf("parameters %s do not round-trip to the configuration (err %v)", values["parameters"], err)
+	}
+}
+
+func TestProvenanceInParquetAndCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frames.parquet")
+	exportRun(t, path, 1)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, _ := f.Stat()
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, ok := file.Lookup("wator.provenance")
+	if want, _ := provenanceJSON(testConfig()); !ok || meta != string(want) {
+		t.Errorf("parquet metadata %q, want %q", meta, want)
+	}
+
+	sim, _ := NewSimulation(testConfig())
+	var buf bytes.Buffer
+	if err := sim.Checkpoint().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := ReadCheckpoint(&buf)
+	if err != nil || cp.Provenance == nil || *cp.Provenance != runProvenance() {
+		t.Errorf("checkpoint provenance %+v, err %v", cp.Provenance, err)
+	}
+}
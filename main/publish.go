@@ -0,0 +1,300 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file publish.go
+ * @brief Streams per-chronon statistics and major events to NATS or MQTT (the -publish option).
+ * @details Both brokers are spoken to directly over TCP with the few protocol messages a
+ * fire-and-forget publisher needs (NATS CONNECT/PUB/PONG, MQTT 3.1.1 CONNECT/PUBLISH at QoS 0),
+ * so no client library is required. Messages are JSON:
+ *   <prefix>.stats   every chronon: chronon, populations, births and deaths
+ *   <prefix>.events  {"type":"extinction","species":...} when a species dies out, and
+ *                    {"type":"mass_starvation",...} when at least a quarter of the sharks
+ *                    starve in one chronon
+ * MQTT topics use '/' instead of '.' as the separator.
+ */
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+/** Fraction of the sharks that must starve in one chronon to count as mass starvation. */
+const massStarvationShare = 0.25
+
+/**
+ * @brief Sends messages to a broker.
+ */
+type Publisher interface {
+	Publish(topic string, payload []byte) error
+	Close() error
+}
+
+/**
+ * @brief Connects to the broker named by a URL.
+ * @param rawURL "nats://host:port/prefix" or "mqtt://host:port/prefix"; the path is the topic
+ * prefix (default "wator").
+ * @return The publisher, the topic separator of the protocol, the prefix, or an error.
+ */
+func dialPublisher(rawURL string) (Publisher, string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("publish URL: %w", err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix == "" {
+		prefix = "wator"
+	}
+	switch u.Scheme {
+	case "nats":
+		p, err := dialNATS(hostWithPort(u.Host, "4222"))
+		return p, ".", strings.ReplaceAll(prefix, "/", "."), err
+	case "mqtt":
+		p, err := dialMQTT(hostWithPort(u.Host, "1883"))
+		return p, "/", prefix, err
+	}
+	return nil, "", "", fmt.Errorf("publish URL: unknown scheme %q (want nats or mqtt)", u.Scheme)
+}
+
+/**
+ * @brief Adds the default port to a host that has none.
+ */
+func hostWithPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+/**
+ * @struct natsPublisher
+ * @brief A publish-only NATS connection.
+ */
+type natsPublisher struct {
+	mu   sync.Mutex ///< Serialises writes from Publish and the PING responder
+	conn net.Conn
+}
+
+/**
+ * @brief Connects to a NATS server and starts answering its keep-alive pings.
+ */
+func dialNATS(addr string) (*natsPublisher, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to NATS: %w", err)
+	}
+	r := bufio.NewReader(conn)
+	if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to NATS: expected INFO, got %q (%v)", line, err)
+	}
+	p := &natsPublisher{conn: conn}
+	if _, err := fmt.Fprint(conn, "CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"wator\"}\r\n"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to NATS: %w", err)
+	}
+	go func() {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return ///< Connection closed
+			}
+			switch {
+			case strings.HasPrefix(line, "PING"):
+				p.mu.Lock()
+				fmt.Fprint(p.conn, "PONG\r\n")
+				p.mu.Unlock()
+			case strings.HasPrefix(line, "-ERR"):
+				slog.Warn("NATS error", "msg", strings.TrimSpace(line))
+			}
+		}
+	}()
+	return p, nil
+}
+
+/** @brief Publishes a message on a subject. */
+func (p *natsPublisher) Publish(subject string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := fmt.Fprintf(p.conn, "PUB %s %d\r\n%s\r\n", subject, len(payload), payload); err != nil {
+		return fmt.Errorf("publishing to NATS: %w", err)
+	}
+	return nil
+}
+
+/** @brief Closes the connection. */
+func (p *natsPublisher) Close() error {
+	return p.conn.Close()
+}
+
+/**
+ * @struct mqttPublisher
+ * @brief A publish-only MQTT 3.1.1 connection at QoS 0.
+ */
+type mqttPublisher struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+/**
+ * @brief Connects to an MQTT broker and waits for its acknowledgement.
+ */
+func dialMQTT(addr string) (*mqttPublisher, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to MQTT: %w", err)
+	}
+	var body []byte
+	body = appendMQTTString(body, "MQTT")
+	body = append(body, 4, 0x02, 0, 0) ///< Protocol level 3.1.1, clean session, no keep-alive
+	body = appendMQTTString(body, fmt.Sprintf("wator-%d", time.Now().UnixNano()))
+	if _, err := conn.Write(mqttPacket(0x10, body)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to MQTT: %w", err)
+	}
+
+	ack := make([]byte, 4)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(conn, ack); err != nil || ack[0] != 0x20 || ack[3] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to MQTT: connection refused (CONNACK % x, %v)", ack, err)
+	}
+	return &mqttPublisher{conn: conn}, nil
+}
+
+/** @brief Publishes a message on a topic at QoS 0. */
+func (p *mqttPublisher) Publish(topic string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.conn.Write(mqttPacket(0x30, append(appendMQTTString(nil, topic), payload...))); err != nil {
+		return fmt.Errorf("publishing to MQTT: %w", err)
+	}
+	return nil
+}
+
+/** @brief Disconnects cleanly and closes the connection. */
+func (p *mqttPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.conn.Write([]byte{0xE0, 0})
+	return errors.Join(err, p.conn.Close())
+}
+
+/**
+ * @brief Appends a length-prefixed UTF-8 string.
+ */
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+/**
+ * @brief Frames a packet with its type byte and variable-length remaining length.
+ */
+func mqttPacket(kind byte, body []byte) []byte {
+	packet := []byte{kind}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+/**
+ * @struct StatsPublisher
+ * @brief Turns chronon reports into stats and event messages.
+ */
+type StatsPublisher struct {
+	pub        Publisher
+	sep        string ///< Topic separator
+	prefix     string ///< Topic prefix
+	prevFish   int    ///< Populations before the latest chronon
+	prevSharks int
+	extinct    map[string]bool ///< Species already reported extinct
+}
+
+/**
+ * @brief Connects to the broker and remembers the starting populations.
+ * @param rawURL The -publish URL.
+ * @param initial The frame the run starts from.
+ * @return The publisher, or an error if the broker could not be reached.
+ */
+func NewStatsPublisher(rawURL string, initial *Frame) (*StatsPublisher, error) {
+	pub, sep, prefix, err := dialPublisher(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	fish, sharks := initial.Counts()
+	return &StatsPublisher{pub: pub, sep: sep, prefix: prefix, prevFish: fish, prevSharks: sharks, extinct: map[string]bool{}}, nil
+}
+
+/**
+ * @brief Publishes the stats of a chronon and any major events; matches ChrononEndHook.
+ * @details Publishing errors are logged rather than returned, so a broker outage never stops
+ * the simulation.
+ * @param f The frame after the chronon.
+ * @param report What happened during the chronon.
+ */
+func (sp *StatsPublisher) Record(f *Frame, report StepReport) {
+	fish, sharks := f.Counts()
+	sp.send("stats", map[string]any{"chronon": f.Chronon(), "fish": fish, "sharks": sharks,
+		"fish_born": report.FishBorn, "sharks_born": report.SharksBorn, "fish_eaten": report.FishEaten,
+		"sharks_starved": report.SharksStarved, "fish_crowded": report.FishCrowded, "fish_poisoned": report.FishPoisoned})
+
+	for _, s := range []struct {
+		species    string
+		prev, left int
+	}{{"fish", sp.prevFish, fish}, {"sharks", sp.prevSharks, sharks}} {
+		if s.left == 0 && s.prev > 0 && !sp.extinct[s.species] {
+			sp.extinct[s.species] = true
+			sp.send("events", map[string]any{"type": "extinction", "species": s.species, "chronon": f.Chronon()})
+		}
+	}
+	if sp.prevSharks > 0 && float64(report.SharksStarved) >= massStarvationShare*float64(sp.prevSharks) {
+		sp.send("events", map[string]any{"type": "mass_starvation", "chronon": f.Chronon(),
+			"starved": report.SharksStarved, "sharks_before": sp.prevSharks})
+	}
+	sp.prevFish, sp.prevSharks = fish, sharks
+}
+
+/**
+ * @brief Encodes and publishes one message, logging failures.
+ */
+func (sp *StatsPublisher) send(kind string, msg map[string]any) {
+	payload, _ := json.Marshal(msg) ///< Maps of plain values always encode
+	if err := sp.pub.Publish(sp.prefix+sp.sep+kind, payload); err != nil {
+		slog.Warn("publish failed", "err", err)
+	}
+}
+
+/** @brief Closes the broker connection. */
+func (sp *StatsPublisher) Close() error {
+	return sp.pub.Close()
+}
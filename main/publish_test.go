@@ -0,0 +1,141 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file publish_test.go
+ * @brief Tests for the NATS/MQTT stats publisher.
+ */
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+/**
+ * @struct recordingPublisher
+ * @brief Publisher that keeps messages in memory.
+ */
+type recordingPublisher struct {
+	topics   []string
+	payloads []string
+}
+
+func (r *recordingPublisher) Publish(topic string, payload []byte) error {
+	r.topics = append(r.topics, topic)
+	r.payloads = append(r.payloads, string(payload))
+	return nil
+}
+
+func (r *recordingPublisher) Close() error { return nil }
+
+func TestStatsPublisherEvents(t *testing.T) {
+	rec := &recordingPublisher{}
+	sp := &StatsPublisher{pub: rec, sep: ".", prefix: "sim", prevFish: 1, prevSharks: 4, extinct: map[string]bool{}}
+
+	f := frameFromASCII(t, "S.\n..\n", 3) ///< The only fish was eaten; three of four sharks starved
+	sp.Record(f, StepReport{StepCounts: StepCounts{FishEaten: 1, SharksStarved: 3}})
+	sp.Record(f, StepReport{}) ///< Extinction is reported once
+
+	want := []string{"sim.stats", "sim.events", "sim.events", "sim.stats"}
+	if strings.Join(rec.topics, " ") != strings.Join(want, " ") {
+		t.Fatalf("topics %v, want %v", rec.topics, want)
+	}
+	if !strings.Contains(rec.payloads[1], `"type":"extinction"`) || !strings.Contains(rec.payloads[1], `"species":"fish"`) {
+		t.Errorf("extinction event %s", rec.payloads[1])
+	}
+	if !strings.Contains(rec.payloads[2], `"type":"mass_starvation"`) || !strings.Contains(rec.payloads[2], `"starved":3`) {
+		t.Errorf("mass starvation event %s", rec.payloads[2])
+	}
+}
+
+func TestNATSPublish(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "INFO {}\r\n")
+		r := bufio.NewReader(conn)
+		var lines []string
+		for len(lines) < 3 {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			lines = append(lines, line)
+		}
+		got <- strings.Join(lines, "")
+	}()
+
+	p, _, prefix, err := dialPublisher("nats://" + ln.Addr().String() + "/lab/wator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != "lab.wator" {
+		t.Errorf("prefix %q, want lab.wator", prefix)
+	}
+	p.Publish("lab.wator.stats", []byte(`{"fish":1}`))
+	wire := <-got
+	p.Close()
+	if !strings.HasPrefix(wire, "CONNECT {") || !strings.HasSuffix(wire, "PUB lab.wator.stats 10\r\n{\"fish\":1}\r\n") {
+		t.Errorf("NATS wire data %q", wire)
+	}
+}
+
+func TestMQTTPublish(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		header := make([]byte, 2)
+		io.ReadFull(conn, header)
+		io.ReadFull(conn, make([]byte, header[1])) ///< CONNECT body (short enough for a 1-byte length)
+		conn.Write([]byte{0x20, 2, 0, 0})
+		publish, _ := io.ReadAll(conn)
+		got <- publish
+	}()
+
+	p, sep, _, err := dialPublisher("mqtt://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Publish("wator"+sep+"stats", []byte("{}"))
+	p.Close()
+	want := "\x30\x0f\x00\x0bwator/stats{}\xe0\x00"
+	if publish := <-got; string(publish) != want {
+		t.Errorf("MQTT wire data % x, want % x", publish, want)
+	}
+}
+
+func TestMQTTRemainingLength(t *testing.T) {
+	if got := mqttPacket(0x30, make([]byte, 321))[:3]; string(got) != "\x30\xc1\x02" {
+		t.Errorf("header for 321 bytes % x, want 30 c1 02", got)
+	}
+}
@@ -0,0 +1,164 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file registry.go
+ * @brief Identities, ages and recent movements of individual entities.
+ * @details The engines move entity pointers between grids without caring who is who, so
+ * identities are handed out here instead: between chronons the registry scans the grid and
+ * gives every entity it has not seen before the next ID and the current chronon as its birth.
+ * Scanning every chronon means a newborn is registered in the chronon it appears, so ages
+ * are exact for everything born during the run (entities present at the start, or restored
+ * from a checkpoint, count from the chronon of the first scan). The registry also keeps the
+ * last few positions of every living entity. It costs a pass over the grid per chronon, so
+ * it only runs when something needs it, such as -inspect.
+ */
+package main
+
+import "sync"
+
+/** Positions kept per entity. */
+const trailLength = 8
+
+/**
+ * @struct EntityInfo
+ * @brief A copy of what the registry knows about one entity.
+ */
+type EntityInfo struct {
+	ID           uint64
+	Species      Species
+	Born         int
+	Age          int ///< Chronons since Born
+	BreedCounter int
+	Energy       int      ///< Sharks only
+	Trail        [][2]int ///< Recent positions, oldest first; the last is the current cell
+}
+
+/**
+ * @struct EntityRegistry
+ * @brief Tracks every entity of a grid by identity. Safe for concurrent use.
+ */
+type EntityRegistry struct {
+	mu       sync.Mutex
+	nextID   uint64
+	chronon  int  ///< Chronon of the last Observe
+	observed bool ///< Whether Observe has run at all
+	size     int
+	byCell   []uint64               ///< ID of the entity in each cell (row-major), 0 if empty
+	records  map[uint64]*EntityInfo ///< Living entities by ID
+}
+
+/**
+ * @brief Creates an empty registry.
+ */
+func NewEntityRegistry() *EntityRegistry {
+	return &EntityRegistry{records: map[uint64]*EntityInfo{}}
+}
+
+/**
+ * @brief Returns the simulation's registry, creating it and its observer on first use.
+ * @details The registry observes the grid in a chronon-start hook registered on the first
+ * call, so every consumer (the inspector, the frame exporter) sees the same identities;
+ * two registries would hand out clashing IDs. Hooks registered later run after it.
+ */
+func (s *Simulation) Registry() *EntityRegistry {
+	s.registryOnce.Do(func() {
+		s.registry = NewEntityRegistry()
+		s.OnChrononStart(func(*Frame) { s.registry.Observe(s.grid) })
+	})
+	return s.registry
+}
+
+/**
+ * @brief Registers new entities, updates everyone's state and forgets the dead.
+ * @details Must not run concurrently with a step of the grid; attach it with
+ * Simulation.OnChrononStart so that it runs between chronons.
+ * @param g The grid.
+ */
+func (r *EntityRegistry) Observe(g *Grid) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.observed && r.chronon == g.Chronon {
+		return ///< Already recorded; trails hold one position per chronon
+	}
+	r.chronon, r.size, r.observed = g.Chronon, g.Size, true
+	if len(r.byCell) != g.Size*g.Size {
+		r.byCell = make([]uint64, g.Size*g.Size)
+	}
+	seen := make(map[uint64]bool, len(r.records))
+	for x := 0; x < g.Size; x++ {
+		for y := 0; y < g.Size; y++ {
+			var id *uint64
+			var born *int
+			info := EntityInfo{}
+			switch e := g.At(x, y).(type) {
+			case *Fish:
+				id, born = &e.ID, &e.Born
+				info = EntityInfo{Species: SpeciesFish, BreedCounter: e.BreedCounter}
+			case *Shark:
+				id, born = &e.ID, &e.Born
+				info = EntityInfo{Species: SpeciesShark, BreedCounter: e.BreedCounter, Energy: e.Energy}
+			default:
+				r.byCell[x*g.Size+y] = 0
+				continue
+			}
+			if *id == 0 {
+				r.nextID++
+				*id, *born = r.nextID, g.Chronon
+			}
+			rec := r.records[*id]
+			if rec == nil {
+				rec = &EntityInfo{ID: *id, Born: *born}
+				r.records[*id] = rec
+			}
+			rec.Species, rec.BreedCounter, rec.Energy = info.Species, info.BreedCounter, info.Energy
+			rec.Age = g.Chronon - rec.Born
+			rec.Trail = append(rec.Trail, [2]int{x, y})
+			if len(rec.Trail) > trailLength {
+				rec.Trail = rec.Trail[len(rec.Trail)-trailLength:]
+			}
+			r.byCell[x*g.Size+y] = *id
+			seen[*id] = true
+		}
+	}
+	for id := range r.records {
+		if !seen[id] {
+			delete(r.records, id)
+		}
+	}
+}
+
+/**
+ * @brief Returns the entity with the given ID, if it is still alive.
+ */
+func (r *EntityRegistry) Lookup(id uint64) (EntityInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.records[id]
+	if !ok {
+		return EntityInfo{}, false
+	}
+	info := *rec
+	info.Trail = append([][2]int(nil), rec.Trail...)
+	return info, true
+}
+
+/**
+ * @brief Returns the ID of the entity in a cell as of the last Observe, or 0 if it is empty.
+ */
+func (r *EntityRegistry) At(x, y int) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size == 0 {
+		return 0
+	}
+	return r.byCell[wrap(x, r.size)*r.size+wrap(y, r.size)]
+}
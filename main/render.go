@@ -0,0 +1,364 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file render.go
+ * @brief Pluggable terminal renderers and themes.
+ * @details A Renderer draws frames as they start; TextRenderer prints one glyph per cell using
+ * a Theme. Themes range from plain ASCII (for logs and CI) through the classic ANSI colours to
+ * 24-bit colour that shades sharks by energy and emoji. The text themes draw each species in its
+ * Style (see styles.go), so -style changes their symbols and colours. The text renderers keep a
+ * glyphCache of every distinct cell appearance and build each frame into a reused buffer, so
+ * after the first frame drawing one allocates nothing and costs a single write.
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+ * @brief Draws frames; attached to a simulation with Simulation.OnChrononStart(r.Render).
+ */
+type Renderer interface {
+	Render(f *Frame)
+}
+
+/**
+ * @struct Theme
+ * @brief Maps cell contents to terminal glyphs.
+ */
+type Theme struct {
+	Name     string                                                   ///< Name used by the -theme flag
+	Glyph    func(st Style, sp Species, energy, maxEnergy int) string ///< Glyph for one cell in the species' style; energy is 0 except for sharks
+	Juvenile string                                                   ///< Glyph for a juvenile fish; empty draws it like an adult
+	Tide     string                                                   ///< Glyph for empty water under a red tide; empty draws it like clear water
+	Heat     func(t float32) string                                   ///< Glyph for empty water at temperature t, with an overlay; nil ignores the overlay
+	Lineage  func(st Style, tag int) string                           ///< Glyph for an animal of lineage tag; nil draws it like an untagged one
+	Width    int                                                      ///< Terminal columns each glyph occupies; 0 means 1
+	Styles   Styles                                                   ///< Per-species symbols and colours; the zero value uses defaultStyles
+}
+
+/**
+ * @brief Returns the style a species is drawn in.
+ */
+func (t Theme) style(sp Species) Style {
+	if t.Styles[sp].Symbol == "" {
+		return defaultStyles[sp]
+	}
+	return t.Styles[sp]
+}
+
+/**
+ * @brief Returns the glyph of a frame's cell, drawing tagged animals with the Lineage glyph,
+ * juvenile fish with the Juvenile glyph,
+ * empty water under a red tide with the Tide glyph and other water with the Heat glyph when the
+ * frame carries a temperature overlay.
+ */
+func (t Theme) cell(f *Frame, x, y, maxEnergy int) string {
+	st := t.style(f.At(x, y))
+	if tag := f.Lineage(x, y); tag > 0 && t.Lineage != nil {
+		return t.Lineage(st, tag)
+	}
+	if t.Juvenile != "" && f.Juvenile(x, y) {
+		return t.Juvenile
+	}
+	if f.At(x, y) == SpeciesNone {
+		if t.Tide != "" && f.Tide(x, y) {
+			return t.Tide
+		}
+		if v, ok := f.Temperature(x, y); ok && t.Heat != nil {
+			return t.Heat(v)
+		}
+	}
+	return t.Glyph(st, f.At(x, y), f.Energy(x, y), maxEnergy)
+}
+
+/** Registered themes by name. */
+var themes = map[string]Theme{
+	"ascii": {Name: "ascii", Juvenile: "f", Tide: "~", Lineage: asciiLineage, Glyph: func(st Style, _ Species, _, _ int) string {
+		return st.Symbol ///< Colours are ignored: ascii output has no escapes
+	}},
+	"ansi":      {Name: "ansi", Juvenile: "\033[32mf\033[0m", Tide: "\033[35m~\033[0m", Heat: ansiHeat, Lineage: ansiLineage, Glyph: ansiGlyph},
+	"truecolor": {Name: "truecolor", Juvenile: "\033[38;2;150;240;170mf\033[0m", Tide: "\033[38;2;170;70;40m~\033[0m", Heat: truecolorHeat, Lineage: truecolorLineage, Glyph: truecolorGlyph},
+	"emoji": {Name: "emoji", Width: 2, Juvenile: "🐠", Tide: "🟫", Glyph: func(_ Style, sp Species, _, _ int) string {
+		return [...]string{"🌊", "🐟", "🦈"}[sp]
+	}},
+}
+
+/**
+ * @brief Renders a cell in the terminal's basic colours: green fish and red sharks unless the
+ * style gives a colour.
+ * @param st The species' style.
+ * @param sp The cell's species.
+ * @return The coloured glyph.
+ */
+func ansiGlyph(st Style, sp Species, _, _ int) string {
+	fg := [...]string{"", "32", "31"}[sp]
+	if st.FG != "" {
+		fg = colourSGR(st.FG, false)
+	}
+	return sgrWrap(st.Symbol, fg, st.bgSGR(true))
+}
+
+/**
+ * @brief Renders a cell in 24-bit colour, shading sharks from dark (hungry) to bright (fed).
+ * @param st The species' style; its foreground replaces the theme's colour and is shaded likewise.
+ * @param sp The cell's species.
+ * @param energy The shark's energy.
+ * @param maxEnergy The energy of a freshly fed shark; values below 1 disable shading.
+ * @return The coloured glyph.
+ */
+func truecolorGlyph(st Style, sp Species, energy, maxEnergy int) string {
+	c := [...][3]int{{40, 70, 140}, {60, 220, 90}, {255, 51, 51}}[sp]
+	if rgb, ok := colourRGB(st.FG); ok {
+		c = rgb
+	}
+	if sp == SpeciesShark && maxEnergy > 0 {
+		level := 70 + 185*min(energy, maxEnergy)/maxEnergy
+		c = [3]int{c[0] * level / 255, c[1] * level / 255, c[2] * level / 255}
+	}
+	return sgrWrap(st.Symbol, fmt.Sprintf("38;2;%d;%d;%d", c[0], c[1], c[2]), st.bgSGR(false))
+}
+
+/**
+ * @brief Renders a tagged animal as its lineage number.
+ */
+func asciiLineage(_ Style, tag int) string {
+	return strconv.Itoa(tag)
+}
+
+/**
+ * @brief Renders a tagged animal's symbol in one of the terminal's basic colours, by lineage.
+ */
+func ansiLineage(st Style, tag int) string {
+	colours := [...]int{33, 35, 36, 34, 37}
+	return sgrWrap(st.Symbol, strconv.Itoa(colours[(tag-1)%len(colours)]), st.bgSGR(true))
+}
+
+/**
+ * @brief Renders a tagged animal's symbol in its lineage's colour.
+ */
+func truecolorLineage(st Style, tag int) string {
+	c := lineageColour(tag)
+	return sgrWrap(st.Symbol, fmt.Sprintf("38;2;%d;%d;%d", c[0], c[1], c[2]), st.bgSGR(false))
+}
+
+/**
+ * @brief Renders empty water in blue when cold and cyan when warm.
+ */
+func ansiHeat(t float32) string {
+	if t < 0.5 {
+		return "\033[34m.\033[0m"
+	}
+	return "\033[36m.\033[0m"
+}
+
+/**
+ * @brief Renders empty water in 24-bit colour, shaded from cold to warm.
+ */
+func truecolorHeat(t float32) string {
+	c := mixRGB(coldRGB, warmRGB, float64(t))
+	return fmt.Sprintf("\033[38;2;%d;%d;%dm.\033[0m", c[0], c[1], c[2])
+}
+
+/**
+ * @brief Looks up a theme by name.
+ * @param name The theme name.
+ * @return The theme, or an error listing the valid names.
+ */
+func themeByName(name string) (Theme, error) {
+	if t, ok := themes[name]; ok {
+		return t, nil
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q (want %s)", name, strings.Join(themeNames(), ", "))
+}
+
+/**
+ * @brief Returns the registered theme names in sorted order.
+ */
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/**
+ * @struct glyphKey
+ * @brief A cell appearance that is not known until a frame shows it.
+ */
+type glyphKey struct {
+	kind byte    ///< 'l' lineage, 'h' heat, 'e' shark energy outside 0..maxEnergy
+	sp   Species ///< Species of a tagged animal
+	n    int     ///< Lineage tag or energy
+	t    float32 ///< Temperature
+}
+
+/**
+ * @struct glyphCache
+ * @brief The glyphs of one theme, each computed once.
+ * @details Gives exactly what Theme.cell gives for the same cell: the common glyphs are built
+ * up front and the others the first time a frame needs them, so rendering calls the theme's
+ * functions, with their string building and formatting, once per distinct glyph rather than
+ * once per cell.
+ */
+type glyphCache struct {
+	theme     Theme
+	maxEnergy int
+	plain     [numSpecies]string  ///< Glyph of each species at energy 0
+	sharks    []string            ///< Shark glyph by energy, 0 to maxEnergy
+	other     map[glyphKey]string ///< Filled on first use
+}
+
+/**
+ * @brief Builds the cache of a theme; a theme without Glyph means "ansi".
+ */
+func newGlyphCache(theme Theme, maxEnergy int) *glyphCache {
+	if theme.Glyph == nil {
+		theme = themes["ansi"]
+	}
+	c := &glyphCache{theme: theme, maxEnergy: maxEnergy, other: map[glyphKey]string{}}
+	for sp := range Species(numSpecies) {
+		c.plain[sp] = theme.Glyph(theme.style(sp), sp, 0, maxEnergy)
+	}
+	for e := 0; e <= maxEnergy; e++ {
+		c.sharks = append(c.sharks, theme.Glyph(theme.style(SpeciesShark), SpeciesShark, e, maxEnergy))
+	}
+	return c
+}
+
+/**
+ * @brief Reports whether the cache was built for this theme and energy.
+ */
+func (c *glyphCache) matches(theme Theme, maxEnergy int) bool {
+	if theme.Glyph == nil {
+		theme = themes["ansi"]
+	}
+	return c != nil && c.theme.Name == theme.Name && c.theme.Styles == theme.Styles && c.maxEnergy == maxEnergy
+}
+
+/**
+ * @brief Returns the glyph of a frame's cell, as Theme.cell does.
+ */
+func (c *glyphCache) cell(f *Frame, x, y int) string {
+	t, sp := c.theme, f.At(x, y)
+	if tag := f.Lineage(x, y); tag > 0 && t.Lineage != nil {
+		return c.memo(glyphKey{kind: 'l', sp: sp, n: tag}, func() string { return t.Lineage(t.style(sp), tag) })
+	}
+	if t.Juvenile != "" && f.Juvenile(x, y) {
+		return t.Juvenile
+	}
+	if sp == SpeciesNone {
+		if t.Tide != "" && f.Tide(x, y) {
+			return t.Tide
+		}
+		if v, ok := f.Temperature(x, y); ok && t.Heat != nil {
+			return c.memo(glyphKey{kind: 'h', t: v}, func() string { return t.Heat(v) })
+		}
+	}
+	if sp != SpeciesShark {
+		return c.plain[sp]
+	}
+	e := f.Energy(x, y)
+	if e >= 0 && e <= c.maxEnergy {
+		return c.sharks[e]
+	}
+	return c.memo(glyphKey{kind: 'e', n: e}, func() string { return t.Glyph(t.style(sp), sp, e, c.maxEnergy) })
+}
+
+/**
+ * @brief Returns the cached glyph of a key, computing it on first use.
+ */
+func (c *glyphCache) memo(k glyphKey, glyph func() string) string {
+	g, ok := c.other[k]
+	if !ok {
+		g = glyph()
+		c.other[k] = g
+	}
+	return g
+}
+
+/**
+ * @struct TextRenderer
+ * @brief Prints frames as bordered text grids, one themed glyph per cell.
+ */
+type TextRenderer struct {
+	W         io.Writer   ///< Destination, usually os.Stdout
+	Theme     Theme       ///< Glyphs to use; the zero value means "ansi"
+	MaxEnergy int         ///< Energy of a freshly fed shark, used for shading
+	glyphs    *glyphCache ///< Rebuilt if Theme or MaxEnergy change
+	buf       []byte      ///< The last frame's text, reused for the next
+}
+
+/**
+ * @brief Prints a step header followed by the frame, in one write.
+ * @details Matches ChrononStartHook so it can be attached with Simulation.OnChrononStart.
+ * @param f The frame to print.
+ */
+func (r *TextRenderer) Render(f *Frame) {
+	if !r.glyphs.matches(r.Theme, r.MaxEnergy) {
+		r.glyphs = newGlyphCache(r.Theme, r.MaxEnergy)
+	}
+	r.buf = appendStepHeader(r.buf[:0], f.Chronon())
+	r.buf = appendFrame(r.buf, f, r.glyphs)
+	r.W.Write(r.buf)
+}
+
+/**
+ * @brief Appends "Step <chronon>:" and a newline.
+ */
+func appendStepHeader(b []byte, chronon int) []byte {
+	b = append(b, "Step "...)
+	b = strconv.AppendInt(b, int64(chronon), 10)
+	return append(b, ":\n"...)
+}
+
+/** Top and bottom border of a drawn frame. */
+const frameBorder = "+---------------------+\n"
+
+/**
+ * @brief Appends a frame with borders, drawn with the cached glyphs.
+ * @param b The buffer to append to.
+ * @param f The frame.
+ * @param glyphs The theme's glyphs.
+ * @return The extended buffer.
+ */
+func appendFrame(b []byte, f *Frame, glyphs *glyphCache) []byte {
+	b = append(b, frameBorder...)
+	for x := 0; x < f.Size(); x++ {
+		b = append(b, "| "...)
+		for y := 0; y < f.Size(); y++ {
+			b = append(b, glyphs.cell(f, x, y)...)
+			b = append(b, ' ')
+		}
+		b = append(b, "|\n"...)
+	}
+	return append(b, frameBorder...)
+}
+
+/**
+ * @brief Writes a frame with borders using the given theme.
+ * @details For one-off frames; renderers keep their cache and buffer between frames.
+ * @param w Destination writer.
+ * @param f The frame.
+ * @param theme The glyphs to use.
+ * @param maxEnergy Energy of a freshly fed shark, used for shading.
+ */
+func writeFrame(w io.Writer, f *Frame, theme Theme, maxEnergy int) {
+	w.Write(appendFrame(nil, f, newGlyphCache(theme, maxEnergy)))
+}
@@ -0,0 +1,259 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file render_test.go
+ * @brief Tests for the terminal renderers and themes.
+ */
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Builds a frame from an ASCII map, with every shark at the given energy.
+ */
+func frameFromASCII(t *testing.T, m string, sharkEnergy int) *Frame {
+	t.Helper()
+	g, err := ReadASCII(strings.NewReader(m), sharkEnergy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newFrame(g)
+}
+
+func TestASCIITheme(t *testing.T) {
+	var out strings.Builder
+	r := &TextRenderer{W: &out, Theme: themes["ascii"]}
+	r.Render(frameFromASCII(t, "FS\n.F\n", 3))
+	want := "Step 0:\n+---------------------+\n| F S |\n| . F |\n+---------------------+\n"
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+	if strings.Contains(out.String(), "\033") {
+		t.Error("the ascii theme must not emit escape sequences")
+	}
+}
+
+func TestDefaultThemeIsANSI(t *testing.T) {
+	f := frameFromASCII(t, "FS\n.F\n", 3)
+	var a, b strings.Builder
+	(&TextRenderer{W: &a}).Render(f)
+	f.Fprint(&b)
+	if a.String() != "Step 0:\n"+b.String() {
+		t.Error("a renderer without a theme should match Frame.Fprint")
+	}
+}
+
+func TestTruecolorShadesSharksByEnergy(t *testing.T) {
+	hungry := truecolorGlyph(defaultStyles[SpeciesShark], SpeciesShark, 1, 10)
+	fed := truecolorGlyph(defaultStyles[SpeciesShark], SpeciesShark, 10, 10)
+	if hungry == fed {
+		t.Error("hungry and fed sharks should be shaded differently")
+	}
+	if !strings.Contains(fed, "38;2;255;") {
+		t.Errorf("a fed shark should be full red, got %q", fed)
+	}
+}
+
+func TestThemeByName(t *testing.T) {
+	for _, name := range themeNames() {
+		if _, err := themeByName(name); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := themeByName("neon"); err == nil {
+		t.Error("expected an error for an unknown theme")
+	}
+}
+
+func TestHalfBlockRendererNoColor(t *testing.T) {
+	var out strings.Builder
+	r := &HalfBlockRenderer{W: &out, Width: 80, NoColor: true}
+	r.Render(frameFromASCII(t, "FF..\nF...\n....\nSSSS\n", 3))
+	want := "Step 0 (1 cells per pixel):\n█▀  \n▄▄▄▄\n"
+	if out.String() != want {
+		t.Errorf("got\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestBrailleRendererNoColor(t *testing.T) {
+	var out strings.Builder
+	r := &BrailleRenderer{W: &out, Width: 80, NoColor: true}
+	r.Render(frameFromASCII(t, "FF..\nF...\n....\nSSSS\n", 3))
+	want := "Step 0 (1 cells per dot):\n⣋⣀\n"
+	if out.String() != want {
+		t.Errorf("got\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestBlockRenderersFitWidth(t *testing.T) {
+	f := frameFromASCII(t, strings.Repeat(strings.Repeat("F", 40)+"\n", 40), 3)
+
+	var half strings.Builder
+	(&HalfBlockRenderer{W: &half, Width: 10}).Render(f)
+	if got := strings.Count(half.String(), "▀"); got != 10*5 {
+		t.Errorf("halfblock drew %d glyphs for a 40x40 grid at width 10, want 10x5", got)
+	}
+
+	var braille strings.Builder
+	(&BrailleRenderer{W: &braille, Width: 10}).Render(f)
+	if got := strings.Count(braille.String(), "⣿"); got != 10*5 {
+		t.Errorf("braille drew %d full glyphs for a 40x40 grid at width 10, want 10x5", got)
+	}
+}
+
+func TestBlockSampleMixesColours(t *testing.T) {
+	if got := (blockSample{cells: 4}).rgb(nil); got != waterRGB {
+		t.Errorf("empty block colour %v, want water %v", got, waterRGB)
+	}
+	if got := (blockSample{sharks: 2, cells: 2}).rgb(nil); got != sharkRGB {
+		t.Errorf("full shark block colour %v, want %v", got, sharkRGB)
+	}
+	half := blockSample{fish: 1, sharks: 1, cells: 2}.rgb(nil)
+	for i := range half {
+		if want := (fishRGB[i] + sharkRGB[i]) / 2; half[i] != want {
+			t.Errorf("mixed channel %d = %d, want %d", i, half[i], want)
+		}
+	}
+}
+
+func TestDiffRendererEmitsOnlyChangedCells(t *testing.T) {
+	var out strings.Builder
+	r := &DiffRenderer{W: &out, Theme: themes["ascii"]}
+	r.Render(frameFromASCII(t, "FS\n.F\n", 3))
+	if !strings.Contains(out.String(), "| F S |\n| . F |\n") {
+		t.Fatalf("first frame not drawn in full: %q", out.String())
+	}
+
+	out.Reset()
+	next := frameFromASCII(t, "FS\nF.\n", 3)
+	next.chronon = 1
+	r.Render(next)
+	want := "\033[1;1HStep 1:\033[K\033[4;3HF\033[4;5H.\033[6;1H"
+	if out.String() != want {
+		t.Errorf("diff output %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	r.Render(frameFromASCII(t, "F..\n...\n...\n", 3))
+	if !strings.HasPrefix(out.String(), "\033[H\033[2J") {
+		t.Errorf("a resized frame should be redrawn in full, got %q", out.String())
+	}
+}
+
+/**
+ * @brief Returns a frame using every kind of glyph: lineages, juveniles, tides, heat and
+ * sharks with energies inside and outside 0..maxEnergy.
+ */
+func glyphTestFrame(t *testing.T) *Frame {
+	f := frameFromASCII(t, "FSF.\nS.F.\n..SF\nF..S\n", 3)
+	f.energy[0*4+1], f.energy[1*4+0], f.energy[3*4+3] = 1, 12, 0
+	f.markLineage(0*4+2, 2)
+	f.markLineage(2*4+2, 7)
+	f.markJuvenile(3*4 + 0)
+	f.markTide(1*4 + 1)
+	f.temp = temperatureGradient(4)
+	return f
+}
+
+func TestGlyphCacheMatchesThemes(t *testing.T) {
+	f := glyphTestFrame(t)
+	for _, name := range themeNames() {
+		theme := themes[name]
+		c := newGlyphCache(theme, 10)
+		for pass := range 2 { ///< The second pass reads memoized glyphs
+			for x := 0; x < f.Size(); x++ {
+				for y := 0; y < f.Size(); y++ {
+					if got, want := c.cell(f, x, y), theme.cell(f, x, y, 10); got != want {
+						t.Errorf("%s pass %d: cell (%d,%d) is %q, want %q", name, pass, x, y, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRenderersReuseBuffers(t *testing.T) {
+	f := glyphTestFrame(t)
+	for _, name := range themeNames() {
+		text := &TextRenderer{W: io.Discard, Theme: themes[name], MaxEnergy: 10}
+		diff := &DiffRenderer{W: io.Discard, Theme: themes[name], MaxEnergy: 10}
+		for _, r := range []Renderer{text, diff} {
+			r.Render(f) ///< Builds the cache and buffers
+			if n := testing.AllocsPerRun(20, func() { r.Render(f) }); n != 0 {
+				t.Errorf("%s %T: %v allocations per frame, want 0", name, r, n)
+			}
+		}
+	}
+}
+
+func BenchmarkTextRenderer(b *testing.B) {
+	g := NewGrid(100)
+	g.Seed(1)
+	g.Initialize(3000, 600, 4)
+	f := newFrame(g)
+	for _, name := range []string{"ascii", "ansi", "truecolor"} {
+		b.Run(name, func(b *testing.B) {
+			r := &TextRenderer{W: io.Discard, Theme: themes[name], MaxEnergy: 4}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.Render(f)
+			}
+		})
+	}
+}
+
+func TestPixelRenderer(t *testing.T) {
+	r := &PixelRenderer{}
+	r.Render(frameFromASCII(t, "FS\n..\n", 3))
+	if len(r.Pix) != 2*2*4 {
+		t.Fatalf("got %d bytes, want 16", len(r.Pix))
+	}
+	for i, want := range [][3]int{fishRGB, sharkRGB, waterRGB, waterRGB} {
+		p := r.Pix[i*4:]
+		if got := [3]int{int(p[0]), int(p[1]), int(p[2])}; got != want || p[3] != 255 {
+			t.Errorf("pixel %d = %v alpha %d, want %v opaque", i, got, p[3], want)
+		}
+	}
+
+	r.MaxEnergy = 6 ///< Sharks at energy 3 are drawn darker than fed ones
+	r.Render(frameFromASCII(t, "FS\n..\n", 3))
+	if r.Pix[4] >= byte(sharkRGB[0]) {
+		t.Errorf("hungry shark red %d, want darker than %d", r.Pix[4], sharkRGB[0])
+	}
+}
+
+func TestColourFallback(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	cases := []struct {
+		term, args, want string
+	}{
+		{term: "xterm", args: "-theme emoji", want: "emoji"},
+		{term: "xterm", args: "-theme emoji -no-color", want: "ascii"},
+		{term: "dumb", args: "-theme emoji", want: "ascii"},
+		{term: "dumb", args: "-theme emoji -force-color", want: "emoji"},
+		{term: "xterm", args: "-theme emoji -no-color -force-color", want: "emoji"},
+	}
+	for _, c := range cases {
+		t.Setenv("TERM", c.term)
+		cfg, err := parseConfig(strings.Fields(c.args), io.Discard)
+		if err != nil {
+			t.Fatalf("TERM=%s %s: %v", c.term, c.args, err)
+		}
+		if cfg.Theme != c.want {
+			t.Errorf("TERM=%s %s: theme %q, want %q", c.term, c.args, cfg.Theme, c.want)
+		}
+	}
+}
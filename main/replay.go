@@ -0,0 +1,108 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file replay.go
+ * @brief The replay subcommand: re-simulates a checkpointed run frame by frame.
+ * @details A checkpoint stores the parameters and seed the run started with, so the run can
+ * be repeated from chronon 0 and drawn at any speed. Runs are only reproducible with one
+ * thread, so the replay is compared with the checkpoint at the end and any divergence is
+ * reported.
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+/**
+ * @brief The replay subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdReplay(args []string) int {
+	cfg := defaultConfig()
+	fs := flag.NewFlagSet("wator replay", flag.ContinueOnError)
+	colour := addRenderFlags(fs, &cfg)
+	delay := fs.Duration("delay", 0, "pause between frames (e.g. 100ms)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wator replay [flags] <checkpoint>")
+		fmt.Fprintln(fs.Output(), "Re-simulates the run saved in a checkpoint from chronon 0 and draws every frame.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitConfigError
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitConfigError
+	}
+	colour.apply(&cfg)
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitConfigError
+	}
+	cp, err := ReadCheckpoint(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitConfigError
+	}
+
+	if p := cp.Provenance; p != nil {
+		fmt.Fprintf(os.Stderr, "Checkpoint of seed %d written by commit %s (%s) on %s, run started %s.\n",
+			cp.Config.Seed, p.commit(), p.GoVersion, p.Host, p.Started.Format(time.RFC3339))
+	}
+	run := cp.Config
+	run.Theme, run.Style, run.Renderer, run.Diff = cfg.Theme, cfg.Style, cfg.Renderer, cfg.Diff
+	run.Viewport, run.Zoom, run.Follow, run.History, run.Inspect = cfg.Viewport, cfg.Zoom, cfg.Follow, cfg.History, cfg.Inspect
+	run.Origin, run.Center, run.Accessible = cfg.Origin, cfg.Center, cfg.Accessible
+	if err := run.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid parameters:\n%v\n", err)
+		return exitConfigError
+	}
+	sim, err := NewSimulation(run)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitConfigError
+	}
+
+	renderer := withControls(context.Background(), run, os.Stdout, os.Stdin, sim)
+	sim.OnChrononStart(func(f *Frame) {
+		renderer.Render(f)
+		time.Sleep(*delay)
+	})
+	for sim.Snapshot().Chronon() < cp.Chronon {
+		sim.Step(context.Background())
+	}
+	renderer.Render(sim.Snapshot())
+
+	if !slices.Equal(sim.Checkpoint().Entities, cp.Entities) {
+		fmt.Fprintf(os.Stderr, "Replay diverged from the checkpoint at chronon %d; runs are only reproducible with 1 thread (this one used %d).\n",
+			cp.Chronon, cp.Config.Threads)
+		if p, here := cp.Provenance, runProvenance(); p != nil && (p.Commit != here.Commit || p.Modified || here.Modified) {
+			fmt.Fprintf(os.Stderr, "It was also written by commit %s and is replayed by %s, whose rules may differ.\n", p.commit(), here.commit())
+		}
+		return exitFailure
+	}
+	return exitOK
+}
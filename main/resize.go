@@ -0,0 +1,170 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file resize.go
+ * @brief Growing and shrinking the world between chronons.
+ * @details Simulation.Resize remaps the grid onto a new size with one of three strategies:
+ * crop keeps the top-left corner in place and cuts or adds rows and columns at the bottom and
+ * right, pad keeps the centre in place and cuts or adds them evenly on every side, and scale
+ * stretches the world, every new cell copying the old cell it falls in (growing by 2 turns
+ * each animal into a 2x2 block of clones; shrinking keeps one animal per block). Animals keep
+ * their counters and energy. Resize takes a width and a height, but the grid stays square,
+ * like every Wa-Tor world here, so the two must be equal until Grid supports other shapes. In
+ * paint mode + and - grow and shrink the world by padding.
+ */
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/**
+ * @brief How Resize maps the old world onto the new one.
+ */
+type ResizeStrategy string
+
+const (
+	ResizeCrop  ResizeStrategy = "crop"  ///< Anchor the top-left corner
+	ResizePad   ResizeStrategy = "pad"   ///< Anchor the centre
+	ResizeScale ResizeStrategy = "scale" ///< Stretch or shrink every cell
+)
+
+/** The strategies in the order they are listed in messages. */
+var resizeStrategies = []ResizeStrategy{ResizeCrop, ResizePad, ResizeScale}
+
+/** Cells added or removed on each side by the paint-mode + and - keys. */
+const paintResizeStep = 5
+
+/**
+ * @brief Returns the old cell a new cell takes its contents from.
+ * @param x, y The new cell.
+ * @param from, to The old and new sizes.
+ * @return The old cell, and false if the new cell lies outside the old world.
+ */
+func (st ResizeStrategy) source(x, y, from, to int) (int, int, bool) {
+	switch st {
+	case ResizeScale:
+		return x * from / to, y * from / to, true
+	case ResizePad:
+		shift := (to - from) / 2
+		x, y = x-shift, y-shift
+	}
+	return x, y, x >= 0 && y >= 0 && x < from && y < from
+}
+
+/**
+ * @brief Returns a copy of an entity for a scaled-up cell; the registry gives it a new identity.
+ */
+func cloneEntity(e Entity) Entity {
+	switch e := e.(type) {
+	case *Fish:
+		c := *e
+		c.ID, c.Born = 0, 0
+		return &c
+	case *Shark:
+		c := *e
+		c.ID, c.Born = 0, 0
+		return &c
+	}
+	return e
+}
+
+/**
+ * @brief Resizes the world from the next chronon on.
+ * @details Safe to call from hooks and from other goroutines. The populations are recounted,
+ * Config reports the new GridSize, red tides are cleared and a -temperature field is rebuilt
+ * for the new size. Hooks that were set up for the old size, such as heatmaps and .npy
+ * recordings, skip frames of a different size.
+ * @param width, height The new dimensions of the grid; at least 1, and equal, since grids are square.
+ * @param strategy How existing entities are remapped.
+ * @return An error, with the world unchanged, for a bad size or strategy.
+ */
+func (s *Simulation) Resize(width, height int, strategy ResizeStrategy) error {
+	if !slices.Contains(resizeStrategies, strategy) {
+		names := make([]string, len(resizeStrategies))
+		for i, st := range resizeStrategies {
+			names[i] = string(st)
+		}
+		return fmt.Errorf("unknown resize strategy %q (want %s)", strategy, strings.Join(names, ", "))
+	}
+	if width < 1 || height < 1 {
+		return fmt.Errorf("grid size must be at least 1, got %dx%d", width, height)
+	}
+	if width != height {
+		return fmt.Errorf("grids are square, got %dx%d", width, height)
+	}
+	size := width
+	newStore, err := storageByName(s.cfg.Storage)
+	if err != nil {
+		return err
+	}
+	var temperature *Temperature
+	if s.cfg.Temperature != "" {
+		if temperature, err = loadTemperature(s.cfg.Temperature, size); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.grid.Size
+	store := newStore(size)
+	used := make(map[Entity]bool) ///< Entities already placed; further copies are clones
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			ox, oy, ok := strategy.source(x, y, old, size)
+			if !ok {
+				continue
+			}
+			e := s.grid.At(ox, oy)
+			if strategy == ResizeScale && size < old {
+				e = s.firstInBlock(x, y, old, size)
+			}
+			if e == nil {
+				continue
+			}
+			if used[e] {
+				e = cloneEntity(e)
+			}
+			used[e] = true
+			store.set(x, y, e)
+		}
+	}
+	s.grid.Size, s.grid.store, s.grid.adj = size, store, adjacencyFor(size)
+	s.cfg.GridSize = size
+	s.pop.Fish, s.pop.Sharks = s.grid.CountEntities()
+	s.rules.Temperature = temperature
+	s.tide = nil
+	s.frame = nil ///< The cached snapshot has the old size
+	if s.fast != nil {
+		s.fast.reset()
+	}
+	return nil
+}
+
+/**
+ * @brief Returns the first animal, in row order, of the block of old cells a shrunk cell covers.
+ * @param x, y The new cell.
+ * @param from, to The old and new sizes, from > to.
+ */
+func (s *Simulation) firstInBlock(x, y, from, to int) Entity {
+	for ox := x * from / to; ox < (x+1)*from/to; ox++ {
+		for oy := y * from / to; oy < (y+1)*from/to; oy++ {
+			if e := s.grid.At(ox, oy); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
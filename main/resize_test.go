@@ -0,0 +1,130 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file resize_test.go
+ * @brief Tests for resizing the world.
+ */
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+/** @brief Builds a 4x4 simulation holding a fish at (0, 0) and a shark at (3, 3). */
+func resizeFixture(t *testing.T) *Simulation {
+	t.Helper()
+	cfg := testConfig()
+	cfg.GridSize, cfg.NumFish, cfg.NumShark = 4, 0, 0
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Apply(CellEdit{X: 0, Y: 0, Species: SpeciesFish}, CellEdit{X: 3, Y: 3, Species: SpeciesShark})
+	return sim
+}
+
+func TestResizeStrategies(t *testing.T) {
+	tests := []struct {
+		strategy      ResizeStrategy
+		size          int
+		fish, sharks  int
+		fishAt, shark [2]int
+	}{
+		{ResizeCrop, 8, 1, 1, [2]int{0, 0}, [2]int{3, 3}},
+		{ResizePad, 8, 1, 1, [2]int{2, 2}, [2]int{5, 5}},
+		{ResizeScale, 8, 4, 4, [2]int{1, 1}, [2]int{7, 7}},
+		{ResizeCrop, 2, 1, 0, [2]int{0, 0}, [2]int{-1, -1}},
+		{ResizePad, 2, 0, 0, [2]int{-1, -1}, [2]int{-1, -1}},
+		{ResizeScale, 2, 1, 1, [2]int{0, 0}, [2]int{1, 1}}, ///< Each cell keeps the first animal of its 2x2 block
+	}
+	for _, tc := range tests {
+		sim := resizeFixture(t)
+		if err := sim.Resize(tc.size, tc.size, tc.strategy); err != nil {
+			t.Fatal(err)
+		}
+		f := sim.Snapshot()
+		if f.Size() != tc.size || sim.Config().GridSize != tc.size {
+			t.Fatalf("%s to %d: frame size %d, config size %d", tc.strategy, tc.size, f.Size(), sim.Config().GridSize)
+		}
+		fish, sharks := f.Counts()
+		if fish != tc.fish || sharks != tc.sharks {
+			t.Errorf("%s to %d: %d fish and %d sharks, want %d and %d", tc.strategy, tc.size, fish, sharks, tc.fish, tc.sharks)
+		}
+		if p := sim.Population(); p.Fish != fish || p.Sharks != sharks {
+			t.Errorf("%s to %d: running population %+v does not match the frame", tc.strategy, tc.size, p)
+		}
+		if x, y := tc.fishAt[0], tc.fishAt[1]; x >= 0 && f.At(x, y) != SpeciesFish {
+			t.Errorf("%s to %d: no fish at (%d, %d)", tc.strategy, tc.size, x, y)
+		}
+		if x, y := tc.shark[0], tc.shark[1]; x >= 0 && f.At(x, y) != SpeciesShark {
+			t.Errorf("%s to %d: no shark at (%d, %d)", tc.strategy, tc.size, x, y)
+		}
+	}
+}
+
+func TestResizeScaleClonesEntities(t *testing.T) {
+	sim := resizeFixture(t)
+	original := sim.Grid().At(0, 0)
+	if err := sim.Resize(8, 8, ResizeScale); err != nil {
+		t.Fatal(err)
+	}
+	g := sim.Grid()
+	if g.At(0, 0) != original {
+		t.Error("the first copy of an animal should be the animal itself")
+	}
+	clone, ok := g.At(1, 1).(*Fish)
+	if !ok || clone == original || clone.ID != 0 {
+		t.Errorf("scaled-up cell holds %#v, want a fresh clone without an identity", g.At(1, 1))
+	}
+}
+
+func TestResizeRejectsBadInput(t *testing.T) {
+	sim := resizeFixture(t)
+	if err := sim.Resize(8, 8, "stretch"); err == nil {
+		t.Error("an unknown strategy was accepted")
+	}
+	if err := sim.Resize(0, 0, ResizePad); err == nil {
+		t.Error("a zero size was accepted")
+	}
+	if err := sim.Resize(8, 6, ResizePad); err == nil || !strings.Contains(err.Error(), "square") {
+		t.Errorf("a non-square size gave %v, want an error saying grids are square", err)
+	}
+	if sim.Config().GridSize != 4 || sim.Snapshot().Size() != 4 {
+		t.Error("a failed resize changed the world")
+	}
+}
+
+func TestStepAfterResize(t *testing.T) {
+	for _, engine := range engineNames() {
+		cfg := testConfig()
+		cfg.Engine, cfg.Threads, cfg.Temperature = engine, 1, "gradient"
+		sim, err := NewSimulation(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sim.Step(context.Background())
+		for _, size := range []int{30, 12} {
+			if err := sim.Resize(size, size, ResizePad); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5; i++ {
+				sim.Step(context.Background())
+				fish, sharks := sim.Snapshot().Counts()
+				if p := sim.Population(); p.Fish != fish || p.Sharks != sharks {
+					t.Fatalf("%s at size %d: running %+v, frame has %d fish and %d sharks", engine, size, p, fish, sharks)
+				}
+			}
+		}
+	}
+}
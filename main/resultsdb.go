@@ -0,0 +1,285 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build !js
+
+/**
+ * @file resultsdb.go
+ * @brief SQLite results database (the -db option of run and sweep).
+ * @details One database file holds an experiment: every run given the same -db appends a row
+ * to runs (seed, parameters, provenance and outcome), one row per chronon to steps and its
+ * events to events, so a sweep of hundreds of runs can be queried with SQL instead of merging
+ * CSV files:
+ *
+ *     SELECT value, avg(fish) FROM runs WHERE param = 'SharkBreed' GROUP BY value;
+ *
+ * Steps and events are committed in batches of dbBatch chronons, so a long run costs few
+ * transactions and an interrupted one keeps what it had recorded; its runs row then has status
+ * "interrupted" (or "running" if the process was killed). The database is opened in WAL mode
+ * with a busy timeout, so several sweep tasks on one machine can append to it at once; SQLite
+ * locking is unreliable on network filesystems, where each task should use a file of its own.
+ * Left out of the browser build, which the SQLite driver does not support (see resultsdb_js.go).
+ */
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" ///< Pure-Go driver, registered as "sqlite"
+)
+
+/** Chronons recorded per transaction. */
+const dbBatch = 500
+
+/** Tables of a results database; created if missing. */
+const dbSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id                 INTEGER PRIMARY KEY,
+	command            TEXT NOT NULL,    -- run or sweep
+	param              TEXT,             -- swept parameter, for sweep runs
+	value              TEXT,             -- its value in this run
+	seed               INTEGER NOT NULL,
+	parameters         TEXT NOT NULL,    -- full configuration as JSON
+	commit_id          TEXT NOT NULL,
+	modified           INTEGER NOT NULL, -- build had uncommitted changes
+	go_version         TEXT NOT NULL,
+	host               TEXT NOT NULL,
+	started            TEXT NOT NULL,    -- RFC 3339, UTC
+	status             TEXT NOT NULL,    -- running, done, interrupted or failed
+	chronons           INTEGER,
+	fish               INTEGER,
+	sharks             INTEGER,
+	extinct            TEXT,
+	extinction_chronon INTEGER,
+	wall_seconds       REAL,
+	fingerprint        TEXT,
+	summary            TEXT              -- the -summary-json document
+);
+CREATE TABLE IF NOT EXISTS steps (
+	run_id         INTEGER NOT NULL REFERENCES runs(id),
+	chronon        INTEGER NOT NULL,
+	fish           INTEGER NOT NULL,
+	sharks         INTEGER NOT NULL,
+	fish_born      INTEGER NOT NULL,
+	sharks_born    INTEGER NOT NULL,
+	fish_eaten     INTEGER NOT NULL,
+	sharks_starved INTEGER NOT NULL,
+	fish_crowded   INTEGER NOT NULL,
+	fish_poisoned  INTEGER NOT NULL,
+	moves          INTEGER NOT NULL,
+	PRIMARY KEY (run_id, chronon)
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS events (
+	run_id  INTEGER NOT NULL REFERENCES runs(id),
+	chronon INTEGER NOT NULL,
+	kind    TEXT NOT NULL,
+	x       INTEGER NOT NULL,
+	y       INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_run ON events (run_id, chronon);
+`
+
+/**
+ * @struct ResultsDB
+ * @brief An open results database.
+ */
+type ResultsDB struct {
+	db     *sql.DB
+	steps  *sql.Stmt ///< Inserts a steps row
+	events *sql.Stmt ///< Inserts an events row
+}
+
+/**
+ * @brief Opens (creating if needed) a results database.
+ * @param path The SQLite file; always on the local filesystem.
+ * @return The database, or an error if it could not be opened or its tables created.
+ */
+func OpenResultsDB(path string) (*ResultsDB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(30000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, fmt.Errorf("opening results database: %w", err)
+	}
+	db.SetMaxOpenConns(1) ///< One writer per process; the busy timeout handles the others
+	if _, err := db.Exec(dbSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening results database %s: %w", path, err)
+	}
+	r := &ResultsDB{db: db}
+	if r.steps, err = db.Prepare(`INSERT INTO steps VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`); err == nil {
+		r.events, err = db.Prepare(`INSERT INTO events VALUES (?, ?, ?, ?, ?)`)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening results database %s: %w", path, err)
+	}
+	return r, nil
+}
+
+/**
+ * @brief Closes the database.
+ */
+func (r *ResultsDB) Close() error {
+	return r.db.Close()
+}
+
+/**
+ * @brief Adds a run in status "running".
+ * @param command The subcommand making the run.
+ * @param param, value The swept parameter and its value, or empty outside a sweep.
+ * @param cfg The configuration of the run, with its seed fixed.
+ * @return The recorder of the run's chronons, or an error.
+ */
+func (r *ResultsDB) BeginRun(command, param, value string, cfg Config) (*RunRecorder, error) {
+	params, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p := runProvenance()
+	res, err := r.db.Exec(`INSERT INTO runs (command, param, value, seed, parameters, commit_id, modified, go_version, host, started, status)
+		VALUES (?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, 'running')`,
+		command, param, value, cfg.Seed, string(params), p.Commit, p.Modified, p.GoVersion, p.Host, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("recording run: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &RunRecorder{results: r, id: id}, nil
+}
+
+/**
+ * @struct RunRecorder
+ * @brief Records the chronons and events of one run.
+ * @details The first error is kept and later records are dropped, as with the CSV writer.
+ */
+type RunRecorder struct {
+	results *ResultsDB
+	id      int64
+	tx      *sql.Tx
+	steps   *sql.Stmt
+	events  *sql.Stmt
+	pending int ///< Chronons in the open transaction
+	err     error
+}
+
+/**
+ * @brief Returns the run's id in the runs table.
+ */
+func (rr *RunRecorder) ID() int64 {
+	return rr.id
+}
+
+/**
+ * @brief Records a simulation's initial populations and registers the hooks recording the rest.
+ * @details Uses stats and event hooks only, so recording makes Step copy no frames.
+ */
+func (rr *RunRecorder) Attach(sim *Simulation) {
+	rr.Record(sim.Population(), StepReport{})
+	sim.OnEvent(rr.Event)
+	sim.OnStats(rr.Record)
+}
+
+/**
+ * @brief Opens the batch transaction if none is open.
+ */
+func (rr *RunRecorder) begin() bool {
+	if rr.err != nil {
+		return false
+	}
+	if rr.tx != nil {
+		return true
+	}
+	if rr.tx, rr.err = rr.results.db.Begin(); rr.err != nil {
+		return false
+	}
+	rr.steps, rr.events = rr.tx.Stmt(rr.results.steps), rr.tx.Stmt(rr.results.events)
+	return true
+}
+
+/**
+ * @brief Records one chronon; usable as a stats hook.
+ * @param p The populations after the chronon.
+ * @param report The engine's report for it.
+ */
+func (rr *RunRecorder) Record(p Population, report StepReport) {
+	if !rr.begin() {
+		return
+	}
+	c := report.StepCounts
+	if _, rr.err = rr.steps.Exec(rr.id, p.Chronon, p.Fish, p.Sharks, c.FishBorn, c.SharksBorn, c.FishEaten,
+		c.SharksStarved, c.FishCrowded, c.FishPoisoned, c.Moves); rr.err != nil {
+		return
+	}
+	if rr.pending++; rr.pending >= dbBatch {
+		rr.commit()
+	}
+}
+
+/**
+ * @brief Records one event; usable as an event hook.
+ */
+func (rr *RunRecorder) Event(e Event) {
+	if rr.begin() {
+		_, rr.err = rr.events.Exec(rr.id, e.Chronon, string(e.Kind), e.X, e.Y)
+	}
+}
+
+/**
+ * @brief Commits the open batch.
+ */
+func (rr *RunRecorder) commit() {
+	if rr.tx == nil {
+		return
+	}
+	if err := rr.tx.Commit(); rr.err == nil {
+		rr.err = err
+	}
+	rr.tx, rr.pending = nil, 0
+}
+
+/**
+ * @brief Commits the remaining chronons and stores the run's outcome.
+ * @param summary The run's summary.
+ * @param failure Why the run failed, or nil.
+ * @return The first error of recording the run, if any.
+ */
+func (rr *RunRecorder) Finish(summary RunSummary, failure error) error {
+	if rr.err != nil && rr.tx != nil {
+		rr.tx.Rollback()
+		rr.tx = nil
+	}
+	rr.commit()
+	status := "done"
+	switch {
+	case failure != nil:
+		status = "failed"
+	case summary.Interrupted:
+		status = "interrupted"
+	}
+	doc, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	if _, err := rr.results.db.Exec(`UPDATE runs SET status = ?, chronons = ?, fish = ?, sharks = ?, extinct = NULLIF(?, ''),
+		extinction_chronon = ?, wall_seconds = ?, fingerprint = NULLIF(?, ''), summary = ? WHERE id = ?`,
+		status, summary.Chronons, summary.Fish, summary.Sharks, summary.Extinct, summary.ExtinctionChronon,
+		summary.WallSeconds, summary.Fingerprint, string(doc), rr.id); rr.err == nil {
+		rr.err = err
+	}
+	if rr.err != nil {
+		return fmt.Errorf("recording run %d: %w", rr.id, rr.err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build js
+
+/**
+ * @file resultsdb_js.go
+ * @brief Stand-in for the browser build, which has no results database.
+ */
+package main
+
+import "errors"
+
+/**
+ * @struct ResultsDB
+ * @brief Never opened in the browser build.
+ */
+type ResultsDB struct{}
+
+/**
+ * @brief Reports that the results database is unavailable in the browser build.
+ */
+func OpenResultsDB(path string) (*ResultsDB, error) {
+	return nil, errors.New("the results database is not supported in the browser build")
+}
+
+func (r *ResultsDB) Close() error { return nil }
+
+func (r *ResultsDB) BeginRun(command, param, value string, cfg Config) (*RunRecorder, error) {
+	return &RunRecorder{}, nil
+}
+
+/**
+ * @struct RunRecorder
+ * @brief Records nothing in the browser build.
+ */
+type RunRecorder struct{}
+
+func (rr *RunRecorder) ID() int64                                      { return 0 }
+func (rr *RunRecorder) Attach(sim *Simulation)                         {}
+func (rr *RunRecorder) Record(p Population, report StepReport)         {}
+func (rr *RunRecorder) Event(e Event)                                  {}
+func (rr *RunRecorder) Finish(summary RunSummary, failure error) error { return nil }
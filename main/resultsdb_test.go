@@ -0,0 +1,76 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build !js
+
+/**
+ * @file resultsdb_test.go
+ * @brief Tests for the SQLite results database.
+ */
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSweepRecordsResultsDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiment.db")
+	cf := newConfigFlags("sweep", "", io.Discard)
+	if _, err := cf.parse([]string{"-chronons", "20", "-engine", "moves", "-db", path, "10", "40", "3", "3", "4", "15", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	values := []string{"2", "4"}
+	var configs []Config
+	for _, v := range values {
+		c, err := cf.with(map[string]string{"SharkBreed": v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, c)
+	}
+	var table strings.Builder
+	for range 2 { ///< A second sweep appends to the same experiment
+		if err := runSweep(context.Background(), "SharkBreed", values, configs, 7, 2, sweepShare{}, &table); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var runs, done int
+	db.QueryRow(`SELECT count(*), count(*) FILTER (WHERE status = 'done' AND param = 'SharkBreed' AND summary IS NOT NULL) FROM runs`).Scan(&runs, &done)
+	if runs != 8 || done != 8 {
+		t.Errorf("%d runs, %d done with their sweep value and summary; want 8 of each", runs, done)
+	}
+
+	var mismatched int
+	err = db.QueryRow(`SELECT count(*) FROM runs r WHERE
+		(SELECT count(*) FROM steps WHERE run_id = r.id) != r.chronons + 1 OR
+		(SELECT fish FROM steps WHERE run_id = r.id AND chronon = r.chronons) != r.fish OR
+		(SELECT sum(sharks_starved) FROM steps WHERE run_id = r.id) != (SELECT count(*) FROM events WHERE run_id = r.id AND kind = 'shark_starved')`).Scan(&mismatched)
+	if err != nil || mismatched != 0 {
+		t.Errorf("%d runs whose steps and events disagree with their outcome (%v)", mismatched, err)
+	}
+
+	var repeats int
+	db.QueryRow(`SELECT count(*) FROM runs a JOIN runs b ON a.id < b.id AND a.value = b.value AND a.seed = b.seed AND a.fish = b.fish`).Scan(&repeats)
+	if repeats != 4 {
+		t.Errorf("%d repeated runs matched their first sweep, want 4", repeats)
+	}
+}
@@ -0,0 +1,99 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file rng.go
+ * @brief Block-buffered random source for the engines' workers.
+ * @details Every worker of the sections, claims and moves engines used to get a new
+ * math/rand default source each chronon, whose seeding fills 607 words of state, and then
+ * paid for every draw through that source. blockSource is wyrand: one word of state, from
+ * which it generates rngBlock values at a time in a tight loop and hands them out in order,
+ * so a draw is usually an index into a buffer. Direction orders take one of these words each
+ * (see randomDirections). The generators live on the Grid and are reseeded in place every
+ * chronon, so a chronon allocates no random state at all. The deterministic and serial
+ * engines keep cellSource, which they reposition for every cell.
+ */
+package main
+
+import (
+	"math/bits"
+	"math/rand"
+)
+
+/** Values generated per refill of a blockSource. */
+const rngBlock = 128
+
+/**
+ * @struct blockSource
+ * @brief A wyrand source that generates its output a block at a time.
+ */
+type blockSource struct {
+	state uint64
+	next  int ///< Index of the next value to hand out; rngBlock when the block is used up
+	block [rngBlock]uint64
+}
+
+/**
+ * @brief Returns a worker's random generator seeded with seed.
+ * @details Not safe for concurrent use; every worker needs its own.
+ */
+func newWorkerRand(seed int64) *rand.Rand {
+	s := &blockSource{}
+	s.Seed(seed)
+	return rand.New(s)
+}
+
+/**
+ * @brief The worker generators of a grid, kept from chronon to chronon.
+ */
+type workerRands []*rand.Rand
+
+/**
+ * @brief Returns the generator of worker i, reseeded from the grid's source.
+ * @details Call before starting workers, once per worker and in worker order: every call
+ * draws the next seed from the grid's source, as creating a new generator per chronon did,
+ * so a run still depends only on its seed.
+ * @param i The worker's index; the pool grows to hold it.
+ * @return The worker's generator, not safe for concurrent use.
+ */
+func (g *Grid) workerRand(i int) *rand.Rand {
+	for len(g.workers) <= i {
+		g.workers = append(g.workers, newWorkerRand(0))
+	}
+	g.workers[i].Seed(g.rng.Int63())
+	return g.workers[i]
+}
+
+func (s *blockSource) Seed(seed int64) { s.state, s.next = uint64(seed), rngBlock }
+
+func (s *blockSource) Uint64() uint64 {
+	if s.next == rngBlock {
+		s.refill()
+	}
+	v := s.block[s.next]
+	s.next++
+	return v
+}
+
+func (s *blockSource) Int63() int64 { return int64(s.Uint64() >> 1) }
+
+/**
+ * @brief Generates the next block of values.
+ */
+func (s *blockSource) refill() {
+	state := s.state
+	for i := range s.block {
+		state += 0xa0761d6478bd642f
+		hi, lo := bits.Mul64(state, state^0xe7037ed1a0b428db)
+		s.block[i] = hi ^ lo
+	}
+	s.state, s.next = state, 0
+}
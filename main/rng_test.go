@@ -0,0 +1,116 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file rng_test.go
+ * @brief Tests and benchmarks of the workers' block-buffered random source.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestBlockSourceIsReproducibleAcrossBlocks(t *testing.T) {
+	a, b := newWorkerRand(42), newWorkerRand(42)
+	for i := 0; i < 3*rngBlock+5; i++ { ///< Crosses several refills
+		if x, y := a.Uint64(), b.Uint64(); x != y {
+			t.Fatalf("draw %d: %x and %x from the same seed", i, x, y)
+		}
+	}
+	if newWorkerRand(1).Uint64() == newWorkerRand(2).Uint64() {
+		t.Error("seeds 1 and 2 start with the same value")
+	}
+}
+
+func TestRandomDirectionsAreUniform(t *testing.T) {
+	rng := newWorkerRand(7)
+	const draws = 240000
+	counts := map[[4]int]int{}
+	for range draws {
+		counts[randomDirections(rng)]++
+	}
+	if len(counts) != len(directionOrders) {
+		t.Fatalf("%d distinct orders drawn, want %d", len(counts), len(directionOrders))
+	}
+	for order, n := range counts { ///< Expect 10000 each; 5 standard deviations is about 490
+		if n < 9500 || n > 10500 {
+			t.Errorf("order %v drawn %d times of %d", order, n, draws)
+		}
+	}
+}
+
+/**
+ * @brief Compares a worker's source as the engines used it before (math/rand's default
+ * source, seeded every chronon) with blockSource: seeding, then a chronon's worth of draws.
+ */
+func BenchmarkWorkerRand(b *testing.B) {
+	sources := map[string]func(seed int64) *rand.Rand{
+		"math-rand": func(seed int64) *rand.Rand { return rand.New(rand.NewSource(seed)) },
+		"block":     newWorkerRand,
+	}
+	for _, name := range []string{"math-rand", "block"} {
+		newRand := sources[name]
+		b.Run(name+"/seed", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				newRand(int64(i))
+			}
+		})
+		b.Run(name+"/reseed", func(b *testing.B) {
+			rng := newRand(1)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				rng.Seed(int64(i)) ///< As Grid.workerRand does every chronon
+				rng.Int63()
+			}
+		})
+		for _, draw := range []string{"directions", "float64", "intn"} {
+			b.Run(fmt.Sprintf("%s/%s", name, draw), func(b *testing.B) {
+				rng := newRand(1)
+				for i := 0; i < b.N; i++ {
+					switch draw {
+					case "directions":
+						randomDirections(rng)
+					case "float64":
+						rng.Float64()
+					default:
+						rng.Intn(10)
+					}
+				}
+			})
+		}
+	}
+}
+
+/**
+ * @brief Times whole chronons under the stochastic rules, which draw a Float64 for every
+ * breeding and starvation decision, so the cost of a Float64 through the workers' sources shows.
+ */
+func BenchmarkStochasticChronon(b *testing.B) {
+	rules := Rules{FishBreed: 3, SharkBreed: 3, StarveEnergy: 4,
+		RuleSet: RuleSetStochastic, FishBreedProb: 0.3, SharkBreedProb: 0.3, StarveProb: 0.25}
+	for _, size := range []int{50, 200} {
+		for _, threads := range []int{1, 4} {
+			b.Run(fmt.Sprintf("size=%d/threads=%d", size, threads), func(b *testing.B) {
+				g := benchGrid(b, size, 0.5)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					g.stepSections(context.Background(), rules, threads)
+				}
+			})
+		}
+	}
+}
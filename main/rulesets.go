@@ -0,0 +1,162 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file rulesets.go
+ * @brief Deterministic-counter and stochastic breeding/starvation rules.
+ * @details The classic Wa-Tor rules use hard thresholds: a fish breeds exactly every FishBreed
+ * chronons and a shark starves exactly StarveEnergy chronons after its last meal. The
+ * stochastic rule set replaces each threshold with a per-chronon probability, which removes
+ * the synchronised breeding waves of the counter model and makes the two easy to compare.
+ *
+ * Under the deterministic rules shark energy follows a cost model: moving and staying each
+ * cost a configurable amount of energy, and a newborn may take a share of its parent's energy
+ * instead of starting full. With the default costs of 1 this is the classic decrement.
+ */
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+/** Names of the rule sets accepted by the -ruleset flag. */
+const (
+	RuleSetDeterministic = "deterministic" ///< Breeding and starvation after fixed numbers of chronons
+	RuleSetStochastic    = "stochastic"    ///< Breeding and starvation with fixed per-chronon probabilities
+)
+
+/** The rule sets in the order they are listed in help text. */
+var ruleSetNames = []string{RuleSetDeterministic, RuleSetStochastic}
+
+/**
+ * @brief Checks that a rule set name is known.
+ * @param name The rule set name.
+ * @return An error listing the valid names, or nil.
+ */
+func validateRuleSet(name string) error {
+	for _, n := range ruleSetNames {
+		if n == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown rule set %q (want %s)", name, strings.Join(ruleSetNames, " or "))
+}
+
+/**
+ * @brief Reports whether the stochastic rule set is selected.
+ */
+func (r Rules) stochastic() bool {
+	return r.RuleSet == RuleSetStochastic
+}
+
+/**
+ * @brief Decides whether a fish that has just moved breeds this chronon.
+ * @param rng The worker's random source; only drawn from by the stochastic rule set.
+ * @param fish The fish, whose breeding counter has already been advanced.
+ * @param x, y The cell the offspring would be left in, whose temperature slows breeding.
+ * @return True if the fish leaves an offspring behind.
+ */
+func (r Rules) fishBreeds(rng *rand.Rand, fish *Fish, x, y int) bool {
+	if fish.Juvenile > 0 {
+		return false ///< Juveniles never breed, under either rule set
+	}
+	if r.stochastic() {
+		return rng.Float64()*r.breedScale(x, y) < r.FishBreedProb
+	}
+	return fish.BreedCounter >= r.breedTime(r.FishBreed, x, y)
+}
+
+/**
+ * @brief Decides whether a shark that has just moved breeds this chronon.
+ * @param rng The worker's random source; only drawn from by the stochastic rule set.
+ * @param shark The shark, whose breeding counter has already been advanced.
+ * @param x, y The cell the offspring would be left in, whose temperature slows breeding.
+ * @return True if the shark leaves an offspring behind.
+ */
+func (r Rules) sharkBreeds(rng *rand.Rand, shark *Shark, x, y int) bool {
+	if r.stochastic() {
+		return rng.Float64()*r.breedScale(x, y) < r.SharkBreedProb
+	}
+	return shark.BreedCounter >= r.breedTime(r.SharkBreed, x, y)
+}
+
+/** @brief Returns the energy a shark spends to move (values below 1 mean 1). */
+func (r Rules) moveCost() int {
+	return max(r.MoveCost, 1)
+}
+
+/** @brief Returns the energy a shark spends to stay put (values below 1 mean 1). */
+func (r Rules) stayCost() int {
+	return max(r.StayCost, 1)
+}
+
+/**
+ * @brief Decides at the start of a chronon whether a shark starves.
+ * @details The counter model starves a shark that cannot afford even the cheaper of moving and
+ * staying. The stochastic model ignores energy and starves the shark with probability StarveProb.
+ * @param rng The worker's random source; only drawn from by the stochastic rule set.
+ * @param shark The shark.
+ * @return True if the shark starves.
+ */
+func (r Rules) hunger(rng *rand.Rand, shark *Shark) bool {
+	if r.stochastic() {
+		return rng.Float64() < r.StarveProb
+	}
+	return shark.Energy-min(r.moveCost(), r.stayCost()) <= 0
+}
+
+/**
+ * @brief Reports whether a shark has the energy to move without eating.
+ * @details A shark that cannot afford to move stays put instead.
+ */
+func (r Rules) canMove(shark *Shark) bool {
+	return r.stochastic() || shark.Energy-r.moveCost() > 0
+}
+
+/**
+ * @brief Charges a shark that did not eat for moving or staying.
+ * @param shark The shark.
+ * @param moved Whether the shark moved.
+ * @return True if the shark ran out of energy (possible only when staying costs more than moving).
+ */
+func (r Rules) spend(shark *Shark, moved bool) bool {
+	if r.stochastic() {
+		return false
+	}
+	if moved {
+		shark.Energy -= r.moveCost()
+	} else {
+		shark.Energy -= r.stayCost()
+	}
+	return shark.Energy <= 0
+}
+
+/**
+ * @brief Creates the offspring of a breeding shark.
+ * @details With BirthShare set, the newborn takes that fraction of its parent's energy (at least
+ * 1) and the parent keeps the rest; a parent that cannot spare it postpones breeding. Otherwise,
+ * and under the stochastic rules, the newborn starts with StarveEnergy.
+ * @param parent The breeding shark.
+ * @return The newborn, or false if the parent is too weak to breed.
+ */
+func (r Rules) offspring(parent *Shark) (*Shark, bool) {
+	if r.stochastic() || r.BirthShare <= 0 {
+		return &Shark{Energy: r.StarveEnergy, Lineage: parent.Lineage}, true
+	}
+	share := max(int(float64(parent.Energy)*r.BirthShare), 1)
+	if share >= parent.Energy {
+		return nil, false
+	}
+	parent.Energy -= share
+	return &Shark{Energy: share, Lineage: parent.Lineage}, true
+}
@@ -0,0 +1,62 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file rulesets_test.go
+ * @brief Tests for the energy cost model of the deterministic rules.
+ */
+package main
+
+import "testing"
+
+func TestEnergyCosts(t *testing.T) {
+	r := Rules{StarveEnergy: 10, MoveCost: 3, StayCost: 1}
+
+	s := &Shark{Energy: 3}
+	if r.hunger(nil, s) {
+		t.Error("a shark that can afford to stay should not starve")
+	}
+	if r.canMove(s) {
+		t.Error("a shark with 3 energy cannot afford a move costing 3")
+	}
+	if r.spend(s, false) || s.Energy != 2 {
+		t.Errorf("staying should cost 1, energy now %d", s.Energy)
+	}
+
+	s = &Shark{Energy: 1}
+	if !r.hunger(nil, s) {
+		t.Error("a shark that cannot afford the cheapest action should starve")
+	}
+
+	s = &Shark{Energy: 4}
+	if !r.canMove(s) || r.spend(s, true) || s.Energy != 1 {
+		t.Errorf("moving should cost 3, energy now %d", s.Energy)
+	}
+}
+
+func TestBirthShare(t *testing.T) {
+	r := Rules{StarveEnergy: 10, BirthShare: 0.5}
+
+	parent := &Shark{Energy: 7}
+	child, ok := r.offspring(parent)
+	if !ok || child.Energy != 3 || parent.Energy != 4 {
+		t.Errorf("got child %+v, parent energy %d; want child energy 3, parent 4", child, parent.Energy)
+	}
+
+	if _, ok := r.offspring(&Shark{Energy: 1}); ok {
+		t.Error("a parent with 1 energy cannot spare any for a newborn")
+	}
+
+	r.BirthShare = 0
+	if child, ok := r.offspring(&Shark{Energy: 2}); !ok || child.Energy != 10 {
+		t.Errorf("without a birth share newborns should start at StarveEnergy, got %+v", child)
+	}
+}
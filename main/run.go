@@ -0,0 +1,537 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file run.go
+ * @brief The run subcommand: simulates and renders a single world.
+ * @details Wires the renderer, logging, statistics and output files to a Simulation and
+ * runs it, including the ensemble mode that aggregates several seeds.
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+/**
+ * @brief The run subcommand: simulates and renders a single world.
+ * @details Initialises the simulation grid, sets parameters for fish and shark behaviour,
+ * and iteratively simulates movement and interactions over a defined number of steps.
+ * @param args Arguments after the subcommand name.
+ * @return The process exit code.
+ */
+func cmdRun(args []string) int {
+	start := time.Now() ///< Record the start time
+
+	cf := newConfigFlags("run", "", os.Stderr)
+	cfg, err := cf.parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid parameters:\n%v\nRun with -h for usage.\n", err)
+		return exitConfigError
+	}
+
+	if err := setupLogger(cfg.LogLevel, cfg.LogJSON); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitConfigError
+	}
+	if outputs, err = openSink(cfg.Output); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid parameters:\n%v\nRun with -h for usage.\n", err)
+		return exitConfigError
+	}
+
+	workerStats := &WorkerStats{}
+	http.Handle("/metrics", workerStats) ///< Exposed by the -pprof server
+
+	stopProfiling, err := startProfiling(cfg.PprofAddr, cfg.TraceFile)
+	if err != nil {
+		slog.Error("profiling setup failed", "err", err)
+		return exitFailure
+	}
+	defer stopProfiling() ///< Flush the execution trace once the run completes
+
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano() ///< Ensures runs are random unless a seed is given
+	}
+	slog.Info("seed", "value", cfg.Seed)
+
+	slog.Info("engine", "name", cfg.Engine, "storage", cfg.Storage, "threads", cfg.Threads)
+
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stopSignals) ///< A second signal kills the process as usual
+
+	if cfg.OTelEndpoint != "" {
+		tracer := NewTracer(cfg.OTelEndpoint)
+		defer tracer.Close() ///< Flush the spans of the last chronons
+		ctx = withTracer(ctx, tracer)
+	}
+
+	if cfg.Ensemble > 1 {
+		if err := runEnsemble(ctx, cfg, os.Stdout); err != nil {
+			slog.Error("ensemble failed", "err", err)
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	if cfg.Pipe {
+		if err := runPipe(ctx, cf, cfg, os.Stdin, os.Stdout); err != nil {
+			slog.Error("pipe session failed", "err", err)
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	sim, err := NewSimulation(cfg) ///< Initialise the grid with sharks and fish
+	if err != nil {
+		slog.Error("simulation setup failed", "err", err)
+		return exitConfigError
+	}
+	cfg = sim.Config() ///< Picks up the size and populations of a -grid map or -resume checkpoint
+	if b := sim.Branch(); b != nil {
+		slog.Info("resumed", "from", b.From, "chronon", b.Chronon, "changes", b.Changes)
+	}
+
+	frames := io.Writer(os.Stdout)
+	if cfg.SummaryJSON == "-" {
+		frames = os.Stderr ///< Standard output then carries only the JSON summary
+	}
+	renderer := withControls(ctx, cfg, frames, os.Stdin, sim)
+	sim.OnChrononStart(renderer.Render) ///< Print the current state of the grid
+	sim.OnChrononStart(func(f *Frame) {
+		numFish, numSharks := f.Counts() ///< Count the number of fish and sharks
+		attrs := []any{"step", f.Chronon(), "fish", numFish, "sharks", numSharks,
+			"mean_shark_energy", fmt.Sprintf("%.2f", f.MeanSharkEnergy())}
+		if cfg.FishMaturity > 0 {
+			attrs = append(attrs, "juvenile_fish", f.Juveniles())
+		}
+		if cfg.redTide() {
+			attrs = append(attrs, "tide_cells", f.TideCells())
+		}
+		slog.Info("chronon", attrs...) ///< Report the counts
+	})
+
+	var totals StepCounts ///< Births and deaths over the whole run
+	sim.OnStats(func(p Population, report StepReport) {
+		workerStats.Record(report.WorkerTimes)
+		totals.Add(report.StepCounts)
+		slog.Debug("chronon events", "step", p.Chronon-1, "fish_born", report.FishBorn, "sharks_born", report.SharksBorn,
+			"fish_eaten", report.FishEaten, "sharks_starved", report.SharksStarved, "fish_crowded", report.FishCrowded,
+			"fish_poisoned", report.FishPoisoned, "moves", report.Moves)
+	})
+
+	if cfg.Check {
+		checker, err := NewInvariantChecker(sim.Grid(), cfg.StarveEnergy)
+		if err != nil {
+			abortOnViolation(sim.Grid(), 0, err)
+		}
+		checker.Speed = max(cfg.FishSpeed, cfg.SharkSpeed)
+		sim.OnChrononEnd(func(f *Frame, report StepReport) {
+			if err := checker.Check(sim.Grid(), report.StepCounts); err != nil {
+				abortOnViolation(sim.Grid(), f.Chronon(), err)
+			}
+		})
+	}
+
+	guard := NewMemoryGuard(cfg.MaxMemory, sim.Stop)
+	sim.OnChrononEnd(guard.Record)
+	if history, ok := renderer.(*History); ok {
+		guard.OnPressure(history.Trim)
+	}
+
+	var events *EventWriter
+	droppedEvents := 0 ///< Event lines not written under memory pressure
+	if cfg.EventsFile != "" {
+		if events, err = NewEventWriter(cfg.EventsFile); err != nil {
+			slog.Error("event log setup failed", "err", err)
+			return exitFailure
+		}
+		sim.OnEvent(func(e Event) {
+			if guard.Degraded() {
+				droppedEvents++
+				return
+			}
+			if err := events.Write([]Event{e}); err != nil {
+				slog.Error("writing events failed", "err", err)
+			}
+		})
+	}
+
+	var stats *CSVWriter
+	if cfg.CSVFile != "" {
+		if stats, err = NewCSVWriter(cfg.CSVFile, cfg); err != nil {
+			slog.Error("CSV stats setup failed", "err", err)
+			return exitFailure
+		}
+		sim.OnChrononEnd(stats.Record)
+	}
+
+	var record *RunRecorder
+	if cfg.Database != "" {
+		results, err := OpenResultsDB(cfg.Database)
+		if err != nil {
+			slog.Error("results database setup failed", "err", err)
+			return exitFailure
+		}
+		defer results.Close()
+		if record, err = results.BeginRun("run", "", "", cfg); err != nil {
+			slog.Error("results database setup failed", "err", err)
+			return exitFailure
+		}
+		record.Attach(sim)
+		slog.Info("recording run", "db", cfg.Database, "run_id", record.ID())
+	}
+
+	if cfg.SharedFrames != "" {
+		shared, err := NewSharedFrameWriter(cfg.SharedFrames, cfg.GridSize)
+		if err != nil {
+			slog.Error("shared frame setup failed", "err", err)
+			return exitFailure
+		}
+		defer shared.Close()
+		shared.Write(sim.Snapshot())
+		sim.OnChrononEnd(func(f *Frame, _ StepReport) { shared.Write(f) })
+		slog.Info("publishing frames", "file", cfg.SharedFrames)
+	}
+
+	if cfg.Publish != "" {
+		publisher, err := NewStatsPublisher(cfg.Publish, sim.Snapshot())
+		if err != nil {
+			slog.Error("publisher setup failed", "err", err)
+			return exitFailure
+		}
+		defer publisher.Close()
+		sim.OnChrononEnd(publisher.Record)
+		slog.Info("publishing stats", "url", cfg.Publish)
+	}
+
+	watch := &ExtinctionWatch{}
+	watch.Add(sim.Population())   ///< A species may be absent from the start
+	series := &PopulationSeries{} ///< For the cycle analysis and -fit-lv
+	series.Add(sim.Population())
+	sim.OnStats(func(p Population, _ StepReport) {
+		watch.Add(p)
+		series.Add(p)
+	})
+
+	fingerprint, err := NewFingerprinter(cfg.Fingerprint, cfg)
+	if err != nil {
+		slog.Error("fingerprint setup failed", "err", err)
+		return exitFailure
+	}
+	fingerprint.Observe(sim.Snapshot())
+	sim.OnChrononEnd(fingerprint.Record)
+
+	var signKey []byte
+	var chain *FrameChain
+	if cfg.SignKey != "" {
+		if signKey, err = readSigningKey(cfg.SignKey); err != nil {
+			slog.Error("signing setup failed", "err", err)
+			return exitFailure
+		}
+		chain = NewFrameChain()
+		chain.Observe(sim.Snapshot())
+		sim.OnChrononEnd(chain.Record)
+	}
+
+	var zones []Zone
+	var zoneStats *ZoneWriter
+	if cfg.Zones != "" {
+		if zones, err = parseZones(cfg.Zones, cfg.GridSize); err != nil {
+			slog.Error("zone setup failed", "err", err)
+			return exitConfigError
+		}
+	}
+	if cfg.ZoneCSV != "" {
+		if zoneStats, err = NewZoneWriter(cfg.ZoneCSV, zones, cfg); err != nil {
+			slog.Error("zone CSV setup failed", "err", err)
+			return exitFailure
+		}
+		sim.OnChrononEnd(zoneStats.Record)
+	}
+
+	var export *FrameExporter
+	if cfg.Export != "" {
+		if export, err = NewFrameExporter(cfg.Export, cfg); err != nil {
+			slog.Error("frame export setup failed", "err", err)
+			return exitFailure
+		}
+		sim.Registry() ///< Registered first, so births are stamped before each frame is exported
+		sim.OnChrononStart(func(*Frame) { export.Record(sim.Grid()) })
+	}
+
+	var tensor *NpyWriter
+	if cfg.Npy != "" {
+		if tensor, err = NewNpyWriter(cfg.Npy, cfg); err != nil {
+			slog.Error("tensor export setup failed", "err", err)
+			return exitFailure
+		}
+		sim.OnChrononStart(tensor.Record)
+	}
+
+	var heatmap *Heatmap
+	if cfg.HeatmapPrefix != "" {
+		heatmap = NewHeatmap(cfg.GridSize) ///< Track occupancy only when an export was requested
+		sim.OnChrononStart(heatmap.Record)
+	}
+
+	notifier := newNotifier(cfg.Notify)
+	if notifier != nil {
+		en := &ExtinctionNotifier{Notifier: notifier}
+		en.Observe(sim.Population(), StepReport{}) ///< A species absent from the start is not announced
+		sim.OnStats(en.Observe)
+	}
+
+	var triggers *TriggerSet
+	if cfg.Triggers != "" {
+		if triggers, err = NewTriggerSet(sim, cfg.Triggers, cfg.TriggerPrefix); err != nil {
+			slog.Error("trigger setup failed", "err", err)
+			return exitConfigError
+		}
+		triggers.Notifier = notifier
+		triggers.Observe(sim.Population(), StepReport{}) ///< A condition may already hold for the initial world
+		sim.OnStats(triggers.Observe)
+	}
+
+	var tuner *AutoTuner
+	if cfg.AutoThreads {
+		tuner = NewAutoTuner(sim, cfg.Threads)
+		sim.OnChrononEnd(tuner.Record)
+	}
+
+	var control *ControlServer
+	if cfg.Control != "" {
+		if control, err = ListenControl(sim, cfg.Control); err != nil {
+			slog.Error("control socket setup failed", "err", err)
+			return exitFailure
+		}
+		context.AfterFunc(ctx, control.Release) ///< An interrupt ends a paused run
+		slog.Info("control socket listening", "path", cfg.Control)
+	}
+
+	sim.SetTick(cfg.Tick)
+	ran, interrupted := sim.Run(ctx, cfg.Chronons) ///< Concurrently update grid state using threads
+	if control != nil {
+		control.Close()
+	}
+	if interrupted != nil {
+		slog.Warn("interrupted, shutting down", "chronons_run", ran)
+	}
+	stopSignals()
+
+	// Final summary
+	final := sim.Snapshot()
+	numFish, numSharks := final.Counts()
+	slog.Info("simulation ended", "fish", numFish, "sharks", numSharks,
+		"fish_born", totals.FishBorn, "sharks_born", totals.SharksBorn,
+		"fish_eaten", totals.FishEaten, "sharks_starved", totals.SharksStarved,
+		"fish_crowded", totals.FishCrowded, "fish_poisoned", totals.FishPoisoned, "moves", totals.Moves) ///< Report final counts and deaths
+	if cfg.Tag > 0 {
+		fish, sharks := final.LineageCounts(cfg.Lineages)
+		for k := range fish {
+			slog.Info("lineage", "tag", k+1, "fish", fish[k], "sharks", sharks[k])
+		}
+	}
+	workerStats.Report() ///< Report load balance across workers
+	if chunks, ok := sim.Grid().store.(*chunkStorage); ok {
+		slog.Info("chunks", "allocated", chunks.allocated(), "total", len(chunks.chunks), "fast_forwarded", sim.FastForwarded())
+	}
+	if tuner != nil {
+		tuner.Report()
+	}
+	if triggers != nil {
+		slog.Info("triggers", "fired", triggers.Fired)
+	}
+
+	if droppedEvents > 0 {
+		slog.Warn("event lines dropped under memory pressure", "count", droppedEvents)
+	}
+	if events != nil {
+		if err := events.Close(); err != nil {
+			slog.Error("closing event log failed", "err", err)
+		}
+	}
+
+	if cfg.Checkpoint != "" {
+		cp := sim.Checkpoint()
+		if chain != nil {
+			if cp.Signature, err = signRun(signKey, cp.Config, chain, final); err != nil {
+				slog.Error("signing checkpoint failed", "err", err)
+			}
+		}
+		if err := cp.Save(cfg.Checkpoint); err != nil {
+			slog.Error("checkpoint failed", "err", err)
+		} else {
+			slog.Info("checkpoint written", "file", cfg.Checkpoint, "chronon", final.Chronon())
+		}
+	}
+
+	if stats != nil {
+		if err := stats.Close(); err != nil {
+			slog.Error("writing CSV stats failed", "err", err)
+		}
+	}
+
+	if err := fingerprint.Close(); err != nil {
+		slog.Error("writing fingerprints failed", "err", err)
+	}
+	slog.Info("fingerprint", "value", fingerprint.Sum())
+
+	for _, z := range zones {
+		fish, sharks := z.Count(final)
+		slog.Info("zone", "name", z.Name, "cells", z.Cells(), "fish", fish, "sharks", sharks)
+	}
+	if zoneStats != nil {
+		if err := zoneStats.Close(); err != nil {
+			slog.Error("writing zone CSV failed", "err", err)
+		}
+	}
+
+	if export != nil {
+		sim.Registry().Observe(sim.Grid())
+		export.Record(sim.Grid()) ///< Include the final state
+		if err := export.Close(); err != nil {
+			slog.Error("writing frame export failed", "err", err)
+		}
+	}
+
+	if tensor != nil {
+		tensor.Record(final) ///< Include the final state
+		if err := tensor.Close(); err != nil {
+			slog.Error("writing tensor failed", "err", err)
+		}
+	}
+
+	if heatmap != nil {
+		heatmap.Record(final) ///< Include the final state
+		if err := heatmap.WritePNGs(cfg.HeatmapPrefix); err != nil {
+			slog.Error("heatmap export failed", "err", err)
+		}
+	}
+
+	end := time.Now()                                      ///< Record the end time
+	slog.Info("execution time", "elapsed", end.Sub(start)) ///< Calculate and report elapsed time
+
+	summary := NewRunSummary(cfg, final, totals, watch, ran, end.Sub(start), interrupted != nil)
+	summary.Fingerprint = fingerprint.Sum()
+	summary.Branch = sim.Branch()
+	summary.PeakRSSBytes = guard.PeakRSS()
+	slog.Info("memory", "peak_rss", ByteSize(summary.PeakRSSBytes).String())
+	if summary.Oscillation = AnalyseOscillation(series); summary.Oscillation != nil {
+		slog.Info("oscillation", "fish_period", summary.Oscillation.Fish.Period, "fish_amplitude", summary.Oscillation.Fish.Amplitude,
+			"sharks_period", summary.Oscillation.Sharks.Period, "sharks_amplitude", summary.Oscillation.Sharks.Amplitude)
+	}
+	if cfg.FitLV {
+		if fit, err := FitLotkaVolterra(series); err != nil {
+			slog.Warn("lotka-volterra fit failed", "err", err)
+		} else {
+			slog.Info("lotka-volterra fit", "alpha", fit.Alpha, "beta", fit.Beta, "gamma", fit.Gamma, "delta", fit.Delta,
+				"fish_rmse", fit.FishRMSE, "sharks_rmse", fit.SharkRMSE, "fish_r2", fit.FishR2, "sharks_r2", fit.SharkR2)
+			summary.LotkaVolterra = &fit
+		}
+	}
+	if chain != nil {
+		if summary.Signature, err = signRun(signKey, summary.Parameters, chain, final); err != nil {
+			slog.Error("signing summary failed", "err", err)
+		}
+	}
+	if cfg.SummaryJSON != "" {
+		if err := summary.WriteFile(cfg.SummaryJSON); err != nil {
+			slog.Error("summary failed", "err", err)
+		}
+	}
+	if record != nil {
+		if err := record.Finish(summary, errors.Join(sim.Rules().Behaviour.Err(), guard.Err())); err != nil {
+			slog.Error("results database failed", "err", err)
+		}
+	}
+
+	switch {
+	case sim.Rules().Behaviour.Err() != nil:
+		slog.Error("behaviour script failed", "err", sim.Rules().Behaviour.Err())
+		return exitFailure
+	case guard.Err() != nil:
+		slog.Error("run stopped", "err", guard.Err())
+		return exitFailure
+	case interrupted != nil:
+		return exitInterrupted
+	case summary.Extinct != "":
+		slog.Info("extinction", "species", summary.Extinct, "chronon", *summary.ExtinctionChronon)
+		return exitExtinct
+	}
+	return exitOK
+}
+
+/**
+ * @brief Dumps the offending frame to stderr and exits after an invariant violation.
+ * @param grid The grid that failed validation.
+ * @param chronon The chronon that produced the frame.
+ * @param err The violations found.
+ */
+func abortOnViolation(grid *Grid, chronon int, err error) {
+	slog.Error("invariant check failed", "chronon", chronon, "err", err)
+	fmt.Fprintf(os.Stderr, "Offending frame (chronon %d):\n", chronon)
+	grid.WriteASCII(os.Stderr) ///< Plain F/S/. map, ready to paste into a bug report or load with -grid
+	os.Exit(exitFailure)
+}
+
+/**
+ * @brief Runs an ensemble and writes its per-chronon statistics as CSV.
+ * @details An interrupted ensemble still writes the chronons every member completed.
+ * @param ctx Context of the run.
+ * @param cfg The configuration shared by every member.
+ * @param w Destination of the CSV table.
+ * @return An error if the ensemble could not be created or the table could not be written.
+ */
+func runEnsemble(ctx context.Context, cfg Config, w io.Writer) error {
+	manager, err := NewManager(cfg, cfg.Ensemble, 0)
+	if err != nil {
+		return err
+	}
+	if err := manager.Run(ctx, cfg.Chronons); err != nil {
+		slog.Warn("interrupted, writing partial ensemble statistics")
+	}
+
+	stats := manager.Stats()
+	for i, st := range stats {
+		if st.Members < cfg.Ensemble {
+			stats = stats[:i] ///< Only report chronons every member reached
+			break
+		}
+	}
+	out := csv.NewWriter(w)
+	out.Write(ensembleColumns)
+	for _, st := range stats {
+		out.Write(st.record())
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return err
+	}
+	if cfg.Plot != "" {
+		title := fmt.Sprintf("%d runs from seed %d, mean and 95%% confidence interval", cfg.Ensemble, cfg.Seed)
+		return writePlotFile(cfg.Plot, title, []PlotSeries{{Stats: stats}})
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file satiation.go
+ * @brief Sated sharks that stop hunting after a meal (the -satiation option).
+ * @details With Satiation R > 0, a shark that eats skips hunting for the next R chronons: it
+ * neither eats nor steers toward fish, and instead wanders to empty cells like a fish or,
+ * with SatedRest, stays where it is. Energy costs, starvation and breeding are unchanged, so
+ * a resting shark still pays StayCost. Giving prey a respite after every kill damps the
+ * boom-and-bust cycles of classic Wa-Tor, as in the satiation variants of the model.
+ */
+package main
+
+/**
+ * @brief Counts down a shark's satiation by one chronon.
+ * @return Whether the shark is sated this chronon.
+ */
+func (s *Shark) digest() bool {
+	if s.Sated == 0 {
+		return false
+	}
+	s.Sated--
+	return true
+}
+
+/**
+ * @brief Returns how far a shark may move and see this chronon, and counts down its satiation.
+ * @details Used by the engines that step entities one at a time (sections and claims). A
+ * vision of 0 means the shark does not hunt, as when it is sated or its food web gives it no
+ * prey; a speed of 0 means it rests.
+ * @param shark The shark about to move.
+ * @return The speed and vision to pass to decidePath.
+ */
+func (r Rules) sharkReach(shark *Shark) (speed, vision int) {
+	if !shark.digest() {
+		if !r.FoodWeb.Eats(SpeciesShark, SpeciesFish) {
+			return r.sharkSpeed(), 0
+		}
+		return r.sharkSpeed(), r.sharkVision()
+	}
+	if r.SatedRest {
+		return 0, 0
+	}
+	return r.sharkSpeed(), 0
+}
@@ -0,0 +1,77 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file satiation_test.go
+ * @brief Tests for sated sharks.
+ */
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+/**
+ * @brief Returns a 3x3 grid with a shark in the centre and fish everywhere else, so nothing can
+ * move and a hunting shark must eat.
+ */
+func surroundedShark(sated int) (*Grid, *Shark) {
+	g := NewGrid(3)
+	g.Seed(1)
+	shark := &Shark{Energy: 5, Sated: sated}
+	for x := 0; x < 3; x++ {
+		for y := 0; y < 3; y++ {
+			g.Set(x, y, &Fish{})
+		}
+	}
+	g.Set(1, 1, shark)
+	return g, shark
+}
+
+func TestSatedSharksDoNotHunt(t *testing.T) {
+	rules := Rules{FishBreed: 10, SharkBreed: 10, StarveEnergy: 5, Satiation: 2}
+	for _, name := range engineNames() {
+		engine, _ := engineByName(name)
+		t.Run(name, func(t *testing.T) {
+			g, shark := surroundedShark(2)
+			if report := engine.Step(context.Background(), g, rules, 1); report.FishEaten != 0 {
+				t.Fatalf("a sated shark ate %d fish", report.FishEaten)
+			}
+			if shark.Sated != 1 {
+				t.Errorf("satiation = %d after a chronon, want 1", shark.Sated)
+			}
+
+			g, shark = surroundedShark(0)
+			if report := engine.Step(context.Background(), g, rules, 1); report.FishEaten != 1 {
+				t.Fatalf("a hungry shark ate %d fish, want 1", report.FishEaten)
+			}
+			if shark.Sated != rules.Satiation || shark.Energy != rules.StarveEnergy {
+				t.Errorf("after eating: sated %d, energy %d; want %d and %d", shark.Sated, shark.Energy, rules.Satiation, rules.StarveEnergy)
+			}
+		})
+	}
+}
+
+func TestSharkReach(t *testing.T) {
+	rules := Rules{SharkSpeed: 2, SharkVision: 3, Satiation: 1}
+	shark := &Shark{Sated: 1}
+	if speed, vision := rules.sharkReach(shark); speed != 2 || vision != 0 {
+		t.Errorf("sated wanderer: speed %d, vision %d; want 2 and 0", speed, vision)
+	}
+	if speed, vision := rules.sharkReach(shark); speed != 2 || vision != 3 {
+		t.Errorf("hungry again: speed %d, vision %d; want 2 and 3", speed, vision)
+	}
+	rules.SatedRest, shark.Sated = true, 1
+	if speed, _ := rules.sharkReach(shark); speed != 0 {
+		t.Errorf("sated rester: speed %d, want 0", speed)
+	}
+}
@@ -0,0 +1,298 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file scaling.go
+ * @brief Strong-scaling analysis of bench results (bench -report).
+ * @details Results are grouped by engine and pinning. Within a group the run with the fewest
+ * threads p0 is the baseline: the speed-up of n threads is T(p0)/T(n) and the efficiency
+ * divides it by n/p0, so 1 is perfect scaling. Two estimates of the serial fraction f are
+ * given: the Karp-Flatt metric of each run, e = (1/S - 1/p)/(1 - 1/p) with p = n/p0, which
+ * rising with n points at overhead growing with the thread count rather than a fixed serial
+ * part, and a least-squares fit of Amdahl's law T(n) = T(p0)(f + (1-f)/p) over the whole group,
+ * which bounds the speed-up at 1/f. The recommended thread count is the fewest that comes within
+ * scalingTolerance of the group's fastest run, since threads beyond it buy almost nothing.
+ */
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/** Fraction of the fastest rate within which fewer threads are recommended instead. */
+const scalingTolerance = 0.05
+
+/**
+ * @struct scalingRow
+ * @brief One bench result with its scaling figures.
+ */
+type scalingRow struct {
+	benchResult
+	Speedup    float64 ///< Over the group's baseline
+	Efficiency float64 ///< Speed-up per unit of added threads; 1 is perfect
+	KarpFlatt  float64 ///< Serial fraction estimated from this run; NaN for the baseline
+}
+
+/**
+ * @struct scalingGroup
+ * @brief The results of one engine and pinning mode, by thread count.
+ */
+type scalingGroup struct {
+	Engine         string
+	Pinned         bool
+	Base           int          ///< Thread count the speed-ups are relative to
+	Rows           []scalingRow ///< By increasing thread count
+	SerialFraction float64      ///< Amdahl fit; NaN with fewer than two thread counts
+	Fastest        int          ///< Thread count of the fastest run
+	Recommended    int          ///< Fewest threads within scalingTolerance of the fastest
+}
+
+/**
+ * @brief Returns the speed-up Amdahl's law allows the group at most, relative to its baseline.
+ */
+func (g scalingGroup) MaxSpeedup() float64 {
+	return 1 / g.SerialFraction
+}
+
+/**
+ * @brief Returns the row of a thread count.
+ */
+func (g scalingGroup) row(threads int) scalingRow {
+	i := slices.IndexFunc(g.Rows, func(r scalingRow) bool { return r.Threads == threads })
+	return g.Rows[i]
+}
+
+/**
+ * @brief Groups bench results by engine and pinning and computes their scaling.
+ * @details Repeated thread counts keep their fastest run; results without a time are skipped.
+ * @return The groups, in the order their engines were first benchmarked.
+ */
+func analyseScaling(results []benchResult) []scalingGroup {
+	var groups []scalingGroup
+	for _, r := range results {
+		if r.Best <= 0 || r.Threads < 1 {
+			continue
+		}
+		i := slices.IndexFunc(groups, func(g scalingGroup) bool { return g.Engine == r.Engine && g.Pinned == r.Pinned })
+		if i < 0 {
+			groups = append(groups, scalingGroup{Engine: r.Engine, Pinned: r.Pinned})
+			i = len(groups) - 1
+		}
+		g := &groups[i]
+		if j := slices.IndexFunc(g.Rows, func(s scalingRow) bool { return s.Threads == r.Threads }); j >= 0 {
+			if r.Best < g.Rows[j].Best {
+				g.Rows[j].benchResult = r
+			}
+			continue
+		}
+		g.Rows = append(g.Rows, scalingRow{benchResult: r})
+	}
+
+	for i := range groups {
+		g := &groups[i]
+		slices.SortFunc(g.Rows, func(a, b scalingRow) int { return a.Threads - b.Threads })
+		base := g.Rows[0]
+		g.Base, g.Fastest = base.Threads, base.Threads
+		var xs, ys []float64 ///< 1/p and T(n)/T(p0), for the Amdahl fit
+		for j := range g.Rows {
+			r := &g.Rows[j]
+			p := float64(r.Threads) / float64(base.Threads)
+			r.Speedup = base.Best.Seconds() / r.Best.Seconds()
+			r.Efficiency = r.Speedup / p
+			r.KarpFlatt = math.NaN()
+			if p > 1 {
+				r.KarpFlatt = (1/r.Speedup - 1/p) / (1 - 1/p)
+			}
+			xs, ys = append(xs, 1/p), append(ys, 1/r.Speedup)
+			if r.Best < g.row(g.Fastest).Best {
+				g.Fastest = r.Threads
+			}
+		}
+		g.SerialFraction = fitAmdahl(xs, ys)
+		fastest := g.row(g.Fastest).Best.Seconds()
+		for _, r := range g.Rows {
+			if fastest/r.Best.Seconds() >= 1-scalingTolerance {
+				g.Recommended = r.Threads
+				break
+			}
+		}
+	}
+	return groups
+}
+
+/**
+ * @brief Fits Amdahl's law y = f + (1-f)x to relative run times.
+ * @param xs 1/p for each run.
+ * @param ys The run's time relative to the baseline's.
+ * @return The serial fraction f clamped to [0, 1], or NaN if the xs do not vary.
+ */
+func fitAmdahl(xs, ys []float64) float64 {
+	// Least squares of y - x = f(1 - x) through the origin.
+	num, den := 0.0, 0.0
+	for i := range xs {
+		num += (ys[i] - xs[i]) * (1 - xs[i])
+		den += (1 - xs[i]) * (1 - xs[i])
+	}
+	if den == 0 {
+		return math.NaN()
+	}
+	return min(max(num/den, 0), 1)
+}
+
+/**
+ * @brief Returns the group with the fastest run of all.
+ */
+func fastestGroup(groups []scalingGroup) scalingGroup {
+	best := 0
+	for i, g := range groups {
+		if g.row(g.Fastest).Best < groups[best].row(groups[best].Fastest).Best {
+			best = i
+		}
+	}
+	return groups[best]
+}
+
+/**
+ * @brief Formats a figure, or "-" where it is undefined.
+ */
+func scalingFigure(v float64, format string) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "-"
+	}
+	return fmt.Sprintf(format, v)
+}
+
+/**
+ * @brief Describes the pinning mode of a group for headings.
+ */
+func (g scalingGroup) label() string {
+	if g.Pinned {
+		return g.Engine + " (pinned)"
+	}
+	return g.Engine
+}
+
+/**
+ * @brief Returns the recommendation of a report as one sentence.
+ */
+func scalingRecommendation(groups []scalingGroup) string {
+	g := fastestGroup(groups)
+	s := fmt.Sprintf("On this machine (%d CPUs), the fastest configuration was %s with %d thread(s)", runtime.NumCPU(), g.label(), g.Fastest)
+	if g.Recommended != g.Fastest {
+		s += fmt.Sprintf("; %d thread(s) come within %.0f%% of it and are the better choice", g.Recommended, scalingTolerance*100)
+	}
+	return s + "."
+}
+
+/**
+ * @brief Prints the scaling of every group after the bench table.
+ * @details Groups benchmarked at a single thread count have nothing to report and are skipped;
+ * when that is all of them, nothing is printed.
+ */
+func writeScalingText(w io.Writer, groups []scalingGroup) {
+	scaled := false
+	for _, g := range groups {
+		if len(g.Rows) < 2 {
+			continue
+		}
+		scaled = true
+		fmt.Fprintf(w, "\n%s: speed-up over %d thread(s)", g.label(), g.Base)
+		for _, r := range g.Rows {
+			fmt.Fprintf(w, "  %d: %.2fx (%.0f%%)", r.Threads, r.Speedup, r.Efficiency*100)
+		}
+		fmt.Fprintf(w, "\n  serial fraction %s, at most %sx; fastest at %d threads, recommended %d\n",
+			scalingFigure(g.SerialFraction, "%.3f"), scalingFigure(g.MaxSpeedup(), "%.1f"), g.Fastest, g.Recommended)
+	}
+	if scaled {
+		fmt.Fprintf(w, "\n%s\n", scalingRecommendation(groups))
+	}
+}
+
+/**
+ * @brief Writes a Markdown scaling report: the setup, a table per group and the recommendation.
+ * @param w Destination writer.
+ * @param cfg The benchmarked configuration.
+ * @param repeat Runs per combination.
+ * @param groups The analysed results.
+ */
+func writeScalingMarkdown(w io.Writer, cfg Config, repeat int, groups []scalingGroup) {
+	p := runProvenance()
+	fmt.Fprintf(w, "# Wa-Tor strong-scaling report\n\n")
+	fmt.Fprintf(w, "| Setting | Value |\n|---|---|\n")
+	fmt.Fprintf(w, "| Host | %s (%s/%s, %d CPUs, GOMAXPROCS %d) |\n", p.Host, runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), runtime.GOMAXPROCS(0))
+	fmt.Fprintf(w, "| Build | %s, %s |\n", p.commit(), p.GoVersion)
+	fmt.Fprintf(w, "| World | %dx%d, %d fish, %d sharks, storage %s |\n", cfg.GridSize, cfg.GridSize, cfg.NumFish, cfg.NumShark, cfg.Storage)
+	fmt.Fprintf(w, "| Runs | %d chronons, seed %d, best of %d |\n", cfg.Chronons, cfg.Seed, repeat)
+
+	for _, g := range groups {
+		fmt.Fprintf(w, "\n## %s\n\n", g.label())
+		fmt.Fprintf(w, "| Threads | Best time | Chronons/s | Speed-up | Efficiency | Karp-Flatt |\n|---:|---:|---:|---:|---:|---:|\n")
+		for _, r := range g.Rows {
+			fmt.Fprintf(w, "| %d | %v | %.1f | %.2fx | %.0f%% | %s |\n", r.Threads, r.Best.Round(time.Microsecond),
+				float64(r.Chronons)/r.Best.Seconds(), r.Speedup, r.Efficiency*100, scalingFigure(r.KarpFlatt, "%.3f"))
+		}
+		if len(g.Rows) < 2 {
+			fmt.Fprintf(w, "\nOnly %d thread(s) were benchmarked; give -thread-counts to measure scaling.\n", g.Base)
+			continue
+		}
+		fmt.Fprintf(w, "\nSpeed-ups are relative to %d thread(s). Fitting Amdahl's law gives a serial fraction of %s, "+
+			"so no thread count can run more than %sx faster than the baseline. The fastest run used %d threads; "+
+			"the fewest within %.0f%% of it is %d.\n",
+			g.Base, scalingFigure(g.SerialFraction, "%.3f"), scalingFigure(g.MaxSpeedup(), "%.1f"), g.Fastest, scalingTolerance*100, g.Recommended)
+	}
+	if len(groups) > 0 {
+		fmt.Fprintf(w, "\n## Recommendation\n\n%s\n", scalingRecommendation(groups))
+	}
+}
+
+/**
+ * @brief Writes the scaling figures as CSV, one row per engine, pinning mode and thread count.
+ * @details The group's Amdahl fit and recommendation are repeated on each of its rows.
+ */
+func writeScalingCSV(w io.Writer, groups []scalingGroup) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"engine", "pinned", "threads", "chronons", "best_seconds", "chronons_per_sec", "speedup", "efficiency",
+		"karp_flatt", "serial_fraction", "max_speedup", "recommended_threads"})
+	f := func(v float64) string {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', 4, 64)
+	}
+	for _, g := range groups {
+		for _, r := range g.Rows {
+			out.Write([]string{g.Engine, strconv.FormatBool(g.Pinned), strconv.Itoa(r.Threads), strconv.Itoa(r.Chronons),
+				f(r.Best.Seconds()), f(float64(r.Chronons) / r.Best.Seconds()), f(r.Speedup), f(r.Efficiency),
+				f(r.KarpFlatt), f(g.SerialFraction), f(g.MaxSpeedup()), strconv.Itoa(g.Recommended)})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+/**
+ * @brief Validates a -report value.
+ */
+func parseBenchReport(s string) (string, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "table", "markdown", "csv":
+		return s, nil
+	}
+	return "", fmt.Errorf("-report %q is not table, markdown or csv", s)
+}
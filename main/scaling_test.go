@@ -0,0 +1,112 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file scaling_test.go
+ * @brief Tests for the strong-scaling analysis of bench results.
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+/**
+ * @brief Returns bench results that follow Amdahl's law with serial fraction f exactly.
+ */
+func amdahlResults(engine string, f float64, threads ...int) []benchResult {
+	var out []benchResult
+	for _, n := range threads {
+		t := time.Duration(float64(time.Second) * (f + (1-f)/float64(n)))
+		out = append(out, benchResult{Engine: engine, Threads: n, Chronons: 100, Best: t})
+	}
+	return out
+}
+
+func TestAnalyseScalingRecoversSerialFraction(t *testing.T) {
+	results := append(amdahlResults("sections", 0.2, 4, 1, 2, 8), amdahlResults("moves", 0, 1)...)
+	results = append(results, benchResult{Engine: "sections", Threads: 2, Chronons: 100, Best: 2 * time.Second}) ///< A slower repeat is ignored
+	groups := analyseScaling(results)
+	if len(groups) != 2 || groups[0].Engine != "sections" || len(groups[0].Rows) != 4 {
+		t.Fatalf("groups %+v, want sections with 4 thread counts then moves", groups)
+	}
+	g := groups[0]
+	if g.Base != 1 || g.Rows[0].Threads != 1 || g.Rows[3].Threads != 8 {
+		t.Fatalf("sections rows %+v, want thread counts 1 to 8 from base 1", g.Rows)
+	}
+	if math.Abs(g.SerialFraction-0.2) > 1e-6 || math.Abs(g.MaxSpeedup()-5) > 1e-4 {
+		t.Errorf("serial fraction %v, max speed-up %v, want 0.2 and 5", g.SerialFraction, g.MaxSpeedup())
+	}
+	// 8 threads: T = 0.3s, S = 3.33, efficiency 0.417, and Karp-Flatt gives back f.
+	r := g.row(8)
+	if math.Abs(r.Speedup-1/0.3) > 1e-4 || math.Abs(r.Efficiency-1/2.4) > 1e-4 || math.Abs(r.KarpFlatt-0.2) > 1e-6 {
+		t.Errorf("8 threads %+v, want speed-up 3.33, efficiency 0.417, Karp-Flatt 0.2", r)
+	}
+	if !math.IsNaN(g.Rows[0].KarpFlatt) {
+		t.Errorf("baseline Karp-Flatt %v, want NaN", g.Rows[0].KarpFlatt)
+	}
+	if g.Fastest != 8 || g.Recommended != 8 {
+		t.Errorf("fastest %d, recommended %d, want 8 and 8", g.Fastest, g.Recommended)
+	}
+	if !math.IsNaN(groups[1].SerialFraction) {
+		t.Errorf("one thread count: serial fraction %v, want NaN", groups[1].SerialFraction)
+	}
+}
+
+func TestAnalyseScalingRecommendsFewestNearlyFastest(t *testing.T) {
+	// 4 threads are within 5% of 8, so 8 buys too little to be recommended.
+	results := []benchResult{
+		{Engine: "claims", Threads: 2, Chronons: 10, Best: 1000 * time.Millisecond},
+		{Engine: "claims", Threads: 4, Chronons: 10, Best: 520 * time.Millisecond},
+		{Engine: "claims", Threads: 8, Chronons: 10, Best: 500 * time.Millisecond},
+	}
+	g := analyseScaling(results)[0]
+	if g.Base != 2 || g.Fastest != 8 || g.Recommended != 4 {
+		t.Errorf("base %d, fastest %d, recommended %d, want 2, 8 and 4", g.Base, g.Fastest, g.Recommended)
+	}
+	if e := g.row(8).Efficiency; math.Abs(e-0.5) > 1e-9 {
+		t.Errorf("efficiency of 8 over 2 threads %v, want 0.5", e)
+	}
+}
+
+func TestScalingReports(t *testing.T) {
+	groups := analyseScaling(amdahlResults("sections", 0.25, 1, 2, 4))
+
+	var md bytes.Buffer
+	writeScalingMarkdown(&md, Config{GridSize: 50, NumFish: 100, NumShark: 10, Chronons: 100, Storage: "entities"}, 3, groups)
+	for _, want := range []string{"# Wa-Tor strong-scaling report", "| 4 | 437.5ms | 228.6 | 2.29x | 57% | 0.250 |",
+		"serial fraction of 0.250", "more than 4.0x", "## Recommendation", "sections with 4 thread(s)"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown lacks %q:\n%s", want, md.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeScalingCSV(&out, groups); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil || len(rows) != 4 {
+		t.Fatalf("csv %v, %v: want a header and 3 rows", rows, err)
+	}
+	if rows[1][8] != "" || rows[3][2] != "4" || rows[3][8] != "0.2500" || rows[3][9] != "0.2500" || rows[3][11] != "4" {
+		t.Errorf("csv rows %v", rows[1:])
+	}
+
+	if _, err := parseBenchReport("pdf"); err == nil {
+		t.Error("-report pdf accepted")
+	}
+}
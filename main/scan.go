@@ -0,0 +1,375 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file scan.go
+ * @brief The scan subcommand: a bifurcation scan over one parameter.
+ * @details Steps a parameter from -from to -to and runs every value headless, with -seeds
+ * seeds each. The first -transient chronons of a run are discarded and the rest are taken as
+ * its quasi-steady state, whose mean, minimum and maximum populations are reported: where the
+ * range opens up the populations oscillate, and where a population stays at zero the species
+ * dies out. The values between which a species goes from surviving to dying out (in most of
+ * the runs) are the extinction thresholds; they are printed to stderr and marked on the -diagram.
+ */
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+/**
+ * @struct ScanPoint
+ * @brief The steady state of one parameter value, over all its runs.
+ */
+type ScanPoint struct {
+	Value        float64 ///< Parameter value
+	Runs         int     ///< Runs of this value
+	FishFinal    float64 ///< Mean final fish population
+	SharkFinal   float64 ///< Mean final shark population
+	FishMean     float64 ///< Mean fish population after the transient
+	SharkMean    float64 ///< Mean shark population after the transient
+	FishMin      float64 ///< Lowest fish population after the transient, over all runs
+	FishMax      float64 ///< Highest fish population after the transient
+	SharkMin     float64 ///< Lowest shark population after the transient
+	SharkMax     float64 ///< Highest shark population after the transient
+	FishExtinct  int     ///< Runs in which the fish died out
+	SharkExtinct int     ///< Runs in which the sharks died out
+}
+
+/**
+ * @struct ScanThreshold
+ * @brief Adjacent parameter values between which a species starts or stops dying out.
+ */
+type ScanThreshold struct {
+	Species string  ///< "fish" or "sharks"
+	From    float64 ///< Value before the change
+	To      float64 ///< Value after the change
+	Dies    bool    ///< Whether the species dies out at To but not at From
+}
+
+/** CSV header of the scan table. */
+var scanColumns = []string{"param", "value", "runs", "fish_final", "sharks_final", "fish_mean", "sharks_mean",
+	"fish_min", "fish_max", "sharks_min", "sharks_max", "fish_extinct_runs", "sharks_extinct_runs"}
+
+/**
+ * @brief The scan subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdScan(args []string) int {
+	cf := newConfigFlags("scan", "Steps -param from -from to -to, runs each value past -transient and prints its steady-state populations as CSV.", os.Stderr)
+	param := cf.fs.String("param", "", "`name` of the parameter to scan: a positional name such as SharkBreed, or a flag such as shark-vision")
+	from := cf.fs.Float64("from", 0, "first `value` of the parameter")
+	to := cf.fs.Float64("to", 0, "last `value` of the parameter")
+	step := cf.fs.Float64("step", 1, "`increment` between values")
+	seeds := cf.fs.Int("seeds", 1, "runs per value, with seeds seed..seed+n-1")
+	transient := cf.fs.Int("transient", -1, "`chronons` discarded before the steady state is measured (default half of -chronons)")
+	diagram := cf.fs.String("diagram", "", "also draw the bifurcation diagram to the SVG `file`")
+	cfg, code, ok := setupCommand(cf, args)
+	if !ok {
+		return code
+	}
+	if *transient < 0 {
+		*transient = cfg.Chronons / 2
+	}
+	switch {
+	case *param == "" || *step <= 0 || *to < *from:
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\nscan needs -param, -from, -to of at least -from, and a positive -step")
+		return exitConfigError
+	case *seeds < 1 || *transient >= cfg.Chronons:
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\nscan needs -seeds of at least 1 and -transient below -chronons")
+		return exitConfigError
+	}
+
+	var values []float64
+	var configs []Config
+	for i := 0; ; i++ {
+		v := *from + float64(i)*(*step) ///< Multiplying avoids drift from repeated addition
+		if v > *to+*step*1e-9 {
+			break
+		}
+		c, err := cf.with(map[string]string{*param: formatValue(v)})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid parameters:\n%v\n", err)
+			return exitConfigError
+		}
+		values, configs = append(values, v), append(configs, c)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	points, err := runScan(ctx, *param, values, configs, cfg.Seed, *seeds, *transient, os.Stdout)
+	if err == nil {
+		for _, th := range scanThresholds(points) {
+			verb := "survive"
+			if th.Dies {
+				verb = "die out"
+			}
+			fmt.Fprintf(os.Stderr, "%s %s from %s=%s (not at %s)\n", th.Species, verb, *param, formatValue(th.To), formatValue(th.From))
+		}
+		if *diagram != "" {
+			err = writeScanPlotFile(*diagram, *param, points)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if ctx.Err() != nil {
+			return exitInterrupted
+		}
+		return exitFailure
+	}
+	return exitOK
+}
+
+/**
+ * @brief Formats a parameter value without trailing zeros, so whole numbers suit integer flags.
+ */
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+/**
+ * @brief Runs every configuration with every seed and writes one CSV row per value.
+ * @param ctx Context of the scan; cancelling it stops after the current run.
+ * @param param Name of the scanned parameter, for the first column.
+ * @param values The value of the parameter in each configuration.
+ * @param configs One configuration per value.
+ * @param seed The first seed.
+ * @param seeds Runs per configuration.
+ * @param transient Chronons discarded at the start of every run.
+ * @param w Destination of the CSV table.
+ * @return The points of the scan, or an error if a run failed, the scan was interrupted, or
+ * the table could not be written.
+ */
+func runScan(ctx context.Context, param string, values []float64, configs []Config, seed int64, seeds, transient int, w io.Writer) ([]ScanPoint, error) {
+	out := csv.NewWriter(w)
+	defer out.Flush()
+	out.Write(scanColumns)
+	points := make([]ScanPoint, 0, len(configs))
+	for i, c := range configs {
+		p := ScanPoint{Value: values[i], Runs: seeds, FishMin: math.Inf(1), SharkMin: math.Inf(1)}
+		for s := 0; s < seeds; s++ {
+			c.Seed = seed + int64(s)
+			sum, series, err := runRecorded(ctx, c, nil)
+			if err != nil {
+				return nil, err
+			}
+			if sum.Interrupted {
+				return nil, fmt.Errorf("scan interrupted")
+			}
+			p.FishFinal += float64(sum.Fish) / float64(seeds)
+			p.SharkFinal += float64(sum.Sharks) / float64(seeds)
+			settled := min(transient, series.Len()-1)
+			fish, sharks := series.Fish[settled:], series.Sharks[settled:]
+			p.FishMean += meanOf(fish) / float64(seeds)
+			p.SharkMean += meanOf(sharks) / float64(seeds)
+			for j := range fish {
+				p.FishMin, p.FishMax = math.Min(p.FishMin, fish[j]), math.Max(p.FishMax, fish[j])
+				p.SharkMin, p.SharkMax = math.Min(p.SharkMin, sharks[j]), math.Max(p.SharkMax, sharks[j])
+			}
+			if sum.Fish == 0 {
+				p.FishExtinct++
+			}
+			if sum.Sharks == 0 {
+				p.SharkExtinct++
+			}
+		}
+		points = append(points, p)
+		out.Write([]string{param, formatValue(p.Value), strconv.Itoa(p.Runs),
+			formatFloat(p.FishFinal), formatFloat(p.SharkFinal), formatFloat(p.FishMean), formatFloat(p.SharkMean),
+			formatFloat(p.FishMin), formatFloat(p.FishMax), formatFloat(p.SharkMin), formatFloat(p.SharkMax),
+			strconv.Itoa(p.FishExtinct), strconv.Itoa(p.SharkExtinct)})
+		out.Flush() ///< Rows appear as values finish
+	}
+	return points, out.Error()
+}
+
+/**
+ * @brief Formats a population statistic with one decimal.
+ */
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+/**
+ * @brief Finds where each species switches between surviving and dying out.
+ * @details A species counts as dying out at a value when it did so in more than half of
+ * the value's runs.
+ * @param points The scan, in parameter order.
+ * @return The thresholds, fish first, each in parameter order.
+ */
+func scanThresholds(points []ScanPoint) []ScanThreshold {
+	var thresholds []ScanThreshold
+	for _, sp := range []struct {
+		name    string
+		extinct func(ScanPoint) int
+	}{
+		{"fish", func(p ScanPoint) int { return p.FishExtinct }},
+		{"sharks", func(p ScanPoint) int { return p.SharkExtinct }},
+	} {
+		for i := 1; i < len(points); i++ {
+			before := 2*sp.extinct(points[i-1]) > points[i-1].Runs
+			after := 2*sp.extinct(points[i]) > points[i].Runs
+			if before != after {
+				thresholds = append(thresholds, ScanThreshold{sp.name, points[i-1].Value, points[i].Value, after})
+			}
+		}
+	}
+	return thresholds
+}
+
+/**
+ * @brief Writes the bifurcation diagram of a scan to a file.
+ * @param path Destination, normally ending in .svg.
+ * @param param Name of the scanned parameter, for the title and x axis.
+ * @param points The scan.
+ * @return An error if the file could not be written.
+ */
+func writeScanPlotFile(path, param string, points []ScanPoint) error {
+	f, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	if err := writeScanPlot(f, param, points); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/**
+ * @brief Draws the fish and shark panels of a bifurcation diagram as an SVG document.
+ * @details Each value gets a bar from the lowest to the highest steady-state population and
+ * a dot at the mean, joined by a line. Values at which the panel's species died out in most
+ * runs are shaded, and the thresholds are dashed lines half way between values.
+ * @param w Destination of the document.
+ * @param param Name of the scanned parameter.
+ * @param points The scan.
+ * @return Any write error.
+ */
+func writeScanPlot(w io.Writer, param string, points []ScanPoint) error {
+	out := bufio.NewWriter(w)
+	height := plotTop + 2*plotPanelHeight + 2*plotGap
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", plotWidth, height)
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(out, `<text x="%d" y="24" text-anchor="middle" font-size="15">Bifurcation scan of %s: steady-state range and mean</text>`+"\n", plotWidth/2, html.EscapeString(param))
+
+	thresholds := scanThresholds(points)
+	panels := []struct {
+		name, species string
+		value         func(ScanPoint) (lo, mean, hi float64, extinct bool)
+	}{
+		{"Fish", "fish", func(p ScanPoint) (float64, float64, float64, bool) {
+			return p.FishMin, p.FishMean, p.FishMax, 2*p.FishExtinct > p.Runs
+		}},
+		{"Sharks", "sharks", func(p ScanPoint) (float64, float64, float64, bool) {
+			return p.SharkMin, p.SharkMean, p.SharkMax, 2*p.SharkExtinct > p.Runs
+		}},
+	}
+	for i, panel := range panels {
+		top := plotTop + i*(plotPanelHeight+plotGap)
+		colour := plotColours[i]
+		var marks []ScanThreshold
+		for _, th := range thresholds {
+			if th.Species == panel.species {
+				marks = append(marks, th)
+			}
+		}
+		scanPanel(out, top, panel.name, param, colour, points, marks, panel.value)
+	}
+	fmt.Fprintln(out, "</svg>")
+	return out.Flush()
+}
+
+/**
+ * @brief Draws one panel of a bifurcation diagram.
+ * @param out Destination.
+ * @param top Y coordinate of the panel's top edge.
+ * @param name Panel title.
+ * @param param Name of the scanned parameter, for the x axis.
+ * @param colour Colour of the bars and mean line.
+ * @param points The scan.
+ * @param thresholds The panel's species' thresholds.
+ * @param value Extracts the range, mean and extinction of the panel's species.
+ */
+func scanPanel(out io.Writer, top int, name, param, colour string, points []ScanPoint, thresholds []ScanThreshold,
+	value func(ScanPoint) (lo, mean, hi float64, extinct bool)) {
+	width := float64(plotWidth - plotLeft - plotRight)
+	height := float64(plotPanelHeight)
+	first, last := points[0].Value, points[len(points)-1].Value
+	span := math.Max(last-first, 1)
+	yMax := 0.0
+	for _, p := range points {
+		_, _, hi, _ := value(p)
+		yMax = max(yMax, hi)
+	}
+	yStep := niceStep(yMax, 5)
+	yMax = math.Max(yStep, math.Ceil(yMax/yStep)*yStep)
+	margin := width * 0.04 ///< Keeps the end bars off the frame
+	px := func(v float64) float64 { return plotLeft + margin + (v-first)/span*(width-2*margin) }
+	py := func(v float64) float64 { return float64(top) + height - v/yMax*height }
+
+	fmt.Fprintf(out, `<text x="%d" y="%d" font-weight="bold">%s</text>`+"\n", plotLeft, top-6, name)
+	half := margin
+	if len(points) > 1 {
+		half = (px(points[1].Value) - px(first)) / 2
+	}
+	for _, p := range points {
+		if _, _, _, extinct := value(p); extinct {
+			x0 := math.Max(px(p.Value)-half, plotLeft)
+			x1 := math.Min(px(p.Value)+half, plotLeft+width)
+			fmt.Fprintf(out, `<rect x="%.1f" y="%d" width="%.1f" height="%.0f" fill="#f4c7c3"/>`+"\n", x0, top, x1-x0, height)
+		}
+	}
+	for v := 0.0; v <= yMax+yStep/2; v += yStep {
+		fmt.Fprintf(out, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n", plotLeft, py(v), plotWidth-plotRight, py(v))
+		fmt.Fprintf(out, `<text x="%d" y="%.1f" text-anchor="end">%g</text>`+"\n", plotLeft-6, py(v)+4, v)
+	}
+	labelEvery := max(1, len(points)/12)
+	for i, p := range points {
+		if i%labelEvery == 0 {
+			fmt.Fprintf(out, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`+"\n", px(p.Value), float64(top)+height+16, formatValue(p.Value))
+		}
+	}
+	fmt.Fprintf(out, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`+"\n", plotWidth-plotRight, float64(top)+height+32, html.EscapeString(param))
+	fmt.Fprintf(out, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="#444"/>`+"\n", plotLeft, top, width, height)
+
+	var mid []string
+	for _, p := range points {
+		lo, mean, hi, _ := value(p)
+		x := px(p.Value)
+		fmt.Fprintf(out, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="3" stroke-opacity="0.4"/>`+"\n", x, py(lo), x, py(hi), colour)
+		fmt.Fprintf(out, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`+"\n", x, py(mean), colour)
+		mid = append(mid, fmt.Sprintf("%.1f,%.1f", x, py(mean)))
+	}
+	fmt.Fprintf(out, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`+"\n", strings.Join(mid, " "), colour)
+
+	for _, th := range thresholds {
+		x := (px(th.From) + px(th.To)) / 2
+		label := "survives"
+		if th.Dies {
+			label = "dies out"
+		}
+		fmt.Fprintf(out, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.0f" stroke="#c0392b" stroke-dasharray="5,4"/>`+"\n", x, top, x, float64(top)+height)
+		fmt.Fprintf(out, `<text x="%.1f" y="%d" fill="#c0392b">%s</text>`+"\n", x+4, top+14, label)
+	}
+}
@@ -0,0 +1,308 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file script.go
+ * @brief Scenario scripts: timed perturbations of a run (the -script option).
+ * @details A script is a text file with one event per line:
+ *
+ *     at chronon 100 add 50 sharks in (10,10)-(30,30)
+ *     at chronon 150 remove all fish in (0,0)-(9,99)
+ *     at chronon 200 set FishBreed=5 shark-vision=3
+ *     at chronon 250 tide in (40,40)-(44,44)
+ *
+ * An event at chronon N is applied to the world as it stands after chronon N (0 is the
+ * initial grid), before chronon N+1 is computed. Entities are added to or removed from
+ * cells chosen with a source seeded from the run's seed, so a scripted run is as
+ * reproducible as an unscripted one.
+ */
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Parameters a script may set: those that only change the rules, plus the worker count.
+ * Names are written as in -pipe and sweep: positional names or flag names without the dash.
+ */
+var scriptParams = []string{"FishBreed", "SharkBreed", "Starve", "Threads",
+	"fish-speed", "shark-speed", "shark-vision", "move-cost", "stay-cost", "birth-share",
+	"crowding-k", "crowding-death", "fish-maturity", "juvenile-energy", "satiation", "sated-rest",
+	"tide-prob", "tide-spread", "tide-decay", "tide-kill", "temperature-effect", "ruleset", "fish-breed-prob", "shark-breed-prob", "starve-prob"}
+
+/**
+ * @struct ScriptEvent
+ * @brief One line of a scenario script.
+ */
+type ScriptEvent struct {
+	Line    int     ///< Line number in the script, for messages and per-event seeding
+	Text    string  ///< The line as written
+	Chronon int     ///< Applied after this chronon
+	Action  string  ///< "add", "remove", "set" or "tide"
+	Count   int     ///< Entities to add or remove; -1 removes all (a tide covers the region)
+	Species Species ///< Species added or removed
+	X1, Y1  int     ///< First corner of the region, inclusive
+	X2, Y2  int     ///< Opposite corner of the region, inclusive
+	Before  Rules   ///< Rules the set was made from
+	Rules   Rules   ///< Rules with the set applied
+	Threads int     ///< Worker count after a set (0 leaves it unchanged)
+}
+
+/**
+ * @struct Script
+ * @brief A parsed scenario script, ordered by chronon.
+ */
+type Script struct {
+	Events []ScriptEvent
+	next   int ///< Index of the first event not yet applied or skipped
+}
+
+/**
+ * @brief Reads and checks a scenario script against a configuration.
+ * @param path The script file.
+ * @param cfg The configuration of the run; sets are checked against it in chronon order.
+ * @return The script, or an error naming every bad line.
+ */
+func loadScript(path string, cfg Config) (*Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening script: %w", err)
+	}
+	defer f.Close()
+	script, err := parseScript(f, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return script, nil
+}
+
+/**
+ * @brief Parses a scenario script.
+ * @details Blank lines and lines starting with # are ignored. Events on the same chronon
+ * keep their order in the file.
+ * @param r The script text.
+ * @param cfg The configuration of the run, giving the grid size and the starting rules.
+ * @return The script, or an error naming every bad line.
+ */
+func parseScript(r io.Reader, cfg Config) (*Script, error) {
+	var events []ScriptEvent
+	var errs []error
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		ev, err := parseScriptLine(text, cfg.GridSize)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		ev.Line, ev.Text = line, text
+		events = append(events, ev)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(events, func(a, b ScriptEvent) int { return cmp.Compare(a.Chronon, b.Chronon) })
+
+	cf := newConfigFlags("script", "", io.Discard)
+	*cf.cfg, *cf.colour.noColor, *cf.colour.force = cfg, false, true ///< The theme is already resolved
+	for i := range events {
+		ev := &events[i]
+		if ev.Action != "set" {
+			continue
+		}
+		params := make(map[string]string)
+		for _, kv := range strings.Fields(ev.Text)[4:] {
+			name, value, _ := strings.Cut(kv, "=")
+			params[name] = value
+		}
+		next, err := cf.with(params)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", ev.Line, err))
+			continue
+		}
+		ev.Before, ev.Rules = cf.cfg.Rules(), next.Rules()
+		*cf.cfg = next ///< Later sets start from this one
+		if _, ok := params["Threads"]; ok {
+			ev.Threads = next.Threads
+		}
+	}
+	return &Script{Events: events}, errors.Join(errs...)
+}
+
+/**
+ * @brief Parses one event line, apart from the values of a set.
+ * @param text The trimmed line.
+ * @param size The grid size, which regions must lie within.
+ * @return The event without its line number and text.
+ */
+func parseScriptLine(text string, size int) (ScriptEvent, error) {
+	words := strings.Fields(text)
+	if len(words) < 4 || words[0] != "at" || words[1] != "chronon" {
+		return ScriptEvent{}, errors.New(`expected "at chronon <n> add|remove|set|tide ..."`)
+	}
+	var ev ScriptEvent
+	var err error
+	if ev.Chronon, err = strconv.Atoi(words[2]); err != nil || ev.Chronon < 0 {
+		return ev, fmt.Errorf("chronon must be a whole number of at least 0, got %q", words[2])
+	}
+	ev.Action, words = words[3], words[4:]
+
+	switch ev.Action {
+	case "set":
+		if len(words) == 0 {
+			return ev, errors.New("set needs at least one <param>=<value>")
+		}
+		for _, kv := range words {
+			name, _, ok := strings.Cut(kv, "=")
+			if !ok {
+				return ev, fmt.Errorf("expected <param>=<value>, got %q", kv)
+			}
+			if !slices.Contains(scriptParams, name) {
+				return ev, fmt.Errorf("cannot set %q during a run; settable parameters: %s", name, strings.Join(scriptParams, ", "))
+			}
+		}
+		return ev, nil
+	case "tide":
+		ev.Count, ev.X2, ev.Y2 = 1, size-1, size-1 ///< Without a region, a bloom at one random cell
+		if len(words) == 0 {
+			return ev, nil
+		}
+		if len(words) != 2 || words[0] != "in" {
+			return ev, errors.New(`expected "tide [in (x1,y1)-(x2,y2)]"`)
+		}
+		ev.Count = -1
+		return ev, ev.parseRegion(words[1], size)
+	case "add", "remove":
+	default:
+		return ev, fmt.Errorf("unknown action %q (want add, remove, set or tide)", ev.Action)
+	}
+
+	if len(words) != 2 && !(len(words) == 4 && words[2] == "in") {
+		return ev, fmt.Errorf(`expected "%s <n> fish|sharks [in (x1,y1)-(x2,y2)]"`, ev.Action)
+	}
+	if words[0] == "all" && ev.Action == "remove" {
+		ev.Count = -1
+	} else if ev.Count, err = strconv.Atoi(words[0]); err != nil || ev.Count < 0 {
+		return ev, fmt.Errorf("count must be a whole number of at least 0, got %q", words[0])
+	}
+	switch words[1] {
+	case "fish":
+		ev.Species = SpeciesFish
+	case "shark", "sharks":
+		ev.Species = SpeciesShark
+	default:
+		return ev, fmt.Errorf("species must be fish or sharks, got %q", words[1])
+	}
+
+	ev.X2, ev.Y2 = size-1, size-1
+	if len(words) == 4 {
+		return ev, ev.parseRegion(words[3], size)
+	}
+	return ev, nil
+}
+
+/**
+ * @brief Sets the event's region from a word such as "(10,10)-(30,30)".
+ * @param word The region as written.
+ * @param size The grid size, which the region must lie within.
+ */
+func (ev *ScriptEvent) parseRegion(word string, size int) error {
+	if _, err := fmt.Sscanf(word, "(%d,%d)-(%d,%d)", &ev.X1, &ev.Y1, &ev.X2, &ev.Y2); err != nil {
+		return fmt.Errorf("region must look like (x1,y1)-(x2,y2), got %q", word)
+	}
+	if ev.X1 < 0 || ev.Y1 < 0 || ev.X2 >= size || ev.Y2 >= size || ev.X1 > ev.X2 || ev.Y1 > ev.Y2 {
+		return fmt.Errorf("region %s must have its first corner above and left of the second, inside the %dx%d grid", word, size, size)
+	}
+	return nil
+}
+
+/**
+ * @brief Applies the events due at a frame's chronon.
+ * @details Used as a chronon-start hook. Events before the frame's chronon (e.g. when
+ * resuming from a checkpoint) are skipped.
+ * @param sim The simulation being scripted.
+ * @param seed Seed the per-event cell choices are derived from.
+ * @param f The frame about to be stepped.
+ */
+func (s *Script) apply(sim *Simulation, seed int64, f *Frame) {
+	for ; s.next < len(s.Events) && s.Events[s.next].Chronon <= f.Chronon(); s.next++ {
+		ev := s.Events[s.next]
+		if ev.Chronon < f.Chronon() {
+			slog.Warn("script event skipped: the run started after its chronon", "line", ev.Line, "chronon", ev.Chronon)
+			continue
+		}
+		switch ev.Action {
+		case "set":
+			sim.ChangeRules(ev.Before, ev.Rules) ///< Only what the set changed, so console sets stay in force
+			if ev.Threads > 0 {
+				sim.SetThreads(ev.Threads)
+			}
+			slog.Info("script", "chronon", ev.Chronon, "event", ev.Text)
+		case "tide":
+			x1, y1, x2, y2 := ev.X1, ev.Y1, ev.X2, ev.Y2
+			if ev.Count > 0 {
+				rng := rand.New(rand.NewSource(seed ^ int64(ev.Line)<<32))
+				x1, y1 = x1+rng.Intn(x2-x1+1), y1+rng.Intn(y2-y1+1)
+				x2, y2 = x1, y1
+			}
+			sim.Bloom(x1, y1, x2, y2)
+			slog.Info("script", "chronon", ev.Chronon, "event", ev.Text, "x", x1, "y", y1)
+		default:
+			rng := rand.New(rand.NewSource(seed ^ int64(ev.Line)<<32))
+			edits := ev.edits(sim.Snapshot(), rng)
+			sim.Apply(edits...)
+			slog.Info("script", "chronon", ev.Chronon, "event", ev.Text, "cells", len(edits))
+			if ev.Count > 0 && len(edits) < ev.Count {
+				slog.Warn("script event changed fewer cells than asked", "line", ev.Line, "asked", ev.Count, "changed", len(edits))
+			}
+		}
+	}
+}
+
+/**
+ * @brief Chooses the cells an add or remove event changes.
+ * @param f The current frame.
+ * @param rng Source for choosing among the candidate cells.
+ * @return One edit per chosen cell; fewer than Count if the region runs out of candidates.
+ */
+func (ev ScriptEvent) edits(f *Frame, rng *rand.Rand) []CellEdit {
+	want, put := SpeciesNone, ev.Species ///< An add fills empty cells
+	if ev.Action == "remove" {
+		want, put = ev.Species, SpeciesNone
+	}
+	var edits []CellEdit
+	for x := ev.X1; x <= ev.X2; x++ {
+		for y := ev.Y1; y <= ev.Y2; y++ {
+			if f.At(x, y) == want {
+				edits = append(edits, CellEdit{X: x, Y: y, Species: put})
+			}
+		}
+	}
+	if ev.Count < 0 || ev.Count >= len(edits) {
+		return edits
+	}
+	rng.Shuffle(len(edits), func(i, j int) { edits[i], edits[j] = edits[j], edits[i] })
+	return edits[:ev.Count]
+}
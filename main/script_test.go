@@ -0,0 +1,116 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file script_test.go
+ * @brief Tests for scenario scripts.
+ */
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	src := `# perturbations
+at chronon 5 set FishBreed=6 shark-vision=2
+at chronon 2 add 10 sharks in (0,0)-(4,4)
+at chronon 5 set Threads=1
+at chronon 3 remove all fish
+at chronon 7 tide in (2,2)-(3,3)
+`
+	s, err := parseScript(strings.NewReader(src), testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chronons []int
+	for _, ev := range s.Events {
+		chronons = append(chronons, ev.Chronon)
+	}
+	if !slices.Equal(chronons, []int{2, 3, 5, 5, 7}) {
+		t.Fatalf("events in order %v, want [2 3 5 5 7]", chronons)
+	}
+	add, remove, set, threads := s.Events[0], s.Events[1], s.Events[2], s.Events[3]
+	if add.Count != 10 || add.Species != SpeciesShark || add.X2 != 4 || add.Y2 != 4 {
+		t.Errorf("add parsed as %+v", add)
+	}
+	if remove.Count != -1 || remove.Species != SpeciesFish || remove.X2 != 19 || remove.Y2 != 19 {
+		t.Errorf("remove without a region should cover the grid, got %+v", remove)
+	}
+	if set.Rules.FishBreed != 6 || set.Rules.SharkVision != 2 || set.Threads != 0 {
+		t.Errorf("set parsed as %+v", set)
+	}
+	if threads.Rules.FishBreed != 6 || threads.Threads != 1 {
+		t.Errorf("a later set should keep earlier values: %+v", threads)
+	}
+	if tide := s.Events[4]; tide.Count != -1 || tide.X1 != 2 || tide.Y2 != 3 {
+		t.Errorf("tide parsed as %+v", tide)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	for _, src := range []string{
+		"after 5 add 1 fish",
+		"at chronon x add 1 fish",
+		"at chronon 1 grow 1 fish",
+		"at chronon 1 add all fish",
+		"at chronon 1 add 1 crabs",
+		"at chronon 1 add 1 fish in (5,5)-(1,1)",
+		"at chronon 1 add 1 fish in (0,0)-(20,20)",
+		"at chronon 1 set GridSize=50",
+		"at chronon 1 set FishBreed=0",
+		"at chronon 1 tide at (0,0)-(1,1)",
+	} {
+		if _, err := parseScript(strings.NewReader(src), testConfig()); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+}
+
+func TestScriptedRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.txt")
+	src := "at chronon 0 remove all sharks\nat chronon 3 add 25 sharks in (0,0)-(9,9)\nat chronon 4 set Starve=9\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.Script, cfg.Threads = path, 1 ///< One thread makes the engine reproducible
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sharks []int
+	sim.OnChrononEnd(func(f *Frame, _ StepReport) {
+		_, n := f.Counts()
+		sharks = append(sharks, n)
+	})
+	sim.Run(context.Background(), 5)
+	if sharks[0] != 0 || sharks[2] != 0 || sharks[3] == 0 {
+		t.Errorf("sharks per chronon %v: want none until 25 are added after chronon 3", sharks)
+	}
+	if sim.Rules().StarveEnergy != 9 {
+		t.Errorf("Starve is %d after the set, want 9", sim.Rules().StarveEnergy)
+	}
+
+	again, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Run(context.Background(), 5)
+	if a, b := again.Checkpoint().Entities, sim.Checkpoint().Entities; !slices.Equal(a, b) {
+		t.Errorf("scripted runs with the same seed differ: %s", describeDifference(a, b))
+	}
+}
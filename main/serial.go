@@ -0,0 +1,67 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file serial.go
+ * @brief The "serial" reference engine for validating the deterministic engine.
+ * @details Deliberately simple: one goroutine visits the cells in the update order and, for
+ * each entity, plans and immediately commits its move with the same per-cell random
+ * streams as the deterministic engine. There are no workers, row bands, per-band plan lists
+ * or ordering to get wrong, so any difference between the two engines is a bug in the
+ * concurrent implementation. Both share the rule code (planCell and commitMove), so this
+ * checks the parallel execution rather than the rules themselves. Planning only reads the
+ * current grid, which is why planning and committing cell by cell gives the same result as
+ * planning everything first.
+ */
+package main
+
+import (
+	"context"
+	"math/rand"
+)
+
+/**
+ * @struct serialEngine
+ * @brief Single-threaded reference for the deterministic engine.
+ */
+type serialEngine struct{}
+
+func (serialEngine) Name() string { return "serial" }
+
+/**
+ * @brief Runs one chronon on the calling goroutine; the thread count is ignored.
+ */
+func (serialEngine) Step(_ context.Context, g *Grid, rules Rules, _ int) StepReport {
+	base := g.rng.Int63()
+	chronon := g.Chronon + 1
+	newGrid := g.emptyLike()
+	fates := make([][]fishFate, g.Size)
+	for i := range fates {
+		fates[i] = make([]fishFate, g.Size)
+	}
+	tally := workerTally{chronon: chronon}
+	src := &cellSource{}
+	rng := rand.New(src)
+
+	g.eachCell(g.scanOrder(rules.Order), orderRand(base, chronon), rowRange{End: g.Size}, SpeciesNone, func(x, y int) {
+		src.seek(base, chronon, x, y, streamPlan)
+		m, ok := g.planCell(rng, x, y, rules)
+		if !ok {
+			return
+		}
+		src.seek(base, chronon, x, y, streamCommit)
+		g.commitMove(newGrid, rng, fates, &tally, m, rules)
+	})
+
+	g.store = newGrid.store
+	g.Chronon++
+	return StepReport{StepCounts: tally.StepCounts, Events: tally.Events}
+}
@@ -0,0 +1,61 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file series.go
+ * @brief The population time series of a run, kept for analysis after it ends.
+ */
+package main
+
+/**
+ * @struct PopulationSeries
+ * @brief Fish and shark counts of every chronon, starting with the initial state.
+ */
+type PopulationSeries struct {
+	Start  int       ///< Chronon of the first sample
+	Fish   []float64 ///< Fish population per chronon
+	Sharks []float64 ///< Shark population per chronon
+}
+
+/**
+ * @brief Appends a frame's populations.
+ * @param f The frame; frames must arrive in chronon order without gaps.
+ */
+func (s *PopulationSeries) Observe(f *Frame) {
+	fish, sharks := f.Counts()
+	s.Add(Population{Chronon: f.Chronon(), Fish: fish, Sharks: sharks})
+}
+
+/**
+ * @brief Appends populations taken from Simulation.Population or a stats hook.
+ * @param p The populations; they must arrive in chronon order without gaps.
+ */
+func (s *PopulationSeries) Add(p Population) {
+	if len(s.Fish) == 0 {
+		s.Start = p.Chronon
+	}
+	s.Fish = append(s.Fish, float64(p.Fish))
+	s.Sharks = append(s.Sharks, float64(p.Sharks))
+}
+
+/**
+ * @brief Appends the populations after a chronon; usable as a chronon-end hook.
+ */
+func (s *PopulationSeries) Record(f *Frame, _ StepReport) {
+	s.Observe(f)
+}
+
+/**
+ * @brief Returns the number of samples.
+ */
+func (s *PopulationSeries) Len() int {
+	return len(s.Fish)
+}
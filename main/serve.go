@@ -0,0 +1,275 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file serve.go
+ * @brief The serve subcommand: runs a simulation and serves its frames over HTTP.
+ * @details The simulation advances in the background at a fixed interval. Handlers only
+ * read Snapshot frames, so any number of clients can poll without slowing the workers.
+ *   GET /            the current frame as a plain-text map
+ *   GET /frame.json  the current frame and populations as JSON
+ *   GET /metrics     worker timings in the Prometheus text format
+ *   /grafana/...     recent populations and rates for the Grafana JSON datasource (see grafana.go)
+ * With -tenants, clients may also create and step worlds of their own under /sims (see tenants.go).
+ * -api-keys, -tls-cert and -client-ca keep strangers out (see auth.go), while -spectate-addr
+ * streams frames to anyone on a separate read-only listener (see spectator.go).
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+/**
+ * @struct frameDocument
+ * @brief JSON form of a frame served on /frame.json.
+ */
+type frameDocument struct {
+	Chronon         int      `json:"chronon"`
+	Size            int      `json:"size"`
+	Fish            int      `json:"fish"`
+	Sharks          int      `json:"sharks"`
+	MeanSharkEnergy float64  `json:"mean_shark_energy"`
+	Rows            []string `json:"rows"` ///< One string per row: F fish, S shark, . empty
+}
+
+/**
+ * @brief Converts a frame to its JSON form.
+ */
+func newFrameDocument(f *Frame) frameDocument {
+	fish, sharks := f.Counts()
+	doc := frameDocument{Chronon: f.Chronon(), Size: f.Size(), Fish: fish, Sharks: sharks, MeanSharkEnergy: f.MeanSharkEnergy()}
+	row := make([]byte, f.Size())
+	for x := 0; x < f.Size(); x++ {
+		for y := 0; y < f.Size(); y++ {
+			row[y] = ".FS"[f.At(x, y)]
+		}
+		doc.Rows = append(doc.Rows, string(row))
+	}
+	return doc
+}
+
+/**
+ * @brief Builds the HTTP handlers for a simulation.
+ * @param sim The simulation to serve.
+ * @param stats Worker timings for /metrics.
+ * @return The handler.
+ */
+func newServeMux(sim *Simulation, stats *WorkerStats) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		f := sim.Snapshot()
+		fish, sharks := f.Counts()
+		fmt.Fprintf(w, "Step %d: %d fish, %d sharks\n", f.Chronon(), fish, sharks)
+		writeFrame(w, f, themes["ascii"], 0)
+	})
+	mux.HandleFunc("GET /frame.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newFrameDocument(sim.Snapshot()))
+	})
+	mux.Handle("GET /metrics", stats)
+	return mux
+}
+
+/**
+ * @brief The serve subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdServe(args []string) int {
+	cf := newConfigFlags("serve", "Runs a simulation in the background and serves its frames on /, /frame.json and /metrics. -chronons 0 runs until interrupted.", os.Stderr)
+	addr := cf.fs.String("addr", ":8080", "listen `address`")
+	interval := cf.fs.Duration("interval", 200*time.Millisecond, "time between chronons")
+	stateDir := cf.fs.String("state-dir", "", "run as a service keeping a checkpoint and a rotated log in `dir`, resuming from the checkpoint on startup")
+	every := cf.fs.Duration("checkpoint-every", time.Minute, "interval between -state-dir checkpoints")
+	logMaxSize := cf.fs.Int64("log-max-size", 10<<20, "rotate the -state-dir log once it passes this many `bytes`")
+	logKeep := cf.fs.Int("log-keep", 3, "`number` of rotated -state-dir logs to keep")
+	tenants := cf.fs.Bool("tenants", false, "let clients create, step and delete simulations of their own under /sims, named by the X-Wator-User header")
+	var limits TenantLimits
+	cf.fs.IntVar(&limits.MaxGridSize, "max-grid", 200, "with -tenants, the largest GridSize a client's simulation may have")
+	cf.fs.IntVar(&limits.MaxSteps, "max-steps", 10000, "with -tenants, the `chronons` a client's simulation may run in total")
+	cf.fs.IntVar(&limits.MaxWorkers, "max-workers", 2, "with -tenants, the worker threads a client's simulation may use; larger Threads are lowered to it")
+	cf.fs.IntVar(&limits.MaxPerUser, "max-sims", 3, "with -tenants, the `number` of simulations a client may hold at once")
+	cf.fs.DurationVar(&limits.IdleTimeout, "idle-timeout", 10*time.Minute, "with -tenants, delete a client's simulation after this long without requests")
+	apiKeys := cf.fs.String("api-keys", "", "require every request to carry one of the keys in `file`, one \"<user> <key>\" per line, as Authorization: Bearer <key> or X-API-Key")
+	tlsCert := cf.fs.String("tls-cert", "", "serve HTTPS with the PEM certificate in `file` (needs -tls-key)")
+	tlsKey := cf.fs.String("tls-key", "", "PEM private key `file` of -tls-cert")
+	clientCA := cf.fs.String("client-ca", "", "with -tls-cert, require client certificates signed by the PEM authority in `file` (mutual TLS)")
+	spectateAddr := cf.fs.String("spectate-addr", "", "stream downsampled frames read-only over WebSocket to anyone on `address`/ws, without authentication")
+	spectateFPS := cf.fs.Float64("spectate-fps", 5, "with -spectate-addr, most frames sent per second")
+	spectateSize := cf.fs.Int("spectate-size", 100, "with -spectate-addr, downsample larger grids to at most this many `cells` per side")
+	spectateMax := cf.fs.Int("spectate-max", 1000, "with -spectate-addr, most spectators connected at once")
+	grafanaSamples := cf.fs.Int("grafana-samples", 20000, "`chronons` of populations and rates kept for the Grafana JSON datasource on /grafana (0 disables it)")
+	cfg, code, ok := setupCommand(cf, args)
+	if !ok {
+		return code
+	}
+	if *spectateAddr != "" && (*spectateFPS <= 0 || *spectateSize < 1 || *spectateMax < 1) {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\n-spectate-fps, -spectate-size and -spectate-max must be positive")
+		return exitConfigError
+	}
+	if *grafanaSamples < 0 {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\n-grafana-samples must be at least 0")
+		return exitConfigError
+	}
+	if (*tlsCert == "") != (*tlsKey == "") || (*clientCA != "" && *tlsCert == "") {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\n-tls-cert and -tls-key go together, and -client-ca needs them")
+		return exitConfigError
+	}
+	var keys *APIKeys
+	if *apiKeys != "" {
+		var err error
+		if keys, err = readAPIKeys(*apiKeys); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid parameters:\n-api-keys: %v\n", err)
+			return exitConfigError
+		}
+	}
+	if *tenants && (limits.MaxGridSize < 1 || limits.MaxSteps < 1 || limits.MaxWorkers < 1 || limits.MaxPerUser < 1 || limits.IdleTimeout <= 0) {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\n-max-grid, -max-steps, -max-workers, -max-sims and -idle-timeout must be positive")
+		return exitConfigError
+	}
+	if *stateDir != "" {
+		if *every <= 0 || *logMaxSize < 1 || *logKeep < 0 {
+			fmt.Fprintln(os.Stderr, "Invalid parameters:\n-checkpoint-every and -log-max-size must be positive and -log-keep at least 0")
+			return exitConfigError
+		}
+		if err := os.MkdirAll(*stateDir, 0o755); err != nil {
+			slog.Error("state directory unusable", "err", err)
+			return exitFailure
+		}
+		logFile, err := openRotatingFile(filepath.Join(*stateDir, stateLog), *logMaxSize, *logKeep)
+		if err != nil {
+			slog.Error("state log failed", "err", err)
+			return exitFailure
+		}
+		defer logFile.Close()
+		setupLoggerTo(io.MultiWriter(os.Stderr, logFile), cfg.LogLevel, cfg.LogJSON) ///< Level already validated
+		resume, err := stateResume(*stateDir)
+		if err != nil {
+			slog.Error("state checkpoint unusable", "err", err)
+			return exitFailure
+		}
+		if resume != "" && cfg.Resume == "" {
+			if cfg, err = cf.parse(append([]string{"-resume", resume}, args...)); err != nil {
+				slog.Error("resuming from the state directory failed", "err", err)
+				return exitConfigError
+			}
+			slog.Info("resuming", "checkpoint", resume)
+		}
+	}
+
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		slog.Error("simulation setup failed", "err", err)
+		return exitConfigError
+	}
+	stats := &WorkerStats{}
+	sim.OnStats(func(_ Population, report StepReport) { stats.Record(report.WorkerTimes) })
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		slog.Error("listen failed", "err", err)
+		return exitFailure
+	}
+	mux := newServeMux(sim, stats)
+	if *grafanaSamples > 0 {
+		series := NewTimeSeries(*grafanaSamples)
+		sim.OnStats(series.Record)
+		series.Register(mux)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *tenants {
+		t := NewTenants(limits)
+		t.Register(mux)
+		go t.Run(ctx)
+	}
+	if *spectateAddr != "" {
+		sln, err := net.Listen("tcp", *spectateAddr)
+		if err != nil {
+			slog.Error("spectator listen failed", "err", err)
+			return exitFailure
+		}
+		hub := NewSpectatorHub(*spectateMax)
+		smux := http.NewServeMux()
+		smux.Handle("GET /ws", hub)
+		spectators := &http.Server{Handler: smux}
+		go hub.Run(ctx, sim, *spectateFPS, *spectateSize)
+		go spectators.Serve(sln)
+		defer spectators.Close()
+		slog.Info("streaming to spectators", "addr", sln.Addr().String(), "fps", *spectateFPS, "size", *spectateSize)
+	}
+	server := &http.Server{Handler: mux}
+	if keys != nil || *clientCA != "" {
+		server.Handler = requireAuth(mux, keys)
+	}
+	if *tlsCert != "" {
+		if server.TLSConfig, err = serverTLSConfig(*clientCA); err != nil {
+			slog.Error("TLS setup failed", "err", err)
+			return exitConfigError
+		}
+	}
+	slog.Info("serving", "addr", ln.Addr().String(), "seed", cfg.Seed, "tenants", *tenants,
+		"tls", *tlsCert != "", "client_certs", *clientCA != "", "api_keys", keys != nil)
+	if keys == nil && *clientCA == "" && !isLoopback(ln.Addr()) {
+		slog.Warn("serving without authentication; anyone who can reach the address can use it", "addr", ln.Addr().String())
+	}
+
+	go func() {
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		for n := 0; cfg.Chronons == 0 || n < cfg.Chronons; n++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sim.Step(ctx)
+			}
+		}
+		slog.Info("simulation finished; still serving the final frame", "chronons", cfg.Chronons)
+	}()
+	if *stateDir != "" {
+		go checkpointEvery(ctx, sim, *stateDir, *every)
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	if *tlsCert != "" {
+		err = server.ServeTLS(ln, *tlsCert, *tlsKey)
+	} else {
+		err = server.Serve(ln)
+	}
+	if *stateDir != "" {
+		saveState(sim, *stateDir) ///< The next start resumes from here
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("server failed", "err", err)
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,128 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file shm.go
+ * @brief Publishes every frame into a memory-mapped file for external visualisers.
+ * @details Other processes map the same file (e.g. /dev/shm/wator) and read the live world
+ * without copies or sockets. Layout, all integers little-endian:
+ *   offset  0  magic "WATR"
+ *   offset  4  uint32 layout version (1)
+ *   offset  8  uint32 width
+ *   offset 12  uint32 height
+ *   offset 16  uint64 sequence number, odd while a frame is being written
+ *   offset 24  uint64 chronon
+ *   offset 32  uint32 fish
+ *   offset 36  uint32 sharks
+ *   offset 40  width*height species bytes, row-major: 0 empty, 1 fish, 2 shark
+ * Readers copy the frame between two reads of the sequence number and retry if it was odd
+ * or changed (a seqlock), so they never see a half-written frame. tools/shm_reader.py is a
+ * reference reader.
+ */
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"unsafe"
+)
+
+/** Shared-frame layout constants. */
+const (
+	shmMagic      = "WATR"
+	shmVersion    = 1
+	shmHeaderSize = 40
+	shmSeqOffset  = 16
+)
+
+/**
+ * @struct SharedFrameWriter
+ * @brief Writes frames into a memory-mapped file.
+ */
+type SharedFrameWriter struct {
+	file *os.File
+	data []byte ///< The mapping
+	size int    ///< Grid dimension the file was sized for
+	seq  *uint64
+}
+
+/**
+ * @brief Creates (or truncates) the file, sizes it for the grid, and maps it.
+ * @param path The file to share, e.g. /dev/shm/wator.
+ * @param size The grid dimension.
+ * @return The writer, or an error if the file could not be created or mapped.
+ */
+func NewSharedFrameWriter(path string, size int) (*SharedFrameWriter, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("creating shared frame file: %w", err)
+	}
+	n := shmHeaderSize + size*size
+	if err := f.Truncate(int64(n)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sizing shared frame file: %w", err)
+	}
+	data, err := mapFile(f, n)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("mapping shared frame file: %w", err)
+	}
+
+	copy(data, shmMagic)
+	binary.LittleEndian.PutUint32(data[4:], shmVersion)
+	binary.LittleEndian.PutUint32(data[8:], uint32(size))
+	binary.LittleEndian.PutUint32(data[12:], uint32(size))
+	return &SharedFrameWriter{file: f, data: data, size: size,
+		seq: (*uint64)(unsafe.Pointer(&data[shmSeqOffset]))}, nil ///< Mappings are page aligned, so the counter is 8-byte aligned
+}
+
+/**
+ * @brief Publishes a frame.
+ * @details Matches ChrononStartHook. Frames of a different size from the file are skipped.
+ * @param f The frame to publish.
+ */
+func (w *SharedFrameWriter) Write(f *Frame) {
+	if f.Size() != w.size {
+		return
+	}
+	atomic.AddUint64(w.seq, 1) ///< Odd: writing
+	encodeSharedFrame(w.data, f)
+	atomic.AddUint64(w.seq, 1) ///< Even: consistent
+}
+
+/**
+ * @brief Writes the frame-specific part of the layout (chronon, counts, cells).
+ * @param data The mapping, at least shmHeaderSize + size*size bytes.
+ * @param f The frame.
+ */
+func encodeSharedFrame(data []byte, f *Frame) {
+	fish, sharks := f.Counts()
+	binary.LittleEndian.PutUint64(data[24:], uint64(f.Chronon()))
+	binary.LittleEndian.PutUint32(data[32:], uint32(fish))
+	binary.LittleEndian.PutUint32(data[36:], uint32(sharks))
+	for i, sp := range f.cells {
+		data[shmHeaderSize+i] = byte(sp)
+	}
+}
+
+/**
+ * @brief Unmaps and closes the file. The file itself is left in place for readers.
+ * @return Any unmap or close error.
+ */
+func (w *SharedFrameWriter) Close() error {
+	err := unmapFile(w.data)
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build !unix
+
+/**
+ * @file shm_other.go
+ * @brief Stand-in for platforms without mmap support in the standard library.
+ */
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+/**
+ * @brief Reports that shared-frame export is unavailable on this platform.
+ */
+func mapFile(*os.File, int) ([]byte, error) {
+	return nil, errors.New("memory-mapped files are not supported on this platform")
+}
+
+/**
+ * @brief Does nothing; nothing can have been mapped.
+ */
+func unmapFile([]byte) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build unix
+
+/**
+ * @file shm_test.go
+ * @brief Tests for the memory-mapped frame export.
+ */
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSharedFrameLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frames")
+	w, err := NewSharedFrameWriter(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := frameFromASCII(t, "FS\n.F\n", 3)
+	f.chronon = 7
+	w.Write(f)
+	w.Write(f)
+
+	data, err := os.ReadFile(path) ///< Another view of the same file, as an external reader would have
+	if err != nil {
+		t.Fatal(err)
+	}
+	le := binary.LittleEndian
+	if string(data[:4]) != "WATR" || le.Uint32(data[4:]) != 1 || le.Uint32(data[8:]) != 2 || le.Uint32(data[12:]) != 2 {
+		t.Errorf("bad header % x", data[:16])
+	}
+	if seq := le.Uint64(data[16:]); seq != 4 {
+		t.Errorf("sequence %d after two writes, want 4", seq)
+	}
+	if le.Uint64(data[24:]) != 7 || le.Uint32(data[32:]) != 2 || le.Uint32(data[36:]) != 1 {
+		t.Errorf("chronon %d fish %d sharks %d, want 7, 2, 1", le.Uint64(data[24:]), le.Uint32(data[32:]), le.Uint32(data[36:]))
+	}
+	if got := data[40:]; string(got) != "\x01\x02\x00\x01" {
+		t.Errorf("cells % x, want 01 02 00 01", got)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,37 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build unix
+
+/**
+ * @file shm_unix.go
+ * @brief Memory mapping for the shared-frame export on Unix systems.
+ */
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+/**
+ * @brief Maps the first n bytes of a file for shared reading and writing.
+ */
+func mapFile(f *os.File, n int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+/**
+ * @brief Releases a mapping made by mapFile.
+ */
+func unmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,262 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file sign.go
+ * @brief Tamper-evident summaries and checkpoints (the -sign option and the attest subcommand).
+ * @details A signed run chains SHA-256 hashes of its frames, each link hashing the previous
+ * link with the next frame's cells and shark energies, so the head of the chain stands for
+ * the whole trajectory. The summary and checkpoint then carry a signature: the head, the
+ * chronons and final populations it covers, and an HMAC-SHA256 over those and the run's
+ * parameters (seed included) under a key the organiser keeps. attest checks the HMAC, which
+ * fails if anything signed was edited or another key was used, and then re-simulates the run
+ * from the signed parameters to confirm that they really produce the signed chain: a valid
+ * HMAC alone only shows that someone holding the key signed the file. Re-simulation needs
+ * runs that are reproducible from their seed, so -sign requires them.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"os"
+	"slices"
+	"unsafe"
+)
+
+/** Name of the MAC written to every signature. */
+const signatureAlgorithm = "hmac-sha256"
+
+/**
+ * @struct Signature
+ * @brief What a signed summary or checkpoint vouches for.
+ */
+type Signature struct {
+	Algorithm string `json:"algorithm"` ///< signatureAlgorithm
+	Chronons  int    `json:"chronons"`  ///< Chronons the chain covers, after the starting frame
+	Chain     string `json:"chain"`     ///< Head of the frame hash chain, hex
+	Fish      int    `json:"fish"`      ///< Final fish population
+	Sharks    int    `json:"sharks"`    ///< Final shark population
+	MAC       string `json:"mac"`       ///< HMAC of the fields above and the parameters, hex
+}
+
+/**
+ * @struct FrameChain
+ * @brief SHA-256 hash chain over every frame of a run.
+ */
+type FrameChain struct {
+	head   [sha256.Size]byte ///< Current link; all zero before the first frame
+	frames int               ///< Frames chained
+	h      hash.Hash
+}
+
+/**
+ * @brief Creates an empty chain.
+ */
+func NewFrameChain() *FrameChain {
+	return &FrameChain{h: sha256.New()}
+}
+
+/**
+ * @brief Appends a frame: the new link hashes the previous one, the chronon, the cells and the energies.
+ * @param f The frame; frames must arrive in chronon order, starting with the initial one.
+ */
+func (c *FrameChain) Observe(f *Frame) {
+	c.h.Reset()
+	c.h.Write(c.head[:])
+	c.h.Write(binary.LittleEndian.AppendUint64(nil, uint64(f.Chronon())))
+	c.h.Write(unsafe.Slice((*byte)(unsafe.SliceData(f.cells)), len(f.cells))) ///< Species is a byte
+	buf := make([]byte, 0, 2*len(f.energy))
+	for _, e := range f.energy {
+		buf = binary.LittleEndian.AppendUint16(buf, e)
+	}
+	c.h.Write(buf)
+	c.h.Sum(c.head[:0])
+	c.frames++
+}
+
+/**
+ * @brief Appends the frame produced by a chronon; usable as a chronon-end hook.
+ */
+func (c *FrameChain) Record(f *Frame, _ StepReport) {
+	c.Observe(f)
+}
+
+/**
+ * @brief Returns the head of the chain as hex.
+ */
+func (c *FrameChain) Head() string {
+	return hex.EncodeToString(c.head[:])
+}
+
+/**
+ * @brief Signs the end of a run.
+ * @param key The HMAC key.
+ * @param cfg The parameters recorded in the document being signed.
+ * @param chain The run's frame chain, including its final frame.
+ * @param final The final frame.
+ * @return The signature, or an error if the parameters cannot be encoded.
+ */
+func signRun(key []byte, cfg Config, chain *FrameChain, final *Frame) (*Signature, error) {
+	fish, sharks := final.Counts()
+	s := &Signature{Algorithm: signatureAlgorithm, Chronons: final.Chronon(), Chain: chain.Head(), Fish: fish, Sharks: sharks}
+	mac, err := s.mac(key, cfg)
+	s.MAC = mac
+	return s, err
+}
+
+/**
+ * @brief Computes the HMAC of the signature's fields and the parameters.
+ * @details The parameters are hashed as their JSON encoding, which survives a round trip
+ * through the document unchanged.
+ */
+func (s *Signature) mac(key []byte, cfg Config) (string, error) {
+	params, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	m := hmac.New(sha256.New, key)
+	fmt.Fprintf(m, "wator-signature-v1\n%s\n%d %s %d %d\n", params, s.Chronons, s.Chain, s.Fish, s.Sharks)
+	return hex.EncodeToString(m.Sum(nil)), nil
+}
+
+/**
+ * @brief Reads an HMAC key from a file, ignoring surrounding whitespace.
+ * @return The key, or an error if the file cannot be read or holds no key.
+ */
+func readSigningKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	if key = bytes.TrimSpace(key); len(key) == 0 {
+		return nil, fmt.Errorf("signing key file %s is empty", path)
+	}
+	return key, nil
+}
+
+/**
+ * @struct signedDocument
+ * @brief The fields attest reads from a -summary-json or -checkpoint file.
+ */
+type signedDocument struct {
+	Signature  *Signature         `json:"signature"`
+	Parameters *Config            `json:"parameters"` ///< Summaries
+	Config     *Config            `json:"config"`     ///< Checkpoints
+	Fish       *int               `json:"fish"`       ///< Summaries
+	Sharks     *int               `json:"sharks"`     ///< Summaries
+	Chronon    *int               `json:"chronon"`    ///< Checkpoints
+	Entities   []checkpointEntity `json:"entities"`   ///< Checkpoints
+}
+
+/**
+ * @brief The attest subcommand.
+ * @param args Arguments after the command name.
+ * @return exitOK for an authentic file, exitFailure for a forged or altered one, exitConfigError for bad input.
+ */
+func cmdAttest(args []string) int {
+	fs := flag.NewFlagSet("wator attest", flag.ContinueOnError)
+	keyPath := fs.String("key", "", "`file` holding the key the run was signed with")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wator attest -key <file> <summary-or-checkpoint>")
+		fmt.Fprintln(fs.Output(), "Checks the signature of a file written with -sign and re-simulates the run to confirm it.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitConfigError
+	}
+	if fs.NArg() != 1 || *keyPath == "" {
+		fs.Usage()
+		return exitConfigError
+	}
+	key, err := readSigningKey(*keyPath)
+	var data []byte
+	if err == nil {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitConfigError
+	}
+	report, err := attest(context.Background(), key, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return exitFailure
+	}
+	fmt.Println(report)
+	return exitOK
+}
+
+/**
+ * @brief Checks a signed summary or checkpoint.
+ * @param ctx Context of the re-simulation.
+ * @param key The HMAC key.
+ * @param data The file's contents.
+ * @return A one-line description of what was confirmed, or an error saying why the file is not authentic.
+ */
+func attest(ctx context.Context, key, data []byte) (string, error) {
+	var doc signedDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("not a summary or checkpoint: %w", err)
+	}
+	cfg := doc.Parameters
+	if cfg == nil {
+		cfg = doc.Config
+	}
+	sig := doc.Signature
+	if cfg == nil || sig == nil {
+		return "", errors.New("not signed (run with -sign)")
+	}
+	if sig.Algorithm != signatureAlgorithm {
+		return "", fmt.Errorf("unknown signature algorithm %q", sig.Algorithm)
+	}
+	want, err := sig.mac(key, *cfg)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(want), []byte(sig.MAC)) {
+		return "", errors.New("signature does not match: the file was altered or signed with another key")
+	}
+	if (doc.Fish != nil && *doc.Fish != sig.Fish) || (doc.Sharks != nil && *doc.Sharks != sig.Sharks) || (doc.Chronon != nil && *doc.Chronon != sig.Chronons) {
+		return "", errors.New("the populations or chronon in the file differ from the signed ones")
+	}
+
+	sim, err := NewSimulation(*cfg)
+	if err != nil {
+		return "", fmt.Errorf("signed parameters: %w", err)
+	}
+	chain := NewFrameChain()
+	chain.Observe(sim.Snapshot())
+	sim.OnChrononEnd(chain.Record)
+	steps := sig.Chronons - sim.Snapshot().Chronon() ///< A -resume run starts at its checkpoint's chronon
+	if ran, err := sim.Run(ctx, steps); err != nil || ran != steps {
+		return "", fmt.Errorf("re-simulation stopped after %d of %d chronons", ran, steps)
+	}
+	if chain.Head() != sig.Chain {
+		return "", fmt.Errorf("the signed parameters (seed %d) do not reproduce the signed frames", cfg.Seed)
+	}
+	if doc.Entities != nil && !slices.Equal(doc.Entities, sim.Checkpoint().Entities) {
+		return "", errors.New("the entities in the checkpoint differ from the re-simulated final state")
+	}
+	return fmt.Sprintf("authentic: seed %d reproduces all %d signed chronons (%d fish, %d sharks)", cfg.Seed, sig.Chronons, sig.Fish, sig.Sharks), nil
+}
@@ -0,0 +1,109 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file sign_test.go
+ * @brief Tests for signed summaries and checkpoints.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Runs a small signed simulation as run does.
+ * @return The signed summary and checkpoint, encoded.
+ */
+func signedRun(t *testing.T, key []byte) (summary, checkpoint []byte) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Engine, cfg.Chronons = "deterministic", 12
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := NewFrameChain()
+	chain.Observe(sim.Snapshot())
+	sim.OnChrononEnd(chain.Record)
+	ran, _ := sim.Run(context.Background(), cfg.Chronons)
+	final := sim.Snapshot()
+
+	s := NewRunSummary(cfg, final, StepCounts{}, &ExtinctionWatch{}, ran, 0, false)
+	cp := sim.Checkpoint()
+	if s.Signature, err = signRun(key, s.Parameters, chain, final); err != nil {
+		t.Fatal(err)
+	}
+	if cp.Signature, err = signRun(key, cp.Config, chain, final); err != nil {
+		t.Fatal(err)
+	}
+	var sb, cb bytes.Buffer
+	s.Write(&sb)
+	cp.Write(&cb)
+	return sb.Bytes(), cb.Bytes()
+}
+
+func TestAttestAcceptsSignedRuns(t *testing.T) {
+	key := []byte("organiser secret")
+	summary, checkpoint := signedRun(t, key)
+	for name, doc := range map[string][]byte{"summary": summary, "checkpoint": checkpoint} {
+		if report, err := attest(context.Background(), key, doc); err != nil || !strings.HasPrefix(report, "authentic") {
+			t.Errorf("%s: %q, %v", name, report, err)
+		}
+	}
+}
+
+func TestAttestRejectsTampering(t *testing.T) {
+	key := []byte("organiser secret")
+	summary, checkpoint := signedRun(t, key)
+	edit := func(doc []byte, change func(map[string]any)) []byte {
+		var m map[string]any
+		json.Unmarshal(doc, &m)
+		change(m)
+		out, _ := json.Marshal(m)
+		return out
+	}
+	cases := map[string][]byte{
+		"other key":     nil,
+		"fish edited":   edit(summary, func(m map[string]any) { m["fish"] = m["fish"].(float64) + 1 }),
+		"seed edited":   edit(summary, func(m map[string]any) { m["parameters"].(map[string]any)["Seed"] = 12.0 }),
+		"counts re-fit": edit(summary, func(m map[string]any) { m["signature"].(map[string]any)["fish"] = 0.0 }),
+		"entity moved": edit(checkpoint, func(m map[string]any) {
+			m["entities"].([]any)[0].(map[string]any)["breed"] = 99.0
+		}),
+		"unsigned": edit(summary, func(m map[string]any) { delete(m, "signature") }),
+	}
+	for name, doc := range cases {
+		k := key
+		if doc == nil {
+			doc, k = summary, []byte("guess")
+		}
+		if report, err := attest(context.Background(), k, doc); err == nil {
+			t.Errorf("%s: accepted (%s)", name, report)
+		}
+	}
+}
+
+func TestSignRequiresReproducibleRun(t *testing.T) {
+	cfg := testConfig()
+	cfg.SignKey, cfg.SummaryJSON, cfg.Engine, cfg.Threads = "key", "-", "sections", 4
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "-sign") {
+		t.Errorf("multi-threaded sections run accepted for -sign: %v", err)
+	}
+	cfg.Engine = "deterministic"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
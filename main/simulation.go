@@ -0,0 +1,432 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file simulation.go
+ * @brief A running simulation: grid, rules, and engine behind a thread-safe API.
+ * @details Step advances the world while Snapshot hands out immutable Frames, so renderers,
+ * stat writers, and servers never read the live grid that the workers are mutating.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/trace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/**
+ * @struct Simulation
+ * @brief Owns a grid and advances it with the configured engine.
+ */
+type Simulation struct {
+	mu      sync.RWMutex  ///< Held for writing during Step, for reading while snapshotting
+	cfg     Config        ///< Parameters the simulation was created with
+	grid    *Grid         ///< The live world; only touched under mu
+	rules   Rules         ///< Rule parameters
+	engine  Engine        ///< Concurrency strategy
+	threads int           ///< Number of worker threads
+	frame   *Frame        ///< Cached snapshot of the current chronon; nil once stale
+	pop     Population    ///< Running populations, kept from the engine's tallies
+	hooks   hooks         ///< Registered observers; guarded by mu
+	stopped atomic.Bool   ///< Set by Stop to end Run early
+	tick    time.Duration ///< Wall-clock interval between chronon starts in Run (0 runs flat out)
+	pool    *workerPool   ///< Pinned worker threads; nil unless PinWorkers is set
+	fast    *fastForward  ///< Stable-chunk skipping; nil unless FastForward is set
+	branch  *BranchPoint  ///< Where the run was resumed from; nil unless Resume is set
+	tide    *Tide         ///< Red-tide toxin levels; nil until a bloom can start
+
+	registryOnce sync.Once       ///< Guards the creation of registry
+	registry     *EntityRegistry ///< Shared by -inspect and -export; nil until Registry is called
+}
+
+/**
+ * @brief Creates and populates a simulation from a validated configuration.
+ * @details With GridFile set, the initial grid is read from that ASCII map and GridSize,
+ * NumFish and NumShark are replaced by the map's values, and with Resume set the grid and red
+ * tide are the checkpoint's, at its chronon; otherwise entities are placed at random.
+ * With Behaviour or Plugins set, the movement script and plugins are compiled once and the run
+ * stops at their first runtime error. With Script set, the script's events are applied by a chronon-start hook registered first.
+ * @param cfg The configuration; Seed must already be fixed.
+ * @return The simulation, or an error if the engine or storage is unknown or the map is invalid.
+ */
+func NewSimulation(cfg Config) (*Simulation, error) {
+	engine, err := engineByName(cfg.Engine)
+	if err != nil {
+		return nil, err
+	}
+	newStore, err := storageByName(cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var grid *Grid
+	var tide *Tide
+	var branch *BranchPoint
+	if cfg.Resume != "" {
+		cp, err := readCheckpointFile(cfg.Resume)
+		if err != nil {
+			return nil, err
+		}
+		if grid, err = cp.Grid(cfg.Storage); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.Resume, err)
+		}
+		if tide, err = cp.redTide(cfg.Seed); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.Resume, err)
+		}
+		grid.Seed(cfg.Seed)
+		cfg.GridSize = grid.Size
+		cfg.NumFish, cfg.NumShark = grid.CountEntities()
+		branch = newBranchPoint(cfg.Resume, cp, cfg)
+	} else if cfg.GridFile != "" {
+		f, err := os.Open(cfg.GridFile)
+		if err != nil {
+			return nil, fmt.Errorf("opening grid map: %w", err)
+		}
+		grid, err = readASCII(f, cfg.StarveEnergy, newStore)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.GridFile, err)
+		}
+		grid.Seed(cfg.Seed)
+		cfg.GridSize = grid.Size
+		cfg.NumFish, cfg.NumShark = grid.CountEntities()
+	} else {
+		grid = newGridWithStorage(cfg.GridSize, newStore)
+		grid.Seed(cfg.Seed)
+		if err := grid.Initialize(cfg.NumFish, cfg.NumShark, cfg.StarveEnergy); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Tag > 0 {
+		tagLineages(grid, cfg.Tag, cfg.Lineages, cfg.Seed)
+	}
+	s := &Simulation{cfg: cfg, grid: grid, rules: cfg.Rules(), engine: engine, threads: cfg.Threads, branch: branch, tide: tide}
+	s.pop.Chronon = grid.Chronon
+	s.pop.Fish, s.pop.Sharks = grid.CountEntities() ///< The only full count; Step keeps it up to date
+	if cfg.FastForward > 0 {
+		s.fast = newFastForward(cfg.FastForward)
+	}
+	if cfg.PinWorkers {
+		s.pool = newWorkerPool()
+		runtime.SetFinalizer(s, func(s *Simulation) { s.pool.Close() }) ///< Ends the pinned threads with the simulation
+	}
+	if cfg.Temperature != "" {
+		if s.rules.Temperature, err = loadTemperature(cfg.Temperature, grid.Size); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Behaviour != "" {
+		if s.rules.Behaviour, err = loadBehaviour(cfg.Behaviour); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Plugins != "" {
+		if s.rules.Behaviour, err = loadPlugins(s.rules.Behaviour, cfg.PluginDir, cfg.Plugins); err != nil {
+			return nil, err
+		}
+	}
+	if s.rules.Behaviour != nil {
+		s.OnChrononEnd(func(*Frame, StepReport) {
+			if s.Rules().Behaviour.Err() != nil {
+				s.Stop() ///< Further chronons would silently use the built-in movement
+			}
+		})
+	}
+	if cfg.Script != "" {
+		script, err := loadScript(cfg.Script, cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.OnChrononStart(func(f *Frame) { script.apply(s, cfg.Seed, f) })
+	}
+	return s, nil
+}
+
+/**
+ * @brief Advances the simulation by one chronon.
+ * @details Runs the chronon-start hooks with the frame before the move, steps the engine,
+ * then runs the event hooks for every event, the stats hooks with the running populations,
+ * and the chronon-end hooks with the new frame. A frame is only copied when a start or end
+ * hook is registered. Hooks run on the caller's goroutine after the grid lock has been released.
+ * @param ctx Context passed to the engine's workers; a started chronon always completes.
+ * @return The engine's report for the chronon.
+ */
+func (s *Simulation) Step(ctx context.Context) StepReport {
+	ctx, task := trace.NewTask(ctx, "chronon")
+	defer task.End()
+
+	s.mu.RLock()
+	h := s.hooks ///< Copy so hooks may register further hooks without deadlocking
+	chronon := s.grid.Chronon
+	s.mu.RUnlock()
+	ctx, endChronon := startSpan(ctx, "chronon", "chronon", chronon+1)
+	defer endChronon()
+
+	if len(h.start) > 0 {
+		_, endRender := startSpan(ctx, "render")
+		before := s.Snapshot()
+		for _, fn := range h.start {
+			fn(before)
+		}
+		endRender()
+	}
+
+	s.mu.Lock()
+	s.frame = nil ///< The cached snapshot describes the previous chronon
+	if s.pool != nil {
+		ctx = withWorkerPool(ctx, s.pool)
+	}
+	stepCtx, endStep := startSpan(ctx, "step", "threads", s.threads)
+	began := time.Now()
+	var frozen *chunkStorage ///< The storage whose stable chunks the engine skips
+	if s.fast != nil && s.fast.prepare(s.grid, s.rules) {
+		frozen = s.grid.store.(*chunkStorage)
+	}
+	report := s.engine.Step(stepCtx, s.grid, s.rules, s.threads)
+	if frozen != nil {
+		s.fast.finish(s.grid, frozen)
+	}
+	report.Elapsed = time.Since(began)
+	endStep()
+	if s.tide == nil && s.rules.TideProb > 0 {
+		s.tide = newTide(s.grid.Size, s.cfg.Seed)
+	}
+	if s.tide != nil && s.tide.step(s.grid, s.rules, &report) && s.fast != nil {
+		s.fast.reset() ///< Poisoned chunks must be stepped again
+	}
+	s.pop.apply(report.StepCounts)
+	pop := s.pop
+	s.mu.Unlock()
+
+	_, endStats := startSpan(ctx, "stats")
+	defer endStats()
+	for _, e := range report.Events {
+		for _, fn := range h.event {
+			fn(e)
+		}
+	}
+	for _, fn := range h.stats {
+		fn(pop, report)
+	}
+	if len(h.end) > 0 {
+		after := s.Snapshot()
+		for _, fn := range h.end {
+			fn(after, report)
+		}
+	}
+	return report
+}
+
+/**
+ * @brief Steps the simulation until the given number of chronons have run, Stop is called, or
+ * the context is cancelled.
+ * @details Cancellation is checked between chronons, so the grid is never left half-updated.
+ * @param ctx Context of the run.
+ * @param chronons The maximum number of chronons to run.
+ * @return The number of chronons actually run, and the context's error if it was cancelled.
+ */
+func (s *Simulation) Run(ctx context.Context, chronons int) (int, error) {
+	pace := newPacer(s.tick)
+	n := 0
+	for ; n < chronons && !s.stopped.Load(); n++ {
+		if err := pace.wait(ctx); err != nil {
+			return n, err
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		s.Step(ctx)
+	}
+	if pace.late > 0 {
+		slog.Info("pacing", "tick", s.tick, "late_chronons", pace.late)
+	}
+	return n, nil
+}
+
+/**
+ * @brief Replaces the contents of cells, between chronons.
+ * @details Safe to call from any goroutine: the edits wait for a running chronon to finish.
+ * Painted fish are newborn and painted sharks start with StarveEnergy; any previous
+ * occupant is removed. Births and deaths caused by edits are not counted in any StepReport.
+ * @param edits The cells to change.
+ */
+func (s *Simulation) Apply(edits ...CellEdit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range edits {
+		x, y := wrap(e.X, s.grid.Size), wrap(e.Y, s.grid.Size)
+		s.pop.replace(speciesOf(s.grid.At(x, y)), e.Species)
+		switch e.Species {
+		case SpeciesFish:
+			s.grid.Set(x, y, s.rules.newFish())
+		case SpeciesShark:
+			s.grid.Set(x, y, &Shark{Energy: s.rules.StarveEnergy})
+		default:
+			s.grid.Set(x, y, nil)
+		}
+	}
+	s.frame = nil ///< The cached snapshot no longer matches the grid
+	if s.fast != nil {
+		s.fast.reset() ///< Edited chunks must be stepped again
+	}
+}
+
+/**
+ * @brief Replaces the rule parameters from the next chronon on.
+ * @details Safe to call from hooks and from other goroutines. Config keeps reporting the
+ * parameters the simulation was created with.
+ * @param rules The new rules.
+ */
+func (s *Simulation) SetRules(rules Rules) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = rules
+}
+
+/**
+ * @brief Applies a change of rule parameters from the next chronon on, keeping the others.
+ * @details The rules in force are read and replaced under one lock, so that a script and the
+ * console changing different parameters do not undo each other's changes.
+ * @param before The rules the change was made from.
+ * @param after The rules with the change; fields equal in before and after keep their current value.
+ */
+func (s *Simulation) ChangeRules(before, after Rules) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = changedRules(s.rules, before, after)
+}
+
+/**
+ * @brief Changes the number of worker threads used from the next chronon on.
+ * @details Safe to call from hooks and from other goroutines.
+ * @param threads The new worker count; values below 1 are treated as 1.
+ */
+func (s *Simulation) SetThreads(threads int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.threads = max(threads, 1)
+}
+
+/**
+ * @brief Returns the number of worker threads the next chronon will use.
+ */
+func (s *Simulation) Threads() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.threads
+}
+
+/**
+ * @brief Paces Run to start one chronon per tick of wall-clock time.
+ * @details Must be called before Run. The time a chronon takes, hooks included, is deducted
+ * from the following sleep.
+ * @param tick The interval; 0 runs as fast as possible.
+ */
+func (s *Simulation) SetTick(tick time.Duration) {
+	s.tick = tick
+}
+
+/**
+ * @brief Asks Run to return after the current chronon.
+ * @details Safe to call from hooks and from other goroutines.
+ */
+func (s *Simulation) Stop() {
+	s.stopped.Store(true)
+}
+
+/**
+ * @brief Returns a read-only view of the current chronon.
+ * @details Frames are immutable, so the same Frame is shared by every caller until the next
+ * Step; only then is a new copy made. Safe to call from any goroutine.
+ * @return The current frame.
+ */
+func (s *Simulation) Snapshot() *Frame {
+	s.mu.RLock()
+	if f := s.frame; f != nil {
+		s.mu.RUnlock()
+		return f
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.frame == nil { ///< Another caller may have built it while we waited
+		s.frame = newFrame(s.grid)
+		if s.tide != nil {
+			s.tide.mark(s.frame)
+		}
+		if s.cfg.TemperatureOverlay {
+			s.frame.temp = s.rules.Temperature
+		}
+	}
+	return s.frame
+}
+
+/**
+ * @brief Counts the fish and sharks of a region of the current chronon (see Grid.Density).
+ * @details Safe to call from any goroutine; full-width regions cost O(rows).
+ */
+func (s *Simulation) Density(r Region) (Density, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.grid.Density(r)
+}
+
+/**
+ * @brief Returns the number of chunk-chronons -fast-forward has skipped so far.
+ */
+func (s *Simulation) FastForwarded() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.fast == nil {
+		return 0
+	}
+	return s.fast.skipped
+}
+
+/**
+ * @brief Returns the live grid.
+ * @details Only safe on the goroutine that calls Step, between steps (e.g. for invariant
+ * checks that need entity identities). Everything else should use Snapshot.
+ */
+func (s *Simulation) Grid() *Grid {
+	return s.grid
+}
+
+/**
+ * @brief Returns where the run was resumed from, or nil for a run started from scratch.
+ */
+func (s *Simulation) Branch() *BranchPoint {
+	return s.branch
+}
+
+/**
+ * @brief Returns the configuration, including any values taken from a -grid map or -resume checkpoint.
+ */
+func (s *Simulation) Config() Config {
+	return s.cfg
+}
+
+/**
+ * @brief Returns the rule parameters.
+ */
+func (s *Simulation) Rules() Rules {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules
+}
@@ -0,0 +1,228 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file simulation_test.go
+ * @brief Tests for the Simulation API and its snapshots.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+/**
+ * @brief Returns a small seeded configuration for tests.
+ */
+func testConfig() Config {
+	cfg := defaultConfig()
+	cfg.GridSize, cfg.NumFish, cfg.NumShark, cfg.Threads, cfg.Seed = 20, 120, 30, 2, 11
+	cfg.Engine = "moves" ///< Race-free at any thread count
+	return cfg
+}
+
+func TestSnapshotSharedUntilStep(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := sim.Snapshot(), sim.Snapshot()
+	if a != b {
+		t.Error("snapshots of the same chronon should share one frame")
+	}
+	fish, sharks := a.Counts()
+	if fish != 120 || sharks != 30 {
+		t.Errorf("initial frame counts %d fish, %d sharks", fish, sharks)
+	}
+
+	sim.Step(context.Background())
+	c := sim.Snapshot()
+	if c == a || c.Chronon() != 1 || a.Chronon() != 0 {
+		t.Errorf("expected a fresh frame for chronon 1, got chronons %d and %d", a.Chronon(), c.Chronon())
+	}
+}
+
+func TestSnapshotConcurrentWithStep(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				f := sim.Snapshot()
+				fish, sharks := f.Counts()
+				n := 0
+				for x := 0; x < f.Size(); x++ {
+					for y := 0; y < f.Size(); y++ {
+						if f.At(x, y) != SpeciesNone {
+							n++
+						}
+					}
+				}
+				if n != fish+sharks {
+					t.Errorf("frame %d has %d occupied cells but counts %d", f.Chronon(), n, fish+sharks)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 30; i++ {
+		sim.Step(context.Background())
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestHooksOrderAndEarlyStop(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	events := 0
+	sim.OnChrononStart(func(f *Frame) {
+		calls = append(calls, fmt.Sprintf("start %d", f.Chronon()))
+	})
+	sim.OnEvent(func(Event) { events++ })
+	sim.OnChrononEnd(func(f *Frame, report StepReport) {
+		calls = append(calls, fmt.Sprintf("end %d", f.Chronon()))
+		events -= len(report.Events)
+		if f.Chronon() == 3 {
+			sim.Stop()
+		}
+	})
+
+	if n, err := sim.Run(context.Background(), 10); err != nil || n != 3 {
+		t.Fatalf("Run stopped after %d chronons (err %v), want 3", n, err)
+	}
+	want := []string{"start 0", "end 1", "start 1", "end 2", "start 2", "end 3"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("hook calls %v, want %v", calls, want)
+	}
+	if events != 0 {
+		t.Errorf("event hook and reports disagree by %d events", events)
+	}
+}
+
+func TestRunStopsOnCancelledContext(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sim.OnChrononEnd(func(f *Frame, _ StepReport) {
+		if f.Chronon() == 2 {
+			cancel()
+		}
+	})
+	n, err := sim.Run(ctx, 10)
+	if n != 2 || err != context.Canceled {
+		t.Errorf("Run returned (%d, %v), want (2, context.Canceled)", n, err)
+	}
+	if got := sim.Snapshot().Chronon(); got != 2 {
+		t.Errorf("grid at chronon %d after cancellation, want 2", got)
+	}
+}
+
+func TestPacerCompensatesAndRestartsWhenLate(t *testing.T) {
+	start := time.Now()
+	now := start
+	p := newPacer(20 * time.Millisecond)
+	p.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	p.wait(ctx) ///< First chronon starts at once
+	now = start.Add(15 * time.Millisecond)
+	began := time.Now()
+	p.wait(ctx) ///< Chronon took 15ms of a 20ms tick: sleep about 5ms
+	if slept := time.Since(began); slept < 4*time.Millisecond || slept > 15*time.Millisecond {
+		t.Errorf("slept %v, want about 5ms", slept)
+	}
+	if want := start.Add(40 * time.Millisecond); !p.next.Equal(want) {
+		t.Errorf("next chronon at +%v, want +40ms", p.next.Sub(start))
+	}
+
+	now = start.Add(100 * time.Millisecond) ///< Overran by 60ms
+	p.wait(ctx)
+	if p.late != 1 || !p.next.Equal(now.Add(20*time.Millisecond)) {
+		t.Errorf("late %d, next +%v; want 1 late and the schedule restarted at +120ms", p.late, p.next.Sub(start))
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := p.wait(cancelled); err == nil {
+		t.Error("wait should return the context's error when cancelled")
+	}
+}
+
+func TestRunWithTick(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.SetTick(10 * time.Millisecond)
+	began := time.Now()
+	sim.Run(context.Background(), 4)
+	if elapsed := time.Since(began); elapsed < 30*time.Millisecond {
+		t.Errorf("4 chronons at a 10ms tick took %v, want at least 30ms", elapsed)
+	}
+}
+
+func TestPopulationMatchesFrames(t *testing.T) {
+	variants := map[string]func(*Config){
+		"classic":    func(*Config) {},
+		"costs":      func(c *Config) { c.MoveCost, c.StayCost = 2, 1 },
+		"crowding":   func(c *Config) { c.CrowdingK, c.CrowdingDeath = 3, 0.5 },
+		"speed":      func(c *Config) { c.FishSpeed, c.SharkSpeed = 2, 3 },
+		"stochastic": func(c *Config) { c.RuleSet = "stochastic" },
+		"tide":       func(c *Config) { c.TideProb, c.TideKill = 0.3, 1 },
+	}
+	for _, engine := range engineNames() {
+		for name, apply := range variants {
+			cfg := testConfig()
+			cfg.Engine, cfg.Threads = engine, 1
+			apply(&cfg)
+			sim, err := NewSimulation(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var hooked Population
+			sim.OnStats(func(p Population, _ StepReport) { hooked = p })
+			for i := 0; i < 30; i++ {
+				if i == 10 {
+					sim.Apply(CellEdit{X: 0, Y: 0, Species: SpeciesShark}, CellEdit{X: 0, Y: 1, Species: SpeciesFish}, CellEdit{X: 0, Y: 1, Species: SpeciesNone})
+				}
+				sim.Step(context.Background())
+				fish, sharks := sim.Snapshot().Counts()
+				want := Population{Chronon: i + 1, Fish: fish, Sharks: sharks}
+				if got := sim.Population(); got != want || hooked != want {
+					t.Fatalf("%s/%s chronon %d: running %+v, hooked %+v, frame %+v", engine, name, i+1, got, hooked, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file sink.go
+ * @brief Where output files go: the local filesystem or object storage (the -output option).
+ * @details Every writer of an output file (-csv, -events, -checkpoint, -heatmap, -trigger PNGs,
+ * -summary-json, ...) creates it through the process's Sink. The default writes paths as given;
+ * "-output dir" writes relative paths under dir, and "-output s3://bucket/prefix" or
+ * "gs://bucket/prefix" stores each file as the object prefix/path. Objects are spooled to a
+ * temporary file and uploaded with one signed PUT when the output is closed, so a run that
+ * crashes leaves no half-written object behind.
+ *
+ * S3 credentials come from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and (for temporary
+ * credentials) AWS_SESSION_TOKEN, the region from AWS_REGION (default us-east-1), and
+ * AWS_ENDPOINT_URL points at an S3-compatible server such as MinIO. Google Cloud Storage is
+ * reached through its S3-compatible XML API with HMAC keys, from GCS_HMAC_ACCESS_ID and
+ * GCS_HMAC_SECRET (or the AWS variables).
+ */
+package main
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+/**
+ * @interface Sink
+ * @brief A destination for output files.
+ */
+type Sink interface {
+	/**
+	 * @brief Creates (or replaces) an output.
+	 * @param name The output's path as given on the command line.
+	 * @return A writer whose Close completes the output, or an error.
+	 */
+	Create(name string) (io.WriteCloser, error)
+}
+
+/** The sink outputs are created through; set once from -output before a run starts. */
+var outputs Sink = localSink{}
+
+/**
+ * @brief Creates an output file through the current sink.
+ */
+func createOutput(name string) (io.WriteCloser, error) {
+	return outputs.Create(name)
+}
+
+/**
+ * @brief Creates an output, writes it with fn and completes it.
+ * @return The first error of creating, writing or closing.
+ */
+func writeOutput(name string, fn func(io.Writer) error) error {
+	w, err := createOutput(name)
+	if err != nil {
+		return err
+	}
+	if err := fn(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+/**
+ * @brief Returns the sink for an -output value.
+ * @param dest Empty for paths as given, a directory, or an s3:// or gs:// URL.
+ * @return The sink, or an error for an unknown scheme or missing credentials.
+ */
+func openSink(dest string) (Sink, error) {
+	u, err := url.Parse(dest)
+	if err != nil || !strings.Contains(dest, "://") {
+		return localSink{dir: dest}, nil
+	}
+	var s *objectSink
+	switch u.Scheme {
+	case "s3":
+		region := cmp.Or(os.Getenv("AWS_REGION"), "us-east-1")
+		endpoint := os.Getenv("AWS_ENDPOINT_URL")
+		s = &objectSink{region: region, pathStyle: endpoint != "",
+			keyID: os.Getenv("AWS_ACCESS_KEY_ID"), secret: os.Getenv("AWS_SECRET_ACCESS_KEY"), token: os.Getenv("AWS_SESSION_TOKEN")}
+		if endpoint == "" {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+		if s.endpoint, err = url.Parse(endpoint); err != nil {
+			return nil, fmt.Errorf("AWS_ENDPOINT_URL: %w", err)
+		}
+	case "gs":
+		s = &objectSink{region: "auto", pathStyle: true, endpoint: &url.URL{Scheme: "https", Host: "storage.googleapis.com"},
+			keyID: cmp.Or(os.Getenv("GCS_HMAC_ACCESS_ID"), os.Getenv("AWS_ACCESS_KEY_ID")), secret: cmp.Or(os.Getenv("GCS_HMAC_SECRET"), os.Getenv("AWS_SECRET_ACCESS_KEY"))}
+	default:
+		return nil, fmt.Errorf("unknown -output scheme %q (want s3:// or gs://, or a directory)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("-output %s names no bucket", dest)
+	}
+	if s.keyID == "" || s.secret == "" {
+		return nil, fmt.Errorf("-output %s needs credentials; see the README", dest)
+	}
+	s.bucket, s.prefix, s.client = u.Host, strings.Trim(u.Path, "/"), &http.Client{Timeout: 10 * time.Minute}
+	return s, nil
+}
+
+/**
+ * @struct localSink
+ * @brief Writes outputs to the filesystem, relative paths under dir (the working directory if empty).
+ */
+type localSink struct {
+	dir string
+}
+
+/**
+ * @brief Returns the file an output name is written to.
+ */
+func (s localSink) resolve(name string) string {
+	if s.dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(s.dir, name)
+}
+
+/**
+ * @brief Creates the file, and its directory under dir if needed.
+ */
+func (s localSink) Create(name string) (io.WriteCloser, error) {
+	p := s.resolve(name)
+	if s.dir != "" {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return os.Create(p)
+}
+
+/**
+ * @struct objectSink
+ * @brief Writes outputs as objects of an S3-compatible bucket.
+ */
+type objectSink struct {
+	endpoint  *url.URL
+	pathStyle bool ///< Address the bucket in the path rather than the host name
+	region    string
+	bucket    string
+	prefix    string ///< Key prefix, without slashes at either end
+	keyID     string
+	secret    string
+	token     string ///< Session token of temporary credentials, if any
+	client    *http.Client
+}
+
+/**
+ * @brief Returns the object key of an output: the prefix and the output's path, which cannot climb above it.
+ */
+func (s *objectSink) key(name string) string {
+	rel := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if s.prefix == "" {
+		return rel
+	}
+	return s.prefix + "/" + rel
+}
+
+/**
+ * @brief Returns a writer that uploads the object when closed.
+ */
+func (s *objectSink) Create(name string) (io.WriteCloser, error) {
+	spool, err := os.CreateTemp("", "wator-output-*")
+	if err != nil {
+		return nil, err
+	}
+	return &objectWriter{sink: s, key: s.key(name), spool: spool, sum: sha256.New()}, nil
+}
+
+/**
+ * @struct objectWriter
+ * @brief An output being spooled for upload.
+ */
+type objectWriter struct {
+	sink  *objectSink
+	key   string
+	spool *os.File
+	sum   hash.Hash ///< SHA-256 of the body, which the signature covers
+	size  int64
+}
+
+/**
+ * @brief Appends to the spooled body.
+ */
+func (w *objectWriter) Write(p []byte) (int, error) {
+	n, err := w.spool.Write(p)
+	w.sum.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+/**
+ * @brief Uploads the spooled body and removes the spool.
+ * @return An error if the upload failed or the store refused it.
+ */
+func (w *objectWriter) Close() error {
+	defer os.Remove(w.spool.Name())
+	defer w.spool.Close()
+	if _, err := w.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	target := *w.sink.endpoint
+	if w.sink.pathStyle {
+		target.Path = "/" + w.sink.bucket + "/" + w.key
+	} else {
+		target.Host = w.sink.bucket + "." + target.Host
+		target.Path = "/" + w.key
+	}
+	target.RawPath = escapeS3Path(target.Path) ///< Sent exactly as signed
+	req, err := http.NewRequest(http.MethodPut, target.String(), w.spool)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = w.size
+	if w.size == 0 {
+		req.Body = http.NoBody ///< Otherwise an empty body would be sent chunked, which S3 refuses
+	}
+	if w.sink.token != "" {
+		req.Header.Set("X-Amz-Security-Token", w.sink.token)
+	}
+	signV4(req, hex.EncodeToString(w.sum.Sum(nil)), w.sink.keyID, w.sink.secret, w.sink.region, time.Now())
+
+	resp, err := w.sink.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("uploading %s: %w", w.key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("uploading %s: %s: %s", w.key, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+/**
+ * @brief Signs an S3 request with AWS Signature Version 4.
+ * @details Every header already set is signed, along with Host, X-Amz-Date and
+ * X-Amz-Content-Sha256, which are added.
+ * @param req The request, with its final URL and headers.
+ * @param payloadHash Hex SHA-256 of the body.
+ * @param keyID, secret The credentials.
+ * @param region The bucket's region ("auto" for Google Cloud Storage).
+ * @param now The signing time.
+ */
+func signV4(req *http.Request, payloadHash, keyID, secret, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	request := strings.Join([]string{req.Method, escapeS3Path(req.URL.Path), req.URL.RawQuery, canonical.String(), signed, payloadHash}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(request))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		keyID, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+/**
+ * @brief Returns HMAC-SHA256 of data under key.
+ */
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+/**
+ * @brief Percent-encodes a path as SigV4 requires: everything but unreserved characters and slashes.
+ */
+func escapeS3Path(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,256 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file spectator.go
+ * @brief Read-only streaming of a served simulation to many viewers (serve -spectate-addr).
+ * @details Spectators connect to GET /ws on a listener of their own, apart from the API that
+ * -api-keys protects, and receive one JSON message per frame: the populations and the grid
+ * downsampled to at most -spectate-size cells per side. Frames are taken at most -spectate-fps
+ * times a second, whatever the simulation's pace, and encoded once for every viewer. Each
+ * viewer has a small queue of its own; a viewer that reads too slowly loses its oldest queued
+ * frames instead of holding up the others, and one that stalls for wsWriteTimeout is dropped.
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/** Frames queued per spectator before the oldest is dropped. */
+const spectatorQueue = 4
+
+/** Connections a single address may hold, so that one host cannot take every slot. */
+const spectatorsPerHost = 4
+
+/**
+ * @struct spectatorDocument
+ * @brief JSON form of a downsampled frame sent to spectators.
+ */
+type spectatorDocument struct {
+	Chronon         int      `json:"chronon"`
+	Size            int      `json:"size"`  ///< Cells per side of the world
+	Scale           int      `json:"scale"` ///< World cells per side of one row character
+	Fish            int      `json:"fish"`
+	Sharks          int      `json:"sharks"`
+	MeanSharkEnergy float64  `json:"mean_shark_energy"`
+	Rows            []string `json:"rows"` ///< One string per row of blocks: the block's most common of F, S and .
+}
+
+/**
+ * @brief Converts a frame to its spectator form.
+ * @param f The frame.
+ * @param maxSize Largest number of blocks per side; larger grids are sampled in square blocks.
+ */
+func newSpectatorDocument(f *Frame, maxSize int) spectatorDocument {
+	fish, sharks := f.Counts()
+	scale := blockScale(f.Size(), 1, maxSize)
+	doc := spectatorDocument{Chronon: f.Chronon(), Size: f.Size(), Scale: scale, Fish: fish, Sharks: sharks, MeanSharkEnergy: f.MeanSharkEnergy()}
+	blocks := (f.Size() + scale - 1) / scale
+	row := make([]byte, blocks)
+	for r := 0; r < blocks; r++ {
+		for c := range row {
+			b := sampleBlock(f, r, c, scale)
+			switch water := b.cells - b.fish - b.sharks; {
+			case b.sharks > 0 && b.sharks >= b.fish && b.sharks >= water:
+				row[c] = 'S'
+			case b.fish > 0 && b.fish >= water:
+				row[c] = 'F'
+			default:
+				row[c] = '.'
+			}
+		}
+		doc.Rows = append(doc.Rows, string(row))
+	}
+	return doc
+}
+
+/**
+ * @struct spectator
+ * @brief One connected viewer.
+ */
+type spectator struct {
+	host  string
+	queue chan []byte ///< Encoded frames waiting to be written
+}
+
+/**
+ * @struct SpectatorHub
+ * @brief Broadcasts encoded frames to every connected spectator.
+ */
+type SpectatorHub struct {
+	maxClients int
+	mu         sync.Mutex
+	clients    map[*spectator]struct{}
+	hosts      map[string]int ///< Connections per remote host
+	latest     []byte         ///< Last message broadcast, sent first to new spectators
+	dropped    atomic.Int64   ///< Frames dropped because a spectator fell behind
+	stop       chan struct{}  ///< Closed when Run returns, ending every stream
+}
+
+/**
+ * @brief Creates a hub without spectators.
+ * @param maxClients Most spectators connected at once.
+ */
+func NewSpectatorHub(maxClients int) *SpectatorHub {
+	return &SpectatorHub{maxClients: maxClients, clients: make(map[*spectator]struct{}), hosts: make(map[string]int), stop: make(chan struct{})}
+}
+
+/**
+ * @brief Queues a message for every spectator without waiting for any of them.
+ * @details A spectator whose queue is full loses its oldest frame, so it always catches up to
+ * the newest one once it reads again.
+ */
+func (h *SpectatorHub) Broadcast(msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.latest = msg
+	for s := range h.clients {
+		for {
+			select {
+			case s.queue <- msg:
+			default:
+				select {
+				case <-s.queue:
+					h.dropped.Add(1)
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+/**
+ * @brief Returns the number of connected spectators.
+ */
+func (h *SpectatorHub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+/**
+ * @brief Adds a spectator from a host, unless the hub or the host is full.
+ * @return The spectator, with the latest frame already queued, or an error.
+ */
+func (h *SpectatorHub) join(host string) (*spectator, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.clients) >= h.maxClients {
+		return nil, errors.New("too many spectators; try again later")
+	}
+	if h.hosts[host] >= spectatorsPerHost {
+		return nil, errors.New("too many spectators from this address")
+	}
+	s := &spectator{host: host, queue: make(chan []byte, spectatorQueue)}
+	if h.latest != nil {
+		s.queue <- h.latest
+	}
+	h.clients[s] = struct{}{}
+	h.hosts[host]++
+	return s, nil
+}
+
+/**
+ * @brief Removes a spectator.
+ */
+func (h *SpectatorHub) leave(s *spectator) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, s)
+	if h.hosts[s.host]--; h.hosts[s.host] <= 0 {
+		delete(h.hosts, s.host)
+	}
+}
+
+/**
+ * @brief Handles GET /ws: streams frames to one spectator until it leaves or falls too far behind.
+ */
+func (h *SpectatorHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	s, err := h.join(host)
+	if err != nil {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer h.leave(s)
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		conn.discardReads()
+		close(closed)
+	}()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-h.stop:
+			conn.writeFrame(wsClose, []byte{0x03, 0xE9}) ///< 1001: going away
+			return
+		case msg := <-s.queue:
+			if err := conn.WriteText(msg); err != nil {
+				slog.Debug("spectator dropped", "host", host, "err", err)
+				return
+			}
+		}
+	}
+}
+
+/**
+ * @brief Broadcasts the simulation's current frame at most fps times a second, until ctx is cancelled.
+ * @details A frame is only encoded when the chronon has changed and someone is watching. When
+ * Run returns, every spectator is sent a close frame.
+ * @param maxSize Largest number of blocks per side sent, see newSpectatorDocument.
+ */
+func (h *SpectatorHub) Run(ctx context.Context, sim *Simulation, fps float64, maxSize int) {
+	defer close(h.stop)
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / fps))
+	defer ticker.Stop()
+	last := -1
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("spectator stream ended", "dropped_frames", h.dropped.Load())
+			return
+		case <-ticker.C:
+		}
+		f := sim.Snapshot()
+		if f.Chronon() == last || h.Len() == 0 {
+			continue
+		}
+		msg, err := json.Marshal(newSpectatorDocument(f, maxSize))
+		if err != nil {
+			slog.Error("spectator frame failed", "err", err)
+			continue
+		}
+		last = f.Chronon()
+		h.Broadcast(msg)
+	}
+}
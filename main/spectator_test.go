@@ -0,0 +1,120 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file spectator_test.go
+ * @brief Tests for the spectator stream and its WebSocket transport.
+ */
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpectatorDocumentDownsamples(t *testing.T) {
+	f := frameFromASCII(t, "FF..\nF.SS\n..S.\n....\n", 3)
+	doc := newSpectatorDocument(f, 2)
+	if doc.Size != 4 || doc.Scale != 2 || doc.Fish != 3 || doc.Sharks != 3 {
+		t.Errorf("got size %d, scale %d, %d fish, %d sharks; want 4, 2, 3, 3", doc.Size, doc.Scale, doc.Fish, doc.Sharks)
+	}
+	if want := []string{"FS", ".."}; !slices.Equal(doc.Rows, want) {
+		t.Errorf("rows %q, want %q", doc.Rows, want)
+	}
+	if doc := newSpectatorDocument(f, 100); doc.Scale != 1 || doc.Rows[1] != "F.SS" {
+		t.Errorf("a grid within the limit should not be downsampled, got scale %d rows %q", doc.Scale, doc.Rows)
+	}
+}
+
+func TestSpectatorHubDropsOldestForSlowClients(t *testing.T) {
+	hub := NewSpectatorHub(10)
+	slow, err := hub.join("10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 10 {
+		hub.Broadcast([]byte{byte('0' + i)})
+	}
+	var got []byte
+	for len(slow.queue) > 0 {
+		got = append(got, <-slow.queue...)
+	}
+	if string(got) != "6789" || hub.dropped.Load() != 6 {
+		t.Errorf("slow client kept %q with %d dropped, want the newest 6789 with 6 dropped", got, hub.dropped.Load())
+	}
+
+	for range spectatorsPerHost - 1 {
+		if _, err := hub.join("10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := hub.join("10.0.0.1"); err == nil {
+		t.Error("a host past spectatorsPerHost was admitted")
+	}
+	if s, err := hub.join("10.0.0.2"); err != nil || string(<-s.queue) != "9" {
+		t.Errorf("a new spectator should start with the latest frame, got %v", err)
+	}
+}
+
+func TestSpectatorWebSocketStream(t *testing.T) {
+	hub := NewSpectatorHub(10)
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: wator\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake: %s, accept %q", resp.Status, resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); hub.Len() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the spectator was not registered with the hub within 5s")
+		}
+	}
+	want, _ := json.Marshal(newSpectatorDocument(frameFromASCII(t, "FS\n..\n", 3), 10))
+	hub.Broadcast(want)
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		t.Fatal(err)
+	}
+	if head[0] != 0x80|wsText || int(head[1]) != len(want) {
+		t.Fatalf("frame header %x, want a final text frame of %d bytes", head, len(want))
+	}
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(r, got); err != nil || !strings.Contains(string(got), `"rows":["FS",".."]`) {
+		t.Errorf("message %s, %v", got, err)
+	}
+
+	conn.Write([]byte{0x80 | wsClose, 0x80, 1, 2, 3, 4}) ///< A masked close frame without a body
+	if _, err := io.ReadFull(r, head); err != nil || head[0] != 0x80|wsClose {
+		t.Errorf("close reply %x, %v", head, err)
+	}
+}
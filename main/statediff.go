@@ -0,0 +1,253 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file statediff.go
+ * @brief The diff subcommand: compares two saved worlds cell by cell.
+ * @details Either side may be a checkpoint (-checkpoint) or an ASCII map (the -grid format).
+ * Cells are compared by species, and for two checkpoints also by breeding counter and shark
+ * energy, which an ASCII map does not record. The report gives both worlds' populations, how
+ * many cells went from each species to each other one, the first differing cell in
+ * row-major order, and the coordinates of the changed cells, which is usually enough to tell
+ * a refactored engine's bug from a harmless change in evaluation order.
+ */
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+/**
+ * @struct savedWorld
+ * @brief A world read by diff, with what its file records.
+ */
+type savedWorld struct {
+	*Checkpoint
+	path     string
+	detailed bool ///< Whether breeding counters and energies are known (checkpoints only)
+}
+
+/**
+ * @struct cellChange
+ * @brief One cell that differs between two worlds.
+ */
+type cellChange struct {
+	X, Y int
+	A, B checkpointEntity ///< Occupants; Species is "" for an empty cell
+}
+
+/**
+ * @struct StateDiff
+ * @brief The differences between two worlds of the same size.
+ */
+type StateDiff struct {
+	Transitions [3][3]int    ///< Cells by species in A (row) and B (column), indexed by Species
+	Changes     []cellChange ///< Every differing cell, in row-major order
+	StateOnly   int          ///< Changes with the same species but a different breeding counter or energy
+}
+
+/**
+ * @brief The diff subcommand.
+ * @param args Arguments after the command name.
+ * @return exitOK for identical worlds, exitFailure if they differ, exitConfigError for bad input.
+ */
+func cmdDiff(args []string) int {
+	fs := flag.NewFlagSet("wator diff", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "list at most `n` changed cells (0 lists all)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wator diff [flags] <a> <b>")
+		fmt.Fprintln(fs.Output(), "Compares two checkpoints or ASCII maps cell by cell and summarises the differences.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitConfigError
+	}
+	if fs.NArg() != 2 || *limit < 0 {
+		fs.Usage()
+		return exitConfigError
+	}
+	var worlds [2]*savedWorld
+	for i, path := range fs.Args() {
+		w, err := readSavedWorld(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return exitConfigError
+		}
+		worlds[i] = w
+	}
+	if worlds[0].Size != worlds[1].Size {
+		fmt.Printf("sizes differ: %dx%d against %dx%d\n", worlds[0].Size, worlds[0].Size, worlds[1].Size, worlds[1].Size)
+		return exitFailure
+	}
+
+	d := diffWorlds(worlds[0], worlds[1])
+	d.Write(os.Stdout, worlds[0], worlds[1], *limit)
+	if len(d.Changes) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+/**
+ * @brief Reads a checkpoint, or failing that an ASCII map.
+ * @return The world, or an error if the file is neither.
+ */
+func readSavedWorld(path string) (*savedWorld, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		cp, err := ReadCheckpoint(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &savedWorld{Checkpoint: cp, path: path, detailed: true}, nil
+	}
+	g, err := ReadASCII(bytes.NewReader(data), 0)
+	if err != nil {
+		return nil, fmt.Errorf("neither a checkpoint nor an ASCII map: %w", err)
+	}
+	return &savedWorld{Checkpoint: NewCheckpoint(g, Config{}), path: path}, nil
+}
+
+/**
+ * @brief Indexes a world's entities by cell, row-major.
+ */
+func (w *savedWorld) cells() []checkpointEntity {
+	cells := make([]checkpointEntity, w.Size*w.Size)
+	for _, e := range w.Entities {
+		if e.X >= 0 && e.X < w.Size && e.Y >= 0 && e.Y < w.Size {
+			cells[e.X*w.Size+e.Y] = e
+		}
+	}
+	return cells
+}
+
+/**
+ * @brief Returns the species code of a checkpointed cell.
+ */
+func entitySpecies(e checkpointEntity) Species {
+	switch e.Species {
+	case "fish":
+		return SpeciesFish
+	case "shark":
+		return SpeciesShark
+	}
+	return SpeciesNone
+}
+
+/**
+ * @brief Compares two worlds of the same size.
+ * @details Breeding counters and energies are only compared when both worlds record them.
+ */
+func diffWorlds(a, b *savedWorld) StateDiff {
+	var d StateDiff
+	detailed := a.detailed && b.detailed
+	ca, cb := a.cells(), b.cells()
+	for i := range ca {
+		sa, sb := entitySpecies(ca[i]), entitySpecies(cb[i])
+		d.Transitions[sa][sb]++
+		x, y := i/a.Size, i%a.Size
+		ea, eb := ca[i], cb[i]
+		ea.X, ea.Y, eb.X, eb.Y = x, y, x, y ///< Empty cells carry no coordinates
+		switch {
+		case sa != sb:
+			d.Changes = append(d.Changes, cellChange{X: x, Y: y, A: ca[i], B: cb[i]})
+		case detailed && ea != eb:
+			d.Changes = append(d.Changes, cellChange{X: x, Y: y, A: ca[i], B: cb[i]})
+			d.StateOnly++ ///< Breeding counter, energy, juvenile or sated chronons
+		}
+	}
+	return d
+}
+
+/**
+ * @brief Describes a cell's occupant, e.g. "shark (breed 2, energy 3)".
+ */
+func describeCell(e checkpointEntity, detailed bool) string {
+	switch {
+	case e.Species == "":
+		return "empty"
+	case !detailed:
+		return e.Species
+	case e.Species == "shark" && e.Sated > 0:
+		return fmt.Sprintf("shark (breed %d, energy %d, sated %d)", e.BreedCounter, e.Energy, e.Sated)
+	case e.Species == "shark":
+		return fmt.Sprintf("shark (breed %d, energy %d)", e.BreedCounter, e.Energy)
+	}
+	if e.Juvenile > 0 {
+		return fmt.Sprintf("fish (breed %d, juvenile %d)", e.BreedCounter, e.Juvenile)
+	}
+	return fmt.Sprintf("%s (breed %d)", e.Species, e.BreedCounter)
+}
+
+/**
+ * @brief Writes the report.
+ * @param w Destination writer.
+ * @param a, b The compared worlds.
+ * @param limit Changed cells to list; 0 lists all.
+ */
+func (d StateDiff) Write(w io.Writer, a, b *savedWorld, limit int) {
+	var counts [2][3]int ///< Cells per species in A and B
+	for from := range d.Transitions {
+		for to, n := range d.Transitions[from] {
+			counts[0][from] += n
+			counts[1][to] += n
+		}
+	}
+	for i, s := range []*savedWorld{a, b} {
+		fmt.Fprintf(w, "%c: %s, chronon %d, %dx%d, %d fish, %d sharks\n", 'a'+i, s.path, s.Chronon, s.Size, s.Size,
+			counts[i][SpeciesFish], counts[i][SpeciesShark])
+	}
+	if len(d.Changes) == 0 {
+		fmt.Fprintln(w, "identical")
+		return
+	}
+
+	cells := a.Size * a.Size
+	fmt.Fprintf(w, "%d of %d cells differ (%.2f%%)", len(d.Changes), cells, 100*float64(len(d.Changes))/float64(cells))
+	if d.StateOnly > 0 {
+		fmt.Fprintf(w, ", %d of them only in breeding counter or energy", d.StateOnly)
+	}
+	fmt.Fprintln(w)
+	names := [3]string{"empty", "fish", "shark"}
+	for from := range d.Transitions {
+		for to, n := range d.Transitions[from] {
+			if from != to && n > 0 {
+				fmt.Fprintf(w, "  %s -> %s: %d\n", names[from], names[to], n)
+			}
+		}
+	}
+
+	detailed := a.detailed && b.detailed
+	first := d.Changes[0]
+	fmt.Fprintf(w, "first differing cell: (%d,%d): a has %s, b has %s\n", first.X, first.Y, describeCell(first.A, detailed), describeCell(first.B, detailed))
+	shown := d.Changes
+	if limit > 0 && len(shown) > limit {
+		shown = shown[:limit]
+	}
+	fmt.Fprintln(w, "changed cells:")
+	for _, c := range shown {
+		fmt.Fprintf(w, "  (%d,%d) %s -> %s\n", c.X, c.Y, describeCell(c.A, detailed), describeCell(c.B, detailed))
+	}
+	if len(shown) < len(d.Changes) {
+		fmt.Fprintf(w, "  ... and %d more (-limit 0 lists all)\n", len(d.Changes)-len(shown))
+	}
+}
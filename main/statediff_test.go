@@ -0,0 +1,88 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file statediff_test.go
+ * @brief Tests for the diff command.
+ */
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Builds a checkpoint of a 4x4 world from its entities.
+ */
+func diffWorld(entities ...checkpointEntity) *savedWorld {
+	return &savedWorld{Checkpoint: &Checkpoint{Version: checkpointVersion, Size: 4, Entities: entities}, detailed: true}
+}
+
+func TestDiffWorlds(t *testing.T) {
+	a := diffWorld(
+		checkpointEntity{X: 0, Y: 1, Species: "fish", BreedCounter: 2},
+		checkpointEntity{X: 1, Y: 1, Species: "shark", BreedCounter: 1, Energy: 3},
+		checkpointEntity{X: 3, Y: 3, Species: "fish"},
+	)
+	b := diffWorld(
+		checkpointEntity{X: 0, Y: 1, Species: "fish", BreedCounter: 2},
+		checkpointEntity{X: 1, Y: 1, Species: "shark", BreedCounter: 1, Energy: 2},
+		checkpointEntity{X: 2, Y: 0, Species: "shark"},
+	)
+	d := diffWorlds(a, b)
+	if len(d.Changes) != 3 || d.StateOnly != 1 {
+		t.Fatalf("%d changes, %d state-only; want 3 and 1", len(d.Changes), d.StateOnly)
+	}
+	if c := d.Changes[0]; c.X != 1 || c.Y != 1 {
+		t.Errorf("first change at (%d,%d), want (1,1)", c.X, c.Y)
+	}
+	if d.Transitions[SpeciesFish][SpeciesNone] != 1 || d.Transitions[SpeciesNone][SpeciesShark] != 1 || d.Transitions[SpeciesNone][SpeciesNone] != 12 {
+		t.Errorf("transitions %v", d.Transitions)
+	}
+
+	a.detailed = false ///< An ASCII map on one side: only species count
+	if d := diffWorlds(a, b); len(d.Changes) != 2 || d.StateOnly != 0 {
+		t.Errorf("against a map: %d changes, %d state-only; want 2 and 0", len(d.Changes), d.StateOnly)
+	}
+
+	var out strings.Builder
+	d.Write(&out, a, b, 1)
+	for _, want := range []string{"3 of 16 cells differ", "fish -> empty: 1", "first differing cell: (1,1)", "and 2 more"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	dir := t.TempDir()
+	cp := filepath.Join(dir, "a.wtr")
+	if err := diffWorld(checkpointEntity{X: 0, Y: 0, Species: "fish"}).WriteFile(cp); err != nil {
+		t.Fatal(err)
+	}
+	same := filepath.Join(dir, "same.txt")
+	other := filepath.Join(dir, "other.txt")
+	os.WriteFile(same, []byte("F...\n....\n....\n....\n"), 0o644)
+	os.WriteFile(other, []byte("S...\n....\n....\n....\n"), 0o644)
+
+	if code := cmdDiff([]string{cp, same}); code != exitOK {
+		t.Errorf("checkpoint against its map: exit %d, want %d", code, exitOK)
+	}
+	if code := cmdDiff([]string{cp, other}); code != exitFailure {
+		t.Errorf("different worlds: exit %d, want %d", code, exitFailure)
+	}
+	if code := cmdDiff([]string{cp, filepath.Join(dir, "missing")}); code != exitConfigError {
+		t.Errorf("missing file: exit %d, want %d", code, exitConfigError)
+	}
+}
@@ -0,0 +1,244 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file storage.go
+ * @brief Interchangeable cell storage backends for the Grid.
+ * @details All engines and tools access cells through Grid.At and Grid.Set, so the memory
+ * layout can be chosen per run with -storage:
+ *  - "entities": a slice of rows holding Entity interface values (the original layout).
+ *  - "cells": one flat slice of structs with separate Fish and Shark pointers. Rows are
+ *    contiguous in memory.
+ *  - "sparse": one hash map per row holding only the occupied cells, so memory grows with
+ *    the population rather than the area. Lookups stay O(1). Measured as the peak RSS of
+ *    "wator bench -engines sections -chronons 1", a 10000x10000 ocean at 1% fill (a million
+ *    entities) takes 435MB with this layout and 3.4GB with "entities"; 5000x5000 at 1% takes
+ *    134MB. About 200 bytes per entity are the entity and its map entries in the old and new
+ *    grid; the rest is mostly the one byte per cell of a Frame, which any snapshot allocates.
+ *  - "chunks": 64x64 chunks allocated on first write (see chunks.go); empty chunks are
+ *    skipped by the engines.
+ * The dense layouts also keep a species byte per cell, which the engines' scans and neighbour
+ * searches read through Grid.speciesAt, so looking for empty cells, fish or sharks is a byte
+ * comparison; the Entity value is only loaded for the cells an engine actually updates.
+ */
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/**
+ * @brief Backing store for the cells of a square grid.
+ * @details Implementations need not be safe for concurrent writes to the same cell; the
+ * engines guarantee that themselves.
+ */
+type storage interface {
+	at(x, y int) Entity           ///< Returns the entity at (x, y), or nil
+	set(x, y int, e Entity)       ///< Stores e (possibly nil) at (x, y)
+	species(x, y int) Species     ///< Returns the species at (x, y) without loading the entity
+	row(x int) (fish, sharks int) ///< Returns the fish and sharks of row x, counted as cells are set
+	empty() storage               ///< Returns a new, empty store of the same size and kind
+	next(x, y int) int            ///< Returns the first column from y on in row x that may be occupied, or the size
+}
+
+/** Storage constructors by -storage name. */
+var storages = map[string]func(size int) storage{
+	"entities": newEntityStorage,
+	"cells":    newCellStorage,
+	"sparse":   newSparseStorage,
+	"chunks":   newChunkStorage,
+}
+
+/**
+ * @brief Returns the registered storage names in sorted order.
+ */
+func storageNames() []string {
+	names := make([]string, 0, len(storages))
+	for name := range storages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/**
+ * @brief Looks up a storage constructor by name.
+ * @param name The storage name.
+ * @return The constructor, or an error listing the valid names.
+ */
+func storageByName(name string) (func(size int) storage, error) {
+	if s, ok := storages[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown storage %q (want %s)", name, strings.Join(storageNames(), " or "))
+}
+
+/**
+ * @struct entityStorage
+ * @brief Rows of Entity interface values.
+ */
+type entityStorage struct {
+	cells  [][]Entity
+	kinds  []Species ///< Row-major species of each cell
+	counts rowCounts
+}
+
+func newEntityStorage(size int) storage {
+	return &entityStorage{cells: newCells(size), kinds: make([]Species, size*size), counts: make(rowCounts, size)}
+}
+
+func (s *entityStorage) at(x, y int) Entity { return s.cells[x][y] }
+func (s *entityStorage) set(x, y int, e Entity) {
+	i, sp := x*len(s.cells)+y, speciesOf(e)
+	s.counts.change(x, s.kinds[i], sp)
+	s.cells[x][y], s.kinds[i] = e, sp
+}
+func (s *entityStorage) row(x int) (int, int)     { return s.counts.row(x) }
+func (s *entityStorage) species(x, y int) Species { return s.kinds[x*len(s.cells)+y] }
+func (s *entityStorage) empty() storage           { return newEntityStorage(len(s.cells)) }
+func (s *entityStorage) next(_, y int) int        { return y }
+
+/**
+ * @struct cell
+ * @brief One grid position in the "cells" layout; at most one pointer is non-nil.
+ */
+type cell struct {
+	fish  *Fish
+	shark *Shark
+}
+
+/**
+ * @struct cellStorage
+ * @brief Flat, row-major slice of cell structs.
+ */
+type cellStorage struct {
+	size   int
+	cells  []cell
+	kinds  []Species ///< Species of each cell, in the same order
+	counts rowCounts
+}
+
+func newCellStorage(size int) storage {
+	return &cellStorage{size: size, cells: make([]cell, size*size), kinds: make([]Species, size*size), counts: make(rowCounts, size)}
+}
+
+func (s *cellStorage) at(x, y int) Entity {
+	c := &s.cells[x*s.size+y]
+	if c.fish != nil {
+		return c.fish
+	}
+	if c.shark != nil {
+		return c.shark
+	}
+	return nil ///< Untyped nil, so callers can compare against nil
+}
+
+func (s *cellStorage) set(x, y int, e Entity) {
+	i := x*s.size + y
+	s.counts.change(x, s.kinds[i], speciesOf(e))
+	switch v := e.(type) {
+	case *Fish:
+		s.cells[i], s.kinds[i] = cell{fish: v}, SpeciesFish
+	case *Shark:
+		s.cells[i], s.kinds[i] = cell{shark: v}, SpeciesShark
+	default:
+		s.cells[i], s.kinds[i] = cell{}, SpeciesNone
+	}
+}
+
+func (s *cellStorage) species(x, y int) Species { return s.kinds[x*s.size+y] }
+func (s *cellStorage) row(x int) (int, int)     { return s.counts.row(x) }
+func (s *cellStorage) empty() storage           { return newCellStorage(s.size) }
+func (s *cellStorage) next(_, y int) int        { return y }
+
+/**
+ * @struct sparseRow
+ * @brief The occupied cells of one row, keyed by column.
+ * @details Workers of the row-partitioned engines write into the rows next to their section
+ * as well as their own, and Go maps do not allow concurrent writes even to different keys, so
+ * every row has its own lock. The map is only allocated once the row is first written.
+ */
+type sparseRow struct {
+	mu     sync.RWMutex
+	cells  map[int32]Entity
+	fish   int ///< Fish among cells, guarded by mu
+	sharks int
+}
+
+/**
+ * @struct sparseStorage
+ * @brief One hash map of occupied cells per row.
+ */
+type sparseStorage struct {
+	rows []sparseRow
+}
+
+func newSparseStorage(size int) storage {
+	return &sparseStorage{rows: make([]sparseRow, size)}
+}
+
+func (s *sparseStorage) at(x, y int) Entity {
+	r := &s.rows[x]
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cells[int32(y)] ///< A missing key, or a nil map, yields nil
+}
+
+func (s *sparseStorage) set(x, y int, e Entity) {
+	r := &s.rows[x]
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count(speciesOf(r.cells[int32(y)]), -1)
+	r.count(speciesOf(e), 1)
+	switch {
+	case e != nil && r.cells == nil:
+		r.cells = map[int32]Entity{int32(y): e}
+	case e != nil:
+		r.cells[int32(y)] = e
+	default:
+		delete(r.cells, int32(y)) ///< Emptied cells take no memory
+	}
+}
+
+/**
+ * @brief Adds n to the row's count of a species; the caller holds the write lock.
+ */
+func (r *sparseRow) count(sp Species, n int) {
+	switch sp {
+	case SpeciesFish:
+		r.fish += n
+	case SpeciesShark:
+		r.sharks += n
+	}
+}
+
+func (s *sparseStorage) row(x int) (int, int) {
+	r := &s.rows[x]
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fish, r.sharks
+}
+
+func (s *sparseStorage) species(x, y int) Species { return speciesOf(s.at(x, y)) } ///< No bytes per cell here
+func (s *sparseStorage) empty() storage           { return newSparseStorage(len(s.rows)) }
+
+func (s *sparseStorage) next(x, y int) int {
+	r := &s.rows[x]
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.cells) == 0 {
+		return len(s.rows) ///< Nothing left in this row
+	}
+	return y
+}
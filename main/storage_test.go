@@ -0,0 +1,222 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file storage_test.go
+ * @brief Shared tests and benchmarks for every storage backend.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestStorageRoundTrip(t *testing.T) {
+	for _, name := range storageNames() {
+		g, err := NewGridWithStorage(5, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fish, shark := &Fish{}, &Shark{Energy: 3}
+		g.Set(1, 2, fish)
+		g.Set(4, 0, shark)
+		if g.At(1, 2) != fish || g.At(4, 0) != shark {
+			t.Errorf("%s: stored entities not returned", name)
+		}
+		if g.At(0, 0) != nil {
+			t.Errorf("%s: empty cell is not nil", name)
+		}
+		g.Set(1, 2, shark) ///< Replacing a fish with a shark must drop the fish
+		if _, ok := g.At(1, 2).(*Shark); !ok {
+			t.Errorf("%s: replacing a fish with a shark returned %T", name, g.At(1, 2))
+		}
+		g.Set(4, 0, nil)
+		if g.At(4, 0) != nil {
+			t.Errorf("%s: cleared cell is not nil", name)
+		}
+		if f, s := g.CountEntities(); f != 0 || s != 1 {
+			t.Errorf("%s: counted %d fish and %d sharks, want 0 and 1", name, f, s)
+		}
+		if g.speciesAt(1, 2) != SpeciesShark || g.speciesAt(4, 0) != SpeciesNone || g.speciesAt(3, 3) != SpeciesNone {
+			t.Errorf("%s: species bytes do not follow Set: %v %v %v", name, g.speciesAt(1, 2), g.speciesAt(4, 0), g.speciesAt(3, 3))
+		}
+	}
+}
+
+func TestUnknownStorage(t *testing.T) {
+	if _, err := NewGridWithStorage(5, "tape"); err == nil {
+		t.Error("expected an error for an unknown storage backend")
+	}
+}
+
+func BenchmarkStorage(b *testing.B) {
+	for _, name := range storageNames() {
+		for _, threads := range []int{1, 8} {
+			b.Run(fmt.Sprintf("%s/threads=%d", name, threads), func(b *testing.B) {
+				g, _ := NewGridWithStorage(200, name)
+				g.Seed(1)
+				g.Initialize(12000, 2000, 4)
+				rules := Rules{FishBreed: 3, SharkBreed: 3, StarveEnergy: 4}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					g.stepSections(context.Background(), rules, threads)
+				}
+			})
+		}
+	}
+}
+
+/**
+ * @brief Compares a full-grid scan for sharks by type assertion on At (the engines' scan before
+ * species bytes) with one by species byte.
+ */
+func BenchmarkSpeciesScan(b *testing.B) {
+	for _, name := range storageNames() {
+		g, _ := NewGridWithStorage(500, name)
+		g.Seed(1)
+		g.Initialize(104000, 21000, 4) ///< Half full, a sixth of it sharks
+		scans := map[string]func(x, y int) bool{
+			"assert":  func(x, y int) bool { _, ok := g.At(x, y).(*Shark); return ok },
+			"species": func(x, y int) bool { return g.speciesAt(x, y) == SpeciesShark },
+		}
+		for _, scan := range []string{"assert", "species"} {
+			b.Run(fmt.Sprintf("%s/%s", name, scan), func(b *testing.B) {
+				isShark := scans[scan]
+				for i := 0; i < b.N; i++ {
+					n := 0
+					for x := 0; x < g.Size; x++ {
+						for y := g.nextOccupied(x, 0); y < g.Size; y = g.nextOccupied(x, y+1) {
+							if isShark(x, y) {
+								n++
+							}
+						}
+					}
+					if n != 21000 {
+						b.Fatalf("counted %d sharks", n)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestStoragesMatchDense(t *testing.T) {
+	for _, engine := range engineNames() {
+		hashes := map[string]uint64{}
+		for _, store := range storageNames() {
+			cfg := testConfig()
+			cfg.Engine, cfg.Storage, cfg.Threads = engine, store, 1
+			sim, err := NewSimulation(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sim.Run(context.Background(), 20)
+			hashes[store] = sim.Snapshot().Hash()
+		}
+		for store, h := range hashes {
+			if h != hashes["cells"] {
+				t.Errorf("%s: %s storage diverged from cells: %016x against %016x", engine, store, h, hashes["cells"])
+			}
+		}
+	}
+}
+
+func TestSparseStoragesConcurrentWorkers(t *testing.T) {
+	for _, store := range []string{"sparse", "chunks"} {
+		for _, engine := range engineNames() {
+			cfg := testConfig()
+			cfg.GridSize, cfg.NumFish, cfg.NumShark = 130, 1200, 200 ///< Chunks that straddle the sections
+			cfg.Engine, cfg.Storage, cfg.Threads = engine, store, 8
+			sim, err := NewSimulation(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sim.Run(context.Background(), 20) ///< Unlocked map writes would abort the test binary
+			fish, sharks := sim.Grid().CountEntities()
+			if p := sim.Population(); p.Fish != fish || p.Sharks != sharks {
+				t.Errorf("%s/%s: grid holds %d fish and %d sharks, running counts %+v", store, engine, fish, sharks, p)
+			}
+		}
+	}
+}
+
+func TestChunkStorageSkipsEmptyChunks(t *testing.T) {
+	s := newChunkStorage(200).(*chunkStorage)
+	if got := s.next(150, 0); got != 200 {
+		t.Errorf("empty world: next column %d, want 200", got)
+	}
+	s.set(150, 70, &Fish{})
+	s.set(10, 10, nil) ///< Clearing an unallocated cell must not allocate
+	if n := s.allocated(); n != 1 {
+		t.Errorf("%d chunks allocated, want 1", n)
+	}
+	if got := s.next(150, 0); got != 64 {
+		t.Errorf("next column %d, want 64, the start of the occupied chunk", got)
+	}
+	if got := s.next(150, 71); got != 71 {
+		t.Errorf("next column %d within the occupied chunk, want 71", got)
+	}
+	if got := s.next(100, 0); got != 200 {
+		t.Errorf("row outside any chunk: next column %d, want 200", got)
+	}
+	s.set(150, 70, nil)
+	if got := s.next(150, 0); got != 200 {
+		t.Errorf("emptied chunk: next column %d, want 200", got)
+	}
+}
+
+func TestFastForwardMatchesFullStep(t *testing.T) {
+	for _, engine := range []string{"deterministic", "serial"} {
+		var breeds [2][]int
+		var acted [2][]int64
+		var hashes [2]uint64
+		for i, after := range []int{0, 2} {
+			cfg := testConfig()
+			cfg.GridSize, cfg.NumFish, cfg.NumShark = 160, 0, 0
+			cfg.Engine, cfg.Storage, cfg.FastForward = engine, "chunks", after
+			sim, err := NewSimulation(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var edits []CellEdit
+			for x := -8; x < 72; x++ { ///< A packed band covering the first row of chunks and a margin
+				for y := 0; y < cfg.GridSize; y++ {
+					edits = append(edits, CellEdit{X: x, Y: y, Species: SpeciesFish})
+				}
+			}
+			for y := 0; y < cfg.GridSize; y += 8 {
+				edits = append(edits, CellEdit{X: 110, Y: y, Species: SpeciesShark})
+			}
+			sim.Apply(edits...)
+			sim.Run(context.Background(), 30)
+			if after > 0 && sim.FastForwarded() == 0 {
+				t.Errorf("%s: no chunk was fast-forwarded", engine)
+			}
+			hashes[i] = sim.Snapshot().Hash()
+			g := sim.Grid()
+			checkRowCounts(t, engine, g) ///< Carried chunks bring their fish with them
+			for x := 0; x < g.Size; x++ {
+				for y := 0; y < g.Size; y++ {
+					if f, ok := g.At(x, y).(*Fish); ok {
+						breeds[i] = append(breeds[i], f.BreedCounter)
+						acted[i] = append(acted[i], f.Acted) ///< Carried fish count as having taken their turn
+					}
+				}
+			}
+		}
+		if hashes[0] != hashes[1] || !slices.Equal(breeds[0], breeds[1]) || !slices.Equal(acted[0], acted[1]) {
+			t.Errorf("%s: fast-forwarded run differs from the full one", engine)
+		}
+	}
+}
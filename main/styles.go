@@ -0,0 +1,234 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file styles.go
+ * @brief Per-species symbols and colours (the -style option).
+ * @details Every species, empty water included, has a Style: the symbol the text themes
+ * draw, optional foreground and background colours, and a priority deciding which species a
+ * shown cell takes when it stands for a block of several cells (a zoomed-out -viewport). A
+ * -style value overrides any of them:
+ *
+ *     fish: symbol=o fg=#00aaff; shark: fg=yellow bg=black priority=3; water: symbol=~
+ *
+ * Colours are one of the basic terminal colour names (red, bright-red, ...) or #rrggbb; a
+ * colour left unset keeps the theme's own, so the default styles draw exactly as the themes
+ * always have. The ascii theme only takes symbols, keeping its output free of escapes; the
+ * ansi theme uses basic colours by name and 24-bit colour for #rrggbb; the truecolor theme
+ * and the halfblock and braille renderers use every colour as RGB. Emoji keep their own
+ * glyphs. Palettes such as blue fish and yellow sharks make the two species distinguishable
+ * for red-green colour-blind viewers. The style is a parameter, so parameter files set it
+ * like any other.
+ */
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/**
+ * @struct Style
+ * @brief How one species is drawn.
+ */
+type Style struct {
+	Symbol   string ///< Glyph in the text themes: one printable character
+	FG       string ///< Foreground colour name or #rrggbb; empty keeps the theme's colour
+	BG       string ///< Background colour name or #rrggbb; empty draws none
+	Priority int    ///< A shown cell standing for several takes the highest-priority species among them
+}
+
+/** @brief The styles of every species, indexed by Species code (water first). */
+type Styles [numSpecies]Style
+
+/** Styles used without -style: the classic letters, the themes' colours, and sharks over fish over water. */
+var defaultStyles = Styles{
+	SpeciesNone:  {Symbol: ".", Priority: 0},
+	SpeciesFish:  {Symbol: "F", Priority: 1},
+	SpeciesShark: {Symbol: "S", Priority: 2},
+}
+
+/** Basic terminal colours: SGR foreground code (background is 10 more) and the RGB used by 24-bit renderers. */
+var namedColours = map[string]struct {
+	sgr int
+	rgb [3]int
+}{
+	"black": {30, [3]int{0, 0, 0}}, "red": {31, [3]int{235, 50, 50}}, "green": {32, [3]int{60, 220, 90}},
+	"yellow": {33, [3]int{230, 200, 40}}, "blue": {34, [3]int{40, 90, 230}}, "magenta": {35, [3]int{200, 60, 200}},
+	"cyan": {36, [3]int{40, 200, 220}}, "white": {37, [3]int{220, 220, 220}},
+	"bright-black": {90, [3]int{110, 110, 110}}, "bright-red": {91, [3]int{255, 100, 100}}, "bright-green": {92, [3]int{120, 255, 140}},
+	"bright-yellow": {93, [3]int{255, 240, 100}}, "bright-blue": {94, [3]int{110, 150, 255}}, "bright-magenta": {95, [3]int{255, 120, 255}},
+	"bright-cyan": {96, [3]int{120, 240, 255}}, "bright-white": {97, [3]int{255, 255, 255}},
+}
+
+/**
+ * @brief Returns the RGB value of a colour.
+ * @return The colour, and false if it is empty or invalid.
+ */
+func colourRGB(colour string) ([3]int, bool) {
+	if c, ok := namedColours[colour]; ok {
+		return c.rgb, true
+	}
+	if len(colour) != 7 || colour[0] != '#' {
+		return [3]int{}, false
+	}
+	v, err := strconv.ParseUint(colour[1:], 16, 32)
+	if err != nil {
+		return [3]int{}, false
+	}
+	return [3]int{int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff)}, true
+}
+
+/**
+ * @brief Returns the SGR parameters selecting a colour in the basic palette when it has a name,
+ * in 24-bit colour otherwise.
+ * @param colour A valid colour.
+ * @param background Whether to select the background.
+ */
+func colourSGR(colour string, background bool) string {
+	if c, ok := namedColours[colour]; ok {
+		if background {
+			return strconv.Itoa(c.sgr + 10)
+		}
+		return strconv.Itoa(c.sgr)
+	}
+	return rgbSGR(colour, background)
+}
+
+/**
+ * @brief Returns the SGR parameters selecting a colour in 24-bit colour.
+ * @param colour A valid colour.
+ * @param background Whether to select the background.
+ */
+func rgbSGR(colour string, background bool) string {
+	c, _ := colourRGB(colour)
+	plane := 38
+	if background {
+		plane = 48
+	}
+	return fmt.Sprintf("%d;2;%d;%d;%d", plane, c[0], c[1], c[2])
+}
+
+/**
+ * @brief Wraps a symbol in an SGR sequence; no parameters leaves it bare.
+ */
+func sgrWrap(symbol string, params ...string) string {
+	var set []string
+	for _, p := range params {
+		if p != "" {
+			set = append(set, p)
+		}
+	}
+	if len(set) == 0 {
+		return symbol
+	}
+	return "\033[" + strings.Join(set, ";") + "m" + symbol + "\033[0m"
+}
+
+/**
+ * @brief Returns the SGR parameters of a style's background in the given colour mode, empty without one.
+ */
+func (st Style) bgSGR(basic bool) string {
+	switch {
+	case st.BG == "":
+		return ""
+	case basic:
+		return colourSGR(st.BG, true)
+	}
+	return rgbSGR(st.BG, true)
+}
+
+/**
+ * @brief Parses and validates a -style value on top of the default styles.
+ * @param spec Entries "<species>: key=value ..." separated by semicolons; the species is water
+ * (or empty), fish or shark, and the keys symbol, fg, bg and priority.
+ * @return The styles, or an error naming the bad entry.
+ */
+func parseStyles(spec string) (Styles, error) {
+	styles := defaultStyles
+	if strings.TrimSpace(spec) == "" {
+		return styles, nil
+	}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		name, settings, ok := strings.Cut(entry, ":")
+		if !ok {
+			return styles, fmt.Errorf("expected \"<species>: key=value ...\", got %q", entry)
+		}
+		name = strings.TrimSpace(name)
+		sp, ok := speciesByName(name)
+		if !ok && (strings.EqualFold(name, "water") || strings.EqualFold(name, "empty")) {
+			sp, ok = SpeciesNone, true
+		}
+		if !ok {
+			return styles, fmt.Errorf("unknown species %q (want water, %s)", name, registeredSpeciesNames())
+		}
+		st := &styles[sp]
+		for _, setting := range strings.Fields(settings) {
+			key, value, _ := strings.Cut(setting, "=")
+			switch key {
+			case "symbol":
+				r, n := utf8.DecodeRuneInString(value)
+				if n == 0 || n != len(value) || !unicode.IsPrint(r) || r == ' ' {
+					return styles, fmt.Errorf("%s: symbol must be one printable character, got %q", name, value)
+				}
+				st.Symbol = value
+			case "fg", "bg":
+				if _, ok := colourRGB(value); !ok {
+					return styles, fmt.Errorf("%s: %s must be a colour name or #rrggbb, got %q", name, key, value)
+				}
+				if key == "fg" {
+					st.FG = value
+				} else {
+					st.BG = value
+				}
+			case "priority":
+				p, err := strconv.Atoi(value)
+				if err != nil {
+					return styles, fmt.Errorf("%s: priority must be a whole number, got %q", name, value)
+				}
+				st.Priority = p
+			default:
+				return styles, fmt.Errorf("%s: unknown style key %q (want symbol, fg, bg or priority)", name, key)
+			}
+		}
+	}
+	return styles, nil
+}
+
+/**
+ * @brief Returns the species a shown cell takes from the species present in its block.
+ * @param present Which species occur in the block, water included.
+ * @return The present species of highest priority, the later code winning ties.
+ */
+func (s *Styles) dominant(present [numSpecies]bool) Species {
+	best := Species(0)
+	found := false
+	for sp := range present {
+		if present[sp] && (!found || s[sp].Priority >= s[best].Priority) {
+			best, found = Species(sp), true
+		}
+	}
+	return best
+}
+
+/**
+ * @brief Returns the pixel colour of a species: its foreground colour when set, otherwise def.
+ */
+func (s *Styles) rgb(sp Species, def [3]int) [3]int {
+	if c, ok := colourRGB(s[sp].FG); ok {
+		return c
+	}
+	return def
+}
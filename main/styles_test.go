@@ -0,0 +1,84 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file styles_test.go
+ * @brief Tests for per-species styles.
+ */
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStyles(t *testing.T) {
+	cases := []struct {
+		spec    string
+		wantErr string ///< Substring of the expected error; empty means success
+	}{
+		{spec: ""},
+		{spec: "fish: symbol=o fg=#00aaff; sharks: fg=bright-yellow bg=black priority=3; water: symbol=~"},
+		{spec: "fish symbol=o", wantErr: "expected"},
+		{spec: "squid: fg=red", wantErr: `unknown species "squid"`},
+		{spec: "fish: symbol=ab", wantErr: "one printable character"},
+		{spec: "fish: fg=teal", wantErr: "colour name or #rrggbb"},
+		{spec: "fish: fg=#12345g", wantErr: "colour name or #rrggbb"},
+		{spec: "shark: priority=high", wantErr: "priority must be a whole number"},
+		{spec: "shark: size=2", wantErr: `unknown style key "size"`},
+	}
+	for _, c := range cases {
+		_, err := parseStyles(c.spec)
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", c.spec, err)
+		case c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)):
+			t.Errorf("%q: error %v, want one containing %q", c.spec, err, c.wantErr)
+		}
+	}
+}
+
+func TestStyledThemes(t *testing.T) {
+	if (&Fish{}).Symbol() != "\033[32mF\033[0m" || (&Shark{}).Symbol() != "\033[31mS\033[0m" {
+		t.Error("the default styles changed the ansi symbols")
+	}
+	styles, err := parseStyles("fish: symbol=o fg=blue; shark: fg=#ffcc00 bg=black; water: symbol=~")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := frameFromASCII(t, "FS\n..\n", 3)
+	for _, tc := range []struct{ theme, want, water string }{
+		{"ascii", "| o S |", "| ~ ~ |"},
+		{"ansi", "| \033[34mo\033[0m \033[38;2;255;204;0;40mS\033[0m |", "| ~ ~ |"},
+		{"truecolor", "| \033[38;2;40;90;230mo\033[0m \033[38;2;208;166;0;48;2;0;0;0mS\033[0m |", "| \033[38;2;40;70;140m~\033[0m \033[38;2;40;70;140m~\033[0m |"},
+	} {
+		var out strings.Builder
+		theme := themes[tc.theme]
+		theme.Styles = styles
+		(&TextRenderer{W: &out, Theme: theme, MaxEnergy: 4}).Render(f)
+		if lines := strings.Split(out.String(), "\n"); lines[2] != tc.want || lines[3] != tc.water {
+			t.Errorf("%s: got %q and %q, want %q and %q", tc.theme, lines[2], lines[3], tc.want, tc.water)
+		}
+	}
+}
+
+func TestViewportPriority(t *testing.T) {
+	f := frameFromASCII(t, "FS\n..\n", 3)
+	v := NewViewport(1, 2)
+	if got := v.Crop(f).At(0, 0); got != SpeciesShark {
+		t.Errorf("by default a block with a shark shows %v", got)
+	}
+	styles, _ := parseStyles("fish: priority=5")
+	v.SetStyles(styles)
+	if got := v.Crop(f).At(0, 0); got != SpeciesFish {
+		t.Errorf("with fish above sharks the block shows %v", got)
+	}
+}
@@ -0,0 +1,152 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file summary.go
+ * @brief Machine-readable end-of-run summary (the -summary-json output).
+ * @details Scripts sweeping parameters need the outcome of a run without scraping logs:
+ * final populations, whether and when a species died out, throughput, and the exact
+ * parameters and seed to reproduce it.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+/**
+ * @struct RunSummary
+ * @brief The final JSON document of a run.
+ */
+type RunSummary struct {
+	Chronons          int        `json:"chronons"`           ///< Chronons simulated
+	Fish              int        `json:"fish"`               ///< Final fish population
+	Sharks            int        `json:"sharks"`             ///< Final shark population
+	Extinct           string     `json:"extinct,omitempty"`  ///< "fish", "sharks" or "both" if a species died out
+	ExtinctionChronon *int       `json:"extinction_chronon"` ///< First chronon at which a species was gone, or null
+	Totals            StepCounts `json:"totals"`             ///< Births and deaths over the run
+	Interrupted       bool       `json:"interrupted"`        ///< Whether the run was stopped by a signal
+	WallSeconds       float64    `json:"wall_seconds"`       ///< Elapsed wall-clock time
+	ChrononsPerSec    float64    `json:"chronons_per_sec"`   ///< Simulation throughput
+	Seed              int64      `json:"seed"`               ///< Seed of the run
+	Parameters        Config     `json:"parameters"`         ///< Full configuration, including the seed
+
+	PeakRSSBytes  uint64            `json:"peak_rss_bytes"`           ///< Peak resident memory of the process
+	Fingerprint   string            `json:"fingerprint,omitempty"`    ///< Hash of every chronon's grid hash; equal for identical runs (run command only)
+	Oscillation   *Oscillation      `json:"oscillation"`              ///< Population cycles, or null for a run too short to analyse
+	LotkaVolterra *LotkaVolterraFit `json:"lotka_volterra,omitempty"` ///< Mean-field fit, with -fit-lv
+	Provenance    *Provenance       `json:"provenance"`               ///< Build and host that produced the run
+	Signature     *Signature        `json:"signature,omitempty"`      ///< With -sign
+	Branch        *BranchPoint      `json:"branch,omitempty"`         ///< Where the run was resumed from, with -resume
+}
+
+/**
+ * @struct ExtinctionWatch
+ * @brief Remembers the first chronon at which either species had no members.
+ */
+type ExtinctionWatch struct {
+	chronon *int
+}
+
+/**
+ * @brief Checks a frame for extinction; usable as both a start and an end hook body.
+ * @param f The frame to check.
+ */
+func (w *ExtinctionWatch) Observe(f *Frame) {
+	fish, sharks := f.Counts()
+	w.Add(Population{Chronon: f.Chronon(), Fish: fish, Sharks: sharks})
+}
+
+/**
+ * @brief Checks running populations for extinction; usable from a stats hook.
+ * @param p The populations to check.
+ */
+func (w *ExtinctionWatch) Add(p Population) {
+	if w.chronon == nil && (p.Fish == 0 || p.Sharks == 0) {
+		c := p.Chronon
+		w.chronon = &c
+	}
+}
+
+/**
+ * @brief Returns the first chronon a species was extinct at, or nil if both survived.
+ */
+func (w *ExtinctionWatch) Chronon() *int {
+	return w.chronon
+}
+
+/**
+ * @brief Builds the summary of a finished run.
+ * @param cfg The configuration of the run.
+ * @param final The last frame.
+ * @param totals Births and deaths over the run.
+ * @param watch Extinction tracking of the run.
+ * @param chronons Chronons simulated.
+ * @param elapsed Wall-clock duration of the run.
+ * @param interrupted Whether the run was stopped early by a signal.
+ * @return The summary.
+ */
+func NewRunSummary(cfg Config, final *Frame, totals StepCounts, watch *ExtinctionWatch, chronons int, elapsed time.Duration, interrupted bool) RunSummary {
+	fish, sharks := final.Counts()
+	s := RunSummary{
+		Chronons: chronons, Fish: fish, Sharks: sharks, ExtinctionChronon: watch.Chronon(), Totals: totals,
+		Interrupted: interrupted, WallSeconds: elapsed.Seconds(), Seed: cfg.Seed, Parameters: cfg,
+		Provenance: currentProvenance(),
+	}
+	switch {
+	case fish == 0 && sharks == 0:
+		s.Extinct = "both"
+	case fish == 0:
+		s.Extinct = "fish"
+	case sharks == 0:
+		s.Extinct = "sharks"
+	}
+	if elapsed > 0 {
+		s.ChrononsPerSec = float64(chronons) / elapsed.Seconds()
+	}
+	return s
+}
+
+/**
+ * @brief Writes the summary as indented JSON.
+ * @param w Destination writer.
+ * @return Any write error.
+ */
+func (s RunSummary) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) ///< Keeps "->" in branch changes readable
+	return enc.Encode(s)
+}
+
+/**
+ * @brief Writes the summary to a file, or to standard output when path is "-".
+ * @param path Destination path.
+ * @return An error if the file could not be written.
+ */
+func (s RunSummary) WriteFile(path string) error {
+	if path == "-" {
+		return s.Write(os.Stdout)
+	}
+	f, err := createOutput(path)
+	if err != nil {
+		return fmt.Errorf("creating summary: %w", err)
+	}
+	if err := s.Write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("writing summary %s: %w", path, err)
+	}
+	return f.Close()
+}
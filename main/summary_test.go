@@ -0,0 +1,112 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file summary_test.go
+ * @brief Tests for the -summary-json document.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummaryRecordsExtinction(t *testing.T) {
+	cfg := testConfig()
+	cfg.NumFish, cfg.NumShark, cfg.StarveEnergy = 0, 5, 2 ///< Sharks with nothing to eat starve
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watch := &ExtinctionWatch{}
+	watch.Observe(sim.Snapshot())
+	sim.OnChrononEnd(func(f *Frame, _ StepReport) { watch.Observe(f) })
+	ran, _ := sim.Run(context.Background(), 5)
+
+	s := NewRunSummary(cfg, sim.Snapshot(), StepCounts{}, watch, ran, time.Second, false)
+	if s.Extinct != "both" {
+		t.Errorf("extinct %q, want both", s.Extinct)
+	}
+	if s.ExtinctionChronon == nil || *s.ExtinctionChronon != 0 {
+		t.Errorf("extinction chronon %v, want 0 (no fish from the start)", s.ExtinctionChronon)
+	}
+	if s.ChrononsPerSec != 5 {
+		t.Errorf("chronons/sec %v, want 5", s.ChrononsPerSec)
+	}
+}
+
+func TestSummaryJSON(t *testing.T) {
+	cfg := testConfig()
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewRunSummary(cfg, sim.Snapshot(), StepCounts{FishBorn: 3}, &ExtinctionWatch{}, 0, 0, false)
+
+	var out strings.Builder
+	if err := s.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(out.String()), &doc); err != nil {
+		t.Fatalf("summary is not valid JSON: %v\n%s", err, out.String())
+	}
+	if doc["extinction_chronon"] != nil {
+		t.Errorf("extinction_chronon %v, want null when both species survive", doc["extinction_chronon"])
+	}
+	if _, ok := doc["extinct"]; ok {
+		t.Error("extinct should be omitted when both species survive")
+	}
+	if doc["seed"] != float64(cfg.Seed) {
+		t.Errorf("seed %v, want %d", doc["seed"], cfg.Seed)
+	}
+	if doc["totals"].(map[string]any)["FishBorn"] != float64(3) {
+		t.Errorf("totals %v, want FishBorn 3", doc["totals"])
+	}
+}
+
+func TestSummaryJSONToStdoutIsTheOnlyOutput(t *testing.T) {
+	if args := os.Getenv("WATOR_TEST_RUN_ARGS"); args != "" {
+		os.Exit(runCommand(strings.Fields(args), os.Stderr)) ///< The child: a real run writing to this process's stdout
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSummaryJSONToStdoutIsTheOnlyOutput$")
+	cmd.Env = append(os.Environ(), "WATOR_TEST_RUN_ARGS=run -seed 7 -chronons 5 -set GridSize=12,NumFish=40,NumShark=8 -summary-json -")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if exit := (*exec.ExitError)(nil); err != nil && !(errors.As(err, &exit) && exit.ExitCode() == exitExtinct) {
+		t.Fatalf("run failed: %v\n%s", err, stderr.String())
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(out))
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatalf("standard output is not a JSON document: %v\n%s", err, out)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		t.Errorf("standard output continues after the summary:\n%s", out)
+	}
+	if doc["chronons"] != float64(5) {
+		t.Errorf("chronons %v, want 5", doc["chronons"])
+	}
+	if stderr.Len() == 0 {
+		t.Error("no frames on standard error")
+	}
+}
@@ -0,0 +1,258 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file sweep.go
+ * @brief The sweep subcommand: runs one parameter over a list of values.
+ * @details Each value is simulated headless with seeds seed..seed+n-1 and the outcome of
+ * every run is printed as a CSV row, ready for plotting or a spreadsheet. With -ensemble,
+ * each value instead runs as an ensemble and the sweep prints the per-chronon mean and 95%
+ * confidence interval of both populations, optionally plotted with -plot: single runs are
+ * too noisy to tell parameter sets apart. -task and -tasks make one share of the runs, and
+ * -emit-jobs writes a cluster job running every share (see jobs.go).
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+/**
+ * @brief The sweep subcommand.
+ * @param args Arguments after the command name.
+ * @return The exit code.
+ */
+func cmdSweep(args []string) int {
+	cf := newConfigFlags("sweep", "Runs -param over -values with -seeds seeds each and prints one CSV row per run.", os.Stderr)
+	param := cf.fs.String("param", "", "`name` of the parameter to vary: a positional name such as FishBreed, or a flag such as shark-vision")
+	values := cf.fs.String("values", "", "comma-separated `values` of the parameter")
+	seeds := cf.fs.Int("seeds", 1, "runs per value, with seeds seed..seed+n-1")
+	emit := cf.fs.String("emit-jobs", "", "instead of running, write a `scheduler` job that splits the sweep into -tasks array tasks, and its manifest for wator collect (slurm)")
+	jobsDir := cf.fs.String("jobs-dir", "sweep-jobs", "with -emit-jobs, `directory` for the job script, the manifest and the task outputs")
+	var share sweepShare
+	cf.fs.IntVar(&share.Tasks, "tasks", 0, "split the sweep into `n` tasks (with -emit-jobs, 0 makes one task per run)")
+	cf.fs.IntVar(&share.Task, "task", 0, "with -tasks, make only the share of task `i`, from 0")
+	cfg, code, ok := setupCommand(cf, args)
+	if !ok {
+		return code
+	}
+	if *param == "" || *values == "" || *seeds < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\nsweep needs -param, -values and -seeds of at least 1")
+		return exitConfigError
+	}
+	if cfg.Ensemble > 1 && *seeds > 1 {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\n-seeds prints one row per run and -ensemble aggregates runs; give one of them")
+		return exitConfigError
+	}
+	if share.Tasks < 0 || share.Task < 0 || (share.Tasks > 0 && share.Task >= share.Tasks) {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\n-task must be at least 0 and below -tasks")
+		return exitConfigError
+	}
+	if (share.Tasks > 0 || *emit != "") && cfg.Plot != "" {
+		fmt.Fprintln(os.Stderr, "Invalid parameters:\n-plot draws the whole sweep; plot the output of wator collect instead of split tasks")
+		return exitConfigError
+	}
+	if *emit != "" && *emit != "slurm" {
+		fmt.Fprintf(os.Stderr, "Invalid parameters:\nunknown -emit-jobs scheduler %q (want slurm)\n", *emit)
+		return exitConfigError
+	}
+
+	var configs []Config
+	for _, v := range strings.Split(*values, ",") {
+		c, err := cf.with(map[string]string{*param: strings.TrimSpace(v)})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid parameters:\n%v\n", err)
+			return exitConfigError
+		}
+		configs = append(configs, c)
+	}
+	if *emit != "" {
+		return emitSweepJobs(cf, cfg, *param, strings.Split(*values, ","), *seeds, share.Tasks, *jobsDir, len(configs))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var err error
+	if cfg.Ensemble > 1 {
+		err = runSweepEnsembles(ctx, *param, strings.Split(*values, ","), configs, share, os.Stdout)
+	} else {
+		err = runSweep(ctx, *param, strings.Split(*values, ","), configs, cfg.Seed, *seeds, share, os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if ctx.Err() != nil {
+			return exitInterrupted
+		}
+		return exitFailure
+	}
+	return exitOK
+}
+
+/**
+ * @brief Runs every configuration with every seed and writes one CSV row per run.
+ * @param ctx Context of the sweep; cancelling it stops after the current run.
+ * @param param Name of the swept parameter, for the first column.
+ * @param values The value of the parameter in each configuration.
+ * @param configs One configuration per value.
+ * @param seed The first seed.
+ * @param seeds Runs per configuration.
+ * @param share The runs to make, numbered value by value and seed by seed; the zero value makes all.
+ * @param w Destination of the CSV table.
+ * @return An error if a run failed, the sweep was interrupted, or the table could not be written.
+ */
+func runSweep(ctx context.Context, param string, values []string, configs []Config, seed int64, seeds int, share sweepShare, w io.Writer) error {
+	var results *ResultsDB
+	if db := configs[0].Database; db != "" {
+		var err error
+		if results, err = OpenResultsDB(db); err != nil {
+			return err
+		}
+		defer results.Close()
+	}
+	out := csv.NewWriter(w)
+	defer out.Flush()
+	out.Write([]string{"param", "value", "seed", "chronons", "fish", "sharks", "extinct", "extinction_chronon",
+		"fish_period", "fish_amplitude", "sharks_period", "sharks_amplitude"})
+	for i, c := range configs {
+		for s := 0; s < seeds; s++ {
+			if !share.owns(i*seeds + s) {
+				continue
+			}
+			c.Seed = seed + int64(s)
+			sum, err := runSweepRun(ctx, c, results, param, strings.TrimSpace(values[i]))
+			if err != nil {
+				return err
+			}
+			if sum.Interrupted {
+				return fmt.Errorf("sweep interrupted")
+			}
+			extinction := ""
+			if sum.ExtinctionChronon != nil {
+				extinction = strconv.Itoa(*sum.ExtinctionChronon)
+			}
+			out.Write(append([]string{param, strings.TrimSpace(values[i]), strconv.FormatInt(c.Seed, 10), strconv.Itoa(sum.Chronons),
+				strconv.Itoa(sum.Fish), strconv.Itoa(sum.Sharks), sum.Extinct, extinction}, sum.Oscillation.record()...))
+			out.Flush() ///< Rows appear as runs finish
+		}
+	}
+	return out.Error()
+}
+
+/**
+ * @brief Makes one run of a sweep, recording it in the results database if there is one.
+ * @param results The -db database, or nil.
+ * @return The run's summary, or an error if it could not be created or recorded.
+ */
+func runSweepRun(ctx context.Context, cfg Config, results *ResultsDB, param, value string) (RunSummary, error) {
+	if results == nil {
+		return runHeadless(ctx, cfg)
+	}
+	record, err := results.BeginRun("sweep", param, value, cfg)
+	if err != nil {
+		return RunSummary{}, err
+	}
+	sum, _, err := runRecorded(ctx, cfg, record.Attach)
+	if ferr := record.Finish(sum, err); err == nil {
+		err = ferr
+	}
+	return sum, err
+}
+
+/**
+ * @brief Runs every configuration as an ensemble and writes one CSV row per value and chronon.
+ * @details Each configuration's Ensemble gives the number of members, with seeds
+ * Seed..Seed+n-1 as in run -ensemble, so every value sees the same seeds. With Plot set,
+ * the means and confidence bands of all values are drawn into one SVG.
+ * @param ctx Context of the sweep; cancelling it stops the current ensemble.
+ * @param param Name of the swept parameter, for the first column.
+ * @param values The value of the parameter in each configuration.
+ * @param configs One configuration per value.
+ * @param share The values to run, by index; the zero value runs all.
+ * @param w Destination of the CSV table.
+ * @return An error if an ensemble failed, the sweep was interrupted, or the output could not be written.
+ */
+func runSweepEnsembles(ctx context.Context, param string, values []string, configs []Config, share sweepShare, w io.Writer) error {
+	out := csv.NewWriter(w)
+	defer out.Flush()
+	out.Write(append([]string{"param", "value"}, ensembleColumns...))
+	var series []PlotSeries
+	for i, c := range configs {
+		if !share.owns(i) {
+			continue
+		}
+		manager, err := NewManager(c, c.Ensemble, 0)
+		if err != nil {
+			return err
+		}
+		if err := manager.Run(ctx, c.Chronons); err != nil {
+			return fmt.Errorf("sweep interrupted")
+		}
+		value := strings.TrimSpace(values[i])
+		stats := manager.Stats()
+		for _, st := range stats {
+			out.Write(append([]string{param, value}, st.record()...))
+		}
+		out.Flush() ///< Rows appear as ensembles finish
+		series = append(series, PlotSeries{Label: param + "=" + value, Stats: stats})
+	}
+	if err := out.Error(); err != nil {
+		return err
+	}
+	if plot := configs[0].Plot; plot != "" {
+		title := fmt.Sprintf("%s sweep, %d runs per value, mean and 95%% confidence interval", param, configs[0].Ensemble)
+		return writePlotFile(plot, title, series)
+	}
+	return nil
+}
+
+/**
+ * @brief Writes the cluster job of a sweep instead of running it.
+ * @param cf The sweep's parsed flags.
+ * @param cfg The sweep's configuration, with its seed fixed.
+ * @param param Name of the swept parameter.
+ * @param values The values of the parameter.
+ * @param seeds Runs per value without -ensemble.
+ * @param tasks Requested number of array tasks; 0 or more than the runs gives one per run.
+ * @param dir Directory for the job files.
+ * @param n Number of values.
+ * @return The exit code.
+ */
+func emitSweepJobs(cf *configFlags, cfg Config, param string, values []string, seeds, tasks int, dir string, n int) int {
+	runs, rowsPerRun := n*seeds, 1
+	if cfg.Ensemble > 1 {
+		runs, rowsPerRun = n, cfg.Chronons+1
+	}
+	if tasks == 0 || tasks > runs {
+		tasks = runs
+	}
+	m := jobManifest{Scheduler: "slurm", Param: param, Seed: cfg.Seed, Seeds: seeds, Ensemble: cfg.Ensemble > 1,
+		Command: sweepCommand(cf.fs, cfg.Seed, "emit-jobs", "jobs-dir", "tasks", "task")}
+	for _, v := range values {
+		m.Values = append(m.Values, strings.TrimSpace(v))
+	}
+	script, err := emitSlurmJobs(dir, m, tasks, runs, rowsPerRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s: %d runs in %d array tasks. Submit it with sbatch, then merge with wator collect %s\n",
+		script, runs, tasks, filepath.Join(dir, jobManifestName))
+	return exitOK
+}
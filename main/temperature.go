@@ -0,0 +1,151 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file temperature.go
+ * @brief A temperature field that slows breeding in cold water (the -temperature option).
+ * @details Every cell has a temperature between 0 (cold) and 1 (warm), either from a
+ * latitudinal gradient, warmest on the middle row and coldest on the first and last, or from a
+ * map file of numbers:
+ *
+ *     # 4x4 map, sampled onto the grid
+ *     0.2 0.4 0.4 0.2
+ *     0.5 1.0 1.0 0.5
+ *     ...
+ *
+ * A map of any square size is stretched over the grid, so a coarse map describes a large ocean.
+ * With TemperatureEffect s, breeding in a cell at temperature t takes 1 + s*(1-t) times as
+ * long: the counter rules raise FishBreed and SharkBreed by that factor (rounded up) and the
+ * stochastic rules divide the breeding probabilities by it. Breeding is judged where the
+ * offspring is left, the cell the parent moved away from, so populations gather in warm bands.
+ */
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/**
+ * @struct Temperature
+ * @brief A read-only temperature per cell, shared by the rules, the engines and frames.
+ */
+type Temperature struct {
+	size  int
+	cells []float32 ///< Row-major temperatures, 0 (cold) to 1 (warm)
+}
+
+/**
+ * @brief Returns the latitudinal gradient for a grid: 1 on the middle row, 0 on the first and last.
+ */
+func temperatureGradient(size int) *Temperature {
+	t := &Temperature{size: size, cells: make([]float32, size*size)}
+	for x := 0; x < size; x++ {
+		v := float32(1)
+		if size > 1 {
+			v = float32(1 - math.Abs(2*float64(x)/float64(size-1)-1))
+		}
+		for y := 0; y < size; y++ {
+			t.cells[x*size+y] = v
+		}
+	}
+	return t
+}
+
+/**
+ * @brief Reads a temperature map and stretches it over a grid.
+ * @param path A file of whitespace-separated numbers between 0 and 1, one row per line; blank
+ * lines and lines starting with # are ignored. The map must be square.
+ * @param size The grid dimension.
+ * @return The field, or an error naming the bad line.
+ */
+func readTemperatureMap(path string, size int) (*Temperature, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening temperature map: %w", err)
+	}
+	defer f.Close()
+
+	var rows [][]float32
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var row []float32
+		for _, word := range strings.Fields(text) {
+			v, err := strconv.ParseFloat(word, 32)
+			if err != nil || v < 0 || v > 1 {
+				return nil, fmt.Errorf("%s: line %d: temperatures must be numbers between 0 and 1, got %q", path, line, word)
+			}
+			row = append(row, float32(v))
+		}
+		if len(rows) > 0 && len(row) != len(rows[0]) {
+			return nil, fmt.Errorf("%s: line %d has %d values, want %d", path, line, len(row), len(rows[0]))
+		}
+		rows = append(rows, row)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 || len(rows) != len(rows[0]) {
+		return nil, fmt.Errorf("%s: temperature maps must be square and not empty", path)
+	}
+
+	n := len(rows)
+	t := &Temperature{size: size, cells: make([]float32, size*size)}
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			t.cells[x*size+y] = rows[x*n/size][y*n/size] ///< Nearest map cell
+		}
+	}
+	return t, nil
+}
+
+/**
+ * @brief Builds the field a configuration asks for.
+ * @param spec The -temperature value: "gradient" or a map file.
+ * @param size The grid dimension.
+ */
+func loadTemperature(spec string, size int) (*Temperature, error) {
+	if spec == "gradient" {
+		return temperatureGradient(size), nil
+	}
+	return readTemperatureMap(spec, size)
+}
+
+/** @brief Returns the temperature at (x, y). */
+func (t *Temperature) At(x, y int) float32 { return t.cells[x*t.size+y] }
+
+/** @brief Returns the grid dimension the field covers. */
+func (t *Temperature) Size() int { return t.size }
+
+/**
+ * @brief Returns how many times longer breeding takes at (x, y): 1 without a temperature field.
+ */
+func (r Rules) breedScale(x, y int) float64 {
+	if r.Temperature == nil {
+		return 1
+	}
+	return 1 + r.TemperatureEffect*(1-float64(r.Temperature.At(x, y)))
+}
+
+/**
+ * @brief Returns a breeding threshold of the counter rules, lengthened for the temperature at (x, y).
+ */
+func (r Rules) breedTime(base, x, y int) int {
+	return int(math.Ceil(float64(base) * r.breedScale(x, y)))
+}
@@ -0,0 +1,72 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file temperature_test.go
+ * @brief Tests for the temperature field.
+ */
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemperatureGradient(t *testing.T) {
+	g := temperatureGradient(5)
+	for x, want := range []float32{0, 0.5, 1, 0.5, 0} {
+		if got := g.At(x, 3); got != want {
+			t.Errorf("row %d: temperature %g, want %g", x, got, want)
+		}
+	}
+}
+
+func TestTemperatureMapIsStretched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sea.txt")
+	if err := os.WriteFile(path, []byte("# warm south-east\n0 0.25\n0.5 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	field, err := readTemperatureMap(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.At(1, 1) != 0 || field.At(0, 3) != 0.25 || field.At(3, 0) != 0.5 || field.At(2, 2) != 1 {
+		t.Errorf("2x2 map stretched to 4x4 as %v", field.cells)
+	}
+
+	for _, bad := range []string{"0 1\n0.5\n", "0 2\n1 1\n", "0 1 1\n1 1 1\n"} {
+		if err := os.WriteFile(path, []byte(bad), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readTemperatureMap(path, 4); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestColdWaterSlowsBreeding(t *testing.T) {
+	rules := Rules{FishBreed: 3, SharkBreed: 4, Temperature: temperatureGradient(5), TemperatureEffect: 1}
+	fish, shark := &Fish{BreedCounter: 3}, &Shark{BreedCounter: 4}
+	if !rules.fishBreeds(nil, fish, 2, 0) || !rules.sharkBreeds(nil, shark, 2, 0) {
+		t.Error("warmest row: breeding should take the base time")
+	}
+	if rules.fishBreeds(nil, fish, 0, 0) || rules.sharkBreeds(nil, shark, 0, 0) {
+		t.Error("coldest row: breeding should take twice the base time")
+	}
+	if got := rules.breedTime(3, 1, 0); got != 5 {
+		t.Errorf("breed time at temperature 0.5 is %d, want ceil(3 * 1.5) = 5", got)
+	}
+	rules.Temperature = nil
+	if rules.breedScale(0, 0) != 1 {
+		t.Error("without a field breeding should not be slowed")
+	}
+}
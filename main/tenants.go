@@ -0,0 +1,349 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file tenants.go
+ * @brief Simulations created by the clients of the serve subcommand, within per-instance quotas.
+ * @details With serve -tenants, clients create and step their own worlds next to the served one:
+ *   POST   /sims                 create a world from a JSON object of parameters
+ *   GET    /sims                 the caller's worlds
+ *   GET    /sims/{id}            a world's status and rows, as on /frame.json
+ *   POST   /sims/{id}/step?n=k   run k chronons (default 1)
+ *   DELETE /sims/{id}            drop a world
+ * The caller is named by the X-Wator-User header ("anonymous" without it) and sees only its own
+ * worlds. Each world is limited in grid size, total chronons and worker threads, each caller in
+ * the number of worlds, and worlds left untouched for the idle timeout are evicted.
+ */
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/** Parameters a client may set when creating a world: the rules, the starting populations and the seed, but nothing that reads or writes files. */
+var tenantParams = []string{"NumShark", "NumFish", "FishBreed", "SharkBreed", "Starve", "GridSize", "Threads",
+	"fish-speed", "shark-speed", "shark-vision", "move-cost", "stay-cost", "birth-share",
+	"crowding-k", "crowding-death", "fish-maturity", "juvenile-energy", "satiation", "sated-rest", "food-web",
+	"tide-prob", "tide-spread", "tide-decay", "tide-kill", "ruleset", "fish-breed-prob", "shark-breed-prob", "starve-prob",
+	"engine", "storage", "seed"}
+
+/** Header naming the client a request acts for. */
+const tenantHeader = "X-Wator-User"
+
+/**
+ * @struct TenantLimits
+ * @brief Quotas enforced on the worlds clients create.
+ */
+type TenantLimits struct {
+	MaxGridSize int           ///< Largest GridSize a world may have
+	MaxSteps    int           ///< Chronons a world may run over its lifetime
+	MaxWorkers  int           ///< Worker threads per world; larger Threads are lowered to it
+	MaxPerUser  int           ///< Worlds a client may hold at once
+	IdleTimeout time.Duration ///< Worlds neither read nor stepped for this long are evicted
+}
+
+/**
+ * @struct tenantSim
+ * @brief One client's world.
+ */
+type tenantSim struct {
+	id, owner string
+	sim       *Simulation
+	created   time.Time
+	mu        sync.Mutex   ///< Serialises steps, which may be requested concurrently
+	steps     atomic.Int64 ///< Chronons run so far
+	lastUsed  time.Time    ///< Guarded by Tenants.mu
+}
+
+/**
+ * @struct tenantStatus
+ * @brief JSON form of a world, without its rows.
+ */
+type tenantStatus struct {
+	ID        string `json:"id"`
+	Chronon   int    `json:"chronon"`
+	Size      int    `json:"size"`
+	Fish      int    `json:"fish"`
+	Sharks    int    `json:"sharks"`
+	Threads   int    `json:"threads"`
+	StepsLeft int    `json:"steps_left"`
+}
+
+/**
+ * @struct Tenants
+ * @brief The worlds of every client, by ID.
+ */
+type Tenants struct {
+	limits TenantLimits
+	mu     sync.Mutex
+	sims   map[string]*tenantSim
+}
+
+/**
+ * @brief Creates an empty set of worlds.
+ * @param limits The quotas every world and client is held to.
+ */
+func NewTenants(limits TenantLimits) *Tenants {
+	return &Tenants{limits: limits, sims: make(map[string]*tenantSim)}
+}
+
+/**
+ * @brief Registers the /sims handlers on a mux.
+ */
+func (t *Tenants) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /sims", t.create)
+	mux.HandleFunc("GET /sims", t.list)
+	mux.HandleFunc("GET /sims/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if ts := t.lookup(w, r); ts != nil {
+			writeJSON(w, http.StatusOK, newFrameDocument(ts.sim.Snapshot()))
+		}
+	})
+	mux.HandleFunc("POST /sims/{id}/step", t.step)
+	mux.HandleFunc("DELETE /sims/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if ts := t.lookup(w, r); ts != nil {
+			t.mu.Lock()
+			delete(t.sims, ts.id)
+			t.mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
+
+/**
+ * @brief Evicts idle worlds until the context is cancelled.
+ */
+func (t *Tenants) Run(ctx context.Context) {
+	ticker := time.NewTicker(max(t.limits.IdleTimeout/4, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.evict(now)
+		}
+	}
+}
+
+/**
+ * @brief Drops the worlds last used more than the idle timeout before now.
+ * @return The number of worlds dropped.
+ */
+func (t *Tenants) evict(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for id, ts := range t.sims {
+		if now.Sub(ts.lastUsed) > t.limits.IdleTimeout {
+			delete(t.sims, id)
+			slog.Info("evicted idle simulation", "id", id, "owner", ts.owner, "idle", now.Sub(ts.lastUsed).Round(time.Second))
+			n++
+		}
+	}
+	return n
+}
+
+/**
+ * @brief Returns the client a request acts for.
+ */
+func tenantOf(r *http.Request) string {
+	if user := strings.TrimSpace(r.Header.Get(tenantHeader)); user != "" {
+		return user
+	}
+	return "anonymous"
+}
+
+/**
+ * @brief Handles POST /sims: checks the parameters against tenantParams and the limits, then creates the world.
+ */
+func (t *Tenants) create(w http.ResponseWriter, r *http.Request) {
+	owner := tenantOf(r)
+	var body map[string]any
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("want a JSON object of parameters: %w", err))
+		return
+	}
+	params := make(map[string]string, len(body))
+	for name, value := range body {
+		if !slices.Contains(tenantParams, name) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("cannot set %q; settable parameters: %s", name, strings.Join(tenantParams, ", ")))
+			return
+		}
+		params[name] = fmt.Sprint(value)
+	}
+	cfg, err := newConfigFlags("sims", "", io.Discard).with(params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if cfg.GridSize > t.limits.MaxGridSize {
+		writeError(w, http.StatusForbidden, fmt.Errorf("GridSize %d exceeds the limit of %d", cfg.GridSize, t.limits.MaxGridSize))
+		return
+	}
+	cfg.Threads = min(cfg.Threads, t.limits.MaxWorkers)
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	now := time.Now()
+	ts := &tenantSim{id: newTenantID(), owner: owner, sim: sim, created: now, lastUsed: now}
+	t.mu.Lock()
+	held := 0
+	for _, other := range t.sims {
+		if other.owner == owner {
+			held++
+		}
+	}
+	if held < t.limits.MaxPerUser {
+		t.sims[ts.id] = ts ///< Counted and added under one lock, so concurrent creates cannot pass the limit
+	}
+	t.mu.Unlock()
+	if held >= t.limits.MaxPerUser {
+		writeError(w, http.StatusForbidden, fmt.Errorf("%s already holds %d simulations, the limit", owner, held))
+		return
+	}
+	slog.Info("created simulation", "id", ts.id, "owner", owner, "size", cfg.GridSize, "threads", cfg.Threads, "seed", cfg.Seed)
+	writeJSON(w, http.StatusCreated, t.status(ts))
+}
+
+/**
+ * @brief Handles GET /sims: the caller's worlds, oldest first.
+ */
+func (t *Tenants) list(w http.ResponseWriter, r *http.Request) {
+	owner := tenantOf(r)
+	t.mu.Lock()
+	var mine []*tenantSim
+	for _, ts := range t.sims {
+		if ts.owner == owner {
+			ts.lastUsed = time.Now()
+			mine = append(mine, ts)
+		}
+	}
+	t.mu.Unlock()
+	slices.SortFunc(mine, func(a, b *tenantSim) int { return a.created.Compare(b.created) })
+	statuses := []tenantStatus{}
+	for _, ts := range mine {
+		statuses = append(statuses, t.status(ts))
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+/**
+ * @brief Handles POST /sims/{id}/step: runs up to the world's remaining chronons.
+ */
+func (t *Tenants) step(w http.ResponseWriter, r *http.Request) {
+	ts := t.lookup(w, r)
+	if ts == nil {
+		return
+	}
+	n := 1
+	if s := r.URL.Query().Get("n"); s != "" {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("n must be a whole number of at least 1, got %q", s))
+			return
+		}
+	}
+
+	ts.mu.Lock()
+	if left := t.limits.MaxSteps - int(ts.steps.Load()); n > left {
+		ts.mu.Unlock()
+		writeError(w, http.StatusForbidden, fmt.Errorf("%d chronons requested, but only %d of the limit of %d are left", n, left, t.limits.MaxSteps))
+		return
+	}
+	ran, _ := ts.sim.Run(r.Context(), n) ///< A client that hangs up stops its own run
+	ts.steps.Add(int64(ran))
+	ts.mu.Unlock()
+	t.touch(ts)
+	writeJSON(w, http.StatusOK, t.status(ts))
+}
+
+/**
+ * @brief Finds the caller's world named in the path, or writes 404.
+ * @return The world, or nil if it does not exist or belongs to another client.
+ */
+func (t *Tenants) lookup(w http.ResponseWriter, r *http.Request) *tenantSim {
+	id := r.PathValue("id")
+	t.mu.Lock()
+	ts, ok := t.sims[id]
+	if ok && ts.owner == tenantOf(r) {
+		ts.lastUsed = time.Now()
+	}
+	t.mu.Unlock()
+	if !ok || ts.owner != tenantOf(r) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no simulation %q", id)) ///< Other clients' worlds are not revealed
+		return nil
+	}
+	return ts
+}
+
+/**
+ * @brief Marks a world as used now, so that a long step does not count as idle time.
+ */
+func (t *Tenants) touch(ts *tenantSim) {
+	t.mu.Lock()
+	ts.lastUsed = time.Now()
+	t.mu.Unlock()
+}
+
+/**
+ * @brief Describes a world.
+ */
+func (t *Tenants) status(ts *tenantSim) tenantStatus {
+	f := ts.sim.Snapshot()
+	fish, sharks := f.Counts()
+	return tenantStatus{ID: ts.id, Chronon: f.Chronon(), Size: f.Size(), Fish: fish, Sharks: sharks,
+		Threads: ts.sim.Threads(), StepsLeft: t.limits.MaxSteps - int(ts.steps.Load())}
+}
+
+/**
+ * @brief Returns a random world ID that cannot be guessed from another.
+ */
+func newTenantID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+/**
+ * @brief Writes a value as a JSON response.
+ */
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+/**
+ * @brief Writes an error as a JSON response {"error": "..."}.
+ */
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,117 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file tenants_test.go
+ * @brief Tests for the simulations clients create under /sims.
+ */
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+/**
+ * @brief Sends a request as a client and decodes the JSON reply into v (if not nil).
+ * @return The status code.
+ */
+func tenantRequest(t *testing.T, srv *httptest.Server, user, method, path, body string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(tenantHeader, user)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestTenantsQuotas(t *testing.T) {
+	tenants := NewTenants(TenantLimits{MaxGridSize: 20, MaxSteps: 5, MaxWorkers: 2, MaxPerUser: 1, IdleTimeout: time.Minute})
+	mux := http.NewServeMux()
+	tenants.Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var errDoc map[string]string
+	if code := tenantRequest(t, srv, "ann", "POST", "/sims", `{"GridSize": 50}`, &errDoc); code != http.StatusForbidden || !strings.Contains(errDoc["error"], "exceeds the limit of 20") {
+		t.Errorf("oversized grid: %d %v, want 403 naming the limit", code, errDoc)
+	}
+	if code := tenantRequest(t, srv, "ann", "POST", "/sims", `{"csv": "/tmp/out.csv"}`, &errDoc); code != http.StatusBadRequest {
+		t.Errorf("output parameter: %d %v, want 400", code, errDoc)
+	}
+
+	var st tenantStatus
+	body := `{"GridSize": 10, "NumFish": 20, "NumShark": 5, "Threads": 8, "engine": "deterministic", "seed": 3}`
+	if code := tenantRequest(t, srv, "ann", "POST", "/sims", body, &st); code != http.StatusCreated {
+		t.Fatalf("create: %d, want 201", code)
+	}
+	if st.Size != 10 || st.Threads != 2 || st.StepsLeft != 5 {
+		t.Errorf("created %+v, want size 10, threads capped at 2 and 5 steps left", st)
+	}
+	if code := tenantRequest(t, srv, "ann", "POST", "/sims", body, &errDoc); code != http.StatusForbidden {
+		t.Errorf("second simulation: %d, want 403", code)
+	}
+	if code := tenantRequest(t, srv, "bob", "GET", "/sims/"+st.ID, "", nil); code != http.StatusNotFound {
+		t.Errorf("another client's simulation: %d, want 404", code)
+	}
+
+	if code := tenantRequest(t, srv, "ann", "POST", "/sims/"+st.ID+"/step?n=4", "", &st); code != http.StatusOK || st.Chronon != 4 || st.StepsLeft != 1 {
+		t.Errorf("step 4: %d %+v, want chronon 4 with 1 step left", code, st)
+	}
+	if code := tenantRequest(t, srv, "ann", "POST", "/sims/"+st.ID+"/step?n=2", "", &errDoc); code != http.StatusForbidden {
+		t.Errorf("step past the limit: %d, want 403", code)
+	}
+	var list []tenantStatus
+	if tenantRequest(t, srv, "bob", "GET", "/sims", "", &list); len(list) != 0 {
+		t.Errorf("bob lists %v, want nothing", list)
+	}
+	if code := tenantRequest(t, srv, "ann", "DELETE", "/sims/"+st.ID, "", nil); code != http.StatusNoContent {
+		t.Errorf("delete: %d, want 204", code)
+	}
+	if tenantRequest(t, srv, "ann", "GET", "/sims", "", &list); len(list) != 0 {
+		t.Errorf("after delete, ann lists %v", list)
+	}
+}
+
+func TestTenantsEvictIdle(t *testing.T) {
+	tenants := NewTenants(TenantLimits{MaxGridSize: 20, MaxSteps: 5, MaxWorkers: 1, MaxPerUser: 2, IdleTimeout: time.Minute})
+	mux := http.NewServeMux()
+	tenants.Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var st tenantStatus
+	tenantRequest(t, srv, "ann", "POST", "/sims", `{"GridSize": 10, "NumFish": 5, "NumShark": 5}`, &st)
+	if n := tenants.evict(time.Now().Add(30 * time.Second)); n != 0 {
+		t.Errorf("evicted %d simulations before the idle timeout", n)
+	}
+	if n := tenants.evict(time.Now().Add(2 * time.Minute)); n != 1 {
+		t.Errorf("evicted %d simulations after the idle timeout, want 1", n)
+	}
+	if code := tenantRequest(t, srv, "ann", "GET", "/sims/"+st.ID, "", nil); code != http.StatusNotFound {
+		t.Errorf("evicted simulation: %d, want 404", code)
+	}
+}
@@ -0,0 +1,76 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file term.go
+ * @brief Deciding whether the terminal can show colours (the -no-color and -force-color options).
+ * @details The themes other than ascii write ANSI escape sequences. Before drawing, the
+ * terminal's support is checked: TERM=dumb has none, and on Windows virtual terminal
+ * processing is switched on for the console, which consoles older than Windows 10 refuse.
+ * Without support, and with -no-color or NO_COLOR, output falls back to the ascii theme;
+ * -force-color skips all of these checks, e.g. for a terminal that is misdetected.
+ */
+package main
+
+import (
+	"flag"
+	"os"
+)
+
+/**
+ * @struct colourFlags
+ * @brief The -no-color and -force-color flags of one flag set.
+ */
+type colourFlags struct {
+	noColor *bool
+	force   *bool
+}
+
+/**
+ * @brief Registers -no-color and -force-color.
+ */
+func addColourFlags(fs *flag.FlagSet) colourFlags {
+	return colourFlags{
+		noColor: fs.Bool("no-color", noColorEnv(), "plain ASCII output without colours, for logs and CI (same as -theme ascii; also set by NO_COLOR, and automatically on terminals without colour support)"),
+		force:   fs.Bool("force-color", false, "keep the chosen -theme even with -no-color, NO_COLOR or a terminal that seems to lack colour support"),
+	}
+}
+
+/**
+ * @brief Switches the configuration to the ascii theme unless colours are wanted and supported.
+ * @details An unknown -theme is left for validation to report.
+ */
+func (c colourFlags) apply(cfg *Config) {
+	if _, err := themeByName(cfg.Theme); err != nil {
+		return
+	}
+	if !*c.force && (*c.noColor || !terminalColour()) {
+		cfg.Theme = "ascii"
+	}
+}
+
+/**
+ * @brief Reports whether standard output can show ANSI colours, enabling them where that is needed.
+ */
+func terminalColour() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return enableVT(os.Stdout)
+}
+
+/**
+ * @brief Reports whether colour output has been disabled through the NO_COLOR convention.
+ * @details See https://no-color.org: any non-empty value disables colour.
+ */
+func noColorEnv() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
@@ -0,0 +1,29 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build !windows
+
+/**
+ * @file term_other.go
+ * @brief Colour support outside Windows.
+ * @details Unix terminals and the browser front end interpret escape sequences without being
+ * asked to, so only TERM is consulted.
+ */
+package main
+
+import "os"
+
+/**
+ * @brief Reports that escape sequences need no enabling.
+ */
+func enableVT(*os.File) bool {
+	return true
+}
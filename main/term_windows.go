@@ -0,0 +1,50 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build windows
+
+/**
+ * @file term_windows.go
+ * @brief Turning on escape sequences in the Windows console.
+ * @details Windows 10 and later interpret ANSI escapes once ENABLE_VIRTUAL_TERMINAL_PROCESSING
+ * is set on the console; older consoles reject the mode and would print the escapes as text.
+ */
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+/** Console mode bit that makes the console interpret escape sequences. */
+const enableVirtualTerminalProcessing = 0x0004
+
+/** SetConsoleMode, which the syscall package does not wrap. */
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+/**
+ * @brief Enables escape sequences on a console.
+ * @param f The output file.
+ * @return Whether escapes will be interpreted: true for files and pipes, which pass them on
+ * to whatever reads them, and for consoles that accept virtual terminal processing.
+ */
+func enableVT(f *os.File) bool {
+	h := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return true ///< Not a console
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	ok, _, _ := setConsoleMode.Call(uintptr(h), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
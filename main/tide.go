@@ -0,0 +1,177 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file tide.go
+ * @brief Red tides: poisonous blooms that spread across the grid (the -tide-prob option).
+ * @details Every cell carries a toxin level between 0 and 1. A bloom sets the level of its
+ * cells to 1; blooms start at a random cell with probability TideProb each chronon, or where a
+ * scenario script says ("at chronon N tide in (x1,y1)-(x2,y2)"). After every engine step the
+ * toxin spreads and decays like a simple cellular automaton:
+ *
+ *     level' = TideDecay * max(level, TideSpread * max of the 4 neighbours' levels)
+ *
+ * and levels below tideFloor clear, so a bloom grows into a patch that drifts outward and
+ * fades. A fish in a covered cell then dies with probability TideKill * level. Sharks are not
+ * harmed. The tide draws from its own source seeded from the run's seed and visits cells in
+ * row order outside the engine, so it is reproducible for any -threads value. Checkpoints
+ * record the levels, so a resumed run keeps its tide; the source restarts from the seed.
+ */
+package main
+
+import "math/rand"
+
+/** Toxin level below which a cell clears. */
+const tideFloor = 0.05
+
+/**
+ * @struct Tide
+ * @brief The toxin levels of a simulation.
+ * @details Owned by the simulation and only touched under its lock.
+ */
+type Tide struct {
+	size  int
+	level []float32 ///< Row-major toxin level, 0 (clear) to 1 (fresh bloom)
+	next  []float32 ///< Spread buffer, swapped with level every chronon
+	cells int       ///< Covered cells
+	rng   *rand.Rand
+}
+
+/**
+ * @brief Creates a clear tide for a grid.
+ * @param size Grid dimension.
+ * @param seed The run's seed.
+ */
+func newTide(size int, seed int64) *Tide {
+	return &Tide{size: size, level: make([]float32, size*size), next: make([]float32, size*size),
+		rng: rand.New(rand.NewSource(seed ^ 0x7469646500000000))}
+}
+
+/**
+ * @brief Reports whether a run may have red tides, and so reports their statistics.
+ * @details Scripted runs count, since a script may start a bloom.
+ */
+func (c Config) redTide() bool {
+	return c.TideProb > 0 || c.Script != ""
+}
+
+/**
+ * @brief Starts a bloom covering a region at full strength.
+ * @param x1, y1 First corner of the region, inclusive.
+ * @param x2, y2 Opposite corner of the region, inclusive; coordinates wrap around the torus.
+ */
+func (t *Tide) bloom(x1, y1, x2, y2 int) {
+	for x := x1; x <= x2; x++ {
+		for y := y1; y <= y2; y++ {
+			i := wrap(x, t.size)*t.size + wrap(y, t.size)
+			if t.level[i] == 0 {
+				t.cells++
+			}
+			t.level[i] = 1
+		}
+	}
+}
+
+/**
+ * @brief Advances the tide by one chronon and poisons the fish it covers.
+ * @details Runs after the engine step, on the grid it produced.
+ * @param g The grid after the step.
+ * @param rules Rules in force.
+ * @param report The step's report; poisonings are added to its counts and events.
+ * @return Whether any fish died.
+ */
+func (t *Tide) step(g *Grid, rules Rules, report *StepReport) bool {
+	if rules.TideProb > 0 && t.rng.Float64() < rules.TideProb {
+		x, y := t.rng.Intn(t.size), t.rng.Intn(t.size)
+		t.bloom(x, y, x, y)
+	}
+	if t.cells == 0 {
+		return false
+	}
+
+	t.cells = 0
+	for x := 0; x < t.size; x++ {
+		for y := 0; y < t.size; y++ {
+			around := max(t.at(x-1, y), t.at(x+1, y), t.at(x, y-1), t.at(x, y+1))
+			v := float32(rules.TideDecay) * max(t.at(x, y), float32(rules.TideSpread)*around)
+			if v < tideFloor {
+				v = 0
+			} else {
+				t.cells++
+			}
+			t.next[x*t.size+y] = v
+		}
+	}
+	t.level, t.next = t.next, t.level
+
+	killed := false
+	for x := 0; x < t.size; x++ {
+		for y := 0; y < t.size; y++ {
+			v := t.level[x*t.size+y]
+			if v == 0 || speciesOf(g.At(x, y)) != SpeciesFish || t.rng.Float64() >= rules.TideKill*float64(v) {
+				continue
+			}
+			g.Set(x, y, nil)
+			report.FishPoisoned++
+			report.Events = append(report.Events, Event{Chronon: g.Chronon, Kind: EventFishPoisoned, X: x, Y: y})
+			killed = true
+		}
+	}
+	return killed
+}
+
+/** @brief Returns the toxin level at (x, y), wrapping around the torus. */
+func (t *Tide) at(x, y int) float32 {
+	return t.level[wrap(x, t.size)*t.size+wrap(y, t.size)]
+}
+
+/**
+ * @brief Lists the covered cells and their levels for a checkpoint.
+ */
+func (t *Tide) checkpoint() []checkpointTide {
+	var cells []checkpointTide
+	for i, v := range t.level {
+		if v > 0 {
+			cells = append(cells, checkpointTide{X: i / t.size, Y: i % t.size, Level: v})
+		}
+	}
+	return cells
+}
+
+/**
+ * @brief Marks the covered cells of a frame.
+ */
+func (t *Tide) mark(f *Frame) {
+	if t.cells == 0 {
+		return
+	}
+	for i, v := range t.level {
+		if v > 0 {
+			f.markTide(i)
+		}
+	}
+}
+
+/**
+ * @brief Starts a red-tide bloom covering a region, from the next chronon on.
+ * @details Safe to call from hooks and from other goroutines.
+ * @param x1, y1 First corner of the region, inclusive.
+ * @param x2, y2 Opposite corner of the region, inclusive.
+ */
+func (s *Simulation) Bloom(x1, y1, x2, y2 int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tide == nil {
+		s.tide = newTide(s.grid.Size, s.cfg.Seed)
+	}
+	s.tide.bloom(x1, y1, x2, y2)
+	s.frame = nil ///< The cached snapshot does not show the bloom
+}
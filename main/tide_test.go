@@ -0,0 +1,72 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file tide_test.go
+ * @brief Tests for red tides.
+ */
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTideSpreadsAndFades(t *testing.T) {
+	rules := Rules{TideSpread: 0.8, TideDecay: 0.9}
+	tide := newTide(21, 1)
+	tide.bloom(10, 10, 10, 10)
+	g := NewGrid(21)
+
+	var covered []int
+	for i := 0; i < 60; i++ {
+		tide.step(g, rules, &StepReport{})
+		covered = append(covered, tide.cells)
+	}
+	if covered[0] != 5 {
+		t.Errorf("after one chronon a single-cell bloom covers %d cells, want 5", covered[0])
+	}
+	if covered[4] <= covered[0] {
+		t.Errorf("the tide did not spread: %d cells after 5 chronons", covered[4])
+	}
+	if covered[59] != 0 {
+		t.Errorf("the tide still covers %d cells after 60 chronons of decay", covered[59])
+	}
+	if tide.at(-1, 10) != tide.at(20, 10) {
+		t.Error("levels do not wrap around the torus")
+	}
+}
+
+func TestTidePoisonsFish(t *testing.T) {
+	cfg := testConfig()
+	cfg.NumShark, cfg.TideDecay, cfg.TideKill = 0, 1, 1
+	sim, err := NewSimulation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Bloom(0, 0, 19, 19)
+	if f := sim.Snapshot(); f.TideCells() != 400 || !f.Tide(5, 5) {
+		t.Fatalf("frame shows %d tide cells after covering the grid, want 400", f.TideCells())
+	}
+	var events int
+	sim.OnEvent(func(e Event) {
+		if e.Kind == EventFishPoisoned {
+			events++
+		}
+	})
+	report := sim.Step(context.Background())
+	if report.FishPoisoned == 0 || events != report.FishPoisoned {
+		t.Errorf("%d fish poisoned with %d events; want some, one event each", report.FishPoisoned, events)
+	}
+	if fish, _ := sim.Snapshot().Counts(); fish != 0 || sim.Population().Fish != 0 {
+		t.Errorf("%d fish (running count %d) survived a full-strength tide with -tide-kill 1", fish, sim.Population().Fish)
+	}
+}
@@ -0,0 +1,196 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file triggers.go
+ * @brief Screenshots on notable events (the -trigger option).
+ * @details A trigger is a condition on the populations, written as a Starlark expression over
+ * chronon, fish, sharks, empty and size:
+ *
+ *     fish < 100; sharks extinct; chronon % 500 == 0 and chronon > 0
+ *
+ * "fish extinct" and "sharks extinct" are shorthand for fish == 0 and sharks == 0. Conditions
+ * are checked on the initial world and after every chronon; a trigger fires when its condition
+ * becomes true, not on every chronon it stays true, so "fish < 100" captures the crash rather
+ * than the whole trough. When any trigger fires, the world is saved as <prefix>-<chronon>.png
+ * and as the checkpoint <prefix>-<chronon>.wtr, which diff, replay and -resume accept. With
+ * -notify the firing is also announced (see notify.go).
+ */
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+/** Shorthand conditions, rewritten to expressions before parsing. */
+var extinctTrigger = regexp.MustCompile(`^(fish|sharks?)\s+extinct$`)
+
+/**
+ * @struct Trigger
+ * @brief One compiled condition.
+ */
+type Trigger struct {
+	Text string ///< The condition as written
+	fn   *starlark.Function
+	env  starlark.StringDict ///< The variables fn reads; updated before every evaluation
+	held bool                ///< Whether the condition was true at the previous check
+	dead bool                ///< Set after an evaluation error, which is reported once
+}
+
+/**
+ * @brief Compiles a semicolon-separated list of conditions.
+ * @param spec The -trigger value.
+ * @return The triggers, or an error naming the first condition that does not compile.
+ */
+func parseTriggers(spec string) ([]*Trigger, error) {
+	var triggers []*Trigger
+	for _, text := range strings.Split(spec, ";") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		src := text
+		if m := extinctTrigger.FindStringSubmatch(text); m != nil {
+			src = "sharks == 0"
+			if m[1] == "fish" {
+				src = "fish == 0"
+			}
+		}
+		t := &Trigger{Text: text, env: starlark.StringDict{
+			"chronon": starlark.MakeInt(0), "fish": starlark.MakeInt(0), "sharks": starlark.MakeInt(0),
+			"empty": starlark.MakeInt(0), "size": starlark.MakeInt(0),
+		}}
+		fn, err := starlark.ExprFuncOptions(&syntax.FileOptions{}, "trigger", src, t.env)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", text, err)
+		}
+		t.fn = fn
+		triggers = append(triggers, t)
+	}
+	if len(triggers) == 0 {
+		return nil, fmt.Errorf("no conditions in %q", spec)
+	}
+	return triggers, nil
+}
+
+/**
+ * @brief Evaluates the condition against a population.
+ * @param p The populations after a chronon.
+ * @param size Grid dimension.
+ * @return Whether the trigger fires: the condition is true and was not at the previous check.
+ */
+func (t *Trigger) Check(p Population, size int) (bool, error) {
+	if t.dead {
+		return false, nil
+	}
+	t.env["chronon"] = starlark.MakeInt(p.Chronon)
+	t.env["fish"] = starlark.MakeInt(p.Fish)
+	t.env["sharks"] = starlark.MakeInt(p.Sharks)
+	t.env["empty"] = starlark.MakeInt(size*size - p.Fish - p.Sharks)
+	t.env["size"] = starlark.MakeInt(size)
+	v, err := starlark.Call(&starlark.Thread{Name: "trigger"}, t.fn, nil, nil)
+	if err != nil {
+		t.dead = true
+		return false, fmt.Errorf("trigger %q: %w", t.Text, err)
+	}
+	was := t.held
+	t.held = bool(v.Truth())
+	return t.held && !was, nil
+}
+
+/**
+ * @struct TriggerSet
+ * @brief The triggers of a run and the simulation they capture.
+ */
+type TriggerSet struct {
+	Triggers []*Trigger
+	Prefix   string   ///< Path prefix of the saved files
+	Fired    int      ///< Chronons at which files were saved
+	Notifier Notifier ///< Told about every firing; nil for none
+	sim      *Simulation
+}
+
+/**
+ * @brief Compiles the configured triggers for a simulation.
+ * @param sim The simulation to capture; register Observe with sim.OnStats.
+ * @param spec The -trigger value.
+ * @param prefix The -trigger-prefix value.
+ * @return The set, or an error if a condition does not compile.
+ */
+func NewTriggerSet(sim *Simulation, spec, prefix string) (*TriggerSet, error) {
+	triggers, err := parseTriggers(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &TriggerSet{Triggers: triggers, Prefix: prefix, sim: sim}, nil
+}
+
+/**
+ * @brief Checks every trigger and saves the world if any fired. Usable as a StatsHook.
+ * @param p The populations after a chronon.
+ */
+func (ts *TriggerSet) Observe(p Population, _ StepReport) {
+	size := ts.sim.Config().GridSize
+	var fired []string
+	for _, t := range ts.Triggers {
+		ok, err := t.Check(p, size)
+		if err != nil {
+			slog.Warn("trigger disabled", "err", err)
+		}
+		if ok {
+			fired = append(fired, t.Text)
+		}
+	}
+	if len(fired) == 0 {
+		return
+	}
+	ts.Fired++
+	base := fmt.Sprintf("%s-%d", ts.Prefix, p.Chronon)
+	if err := writeFramePNG(base+".png", ts.sim.Snapshot()); err != nil {
+		slog.Error("trigger screenshot failed", "err", err)
+	}
+	if err := ts.sim.Checkpoint().Save(base + ".wtr"); err != nil {
+		slog.Error("trigger checkpoint failed", "err", err)
+	}
+	slog.Info("trigger fired", "chronon", p.Chronon, "triggers", fired, "png", base+".png", "checkpoint", base+".wtr")
+	if ts.Notifier != nil {
+		notify(ts.Notifier, "Wa-Tor: trigger fired", fmt.Sprintf("%s at chronon %d; saved %s.png", strings.Join(fired, "; "), p.Chronon, base))
+	}
+}
+
+/**
+ * @brief Writes a frame as a PNG, scaled up so that small grids are at least 256 pixels wide.
+ * @param path Destination file path.
+ * @param f The frame.
+ * @return An error if the file could not be created or encoded.
+ */
+func writeFramePNG(path string, f *Frame) error {
+	scale := max(1, 256/f.Size())
+	img := image.NewRGBA(image.Rect(0, 0, f.Size()*scale, f.Size()*scale))
+	paintFrame(img, f, 0, 0, scale)
+	out, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(out, img); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
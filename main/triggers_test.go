@@ -0,0 +1,94 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file triggers_test.go
+ * @brief Tests for -trigger conditions and the files they save.
+ */
+package main
+
+import (
+	"context"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTriggerFiresOnBecomingTrue(t *testing.T) {
+	triggers, err := parseTriggers("fish < 10; sharks extinct;; chronon % 5 == 0 and chronon > 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(triggers) != 3 {
+		t.Fatalf("%d triggers, want 3", len(triggers))
+	}
+	fishes := []int{20, 9, 5, 12, 8}
+	want := []bool{false, true, false, false, true}
+	for i, fish := range fishes {
+		fired, err := triggers[0].Check(Population{Chronon: i, Fish: fish, Sharks: 1}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fired != want[i] {
+			t.Errorf("fish %d after %v: fired = %v, want %v", fish, fishes[:i], fired, want[i])
+		}
+	}
+	if fired, _ := triggers[1].Check(Population{Sharks: 0}, 10); !fired {
+		t.Error("sharks extinct did not fire with no sharks")
+	}
+	if fired, _ := triggers[2].Check(Population{Chronon: 5}, 10); !fired {
+		t.Error("chronon % 5 == 0 did not fire at chronon 5")
+	}
+
+	for _, bad := range []string{"", "fosh < 3", "fish <"} {
+		if _, err := parseTriggers(bad); err == nil {
+			t.Errorf("parseTriggers(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestTriggerSavesScreenshotAndCheckpoint(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := filepath.Join(t.TempDir(), "snap")
+	ts, err := NewTriggerSet(sim, "chronon == 3", prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.OnStats(ts.Observe)
+	sim.Run(context.Background(), 5)
+	if ts.Fired != 1 {
+		t.Fatalf("fired %d times, want 1", ts.Fired)
+	}
+
+	cp, err := readCheckpointFile(prefix + "-3.wtr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Chronon != 3 {
+		t.Errorf("checkpoint at chronon %d, want 3", cp.Chronon)
+	}
+	f, err := os.Open(prefix + "-3.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := img.Bounds().Dx(); w < cp.Size || w%cp.Size != 0 {
+		t.Errorf("screenshot %d pixels wide for a %d-cell grid", w, cp.Size)
+	}
+}
@@ -0,0 +1,68 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file turns.go
+ * @brief One turn per entity per chronon.
+ * @details The engines read the current grid and write a new one, so an entity that moves
+ * into a row not yet scanned is not met again, and one that moves backwards is not missed.
+ * That only holds while every entity sits in exactly one cell of the current grid. Each
+ * entity also carries a generation counter, Acted: the chronon of its last turn. takeTurn
+ * stamps it at the start of the turn and refuses a second turn in the same chronon, so an
+ * entity reachable from two cells (after an edit, a plugin or a bug that aliases it) still
+ * acts once and ends up in one cell. Frozen -fast-forward chunks stamp their fish when they
+ * are carried over. Newborns keep Acted at 0 until their first turn in the next chronon.
+ *
+ * The stamp is set with a compare-and-swap, so when two workers of the row-partitioned
+ * engines meet the same entity in their own rows only one of them takes its turn.
+ */
+package main
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+/**
+ * @brief Starts an entity's turn in a chronon.
+ * @param e The entity about to act.
+ * @param chronon The chronon being computed.
+ * @return False, and logs it, if the entity has already acted in this chronon.
+ */
+func takeTurn(e Entity, chronon int) bool {
+	acted := actedIn(e)
+	if acted == nil {
+		return true
+	}
+	for {
+		last := atomic.LoadInt64(acted)
+		if last == int64(chronon) {
+			slog.Debug("entity already acted", "chronon", chronon, "entity", speciesName(e))
+			return false
+		}
+		if atomic.CompareAndSwapInt64(acted, last, int64(chronon)) {
+			return true
+		}
+	}
+}
+
+/**
+ * @brief Returns the entity's Acted field, or nil for entities without one.
+ */
+func actedIn(e Entity) *int64 {
+	switch e := e.(type) {
+	case *Fish:
+		return &e.Acted
+	case *Shark:
+		return &e.Acted
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file turns_test.go
+ * @brief Tests that every entity acts exactly once per chronon.
+ */
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+/**
+ * @brief Returns the entities of a grid in row-major order.
+ */
+func entitiesOf(g *Grid) []Entity {
+	var es []Entity
+	for x := 0; x < g.Size; x++ {
+		for y := 0; y < g.Size; y++ {
+			if e := g.At(x, y); e != nil {
+				es = append(es, e)
+			}
+		}
+	}
+	return es
+}
+
+/** Threads the turn tests run every engine with, so that workers share band boundaries. */
+const turnThreads = 3
+
+func TestEveryEntityTakesOneTurnPerChronon(t *testing.T) {
+	rules := Rules{FishBreed: 3, SharkBreed: 6, StarveEnergy: 5, CrowdingK: 5, CrowdingDeath: 0.2}
+	for _, name := range engineNames() {
+		for _, order := range orderNames {
+			rules.Order = order
+			g := NewGrid(30)
+			g.Seed(5)
+			g.Initialize(300, 60, rules.StarveEnergy)
+			for c := 0; c < 20; c++ {
+				before := entitiesOf(g)
+				old := map[Entity]bool{}
+				for _, e := range before {
+					old[e] = true
+				}
+				report := engines[name].Step(context.Background(), g, rules, turnThreads)
+				after := map[Entity]bool{}
+				for _, e := range entitiesOf(g) {
+					after[e] = true
+				}
+
+				var goneFish, goneSharks, kept int
+				for _, e := range before {
+					_, isFish := e.(*Fish)
+					switch {
+					case after[e]:
+						kept++
+					case isFish:
+						goneFish++
+					default:
+						goneSharks++
+					}
+					if *actedIn(e) == int64(g.Chronon) {
+						continue
+					}
+					if !isFish || after[e] {
+						t.Fatalf("%s, %s order, chronon %d: a %s missed its turn without being eaten", name, order, g.Chronon, speciesName(e))
+					}
+				}
+				if goneFish != report.FishEaten+report.FishCrowded || goneSharks != report.SharksStarved {
+					t.Fatalf("%s, %s order, chronon %d: %d fish and %d sharks gone, report has %d eaten, %d crowded and %d starved",
+						name, order, g.Chronon, goneFish, goneSharks, report.FishEaten, report.FishCrowded, report.SharksStarved)
+				}
+				if born := len(after) - kept; born != report.FishBorn+report.SharksBorn {
+					t.Fatalf("%s, %s order, chronon %d: %d new entities, report has %d births", name, order, g.Chronon, born, report.FishBorn+report.SharksBorn)
+				}
+				for e := range after {
+					if acted := *actedIn(e); old[e] && acted != int64(g.Chronon) || !old[e] && acted != 0 {
+						t.Fatalf("%s, %s order, chronon %d: entity in the new grid last acted in chronon %d", name, order, g.Chronon, acted)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAliasedEntityActsOnce(t *testing.T) {
+	rules := Rules{FishBreed: 100, SharkBreed: 100, StarveEnergy: 5}
+	for _, name := range engineNames() {
+		g := NewGrid(8)
+		g.Seed(1)
+		fish, shark := &Fish{}, &Shark{Energy: 5}
+		g.Set(1, 1, fish)
+		g.Set(5, 5, fish) ///< The same fish reachable from two cells
+		g.Set(1, 5, shark)
+		g.Set(5, 1, shark)
+		engines[name].Step(context.Background(), g, rules, turnThreads)
+
+		cells := map[Entity]int{}
+		for _, e := range entitiesOf(g) {
+			cells[e]++
+		}
+		if cells[fish] != 1 || fish.BreedCounter != 1 || fish.Acted != 1 {
+			t.Errorf("%s: aliased fish ended in %d cells with breed counter %d", name, cells[fish], fish.BreedCounter)
+		}
+		if cells[shark] != 1 || shark.BreedCounter != 1 || shark.Energy != 4 {
+			t.Errorf("%s: aliased shark ended in %d cells with breed counter %d and energy %d", name, cells[shark], shark.BreedCounter, shark.Energy)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file verify.go
+ * @brief The verify subcommand: checks the deterministic engine against the serial reference.
+ * @details Both engines start from the same seed, and after every chronon the complete state
+ * of each grid (every entity's position, breeding counter and energy) must be identical.
+ * With -thread-counts, several parallel runs are checked against the one reference.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+)
+
+/**
+ * @brief The verify subcommand.
+ * @param args Arguments after the command name.
+ * @return exitOK if every chronon matched, exitFailure at the first difference.
+ */
+func cmdVerify(args []string) int {
+	cf := newConfigFlags("verify", "Runs the deterministic engine and the serial reference engine from the same seed and compares the grids after every chronon.", os.Stderr)
+	threadList := cf.fs.String("thread-counts", "", "comma-separated thread `counts` of the deterministic runs to check (default: Threads)")
+	cfg, code, ok := setupCommand(cf, args)
+	if !ok {
+		return code
+	}
+
+	threads := []int{cfg.Threads}
+	if *threadList != "" {
+		var err error
+		if threads, err = parseInts(*threadList); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid parameters:\n-thread-counts: %v\n", err)
+			return exitConfigError
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := verifyEngines(ctx, cfg, threads, os.Stdout)
+	switch {
+	case ctx.Err() != nil:
+		return exitInterrupted
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+/**
+ * @brief Steps a serial reference and one deterministic run per thread count in lockstep.
+ * @param ctx Context of the runs; cancelling it stops between chronons.
+ * @param cfg The configuration; Seed must already be fixed. Engine is ignored.
+ * @param threads Thread counts of the deterministic runs.
+ * @param out Destination of the final report.
+ * @return nil if every chronon matched, or an error describing the first difference.
+ */
+func verifyEngines(ctx context.Context, cfg Config, threads []int, out io.Writer) error {
+	ref := cfg
+	ref.Engine, ref.Threads, ref.PinWorkers = "serial", 1, false
+	reference, err := NewSimulation(ref)
+	if err != nil {
+		return err
+	}
+	runs := make([]*Simulation, len(threads))
+	for i, n := range threads {
+		c := cfg
+		c.Engine, c.Threads = "deterministic", max(n, 1)
+		if runs[i], err = NewSimulation(c); err != nil {
+			return err
+		}
+	}
+
+	for chronon := 1; chronon <= cfg.Chronons; chronon++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		reference.Step(ctx)
+		want := reference.Checkpoint().Entities
+		for i, sim := range runs {
+			sim.Step(ctx)
+			if got := sim.Checkpoint().Entities; !slices.Equal(got, want) {
+				return fmt.Errorf("chronon %d: deterministic engine with %d threads differs from the serial reference: %s",
+					chronon, threads[i], describeDifference(want, got))
+			}
+		}
+	}
+	fish, sharks := reference.Snapshot().Counts()
+	fmt.Fprintf(out, "verified %d chronons (seed %d, threads %v): identical to the serial reference (%d fish, %d sharks)\n",
+		cfg.Chronons, cfg.Seed, threads, fish, sharks)
+	return nil
+}
+
+/**
+ * @brief Describes the first entity that differs between two row-major entity lists.
+ */
+func describeDifference(want, got []checkpointEntity) string {
+	for i := range min(len(want), len(got)) {
+		if want[i] != got[i] {
+			return fmt.Sprintf("reference has %+v, parallel run has %+v", want[i], got[i])
+		}
+	}
+	return fmt.Sprintf("reference has %d entities, parallel run has %d", len(want), len(got))
+}
@@ -0,0 +1,50 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file verify_test.go
+ * @brief Tests for the serial reference engine and the verify subcommand.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestVerifyDeterministicEngine(t *testing.T) {
+	cfg := testConfig()
+	cfg.Chronons = 40
+	for _, ruleSet := range []string{RuleSetDeterministic, RuleSetStochastic} {
+		cfg.RuleSet = ruleSet
+		cfg.FishBreedProb, cfg.SharkBreedProb, cfg.StarveProb = 0.3, 0.15, 0.2
+		var out bytes.Buffer
+		if err := verifyEngines(context.Background(), cfg, []int{1, 3, 8}, &out); err != nil {
+			t.Fatalf("%s rules: %v", ruleSet, err)
+		}
+		if !strings.Contains(out.String(), "identical to the serial reference") {
+			t.Errorf("unexpected report %q", out.String())
+		}
+	}
+}
+
+func TestDescribeDifference(t *testing.T) {
+	a := []checkpointEntity{{X: 1, Y: 2, Species: "fish"}, {X: 3, Y: 4, Species: "shark", Energy: 2}}
+	b := []checkpointEntity{{X: 1, Y: 2, Species: "fish"}, {X: 3, Y: 4, Species: "shark", Energy: 1}}
+	if got := describeDifference(a, b); !strings.Contains(got, "Energy:2") || !strings.Contains(got, "Energy:1") {
+		t.Errorf("describeDifference = %q", got)
+	}
+	if got := describeDifference(a, a[:1]); !strings.Contains(got, "2 entities") {
+		t.Errorf("describeDifference = %q", got)
+	}
+}
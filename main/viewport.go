@@ -0,0 +1,345 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file viewport.go
+ * @brief A movable camera over the world for grids larger than the terminal.
+ * @details The viewport crops each frame to a square window that can be scrolled, zoomed
+ * (each shown cell aggregates a block of world cells) and made to follow a fish or shark.
+ * Frames carry no entity identities, so following tracks the nearest animal of the chosen
+ * species to where the camera last saw one; if it dies the camera latches onto the next
+ * nearest. Controls are single keys read from standard input, one command per line.
+ */
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+/**
+ * @struct Viewport
+ * @brief The visible window of the world. Safe for concurrent use.
+ */
+type Viewport struct {
+	mu     sync.Mutex
+	span   int     ///< Shown cells per side
+	zoom   int     ///< World cells per shown cell, per side
+	x, y   int     ///< World cell at the top-left corner
+	follow Species ///< Species being followed, or SpeciesNone
+	target [2]int  ///< Last seen position of the followed animal
+	locked bool    ///< Whether target holds a position
+	styles Styles  ///< Priorities deciding what a zoomed-out cell shows
+}
+
+/**
+ * @brief Creates a viewport at the world origin.
+ * @param span Shown cells per side; values below 1 are treated as 1.
+ * @param zoom World cells per shown cell; values below 1 are treated as 1.
+ * @return The viewport.
+ */
+func NewViewport(span, zoom int) *Viewport {
+	return &Viewport{span: max(span, 1), zoom: max(zoom, 1), styles: defaultStyles}
+}
+
+/**
+ * @brief Sets the styles whose priorities decide which species a zoomed-out cell shows.
+ */
+func (v *Viewport) SetStyles(styles Styles) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.styles = styles
+}
+
+/**
+ * @brief Scrolls the window by whole shown cells.
+ * @details Scrolling stops following.
+ * @param dx Rows to move down (negative moves up).
+ * @param dy Columns to move right (negative moves left).
+ */
+func (v *Viewport) Pan(dx, dy int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.x += dx * v.zoom
+	v.y += dy * v.zoom
+	v.follow, v.locked = SpeciesNone, false
+}
+
+/**
+ * @brief Changes how many world cells each shown cell covers, keeping the centre in place.
+ * @param zoom The new zoom; values below 1 are treated as 1.
+ */
+func (v *Viewport) SetZoom(zoom int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	zoom = max(zoom, 1)
+	half := v.span / 2
+	v.x += half * (v.zoom - zoom)
+	v.y += half * (v.zoom - zoom)
+	v.zoom = zoom
+}
+
+/** @brief Returns the current zoom. */
+func (v *Viewport) Zoom() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.zoom
+}
+
+/**
+ * @brief Keeps the window centred on an animal of the given species.
+ * @param sp The species to follow; SpeciesNone stops following.
+ */
+func (v *Viewport) Follow(sp Species) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.follow, v.locked = sp, false
+}
+
+/**
+ * @brief Handles one control key.
+ * @details w/a/s/d or k/h/j/l scroll by a quarter of the window, + and - zoom, f follows a
+ * shark, g follows a fish and u stops following.
+ * @param key The key pressed.
+ * @return false if the key is not a control.
+ */
+func (v *Viewport) HandleKey(key byte) bool {
+	step := max(v.spanCells()/4, 1)
+	switch key {
+	case 'w', 'k':
+		v.Pan(-step, 0)
+	case 's', 'j':
+		v.Pan(step, 0)
+	case 'a', 'h':
+		v.Pan(0, -step)
+	case 'd', 'l':
+		v.Pan(0, step)
+	case '+', '=':
+		v.SetZoom(v.Zoom() / 2)
+	case '-', '_':
+		v.SetZoom(v.Zoom() * 2)
+	case 'f':
+		v.Follow(SpeciesShark)
+	case 'g':
+		v.Follow(SpeciesFish)
+	case 'u':
+		v.Follow(SpeciesNone)
+	default:
+		return false
+	}
+	return true
+}
+
+/** @brief Returns the window side in shown cells. */
+func (v *Viewport) spanCells() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.span
+}
+
+/**
+ * @brief Reads control keys until the reader is exhausted.
+ * @param r Source of keys, usually os.Stdin.
+ */
+func (v *Viewport) ReadControls(r io.Reader) {
+	readControls(r, v)
+}
+
+/**
+ * @brief Crops a frame to the window.
+ * @details The window wraps around the torus. A shown cell holds the species of highest style
+ * priority found in its block: by default a shark if any cell of the block holds one (with the
+ * highest energy among them), otherwise a fish if any does, so sparse animals stay visible
+ * when zoomed out. When following, the window is first re-centred.
+ * @param f The full frame.
+ * @return A frame of span x span cells with the same chronon.
+ */
+func (v *Viewport) Crop(f *Frame) *Frame {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.follow != SpeciesNone {
+		v.track(f)
+	}
+
+	out := &Frame{chronon: f.chronon, size: v.span, cells: make([]Species, v.span*v.span), energy: make([]uint16, v.span*v.span)}
+	if f.temp != nil {
+		out.temp = &Temperature{size: v.span, cells: make([]float32, v.span*v.span)}
+	}
+	for r := 0; r < v.span; r++ {
+		for c := 0; c < v.span; c++ {
+			energy, adult, tide := 0, false, false
+			var present [numSpecies]bool
+			var tags [3]int ///< A lineage seen per species
+			for dx := 0; dx < v.zoom; dx++ {
+				for dy := 0; dy < v.zoom; dy++ {
+					x, y := wrap(v.x+r*v.zoom+dx, f.size), wrap(v.y+c*v.zoom+dy, f.size)
+					present[f.At(x, y)] = true
+					switch f.At(x, y) {
+					case SpeciesShark:
+						energy = max(energy, f.Energy(x, y))
+					case SpeciesFish:
+						adult = adult || !f.Juvenile(x, y)
+					}
+					tide = tide || f.Tide(x, y)
+					if tag := f.Lineage(x, y); tag > 0 && tags[f.At(x, y)] == 0 {
+						tags[f.At(x, y)] = tag
+					}
+				}
+			}
+			sp := v.styles.dominant(present)
+			out.cells[r*v.span+c] = sp
+			if tide {
+				out.markTide(r*v.span + c) ///< Any covered cell marks the block
+			}
+			if tags[sp] > 0 {
+				out.markLineage(r*v.span+c, tags[sp]) ///< A tagged animal of the shown species marks the block
+			}
+			if out.temp != nil {
+				out.temp.cells[r*v.span+c] = f.temp.At(wrap(v.x+r*v.zoom, f.size), wrap(v.y+c*v.zoom, f.size))
+			}
+			switch sp {
+			case SpeciesFish:
+				out.fish++
+				if !adult {
+					out.markJuvenile(r*v.span + c) ///< Shown as juvenile only if every fish of the block is
+				}
+			case SpeciesShark:
+				out.sharks++
+				out.total += energy
+				out.energy[r*v.span+c] = uint16(energy)
+			}
+		}
+	}
+	return out
+}
+
+/**
+ * @brief Moves the target to the nearest followed animal and centres the window on it.
+ * @details Without a previous target the search starts from the window centre. Leaves the
+ * window alone if no animal of the species exists. Called with mu held.
+ */
+func (v *Viewport) track(f *Frame) {
+	from := v.target
+	if !v.locked {
+		half := v.span * v.zoom / 2
+		from = [2]int{wrap(v.x+half, f.size), wrap(v.y+half, f.size)}
+	}
+	best, found := 0, false
+	for x := 0; x < f.size; x++ {
+		for y := 0; y < f.size; y++ {
+			if f.At(x, y) != v.follow {
+				continue
+			}
+			if d := torusDistance(f.size, from, [2]int{x, y}); !found || d < best {
+				best, found, v.target = d, true, [2]int{x, y}
+			}
+		}
+	}
+	if !found {
+		v.locked = false
+		return
+	}
+	v.locked = true
+	half := v.span * v.zoom / 2
+	v.x, v.y = v.target[0]-half, v.target[1]-half
+}
+
+/**
+ * @brief Wraps a coordinate onto [0, size).
+ */
+func wrap(i, size int) int {
+	return ((i % size) + size) % size
+}
+
+/**
+ * @brief Builds the terminal renderer with the origin, viewport, inspector, painter and
+ * history requested by the configuration.
+ * @details Starts one background reader of control keys that feeds all of them. The
+ * inspector sees whole frames, so its cursor is in world coordinates, and the history wraps
+ * everything, so rewound frames are cropped and inspected as usual. Painting needs the
+ * inspector's cursor and the history's pause, so -paint turns both on (with one kept frame
+ * if -history is not given). Register the result with sim.OnChrononStart after calling this,
+ * so the entity registry is updated before each frame is drawn.
+ * @param ctx Context of the run; when it ends, a paused history lets the run finish.
+ * @param cfg Validated configuration.
+ * @param out Destination of the rendered frames.
+ * @param controls Source of control keys, read in the background until exhausted.
+ * @param sim The simulation being shown.
+ * @return The renderer to use.
+ */
+func withControls(ctx context.Context, cfg Config, out io.Writer, controls io.Reader, sim *Simulation) Renderer {
+	if cfg.Paint {
+		cfg.Inspect, cfg.History = true, max(cfg.History, 1)
+	}
+	var handlers []keyHandler
+	var inspector *Inspector
+	if cfg.Inspect {
+		inspector = NewInspector(sim.Registry(), cfg.GridSize, out)
+		out = inspector ///< The grid is drawn into the inspector, which adds its panel
+	}
+	r := newRenderer(cfg, out)
+	if cfg.Origin != "" || cfg.Center {
+		x, y, _ := parseOrigin(cfg.Origin) ///< Validated; empty with -center
+		origin := NewOrigin(x, y, cfg.Center)
+		handlers = append(handlers, origin)
+		r = &OriginRenderer{Renderer: r, Origin: origin}
+	}
+	if cfg.Viewport > 0 {
+		view := NewViewport(cfg.Viewport, cfg.Zoom)
+		styles, _ := parseStyles(cfg.Style) ///< Validated
+		view.SetStyles(styles)
+		view.Follow(map[string]Species{"shark": SpeciesShark, "fish": SpeciesFish}[cfg.Follow])
+		handlers = append(handlers, view)
+		r = &ViewportRenderer{Renderer: r, View: view}
+	}
+	if inspector != nil {
+		inspector.Renderer = r
+		handlers = append(handlers, inspector)
+		r = inspector
+	}
+	if cfg.Console {
+		handlers = append(handlers, NewConsole(sim, os.Stderr))
+	}
+	if cfg.History > 0 {
+		history := NewHistory(cfg.History, r)
+		context.AfterFunc(ctx, history.Release)
+		if cfg.Paint {
+			painter := &Painter{Sim: sim, Inspector: inspector, History: history}
+			inspector.Status = painter.Status
+			handlers = append(handlers, painter)
+		}
+		handlers = append(handlers, history) ///< Last, so that it redraws after the other keys
+		r = history
+	}
+	if len(handlers) > 0 {
+		go readControls(controls, handlers...) ///< Exits with the process
+	}
+	return r
+}
+
+/**
+ * @struct ViewportRenderer
+ * @brief Renders only the part of each frame inside a viewport.
+ */
+type ViewportRenderer struct {
+	Renderer Renderer  ///< Draws the cropped frame
+	View     *Viewport ///< The window to show
+}
+
+/**
+ * @brief Crops the frame to the viewport and passes it on.
+ * @param f The full frame.
+ */
+func (r *ViewportRenderer) Render(f *Frame) {
+	r.Renderer.Render(r.View.Crop(f))
+}
@@ -0,0 +1,84 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file viewport_test.go
+ * @brief Tests for the scrollable, zoomable viewport.
+ */
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Renders a frame as rows of F, S and '.' for comparison.
+ */
+func frameRows(f *Frame) string {
+	var b strings.Builder
+	for x := 0; x < f.Size(); x++ {
+		for y := 0; y < f.Size(); y++ {
+			b.WriteByte(".FS"[f.At(x, y)])
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func TestViewportCropWraps(t *testing.T) {
+	f := frameFromASCII(t, "F...\n....\n....\n...S\n", 3)
+	v := NewViewport(2, 1)
+	v.Pan(-1, -1)
+	got := v.Crop(f)
+	if want := "S.\n.F\n"; frameRows(got) != want {
+		t.Errorf("window across the wrap:\n%swant\n%s", frameRows(got), want)
+	}
+	if fish, sharks := got.Counts(); fish != 1 || sharks != 1 {
+		t.Errorf("cropped counts %d fish %d sharks, want 1 and 1", fish, sharks)
+	}
+	if got.Energy(0, 0) != 3 {
+		t.Errorf("shark energy %d, want 3", got.Energy(0, 0))
+	}
+}
+
+func TestViewportZoomAggregates(t *testing.T) {
+	f := frameFromASCII(t, "F...\n..S.\n....\n...F\n", 3)
+	got := NewViewport(2, 2).Crop(f)
+	if want := "FS\n.F\n"; frameRows(got) != want {
+		t.Errorf("zoomed out:\n%swant\n%s", frameRows(got), want)
+	}
+}
+
+func TestViewportFollowsNearest(t *testing.T) {
+	f := frameFromASCII(t, ".....\n.....\n.....\n.....\n....S\n", 3)
+	v := NewViewport(3, 1)
+	v.Follow(SpeciesShark)
+	if got := v.Crop(f); got.At(1, 1) != SpeciesShark {
+		t.Errorf("followed shark not centred:\n%s", frameRows(got))
+	}
+
+	moved := frameFromASCII(t, "S....\n.....\n.....\n.....\n.....\n", 3) ///< One step across the wrap
+	if got := v.Crop(moved); got.At(1, 1) != SpeciesShark {
+		t.Errorf("camera lost the shark after it moved:\n%s", frameRows(got))
+	}
+}
+
+func TestViewportKeys(t *testing.T) {
+	v := NewViewport(8, 1)
+	v.ReadControls(strings.NewReader("sd-x\n"))
+	if v.x != -2 || v.y != -2 || v.Zoom() != 2 { ///< Scrolled by 2, then zoomed out around the centre
+		t.Errorf("after s, d, -: origin (%d,%d) zoom %d, want (-2,-2) zoom 2", v.x, v.y, v.Zoom())
+	}
+	if v.HandleKey('x') {
+		t.Error("x is not a control")
+	}
+}
@@ -0,0 +1,74 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file vision.go
+ * @brief Shark pursuit of fish beyond the adjacent cells (the -shark-vision option).
+ * @details A shark that sees no adjacent fish normally wanders at random. With a vision of N,
+ * it runs a breadth-first search through empty cells, up to N steps on the torus, and takes
+ * one step along the shortest path to the nearest fish it finds.
+ */
+package main
+
+import "math/rand"
+
+/**
+ * @brief Finds the first step of a shortest path from (x, y) to the nearest visible fish.
+ * @details The search walks only through cells that are empty in this grid; the first step
+ * must additionally satisfy free and must not be on the path walked so far. Neighbours are
+ * visited in a random order, so ties between equally near fish are broken at random.
+ * @param rng The worker's random source.
+ * @param x The x-coordinate of the shark.
+ * @param y The y-coordinate of the shark.
+ * @param vision Maximum path length to a fish.
+ * @param path Cells already walked this chronon.
+ * @param free Reports whether a first step is still available (e.g. unclaimed in the new grid).
+ * @return The first step, or (-1, -1) if no fish is within sight.
+ */
+func (g *Grid) stepTowardFish(rng *rand.Rand, x, y, vision int, path [][2]int, free func(x, y int) bool) (int, int) {
+	type node struct {
+		x, y  int
+		first [2]int ///< First step of the path that reached this node
+		depth int    ///< Path length from the shark
+	}
+	order := randomDirections(rng)
+	visited := map[[2]int]bool{{x, y}: true}
+	var queue []node
+
+	for _, d := range order {
+		nx, ny := g.neighbour(x, y, d)
+		visited[[2]int{nx, ny}] = true
+		if g.speciesAt(nx, ny) == SpeciesNone && free(nx, ny) && !onPath(path, nx, ny) {
+			queue = append(queue, node{nx, ny, [2]int{nx, ny}, 1})
+		}
+	}
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, d := range order {
+			nx, ny := g.neighbour(n.x, n.y, d)
+			if visited[[2]int{nx, ny}] {
+				continue
+			}
+			visited[[2]int{nx, ny}] = true
+			switch g.speciesAt(nx, ny) {
+			case SpeciesFish:
+				return n.first[0], n.first[1] ///< Nearest fish, n.depth+1 steps away
+			case SpeciesNone:
+				if n.depth+1 < vision {
+					queue = append(queue, node{nx, ny, n.first, n.depth + 1})
+				}
+			}
+		}
+	}
+	return -1, -1
+}
@@ -0,0 +1,106 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+//go:build js && wasm
+
+/**
+ * @file wasm.go
+ * @brief Browser entry point: exposes the simulation to JavaScript.
+ * @details Build with "GOOS=js GOARCH=wasm go build -o ../web/wator.wasm ." and open the page
+ * in web/. The entry point registers a global "wator" object and then waits forever;
+ * JavaScript drives the simulation:
+ *   wator.start(params)   creates a simulation; params mirror the command-line flags
+ *                         (numShark, numFish, fishBreed, sharkBreed, starve, gridSize,
+ *                         threads, seed, engine, ruleset, order, sharkVision, crowdingK).
+ *                         Returns an error message, or "" on success.
+ *   wator.step()          advances one chronon and returns {chronon, fish, sharks}.
+ *   wator.draw(pixels)    copies the current frame into a Uint8ClampedArray of
+ *                         gridSize*gridSize*4 bytes, ready for ImageData.
+ */
+package main
+
+import (
+	"context"
+	"syscall/js"
+	"time"
+)
+
+/**
+ * @brief Registers the JavaScript API and keeps the Go runtime alive.
+ */
+func main() {
+	var sim *Simulation
+	pixels := &PixelRenderer{}
+
+	js.Global().Set("wator", js.ValueOf(map[string]any{
+		"start": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			cfg, err := browserConfig(args[0])
+			if err == nil {
+				sim, err = NewSimulation(cfg)
+			}
+			if err != nil {
+				sim = nil
+				return err.Error()
+			}
+			pixels.MaxEnergy = cfg.StarveEnergy
+			return ""
+		}),
+		"step": js.FuncOf(func(js.Value, []js.Value) any {
+			if sim == nil {
+				return nil
+			}
+			sim.Step(context.Background())
+			f := sim.Snapshot()
+			fish, sharks := f.Counts()
+			return map[string]any{"chronon": f.Chronon(), "fish": fish, "sharks": sharks}
+		}),
+		"draw": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			if sim == nil {
+				return nil
+			}
+			pixels.Render(sim.Snapshot())
+			js.CopyBytesToJS(args[0], pixels.Pix)
+			return nil
+		}),
+	}))
+	select {}
+}
+
+/**
+ * @brief Builds a validated configuration from a JavaScript parameter object.
+ * @details Missing properties keep their command-line defaults; a seed of 0 picks one from the clock.
+ * @param p The parameter object.
+ * @return The configuration, or the validation error.
+ */
+func browserConfig(p js.Value) (Config, error) {
+	cfg := defaultConfig()
+	for name, field := range map[string]*int{
+		"numShark": &cfg.NumShark, "numFish": &cfg.NumFish, "fishBreed": &cfg.FishBreed, "sharkBreed": &cfg.SharkBreed,
+		"starve": &cfg.StarveEnergy, "gridSize": &cfg.GridSize, "threads": &cfg.Threads,
+		"sharkVision": &cfg.SharkVision, "crowdingK": &cfg.CrowdingK,
+	} {
+		if v := p.Get(name); v.Type() == js.TypeNumber {
+			*field = v.Int()
+		}
+	}
+	for name, field := range map[string]*string{"engine": &cfg.Engine, "ruleset": &cfg.RuleSet, "order": &cfg.Order} {
+		if v := p.Get(name); v.Type() == js.TypeString {
+			*field = v.String()
+		}
+	}
+	if v := p.Get("seed"); v.Type() == js.TypeNumber {
+		cfg.Seed = int64(v.Float())
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	return cfg, cfg.Validate()
+}
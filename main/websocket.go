@@ -0,0 +1,186 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file websocket.go
+ * @brief The server side of the WebSocket protocol (RFC 6455), as far as streaming needs it.
+ * @details The server sends unfragmented text messages. Of what clients send, it answers pings
+ * and close frames and discards everything else, so a spectator cannot drive the simulation.
+ */
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/** Appended to the client's key to compute Sec-WebSocket-Accept. */
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+/** Frame opcodes. */
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+/** Longest control or data frame accepted from a client; spectators have nothing long to say. */
+const wsMaxClientFrame = 1 << 12
+
+/** Time allowed for one message to reach a client before it is dropped as stalled. */
+const wsWriteTimeout = 10 * time.Second
+
+/**
+ * @struct wsConn
+ * @brief An upgraded WebSocket connection.
+ */
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	mu   sync.Mutex ///< Serialises frames written by the sender and by the reader's replies
+}
+
+/**
+ * @brief Completes the opening handshake of a WebSocket request.
+ * @return The connection, or an error after answering 400 to a request that is not a WebSocket handshake.
+ */
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("not a WebSocket handshake")
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, r: rw.Reader}, nil
+}
+
+/**
+ * @brief Reports whether a comma-separated header lists a token, ignoring case.
+ */
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/**
+ * @brief Writes one unmasked frame, as servers must.
+ */
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	header := []byte{0x80 | opcode} ///< FIN: every message is a single frame
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = binary.BigEndian.AppendUint16(append(header, 126), uint16(n))
+	default:
+		header = binary.BigEndian.AppendUint64(append(header, 127), uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	_, err := (&net.Buffers{header, payload}).WriteTo(c.conn)
+	return err
+}
+
+/**
+ * @brief Sends a text message.
+ */
+func (c *wsConn) WriteText(msg []byte) error {
+	return c.writeFrame(wsText, msg)
+}
+
+/**
+ * @brief Reads client frames until the client closes the connection or breaks the protocol.
+ * @details Pings are answered and a close frame is echoed; data frames are discarded.
+ * @return nil after a close handshake, otherwise the error that ended the connection.
+ */
+func (c *wsConn) discardReads() error {
+	var head [2]byte
+	for {
+		if _, err := io.ReadFull(c.r, head[:]); err != nil {
+			return err
+		}
+		opcode, masked := head[0]&0x0F, head[1]&0x80 != 0
+		n := uint64(head[1] & 0x7F)
+		switch n {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+				return err
+			}
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+				return err
+			}
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+		if !masked || n > wsMaxClientFrame {
+			c.writeFrame(wsClose, []byte{0x03, 0xEA}) ///< 1002: protocol error
+			return errors.New("unmasked or oversized client frame")
+		}
+		var mask [4]byte
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(c.r, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+		switch opcode {
+		case wsClose:
+			c.writeFrame(wsClose, payload[:min(len(payload), 2)]) ///< Echo the status code
+			return nil
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+/**
+ * @brief Closes the underlying connection.
+ */
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,183 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file workerstats.go
+ * @brief Per-worker timing breakdown and load-imbalance reporting.
+ * @details Static row partitioning gives every worker the same number of rows, but not the
+ * same number of entities. These statistics show how unevenly the work is spread, both as
+ * an end-of-run report and as Prometheus metrics on the /metrics endpoint. The worker count
+ * can change during a run (-auto-threads, or Threads set by a script or the console), and
+ * times of different worker counts are not comparable, so totals are kept per worker count.
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+/**
+ * @struct WorkerStats
+ * @brief Accumulates worker timings across chronons.
+ * @details Safe for concurrent use: the simulation loop records while the metrics
+ * endpoint reads.
+ */
+type WorkerStats struct {
+	mu        sync.Mutex
+	chronons  int                   ///< Number of recorded chronons
+	byWorkers map[int]*workerTotals ///< Totals of the chronons run with each worker count
+	last      []time.Duration       ///< Busy time per worker in the latest chronon
+}
+
+/**
+ * @struct workerTotals
+ * @brief The accumulated timings of the chronons run with one worker count.
+ */
+type workerTotals struct {
+	chronons     int             ///< Number of chronons run with this many workers
+	totals       []time.Duration ///< Total busy time per worker
+	imbalanceSum float64         ///< Sum of per-chronon imbalance percentages
+}
+
+/**
+ * @brief Records the worker timings of one chronon.
+ * @param times Busy time of each worker, indexed by worker number.
+ */
+func (ws *WorkerStats) Record(times []time.Duration) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.byWorkers == nil {
+		ws.byWorkers = make(map[int]*workerTotals)
+	}
+	wt := ws.byWorkers[len(times)]
+	if wt == nil {
+		wt = &workerTotals{totals: make([]time.Duration, len(times))}
+		ws.byWorkers[len(times)] = wt
+	}
+	for i, t := range times {
+		wt.totals[i] += t
+	}
+	wt.imbalanceSum += imbalance(times)
+	wt.chronons++
+	ws.last = append(ws.last[:0], times...)
+	ws.chronons++
+}
+
+/**
+ * @brief Computes the load imbalance of a set of worker timings.
+ * @details Defined as how much longer the slowest worker took than the average, in percent.
+ * 0% means perfectly balanced; 100% means the slowest worker took twice the mean.
+ * @param times Worker timings.
+ * @return The imbalance percentage, or 0 when there is nothing to compare.
+ */
+func imbalance(times []time.Duration) float64 {
+	_, longest, mean := durationSummary(times)
+	if mean <= 0 {
+		return 0
+	}
+	return (float64(longest)/float64(mean) - 1) * 100
+}
+
+/**
+ * @brief Returns the minimum, maximum, and mean of a set of durations.
+ * @param times The durations to summarise.
+ * @return (shortest, longest, mean), all zero for an empty slice.
+ */
+func durationSummary(times []time.Duration) (shortest, longest, mean time.Duration) {
+	if len(times) == 0 {
+		return 0, 0, 0
+	}
+	shortest, longest = times[0], times[0]
+	var sum time.Duration
+	for _, t := range times {
+		if t < shortest {
+			shortest = t
+		}
+		if t > longest {
+			longest = t
+		}
+		sum += t
+	}
+	return shortest, longest, sum / time.Duration(len(times))
+}
+
+/**
+ * @brief Logs the end-of-run worker timing report.
+ * @details Reports min/max/mean total busy time across workers, the imbalance of those
+ * totals, and the average per-chronon imbalance, once for every worker count the run used.
+ */
+func (ws *WorkerStats) Report() {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	for _, workers := range slices.Sorted(maps.Keys(ws.byWorkers)) {
+		wt := ws.byWorkers[workers]
+		shortest, longest, mean := durationSummary(wt.totals)
+		slog.Info("worker timing",
+			"workers", workers,
+			"chronons", wt.chronons,
+			"min", shortest,
+			"max", longest,
+			"mean", mean,
+			"imbalance_pct", fmt.Sprintf("%.1f", imbalance(wt.totals)),
+			"mean_chronon_imbalance_pct", fmt.Sprintf("%.1f", wt.imbalanceSum/float64(wt.chronons)))
+		for i, t := range wt.totals {
+			slog.Debug("worker total", "workers", workers, "worker", i, "busy", t)
+		}
+	}
+}
+
+/**
+ * @brief Writes the statistics in the Prometheus text exposition format.
+ * @param w Destination writer.
+ */
+func (ws *WorkerStats) WritePrometheus(w io.Writer) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP wator_chronons_total Chronons simulated so far.")
+	fmt.Fprintln(w, "# TYPE wator_chronons_total counter")
+	fmt.Fprintf(w, "wator_chronons_total %d\n", ws.chronons)
+
+	fmt.Fprintln(w, "# HELP wator_worker_busy_seconds_total Time each worker spent processing its rows, by worker count.")
+	fmt.Fprintln(w, "# TYPE wator_worker_busy_seconds_total counter")
+	for _, workers := range slices.Sorted(maps.Keys(ws.byWorkers)) {
+		for i, t := range ws.byWorkers[workers].totals {
+			fmt.Fprintf(w, "wator_worker_busy_seconds_total{workers=\"%d\",worker=\"%d\"} %g\n", workers, i, t.Seconds())
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP wator_worker_last_chronon_seconds Time each worker spent in the latest chronon.")
+	fmt.Fprintln(w, "# TYPE wator_worker_last_chronon_seconds gauge")
+	for i, t := range ws.last {
+		fmt.Fprintf(w, "wator_worker_last_chronon_seconds{worker=\"%d\"} %g\n", i, t.Seconds())
+	}
+
+	fmt.Fprintln(w, "# HELP wator_worker_imbalance_percent Load imbalance of the latest chronon (slowest worker vs mean).")
+	fmt.Fprintln(w, "# TYPE wator_worker_imbalance_percent gauge")
+	fmt.Fprintf(w, "wator_worker_imbalance_percent %g\n", imbalance(ws.last))
+}
+
+/**
+ * @brief Serves the statistics as a Prometheus scrape target.
+ */
+func (ws *WorkerStats) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	ws.WritePrometheus(w)
+}
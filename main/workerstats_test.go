@@ -0,0 +1,109 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file workerstats_test.go
+ * @brief Tests for the worker timing summary, load imbalance and Prometheus output.
+ */
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationSummary(t *testing.T) {
+	cases := []struct {
+		times                   []time.Duration
+		shortest, longest, mean time.Duration
+	}{
+		{nil, 0, 0, 0},
+		{[]time.Duration{5}, 5, 5, 5},
+		{[]time.Duration{3, 1, 2}, 1, 3, 2},
+		{[]time.Duration{10, 10, 10, 10}, 10, 10, 10},
+		{[]time.Duration{0, 7}, 0, 7, 3}, ///< The mean truncates like integer division
+	}
+	for _, c := range cases {
+		shortest, longest, mean := durationSummary(c.times)
+		if shortest != c.shortest || longest != c.longest || mean != c.mean {
+			t.Errorf("durationSummary(%v) = %v, %v, %v; want %v, %v, %v", c.times, shortest, longest, mean, c.shortest, c.longest, c.mean)
+		}
+	}
+}
+
+func TestImbalance(t *testing.T) {
+	cases := []struct {
+		times []time.Duration
+		want  float64
+	}{
+		{nil, 0},
+		{[]time.Duration{0, 0}, 0}, ///< No work at all is not imbalanced
+		{[]time.Duration{4}, 0},
+		{[]time.Duration{4, 4, 4}, 0},
+		{[]time.Duration{1, 3}, 50},
+		{[]time.Duration{0, 0, 0, 4}, 300},
+		{[]time.Duration{2, 4, 6}, 50},
+	}
+	for _, c := range cases {
+		if got := imbalance(c.times); got != c.want {
+			t.Errorf("imbalance(%v) = %g, want %g", c.times, got, c.want)
+		}
+	}
+}
+
+func TestWorkerStatsPrometheus(t *testing.T) {
+	var ws WorkerStats
+	ws.Record([]time.Duration{time.Second, 3 * time.Second})
+	ws.Record([]time.Duration{2 * time.Second, 2 * time.Second, 500 * time.Millisecond}) ///< A third worker joins
+	ws.Record([]time.Duration{3 * time.Second, 2 * time.Second, time.Second})
+
+	rec := httptest.NewRecorder()
+	ws.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4" {
+		t.Errorf("content type %q", ct)
+	}
+	want := `# HELP wator_chronons_total Chronons simulated so far.
+# TYPE wator_chronons_total counter
+wator_chronons_total 3
+# HELP wator_worker_busy_seconds_total Time each worker spent processing its rows, by worker count.
+# TYPE wator_worker_busy_seconds_total counter
+wator_worker_busy_seconds_total{workers="2",worker="0"} 1
+wator_worker_busy_seconds_total{workers="2",worker="1"} 3
+wator_worker_busy_seconds_total{workers="3",worker="0"} 5
+wator_worker_busy_seconds_total{workers="3",worker="1"} 4
+wator_worker_busy_seconds_total{workers="3",worker="2"} 1.5
+# HELP wator_worker_last_chronon_seconds Time each worker spent in the latest chronon.
+# TYPE wator_worker_last_chronon_seconds gauge
+wator_worker_last_chronon_seconds{worker="0"} 3
+wator_worker_last_chronon_seconds{worker="1"} 2
+wator_worker_last_chronon_seconds{worker="2"} 1
+# HELP wator_worker_imbalance_percent Load imbalance of the latest chronon (slowest worker vs mean).
+# TYPE wator_worker_imbalance_percent gauge
+wator_worker_imbalance_percent 50
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWorkerStatsPrometheusBeforeFirstChronon(t *testing.T) {
+	var ws WorkerStats
+	var out strings.Builder
+	ws.WritePrometheus(&out)
+	if !strings.Contains(out.String(), "wator_chronons_total 0\n") || !strings.HasSuffix(out.String(), "wator_worker_imbalance_percent 0\n") {
+		t.Errorf("empty statistics:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "{worker=") {
+		t.Errorf("series for workers that never ran:\n%s", out.String())
+	}
+}
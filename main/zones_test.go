@@ -0,0 +1,104 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file zones_test.go
+ * @brief Tests for per-zone population statistics.
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseZones(t *testing.T) {
+	zones, err := parseZones("quadrants; reef = (2,3)-(4, 9)", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 5 || zones[3] != (Zone{"se", 10, 10, 19, 19}) || zones[4] != (Zone{"reef", 2, 3, 4, 9}) {
+		t.Errorf("got %+v", zones)
+	}
+	if zones[4].Cells() != 21 {
+		t.Errorf("reef has %d cells, want 21", zones[4].Cells())
+	}
+	for spec, want := range map[string]string{
+		"reef":                        "expected quadrants",
+		"reef=(0,0)":                  "expected quadrants",
+		"=(0,0)-(1,1)":                "expected quadrants",
+		"reef=(0,0)-(20,1)":           "not a rectangle",
+		"reef=(5,5)-(1,1)":            "not a rectangle",
+		"nw=(0,0)-(1,1);quadrants":    "defined twice",
+		"a=(0,0)-(1,1);a=(2,2)-(3,3)": "defined twice",
+	} {
+		if _, err := parseZones(spec, 20); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want an error mentioning %q", spec, err, want)
+		}
+	}
+}
+
+func TestQuadrantsCoverTheGrid(t *testing.T) {
+	sim, err := NewSimulation(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "zones.csv")
+	zones, err := parseZones("quadrants", testConfig().GridSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw, err := NewZoneWriter(path, zones, testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals := make(map[string][2]int) ///< Population totals per chronon, from the frames
+	sim.OnChrononEnd(func(f *Frame, _ StepReport) {
+		fish, sharks := f.Counts()
+		totals[strconv.Itoa(f.Chronon())] = [2]int{fish, sharks}
+	})
+	sim.OnChrononEnd(zw.Record)
+	sim.Run(context.Background(), 3)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := csv.NewReader(strings.NewReader(string(data)))
+	r.Comment = '#' ///< Provenance
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1+3*4 {
+		t.Fatalf("got %d rows, want header plus 4 zones for each of 3 chronons", len(rows))
+	}
+	sums := make(map[string][2]int)
+	for _, r := range rows[1:] {
+		fish, _ := strconv.Atoi(r[3])
+		sharks, _ := strconv.Atoi(r[4])
+		s := sums[r[0]]
+		sums[r[0]] = [2]int{s[0] + fish, s[1] + sharks}
+	}
+	for chronon, want := range totals {
+		if sums[chronon] != want {
+			t.Errorf("chronon %s: quadrants sum to %v, want %v", chronon, sums[chronon], want)
+		}
+	}
+}
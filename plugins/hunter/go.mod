@@ -0,0 +1,3 @@
+module wator-plugin-hunter
+
+go 1.24
@@ -0,0 +1,74 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Implementation of the Wa-Tor simulation to demonstrate understanding
+// of Go concurrency and threading.
+// Issues:
+// None
+// --------------------------------------------
+
+/**
+ * @file main.go
+ * @brief Example species plugin: sharks that hunt within two cells and rest when hungry.
+ * @details Build it into the plugins directory with
+ *
+ *     GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o ../hunter.wasm .
+ *
+ * and run "wator run -plugin shark=hunter". The ABI is described in main/plugin.go.
+ */
+package main
+
+import (
+	"encoding/binary"
+	"unsafe"
+)
+
+/** Cell codes in the input buffer. */
+const (
+	empty = 0
+	fish  = 1
+)
+
+/** Actions returned by wator_decide besides the directions 0-3 (north, south, west, east). */
+const (
+	stay    = 4
+	builtin = -1
+)
+
+var input [48]byte ///< Filled by the host before every call
+
+/**
+ * @brief Returns the address of the input buffer.
+ */
+//go:wasmexport wator_input
+func watorInput() int32 {
+	return int32(uintptr(unsafe.Pointer(&input)))
+}
+
+/**
+ * @brief Eats an adjacent fish, steps toward one two cells away, and otherwise rests
+ * while hungry or wanders with the built-in movement.
+ */
+//go:wasmexport wator_decide
+func watorDecide() int32 {
+	cell := func(dx, dy int) byte { return input[(dx+2)*5+dy+2] }
+	steps := [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} ///< north, south, west, east
+	for d, s := range steps {
+		if cell(s[0], s[1]) == fish {
+			return int32(d)
+		}
+	}
+	for d, s := range steps {
+		if cell(s[0], s[1]) == empty && cell(2*s[0], 2*s[1]) == fish {
+			return int32(d)
+		}
+	}
+	if energy := int32(binary.LittleEndian.Uint32(input[32:])); energy <= 2 {
+		return stay
+	}
+	return builtin
+}
+
+func main() {}
@@ -0,0 +1,49 @@
+#!/usr/bin/env python3
+# --------------------------------------------
+# Author: Kirubel Temesgen (C00260396)
+# Date: 07/12/2024
+# Project: Wa-Tor Simulation
+# Description:
+# Reference reader for the -shm frame export: maps the file the simulation
+# publishes to and prints the population of every new frame. The layout is
+# documented in main/shm.go.
+# Issues:
+# None
+# --------------------------------------------
+"""Usage: python3 tools/shm_reader.py /dev/shm/wator"""
+
+import mmap
+import struct
+import sys
+import time
+
+HEADER = struct.Struct("<4sIIIQQII")  # magic, version, width, height, seq, chronon, fish, sharks
+
+
+def read_frame(m):
+    """Returns (chronon, fish, sharks, width, height, cells) from a consistent frame."""
+    while True:
+        magic, version, width, height, seq, chronon, fish, sharks = HEADER.unpack_from(m, 0)
+        if magic != b"WATR" or version != 1:
+            raise ValueError("not a Wa-Tor frame file")
+        if seq % 2:
+            continue  # Frame being written
+        cells = m[HEADER.size:HEADER.size + width * height]
+        if struct.unpack_from("<Q", m, 16)[0] == seq:
+            return chronon, fish, sharks, width, height, cells
+
+
+def main():
+    with open(sys.argv[1], "rb") as f, mmap.mmap(f.fileno(), 0, access=mmap.ACCESS_READ) as m:
+        last = None
+        while True:
+            chronon, fish, sharks, width, height, cells = read_frame(m)
+            if chronon != last:
+                print(f"chronon {chronon}: {fish} fish, {sharks} sharks "
+                      f"({width}x{height}, first row {bytes(cells[:min(width, 40)]).translate(bytes.maketrans(bytes([0, 1, 2]), b'.FS')).decode()})")
+                last = chronon
+            time.sleep(0.05)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,82 @@
+#!/usr/bin/env python3
+# --------------------------------------------
+# Author: Kirubel Temesgen (C00260396)
+# Date: 07/12/2024
+# Project: Wa-Tor Simulation
+# Description:
+# Reference client for the -pipe protocol (documented in main/pipe.go), for
+# driving the simulation from Python scripts and Jupyter notebooks.
+# Issues:
+# None
+# --------------------------------------------
+"""Drive a Wa-Tor simulation from Python.
+
+    from wator_client import Wator
+    with Wator(["go", "run", "./main"], "-seed", "1") as w:
+        history = [w.step()["fish"] for _ in range(100)]
+        grid = w.frame()["rows"]           # list of strings: F fish, S shark, . empty
+        w.reset(FishBreed=5, **{"shark-vision": 2})
+"""
+
+import json
+import subprocess
+
+
+class WatorError(RuntimeError):
+    """A request was rejected by the simulation."""
+
+
+class Wator:
+    """A simulation running in a child process, spoken to over -pipe."""
+
+    def __init__(self, command=("go", "run", "./main"), *args):
+        """Starts the simulation; args are extra flags or positional parameters."""
+        self._proc = subprocess.Popen([*command, "-pipe", *args], stdin=subprocess.PIPE,
+                                      stdout=subprocess.PIPE, text=True, bufsize=1)
+
+    def request(self, cmd, **fields):
+        """Sends one request and returns the reply, raising WatorError if it failed."""
+        self._proc.stdin.write(json.dumps({"cmd": cmd, **fields}) + "\n")
+        self._proc.stdin.flush()
+        line = self._proc.stdout.readline()
+        if not line:
+            raise WatorError("simulation exited")
+        reply = json.loads(line)
+        if not reply["ok"]:
+            raise WatorError(reply["error"])
+        return reply
+
+    def step(self, n=1):
+        """Advances n chronons and returns the stats."""
+        return self.request("step", n=n)
+
+    def stats(self):
+        """Returns chronon, populations, mean shark energy and birth/death totals."""
+        return self.request("stats")
+
+    def frame(self):
+        """Returns the stats plus size and rows of the current grid."""
+        return self.request("frame")
+
+    def config(self):
+        """Returns the current parameters."""
+        return self.request("config")["config"]
+
+    def reset(self, seed=0, **params):
+        """Starts a new world; params use positional names (FishBreed) or flag names (shark-vision)."""
+        return self.request("reset", params=params, seed=seed)
+
+    def close(self):
+        """Asks the simulation to exit and waits for it."""
+        if self._proc.poll() is None:
+            try:
+                self.request("quit")
+            except (WatorError, BrokenPipeError):
+                pass
+            self._proc.wait()
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
@@ -0,0 +1,70 @@
+// --------------------------------------------
+// Author: Kirubel Temesgen (C00260396)
+// Date: 07/12/2024
+// Project: Wa-Tor Simulation
+// Description:
+// Loads the WebAssembly build, wires the sliders to wator.start and draws every
+// chronon onto the canvas (see main/wasm.go for the API).
+// Issues:
+// None
+// --------------------------------------------
+
+const canvas = document.getElementById("world");
+const ctx = canvas.getContext("2d");
+const form = document.getElementById("params");
+const stats = document.getElementById("stats");
+const error = document.getElementById("error");
+
+let image = null; // ImageData matching the grid size
+let timer = null; // Interval driving the simulation
+
+/** Shows each slider's value next to it. */
+for (const input of form.querySelectorAll("input[type=range]")) {
+  const show = () => (input.nextElementSibling.value = input.value);
+  input.addEventListener("input", show);
+  show();
+}
+
+/** Reads the sliders into a parameter object for wator.start. */
+function params() {
+  const p = {};
+  for (const input of form.querySelectorAll("input[type=range]")) {
+    p[input.name] = Number(input.value);
+  }
+  return p;
+}
+
+/** Creates a new simulation from the sliders and starts stepping it. */
+function restart() {
+  clearInterval(timer);
+  const p = params();
+  const err = wator.start(p);
+  error.textContent = err;
+  if (err) {
+    return;
+  }
+  canvas.width = canvas.height = p.gridSize;
+  image = ctx.createImageData(p.gridSize, p.gridSize);
+  draw({ chronon: 0 });
+  timer = setInterval(() => draw(wator.step()), 1000 / p.speed);
+}
+
+/** Copies the current frame onto the canvas and updates the counters. */
+function draw(counts) {
+  wator.draw(image.data);
+  ctx.putImageData(image, 0, 0);
+  if (counts.fish !== undefined) {
+    stats.textContent = `Chronon ${counts.chronon}: ${counts.fish} fish, ${counts.sharks} sharks`;
+  }
+}
+
+form.addEventListener("submit", (e) => {
+  e.preventDefault();
+  restart();
+});
+
+const go = new Go();
+WebAssembly.instantiateStreaming(fetch("wator.wasm"), go.importObject).then((result) => {
+  go.run(result.instance);
+  restart();
+});